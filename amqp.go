@@ -0,0 +1,465 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// amqpImports are the imports of the AMQP transport, besides those of the
+// endpoints.
+var amqpImports = map[string]string{
+	"context":                              "",
+	"encoding/json":                        "",
+	"strconv":                              "",
+	"sync":                                 "",
+	"github.com/go-kit/kit/endpoint":       "",
+	"github.com/go-kit/kit/transport/amqp": "amqptransport",
+	"github.com/streadway/amqp":            "amqp",
+}
+
+// resolveAMQP sets the queues and routing keys of the methods of fns from
+// their "//kit:amqp [queue=<queue>] [key=<routing key>]" annotations.
+func resolveAMQP(fns []Func) error {
+	for i := range fns {
+		fn := &fns[i]
+		a, ok := fn.Annotation("amqp")
+		if !ok {
+			continue
+		}
+		if len(a.Args) == 0 {
+			return fn.errorf(a, "kit:amqp takes queue=<queue> and/or key=<routing key>")
+		}
+		for _, arg := range a.Args {
+			var name *string
+			switch {
+			case strings.HasPrefix(arg, "queue="):
+				name = &fn.AMQPQueue
+			case strings.HasPrefix(arg, "key="):
+				name = &fn.AMQPKey
+			default:
+				return fn.errorf(a, "kit:amqp: unknown argument %q, want queue=<queue> or key=<routing key>", arg)
+			}
+			*name = arg[strings.Index(arg, "=")+1:]
+			if *name == "" || len(*name) > 255 || strings.HasPrefix(*name, "amq.") {
+				return fn.errorf(a, "kit:amqp: invalid %s, want a name of at most 255 bytes not starting with amq.", arg)
+			}
+		}
+	}
+	return nil
+}
+
+// AMQPQueue returns the queue f consumes its requests from over AMQP: that
+// of its kit:amqp annotation, or else made of the names of the interface
+// and of f, e.g. user-service.get-user.
+func (s Service) AMQPQueue(f Func) string {
+	if f.AMQPQueue != "" {
+		return f.AMQPQueue
+	}
+	return kebabCase(s.IFaceName()) + "." + kebabCase(f.Name)
+}
+
+// AMQPKey returns the routing key binding the queue of f to the exchange of
+// the subscribers: that of its kit:amqp annotation, or else its queue.
+func (s Service) AMQPKey(f Func) string {
+	if f.AMQPKey != "" {
+		return f.AMQPKey
+	}
+	return s.AMQPQueue(f)
+}
+
+// checkAMQP returns an error if two methods of s consume the same AMQP
+// queue or are bound with the same routing key, or if s takes the tenant
+// from the HTTP request, which AMQP messages don't carry.
+func checkAMQP(s Service) error {
+	if s.Tenant != "" {
+		return fmt.Errorf("-transports: amqp can't be combined with -tenant, AMQP messages don't carry the tenant")
+	}
+	queues, keys := map[string]string{}, map[string]string{}
+	for _, f := range s.Funcs {
+		queue, key := s.AMQPQueue(f), s.AMQPKey(f)
+		if m, ok := queues[queue]; ok {
+			return fmt.Errorf("-transports: %s and %s both consume the AMQP queue %s, see kit:amqp", m, f.Name, queue)
+		}
+		if m, ok := keys[key]; ok {
+			return fmt.Errorf("-transports: %s and %s are both bound with the AMQP routing key %s, see kit:amqp", m, f.Name, key)
+		}
+		queues[queue], keys[key] = f.Name, f.Name
+	}
+	return nil
+}
+
+// AMQPImports returns the imports of the AMQP transport of s.
+func (s Service) AMQPImports() map[string]string {
+	imps := map[string]string{}
+	for imp, alias := range s.Imports {
+		imps[imp] = alias
+	}
+	for imp, alias := range amqpImports {
+		imps[imp] = alias
+	}
+	return imps
+}
+
+const amqpTemplate = `
+{{ define "amqp" }}
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package {{ .Pkg }}
+{{ $svc := . }}
+import ({{ range $imp, $alias := .AMQPImports }}{{ $alias }} "{{ $imp }}"
+{{ end }}
+)
+
+// The queues the methods of {{ .IFace }} consume their requests from over
+// AMQP, and the routing keys binding them to the exchange of SubscribeAMQP.
+const ({{ range .Funcs }}
+	{{ .Name }}AMQPQueue = "{{ $svc.AMQPQueue . }}"
+	{{ .Name }}AMQPKey   = "{{ $svc.AMQPKey . }}"{{ end }}
+)
+{{ if .Hooks }}
+// AMQPSubscriberOptions are passed to the subscriber of every method, e.g. to
+// add amqptransport.SubscriberBefore functions. Set them at init time, before
+// NewAMQPSubscribers is called.
+var AMQPSubscriberOptions []amqptransport.SubscriberOption
+{{ end }}
+// NewAMQPSubscribers returns the AMQP subscribers of the endpoints of svc, by
+// queue. They reply to the queue of the ReplyTo of the requests, with their
+// correlation ID, the JSON of the response types of the endpoints,{{ if .Compression }}
+// compressed with the codec of the request,{{ end }} or
+// {"err": "<message>"} if they fail, and acknowledge the requests once
+// replied to.{{ if .DeadLetter }} They retry the requests as set by MessageRetry, and publish
+// those they dead-letter, see amqpDeadLetter.{{ end }}
+func NewAMQPSubscribers(svc {{ .IFace }}) map[string]*amqptransport.Subscriber {
+	options := []amqptransport.SubscriberOption{
+		amqptransport.SubscriberBefore(amqptransport.SetContentType("application/json")),{{ if .Compression }}
+		amqptransport.SubscriberBefore(amqpCodecToContext),{{ end }}{{ if .DeadLetter }}
+		amqptransport.SubscriberBefore(amqpMessageToContext),{{ end }}
+		amqptransport.SubscriberResponsePublisher(replyAMQP),{{ if not .DeadLetter }}
+		amqptransport.SubscriberErrorEncoder(amqptransport.ReplyAndAckErrorEncoder),{{ end }}
+	}{{ if .Hooks }}
+	options = append(options, AMQPSubscriberOptions...){{ end }}
+	return map[string]*amqptransport.Subscriber{ {{ range .Funcs }}
+		{{ .Name }}AMQPQueue: amqptransport.NewSubscriber(
+			{{ if $svc.DeadLetter }}retryMessages("amqp", "{{ .Name }}")({{ $svc.Endpoint . }}){{ else }}{{ $svc.Endpoint . }}{{ end }},
+			DecodeAMQP{{ .Name }}Request,
+			{{ if $svc.Compression }}encodeAMQPResponse{{ else }}amqptransport.EncodeJSONResponse{{ end }},
+			{{ if $svc.DeadLetter }}append([]amqptransport.SubscriberOption{amqptransport.SubscriberErrorEncoder(amqpDeadLetter({{ .Name }}AMQPQueue, "{{ .Name }}"))}, options...)...{{ else }}options...{{ end }},
+		),{{ end }}
+	}
+}
+
+// SubscribeAMQP declares the durable queues of the subscribers of
+// NewAMQPSubscribers(svc) on ch, binds them to exchange with their routing
+// keys unless it is "", the default exchange routing the requests by queue,
+// and serves the requests delivered to them until ch is closed. The
+// requests of a queue are served one at a time: bound those delivered ahead
+// with ch.Qos, and run more instances of the service to serve more of them.{{ if .DeadLetter }}
+// It declares the durable dead-letter queues of the queues, <queue>.dlq, as
+// well.{{ end }}
+func SubscribeAMQP(ch *amqp.Channel, exchange string, svc {{ .IFace }}) error {
+	subscribers := NewAMQPSubscribers(svc)
+	for _, q := range []struct{ queue, key string }{ {{ range .Funcs }}
+		{ {{- .Name }}AMQPQueue, {{ .Name }}AMQPKey},{{ end }}
+	} {
+		if _, err := ch.QueueDeclare(q.queue, true, false, false, false, nil); err != nil {
+			return err
+		}{{ if .DeadLetter }}
+		if _, err := ch.QueueDeclare(q.queue+".dlq", true, false, false, false, nil); err != nil {
+			return err
+		}{{ end }}
+		if exchange != "" {
+			if err := ch.QueueBind(q.queue, q.key, exchange, false, nil); err != nil {
+				return err
+			}
+		}
+		deliveries, err := ch.Consume(q.queue, "", false, false, false, false, nil)
+		if err != nil {
+			return err
+		}
+		serve := subscribers[q.queue].ServeDelivery(ch)
+		go func() {
+			for d := range deliveries {
+				serve(&d)
+			}
+		}()
+	}
+	return nil
+}
+
+// replyAMQP publishes the reply to a request like
+// amqptransport.DefaultResponsePublisher, then acknowledges the request.
+func replyAMQP(ctx context.Context, d *amqp.Delivery, ch amqptransport.Channel, pub *amqp.Publishing) error {
+	if err := amqptransport.DefaultResponsePublisher(ctx, d, ch, pub); err != nil {
+		return err
+	}
+	return d.Ack(false)
+}
+{{ if .Compression }}
+// amqpCodecToContext puts the codec a request is compressed with in its
+// context, for encodeAMQPResponse to reply with.
+func amqpCodecToContext(ctx context.Context, _ *amqp.Publishing, d *amqp.Delivery) context.Context {
+	return context.WithValue(ctx, codecContextKey, payloadCodec(d.Body))
+}
+
+// encodeAMQPResponse encodes the JSON of a response into the body of pub,
+// compressed with the codec of the request, which it names as its content
+// encoding.
+func encodeAMQPResponse(ctx context.Context, pub *amqp.Publishing, response interface{}) error {
+	body, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	codec, _ := ctx.Value(codecContextKey).(string)
+	if pub.Body, err = compress(codec, body); err != nil {
+		return err
+	}
+	pub.ContentEncoding = codec
+	return nil
+}
+{{ end }}{{ if .DeadLetter }}
+// amqpMessageToContext puts the state of a request in its context, for
+// retryMessages to record how its endpoint did and amqpDeadLetter to
+// dead-letter it.
+func amqpMessageToContext(ctx context.Context, _ *amqp.Publishing, _ *amqp.Delivery) context.Context {
+	return context.WithValue(ctx, messageContextKey, &message{})
+}
+
+// amqpDeadLetter returns the error encoder of the AMQP subscriber of method,
+// consuming queue. It publishes the requests dead-lettered by
+// deadLetterReason to the dead-letter queue of queue, <queue>.dlq, as they
+// were delivered, counting them in DeadLetterCount, then replies like
+// amqptransport.ReplyAndAckErrorEncoder.{{ if .Hooks }} AMQPSubscriberOptions can replace it.{{ end }}
+func amqpDeadLetter(queue, method string) amqptransport.ErrorEncoder {
+	return func(ctx context.Context, err error, d *amqp.Delivery, ch amqptransport.Channel, pub *amqp.Publishing) {
+		if reason := deadLetterReason(ctx, err); reason != "" {
+			dead := amqp.Publishing{
+				Headers:         d.Headers,
+				ContentType:     d.ContentType,
+				ContentEncoding: d.ContentEncoding,
+				DeliveryMode:    amqp.Persistent,
+				Body:            d.Body,
+			}
+			if ch.Publish("", queue+".dlq", false, false, dead) == nil {
+				DeadLetterCount.With("transport", "amqp", "method", method, "reason", reason).Add(1)
+			}
+		}
+		amqptransport.ReplyAndAckErrorEncoder(ctx, err, d, ch, pub)
+	}
+}
+{{ end }}{{ range .Funcs }}
+// DecodeAMQP{{ .Name }}Request decodes the JSON {{ $svc.Request . }} of a request
+// delivered to {{ .Name }}AMQPQueue{{ if $svc.Compression }}, decompressing it first if it is compressed{{ end }}.
+func DecodeAMQP{{ .Name }}Request(_ context.Context, d *amqp.Delivery) (interface{}, error) {
+	var request {{ $svc.Request . }}{{ if $svc.Compression }}
+	body, err := decompress(d.Body)
+	if err != nil {
+		return nil, err
+	}{{ end }}
+	if len({{ if $svc.Compression }}body{{ else }}d.Body{{ end }}) > 0 {
+		if err := json.Unmarshal({{ if $svc.Compression }}body{{ else }}d.Body{{ end }}, &request); err != nil {
+			return nil, err
+		}
+	}{{ if .RequestType }}
+	if v, ok := interface{}(request).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}{{ else if HasConstraints . }}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}{{ end }}
+	return request, nil
+}
+{{ end }}{{ end }}
+`
+
+const amqpClientTemplate = `
+{{ define "amqpclient" }}
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package {{ .Pkg }}
+{{ $svc := . }}
+import ({{ range $imp, $alias := .AMQPImports }}{{ $alias }} "{{ $imp }}"
+{{ end }}
+)
+
+// AMQPReplies receives the replies to the requests of the AMQP clients on an
+// exclusive queue, handing each to the call waiting for its correlation ID,
+// for the calls made concurrently on a channel to get their own replies.
+type AMQPReplies struct {
+	ch    *amqp.Channel
+	queue amqp.Queue
+
+	mu      sync.Mutex
+	seq     uint64
+	pending map[string]chan amqp.Delivery
+	closed  bool
+}
+
+// NewAMQPReplies declares an exclusive reply queue, named by the broker, on
+// ch and consumes it until ch is closed, failing the calls waiting for a
+// reply then with amqp.ErrClosed.
+func NewAMQPReplies(ch *amqp.Channel) (*AMQPReplies, error) {
+	queue, err := ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := ch.Consume(queue.Name, "", true, true, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	r := &AMQPReplies{ch: ch, queue: queue, pending: map[string]chan amqp.Delivery{}}
+	go r.dispatch(deliveries)
+	return r, nil
+}
+
+func (r *AMQPReplies) dispatch(deliveries <-chan amqp.Delivery) {
+	for d := range deliveries {
+		r.mu.Lock()
+		reply, ok := r.pending[d.CorrelationId]
+		delete(r.pending, d.CorrelationId)
+		r.mu.Unlock()
+		if ok {
+			reply <- d
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	for id, reply := range r.pending {
+		close(reply)
+		delete(r.pending, id)
+	}
+}
+
+// deliverer returns the amqptransport.Deliverer of the clients publishing
+// their requests on exchange with the routing key key. It replaces the
+// correlation ID of every request with one unique to r and waits for the
+// reply carrying it, rather than consuming the reply queue itself as
+// amqptransport.DefaultDeliverer does.
+func (r *AMQPReplies) deliverer(exchange, key string) amqptransport.Deliverer {
+	return func(ctx context.Context, _ amqptransport.Publisher, pub *amqp.Publishing) (*amqp.Delivery, error) {
+		reply := make(chan amqp.Delivery, 1)
+		r.mu.Lock()
+		if r.closed {
+			r.mu.Unlock()
+			return nil, amqp.ErrClosed
+		}
+		r.seq++
+		id := strconv.FormatUint(r.seq, 10)
+		r.pending[id] = reply
+		r.mu.Unlock()
+		defer func() {
+			r.mu.Lock()
+			delete(r.pending, id)
+			r.mu.Unlock()
+		}()
+
+		pub.CorrelationId, pub.ReplyTo = id, r.queue.Name
+		if err := r.ch.Publish(exchange, key, false, false, *pub); err != nil {
+			return nil, err
+		}
+		select {
+		case d, ok := <-reply:
+			if !ok {
+				return nil, amqp.ErrClosed
+			}
+			return &d, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// amqpKey returns the routing key of the requests published on exchange to
+// queue: key, or the name of queue itself on the default exchange.
+func amqpKey(exchange, queue, key string) string {
+	if exchange == "" {
+		return queue
+	}
+	return key
+}
+
+// encodeAMQPRequest encodes the JSON of a request into the body of pub{{ if .Compression }},
+// compressed with AMQPCompression, which it names as its content encoding{{ end }}.
+func encodeAMQPRequest(_ context.Context, pub *amqp.Publishing, request interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}{{ if .Compression }}
+	if pub.Body, err = compress(AMQPCompression, body); err != nil {
+		return err
+	}
+	pub.ContentEncoding = AMQPCompression{{ else }}
+	pub.Body = body{{ end }}
+	return nil
+}
+{{ range .Funcs }}
+// {{ .Name }}AMQPClient returns an endpoint calling {{ .Name }} with a request
+// published on exchange to {{ .Name }}AMQPQueue, receiving the reply on replies{{ if .Budget }},
+// timing out after {{ .Name }}Budget{{ end }}.
+func {{ .Name }}AMQPClient(replies *AMQPReplies, exchange string, options ...amqptransport.PublisherOption) endpoint.Endpoint {
+	options = append([]amqptransport.PublisherOption{
+		amqptransport.PublisherBefore(amqptransport.SetContentType("application/json")),
+		amqptransport.PublisherDeliverer(replies.deliverer(exchange, amqpKey(exchange, {{ .Name }}AMQPQueue, {{ .Name }}AMQPKey))),{{ if .Budget }}
+		amqptransport.PublisherTimeout({{ .Name }}Budget),{{ end }}
+	}, options...)
+	return amqptransport.NewPublisher(
+		replies.ch,
+		&replies.queue,
+		encodeAMQPRequest,
+		DecodeAMQP{{ .Name }}Response,
+		options...,
+	).Endpoint()
+}
+
+// DecodeAMQP{{ .Name }}Response decodes the JSON {{ $svc.Response . }} of a reply from
+// {{ .Name }}AMQPQueue, or the error it answers with into an *AMQPError.
+func DecodeAMQP{{ .Name }}Response(_ context.Context, d *amqp.Delivery) (interface{}, error) {
+	if err := decodeAMQPError(d); err != nil {
+		return nil, err
+	}{{ if $svc.Compression }}
+	body, err := decompress(d.Body)
+	if err != nil {
+		return nil, err
+	}{{ end }}
+	var response {{ $svc.Response . }}
+	if err := json.Unmarshal({{ if $svc.Compression }}body{{ else }}d.Body{{ end }}, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+{{ end }}
+// AMQPError is the error an AMQP subscriber replied to a request with.
+type AMQPError struct {
+	Message string
+}
+
+func (e *AMQPError) Error() string { return e.Message }
+
+// decodeAMQPError returns the *AMQPError of a reply holding one, encoded by
+// amqptransport.ReplyErrorEncoder as {"err": "<message>"}, or nil.
+func decodeAMQPError(d *amqp.Delivery) error {
+	var reply map[string]json.RawMessage
+	if json.Unmarshal(d.Body, &reply) != nil || len(reply) != 1 {
+		return nil
+	}
+	var message string
+	if raw, ok := reply["err"]; !ok || json.Unmarshal(raw, &message) != nil {
+		return nil
+	}
+	return &AMQPError{Message: message}
+}
+{{ if .EndpointSet }}
+// NewAMQPClient returns the Endpoints calling the AMQP subscribers of
+// {{ .IFace }} with requests published on exchange, a client implementing
+// {{ .IFace }}.
+func NewAMQPClient(replies *AMQPReplies, exchange string, options ...amqptransport.PublisherOption) Endpoints {
+	return Endpoints{ {{ range .Funcs }}
+		{{ .Name }}Endpoint: {{ $svc.ClientEndpoint . (printf "%sAMQPClient(replies, exchange, options...)" .Name) }},{{ end }}
+	}
+}
+{{ end }}{{ end }}
+`
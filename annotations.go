@@ -0,0 +1,144 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// annotationPrefixes are the comment prefixes that mark a kitboiler annotation.
+var annotationPrefixes = []string{"kit:", "kitboiler:"}
+
+// knownAnnotations are the names of the annotations of interface methods.
+// Embedded interfaces take kit:skip and kit:include.
+var knownAnnotations = map[string]bool{
+	"skip":      true,
+	"etag":      true,
+	"ifmatch":   true,
+	"budget":    true,
+	"hedge":     true,
+	"event":     true,
+	"tx":        true,
+	"group":     true,
+	"slo":       true,
+	"optional":  true,
+	"request":   true,
+	"response":  true,
+	"sensitive": true,
+	"pii":       true,
+	"nil":       true,
+	"oneof":     true,
+	"scope":     true,
+	"unwrap":    true,
+	"http":      true,
+	"log":       true,
+	"fallback":  true,
+	"nats":      true,
+	"amqp":      true,
+}
+
+// Annotation is a directive in the doc comment of an interface method, such as
+// "//kit:etag Version".
+type Annotation struct {
+	Name string
+	Args []string
+	Pos  token.Pos // of the name, including its prefix
+}
+
+// Arg returns the value of a key=value argument of the annotation.
+func (a Annotation) Arg(key string) (string, bool) {
+	for _, arg := range a.Args {
+		if strings.HasPrefix(arg, key+"=") {
+			return arg[len(key)+1:], true
+		}
+	}
+	return "", false
+}
+
+// parseAnnotations extracts all annotations from a doc comment.
+func parseAnnotations(doc *ast.CommentGroup) []Annotation {
+	if doc == nil {
+		return nil
+	}
+	var annotations []Annotation
+	for _, c := range doc.List {
+		text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+		offset := strings.Index(c.Text, text)
+		for _, prefix := range annotationPrefixes {
+			if !strings.HasPrefix(text, prefix) {
+				continue
+			}
+			fields := strings.Fields(text[len(prefix):])
+			if len(fields) > 0 {
+				annotations = append(annotations, Annotation{Name: fields[0], Args: fields[1:], Pos: c.Slash + token.Pos(offset)})
+			}
+			break
+		}
+	}
+	return annotations
+}
+
+// parseDoc returns the text of a doc comment without its annotations.
+func parseDoc(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	var lines []string
+	for _, c := range doc.List {
+		text := strings.TrimPrefix(c.Text, "//")
+		if strings.HasPrefix(c.Text, "/*") {
+			text = strings.TrimSuffix(strings.TrimPrefix(c.Text, "/*"), "*/")
+		}
+		for _, line := range strings.Split(text, "\n") {
+			if isAnnotation(strings.TrimSpace(line)) {
+				continue
+			}
+			lines = append(lines, strings.TrimRight(strings.TrimPrefix(line, " "), " \t\r"))
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// isAnnotation reports whether the comment text is an annotation.
+func isAnnotation(text string) bool {
+	for _, prefix := range annotationPrefixes {
+		if strings.HasPrefix(text, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary returns the first sentence of the doc comment of f, or "" if it
+// has none.
+func (f Func) Summary() string {
+	doc := strings.Join(strings.Fields(f.Doc), " ")
+	if i := strings.Index(doc, ". "); i >= 0 {
+		return doc[:i+1]
+	}
+	return doc
+}
+
+// Comment returns text as a // comment, one line of the comment per line of
+// text.
+func Comment(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = "//"
+		} else {
+			lines[i] = "// " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Annotation returns the first annotation of f with the given name.
+func (f Func) Annotation(name string) (Annotation, bool) {
+	for _, a := range f.Annotations {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Annotation{}, false
+}
@@ -0,0 +1,61 @@
+package main
+
+// Implementation is a generated implementation of the interface, asserted
+// to implement it, see -assertions.
+type Implementation struct {
+	Value string // expression of a value of the type, e.g. (*MockService)(nil)
+	Doc   string // what the type is
+}
+
+// Implementations returns the implementations of the interface generated
+// for s.
+func (s Service) Implementations() []Implementation {
+	var impls []Implementation
+	if s.Outbound {
+		impls = append(impls, Implementation{"(*Dispatcher)(nil)", "webhook dispatcher"})
+	}
+	if s.EndpointSet {
+		impls = append(impls, Implementation{"Endpoints{}", "client calling the endpoints"})
+	}
+	if s.UsesTx() {
+		impls = append(impls, Implementation{"txMiddleware{}", "transaction middleware"})
+	}
+	if s.UsesEvents() {
+		impls = append(impls, Implementation{"outboxMiddleware{}", "outbox middleware"})
+	}
+	if s.Logging {
+		impls = append(impls, Implementation{"loggingMiddleware{}", "logging middleware"})
+	}
+	if s.Instrumenting {
+		impls = append(impls, Implementation{"instrumentingMiddleware{}", "instrumenting middleware"})
+	}
+	if s.Shadow {
+		impls = append(impls, Implementation{"shadowMiddleware{}", "shadow middleware"})
+	}
+	if s.Routing {
+		impls = append(impls, Implementation{"routingMiddleware{}", "routing middleware"})
+	}
+	if s.Mock {
+		impls = append(impls, Implementation{"(*MockService)(nil)", "mock"})
+	}
+	return impls
+}
+
+const assertionsTemplate = `
+{{ define "assertions" }}
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package {{ .Pkg }}
+
+import ({{ range $imp, $alias := .Imports }}{{ $alias }} "{{ $imp }}"
+{{ end }}
+)
+
+// The generated implementations of {{ .IFace }}, which fail to compile
+// here as soon as they drift from it.
+var ({{ range .Implementations }}
+	_ {{ $.IFace }} = {{ .Value }} // {{ .Doc }}{{ end }}
+)
+{{ end }}
+`
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// budgetImports are the imports required by the latency budget code.
+var budgetImports = []string{"strconv", "time", "github.com/go-kit/kit/metrics", "github.com/go-kit/kit/metrics/discard"}
+
+// resolveBudgets sets the latency budget of every method of fns from its
+// "//kit:budget <duration>" annotation, or to def if it has none.
+func resolveBudgets(fns []Func, def time.Duration) error {
+	for i := range fns {
+		fn := &fns[i]
+		fn.Budget = def
+		a, ok := fn.Annotation("budget")
+		if !ok {
+			continue
+		}
+		if len(a.Args) != 1 {
+			return fn.errorf(a, "kit:budget takes exactly one duration")
+		}
+		d, err := time.ParseDuration(a.Args[0])
+		if err != nil || d <= 0 {
+			return fn.errorf(a, "kit:budget: invalid duration %q", a.Args[0])
+		}
+		fn.Budget = d
+	}
+	return nil
+}
+
+// durationLiteral formats d as a Go constant expression, e.g. "250 * time.Millisecond".
+func durationLiteral(d time.Duration) string {
+	units := []struct {
+		unit time.Duration
+		name string
+	}{
+		{time.Hour, "time.Hour"},
+		{time.Minute, "time.Minute"},
+		{time.Second, "time.Second"},
+		{time.Millisecond, "time.Millisecond"},
+		{time.Microsecond, "time.Microsecond"},
+	}
+	for _, u := range units {
+		if d%u.unit == 0 {
+			return fmt.Sprintf("%d * %s", d/u.unit, u.name)
+		}
+	}
+	return fmt.Sprintf("%d * time.Nanosecond", d)
+}
+
+const budgetTemplate = `
+{{ define "budget" }}
+// Latency budgets of the methods.
+const ({{ range .Funcs }}{{ if .Budget }}
+	{{ .Name }}Budget = {{ DurationLiteral .Budget }}{{ end }}{{ end }}
+){{ if .HTTP }}
+
+// timeoutHeader propagates the time left until the deadline of a request in milliseconds.
+const timeoutHeader = "X-Request-Timeout"{{ end }}
+
+var (
+	// BudgetShed counts the requests rejected because their propagated deadline
+	// left less time than the budget of the method, labeled by "method".
+	BudgetShed metrics.Counter = discard.NewCounter()
+
+	// BudgetRemaining observes the seconds left until the propagated deadline
+	// when a request arrives, labeled by "method".
+	BudgetRemaining metrics.Histogram = discard.NewHistogram()
+)
+
+// ErrInsufficientBudget is returned when the propagated deadline of a request
+// leaves less time than the budget of the method.
+var ErrInsufficientBudget error = insufficientBudgetError{}
+
+type insufficientBudgetError struct{}
+
+func (insufficientBudgetError) Error() string { return "deadline leaves insufficient time to handle request" }
+
+// StatusCode makes the error encoder respond with 503 Service Unavailable.
+func (insufficientBudgetError) StatusCode() int { return http.StatusServiceUnavailable }
+{{ if .HTTP }}
+// DeadlineFromHTTPHeader stores the deadline propagated in the X-Request-Timeout
+// header in the context.
+func DeadlineFromHTTPHeader(ctx context.Context, r *http.Request) context.Context {
+	ms, err := strconv.ParseInt(r.Header.Get(timeoutHeader), 10, 64)
+	if err != nil || ms < 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, deadlineContextKey, time.Now().Add(time.Duration(ms)*time.Millisecond))
+}
+
+// DeadlineToHTTPHeader propagates the deadline of ctx in the X-Request-Timeout
+// header. Use it as an httptransport.ClientBefore option.
+func DeadlineToHTTPHeader(ctx context.Context, r *http.Request) context.Context {
+	if deadline, ok := ctx.Deadline(); ok {
+		r.Header.Set(timeoutHeader, strconv.FormatInt(int64(time.Until(deadline)/time.Millisecond), 10))
+	}
+	return ctx
+}
+{{ end }}
+// ShedOnBudget rejects requests whose propagated deadline leaves less than
+// budget{{ if .UsesSwitches }}, or the one set by Switches.SetBudgets,{{ end }} with ErrInsufficientBudget, and runs the other
+// requests under their propagated deadline.
+func ShedOnBudget(method string, budget time.Duration) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) { {{ if .UsesSwitches }}
+			budget := Switches.Budget(method, budget){{ end }}
+			deadline, ok := ctx.Value(deadlineContextKey).(time.Time)
+			if !ok {
+				return next(ctx, request)
+			}
+			remaining := time.Until(deadline)
+			BudgetRemaining.With("method", method).Observe(remaining.Seconds())
+			if remaining < budget {
+				BudgetShed.With("method", method).Add(1)
+				return nil, ErrInsufficientBudget
+			}
+			ctx, cancel := context.WithDeadline(ctx, deadline)
+			defer cancel()
+			return next(ctx, request)
+		}
+	}
+}
+
+// WithBudget bounds the calls of a client endpoint by budget, unless their
+// context has an earlier deadline already.
+func WithBudget(budget time.Duration) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			ctx, cancel := context.WithTimeout(ctx, budget)
+			defer cancel()
+			return next(ctx, request)
+		}
+	}
+}
+{{ end }}
+`
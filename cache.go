@@ -0,0 +1,335 @@
+package main
+
+// clientCacheImports are the imports required by the client cache code.
+var clientCacheImports = []string{"bytes", "context", "crypto/sha256", "encoding/hex", "fmt", "io/ioutil", "strconv", "strings", "sync", "time"}
+
+// CachedFuncs returns the read methods, answered with an ETag by kit:etag,
+// that aren't served on GET, whose requests the caching client transport
+// caches as well.
+func (s Service) CachedFuncs() []Func {
+	var fns []Func
+	for _, f := range s.Funcs {
+		if f.ETag != nil && f.HTTPMethod != "GET" {
+			fns = append(fns, f)
+		}
+	}
+	return fns
+}
+
+const clientCacheTemplate = `
+{{ define "clientcache" }}{{ $svc := . }}
+// CachedResponse is a response stored by the caching client transport.
+type CachedResponse struct {
+	StatusCode           int
+	Header               http.Header
+	Body                 []byte
+	Stored               time.Time
+	MaxAge               time.Duration
+	StaleWhileRevalidate time.Duration
+	// RequestHeader holds the headers named by the Vary header of the
+	// response, as sent with the request it answers.
+	RequestHeader http.Header
+}
+
+// CacheStore stores cached responses by request URL.
+type CacheStore interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, res *CachedResponse)
+}
+
+// NewMemoryCacheStore returns an unbounded CacheStore kept in memory.
+func NewMemoryCacheStore() CacheStore {
+	return &memoryCacheStore{responses: map[string]*CachedResponse{}}
+}
+
+type memoryCacheStore struct {
+	mu        sync.RWMutex
+	responses map[string]*CachedResponse
+}
+
+func (s *memoryCacheStore) Get(key string) (*CachedResponse, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res, ok := s.responses[key]
+	return res, ok
+}
+
+func (s *memoryCacheStore) Set(key string, res *CachedResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[key] = res
+}
+
+// NewCachingTransport returns an http.RoundTripper caching the responses to GET
+// requests{{ if .CachedFuncs }} and to the requests of cachedRoutes, keyed by their body as well,{{ end }}
+// in store according to their Cache-Control, Vary and ETag headers. The responses
+// to requests with credentials, in their Authorization or Cookie header, are
+// kept apart by a hash of the credentials, and private responses aren't
+// stored, so that store may be shared by the clients of several users. Fresh
+// responses are served from the cache, stale responses within their
+// stale-while-revalidate window are served from the cache while being
+// revalidated in the background and other responses are revalidated using
+// If-None-Match before being served.
+func NewCachingTransport(store CacheStore, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &cachingTransport{store: store, next: next, revalidating: map[string]bool{}}
+}
+
+type cachingTransport struct {
+	store        CacheStore
+	next         http.RoundTripper
+	mu           sync.Mutex
+	revalidating map[string]bool
+}
+
+func (t *cachingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	key := r.URL.String()
+	switch {
+	case r.Method == http.MethodGet:{{ if .CachedFuncs }}
+	case cachedRoute(r):
+		var body []byte
+		if r.Body != nil {
+			var err error
+			body, err = ioutil.ReadAll(r.Body)
+			r.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+		resend := *r
+		resend.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+		resend.Body, _ = resend.GetBody()
+		r = &resend
+		key = r.Method + " " + key + " " + string(body){{ end }}
+	default:
+		return t.next.RoundTrip(r)
+	}
+	key += credentialsKey(r)
+	cached, ok := t.store.Get(key)
+	if !ok || cached.varies(r) {
+		return t.fetch(r, key, nil)
+	}
+	age := time.Since(cached.Stored)
+	switch {
+	case age < cached.MaxAge:
+		return cached.response(r), nil
+	case age < cached.MaxAge+cached.StaleWhileRevalidate:
+		t.revalidate(r, key, cached)
+		return cached.response(r), nil
+	}
+	return t.fetch(r, key, cached)
+}
+
+// revalidate refreshes the cached response for key in the background, unless
+// that is already in progress.
+func (t *cachingTransport) revalidate(r *http.Request, key string, cached *CachedResponse) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.revalidating[key] {
+		return
+	}
+	t.revalidating[key] = true
+	go func() {
+		defer func() {
+			t.mu.Lock()
+			delete(t.revalidating, key)
+			t.mu.Unlock()
+		}()
+		if res, err := t.fetch(r.WithContext(context.Background()), key, cached); err == nil {
+			res.Body.Close()
+		}
+	}()
+}
+
+// fetch performs r, conditionally if a cached response with an ETag exists,
+// and stores the response if it may be cached.
+func (t *cachingTransport) fetch(r *http.Request, key string, cached *CachedResponse) (*http.Response, error) { {{ if .CachedFuncs }}
+	if r.GetBody != nil {
+		body, err := r.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		resend := *r
+		resend.Body = body
+		r = &resend
+	}{{ end }}
+	if cached != nil && cached.Header.Get("ETag") != "" {
+		conditional := *r
+		conditional.Header = make(http.Header, len(r.Header)+1)
+		for k, v := range r.Header {
+			conditional.Header[k] = v
+		}
+		conditional.Header.Set("If-None-Match", cached.Header.Get("ETag"))
+		r = &conditional
+	}
+	res, err := t.next.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusNotModified && cached != nil {
+		res.Body.Close()
+		refreshed := *cached
+		refreshed.Stored = time.Now()
+		if cc := res.Header.Get("Cache-Control"); cc != "" {
+			refreshed.MaxAge, refreshed.StaleWhileRevalidate, _ = parseCacheControl(cc)
+		}
+		t.store.Set(key, &refreshed)
+		return refreshed.response(r), nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return res, nil
+	}
+	maxAge, swr, ok := parseCacheControl(res.Header.Get("Cache-Control"))
+	if !ok || (maxAge == 0 && res.Header.Get("ETag") == "") {
+		return res, nil
+	}
+	varied := http.Header{}
+	for _, name := range varyHeaders(res.Header) {
+		if name == "*" {
+			return res, nil
+		}
+		varied[name] = r.Header[name]
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	t.store.Set(key, &CachedResponse{
+		StatusCode:           res.StatusCode,
+		Header:               res.Header,
+		Body:                 body,
+		Stored:               time.Now(),
+		MaxAge:               maxAge,
+		StaleWhileRevalidate: swr,
+		RequestHeader:        varied,
+	})
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return res, nil
+}
+
+{{ with .CachedFuncs }}
+// cachedRoutes are the routes of the read methods answered with an ETag that
+// aren't served on GET, by method and path.
+var cachedRoutes = []struct{ method, path string }{ {{ range . }}
+	{"{{ .HTTPMethod }}", "{{ $svc.Route . }}"},{{ end }}
+}
+
+// cachedRoute reports whether r is a request to one of cachedRoutes, after
+// the base path of the server, matching the variables of their paths, e.g.
+// {id}, with any segment.
+func cachedRoute(r *http.Request) bool {
+	segments := strings.Split(r.URL.EscapedPath(), "/")
+	for _, route := range cachedRoutes {
+		pattern := strings.Split(route.path, "/")[1:]
+		if route.method != r.Method || len(pattern) >= len(segments) {
+			continue
+		}
+		match := true
+		for i, s := range segments[len(segments)-len(pattern):] {
+			if p := pattern[i]; p != s && !strings.HasPrefix(p, "{") {
+				match = false
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+{{ end }}
+// credentialsKey returns the suffix of the cache key of r keeping apart the
+// responses to requests with different credentials: a hash of its
+// Authorization and Cookie headers, if it has any.
+func credentialsKey(r *http.Request) string {
+	auth, cookie := r.Header.Get("Authorization"), strings.Join(r.Header["Cookie"], "; ")
+	if auth == "" && cookie == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(auth + "\n" + cookie))
+	return " " + hex.EncodeToString(sum[:])
+}
+
+// varyHeaders returns the canonical names of the headers listed by the Vary
+// header of a response.
+func varyHeaders(header http.Header) []string {
+	var names []string
+	for _, vary := range header["Vary"] {
+		for _, name := range strings.Split(vary, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// varies reports whether r differs from the request c answers in a header
+// named by the Vary header of c.
+func (c *CachedResponse) varies(r *http.Request) bool {
+	for _, name := range varyHeaders(c.Header) {
+		if strings.Join(r.Header[name], ", ") != strings.Join(c.RequestHeader[name], ", ") {
+			return true
+		}
+	}
+	return false
+}
+
+// response returns a copy of the cached response as the response to r.
+func (c *CachedResponse) response(r *http.Request) *http.Response {
+	header := make(http.Header, len(c.Header))
+	for k, v := range c.Header {
+		header[k] = v
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", c.StatusCode, http.StatusText(c.StatusCode)),
+		StatusCode:    c.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(c.Body)),
+		ContentLength: int64(len(c.Body)),
+		Request:       r,
+	}
+}
+
+// parseCacheControl returns the max-age and stale-while-revalidate durations
+// of a Cache-Control header, and false if the response must not be stored,
+// as with no-store, or by a cache shared by several users, as with private.
+func parseCacheControl(header string) (maxAge, swr time.Duration, ok bool) {
+	noCache := false
+	for _, directive := range strings.Split(header, ",") {
+		name := strings.ToLower(strings.TrimSpace(directive))
+		value := ""
+		if eq := strings.Index(name, "="); eq >= 0 {
+			name, value = name[:eq], strings.Trim(name[eq+1:], "\"")
+		}
+		switch name {
+		case "no-store", "private":
+			return 0, 0, false
+		case "no-cache":
+			noCache = true
+		case "max-age":
+			if secs, err := strconv.Atoi(value); err == nil {
+				maxAge = time.Duration(secs) * time.Second
+			}
+		case "stale-while-revalidate":
+			if secs, err := strconv.Atoi(value); err == nil {
+				swr = time.Duration(secs) * time.Second
+			}
+		}
+	}
+	if noCache {
+		return 0, 0, true
+	}
+	return maxAge, swr, true
+}
+{{ end }}
+`
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// changelogName is the name of the changelog in the output directory.
+const changelogName = "CHANGELOG.md"
+
+// apiChanges are the changes of the methods of the generated API since the
+// previous manifest.
+type apiChanges struct {
+	Added   []ManifestMethod
+	Changed [][2]ManifestMethod // previous and current
+	Removed []ManifestMethod
+}
+
+// diffMethods returns the changes from the methods prev to cur.
+func diffMethods(prev, cur []ManifestMethod) apiChanges {
+	var c apiChanges
+	byName := map[string]ManifestMethod{}
+	for _, m := range prev {
+		byName[m.Name] = m
+	}
+	current := map[string]bool{}
+	for _, m := range cur {
+		current[m.Name] = true
+		p, ok := byName[m.Name]
+		switch {
+		case !ok:
+			c.Added = append(c.Added, m)
+		case p != m:
+			c.Changed = append(c.Changed, [2]ManifestMethod{p, m})
+		}
+	}
+	for _, m := range prev {
+		if !current[m.Name] {
+			c.Removed = append(c.Removed, m)
+		}
+	}
+	return c
+}
+
+func (c apiChanges) empty() bool {
+	return len(c.Added) == 0 && len(c.Changed) == 0 && len(c.Removed) == 0
+}
+
+// releaseVersion returns the version of the API generated for fns and its
+// changes since the manifest prev. Removing or changing a method breaks
+// clients and bumps the major version, adding one bumps the minor version;
+// before v1.0.0, they bump the minor and patch versions instead. The first
+// version is v0.1.0.
+func releaseVersion(prev Manifest, fns []Func) (string, apiChanges) {
+	c := diffMethods(prev.Methods, manifestMethods(fns))
+	major, minor, patch, ok := parseSemver(prev.Version)
+	if !ok {
+		c = diffMethods(nil, manifestMethods(fns))
+		return "v0.1.0", c
+	}
+	breaking := len(c.Changed) > 0 || len(c.Removed) > 0
+	switch {
+	case c.empty():
+	case breaking && major > 0:
+		major, minor, patch = major+1, 0, 0
+	case breaking || major > 0:
+		minor, patch = minor+1, 0
+	default:
+		patch++
+	}
+	return fmt.Sprintf("v%d.%d.%d", major, minor, patch), c
+}
+
+// parseSemver parses a vMAJOR.MINOR.PATCH version.
+func parseSemver(v string) (major, minor, patch int, ok bool) {
+	parts := strings.Split(strings.TrimPrefix(v, "v"), ".")
+	if !strings.HasPrefix(v, "v") || len(parts) != 3 {
+		return 0, 0, 0, false
+	}
+	var n [3]int
+	for i, p := range parts {
+		var err error
+		if n[i], err = strconv.Atoi(p); err != nil || n[i] < 0 {
+			return 0, 0, 0, false
+		}
+	}
+	return n[0], n[1], n[2], true
+}
+
+// changelogEntry returns the entry of the changelog describing the changes
+// c of version, released on date.
+func changelogEntry(version string, c apiChanges, date time.Time) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "## %s - %s\n", version, date.Format("2006-01-02"))
+	if len(c.Added) > 0 {
+		buf.WriteString("\n### Added\n\n")
+		for _, m := range c.Added {
+			fmt.Fprintf(&buf, "- `%s%s` (`%s`)\n", m.Name, m.Signature, m.Route)
+		}
+	}
+	if len(c.Changed) > 0 {
+		buf.WriteString("\n### Changed (breaking)\n\n")
+		for _, pc := range c.Changed {
+			p, m := pc[0], pc[1]
+			fmt.Fprintf(&buf, "- `%s`:", m.Name)
+			if p.Signature != m.Signature {
+				fmt.Fprintf(&buf, " `%s` is now `%s`", p.Signature, m.Signature)
+			}
+			if p.Route != m.Route {
+				if p.Signature != m.Signature {
+					buf.WriteString(",")
+				}
+				fmt.Fprintf(&buf, " served on `%s` instead of `%s`", m.Route, p.Route)
+			}
+			buf.WriteString("\n")
+		}
+	}
+	if len(c.Removed) > 0 {
+		buf.WriteString("\n### Removed (breaking)\n\n")
+		for _, m := range c.Removed {
+			fmt.Fprintf(&buf, "- `%s` (`%s`)\n", m.Name, m.Route)
+		}
+	}
+	return buf.Bytes()
+}
+
+// writeChangelog adds entry to the top of the changelog in dir.
+func writeChangelog(dir string, entry []byte) error {
+	path := filepath.Join(dir, changelogName)
+	const title = "# Changelog\n"
+	old, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		old, err = []byte(title), nil
+	}
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	rest := old
+	if bytes.HasPrefix(old, []byte(title)) {
+		buf.WriteString(title)
+		rest = old[len(title):]
+	}
+	buf.WriteString("\n")
+	buf.Write(entry)
+	if len(bytes.TrimSpace(rest)) > 0 {
+		buf.WriteString("\n")
+		buf.Write(bytes.TrimLeft(rest, "\n"))
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// updateChangelog adds an entry to the changelog in dir if the methods of
+// fns changed since the manifest prev, printing the version to release.
+func updateChangelog(dir string, prev Manifest, fns []Func) error {
+	version, c := releaseVersion(prev, fns)
+	if version == prev.Version {
+		return nil
+	}
+	if err := writeChangelog(dir, changelogEntry(version, c, time.Now())); err != nil {
+		return err
+	}
+	if prev.Version != "" {
+		fmt.Printf("suggested version %s (was %s), see %s\n", version, prev.Version, filepath.Join(dir, changelogName))
+	} else {
+		fmt.Printf("suggested version %s, see %s\n", version, filepath.Join(dir, changelogName))
+	}
+	return nil
+}
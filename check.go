@@ -0,0 +1,25 @@
+package main
+
+// checkInterface validates the methods of fns up front, before any code is
+// generated, reporting every problem at its position in the source of the
+// interface rather than generating code that doesn't compile: parameters
+// other than a context.Context must be named, as they name the fields of
+// the requests. Skipped methods aren't checked.
+func checkInterface(fns []Func) error {
+	var errs errorList
+	for i := range fns {
+		fn := &fns[i]
+		if fn.Skip {
+			continue
+		}
+		for j, p := range fn.Params {
+			if (p.Name == "" || p.Name == "_") && p.Type != "context.Context" {
+				errs = append(errs, errorAt(fn.src.FileSet, p.pos, "%s: parameter %d (%s) is unnamed: name it, as it names the field of the request holding it", fn.Name, j+1, p.Type))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
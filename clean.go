@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// generatedHeader is part of the header of every file generated by
+// KitBoiler that is overwritten on every run.
+var generatedHeader = []byte("by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.")
+
+// runClean removes the files in the -o directory that were generated for an
+// earlier version of the interface or options but aren't anymore, such as
+// the fixtures of a removed method. They are the files listed by the
+// manifest and the files with the generated header. Files that have been
+// edited since they were generated, such as fixtures or the implementation
+// of a scaffolded command, are left alone and reported.
+func runClean(args []string) error {
+	if *flagOutDir == "" {
+		return errors.New("clean requires -o")
+	}
+	svc, err := loadService(args)
+	if err != nil {
+		return err
+	}
+	files, err := genFiles(svc)
+	if err != nil {
+		return err
+	}
+	current := map[string]bool{manifestName: true}
+	for _, f := range files {
+		current[filepath.ToSlash(f.Name)] = true
+	}
+
+	stale, err := staleFiles(*flagOutDir, current)
+	if err != nil {
+		return err
+	}
+	var kept []string
+	for _, name := range stale.names() {
+		if stale[name] {
+			kept = append(kept, name)
+			continue
+		}
+		if err := os.Remove(filepath.Join(*flagOutDir, filepath.FromSlash(name))); err != nil {
+			return err
+		}
+		fmt.Println("removed", name)
+		removeEmptyDirs(*flagOutDir, filepath.Dir(filepath.FromSlash(name)))
+	}
+	for _, name := range kept {
+		fmt.Printf("kept %s, edited since it was generated\n", name)
+	}
+
+	if !*flagManifest {
+		return nil
+	}
+	files, err = withManifest(svc, files)
+	if err != nil {
+		return err
+	}
+	return writeFiles(*flagOutDir, files[len(files)-1:])
+}
+
+// removeStaleSplitFiles removes the files of the package pkg in dir that
+// aren't in files anymore: those of -split for the methods removed from the
+// interface, those of an earlier run with -split, or <pkg>.go when switching
+// to -split, as they would declare what the package declares again. Edited
+// ones are left alone and reported.
+func removeStaleSplitFiles(dir, pkg string, files []File) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	current := map[string]bool{manifestName: true}
+	for _, f := range files {
+		current[filepath.ToSlash(f.Name)] = true
+	}
+	stale, err := staleFiles(dir, current)
+	if err != nil {
+		return err
+	}
+	for _, name := range stale.names() {
+		split := name == "common.go" || strings.HasSuffix(name, "_endpoint.go") || strings.HasSuffix(name, "_transport.go")
+		if strings.Contains(name, "/") || !split && name != pkg+".go" {
+			continue
+		}
+		if stale[name] {
+			fmt.Printf("kept %s, edited since it was generated\n", name)
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+		fmt.Println("removed", name)
+	}
+	return nil
+}
+
+// staleSet maps the names of stale files to whether they have been edited.
+type staleSet map[string]bool
+
+func (s staleSet) names() []string {
+	var names []string
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// staleFiles returns the generated files in dir that aren't in current, by
+// slash separated name relative to dir. Subdirectories with a manifest of
+// their own hold the output of another interface and are skipped.
+func staleFiles(dir string, current map[string]bool) (staleSet, error) {
+	stale := staleSet{}
+	if m, err := readManifest(dir); err == nil {
+		for _, f := range m.Files {
+			if current[f.Name] {
+				continue
+			}
+			content, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(f.Name)))
+			if err != nil {
+				continue // removed already
+			}
+			stale[f.Name] = hash(content) != f.SHA256 && !isGenerated(content)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path == dir {
+				return nil
+			}
+			if name := info.Name(); strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, manifestName)); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if _, ok := stale[name]; ok || current[name] {
+			return nil
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if isGenerated(content) {
+			stale[name] = false
+		}
+		return nil
+	})
+	return stale, err
+}
+
+// isGenerated reports whether content starts with the generated header.
+func isGenerated(content []byte) bool {
+	if len(content) > 512 {
+		content = content[:512]
+	}
+	return bytes.Contains(content, generatedHeader)
+}
+
+// removeEmptyDirs removes the directory rel of dir if it is empty, and
+// then its parents up to dir.
+func removeEmptyDirs(dir, rel string) {
+	for ; rel != "." && rel != string(filepath.Separator); rel = filepath.Dir(rel) {
+		if err := os.Remove(filepath.Join(dir, rel)); err != nil {
+			return // not empty
+		}
+	}
+}
@@ -0,0 +1,508 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// version is the version of kitboiler, set with -ldflags "-X main.version=..."
+// or taken from the module version it was installed at.
+var version = "devel"
+
+// errUsage makes main print the usage.
+var errUsage = errors.New("usage")
+
+// command is a subcommand of kitboiler.
+type command struct {
+	Name    string
+	Args    string
+	Summary string
+	run     func(args []string) error
+}
+
+// commands are the subcommands of kitboiler; gen is the default.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"gen", "[flags] [<iface>]", "generate the package (the default command)", runGen},
+		{"vet", "[flags] [<iface>]", "check the interface and its annotations without writing anything", runVet},
+		{"diff", "[flags] [<iface>]", "show how the files in the -o directory differ from the generated ones", runDiff},
+		{"plan", "[flags] [<iface>]", "write the -plan file listing the files gen would write, with their symbols and contents", runPlan},
+		{"apply", "[-plan <file>]", "write the files of a plan, unless they changed since it was made", runApply},
+		{"clean", "[flags] [<iface>]", "remove the files in the -o directory that are no longer generated", runClean},
+		{"list", "[flags] [<iface>]", "list the methods of the interface with their routes and annotations", runList},
+		{"inspect", "[flags] [-o <file>] [<iface>]", "write the model of the interface analyzed by kitboiler as JSON, for other tools to build on", runInspect},
+		{"import", "[-o <dir>] <spec>", "write the Go interface described by an OpenAPI spec or .proto file, to generate its package from", runImport},
+		{"init", "[flags]", "answer a few questions to write kitboiler.yaml, then generate the package", runInitGen},
+		{"demo", "[flags] <dir>", "write a runnable example project, a todo service generated with most features, to an empty directory", runDemo},
+		{"version", "", "print the version of kitboiler", runVersion},
+		{"completion", "bash|zsh|fish", "print a shell completion script", runCompletion},
+	}
+}
+
+func lookupCommand(name string) *command {
+	for i := range commands {
+		if commands[i].Name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func main() {
+	flag.Usage = printUsage
+	args := os.Args[1:]
+	cmd := lookupCommand("gen")
+	if len(args) > 0 {
+		if c := lookupCommand(args[0]); c != nil {
+			cmd, args = c, args[1:]
+		}
+	}
+	_ = flag.CommandLine.Parse(args)
+	if err := cmd.run(flag.Args()); err != nil {
+		if err == errUsage {
+			flag.Usage()
+			os.Exit(2)
+		}
+		fatal(err)
+	}
+}
+
+func printUsage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprint(out, usage)
+	fmt.Fprintln(out, "\nCommands:")
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %s %s\t%s\n", c.Name, c.Args, c.Summary)
+	}
+	w.Flush()
+	fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
+}
+
+func fatal(err error) {
+	printError(os.Stderr, err, useColor(os.Stderr))
+	os.Exit(1)
+}
+
+// loadService analyzes the interface named by args, or else by the config
+// file, and returns the service to generate code for.
+func loadService(args []string) (Service, error) {
+	if *flagSrcDir == "" {
+		if dir, err := os.Getwd(); err == nil {
+			*flagSrcDir = dir
+		}
+	}
+	var iface string
+	if len(args) > 0 {
+		if *flagProfile != "" {
+			return Service{}, fmt.Errorf("-profile %s: profiles are only read from -config, which isn't used when the interface is given", *flagProfile)
+		}
+		iface = args[0]
+	} else if _, err := os.Stat(*flagConfig); err == nil {
+		cfg, err := loadConfig(*flagConfig)
+		if err != nil {
+			return Service{}, err
+		}
+		iface = cfg.Interface
+	} else {
+		return Service{}, errUsage
+	}
+	if *flagPreset != "" {
+		if err := applyPreset(*flagPreset); err != nil {
+			return Service{}, err
+		}
+	}
+	if *flagMinimal {
+		if err := checkMinimal(); err != nil {
+			return Service{}, err
+		}
+	}
+	if *flagScalars != "" {
+		if err := loadScalars(*flagScalars); err != nil {
+			return Service{}, err
+		}
+	}
+
+	fns, err := funcs(iface, *flagSrcDir)
+	if err != nil {
+		return Service{}, err
+	}
+	resolvers := []func([]Func) error{
+		checkInterface,
+		resolveUnnamed,
+		func(fns []Func) error { return resolveNames(fns, *flagRequestName, *flagResponseName, *flagJSONCase) },
+		resolveIdents,
+		resolveUserTypes,
+		func(fns []Func) error { return resolveRoutes(fns, *flagRouter) },
+		func(fns []Func) error { return resolveGetMethods(fns, *flagGetMethods) },
+		linkETags,
+		func(fns []Func) error { return resolveBudgets(fns, *flagBudget) },
+		resolveHedges,
+		func(fns []Func) error { return resolveNilResults(fns, *flagNilResult) },
+		resolveUnions,
+		resolveUnwrap,
+		resolveEvents,
+		resolveTx,
+		resolveLogging,
+		resolveFallbacks,
+		resolveGroups,
+		resolveNATS,
+		resolveAMQP,
+		resolveScopes,
+		resolveOptional,
+		resolveSensitive,
+		resolvePII,
+		func(fns []Func) error { return resolveSLOs(fns, *flagSLO) },
+	}
+	if *flagConstraints != "" {
+		resolvers = append(resolvers, func(fns []Func) error { return loadConstraints(*flagConstraints, fns) })
+	}
+	if *flagExamples != "" {
+		resolvers = append(resolvers, func(fns []Func) error { return loadExamples(*flagExamples, fns) })
+	}
+	for _, resolve := range resolvers {
+		if err := resolve(fns); err != nil {
+			return Service{}, err
+		}
+	}
+	if *flagMinimal {
+		minimize(fns)
+	}
+	if err := prefixRoutes(fns, *flagRoutePrefix); err != nil {
+		return Service{}, err
+	}
+	annotations, err := interfaceAnnotations(iface, *flagSrcDir)
+	if err != nil {
+		return Service{}, err
+	}
+	outbound := false
+	for _, a := range annotations {
+		outbound = outbound || a.Name == "outbound"
+	}
+	if outbound {
+		if err := checkOutbound(iface, fns); err != nil {
+			return Service{}, err
+		}
+	}
+
+	svc, err := newService(iface, *flagPkgName, fns)
+	if err != nil {
+		return Service{}, err
+	}
+	if svc.Outbound = outbound; outbound {
+		for _, i := range dispatcherImports {
+			svc.Imports[i] = ""
+		}
+	}
+	if *flagModule != "" {
+		if *flagOutDir == "" {
+			return Service{}, errors.New("-module requires -o")
+		}
+		pkg, err := importPackage(svc.IFacePath, *flagSrcDir)
+		if err != nil {
+			return Service{}, err
+		}
+		src, err := findGoMod(pkg.Dir)
+		if err != nil {
+			return Service{}, err
+		}
+		svc.Module = &Module{Path: *flagModule, Dir: *flagOutDir, Src: src}
+		svc.ImportPath = *flagModule
+	}
+	if *flagOutDir != "" {
+		if svc.Module == nil {
+			if svc.ImportPath, err = importPath(*flagOutDir); err != nil && (svc.StubServer || svc.DTO || svc.Scaffold || svc.UsesScopes() || svc.GRPC) {
+				return Service{}, err
+			}
+		}
+		if svc.DTO {
+			svc.Imports[svc.ImportPath+"/dto"] = ""
+		}
+		if svc.UsesScopes() {
+			svc.Imports[svc.ImportPath+"/scopes"] = ""
+		}
+	}
+	if err := resolveGone(&svc); err != nil {
+		return Service{}, err
+	}
+	if svc.GRPC && svc.ImportPath == "" {
+		return Service{}, errors.New("-transports: grpc requires -o, the gRPC server importing the pb package generated next to it")
+	}
+	return svc, nil
+}
+
+func runGen(args []string) error {
+	svc, err := loadService(args)
+	if err != nil {
+		return err
+	}
+	svc.Gone = advanceGone(svc.Gone)
+	files, err := genFiles(svc)
+	if err != nil {
+		return err
+	}
+	if *flagOutDir != "" {
+		prev, err := readManifest(*flagOutDir)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if *flagFollowRenames {
+			if err := followRenames(*flagOutDir, prev, files, detectRenames(prev, svc.Funcs)); err != nil {
+				return err
+			}
+		}
+		if err := removeStaleSplitFiles(*flagOutDir, svc.Pkg, files); err != nil {
+			return err
+		}
+		if *flagChangelog {
+			if err := updateChangelog(*flagOutDir, prev, svc.Funcs); err != nil {
+				return err
+			}
+		}
+		if files, err = withManifest(svc, files); err != nil {
+			return err
+		}
+		if err := writeFiles(*flagOutDir, files); err != nil {
+			return err
+		}
+		if *flagGoGenerate {
+			return writeGenerateFile(svc)
+		}
+		return nil
+	}
+	if *flagGoGenerate {
+		return errors.New("-go-generate requires -o")
+	}
+	if len(files) > 1 {
+		return errors.New("generating more than one file requires -o")
+	}
+	fmt.Print(string(files[0].Content))
+	return nil
+}
+
+func runInitGen(args []string) error {
+	if len(args) > 0 {
+		return errUsage
+	}
+	if *flagSrcDir == "" {
+		if dir, err := os.Getwd(); err == nil {
+			*flagSrcDir = dir
+		}
+	}
+	if err := runInit(os.Stdin, os.Stdout, *flagConfig); err != nil {
+		return err
+	}
+	return runGen(nil)
+}
+
+// runVet generates the files in memory, reporting invalid annotations,
+// unknown annotations and generated Go code that doesn't parse.
+func runVet(args []string) error {
+	svc, err := loadService(args)
+	if err != nil {
+		return err
+	}
+	var problems errorList
+	for _, f := range svc.AllFuncs {
+		for _, a := range f.Annotations {
+			if !knownAnnotations[a.Name] {
+				problems = append(problems, f.errorf(a, "unknown annotation kit:%s", a.Name))
+			}
+		}
+	}
+	files, err := genFiles(svc)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if strings.HasSuffix(f.Name, ".go") {
+			if _, err := format.Source(f.Content); err != nil {
+				problems = append(problems, fmt.Errorf("generated %s: %v", f.Name, err))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// runDiff prints the differences between the files in the -o directory and
+// the generated ones, failing if there are any. Files generated only once,
+// such as fixtures, are compared only if they don't exist.
+func runDiff(args []string) error {
+	if *flagOutDir == "" {
+		return errors.New("diff requires -o")
+	}
+	svc, err := loadService(args)
+	if err != nil {
+		return err
+	}
+	files, err := genFiles(svc)
+	if err == nil {
+		files, err = withManifest(svc, files)
+	}
+	if err != nil {
+		return err
+	}
+	changed := 0
+	for _, f := range files {
+		old, err := ioutil.ReadFile(filepath.Join(*flagOutDir, f.Name))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if f.Keep && err == nil {
+			continue
+		}
+		if d := unifiedDiff(filepath.ToSlash(f.Name), old, f.Content); d != "" {
+			fmt.Print(d)
+			changed++
+		}
+	}
+	if changed > 0 {
+		return fmt.Errorf("%d generated file(s) out of date", changed)
+	}
+	return nil
+}
+
+func runList(args []string) error {
+	svc, err := loadService(args)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "METHOD\tROUTE\tANNOTATIONS")
+	for _, f := range svc.AllFuncs {
+		route := f.HTTPMethod + " " + svc.Route(f)
+		if f.Skip {
+			route = "-"
+		}
+		var annotations []string
+		for _, a := range f.Annotations {
+			annotations = append(annotations, strings.TrimSpace("kit:"+a.Name+" "+strings.Join(a.Args, " ")))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", f.Name, route, strings.Join(annotations, ", "))
+	}
+	return w.Flush()
+}
+
+func runVersion(args []string) error {
+	fmt.Println("kitboiler", kitboilerVersion())
+	return nil
+}
+
+// kitboilerVersion returns the version of kitboiler, see version.
+func kitboilerVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && version == "devel" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return version
+}
+
+func runCompletion(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	var flags []*flag.Flag
+	flag.VisitAll(func(f *flag.Flag) { flags = append(flags, f) })
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion(flags))
+	case "zsh":
+		fmt.Print(zshCompletion(flags))
+	case "fish":
+		fmt.Print(fishCompletion(flags))
+	default:
+		return fmt.Errorf("completion: unsupported shell %q, want bash, zsh or fish", args[0])
+	}
+	return nil
+}
+
+// isBoolFlag reports whether f doesn't take a value.
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// flagSummary returns the usage of f, without the back quotes marking its
+// value name.
+func flagSummary(f *flag.Flag) string {
+	_, usage := flag.UnquoteUsage(f)
+	return usage
+}
+
+func bashCompletion(flags []*flag.Flag) string {
+	var names, cmds []string
+	for _, f := range flags {
+		names = append(names, "-"+f.Name)
+	}
+	for _, c := range commands {
+		cmds = append(cmds, c.Name)
+	}
+	return `# bash completion for kitboiler; load with: source <(kitboiler completion bash)
+_kitboiler() {
+	local cur=${COMP_WORDS[COMP_CWORD]}
+	if [[ $COMP_CWORD -eq 1 && $cur != -* ]]; then
+		COMPREPLY=($(compgen -W "` + strings.Join(cmds, " ") + `" -- "$cur"))
+	elif [[ $cur == -* ]]; then
+		COMPREPLY=($(compgen -W "` + strings.Join(names, " ") + `" -- "$cur"))
+	fi
+}
+complete -o default -F _kitboiler kitboiler
+`
+}
+
+func zshCompletion(flags []*flag.Flag) string {
+	quote := func(s string) string {
+		s = strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`, ":", `\:`).Replace(s)
+		return s
+	}
+	var b strings.Builder
+	b.WriteString("#compdef kitboiler\n# zsh completion for kitboiler; save as _kitboiler in a directory of $fpath\n\n_kitboiler() {\n\tlocal -a commands flags\n\tcommands=(\n")
+	for _, c := range commands {
+		fmt.Fprintf(&b, "\t\t'%s:%s'\n", c.Name, quote(c.Summary))
+	}
+	b.WriteString("\t)\n\tflags=(\n")
+	for _, f := range flags {
+		if isBoolFlag(f) {
+			fmt.Fprintf(&b, "\t\t'-%s[%s]'\n", f.Name, quote(flagSummary(f)))
+		} else {
+			name, _ := flag.UnquoteUsage(f)
+			fmt.Fprintf(&b, "\t\t'-%s[%s]:%s:'\n", f.Name, quote(flagSummary(f)), quote(name))
+		}
+	}
+	b.WriteString("\t)\n\tif (( CURRENT == 2 )) && [[ $words[CURRENT] != -* ]]; then\n\t\t_describe -t commands 'kitboiler command' commands\n\telse\n\t\t_arguments $flags '*:interface:'\n\tfi\n}\n\n_kitboiler \"$@\"\n")
+	return b.String()
+}
+
+func fishCompletion(flags []*flag.Flag) string {
+	quote := func(s string) string {
+		return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+	}
+	var b strings.Builder
+	b.WriteString("# fish completion for kitboiler; load with: kitboiler completion fish | source\n")
+	for _, c := range commands {
+		fmt.Fprintf(&b, "complete -c kitboiler -n __fish_use_subcommand -f -a %s -d %s\n", c.Name, quote(c.Summary))
+	}
+	for _, f := range flags {
+		req := " -r"
+		if isBoolFlag(f) {
+			req = ""
+		}
+		fmt.Fprintf(&b, "complete -c kitboiler -o %s%s -d %s\n", f.Name, req, quote(flagSummary(f)))
+	}
+	return b.String()
+}
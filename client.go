@@ -0,0 +1,282 @@
+package main
+
+// clientImports are the imports of the HTTP client on top of those of the
+// package.
+var clientImports = []string{"context", "encoding", "encoding/json", "errors", "fmt", "io", "io/ioutil", "net/http", "net/url", "reflect", "strconv", "strings", "time", "github.com/go-kit/kit/endpoint", "github.com/go-kit/kit/transport/http"}
+
+// ClientImports returns the imports of client.go.
+func (s Service) ClientImports() map[string]string {
+	imps := map[string]string{}
+	for imp, alias := range s.Imports {
+		imps[imp] = alias
+	}
+	for _, imp := range clientImports {
+		imps[imp] = ""
+	}
+	imps["github.com/go-kit/kit/transport/http"] = "httptransport"
+	return imps
+}
+
+// HTTPClient returns the name of the function returning the HTTP client
+// endpoint of f, <Method>HTTPClient, renamed like the identifiers of
+// resolveIdents if it collides with NewHTTPClient, e.g. NewHTTPClient_ for
+// a method New.
+func (f Func) HTTPClient() string {
+	return safeIdent(f.Name+"HTTPClient", func(name string) bool { return name == "NewHTTPClient" })
+}
+
+// PathVars returns the parameters of f decoded from the path of its route.
+func (f Func) PathVars() []Param {
+	var params []Param
+	for _, p := range f.Params {
+		if p.PathVar {
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+// UsesQueryParams reports whether any request is passed as query
+// parameters.
+func (s Service) UsesQueryParams() bool {
+	for _, f := range s.Funcs {
+		if len(f.QueryParams()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+const clientTemplate = `
+{{ define "client" }}
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package {{ .Pkg }}
+{{ $svc := . }}
+import ({{ range $imp, $alias := .ClientImports }}{{ $alias }} "{{ $imp }}"
+{{ end }}
+)
+
+// NewHTTPClient returns the Endpoints calling the HTTP server of MakeHTTPHandler
+// at instance, e.g. http://localhost:8080, a client implementing {{ .IFace }}.{{ range .HandlerGroups }}{{ if .Name }}
+// The methods of the {{ .Name }} group call the server of {{ .Handler }} at {{ .Name }}Instance.{{ end }}{{ end }}
+// The options are passed to the client of every method, e.g. httptransport.SetClient.{{ if .UsesBudgets }}
+// The calls of the methods with a latency budget time out after it.{{ end }}
+func NewHTTPClient(instance{{ range .HandlerGroups }}{{ if .Name }}, {{ .Name }}Instance{{ end }}{{ end }} string, options ...httptransport.ClientOption) (Endpoints, error) { {{ range .HandlerGroups }}
+	{{ if .Name }}{{ .Name }}URL, err := parseInstance({{ .Name }}Instance){{ else }}u, err := parseInstance(instance){{ end }}
+	if err != nil {
+		return Endpoints{}, err
+	}{{ end }}
+	return Endpoints{ {{ range .Funcs }}{{ $u := "u" }}{{ if .Group }}{{ $u = printf "%sURL" .Group }}{{ end }}{{ $e := printf "%s(%s, options...)" .HTTPClient $u }}{{ if .Budget }}{{ $e = printf "WithBudget(%sBudget)(%s)" .Name $e }}{{ end }}
+		{{ .Name }}Endpoint: {{ $svc.ClientEndpoint . $e }},{{ end }}
+	}, nil
+}
+
+// parseInstance parses the URL of a server, defaulting to http.
+func parseInstance(instance string) (*url.URL, error) {
+	if !strings.Contains(instance, "://") {
+		instance = "http://" + instance
+	}
+	u, err := url.Parse(instance)
+	if err != nil {
+		return nil, fmt.Errorf("invalid instance %q: %v", instance, err)
+	}
+	return u, nil
+}
+{{ range .Funcs }}
+// {{ .HTTPClient }} returns an endpoint calling {{ .Name }} on the HTTP server at
+// instance, with {{ .HTTPMethod }} {{ $svc.Route . }}.{{ if ne .HTTPClient (print .Name "HTTPClient") }}
+// Renamed from {{ .Name }}HTTPClient, the constructor of the client of all the methods.{{ end }}
+func {{ .HTTPClient }}(instance *url.URL, options ...httptransport.ClientOption) endpoint.Endpoint {
+	return httptransport.NewClient(
+		"{{ .HTTPMethod }}",
+		instance,
+		EncodeHTTP{{ .Name }}Request,
+		DecodeHTTP{{ .Name }}Response,
+		options...,
+	).Endpoint()
+}
+
+// EncodeHTTP{{ .Name }}Request encodes a {{ $svc.Request . }} into a request to
+// {{ .HTTPMethod }} {{ $svc.Route . }}, as decoded by Decode{{ .Name }}Request.
+func EncodeHTTP{{ .Name }}Request(ctx context.Context, r *http.Request, request interface{}) error { {{ if or .PathVars .QueryParams }}
+	req := request.({{ $svc.Request . }}){{ end }}{{ if eq $svc.Tenant "path" }}
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return ErrNoTenant
+	}{{ end }}
+	if err := setRoutePath(r.URL, "{{ $svc.Route . }}", map[string]interface{}{ {{ if eq $svc.Tenant "path" }}
+		"tenant": tenant,{{ end }}{{ range .PathVars }}
+		"{{ .Name }}": req.{{ .Field }},{{ end }}
+	}); err != nil {
+		return err
+	}{{ with .QueryParams }}
+	q := r.URL.Query(){{ range . }}
+	if err := encodeQueryParam(q, "{{ .JSONField }}", req.{{ .Field }}); err != nil {
+		return err
+	}{{ end }}
+	r.URL.RawQuery = q.Encode(){{ end }}{{ if .Budget }}
+	DeadlineToHTTPHeader(ctx, r){{ end }}{{ if $svc.Tracing }}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header)){{ end }}{{ if .HasBody }}
+	return httptransport.EncodeJSONRequest(ctx, r, request){{ else }}
+	return nil{{ end }}
+}
+
+// DecodeHTTP{{ .Name }}Response decodes a response to {{ .HTTPMethod }} {{ $svc.Route . }} into a
+// {{ $svc.Response . }}, as encoded by {{ if or .ETag .NilResult .RawBytes }}Encode{{ .Name }}Response{{ else }}EncodeResponse{{ end }}{{ if and $svc.Envelope (not .RawBytes) }} in an Envelope{{ end }}, or an
+// error status into an *HTTPError.
+func DecodeHTTP{{ .Name }}Response(_ context.Context, r *http.Response) (interface{}, error) {
+	var response {{ $svc.Response . }}{{ if eq .NilResult "not-found" }}
+	if r.StatusCode == http.StatusNotFound {
+		return response, nil
+	}{{ end }}
+	if r.StatusCode >= 400 {
+		return nil, decodeHTTPError(r)
+	}{{ if eq .NilResult "no-content" }}
+	if r.StatusCode == http.StatusNoContent {
+		return response, nil
+	}{{ end }}{{ if .RawBytes }}
+	return ioutil.ReadAll(r.Body){{ else if $svc.Envelope }}
+	envelope := Envelope{Data: &response}
+	if err := json.NewDecoder(r.Body).Decode(&envelope); err != nil {
+		return nil, err
+	}
+	if err := envelope.Failed(); err != nil {
+		return nil, &HTTPError{Code: r.StatusCode, Message: err.Error()}
+	}
+	return response, nil{{ else }}
+	if err := json.NewDecoder(r.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	return response, nil{{ end }}
+}
+{{ end }}
+// HTTPError is the error of a call answered with an error status, e.g. 404
+// Not Found, with the body of the response as its message.
+type HTTPError struct {
+	Code    int
+	Message string
+}
+
+func (e *HTTPError) Error() string {
+	if e.Message == "" {
+		return http.StatusText(e.Code)
+	}
+	return e.Message
+}
+
+// StatusCode makes the error encoder of a server calling the client respond
+// with the same status.
+func (e *HTTPError) StatusCode() int { return e.Code }
+
+{{ if eq .Tenant "path" }}
+// ErrNoTenant is returned by the HTTP clients called with a context without
+// a tenant, see ContextWithTenant: the tenant leads the paths of the routes.
+var ErrNoTenant = errors.New("no tenant in the context")
+{{ end }}
+// decodeHTTPError returns the *HTTPError of a response with an error status{{ if .Envelope }},
+// whose message is the error of its Envelope, or else its body{{ end }}.
+func decodeHTTPError(r *http.Response) error {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 64<<10))
+	if err != nil {
+		return err
+	}{{ if .Envelope }}
+	var envelope Envelope
+	if json.Unmarshal(body, &envelope) == nil && envelope.Error != "" {
+		return &HTTPError{Code: r.StatusCode, Message: envelope.Error}
+	}{{ end }}
+	return &HTTPError{Code: r.StatusCode, Message: strings.TrimSpace(string(body))}
+}
+
+// setRoutePath appends route to the path of u, with the values of vars as
+// its variables, e.g. {id}.
+func setRoutePath(u *url.URL, route string, vars map[string]interface{}) error {
+	for name, value := range vars {
+		s, err := encodeRouteValue(reflect.ValueOf(value))
+		if err != nil {
+			return fmt.Errorf("invalid %s: %v", name, err)
+		}
+		route = strings.Replace(route, "{"+name+"}", url.PathEscape(s), 1)
+	}
+	rawPath := strings.TrimSuffix(u.EscapedPath(), "/") + route
+	path, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return err
+	}
+	u.Path, u.RawPath = path, rawPath
+	return nil
+}
+{{ if .UsesQueryParams }}
+// encodeQueryParam sets the query parameter name of q to value, as decoded
+// by decodeQueryParam: every element of a slice as a value of its own, e.g.
+// ?id=1&id=2. Nil values are left out.
+func encodeQueryParam(q url.Values, name string, value interface{}) error {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+	}
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		s, err := encodeRouteValue(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %v", name, err)
+		}
+		q.Set(name, s)
+		return nil
+	}
+	for i := 0; i < v.Len(); i++ {
+		s, err := encodeRouteValue(v.Index(i))
+		if err != nil {
+			return fmt.Errorf("invalid %s: %v", name, err)
+		}
+		q.Add(name, s)
+	}
+	return nil
+}
+{{ end }}
+// encodeRouteValue encodes v as decoded by decodeRouteValue: by its
+// MarshalText method if it has one, formatted if it is a string, bool,
+// number or time.Duration, and as JSON otherwise, unquoted if a JSON string.
+func encodeRouteValue(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+		return encodeRouteValue(v.Elem())
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		return string(text), err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == reflect.TypeOf(time.Duration(0)) {
+			return time.Duration(v.Int()).String(), nil
+		}
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return "", err
+	}
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		return s, nil
+	}
+	return string(data), nil
+}
+{{ end }}
+`
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the content of a kitboiler.yaml file: the interface to
+// generate code for and the options to generate it with, by flag name.
+// Profiles are options by profile name, e.g. dev or prod, applied on top of
+// Options when selected by -profile.
+type Config struct {
+	Interface string                   `yaml:"interface"`
+	Options   yaml.MapSlice            `yaml:"options,omitempty"`
+	Profiles  map[string]yaml.MapSlice `yaml:"profiles,omitempty"`
+}
+
+// loadConfig reads the config file path and sets the flags of the options
+// of the profile selected by -profile, then of its options, that aren't set
+// on the command line.
+func loadConfig(path string) (Config, error) {
+	var cfg Config
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("%s: %v", path, err)
+	}
+	if cfg.Interface == "" {
+		return cfg, fmt.Errorf("%s: no interface", path)
+	}
+	if *flagProfile != "" {
+		profile, ok := cfg.Profiles[*flagProfile]
+		if !ok {
+			var names []string
+			for name := range cfg.Profiles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return cfg, fmt.Errorf("%s: -profile: unknown profile %q, want one of %v", path, *flagProfile, names)
+		}
+		if err := setUnset(options(profile)); err != nil {
+			return cfg, fmt.Errorf("%s: profile %s: %v", path, *flagProfile, err)
+		}
+	}
+	if err := setUnset(options(cfg.Options)); err != nil {
+		return cfg, fmt.Errorf("%s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// options returns the values of the options of a config file by flag name.
+func options(opts yaml.MapSlice) map[string]string {
+	values := map[string]string{}
+	for _, o := range opts {
+		values[fmt.Sprint(o.Key)] = fmt.Sprint(o.Value)
+	}
+	return values
+}
+
+// setUnset sets the flags named by the keys of values that aren't set yet.
+func setUnset(values map[string]string) error {
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for name, v := range values {
+		if set[name] {
+			continue
+		}
+		if flag.Lookup(name) == nil {
+			return fmt.Errorf("unknown option %s", name)
+		}
+		if err := flag.Set(name, v); err != nil {
+			return fmt.Errorf("-%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// wizardMiddleware are the options offered by the init wizard as
+// middleware.
+var wizardMiddleware = []string{"recover", "access-log", "metrics", "health", "options-head", "hedge", "client-cache", "ratelimit"}
+
+// runInit asks for the interface, the transports, the middleware and the
+// output layout on in and out, and writes the answers to the config file
+// path.
+func runInit(in io.Reader, out io.Writer, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+	r := bufio.NewReader(in)
+	ask := func(question, def string) (string, error) {
+		if def != "" {
+			fmt.Fprintf(out, "%s [%s]: ", question, def)
+		} else {
+			fmt.Fprintf(out, "%s: ", question)
+		}
+		line, err := r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+		if line = strings.TrimSpace(line); line == "" {
+			return def, nil
+		}
+		return line, nil
+	}
+	yes := func(question string, def bool) (bool, error) {
+		d := "y/N"
+		if def {
+			d = "Y/n"
+		}
+		a, err := ask(question, d)
+		if err != nil || a == d {
+			return def, err
+		}
+		return strings.HasPrefix(strings.ToLower(a), "y"), nil
+	}
+
+	var cfg Config
+	opt := func(name string, value interface{}) {
+		cfg.Options = append(cfg.Options, yaml.MapItem{Key: name, Value: value})
+	}
+	for {
+		iface, err := ask("Interface (e.g. github.com/me/mypkg/api.MyService)", "")
+		if err != nil {
+			return err
+		}
+		if iface == "" {
+			continue
+		}
+		if _, err := funcs(iface, *flagSrcDir); err != nil {
+			fmt.Fprintln(out, err)
+			continue
+		}
+		cfg.Interface = iface
+		break
+	}
+
+	var transports map[string]bool
+	for {
+		list, err := ask("Transports (comma separated: http, grpc, nats, amqp)", "http")
+		if err != nil {
+			return err
+		}
+		if transports, err = parseTransports(list); err != nil {
+			fmt.Fprintln(out, err)
+			continue
+		}
+		if list != "http" {
+			opt("transports", list)
+		}
+		break
+	}
+	for transports["http"] {
+		router, err := ask("Router of the HTTP routes (chi, mux or none for a net/http ServeMux)", "none")
+		if err != nil {
+			return err
+		}
+		if router == "none" {
+			break
+		}
+		if err := checkRouter(router); err != nil {
+			fmt.Fprintln(out, err)
+			continue
+		}
+		opt("router", router)
+		break
+	}
+
+	for _, spec := range []struct{ name, question string }{
+		{"openapi", "Generate an OpenAPI spec"},
+		{"proto", "Generate a proto file"},
+	} {
+		if httpFlags[spec.name] && !transports["http"] {
+			continue
+		}
+		ok, err := yes(spec.question, false)
+		if err != nil {
+			return err
+		}
+		if ok {
+			opt(spec.name, true)
+		}
+	}
+
+	middleware, def := wizardMiddleware, "recover,access-log,metrics"
+	if !transports["http"] {
+		middleware, def = nil, "none"
+		for _, m := range wizardMiddleware {
+			if !httpFlags[m] {
+				middleware = append(middleware, m)
+			}
+		}
+	}
+	mw, err := ask("Middleware (comma separated: "+strings.Join(middleware, ", ")+")", def)
+	if err != nil {
+		return err
+	}
+	for _, m := range strings.Split(mw, ",") {
+		m = strings.TrimSpace(m)
+		known := false
+		for _, w := range middleware {
+			known = known || m == w
+		}
+		switch {
+		case m == "" || m == "none":
+		case !known:
+			fmt.Fprintf(out, "ignoring unknown middleware %q\n", m)
+		case m == "ratelimit":
+			key, err := ask("Rate limit clients by ip, apikey or jwt", "ip")
+			if err != nil {
+				return err
+			}
+			opt(m, key)
+		default:
+			opt(m, true)
+		}
+	}
+
+	dir, err := ask("Output directory", "endpoints")
+	if err != nil {
+		return err
+	}
+	opt("o", dir)
+	pkg, err := ask("Package name", filepath.Base(dir))
+	if err != nil {
+		return err
+	}
+	opt("pkg", pkg)
+	for _, layout := range []struct{ name, question string }{
+		{"dto", "Generate the request and response types into a separate dto package"},
+		{"scaffold", "Generate a command serving the service"},
+	} {
+		ok, err := yes(layout.question, false)
+		if err != nil {
+			return err
+		}
+		if ok {
+			opt(layout.name, true)
+		}
+	}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "wrote %s, run kitboiler in this directory to regenerate\n", path)
+	return nil
+}
@@ -0,0 +1,463 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Constraints restrict the values of a request field, as read from the
+// schema of the field in an OpenAPI spec.
+type Constraints struct {
+	Minimum, Maximum                   *float64
+	ExclusiveMinimum, ExclusiveMaximum bool
+	MinLength, MaxLength               *int
+	MinItems, MaxItems                 *int
+	Pattern                            string
+	Enum                               []interface{}
+}
+
+// loadConstraints sets the constraints of the parameters of fns from the
+// OpenAPI (or Swagger 2) spec at path. The request fields of a method are
+// looked up in the parameters and JSON request body of the operation whose
+// operationId matches the method name or, failing that, in the schema named
+// <Method>Request. Fields are matched by name, ignoring case.
+func loadConstraints(path string, fns []Func) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var spec interface{}
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	for i := range fns {
+		props := requestProperties(spec, fns[i].Name)
+		for j := range fns[i].Params {
+			p := &fns[i].Params[j]
+			schema := matchProperty(props, p.Name)
+			if schema == nil && p.JSONName != "" {
+				schema = matchProperty(props, p.JSONName)
+			}
+			if schema == nil {
+				continue
+			}
+			p.Constraints = constraintsOf(schema)
+			if p.Constraints != nil && !validatable(p.Type) {
+				_, _ = fmt.Fprintf(os.Stderr, "%s: ignoring constraints on %s %s: unsupported type\n", fns[i].Name, p.Name, p.Type)
+				p.Constraints = nil
+			}
+		}
+	}
+	return nil
+}
+
+// requestProperties returns the schemas of the request fields of method by name.
+func requestProperties(spec interface{}, method string) map[string]interface{} {
+	props := map[string]interface{}{}
+	if op := findOperation(spec, method); op != nil {
+		for _, param := range asSlice(get(op, "parameters")) {
+			param = resolve(spec, param)
+			if get(param, "in") == "body" {
+				addProperties(spec, props, get(param, "schema"))
+				continue
+			}
+			name, _ := get(param, "name").(string)
+			schema := get(param, "schema")
+			if schema == nil {
+				// Swagger 2 declares constraints on the parameter itself
+				schema = param
+			}
+			props[name] = resolve(spec, schema)
+		}
+		addProperties(spec, props, get(op, "requestBody", "content", "application/json", "schema"))
+	}
+	if len(props) == 0 {
+		addProperties(spec, props, get(spec, "components", "schemas", method+"Request"))
+		addProperties(spec, props, get(spec, "definitions", method+"Request"))
+	}
+	return props
+}
+
+// findOperation returns the operation with the operationId method.
+func findOperation(spec interface{}, method string) interface{} {
+	paths, _ := get(spec, "paths").(map[interface{}]interface{})
+	for _, item := range paths {
+		for _, verb := range []string{"get", "put", "post", "delete", "patch"} {
+			op := get(item, verb)
+			if id, ok := get(op, "operationId").(string); ok && strings.EqualFold(id, method) {
+				return op
+			}
+		}
+	}
+	return nil
+}
+
+// addProperties adds the properties of schema, including those of its allOf
+// schemas, to props.
+func addProperties(spec interface{}, props map[string]interface{}, schema interface{}) {
+	schema = resolve(spec, schema)
+	if properties, ok := get(schema, "properties").(map[interface{}]interface{}); ok {
+		for name, prop := range properties {
+			props[fmt.Sprint(name)] = resolve(spec, prop)
+		}
+	}
+	for _, sub := range asSlice(get(schema, "allOf")) {
+		addProperties(spec, props, sub)
+	}
+}
+
+// matchProperty returns the schema of the property name, ignoring case.
+func matchProperty(props map[string]interface{}, name string) interface{} {
+	if schema, ok := props[name]; ok {
+		return schema
+	}
+	for prop, schema := range props {
+		if strings.EqualFold(prop, name) {
+			return schema
+		}
+	}
+	return nil
+}
+
+// constraintsOf returns the constraints declared by schema, or nil if there
+// are none.
+func constraintsOf(schema interface{}) *Constraints {
+	var c Constraints
+	found := false
+	number := func(key string) *float64 {
+		if f, ok := toFloat(get(schema, key)); ok {
+			found = true
+			return &f
+		}
+		return nil
+	}
+	integer := func(key string) *int {
+		if f, ok := toFloat(get(schema, key)); ok {
+			found = true
+			i := int(f)
+			return &i
+		}
+		return nil
+	}
+	c.Minimum = number("minimum")
+	c.Maximum = number("maximum")
+	// OpenAPI 3.0 flags the bounds as exclusive, OpenAPI 3.1 replaces them
+	switch v := get(schema, "exclusiveMinimum").(type) {
+	case bool:
+		c.ExclusiveMinimum = v
+	default:
+		if min := number("exclusiveMinimum"); min != nil {
+			c.Minimum, c.ExclusiveMinimum = min, true
+		}
+	}
+	switch v := get(schema, "exclusiveMaximum").(type) {
+	case bool:
+		c.ExclusiveMaximum = v
+	default:
+		if max := number("exclusiveMaximum"); max != nil {
+			c.Maximum, c.ExclusiveMaximum = max, true
+		}
+	}
+	c.MinLength = integer("minLength")
+	c.MaxLength = integer("maxLength")
+	c.MinItems = integer("minItems")
+	c.MaxItems = integer("maxItems")
+	if pattern, ok := get(schema, "pattern").(string); ok {
+		c.Pattern, found = pattern, true
+	}
+	if enum := asSlice(get(schema, "enum")); len(enum) > 0 {
+		c.Enum, found = enum, true
+	}
+	if !found {
+		return nil
+	}
+	return &c
+}
+
+// get returns the value at the path keys in the YAML document v, or nil.
+func get(v interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		switch m := v.(type) {
+		case map[interface{}]interface{}:
+			v = m[key]
+		case map[string]interface{}:
+			v = m[key]
+		case yaml.MapSlice:
+			v = nil
+			for _, item := range m {
+				if fmt.Sprint(item.Key) == key {
+					v = item.Value
+					break
+				}
+			}
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+// entries returns the keys and values of the YAML mapping v, in order if v
+// was decoded as a yaml.MapSlice and else sorted by key.
+func entries(v interface{}) yaml.MapSlice {
+	switch m := v.(type) {
+	case yaml.MapSlice:
+		return m
+	case map[interface{}]interface{}:
+		var items yaml.MapSlice
+		for k, v := range m {
+			items = append(items, yaml.MapItem{Key: fmt.Sprint(k), Value: v})
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].Key.(string) < items[j].Key.(string) })
+		return items
+	}
+	return nil
+}
+
+// resolve follows the local $ref of v, if any.
+func resolve(spec, v interface{}) interface{} {
+	for i := 0; i < 32; i++ {
+		ref, ok := get(v, "$ref").(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return v
+		}
+		var keys []string
+		for _, key := range strings.Split(ref[2:], "/") {
+			keys = append(keys, strings.Replace(strings.Replace(key, "~1", "/", -1), "~0", "~", -1))
+		}
+		v = get(spec, keys...)
+	}
+	return v
+}
+
+func asSlice(v interface{}) []interface{} {
+	s, _ := v.([]interface{})
+	return s
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// numericTypes are the Go types checked against minimum, maximum and numeric enums.
+var numericTypes = map[string]bool{
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true,
+}
+
+// validatable reports whether constraints can be checked on a field of type typ.
+func validatable(typ string) bool {
+	typ = strings.TrimPrefix(typ, "*")
+	return numericTypes[typ] || typ == "string" || strings.HasPrefix(typ, "[]")
+}
+
+// HasConstraints reports whether any request field of f has constraints or
+// is an enum.
+func HasConstraints(f Func) bool {
+	for _, p := range f.Params {
+		if p.Constraints != nil || p.Enum != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// patternVar returns the name of the variable holding the compiled pattern of
+// the request field p of f.
+func patternVar(f Func, p Param) string {
+	return strings.ToLower(f.Name[:1]) + f.Name[1:] + p.Field + "Pattern"
+}
+
+// ValidationPatterns returns the declarations of the compiled patterns used
+// by the validation of the request of f.
+func ValidationPatterns(f Func) []string {
+	var decls []string
+	for _, p := range f.Params {
+		if p.Constraints != nil && p.Constraints.Pattern != "" {
+			decls = append(decls, fmt.Sprintf("%s = regexp.MustCompile(%s)", patternVar(f, p), strconv.Quote(p.Constraints.Pattern)))
+		}
+	}
+	return decls
+}
+
+// Validation returns the statements checking the request field p of f
+// against its constraints and enum constants, returning a ValidationError on
+// violations.
+func Validation(f Func, p Param) string {
+	c := p.Constraints
+	if c == nil {
+		c = &Constraints{}
+	}
+	field := p.Field
+	typ, v := p.Type, "r."+field
+	nillable := strings.HasPrefix(typ, "*")
+	if nillable {
+		typ, v = typ[1:], "*"+v
+	}
+
+	var checks []string
+	check := func(cond, reason string) {
+		checks = append(checks, fmt.Sprintf("if %s {\nreturn ValidationError{Field: %q, Reason: %q}\n}", cond, p.JSONField(), reason))
+	}
+	num := func(f float64) string { return strconv.FormatFloat(f, 'g', -1, 64) }
+	switch {
+	case numericTypes[typ]:
+		if c.Minimum != nil {
+			if c.ExclusiveMinimum {
+				check(fmt.Sprintf("float64(%s) <= %s", v, num(*c.Minimum)), "must be greater than "+num(*c.Minimum))
+			} else {
+				check(fmt.Sprintf("float64(%s) < %s", v, num(*c.Minimum)), "must be at least "+num(*c.Minimum))
+			}
+		}
+		if c.Maximum != nil {
+			if c.ExclusiveMaximum {
+				check(fmt.Sprintf("float64(%s) >= %s", v, num(*c.Maximum)), "must be less than "+num(*c.Maximum))
+			} else {
+				check(fmt.Sprintf("float64(%s) > %s", v, num(*c.Maximum)), "must be at most "+num(*c.Maximum))
+			}
+		}
+		if len(c.Enum) > 0 {
+			var values []string
+			for _, e := range c.Enum {
+				if f, ok := toFloat(e); ok {
+					values = append(values, num(f))
+				}
+			}
+			check(enumCond("float64("+v+")", values), "must be one of "+strings.Join(values, ", "))
+		}
+	case typ == "string":
+		if c.MinLength != nil {
+			check(fmt.Sprintf("utf8.RuneCountInString(%s) < %d", v, *c.MinLength), fmt.Sprintf("must be at least %d characters long", *c.MinLength))
+		}
+		if c.MaxLength != nil {
+			check(fmt.Sprintf("utf8.RuneCountInString(%s) > %d", v, *c.MaxLength), fmt.Sprintf("must be at most %d characters long", *c.MaxLength))
+		}
+		if c.Pattern != "" {
+			check(fmt.Sprintf("!%s.MatchString(%s)", patternVar(f, p), v), "must match "+c.Pattern)
+		}
+		if len(c.Enum) > 0 {
+			var values []string
+			for _, e := range c.Enum {
+				values = append(values, strconv.Quote(fmt.Sprint(e)))
+			}
+			sort.Strings(values)
+			check(enumCond(v, values), "must be one of "+strings.Join(values, ", "))
+		}
+	case strings.HasPrefix(typ, "[]"):
+		if c.MinItems != nil {
+			check(fmt.Sprintf("len(%s) < %d", v, *c.MinItems), fmt.Sprintf("must have at least %d items", *c.MinItems))
+		}
+		if c.MaxItems != nil {
+			check(fmt.Sprintf("len(%s) > %d", v, *c.MaxItems), fmt.Sprintf("must have at most %d items", *c.MaxItems))
+		}
+	}
+	if e := p.Enum; e != nil {
+		values := e.Values
+		if len(values) == 0 {
+			values = e.Consts
+		}
+		check(enumCond(v, e.Consts), "must be one of "+strings.Join(values, ", "))
+	}
+	if len(checks) == 0 {
+		return ""
+	}
+	code := strings.Join(checks, "\n")
+	switch {
+	case nillable:
+		code = fmt.Sprintf("if r.%s != nil {\n%s\n}", field, code)
+	case p.Optional:
+		// optional fields are only checked when set
+		code = fmt.Sprintf("if %s {\n%s\n}", nonZero(v, typ), code)
+	}
+	return code + "\n"
+}
+
+// nonZero returns a condition that holds when v of type typ isn't the zero value.
+func nonZero(v, typ string) string {
+	switch {
+	case typ == "string":
+		return v + ` != ""`
+	case numericTypes[typ]:
+		return v + " != 0"
+	case strings.HasPrefix(typ, "[]"):
+		return "len(" + v + ") > 0"
+	}
+	return v + " != *new(" + typ + ")"
+}
+
+// enumCond returns a condition that holds when v is none of values.
+func enumCond(v string, values []string) string {
+	var conds []string
+	for _, value := range values {
+		conds = append(conds, v+" != "+value)
+	}
+	return strings.Join(conds, " && ")
+}
+
+// ValidationImports returns the imports required by the validation of the
+// requests of fns.
+func ValidationImports(fns []Func) []string {
+	var imps []string
+	for _, f := range fns {
+		for _, p := range f.Params {
+			if p.Constraints == nil {
+				continue
+			}
+			if p.Constraints.Pattern != "" {
+				imps = append(imps, "regexp")
+			}
+			if (p.Constraints.MinLength != nil || p.Constraints.MaxLength != nil) && strings.TrimPrefix(p.Type, "*") == "string" {
+				imps = append(imps, "unicode/utf8")
+			}
+		}
+	}
+	return imps
+}
+
+const validationTemplate = `
+{{ define "validation" }}{{ if HasConstraints . }}{{ with ValidationPatterns . }}
+var ({{ range . }}
+	{{ . }}{{ end }}
+)
+{{ end }}
+// Validate checks the fields of the request against the constraints of the API spec.
+func (r {{ .RequestName }}) Validate() error {
+	{{ range .Params }}{{ Validation $ . }}{{ end }}return nil
+}
+{{ end }}{{ end }}
+
+{{ define "validationerror" }}
+// ValidationError is returned by the request decoders when a field of a
+// request violates the constraints of the API spec.
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (e ValidationError) Error() string {
+	return e.Field + " " + e.Reason
+}
+
+// StatusCode makes the error encoder respond with 400 Bad Request.
+func (e ValidationError) StatusCode() int {
+	return http.StatusBadRequest
+}
+{{ end }}
+`
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Conversion is a struct type of the domain mirrored by a generated DTO,
+// see -convert.
+type Conversion struct {
+	Type   string // domain type as referred to in the generated package, e.g. "model.User"
+	DTO    string // name of the DTO type, e.g. "UserDTO"
+	Hook   string // prefix of the override hooks, e.g. "User"
+	Fields []ConversionField
+}
+
+// ConversionField is an exported field of a domain struct and its DTO.
+type ConversionField struct {
+	Name       string // name of the field in the DTO
+	Embedded   bool
+	Type       string // type of the field in the DTO
+	Tag        string // struct tag literal, copied from the domain type
+	ToDomain   string // statements copying the field from d to m
+	FromDomain string // statements copying the field from m to d
+}
+
+// converter collects the conversions of the domain structs referred to by
+// the requests and responses of a service.
+type converter struct {
+	qual        string // qualifier of the DTOs in the main package
+	conversions []*Conversion
+	byType      map[string]*Conversion // by fully qualified type
+	names       map[string]bool
+	pkgs        map[string]*specPkg
+	imports     map[string]bool
+	srcDir      string
+}
+
+// convertTypes replaces the domain structs in the request parameters and
+// results of the methods of svc by DTOs mirroring them, and returns the
+// conversions between the two. Parameters are converted to the domain in
+// the endpoints and results from it, and the other way around in the
+// methods of Endpoints; option setters are left alone.
+func convertTypes(svc *Service) ([]*Conversion, []string) {
+	c := &converter{qual: svc.DTOQual(), byType: map[string]*Conversion{}, names: map[string]bool{}, pkgs: map[string]*specPkg{}, imports: map[string]bool{}}
+	for i := range svc.Funcs {
+		f := &svc.Funcs[i]
+		c.srcDir = f.src.srcDir
+		if f.RequestType == nil {
+			for j := range f.Params {
+				p := &f.Params[j]
+				x, err := parser.ParseExpr(p.Type)
+				if err != nil || IsOptionSetter(p.Type) || !c.needs(f.src, x) {
+					continue
+				}
+				p.DTOType = c.typeString(f.src, x, true, "")
+				p.ToDomain = c.declare(f.src, x, p.VarName(), "req."+p.Field, true)
+				p.FromDomain = c.declare(f.src, x, paramName(*p, "p", j)+"DTO", paramName(*p, "p", j), false)
+			}
+		}
+		if f.ResponseType == nil && !f.Unwrap {
+			for j := range f.Res {
+				r := &f.Res[j]
+				x, err := parser.ParseExpr(r.Type)
+				if err != nil || !c.needs(f.src, x) {
+					continue
+				}
+				r.DTOType = c.typeString(f.src, x, true, "")
+				r.FromDomain = c.declare(f.src, x, r.VarName()+"DTO", r.VarName(), false)
+				r.ToDomain = c.assign(f.src, x, paramName(*r, "r", j), "res."+r.Field, true, c.qual, 0)
+			}
+		}
+	}
+	var imports []string
+	for i := range c.imports {
+		imports = append(imports, i)
+	}
+	return c.conversions, imports
+}
+
+// needs reports whether the type x, as used in the package p, refers to a
+// domain struct.
+func (c *converter) needs(p Pkg, x ast.Expr) bool {
+	switch x := x.(type) {
+	case *ast.StarExpr:
+		return c.needs(p, x.X)
+	case *ast.ArrayType:
+		return c.needs(p, x.Elt)
+	case *ast.MapType:
+		return c.needs(p, x.Value)
+	case *ast.Ident:
+		return c.domainStruct(p.ImportPath, x.Name) != nil
+	case *ast.SelectorExpr:
+		if qual, ok := x.X.(*ast.Ident); ok {
+			return c.domainStruct(p.importPathOf(qual.Name), x.Sel.Name) != nil
+		}
+	}
+	return false
+}
+
+// domainStruct returns the declaration of the struct type name in the
+// package path, or nil if it isn't a struct declared outside the standard
+// library or a known scalar.
+func (c *converter) domainStruct(path, name string) *ast.StructType {
+	if _, ok := scalars[path+"."+name]; ok {
+		return nil
+	}
+	pkg := c.pkg(path)
+	if pkg == nil || pkg.Goroot || pkg.types[name] == nil {
+		return nil
+	}
+	st, _ := pkg.types[name].Type.(*ast.StructType)
+	return st
+}
+
+// typeString returns the type x, as used in the package p, as referred to
+// in the generated package. If dto is set, domain structs are replaced by
+// their DTOs, qualified by qual.
+func (c *converter) typeString(p Pkg, x ast.Expr, dto bool, qual string) string {
+	switch x := x.(type) {
+	case *ast.StarExpr:
+		return "*" + c.typeString(p, x.X, dto, qual)
+	case *ast.ArrayType:
+		n := ""
+		if x.Len != nil {
+			n = p.gofmt(x.Len)
+		}
+		return "[" + n + "]" + c.typeString(p, x.Elt, dto, qual)
+	case *ast.MapType:
+		return "map[" + c.typeString(p, x.Key, dto, qual) + "]" + c.typeString(p, x.Value, dto, qual)
+	case *ast.Ident:
+		if _, ok := basicSchemas[x.Name]; ok || x.Name == "error" || !x.IsExported() {
+			return x.Name
+		}
+		return c.named(p.ImportPath, x.Name, dto, qual)
+	case *ast.SelectorExpr:
+		if id, ok := x.X.(*ast.Ident); ok {
+			if path := p.importPathOf(id.Name); path != "" {
+				return c.named(path, x.Sel.Name, dto, qual)
+			}
+		}
+	}
+	return p.gofmt(x)
+}
+
+// named returns the type name declared in the package path as referred to
+// in the generated package.
+func (c *converter) named(path, name string, dto bool, qual string) string {
+	if dto && c.domainStruct(path, name) != nil {
+		return qual + c.conversion(path, name).DTO
+	}
+	pkg := c.pkg(path)
+	if pkg == nil {
+		return name
+	}
+	c.imports[path] = true
+	return pkg.Name + "." + name
+}
+
+// conversion returns the conversion of the domain struct name declared in
+// the package path, adding it and the conversions of the structs it refers
+// to if needed.
+func (c *converter) conversion(path, name string) *Conversion {
+	key := path + "." + name
+	if conv, ok := c.byType[key]; ok {
+		return conv
+	}
+	pkg := c.pkg(path)
+	conv := &Conversion{Type: c.named(path, name, false, ""), Hook: name}
+	if c.names[conv.Hook] {
+		conv.Hook = Exported(pkg.Name) + name
+	}
+	conv.DTO = conv.Hook + "DTO"
+	c.names[conv.Hook] = true
+	c.byType[key] = conv
+	c.conversions = append(c.conversions, conv)
+
+	for _, field := range c.domainStruct(path, name).Fields.List {
+		tag := ""
+		if field.Tag != nil {
+			tag = field.Tag.Value
+		}
+		typ := c.typeString(pkg.Pkg, field.Type, true, "")
+		if len(field.Names) == 0 {
+			// embedded fields are named after their type, in the DTO too
+			domain := embeddedName(field.Type)
+			if !ast.IsExported(domain) {
+				continue
+			}
+			dto := embeddedName(&ast.Ident{Name: strings.TrimPrefix(typ, "*")})
+			conv.Fields = append(conv.Fields, ConversionField{
+				Name:       dto,
+				Embedded:   true,
+				Type:       typ,
+				Tag:        tag,
+				ToDomain:   c.assign(pkg.Pkg, field.Type, "m."+domain, "d."+dto, true, "", 0),
+				FromDomain: c.assign(pkg.Pkg, field.Type, "d."+dto, "m."+domain, false, "", 0),
+			})
+			continue
+		}
+		for _, n := range field.Names {
+			if !n.IsExported() {
+				continue
+			}
+			conv.Fields = append(conv.Fields, ConversionField{
+				Name:       n.Name,
+				Type:       typ,
+				Tag:        tag,
+				ToDomain:   c.assign(pkg.Pkg, field.Type, "m."+n.Name, "d."+n.Name, true, "", 0),
+				FromDomain: c.assign(pkg.Pkg, field.Type, "d."+n.Name, "m."+n.Name, false, "", 0),
+			})
+		}
+	}
+	return conv
+}
+
+// embeddedName returns the name of an embedded field of type x.
+func embeddedName(x ast.Expr) string {
+	if star, ok := x.(*ast.StarExpr); ok {
+		x = star.X
+	}
+	switch x := x.(type) {
+	case *ast.SelectorExpr:
+		return x.Sel.Name
+	case *ast.Ident:
+		if dot := strings.LastIndex(x.Name, "."); dot >= 0 {
+			return x.Name[dot+1:]
+		}
+		return x.Name
+	}
+	return ""
+}
+
+// declare returns the statements declaring the variable name holding src,
+// of the type x as used in the package p, converted to the domain or from it.
+func (c *converter) declare(p Pkg, x ast.Expr, name, src string, toDomain bool) string {
+	return define(name, c.typeString(p, x, !toDomain, c.qual), c.assign(p, x, name, src, toDomain, c.qual, 0))
+}
+
+// define returns the statements declaring the variable name of type typ
+// and assigning it by stmts.
+func define(name, typ, stmts string) string {
+	if !strings.Contains(stmts, "\n") {
+		return strings.Replace(stmts, " = ", " := ", 1)
+	}
+	return "var " + name + " " + typ + "\n" + stmts
+}
+
+// assign returns the statements assigning src to dst, of the type x as used
+// in the package p, converted to the domain or from it, with the DTOs
+// qualified by qual. Nil pointers, slices and maps stay nil.
+func (c *converter) assign(p Pkg, x ast.Expr, dst, src string, toDomain bool, qual string, depth int) string {
+	if !c.needs(p, x) {
+		return dst + " = " + src
+	}
+	target := func(x ast.Expr) string {
+		if toDomain {
+			return c.typeString(p, x, false, "")
+		}
+		return c.typeString(p, x, true, qual)
+	}
+	v := "v" + strconv.Itoa(depth)
+	switch x := x.(type) {
+	case *ast.StarExpr:
+		return fmt.Sprintf("if %s != nil {\n%s\n%s = &%s\n}", src, define(v, target(x.X), c.assign(p, x.X, v, "*"+src, toDomain, qual, depth+1)), dst, v)
+	case *ast.ArrayType:
+		i := "i" + strconv.Itoa(depth)
+		loop := fmt.Sprintf("for %s := range %s {\n%s\n}", i, src, c.assign(p, x.Elt, dst+"["+i+"]", src+"["+i+"]", toDomain, qual, depth+1))
+		if x.Len != nil {
+			return loop
+		}
+		return fmt.Sprintf("if %s != nil {\n%s = make(%s, len(%s))\n%s\n}", src, dst, target(x), src, loop)
+	case *ast.MapType:
+		k, w := "k"+strconv.Itoa(depth), "w"+strconv.Itoa(depth)
+		return fmt.Sprintf("if %s != nil {\n%s = make(%s, len(%s))\nfor %s, %s := range %s {\n%s\n%s[%s] = %s\n}\n}",
+			src, dst, target(x), src, k, v, src, define(w, target(x.Value), c.assign(p, x.Value, w, v, toDomain, qual, depth+1)), dst, k, w)
+	}
+	if toDomain {
+		if strings.HasPrefix(src, "*") {
+			src = "(" + src + ")"
+		}
+		return dst + " = " + src + ".ToDomain()"
+	}
+	// x is a domain struct, which typeString maps to its DTO
+	return dst + " = " + c.typeString(p, x, true, qual) + "FromDomain(" + src + ")"
+}
+
+// pkg returns the parsed package path, or nil if it can't be found.
+func (c *converter) pkg(path string) *specPkg {
+	if path == "" {
+		return nil
+	}
+	if pkg, ok := c.pkgs[path]; ok {
+		return pkg
+	}
+	c.pkgs[path] = nil
+	bpkg, err := importPackage(path, c.srcDir)
+	if err != nil {
+		return nil
+	}
+	pkg := &specPkg{Pkg: Pkg{Package: bpkg, FileSet: token.NewFileSet(), srcDir: c.srcDir}, types: map[string]*ast.TypeSpec{}}
+	for _, file := range bpkg.GoFiles {
+		f, err := parser.ParseFile(pkg.FileSet, filepath.Join(bpkg.Dir, file), nil, 0)
+		if err != nil {
+			continue
+		}
+		for _, decl := range f.Decls {
+			if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.TYPE {
+				for _, spec := range decl.Specs {
+					spec := spec.(*ast.TypeSpec)
+					pkg.types[spec.Name.Name] = spec
+				}
+			}
+		}
+	}
+	c.pkgs[path] = pkg
+	return pkg
+}
+
+const convertTemplate = `
+{{ define "convert" }}{{ range .Conversions }}
+// {{ .DTO }} is the transport representation of {{ .Type }}.
+type {{ .DTO }} struct { {{ range .Fields }}
+	{{ if not .Embedded }}{{ .Name }} {{ end }}{{ .Type }} {{ .Tag }}{{ end }}
+}
+
+// ToDomain converts d to a {{ .Type }}, copying the fields of the same name,
+// and calls {{ .Hook }}ToDomain, if set, to map the others.
+func (d {{ .DTO }}) ToDomain() {{ .Type }} {
+	var m {{ .Type }}{{ range .Fields }}
+	{{ .ToDomain }}{{ end }}
+	if {{ .Hook }}ToDomain != nil {
+		{{ .Hook }}ToDomain(d, &m)
+	}
+	return m
+}
+
+// {{ .DTO }}FromDomain converts m to a {{ .DTO }}, copying the fields of the
+// same name, and calls {{ .Hook }}FromDomain, if set, to map the others.
+func {{ .DTO }}FromDomain(m {{ .Type }}) {{ .DTO }} {
+	var d {{ .DTO }}{{ range .Fields }}
+	{{ .FromDomain }}{{ end }}
+	if {{ .Hook }}FromDomain != nil {
+		{{ .Hook }}FromDomain(m, &d)
+	}
+	return d
+}
+{{ end }}
+// Hooks completing the conversions between the DTOs and the domain types,
+// e.g. to map unexported or renamed fields. They are called after the
+// fields of the same name are copied.
+var ({{ range .Conversions }}
+	{{ .Hook }}ToDomain   func(d {{ .DTO }}, m *{{ .Type }})
+	{{ .Hook }}FromDomain func(m {{ .Type }}, d *{{ .DTO }}){{ end }}
+)
+{{ end }}
+`
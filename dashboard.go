@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// genDashboard returns a Grafana dashboard with a row of rate, error and
+// duration panels per method of svc, querying the metrics set by
+// UsePrometheusMetrics.
+func genDashboard(svc Service) ([]byte, error) {
+	ns := svc.MetricsNamespace()
+	type M = map[string]interface{}
+	target := func(expr, legend string) M {
+		return M{"expr": expr, "legendFormat": legend, "datasource": M{"type": "prometheus", "uid": "${datasource}"}}
+	}
+	panel := func(id, x, y int, title, unit string, targets ...M) M {
+		return M{
+			"id":          id,
+			"type":        "timeseries",
+			"title":       title,
+			"datasource":  M{"type": "prometheus", "uid": "${datasource}"},
+			"gridPos":     M{"x": x, "y": y, "w": 8, "h": 8},
+			"fieldConfig": M{"defaults": M{"unit": unit}, "overrides": []M{}},
+			"targets":     targets,
+		}
+	}
+
+	var panels []M
+	id := 1
+	for i, f := range svc.Funcs {
+		y := i * 9
+		sel := fmt.Sprintf("method=%q", f.Name)
+		panels = append(panels, M{"id": id, "type": "row", "title": f.Name, "collapsed": false, "gridPos": M{"x": 0, "y": y, "w": 24, "h": 1}, "panels": []M{}})
+		panels = append(panels,
+			panel(id+1, 0, y+1, "Rate", "reqps",
+				target(fmt.Sprintf("sum by (code) (rate(%s_http_requests_total{%s}[$__rate_interval]))", ns, sel), "{{code}}")),
+			panel(id+2, 8, y+1, "Errors", "percentunit",
+				target(fmt.Sprintf("sum(rate(%s_http_requests_total{%s,code=~\"5..\"}[$__rate_interval])) / sum(rate(%s_http_requests_total{%s}[$__rate_interval]))", ns, sel, ns, sel), "5xx")),
+			panel(id+3, 16, y+1, "Duration", "s",
+				target(fmt.Sprintf("histogram_quantile(0.5, sum by (le) (rate(%s_http_request_duration_seconds_bucket{%s}[$__rate_interval])))", ns, sel), "p50"),
+				target(fmt.Sprintf("histogram_quantile(0.95, sum by (le) (rate(%s_http_request_duration_seconds_bucket{%s}[$__rate_interval])))", ns, sel), "p95"),
+				target(fmt.Sprintf("histogram_quantile(0.99, sum by (le) (rate(%s_http_request_duration_seconds_bucket{%s}[$__rate_interval])))", ns, sel), "p99")),
+		)
+		id += 4
+	}
+
+	dashboard := M{
+		"uid":           ns,
+		"title":         svc.IFaceName(),
+		"description":   "Generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.",
+		"tags":          []string{"kitboiler"},
+		"schemaVersion": 36,
+		"editable":      false,
+		"time":          M{"from": "now-6h", "to": "now"},
+		"refresh":       "1m",
+		"templating": M{"list": []M{{
+			"name":  "datasource",
+			"label": "Data source",
+			"type":  "datasource",
+			"query": "prometheus",
+		}}},
+		"panels": panels,
+	}
+	data, err := json.MarshalIndent(dashboard, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
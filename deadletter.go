@@ -0,0 +1,127 @@
+package main
+
+import "errors"
+
+// checkDeadLetter returns an error if s has none of the transports whose
+// subscribers -dead-letter retries the requests of.
+func checkDeadLetter(s Service) error {
+	if !s.NATS && !s.AMQP {
+		return errors.New("-dead-letter retries the requests of the nats and amqp subscribers, add one of them to -transports")
+	}
+	return nil
+}
+
+const deadLetterTemplate = `
+{{ define "deadletter" }}
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package {{ .Pkg }}
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"{{ if .NATS }}
+	nats "github.com/nats-io/nats.go"{{ end }}
+)
+
+// MessageRetry is how the {{ if and .NATS .AMQP }}NATS and AMQP{{ else if .NATS }}NATS{{ else }}AMQP{{ end }} subscribers retry the requests
+// their endpoint fails to serve before dead-lettering them. Set it at init
+// time, before the subscribers are made.
+var MessageRetry = RetryPolicy{Attempts: 3, Backoff: 100 * time.Millisecond, MaxBackoff: 2 * time.Second}
+
+// RetryPolicy is how often, and how far apart, a failing request is served.
+type RetryPolicy struct {
+	Attempts   int           // times the request is served at most, 1 not to retry it
+	Backoff    time.Duration // wait before the first retry, doubled before every next one
+	MaxBackoff time.Duration // longest wait between two retries
+}
+
+// RetryCount counts the retries of the requests of the subscribers, labeled
+// by "transport"{{ if and .NATS .AMQP }}, nats or amqp,{{ end }} and "method". DeadLetterCount counts the
+// requests they dead-letter, labeled by "transport", "method" and "reason":
+// "failed" or "undecodable". They discard all observations until set to
+// real metrics.
+var (
+	RetryCount      metrics.Counter = discard.NewCounter()
+	DeadLetterCount metrics.Counter = discard.NewCounter()
+)
+
+// IsPermanent reports whether err is the error of a request failing the same
+// however often it is served, which the subscribers neither retry nor
+// dead-letter: one with a status below 500 by {{ if .EncodesErrors }}errorStatus{{ else }}its StatusCode method{{ end }}, e.g. a
+// ValidationError.
+var IsPermanent = func(err error) bool { {{ if .EncodesErrors }}
+	status, ok := errorStatus(err)
+	return ok && status < 500{{ else }}
+	sc, ok := err.(interface{ StatusCode() int })
+	return ok && sc.StatusCode() < 500{{ end }}
+}
+
+// message is the state of a request served by a subscriber, put in its
+// context by their before function, for retryMessages to record how its
+// endpoint did.
+type message struct { {{ if .NATS }}
+	msg    *nats.Msg // the request, if served by a NATS subscriber{{ end }}
+	served bool      // the request was decoded and its endpoint called
+	failed bool      // the endpoint failed after every retry
+}
+
+// retryMessages returns the endpoint middleware of the subscriber of method
+// over transport, calling the endpoint again, as set by MessageRetry, while
+// it fails with an error IsPermanent doesn't report, and counting the
+// retries in RetryCount.
+func retryMessages(transport, method string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			m, ok := ctx.Value(messageContextKey).(*message)
+			if !ok {
+				m = &message{}
+			}
+			m.served = true
+			backoff := MessageRetry.Backoff
+			for attempt := 1; ; attempt++ {
+				response, err := next(ctx, request)
+				if err == nil || IsPermanent(err) {
+					return response, err
+				}
+				if attempt >= MessageRetry.Attempts {
+					m.failed = true
+					return response, err
+				}
+				RetryCount.With("transport", transport, "method", method).Add(1)
+				select {
+				case <-time.After(backoff):
+				case <-ctx.Done():
+					m.failed = true
+					return response, err
+				}
+				if backoff *= 2; backoff > MessageRetry.MaxBackoff {
+					backoff = MessageRetry.MaxBackoff
+				}
+			}
+		}
+	}
+}
+
+// deadLetterReason returns why the request of ctx, failing with err, is
+// dead-lettered: "failed" if its endpoint failed after every retry, or
+// "undecodable" if it failed to be decoded with an error IsPermanent doesn't
+// report. It returns "" for the requests which aren't.
+func deadLetterReason(ctx context.Context, err error) string {
+	m, ok := ctx.Value(messageContextKey).(*message)
+	switch {
+	case !ok:
+		return ""
+	case m.failed:
+		return "failed"
+	case !m.served && !IsPermanent(err):
+		return "undecodable"
+	}
+	return ""
+}
+{{ end }}
+`
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// demoModule is the module path of the project written by kitboiler demo.
+const demoModule = "example.com/demo"
+
+// demoFlags are the flags the package of the demo is generated with, unless
+// set on the command line: all generators working together without
+// dependencies beyond demoRequires.
+var demoFlags = map[string]string{
+	"scaffold":    "true",
+	"harness":     "true",
+	"stub-server": "true",
+	"loadtest":    "true",
+	"openapi":     "true",
+	"proto":       "true",
+	"health":      "true",
+	"recover":     "true",
+	"access-log":  "true",
+	"metrics":     "true",
+	"admin":       "true",
+}
+
+// demoRequires are the modules required by the generated code, at versions
+// it is known to build with, including logfmt, which go-kit v0.9.0 imports
+// without requiring it in a go.mod.
+var demoRequires = map[string]string{
+	"github.com/go-kit/kit":       "v0.9.0",
+	"github.com/go-logfmt/logfmt": "v0.6.1",
+	"gopkg.in/yaml.v2":            "v2.4.0",
+}
+
+// runDemo writes a runnable example project to the directory args[0]: a
+// todo service with an in-memory implementation, its tests and a client,
+// along with the package generated for it.
+func runDemo(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	dir := args[0]
+	if entries, err := ioutil.ReadDir(dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%s isn't empty", dir)
+	} else if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var err error
+	flag.Visit(func(f *flag.Flag) {
+		if err == nil && (f.Name == "o" || f.Name == "dir" || f.Name == "pkg") {
+			err = fmt.Errorf("-%s isn't supported by demo, which chooses its own layout", f.Name)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if err := setUnset(demoFlags); err != nil {
+		return err
+	}
+	if !*flagScaffold {
+		return errors.New("the demo requires -scaffold")
+	}
+
+	files := []File{
+		{Name: "go.mod", Content: demoGoMod()},
+		{Name: "README.md", Content: []byte(demoReadme)},
+		{Name: filepath.Join("api", "api.go"), Content: []byte(demoAPI)},
+		// written before generating the package, which keeps them
+		{Name: filepath.Join("endpoints", "cmd", "todo-service", "service.go"), Content: []byte(demoService)},
+		{Name: filepath.Join("endpoints", "cmd", "todo-service", "service_test.go"), Content: []byte(demoServiceTest)},
+		{Name: filepath.Join("cmd", "todo-client", "main.go"), Content: []byte(demoClient)},
+	}
+	if err := writeFiles(dir, files); err != nil {
+		return err
+	}
+	// the interface is looked up in the module of the working directory
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(abs); err != nil {
+		return err
+	}
+	*flagSrcDir = abs
+	*flagOutDir = filepath.Join(abs, "endpoints")
+	if err := runGen([]string{demoModule + "/api.TodoService"}); err != nil {
+		return fmt.Errorf("generating the demo package: %v", err)
+	}
+	fmt.Printf("wrote the demo to %s; run it with\n\n", dir)
+	fmt.Printf("\tcd %s\n\tgo mod tidy\n\tgo test ./endpoints -update-golden\n\tgo test ./...\n\tgo run ./endpoints/cmd/todo-service\n", dir)
+	return nil
+}
+
+// demoGoMod returns the go.mod of the demo.
+func demoGoMod() []byte {
+	var mods []string
+	for mod := range demoRequires {
+		mods = append(mods, mod)
+	}
+	sort.Strings(mods)
+	var b strings.Builder
+	fmt.Fprintf(&b, "module %s\n\ngo 1.13\n\nrequire (\n", demoModule)
+	for _, mod := range mods {
+		fmt.Fprintf(&b, "\t%s %s\n", mod, demoRequires[mod])
+	}
+	b.WriteString(")\n")
+	return []byte(b.String())
+}
+
+const demoReadme = `# KitBoiler demo
+
+A todo service generated by kitboiler demo, as an example of what KitBoiler
+generates and how the pieces fit together:
+
+* api/api.go: the TodoService interface, annotated with //kit: directives
+* endpoints/: the package KitBoiler generated for it: the Go kit endpoints and
+  HTTP handlers, a mock, a golden file test harness, a stub server, a load
+  test, the OpenAPI spec and the proto file
+* endpoints/cmd/todo-service: the command serving the service, with its
+  in-memory implementation in service.go, tested through the HTTP handlers
+  in service_test.go
+* cmd/todo-client: a client of the service
+
+Record the golden files of the harness, run the tests, then run the service
+and add a todo with the client:
+
+	go mod tidy
+	go test ./endpoints -update-golden
+	go test ./...
+	go run ./endpoints/cmd/todo-service &
+	go run ./cmd/todo-client buy milk
+
+Regenerate the package after changing the interface with:
+
+	kitboiler -o endpoints -scaffold -harness -stub-server -loadtest -openapi -proto -health -recover -access-log -metrics -admin example.com/demo/api.TodoService
+`
+
+const demoAPI = `// Package api declares the todo service of the KitBoiler demo.
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// Todo is an item of the todo list.
+type Todo struct {
+	ID        string    ` + "`json:\"id\"`" + `
+	Title     string    ` + "`json:\"title\"`" + `
+	Done      bool      ` + "`json:\"done\"`" + `
+	Version   int       ` + "`json:\"version\"`" + `
+	CreatedAt time.Time ` + "`json:\"created_at\"`" + `
+}
+
+// TodoService manages a todo list.
+type TodoService interface {
+	// CreateTodo adds a todo titled title to the list.
+	//kit:budget 200ms
+	CreateTodo(ctx context.Context, title string) (todo *Todo, err error)
+	// GetTodo returns the todo id, or nil if there is none.
+	//kit:etag Version
+	//kit:nil not-found
+	GetTodo(ctx context.Context, id string) (todo *Todo, err error)
+	// ListTodos returns the todos left to do, or all of them if all is set.
+	//kit:optional all
+	ListTodos(ctx context.Context, all bool) (todos []*Todo, err error)
+	// CompleteTodo marks the todo id as done.
+	//kit:ifmatch GetTodo
+	CompleteTodo(ctx context.Context, id string) (todo *Todo, err error)
+	// DeleteTodo removes the todo id from the list.
+	//kit:group admin
+	DeleteTodo(ctx context.Context, id string) (err error)
+}
+`
+
+const demoService = `package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"example.com/demo/api"
+)
+
+// newService returns the in-memory implementation of api.TodoService served
+// by the command. KitBoiler doesn't overwrite this file once it exists.
+func newService(cfg Config, logger log.Logger) (api.TodoService, error) {
+	return &service{todos: map[string]*api.Todo{}}, nil
+}
+
+// errNotFound is returned for the todos that don't exist.
+var errNotFound error = notFoundError{}
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string { return "todo not found" }
+
+// StatusCode makes the error encoder respond with 404 Not Found.
+func (notFoundError) StatusCode() int { return http.StatusNotFound }
+
+// service keeps the todos in memory. It returns copies of them, which the
+// callers may change.
+type service struct {
+	mu    sync.Mutex
+	last  int
+	todos map[string]*api.Todo
+}
+
+func (s *service) CreateTodo(ctx context.Context, title string) (todo *api.Todo, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last++
+	todo = &api.Todo{ID: strconv.Itoa(s.last), Title: title, Version: 1, CreatedAt: time.Now().UTC()}
+	s.todos[todo.ID] = todo
+	return copyTodo(todo), nil
+}
+
+func (s *service) GetTodo(ctx context.Context, id string) (todo *api.Todo, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if todo, ok := s.todos[id]; ok {
+		return copyTodo(todo), nil
+	}
+	return nil, nil
+}
+
+func (s *service) ListTodos(ctx context.Context, all bool) (todos []*api.Todo, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	todos = []*api.Todo{}
+	for _, todo := range s.todos {
+		if all || !todo.Done {
+			todos = append(todos, copyTodo(todo))
+		}
+	}
+	sort.Slice(todos, func(i, j int) bool {
+		a, _ := strconv.Atoi(todos[i].ID)
+		b, _ := strconv.Atoi(todos[j].ID)
+		return a < b
+	})
+	return todos, nil
+}
+
+func (s *service) CompleteTodo(ctx context.Context, id string) (todo *api.Todo, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	todo, ok := s.todos[id]
+	if !ok {
+		return nil, errNotFound
+	}
+	if !todo.Done {
+		todo.Done = true
+		todo.Version++
+	}
+	return copyTodo(todo), nil
+}
+
+func (s *service) DeleteTodo(ctx context.Context, id string) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.todos[id]; !ok {
+		return errNotFound
+	}
+	delete(s.todos, id)
+	return nil
+}
+
+func copyTodo(todo *api.Todo) *api.Todo {
+	c := *todo
+	return &c
+}
+`
+
+const demoServiceTest = `package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"example.com/demo/endpoints"
+)
+
+func TestTodoService(t *testing.T) {
+	svc, err := newService(Config{}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(svc))
+	defer srv.Close()
+	admin := httptest.NewServer(endpoints.MakeAdminHTTPHandler(svc))
+	defer admin.Close()
+
+	var created endpoints.CreateTodoResponse
+	if code := post(t, srv.URL+"/create-todo", "", endpoints.CreateTodoRequest{Title: "write the demo"}, &created); code != http.StatusOK {
+		t.Fatalf("create-todo: got %d, want 200", code)
+	}
+	if created.Todo == nil || created.Todo.Title != "write the demo" {
+		t.Fatalf("create-todo: got %+v", created.Todo)
+	}
+	id := created.Todo.ID
+
+	if code := post(t, srv.URL+"/complete-todo", ` + "`\"0\"`" + `, endpoints.CompleteTodoRequest{Id: id}, nil); code != http.StatusPreconditionFailed {
+		t.Errorf("complete-todo with a stale ETag: got %d, want 412", code)
+	}
+	var completed endpoints.CompleteTodoResponse
+	if code := post(t, srv.URL+"/complete-todo", ` + "`\"1\"`" + `, endpoints.CompleteTodoRequest{Id: id}, &completed); code != http.StatusOK {
+		t.Fatalf("complete-todo: got %d, want 200", code)
+	}
+	if !completed.Todo.Done {
+		t.Errorf("complete-todo: todo %s isn't done", id)
+	}
+
+	var left, all endpoints.ListTodosResponse
+	post(t, srv.URL+"/list-todos", "", endpoints.ListTodosRequest{}, &left)
+	post(t, srv.URL+"/list-todos", "", endpoints.ListTodosRequest{All: true}, &all)
+	if len(left.Todos) != 0 || len(all.Todos) != 1 {
+		t.Errorf("list-todos: got %d left and %d in all, want 0 and 1", len(left.Todos), len(all.Todos))
+	}
+
+	if code := post(t, srv.URL+"/delete-todo", "", endpoints.DeleteTodoRequest{Id: id}, nil); code != http.StatusNotFound {
+		t.Errorf("delete-todo on the public handler: got %d, want 404", code)
+	}
+	if code := post(t, admin.URL+"/delete-todo", "", endpoints.DeleteTodoRequest{Id: id}, nil); code != http.StatusOK {
+		t.Errorf("delete-todo: got %d, want 200", code)
+	}
+	if code := post(t, srv.URL+"/get-todo", "", endpoints.GetTodoRequest{Id: id}, nil); code != http.StatusNotFound {
+		t.Errorf("get-todo of a deleted todo: got %d, want 404", code)
+	}
+}
+
+// post posts req to url with the If-Match header ifMatch, if set, decodes the
+// response into res, if set and the request succeeded, and returns its status
+// code.
+func post(t *testing.T, url, ifMatch string, req, res interface{}) int {
+	t.Helper()
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ifMatch != "" {
+		r.Header.Set("If-Match", ifMatch)
+	}
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if res != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+`
+
+const demoClient = `// Command todo-client adds the todo titled by its arguments, if any, to the
+// todo service at -addr and lists the todos left to do.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"example.com/demo/endpoints"
+)
+
+var addr = flag.String("addr", "http://localhost:8080", "base URL of the todo service")
+
+func main() {
+	flag.Parse()
+	if flag.NArg() > 0 {
+		var created endpoints.CreateTodoResponse
+		if err := call("/create-todo", endpoints.CreateTodoRequest{Title: strings.Join(flag.Args(), " ")}, &created); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("added", created.Todo.ID)
+	}
+	var listed endpoints.ListTodosResponse
+	if err := call("/list-todos", endpoints.ListTodosRequest{}, &listed); err != nil {
+		log.Fatal(err)
+	}
+	for _, todo := range listed.Todos {
+		fmt.Printf("%s\t%s\n", todo.ID, todo.Title)
+	}
+}
+
+// call posts req to the route of a method and decodes its response into res.
+func call(route string, req, res interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(*addr+route, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", route, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(res)
+}
+`
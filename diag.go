@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// PosError is an error at a position in the source of the interface, such
+// as an invalid annotation.
+type PosError struct {
+	Pos token.Position
+	Msg string
+}
+
+func (e *PosError) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// errorAt returns a *PosError at pos in fset, or a plain error if pos is
+// unknown.
+func errorAt(fset *token.FileSet, pos token.Pos, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	if fset == nil || !pos.IsValid() {
+		return fmt.Errorf("%s", msg)
+	}
+	return &PosError{Pos: fset.Position(pos), Msg: msg}
+}
+
+// errorf returns an error about fn at the annotation a, or at the method if
+// a has no position.
+func (fn *Func) errorf(a Annotation, format string, args ...interface{}) error {
+	pos := a.Pos
+	if !pos.IsValid() {
+		pos = fn.Pos
+	}
+	return errorAt(fn.src.FileSet, pos, "%s: "+format, append([]interface{}{fn.Name}, args...)...)
+}
+
+// warnf writes a warning about fn to stderr, at the method, or at the
+// embedding of its interface if fn is the method of an embedded interface,
+// which may be declared outside of the module.
+func (fn *Func) warnf(format string, args ...interface{}) {
+	name, fset, pos := fn.Name, fn.src.FileSet, fn.Pos
+	if e := fn.embedded; e != nil {
+		name, fset, pos = fn.Name+" of the embedded "+e.iface, e.src.FileSet, e.pos
+	}
+	msg := fmt.Sprintf("warning: %s: "+format, append([]interface{}{name}, args...)...)
+	if fset != nil && pos.IsValid() {
+		msg = fset.Position(pos).String() + ": " + msg
+	}
+	fmt.Fprintln(os.Stderr, msg)
+}
+
+// GeneratedError is a syntax error in the code generated by a template,
+// reported along with the offending lines of the code.
+type GeneratedError struct {
+	Template string
+	Pos      token.Position // in the generated code
+	Msg      string
+	Src      []byte
+}
+
+func (e *GeneratedError) Error() string {
+	return fmt.Sprintf("template %s generated invalid Go code, line %d:%d: %s (pass -allow-invalid to write it unformatted)", e.Template, e.Pos.Line, e.Pos.Column, e.Msg)
+}
+
+// generatedError returns the *GeneratedError of err, the error formatting
+// the code src generated by the template name.
+func generatedError(name string, src []byte, err error) error {
+	e := &GeneratedError{Template: name, Msg: err.Error(), Src: src}
+	if list, ok := err.(scanner.ErrorList); ok && len(list) > 0 {
+		e.Pos, e.Msg = list[0].Pos, list[0].Msg
+	}
+	return e
+}
+
+// errorList is a list of errors reported together, such as the problems
+// found by vet.
+type errorList []error
+
+func (l errorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, err := range l {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// useColor reports whether diagnostics written to f are colored: only on
+// terminals, unless the NO_COLOR environment variable is set or TERM is
+// dumb.
+func useColor(f *os.File) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+const (
+	colorBold  = "\x1b[1m"
+	colorRed   = "\x1b[1;31m"
+	colorGreen = "\x1b[1;32m"
+	colorReset = "\x1b[0m"
+)
+
+// printError writes err to w like a compiler diagnostic: errors at a
+// position in the source are followed by the source line and a caret
+// pointing at the column.
+func printError(w io.Writer, err error, color bool) {
+	paint := func(c, s string) string {
+		if !color {
+			return s
+		}
+		return c + s + colorReset
+	}
+	if list, ok := err.(errorList); ok {
+		for _, err := range list {
+			printError(w, err, color)
+		}
+		return
+	}
+	if ge, ok := err.(*GeneratedError); ok {
+		fmt.Fprintf(w, "%s %s\n", paint(colorRed, "error:"), ge)
+		printSnippet(w, ge, paint)
+		return
+	}
+	pe, ok := err.(*PosError)
+	if !ok {
+		fmt.Fprintf(w, "%s %s\n", paint(colorRed, "error:"), err)
+		return
+	}
+	fmt.Fprintf(w, "%s %s %s\n", paint(colorBold, pe.Pos.String()+":"), paint(colorRed, "error:"), pe.Msg)
+	line, ok := sourceLine(pe.Pos)
+	if !ok {
+		return
+	}
+	// keep tabs in the indentation of the caret so that it lines up with the source
+	prefix := line
+	if pe.Pos.Column-1 < len(prefix) {
+		prefix = prefix[:pe.Pos.Column-1]
+	}
+	indent := []rune(prefix)
+	for i, r := range indent {
+		if r != '\t' {
+			indent[i] = ' '
+		}
+	}
+	fmt.Fprintf(w, "\t%s\n\t%s%s\n", line, string(indent), paint(colorGreen, "^"))
+}
+
+// printSnippet writes the lines of the generated code around the error e,
+// numbered, with a caret pointing at its column.
+func printSnippet(w io.Writer, e *GeneratedError, paint func(c, s string) string) {
+	lines := strings.Split(string(e.Src), "\n")
+	if e.Pos.Line < 1 || e.Pos.Line > len(lines) {
+		return
+	}
+	for n := e.Pos.Line - 2; n <= e.Pos.Line+2; n++ {
+		if n < 1 || n > len(lines) {
+			continue
+		}
+		line := strings.TrimRight(lines[n-1], "\r")
+		fmt.Fprintf(w, "%5d | %s\n", n, line)
+		if n != e.Pos.Line {
+			continue
+		}
+		indent := []rune(line)
+		if e.Pos.Column-1 < len(indent) {
+			indent = indent[:e.Pos.Column-1]
+		}
+		for i, r := range indent {
+			if r != '\t' {
+				indent[i] = ' '
+			}
+		}
+		fmt.Fprintf(w, "      | %s%s\n", string(indent), paint(colorGreen, "^"))
+	}
+}
+
+// sourceLine returns the line of the source file at pos.
+func sourceLine(pos token.Position) (string, bool) {
+	if pos.Filename == "" || pos.Line < 1 {
+		return "", false
+	}
+	data, err := ioutil.ReadFile(pos.Filename)
+	if err != nil {
+		return "", false
+	}
+	lines := strings.Split(string(data), "\n")
+	if pos.Line > len(lines) {
+		return "", false
+	}
+	return strings.TrimRight(lines[pos.Line-1], "\r"), true
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// checkDiscovery returns an error if -discovery names an unknown registry
+// or s has no scaffolded command to register.
+func checkDiscovery(s Service) error {
+	if s.Discovery != "consul" {
+		return fmt.Errorf("-discovery: unknown registry %q, want consul", s.Discovery)
+	}
+	if !s.Scaffold {
+		return errors.New("-discovery registers the scaffolded command, add -scaffold")
+	}
+	return nil
+}
+
+const discoveryTemplate = `
+{{ define "registrar" }}
+// newRegistrar returns the registrar of the command in Consul, whose agent is
+// that of the CONSUL_HTTP_ADDR environment variable, localhost:8500 by
+// default. It registers the command at cfg.AdvertiseAddr, the hostname and
+// the port of cfg.Addr if empty, with a {{ if not .HTTP }}gRPC health check of the server{{ else if .Health }}check of /healthz{{ else }}TCP check of the listener{{ end }},
+// deregistered by Consul if it keeps failing.
+func newRegistrar(cfg Config, logger log.Logger) (*consulsd.Registrar, error) {
+	addr := cfg.AdvertiseAddr
+	if addr == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+		_, port, err := net.SplitHostPort(cfg.Addr)
+		if err != nil {
+			return nil, err
+		}
+		addr = net.JoinHostPort(hostname, port)
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("advertise address: %v", err)
+	}
+	portNum, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, fmt.Errorf("advertise address %s: %v", addr, err)
+	}
+	client, err := consulapi.NewClient(consulapi.DefaultConfig())
+	if err != nil {
+		return nil, err
+	}
+	return consulsd.NewRegistrar(consulsd.NewClient(client), &consulapi.AgentServiceRegistration{
+		ID:      "{{ .CommandName }}-" + addr,
+		Name:    "{{ .CommandName }}",
+		Address: host,
+		Port:    portNum,
+		Check: &consulapi.AgentServiceCheck{ {{ if not .HTTP }}
+			GRPC:                           addr,{{ else if .Health }}
+			HTTP:                           "http://" + addr + "/healthz",{{ else }}
+			TCP:                            addr,{{ end }}
+			Interval:                       "10s",
+			DeregisterCriticalServiceAfter: "1m",
+		},
+	}, logger), nil
+}
+{{ end }}
+`
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strconv"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// DTOQual returns the qualifier of the request and response types in the
+// main package: "dto." when they are generated into the dto package.
+func (s Service) DTOQual() string {
+	if s.DTO {
+		return "dto."
+	}
+	return ""
+}
+
+// DTOImports returns the imports of the dto package.
+func (s Service) DTOImports() map[string]string {
+	// types declared along with the interface
+	imps := map[string]string{s.IFacePath: ""}
+	for _, f := range s.Funcs {
+		for _, i := range f.RequiredImports {
+			imps[i] = ""
+		}
+	}
+	for _, i := range ValidationImports(s.Funcs) {
+		imps[i] = ""
+	}
+	for _, i := range s.conversionImports {
+		imps[i] = ""
+	}
+	if s.UsesRedaction() {
+		for _, i := range redactImports {
+			imps[i] = ""
+		}
+	}
+	if s.UsesConstraints() {
+		imps["net/http"] = ""
+	}
+	if s.UsesUnions() {
+		imps["encoding/json"] = ""
+		imps["fmt"] = ""
+	}
+	return imps
+}
+
+// pruneImports removes the imports src doesn't use. Packages are assumed
+// to have the names found when loading the interface, or else the names
+// guessed from their import paths, unless imported with a name.
+func pruneImports(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	// deleting an import removes it from f.Imports
+	imports := append([]*ast.ImportSpec(nil), f.Imports...)
+	for _, imp := range imports {
+		p, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+		name, ok := packageNames[p]
+		if !ok {
+			name = guessPackageName(p)
+		}
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		if name == "_" || name == "." || usesIdent(f, name) {
+			continue
+		}
+		if imp.Name != nil {
+			astutil.DeleteNamedImport(fset, f, imp.Name.Name, p)
+		} else {
+			astutil.DeleteImport(fset, f, p)
+		}
+	}
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, f); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// usesIdent reports whether f refers to the package name in a selector.
+func usesIdent(f *ast.File, name string) bool {
+	used := false
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && id.Name == name && id.Obj == nil {
+				used = true
+			}
+		}
+		return !used
+	})
+	return used
+}
+
+const dtoTemplate = `
+{{ define "types" }}{{ if not .RequestType }}
+type {{ .RequestName }} struct { {{ range .Params }}{{ if ne .Type "context.Context" }}{{ .Field }} {{ .FieldType }}{{ with .Tag }} {{ . }}{{ end }}
+{{ end }}{{ end }} }
+{{ template "validation" . }}{{ template "redact" . }}{{ end }}{{ if not (or .ResponseType .Unwrap) }}
+type {{ .ResponseName }} struct { {{ range FilterError .Res }}{{ .Field }} {{ .FieldType }}{{ with .Tag }} {{ . }}{{ end }}
+{{ end }} }
+{{ range FilterError .Res }}{{ with .Union }}{{ template "union" . }}{{ end }}{{ end }}{{ end }}{{ end }}
+
+{{ define "dto" }}
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+
+// Package dto holds the request and response types of {{ .IFace }}, shared
+// by its server and clients.
+package dto
+
+import ({{ range $imp, $alias := .DTOImports }}
+	{{ $alias }} "{{ $imp }}"{{ end }}
+)
+{{ range .Funcs }}{{ template "types" . }}{{ end }}{{ if .UsesConstraints }}{{ template "validationerror" . }}{{ end }}{{ if .Conversions }}{{ template "convert" . }}{{ end }}{{ end }}
+`
@@ -0,0 +1,62 @@
+package main
+
+// ParamName returns the name of the ith parameter of f, as named by
+// Signature.
+func ParamName(f Func, i int) string {
+	return paramName(f.Params[i], "p", i)
+}
+
+// HasSkipped reports whether any method of the interface is left out of the
+// generated code.
+func (s Service) HasSkipped() bool {
+	return len(s.AllFuncs) > len(s.Funcs)
+}
+
+const endpointSetTemplate = `
+{{ define "endpointset" }}{{ $svc := . }}
+// Endpoints collects the endpoints of {{ .IFace }}. It implements
+// {{ .IFace }} itself by calling them, so that endpoints calling a remote
+// service, e.g. made with httptransport.NewClient, can be used as its client.
+type Endpoints struct { {{ range .Funcs }}
+	{{ .Name }}Endpoint endpoint.Endpoint{{ end }}
+}
+
+{{ if not .Assertions }}
+var _ {{ .IFace }} = Endpoints{}
+{{ end }}
+// MakeEndpoints returns the endpoints of svc, wrapped in the middlewares
+// enabled for them.
+func MakeEndpoints(svc {{ .IFace }}) Endpoints {
+	return Endpoints{ {{ range .Funcs }}
+		{{ .Name }}Endpoint: {{ $svc.Endpoint . }},{{ end }}
+	}
+}
+{{ range $f := .AllFuncs }}{{ if .Skip }}
+// {{ .Name }} has no endpoint{{ if HasError . }}, it fails with ErrNoEndpoint{{ end }}.
+func (e Endpoints) {{ .Name }}{{ Signature . }} { {{ if HasError . }}
+	{{ ErrorName . }} = ErrNoEndpoint{{ end }}
+	return
+}
+{{ else }}
+// {{ .Name }} calls the {{ .Name }}Endpoint of e.
+func (e Endpoints) {{ .Name }}{{ Signature . }} { {{ range $i, $p := .Params }}{{ if IsOptionSetter .Type }}
+	var {{ ParamName $f $i }}Options {{ OptionSetterStruct .Type }}
+	for _, set := range {{ ParamName $f $i }} {
+		set(&{{ ParamName $f $i }}Options)
+	}{{ else }}{{ with .FromDomain }}
+	{{ . }}{{ end }}{{ end }}{{ end }}
+	{{ if FilterError .Res }}response, {{ or (ErrorName .) "_" }} :={{ else }}_, {{ or (ErrorName .) "_" }} ={{ end }} e.{{ .Name }}Endpoint({{ ContextArg . }}, {{ $svc.Request . }}{ {{ range $i, $p := .Params }}{{ if ne .Type "context.Context" }}
+		{{ .Field }}: {{ ParamName $f $i }}{{ if IsOptionSetter .Type }}Options{{ else if .FromDomain }}DTO{{ end }},{{ end }}{{ end }}
+	}){{ if FilterError .Res }}
+	if res, ok := response.({{ $svc.Response . }}); ok { {{ range $i, $r := .Res }}{{ if ne .Type "error" }}{{ if .ToDomain }}
+		{{ .ToDomain }}{{ else }}
+		{{ ResultName $f $i }} = res{{ if not $f.Unwrap }}.{{ .Field }}{{ end }}{{ if .Union }}.Value{{ end }}{{ end }}{{ end }}{{ end }}
+	}{{ end }}
+	return
+}
+{{ end }}{{ end }}{{ if .HasSkipped }}
+// ErrNoEndpoint is returned by the methods of Endpoints left out of the
+// generated code.
+var ErrNoEndpoint = errors.New("method has no endpoint")
+{{ end }}{{ end }}
+`
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
+)
+
+// Enum describes a defined string or integer type with a set of exported
+// constants. Request fields of the type are restricted to those constants.
+type Enum struct {
+	Consts []string // qualified names of the constants, e.g. "model.StatusActive"
+	Values []string // Go literals of the values of the constants, empty if unknown
+}
+
+// enumKinds are the underlying types of types that can be enums.
+var enumKinds = map[string]bool{
+	"string": true,
+	"int":    true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+}
+
+// enum returns the enum of the (pointer to the) type typ, as used in the
+// package p, or nil if typ isn't an enum.
+func (p Pkg) enum(typ string) *Enum {
+	typ = strings.TrimPrefix(typ, "*")
+	dot := strings.Index(typ, ".")
+	if dot < 0 || strings.ContainsAny(typ, "[]() ") {
+		return nil
+	}
+	qual, id := typ[:dot], typ[dot+1:]
+	path := p.importPathOf(qual)
+	if path == "" {
+		return nil
+	}
+
+	pkg, err := importPackage(path, p.srcDir)
+	if err != nil || pkg.Goroot {
+		return nil
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	isEnum := false
+	for _, file := range pkg.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, file), nil, 0)
+		if err != nil {
+			continue
+		}
+		files = append(files, f)
+		for _, decl := range f.Decls {
+			decl, ok := decl.(*ast.GenDecl)
+			if !ok || decl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				spec := spec.(*ast.TypeSpec)
+				if under, ok := spec.Type.(*ast.Ident); ok && spec.Name.Name == id && spec.Assign == 0 {
+					isEnum = enumKinds[under.Name]
+				}
+			}
+		}
+	}
+	if !isEnum {
+		return nil
+	}
+
+	e := &Enum{}
+	known := true
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			decl, ok := decl.(*ast.GenDecl)
+			if !ok || decl.Tok != token.CONST {
+				continue
+			}
+			// specs without values repeat the type and values of the previous spec
+			var typ ast.Expr
+			var values []ast.Expr
+			for iota, spec := range decl.Specs {
+				spec := spec.(*ast.ValueSpec)
+				if len(spec.Values) > 0 {
+					typ, values = spec.Type, spec.Values
+				}
+				if t, ok := typ.(*ast.Ident); !ok || t.Name != id {
+					continue
+				}
+				for i, name := range spec.Names {
+					if !name.IsExported() || i >= len(values) {
+						continue
+					}
+					e.Consts = append(e.Consts, qual+"."+name.Name)
+					v, ok := constValue(values[i], iota)
+					known = known && ok
+					e.Values = append(e.Values, v)
+				}
+			}
+		}
+	}
+	if len(e.Consts) == 0 {
+		return nil
+	}
+	if !known {
+		e.Values = nil
+	}
+	return e
+}
+
+// constValue evaluates the constant expression x at the position iota of
+// its declaration, returning its value as a Go literal. Expressions
+// referring to other constants can't be evaluated.
+func constValue(x ast.Expr, iota int) (string, bool) {
+	pkg := types.NewPackage("enum", "enum")
+	pkg.Scope().Insert(types.NewConst(token.NoPos, pkg, "iota", types.Typ[types.UntypedInt], constant.MakeInt64(int64(iota))))
+	tv, err := types.Eval(token.NewFileSet(), pkg, token.NoPos, types.ExprString(x))
+	if err != nil || tv.Value == nil {
+		return "", false
+	}
+	return tv.Value.ExactString(), true
+}
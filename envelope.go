@@ -0,0 +1,54 @@
+package main
+
+import "gopkg.in/yaml.v2"
+
+// envelopeContent returns the JSON content of successful responses, and
+// their example, wrapped in the data property of the envelope of
+// -response-envelope. Raw bytes aren't wrapped.
+func envelopeContent(content yaml.MapSlice, example string) (yaml.MapSlice, string) {
+	if content[0].Key != "application/json" {
+		return content, example
+	}
+	media := content[0].Value.(yaml.MapSlice)
+	schema := yaml.MapSlice{
+		{Key: "type", Value: "object"},
+		{Key: "properties", Value: yaml.MapSlice{{Key: "data", Value: media[0].Value}}},
+		{Key: "required", Value: []string{"data"}},
+	}
+	if example != "" {
+		example = `{"data": ` + example + `}`
+	}
+	return yaml.MapSlice{{Key: "application/json", Value: yaml.MapSlice{{Key: "schema", Value: schema}}}}, example
+}
+
+// envelopeError is the JSON content of the error responses with
+// -response-envelope.
+var envelopeError = yaml.MapSlice{{Key: "application/json", Value: yaml.MapSlice{
+	{Key: "schema", Value: yaml.MapSlice{
+		{Key: "type", Value: "object"},
+		{Key: "properties", Value: yaml.MapSlice{{Key: "error", Value: yaml.MapSlice{{Key: "type", Value: "string"}}}}},
+		{Key: "required", Value: []string{"error"}},
+	}},
+}}}
+
+const envelopeTemplate = `
+{{ define "envelope" }}
+// Envelope is the body of every JSON response: Data holds the response of a
+// successful call and Error the message of the error of a failed one, as
+// encoded by EncodeResponse and EncodeError.
+type Envelope struct {
+	Data  interface{} ` + "`json:\"data,omitempty\"`" + `
+	Error string      ` + "`json:\"error,omitempty\"`" + `
+}
+
+// Failed implements endpoint.Failer, returning the error of a failed call.
+func (e Envelope) Failed() error {
+	if e.Error == "" {
+		return nil
+	}
+	return errors.New(e.Error)
+}
+
+var _ endpoint.Failer = Envelope{}
+{{ end }}
+`
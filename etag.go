@@ -0,0 +1,117 @@
+package main
+
+import (
+	"go/types"
+	"strings"
+)
+
+// ETag describes how the ETag of a response is derived: from Field of the
+// Result returned by the method, as declared by a "//kit:etag <Field>"
+// annotation.
+type ETag struct {
+	Result Param
+	Field  string
+}
+
+// Nillable reports whether the result has to be checked for nil before its
+// field can be read.
+func (e ETag) Nillable() bool {
+	return strings.HasPrefix(e.Result.Type, "*")
+}
+
+// IfMatch describes how the current ETag for a write method is obtained, as
+// declared by a "//kit:ifmatch <GetMethod>" annotation.
+type IfMatch struct {
+	Get     string // name of the method reporting the current version
+	Args    string // arguments passed to Get, taken from the write request
+	Results string // variables assigned from the results of Get
+	HasErr  bool   // whether Get returns an error
+	UsesReq bool   // whether Args refers to the write request
+	ETag    ETag
+}
+
+// linkETags resolves the etag and ifmatch annotations of fns. Every ifmatch
+// method must refer to a method with an etag annotation whose parameters can
+// all be taken from the ifmatch method's own parameters (matched by name and type).
+func linkETags(fns []Func) error {
+	byName := map[string]*Func{}
+	for i := range fns {
+		fn := &fns[i]
+		byName[fn.Name] = fn
+		a, ok := fn.Annotation("etag")
+		if !ok {
+			continue
+		}
+		if len(a.Args) != 1 {
+			return fn.errorf(a, "kit:etag takes exactly one field name")
+		}
+		res := FilterError(fn.Res)
+		if len(res) == 0 {
+			return fn.errorf(a, "kit:etag requires a non-error result")
+		}
+		if typ := res[0].typ; typ != nil {
+			obj, _, _ := types.LookupFieldOrMethod(typ, true, nil, a.Args[0])
+			if _, ok := obj.(*types.Var); !ok {
+				return fn.errorf(a, "kit:etag: %s has no field %s", res[0].Type, a.Args[0])
+			}
+		}
+		fn.ETag = &ETag{Result: res[0], Field: a.Args[0]}
+	}
+
+	for i := range fns {
+		fn := &fns[i]
+		a, ok := fn.Annotation("ifmatch")
+		if !ok {
+			continue
+		}
+		if len(a.Args) != 1 {
+			return fn.errorf(a, "kit:ifmatch takes exactly one method name")
+		}
+		get, ok := byName[a.Args[0]]
+		if !ok || get.ETag == nil {
+			return fn.errorf(a, "kit:ifmatch %s: no such method with a kit:etag annotation", a.Args[0])
+		}
+
+		im := &IfMatch{Get: get.Name, ETag: *get.ETag}
+		var args []string
+		for _, p := range get.Params {
+			switch {
+			case p.Type == "context.Context":
+				args = append(args, "ctx")
+			case IsOptionSetter(p.Type):
+				// leave the options of the read method at their defaults
+			case findParam(fn.Params, p) != nil:
+				args = append(args, "req."+findParam(fn.Params, p).Field)
+				im.UsesReq = true
+			default:
+				return fn.errorf(a, "kit:ifmatch %s: parameter %s %s not found", get.Name, p.Name, p.Type)
+			}
+		}
+		var results []string
+		for _, r := range get.Res {
+			switch {
+			case r.Name == im.ETag.Result.Name:
+				results = append(results, r.Name)
+			case r.Type == "error":
+				results = append(results, "err")
+				im.HasErr = true
+			default:
+				results = append(results, "_")
+			}
+		}
+		im.Args = strings.Join(args, ", ")
+		im.Results = strings.Join(results, ", ")
+		fn.IfMatch = im
+	}
+	return nil
+}
+
+// findParam returns the parameter in params with the name and type of p, if any.
+func findParam(params []Param, p Param) *Param {
+	for i, q := range params {
+		if q.Name == p.Name && q.Type == p.Type {
+			return &params[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// loadExamples sets the example requests and responses of the methods of
+// fns from the files <Method>.request.json and <Method>.response.json of
+// dir, see -examples. They are validated against the spec by checkExamples.
+func loadExamples(dir string, fns []Func) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("-examples: %v", err)
+	}
+	byName := map[string]*Func{}
+	for i := range fns {
+		if !fns[i].Skip {
+			byName[fns[i].Name] = &fns[i]
+		}
+	}
+	var errs errorList
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || filepath.Ext(name) != ".json" {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		method, kind := strings.TrimSuffix(name, ".json"), ""
+		if i := strings.LastIndex(method, "."); i >= 0 {
+			method, kind = method[:i], method[i+1:]
+		}
+		fn := byName[method]
+		switch {
+		case kind != "request" && kind != "response":
+			errs = append(errs, fmt.Errorf("-examples: %s: want <Method>.request.json or <Method>.response.json", path))
+			continue
+		case fn == nil:
+			errs = append(errs, fmt.Errorf("-examples: %s: no method %s", path, method))
+			continue
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("-examples: %v", err)
+		}
+		if !json.Valid(data) {
+			errs = append(errs, fmt.Errorf("-examples: %s: invalid JSON", path))
+			continue
+		}
+		if kind == "request" {
+			fn.RequestExample = string(bytes.TrimSpace(data))
+		} else {
+			fn.ResponseExample = string(bytes.TrimSpace(data))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// UsesExamples reports whether any method has an example request or
+// response.
+func (s Service) UsesExamples() bool {
+	for _, f := range s.Funcs {
+		if f.RequestExample != "" || f.ResponseExample != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkExamples validates the examples of the methods of svc against the
+// schemas of their requests and responses in spec, reporting every
+// mismatch, e.g. an unknown property or a string for a number.
+func checkExamples(svc Service, spec *Spec) error {
+	var errs errorList
+	for _, f := range svc.Funcs {
+		if f.ResponseExample != "" && f.RawBytes() {
+			errs = append(errs, fmt.Errorf("-examples: %s: %s responds with raw bytes, which take no example", filepath.Join(*flagExamples, f.Name+".response.json"), f.Name))
+			continue
+		}
+		for _, ex := range []struct {
+			kind, example string
+			schema        *Schema
+		}{
+			{"request", f.RequestExample, &Schema{Type: "object", Ref: f.RequestName}},
+			{"response", f.ResponseExample, responseSchema(f, spec)},
+		} {
+			if ex.example == "" {
+				continue
+			}
+			for _, msg := range spec.check(ex.schema, decodeExample(ex.example), "") {
+				errs = append(errs, fmt.Errorf("-examples: %s: %s", filepath.Join(*flagExamples, f.Name+"."+ex.kind+".json"), msg))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// responseSchema returns the schema of the successful responses of f: its
+// response component, or the schema of its result if it's unwrapped.
+func responseSchema(f Func, spec *Spec) *Schema {
+	if f.Unwrap {
+		return spec.byName[f.ResponseName].Properties[0].Schema
+	}
+	return &Schema{Type: "object", Ref: f.ResponseName}
+}
+
+// check returns the mismatches of v, a value decoded by decodeExample, with
+// schema, prefixed by the path of the value, e.g. user.Tags.
+func (s *Spec) check(schema *Schema, v interface{}, path string) []string {
+	mismatch := func(want string) []string {
+		msg := fmt.Sprintf("%s, want %s", describeValue(v), want)
+		if path != "" {
+			msg = path + ": " + msg
+		}
+		return []string{msg}
+	}
+	if schema == nil {
+		return nil
+	}
+	if v == nil {
+		// nil pointers, slices and maps are encoded as null
+		if schema.Nullable || schema.Type == "" || schema.Type == "array" || schema.Type == "object" && schema.Ref == "" {
+			return nil
+		}
+		return mismatch(schema.Type)
+	}
+	var ok bool
+	switch schema.Type {
+	case "":
+		return nil
+	case "string":
+		_, ok = v.(string)
+	case "integer":
+		switch v.(type) {
+		case int64, uint64:
+			ok = true
+		}
+	case "number":
+		switch v.(type) {
+		case int64, uint64, float64:
+			ok = true
+		}
+	case "boolean":
+		_, ok = v.(bool)
+	case "array":
+		items, ok := v.([]interface{})
+		if !ok {
+			return mismatch("array")
+		}
+		var msgs []string
+		for i, item := range items {
+			msgs = append(msgs, s.check(schema.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return msgs
+	case "object":
+		obj, ok := v.(yaml.MapSlice)
+		if !ok {
+			return mismatch("object")
+		}
+		if schema.Ref == "" {
+			var msgs []string
+			for _, item := range obj {
+				msgs = append(msgs, s.check(schema.Items, item.Value, propertyPath(path, item.Key.(string)))...)
+			}
+			return msgs
+		}
+		if c := s.byName[schema.Ref]; c != nil {
+			return s.checkObject(c, obj, path)
+		}
+		return nil
+	}
+	if !ok {
+		return mismatch(schema.Type)
+	}
+	if len(schema.Enum) > 0 {
+		var values []string
+		for _, e := range schema.Enum {
+			if literalValue(e) == v {
+				return nil
+			}
+			values = append(values, e)
+		}
+		return mismatch("one of " + strings.Join(values, ", "))
+	}
+	return nil
+}
+
+// checkObject returns the mismatches of obj with the component c: unknown,
+// missing and mismatched properties.
+func (s *Spec) checkObject(c *Component, obj yaml.MapSlice, path string) []string {
+	if len(c.OneOf) > 0 {
+		typ, _ := lookupExample(obj, c.Discriminator).(string)
+		var names []string
+		for _, v := range c.OneOf {
+			if v.Name == typ {
+				return s.checkObject(s.byName[v.Schema.Ref], obj, path)
+			}
+			names = append(names, strconv.Quote(v.Name))
+		}
+		return []string{fmt.Sprintf("%s: %q, want one of %s", propertyPath(path, c.Discriminator), typ, strings.Join(names, ", "))}
+	}
+	props := c.Properties
+	if base := s.byName[c.AllOf]; base != nil {
+		props = append(append([]Property(nil), base.Properties...), props...)
+	}
+	byName := map[string]Property{}
+	for _, p := range props {
+		byName[p.Name] = p
+	}
+	var msgs []string
+	for _, item := range obj {
+		name := item.Key.(string)
+		p, ok := byName[name]
+		if !ok {
+			msgs = append(msgs, propertyPath(path, name)+": unknown property")
+			continue
+		}
+		msgs = append(msgs, s.check(p.Schema, item.Value, propertyPath(path, name))...)
+	}
+	for _, p := range props {
+		if !p.Optional && !hasKey(obj, p.Name) {
+			msgs = append(msgs, propertyPath(path, p.Name)+": missing")
+		}
+	}
+	return msgs
+}
+
+// propertyPath returns the path of the property name of the object at path.
+func propertyPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// describeValue returns the JSON type of v, a value decoded by
+// decodeExample, e.g. "string "x"" or "null".
+func describeValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string " + strconv.Quote(v)
+	case int64, uint64, float64:
+		return fmt.Sprintf("number %v", v)
+	case bool:
+		return fmt.Sprintf("boolean %v", v)
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
+
+// lookupExample returns the value of the property name of obj, or nil.
+func lookupExample(obj yaml.MapSlice, name string) interface{} {
+	for _, item := range obj {
+		if item.Key == name {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+// hasKey reports whether obj has the property name, maybe null.
+func hasKey(obj yaml.MapSlice, name string) bool {
+	for _, item := range obj {
+		if item.Key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeExample decodes the valid JSON example, keeping the order of the
+// properties of its objects: objects as yaml.MapSlice, arrays as
+// []interface{} and numbers as int64, uint64 or float64.
+func decodeExample(example string) interface{} {
+	dec := json.NewDecoder(strings.NewReader(example))
+	dec.UseNumber()
+	v, _ := decodeValue(dec)
+	return v
+}
+
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		switch tok {
+		case '{':
+			obj := yaml.MapSlice{}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				v, err := decodeValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				obj = append(obj, yaml.MapItem{Key: key, Value: v})
+			}
+			_, err := dec.Token()
+			return obj, err
+		case '[':
+			items := []interface{}{}
+			for dec.More() {
+				v, err := decodeValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, v)
+			}
+			_, err := dec.Token()
+			return items, err
+		}
+	case json.Number:
+		if i, err := strconv.ParseInt(string(tok), 10, 64); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(string(tok), 10, 64); err == nil {
+			return u, nil
+		}
+		f, err := tok.Float64()
+		return f, err
+	}
+	return tok, nil
+}
+
+// StubExample returns the example response of f as the JSON object the stub
+// server decodes its results from, with the JSON names of the response, or
+// "" if it has none or its results can't be decoded from it: converted
+// results, unions and kit:response types.
+func StubExample(f Func) string {
+	if f.ResponseExample == "" || f.ResponseType != nil {
+		return ""
+	}
+	for _, r := range FilterError(f.Res) {
+		if r.DTOType != "" || r.Union != nil {
+			return ""
+		}
+	}
+	example := f.ResponseExample
+	if f.Unwrap {
+		example = `{"` + f.UnwrappedResult().JSONField() + `": ` + example + `}`
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(example)); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// StubExamples reports whether the stub server decodes the results of any
+// method from its example response.
+func (s Service) StubExamples() bool {
+	for _, f := range s.Funcs {
+		if StubExample(f) != "" {
+			return true
+		}
+	}
+	return false
+}
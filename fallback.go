@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// fallbackImports are the imports required by the fallbacks of the clients.
+var fallbackImports = []string{"context", "encoding/json", "errors", "github.com/go-kit/kit/endpoint", "github.com/go-kit/kit/metrics", "github.com/go-kit/kit/metrics/discard"}
+
+// resolveFallbacks sets the fallback responses of the methods of fns
+// annotated with "//kit:fallback <json>", which the clients answer their
+// calls with in degraded mode, as the JSON of the response of the method.
+func resolveFallbacks(fns []Func) error {
+	for i := range fns {
+		fn := &fns[i]
+		a, ok := fn.Annotation("fallback")
+		if !ok {
+			continue
+		}
+		if len(a.Args) == 0 {
+			return fn.errorf(a, `kit:fallback takes the JSON of the response, e.g. kit:fallback {"users": []}`)
+		}
+		fallback := strings.Join(a.Args, " ")
+		if !json.Valid([]byte(fallback)) {
+			return fn.errorf(a, "kit:fallback: invalid JSON %s", fallback)
+		}
+		fn.Fallback = fallback
+	}
+	return nil
+}
+
+// UsesFallbacks reports whether any method has a fallback response.
+func (s Service) UsesFallbacks() bool {
+	for _, f := range s.Funcs {
+		if f.Fallback != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// FallbackClients returns the constructors of the clients applying the
+// fallbacks, e.g. "NewHTTPClient and NewGRPCClient".
+func (s Service) FallbackClients() string {
+	var clients []string
+	if s.Client {
+		clients = append(clients, "NewHTTPClient")
+	}
+	if s.GRPC && s.EndpointSet {
+		clients = append(clients, "NewGRPCClient")
+	}
+	if s.NATS && s.EndpointSet {
+		clients = append(clients, "NewNATSClient")
+	}
+	if s.AMQP && s.EndpointSet {
+		clients = append(clients, "NewAMQPClient")
+	}
+	if len(clients) > 2 {
+		return strings.Join(clients[:len(clients)-1], ", ") + " and " + clients[len(clients)-1]
+	}
+	return strings.Join(clients, " and ")
+}
+
+// ClientEndpoint returns the expression of the endpoint of f in the client
+// made by the constructor of e, the expression of its bare client endpoint.
+func (s Service) ClientEndpoint(f Func, e string) string {
+	if f.HedgeDelay > 0 {
+		e = "Hedge(" + f.Name + "HedgeDelay)(" + e + ")"
+	}
+	if !s.UsesFallbacks() {
+		return e
+	}
+	fallback := "nil"
+	if f.Fallback != "" {
+		fallback = f.Name + "Fallback"
+	}
+	return "clientEndpoint(\"" + f.Name + "\", " + e + ", " + fallback + ")"
+}
+
+const fallbackTemplate = `
+{{ define "fallback" }}{{ $svc := . }}
+// FallbackCount counts the calls answered with the fallback response of their
+// method, labeled by "method" and "reason": "circuit-open" or "timeout". It
+// discards all observations until set to a real metric.
+var FallbackCount metrics.Counter = discard.NewCounter()
+{{ with .FallbackClients }}
+// ClientBreaker, if set, returns the circuit breaker wrapping the endpoint of
+// method in the clients made by {{ . }}, inside its
+// fallback, e.g.
+//
+//	ClientBreaker = func(method string) endpoint.Middleware {
+//		return circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{Name: method}))
+//	}
+//
+// Set it at init time, before the clients are made, along with
+// CircuitOpenErrors.
+var ClientBreaker func(method string) endpoint.Middleware
+
+// clientEndpoint wraps e, the client endpoint of method, in ClientBreaker, if
+// set, and in the Fallback of method, if it has one.
+func clientEndpoint(method string, e endpoint.Endpoint, fallback func() (interface{}, error)) endpoint.Endpoint {
+	if ClientBreaker != nil {
+		e = ClientBreaker(method)(e)
+	}
+	if fallback != nil {
+		e = Fallback(method, fallback)(e)
+	}
+	return e
+}
+{{ end }}
+// CircuitOpenErrors are the errors returned by the circuit breaker as it is
+// open, which Fallback answers with the fallback response, e.g. those of
+// github.com/sony/gobreaker, as wrapped by go-kit's circuitbreaker.Gobreaker:
+//
+//	CircuitOpenErrors = []error{gobreaker.ErrOpenState, gobreaker.ErrTooManyRequests}
+//
+// or breaker.ErrCircuitOpen of github.com/streadway/handy and
+// hystrix.ErrCircuitOpen of github.com/afex/hystrix-go. Set it at init time,
+// along with ClientBreaker.
+var CircuitOpenErrors []error
+
+// IsCircuitOpen reports whether err is, or wraps, one of CircuitOpenErrors.
+var IsCircuitOpen = func(err error) bool {
+	for _, open := range CircuitOpenErrors {
+		if errors.Is(err, open) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTimeout reports whether err is the error of a call that timed out, which
+// Fallback answers with the fallback response: its context expired{{ if .GRPC }}, the
+// gRPC call failed with DEADLINE_EXCEEDED{{ end }} or a network operation timed out.
+var IsTimeout = func(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}{{ if .GRPC }}
+	if status.Code(err) == codes.DeadlineExceeded {
+		return true
+	}{{ end }}
+	var timeout interface{ Timeout() bool }
+	return errors.As(err, &timeout) && timeout.Timeout()
+}
+
+// Fallback returns an endpoint middleware answering the calls of method
+// failing as the circuit breaker is open or as they timed out with the
+// response returned by fallback, counting them in FallbackCount. Other
+// errors are returned as is.
+func Fallback(method string, fallback func() (interface{}, error)) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			response, err := next(ctx, request)
+			if err == nil {
+				return response, nil
+			}
+			var reason string
+			switch {
+			case IsCircuitOpen(err):
+				reason = "circuit-open"
+			case IsTimeout(err):
+				reason = "timeout"
+			default:
+				return response, err
+			}
+			FallbackCount.With("method", method, "reason", reason).Add(1)
+			return fallback()
+		}
+	}
+}
+{{ range .Funcs }}{{ if .Fallback }}
+// {{ .Name }}Fallback returns the fallback response of {{ .Name }}, declared by its
+// kit:fallback annotation.
+func {{ .Name }}Fallback() (interface{}, error) {
+	var response {{ $svc.Response . }}
+	if err := json.Unmarshal([]byte({{ printf "%q" .Fallback }}), &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+{{ end }}{{ end }}{{ end }}
+`
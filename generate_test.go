@@ -0,0 +1,2678 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+	"gopkg.in/yaml.v2"
+
+	"github.com/jeroenvand/kitboiler/kitboilertest"
+)
+
+// fixtures is the module declaring the interfaces the tests generate
+// packages for.
+var fixtures = filepath.Join("testdata", "fixtures")
+
+const (
+	userService    = "example.com/fixtures/api.UserService"
+	orderService   = "example.com/fixtures/orders.OrderService"
+	paymentService = "example.com/fixtures/orders.PaymentService"
+	shapeService   = "example.com/fixtures/api.ShapeService"
+	userEvents     = "example.com/fixtures/api.UserEvents"
+	placeService   = "example.com/fixtures/api.PlaceService"
+	accountService = "example.com/fixtures/api.AccountService"
+	blobService    = "example.com/fixtures/api.BlobService"
+	directory      = "example.com/fixtures/api.DirectoryService"
+	queryService   = "example.com/fixtures/api.QueryService"
+	loginService   = "example.com/fixtures/api.LoginService"
+	wordService    = "example.com/fixtures/api.WordService"
+	catalogService = "example.com/fixtures/api.CatalogService"
+	inventory      = "example.com/fixtures/inventory.InventoryService"
+)
+
+// TestMain builds the kitboiler command the tests run, as $KITBOILER. It
+// serves as the package driver of TestPackagesDriver instead when
+// $KITBOILER_DRIVER_LOG is set.
+func TestMain(m *testing.M) {
+	if log := os.Getenv("KITBOILER_DRIVER_LOG"); log != "" {
+		if err := packagesDriver(log, os.Args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	dir, err := ioutil.TempDir("", "kitboiler")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	bin := filepath.Join(dir, "kitboiler")
+	if out, err := exec.Command("go", "build", "-o", bin, ".").CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "building kitboiler: %v\n%s", err, out)
+		os.Exit(1)
+	}
+	os.Setenv("KITBOILER", bin)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// packagesDriver answers a GOPACKAGESDRIVER request for patterns with the
+// packages listed by go list and their dependencies, appending the patterns
+// to the file log.
+func packagesDriver(log string, patterns []string) error {
+	var req struct {
+		Mode packages.LoadMode `json:"mode"`
+	}
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(log, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(f, strings.Join(patterns, " "))
+	f.Close()
+
+	cfg := &packages.Config{Mode: req.Mode, Env: append(os.Environ(), "GOPACKAGESDRIVER=off")}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Roots    []string
+		Packages []*packages.Package
+	}
+	for _, p := range pkgs {
+		resp.Roots = append(resp.Roots, p.ID)
+	}
+	packages.Visit(pkgs, nil, func(p *packages.Package) {
+		resp.Packages = append(resp.Packages, p)
+	})
+	return json.NewEncoder(os.Stdout).Encode(resp)
+}
+
+// copyFixtures copies the fixtures to a temporary directory, which the
+// caller removes.
+func copyFixtures(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "kitboiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := copyDir(fixtures, dir); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// generate runs kitboiler with args in dir, a copy of the fixtures, writing
+// the package to dir/endpoints, as example.com/fixtures/endpoints. It
+// returns the generated files, keyed by their slash separated paths
+// relative to it.
+func generate(t *testing.T, dir string, args ...string) map[string]string {
+	t.Helper()
+	kitboiler(t, dir, append([]string{"-o", "endpoints"}, args...)...)
+	out := filepath.Join(dir, "endpoints")
+	files := map[string]string{}
+	err := filepath.Walk(out, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(out, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// kitboiler runs the kitboiler command with args in dir and returns what it
+// writes to stdout.
+func kitboiler(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command(os.Getenv("KITBOILER"), args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("kitboiler %s: %v\n%s", strings.Join(args, " "), err, stderr.Bytes())
+	}
+	return stdout.String()
+}
+
+// kitboilerFails runs kitboiler with args in dir, expecting it to fail,
+// and returns its standard error.
+func kitboilerFails(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command(os.Getenv("KITBOILER"), args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err == nil {
+		t.Fatalf("kitboiler %s succeeded", strings.Join(args, " "))
+	}
+	return stderr.String()
+}
+
+// goTest runs go test with args in dir, a copy of the fixtures. It builds
+// the generated package, and so needs the modules it requires; -short
+// skips it.
+func goTest(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("builds the generated package")
+	}
+	cmd := exec.Command("go", append([]string{"test"}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go test %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+// TestGenerated generates the package of the fixture interface with the
+// flags of a feature and checks that the code has what the feature
+// generates.
+func TestGenerated(t *testing.T) {
+	cases := []struct {
+		name  string
+		flags []string
+		iface string   // userService if empty
+		files []string // files generated, by their paths in the package
+		want  []string // code the package has
+		not   []string // code the package doesn't have
+	}{
+		{
+			name: "handler",
+			want: []string{
+				"func MakeHTTPHandler(svc api.UserService) http.Handler {",
+				`mux.Handle("/get-user", GetUserHTTPJSONHandler(GetUserEndPoint(svc)))`,
+			},
+		},
+		{
+			name:  "options-head",
+			flags: []string{"-options-head"},
+			want: []string{
+				`mux.Handle("/get-user", allowMethods("POST", GetUserHTTPJSONHandler(GetUserEndPoint(svc))))`,
+				"func allowMethods(method string, h http.Handler) http.Handler {",
+			},
+		},
+		{
+			name:  "ratelimit",
+			flags: []string{"-ratelimit", "jwt"},
+			want: []string{
+				"func NewRateLimitHandler(store RateLimitStore, h http.Handler) http.Handler {",
+				"func WithRateLimitClient(r *http.Request, client string) *http.Request {",
+				`return "sub:" + client`,
+			},
+			not: []string{"X-API-Key", "Authorization"},
+		},
+		{
+			name:  "hedge",
+			flags: []string{"-hedge"},
+			want:  []string{"func Hedge(delay time.Duration) endpoint.Middleware {"},
+		},
+		{
+			name:  "client-cache",
+			flags: []string{"-client-cache"},
+			want: []string{
+				"func NewCachingTransport(store CacheStore, next http.RoundTripper) http.RoundTripper {",
+				"func NewMemoryCacheStore() CacheStore {",
+				`{"POST", "/get-user"},`,
+				`{"POST", "/profile"},`,
+			},
+			not: []string{`{"POST", "/update-user"},`},
+		},
+		{
+			name:  "budget",
+			flags: []string{"-budget", "1s"},
+			want: []string{
+				"GetUserBudget = 1 * time.Second",
+				"UpdateUserBudget = 250 * time.Millisecond",
+				`UpdateUserHTTPJSONHandler(ShedOnBudget("UpdateUser", UpdateUserBudget)(UpdateUserIfMatch(svc)(UpdateUserEndPoint(svc))))`,
+				"httptransport.ServerBefore(DeadlineFromHTTPHeader),",
+			},
+		},
+		{
+			name:  "client-budget",
+			flags: []string{"-client"},
+			files: []string{"client.go"},
+			want: []string{
+				"UpdateUserEndpoint: WithBudget(UpdateUserBudget)(UpdateUserHTTPClient(u, options...)),",
+				"GetUserEndpoint: GetUserHTTPClient(u, options...),",
+			},
+		},
+		{
+			name:  "skip-embedded",
+			iface: "example.com/fixtures/store.Store",
+			want: []string{
+				"func CountEndPoint(",
+				"r0 := svc.String() return StringResponse{ R0: r0, }, nil",
+			},
+			not: []string{"func CloseEndPoint("},
+		},
+		{
+			name: "skip",
+			want: []string{"func ProfileEndPoint("},
+			not:  []string{"func PingEndPoint(", "svc.Ping()"},
+		},
+		{
+			name:  "mock",
+			flags: []string{"-mock"},
+			want: []string{
+				"type MockService struct {",
+				"func (m *MockService) GetUser(ctx context.Context, id string)",
+			},
+		},
+		{
+			name:  "stub-server",
+			flags: []string{"-stub-server"},
+			files: []string{"mock.go", "stubserver/fixtures/GetUser.yaml"},
+			want:  []string{"svc.GetUserFunc = func(ctx context.Context, id string) (user *model.User, err error) {"},
+		},
+		{
+			name:  "loadtest",
+			flags: []string{"-loadtest"},
+			files: []string{"loadtest/loadtest.go"},
+			want:  []string{`{Name: "GetUser", Call: httpCall(client, "POST", baseURL+"/get-user", p.GetUser)},`},
+		},
+		{
+			name:  "event",
+			iface: orderService,
+			want: []string{
+				`OrderPlacedEvent = "OrderPlaced"`,
+				"type OrderPlacedPayload struct {",
+				"func (m outboxMiddleware) PlaceOrder(ctx context.Context, item string, quantity int) (id string, err error) {",
+			},
+			not: []string{"func (m outboxMiddleware) CancelOrder("},
+		},
+		{
+			name:  "openapi-constraints",
+			flags: []string{"-openapi-constraints", "specs/users.yaml"},
+			want: []string{
+				"func (r CreateUserRequest) Validate() error {",
+				"type ValidationError struct {",
+			},
+			not: []string{"func (r GetUserRequest) validate() error {"},
+		},
+		{
+			name: "enum",
+			want: []string{"if r.Status != model.StatusActive && r.Status != model.StatusSuspended {"},
+		},
+		{
+			name:  "scalars",
+			flags: []string{"-stub-server", "-scalars", "specs/scalars.yaml"},
+			iface: orderService,
+			want: []string{
+				`# at: "2006-01-02T15:04:05Z" # time.Time`,
+				`# total: "12.34 EUR" # model.Money`,
+			},
+		},
+		{
+			name:  "proto",
+			flags: []string{"-proto"},
+			files: []string{"endpoints.proto"},
+			want: []string{
+				"service UserService {",
+				`optional string name = 2 [json_name = "Name"];`,
+				`optional User user = 1 [json_name = "User"];`,
+			},
+		},
+		{
+			name:  "oneof",
+			flags: []string{"-openapi", "-proto"},
+			iface: shapeService,
+			files: []string{"openapi.yaml", "endpoints.proto"},
+			want: []string{
+				"type GetShapeShape struct { Value model.Shape }",
+				"Shape: GetShapeShape{Value: shape},",
+				"discriminator: propertyName: type mapping: Circle: '#/components/schemas/GetShapeShapeCircle'",
+				`oneof value { Circle circle = 1 [json_name = "Circle"]; Square square = 2 [json_name = "Square"]; }`,
+			},
+		},
+		{
+			name:  "metrics",
+			flags: []string{"-metrics"},
+			want: []string{
+				`mux.Handle("/get-user", instrumentHTTP("GetUser", GetUserHTTPJSONHandler(GetUserEndPoint(svc))))`,
+				"ResponseSize metrics.Histogram = discard.NewHistogram()",
+			},
+		},
+		{
+			name:  "slo",
+			flags: []string{"-slo", "99"},
+			iface: paymentService,
+			files: []string{"slo.rules.yaml"},
+			want: []string{
+				"func UsePrometheusMetrics() {",
+				`mux.Handle("/charge", instrumentHTTP("Charge", ChargeHTTPJSONHandler(ChargeEndPoint(svc))))`,
+			},
+		},
+		{
+			name:  "dto",
+			flags: []string{"-dto", "-openapi-constraints", "specs/users.yaml"},
+			files: []string{"dto/dto.go"},
+			want: []string{
+				"package dto",
+				"func (r CreateUserRequest) Validate() error {",
+				"var request dto.CreateUserRequest",
+				"if err := request.Validate(); err != nil {",
+			},
+		},
+		{
+			name:  "request-type",
+			iface: paymentService,
+			want: []string{
+				`"example.com/fixtures/orders"`,
+				"var request orders.ChargeRequest",
+				"if v, ok := interface{}(request).(interface{ Validate() error }); ok {",
+				"err := svc.Charge(ctx, req.OrderID)",
+			},
+			not: []string{"type ChargeRequest struct"},
+		},
+		{
+			name:  "convert",
+			flags: []string{"-convert"},
+			want: []string{
+				"type UserDTO struct {",
+				"func (d UserDTO) ToDomain() model.User {",
+				"func UserDTOFromDomain(m model.User) UserDTO {",
+				"Users []*UserDTO",
+				"profileDTO := UserDTOFromDomain(profile)",
+			},
+		},
+		{
+			name:  "sensitive",
+			iface: paymentService,
+			want: []string{
+				`return fmt.Sprintf("RefundRequest{Order:%+v Iban:[REDACTED] Holder:[REDACTED]}", r.Order)`,
+				"func (r RefundRequest) LogValue() slog.Value {",
+				`slog.String("Iban", "[REDACTED]"),`,
+			},
+		},
+		{
+			name:  "pii",
+			iface: paymentService,
+			want: []string{
+				"var PersonalData = []PersonalDataField{",
+				`{Method: "Refund", Field: "Holder", Category: "financial"},`,
+			},
+		},
+		{
+			name:  "minimal",
+			flags: []string{"-minimal"},
+			want:  []string{`mux.Handle("/get-user", GetUserHTTPJSONHandler(GetUserEndPoint(svc)))`},
+			not:   []string{"ifMatchContextKey", "ShedOnBudget", "func EncodeGetUserResponse("},
+		},
+		{
+			name:  "preset-production",
+			flags: []string{"-preset", "production"},
+			files: []string{"cmd/user-service/main.go", "cmd/user-service/service.go", "dashboard.json", "slo.rules.yaml"},
+			want: []string{
+				`mux.Handle("/get-user", logHTTP("GetUser", instrumentHTTP("GetUser", recoverHTTP("GetUser", GetUserHTTPJSONHandler(GetUserEndPoint(svc))))))`,
+				`mux.HandleFunc("/healthz", healthz)`,
+			},
+		},
+		{
+			name: "value-result",
+			want: []string{
+				"type ProfileResponse struct { Profile model.User }",
+				"profile, err := svc.Profile(ctx, req.Id)",
+			},
+		},
+		{
+			name:  "nil-result",
+			flags: []string{"-nil-result", "no-content"},
+			want: []string{
+				"if res.User == nil { w.WriteHeader(http.StatusNoContent)",
+				"CreateUserHTTPJSONHandler(e endpoint.Endpoint) http.Handler { return httptransport.NewServer( e, DecodeCreateUserRequest, EncodeCreateUserResponse,",
+			},
+			not: []string{"res.Profile == nil"},
+		},
+		{
+			name:  "route-prefix",
+			flags: []string{"-route-prefix", "/internal/users/", "-health"},
+			want: []string{
+				`mux.Handle("/internal/users/get-user", GetUserHTTPJSONHandler(GetUserEndPoint(svc)))`,
+				`mux.HandleFunc("/healthz", healthz)`,
+			},
+		},
+		{
+			name:  "tenant-host",
+			flags: []string{"-tenant", "host", "-openapi"},
+			files: []string{"openapi.yaml"},
+			want: []string{
+				`routes.Handle("/get-user", GetUserHTTPJSONHandler(GetUserEndPoint(svc)))`,
+				`mux.Handle("/", withTenant(routes))`,
+				"tenant, ok := TenantFromHost(r.Host)",
+			},
+			not: []string{"/t/{tenant}/get-user"},
+		},
+		{
+			name:  "group-scaffold",
+			flags: []string{"-scaffold", "-stub-server"},
+			iface: orderService,
+			files: []string{"cmd/order-service/main.go", "stubserver/main.go"},
+			want: []string{
+				`fs.StringVar(&cfg.AdminAddr, "admin-addr", cfg.AdminAddr,`,
+				"newServer(cfg, cfg.AdminAddr, http.TimeoutHandler(endpoints.MakeAdminHTTPHandler(svc), cfg.HandlerTimeout, \"\")),",
+				`adminAddr = flag.String("admin-addr", ":8081", "listen address of the admin group")`,
+			},
+		},
+		{
+			name:  "admin-scaffold",
+			flags: []string{"-admin", "-scaffold"},
+			files: []string{"cmd/user-service/main.go"},
+			want: []string{
+				`AdminAddr: ":8081",`,
+				`admin.Handle("/switches/", switches)`,
+				"newServer(cfg, cfg.AdminAddr, admin),",
+			},
+		},
+		{
+			name:  "metrics-otel",
+			flags: []string{"-metrics-exporter", "otel", "-scaffold", "-namespace", "users"},
+			files: []string{"cmd/user-service/main.go"},
+			want: []string{
+				"func UseOTelMetrics(meter metric.Meter) error {",
+				`meter.Float64Counter("users.http.requests",`,
+				`endpoints.UseOTelMetrics(otel.Meter("example.com/fixtures/endpoints"))`,
+			},
+			not: []string{"func UsePrometheusMetrics() {"},
+		},
+		{
+			name:  "namespace",
+			flags: []string{"-dashboard", "-namespace", "billing"},
+			iface: paymentService,
+			want: []string{
+				"billing_http_requests_total",
+			},
+			not: []string{"payment_service_http_"},
+		},
+		{
+			name:  "dashboard",
+			flags: []string{"-dashboard"},
+			iface: paymentService,
+			files: []string{"dashboard.json"},
+			want:  []string{"func UsePrometheusMetrics() {"},
+		},
+		{
+			name: "etag",
+			want: []string{
+				"func EncodeGetUserResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {",
+				"func UpdateUserIfMatch(svc api.UserService) endpoint.Middleware {",
+				"httptransport.ServerBefore(ifMatchToContext),",
+				"UpdateUserIfMatch(svc)(UpdateUserEndPoint(svc))",
+			},
+		},
+		{
+			name:  "scope-openapi",
+			flags: []string{"-openapi", "-token-url", "https://auth.example.com/token"},
+			iface: accountService,
+			files: []string{"openapi.yaml", "scopes/scopes.go"},
+			want: []string{
+				"security: - oauth2: - accounts:write - admin",
+				"tokenUrl: https://auth.example.com/token scopes: accounts:read: Required by GetBalance. accounts:write: Required by CloseAccount. admin: Required by CloseAccount.",
+				"RequireScopes(\"CloseAccount\", scopes.AccountsWrite, scopes.Admin)(CloseAccountEndPoint(svc))",
+			},
+		},
+		{
+			name:  "split",
+			flags: []string{"-split"},
+			files: []string{"common.go", "get_user_endpoint.go", "get_user_transport.go", "update_user_endpoint.go"},
+			want:  []string{"func UpdateUserIfMatch(svc api.UserService) endpoint.Middleware {"},
+		},
+		{
+			name:  "grpc",
+			flags: []string{"-transports", "http,grpc"},
+			files: []string{"grpc.go", "pb/endpoints.proto", "pb/doc.go"},
+			want: []string{
+				`option go_package = "example.com/fixtures/endpoints/pb";`,
+				"rpc GetUser(GetUserRequest) returns (GetUserResponse);",
+				"func NewGRPCServer(svc api.UserService) pb.UserServiceServer {",
+				"func (s *grpcServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUserResponse, error) {",
+				"func DecodeGRPCGetUserRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {",
+			},
+		},
+		{
+			name:  "auth-scheme",
+			flags: []string{"-openapi", "-auth-scheme", "jwt"},
+			iface: accountService,
+			want: []string{
+				"description: The token lacks one of the scopes accounts:write, admin.",
+				"security: - bearerAuth: []",
+				"securitySchemes: bearerAuth: type: http scheme: bearer bearerFormat: JWT",
+			},
+			not: []string{"oauth2"},
+		},
+		{
+			name:  "assertions",
+			flags: []string{"-assertions", "-mock", "-endpoint-set"},
+			iface: orderService,
+			files: []string{"assertions.go"},
+			want: []string{
+				"_ orders.OrderService = Endpoints{} // client calling the endpoints",
+				"_ orders.OrderService = txMiddleware{} // transaction middleware",
+				"_ orders.OrderService = (*MockService)(nil) // mock",
+			},
+			not: []string{"var _ orders.OrderService = Endpoints{}"},
+		},
+		{
+			name:  "unwrap-openapi",
+			flags: []string{"-openapi"},
+			iface: blobService,
+			want: []string{
+				"application/octet-stream: schema: type: string format: binary",
+				"application/json: schema: type: integer format: int64",
+			},
+			not: []string{"ReadResponse:", "SizeResponse:", "OwnerResponse:", "type SizeResponse struct"},
+		},
+		{
+			name:  "grpc-client",
+			flags: []string{"-transports", "http,grpc", "-endpoint-set"},
+			files: []string{"grpc_client.go", "grpc_service_config.json"},
+			want: []string{
+				"func GetUserGRPCClient(conn *grpc.ClientConn, options ...grpctransport.ClientOption) endpoint.Endpoint {",
+				`"endpoints.UserService", "GetUser", EncodeGRPCGetUserRequest, DecodeGRPCGetUserResponse, pb.GetUserResponse{},`,
+				"func NewGRPCClient(conn *grpc.ClientConn, options ...grpctransport.ClientOption) Endpoints {",
+				"grpc.WithDefaultServiceConfig(GRPCServiceConfig),",
+			},
+		},
+		{
+			name:  "instrumentation",
+			flags: []string{"-middleware", "logging,instrumentation", "-scaffold"},
+			iface: loginService,
+			files: []string{"cmd/login-service/main.go"},
+			want: []string{
+				"func InstrumentingMiddleware(requestCount metrics.Counter, requestLatency metrics.Histogram) func(api.LoginService) api.LoginService {",
+				`Namespace: "login_service", Subsystem: "service", Name: "requests_total",`,
+				`Namespace: "login_service", Subsystem: "service", Name: "request_duration_seconds",`,
+				`lvs := []string{"method", "Logout", "error", strconv.FormatBool(err != nil)}`,
+				"mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())",
+				"return mw.LoginService.Login(ctx, email, password, remember)",
+				"instrumenting, err := endpoints.NewPrometheusInstrumentingMiddleware(prometheus.DefaultRegisterer)",
+				`mux.Handle("/metrics", promhttp.Handler())`,
+			},
+		},
+		{
+			name:  "renamed-types",
+			flags: []string{"-request-name", "{method}"},
+			iface: wordService,
+			want: []string{
+				"type new_ struct {",
+				"req := request.(error_)",
+				"predeclared identifiers or identifiers of the generated code: new as new_, req as req_.",
+			},
+			not: []string{"type new struct", "type error struct"},
+		},
+		{
+			name:  "tracing",
+			flags: []string{"-tracing", "-scaffold"},
+			iface: loginService,
+			files: []string{"cmd/login-service/main.go"},
+			want: []string{
+				`mux.Handle("/login", traceHTTP(LoginHTTPJSONHandler(TraceEndpoint("Login")(LoginEndPoint(svc)))))`,
+				"ctx, span := tracer.Start(ctx, method, trace.WithSpanKind(trace.SpanKindServer))",
+				"span.RecordError(err) span.SetStatus(otelcodes.Error, err.Error())",
+				"ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))",
+				"otel.SetTextMapPropagator(propagation.TraceContext{})",
+			},
+		},
+		{
+			name:  "json-naming-lower",
+			flags: []string{"-json-naming", "lower", "-router", "chi", "-client"},
+			iface: directory,
+			want: []string{
+				"Limit int `json:\"limit,omitempty\"`",
+				`if v, ok := r.URL.Query()["limit"]; ok {`,
+				`if err := encodeQueryParam(q, "limit", req.Limit); err != nil {`,
+			},
+			not: []string{`Query()["Limit"]`},
+		},
+		{
+			name:  "status-grpc",
+			flags: []string{"-transports", "http,grpc"},
+			iface: inventory,
+			want: []string{
+				"{func(err error) bool { return errors.Is(err, inventory.ErrNotFound) }, 404},",
+				"var target *inventory.ConflictError return errors.As(err, &target) }, 409},",
+				"httptransport.ServerErrorEncoder(EncodeError),",
+				"if sc, ok := errorStatus(err); ok { if c, ok := grpcCodes[sc]; ok {",
+			},
+		},
+		{
+			name:  "nats",
+			flags: []string{"-transports", "http,nats", "-endpoint-set"},
+			files: []string{"nats.go", "nats_client.go"},
+			want: []string{
+				`GetUserNATSSubject = "user-service.get-user"`,
+				"GetUserNATSSubject: natstransport.NewSubscriber( GetUserEndPoint(svc), DecodeNATSGetUserRequest, natstransport.EncodeJSONResponse,",
+				"func SubscribeNATS(nc *nats.Conn, queue string, svc api.UserService) ([]*nats.Subscription, error) {",
+				"func GetUserNATSClient(nc *nats.Conn, options ...natstransport.PublisherOption) endpoint.Endpoint {",
+				"GetUserEndpoint: GetUserNATSClient(nc, options...),",
+			},
+		},
+		{
+			name:  "grpc-web",
+			flags: []string{"-transports", "http,grpc", "-grpc-web", "-scaffold"},
+			files: []string{"grpc.go", "cmd/user-service/main.go"},
+			want: []string{
+				"func MakeGRPCWebHandler(svc api.UserService, rest http.Handler, options ...grpcweb.Option) http.Handler {",
+				"if web.IsGrpcWebRequest(r) || web.IsAcceptableGrpcCorsRequest(r) { web.ServeHTTP(w, r) return } rest.ServeHTTP(w, r)",
+				"http.TimeoutHandler(endpoints.MakeGRPCWebHandler(svc, endpoints.MakeHTTPHandler(svc)), cfg.HandlerTimeout, \"\")",
+			},
+		},
+		{
+			name:  "grpc-internal",
+			flags: []string{"-preset", "grpc-internal"},
+			files: []string{"endpoints.go", "cmd/user-service/main.go"},
+			want: []string{
+				"healthpb.RegisterHealthServer(server, healthServer)",
+				"registrar, err := newRegistrar(cfg, logger)",
+			},
+			not: []string{"func MakeHTTPHandler(", "func EncodeResponse(", `httptransport "github.com/go-kit/kit/transport/http"`},
+		},
+		{
+			name: "doc",
+			want: []string{
+				"// GetUserHTTPJSONHandler serves POST /get-user with the endpoint of // api.UserService.GetUser, documented as: // // GetUser returns the user with the given id. func GetUserHTTPJSONHandler(",
+				"// UpdateUserHTTPJSONHandler serves POST /update-user with the endpoint of // api.UserService.UpdateUser. func UpdateUserHTTPJSONHandler(",
+			},
+			not: []string{"// kit:etag", "//kit:etag"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			iface := c.iface
+			if iface == "" {
+				iface = userService
+			}
+			dir := copyFixtures(t)
+			defer os.RemoveAll(dir)
+			files := generate(t, dir, append(c.flags, iface)...)
+			var names []string
+			for name := range files {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range c.files {
+				if _, ok := files[name]; !ok {
+					t.Errorf("%s isn't generated", name)
+				}
+			}
+			var code string
+			for _, name := range names {
+				code += fields(files[name]) + " "
+			}
+			for _, want := range c.want {
+				if !strings.Contains(code, fields(want)) {
+					t.Errorf("the package has no %s", want)
+				}
+			}
+			for _, not := range c.not {
+				if strings.Contains(code, fields(not)) {
+					t.Errorf("the package has %s", not)
+				}
+			}
+		})
+	}
+}
+
+// TestGolden checks the packages generated for the fixture interfaces
+// against testdata/golden, with kitboilertest. Run it with
+// -update-kitboiler-golden to record them after changing the templates.
+func TestGolden(t *testing.T) {
+	cases := []kitboilertest.Case{
+		{Name: "default"},
+		{Name: "minimal", Flags: []string{"-minimal"}},
+		{Name: "production", Flags: []string{"-preset", "production"}},
+		{Name: "client", Flags: []string{"-client", "-endpoint-set", "-mock"}},
+		{Name: "split", Flags: []string{"-split"}},
+		{Name: "specs", Flags: []string{"-openapi", "-proto"}},
+		{Name: "dto", Flags: []string{"-dto"}},
+		{Name: "hooks", Flags: []string{"-hooks", "-response-envelope"}},
+		{Name: "transports", Flags: []string{"-transports", "http,grpc,nats,amqp", "-endpoint-set"}},
+		{Name: "compression", Flags: []string{"-transports", "http,grpc,nats,amqp", "-compression", "zstd"}},
+		{Name: "dead-letter", Flags: []string{"-transports", "http,nats,amqp", "-dead-letter", "-metrics-exporter", "prometheus"}},
+		{Name: "grpc-web", Flags: []string{"-transports", "http,grpc", "-grpc-web", "-scaffold"}},
+		{Name: "grpc-internal", Flags: []string{"-preset", "grpc-internal"}},
+		{Name: "idents", Iface: wordService, Flags: []string{"-mock", "-endpoint-set", "-client"}},
+	}
+	for i := range cases {
+		if cases[i].Iface == "" {
+			cases[i].Iface = userService
+		}
+		cases[i].Dir = fixtures
+	}
+	kitboilertest.Run(t, cases...)
+}
+
+// fields separates the tokens of code by single spaces, so that it can be
+// searched regardless of its layout.
+func fields(code string) string {
+	return strings.Join(strings.Fields(code), " ")
+}
+
+// TestRateLimit checks that the handler generated with -ratelimit limits
+// every client to its own burst of requests.
+func TestRateLimit(t *testing.T) {
+	testFixture(t, "ratelimit", userService, "-ratelimit", "apikey")
+}
+
+// TestHedge checks that the middleware generated with -hedge answers with
+// the hedged request when the first one is slow, and that kit:hedge takes a
+// valid delay. TestClient checks the client of a method annotated with it.
+func TestHedge(t *testing.T) {
+	testFixture(t, "hedge", userService, "-hedge")
+
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	writeFile(t, filepath.Join(dir, "api", "hedged.go"), `package api
+
+import "context"
+
+type HedgedService interface {
+	//kit:hedge soon
+	Get(ctx context.Context, id string) (n int, err error)
+}
+`)
+	out := kitboilerFails(t, dir, "-o", "endpoints", "example.com/fixtures/api.HedgedService")
+	if want := `Get: kit:hedge: invalid delay "soon"`; !strings.Contains(out, want) {
+		t.Errorf("kitboiler: %s, want %s", out, want)
+	}
+}
+
+// TestClientCache checks that the transport generated with -client-cache
+// serves fresh responses from its cache and revalidates stale ones, for GET
+// requests and the requests of the read methods annotated with kit:etag,
+// keeping the responses to different users apart.
+func TestClientCache(t *testing.T) {
+	testFixture(t, "clientcache", userService, "-client-cache")
+}
+
+// TestBudget checks that the middlewares generated for kit:budget shed the
+// requests whose propagated deadline leaves less than the budget, and that
+// the client of -client propagates the budget of its calls.
+func TestBudget(t *testing.T) {
+	testFixture(t, "budget", userService, "-budget", "1s", "-client")
+}
+
+// TestHarness checks that the test generated with -harness records the
+// responses of the handlers backed by the mock and passes against them.
+func TestHarness(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	generate(t, dir, "-harness", userService)
+	goTest(t, dir, "./endpoints/", "-update-golden")
+	goTest(t, dir, "./endpoints/")
+}
+
+// TestStubServer checks that the command generated with -stub-server
+// responds with the error of a fixture.
+func TestStubServer(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	generate(t, dir, "-stub-server", userService)
+	stubs := filepath.Join(dir, "endpoints", "stubserver")
+	if err := ioutil.WriteFile(filepath.Join(stubs, "fixtures", "GetUser.yaml"), []byte("error: user is gone\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	bin := goBuild(t, dir, "./endpoints/stubserver/")
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	cmd := exec.Command(bin, "-addr", addr, "-fixtures", filepath.Join(stubs, "fixtures"))
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+
+	var resp *http.Response
+	for i := 0; ; i++ {
+		resp, err = http.Post("http://"+addr+"/get-user", "application/json", strings.NewReader("{}"))
+		if err == nil {
+			break
+		}
+		if i == 50 {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusInternalServerError || !strings.Contains(string(body), "user is gone") {
+		t.Errorf("POST /get-user: %s %s, want the error of the fixture", resp.Status, body)
+	}
+}
+
+// TestHotReload checks that the command scaffolded with -hot-reload applies
+// the changes of its configuration file without a restart, and keeps the
+// previous configuration when it is invalid.
+func TestHotReload(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	generate(t, dir, "-hot-reload", "-access-log", "-mock", userService)
+	writeFile(t, filepath.Join(dir, "endpoints", "cmd", "user-service", "service.go"), `package main
+
+import (
+	"github.com/go-kit/kit/log"
+
+	"example.com/fixtures/api"
+	"example.com/fixtures/endpoints"
+)
+
+func newService(cfg Config, logger log.Logger) (api.UserService, error) {
+	return &endpoints.MockService{}, nil
+}
+`)
+	bin := goBuild(t, dir, "./endpoints/cmd/user-service/")
+	config := filepath.Join(dir, "config.json")
+	writeFile(t, config, `{"log_level": "none"}`)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	var stderr syncBuffer
+	cmd := exec.Command(bin, "-addr", addr, "-config-file", config, "-reload-interval", "20ms")
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+	call := func() {
+		t.Helper()
+		for i := 0; ; i++ {
+			resp, err := http.Post("http://"+addr+"/delete-user", "application/json", strings.NewReader(`{"id": "1"}`))
+			if err == nil {
+				resp.Body.Close()
+				return
+			}
+			if i == 50 {
+				t.Fatal(err)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+	waitFor := func(msg string) {
+		t.Helper()
+		for i := 0; !strings.Contains(stderr.String(), msg); i++ {
+			if i == 50 {
+				t.Fatalf("the command didn't log %q:\n%s", msg, stderr.String())
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	call()
+	if strings.Contains(stderr.String(), "/delete-user") {
+		t.Errorf("the request is logged with the log level none:\n%s", stderr.String())
+	}
+	writeFile(t, config, `{"log_level": "info"}`)
+	waitFor("reloaded the configuration")
+	call()
+	waitFor("/delete-user")
+	writeFile(t, config, `{"log_level": "loud"}`)
+	waitFor("keeping the configuration")
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// TestLoadTest checks that the package generated with -loadtest calls the
+// routes and counts the calls that fail.
+func TestLoadTest(t *testing.T) {
+	testFixture(t, "load", userService, "-loadtest", "-mock")
+}
+
+// TestOutbox checks that the outbox generated for kit:event writes the
+// events of successful calls and that its relay publishes them once.
+func TestOutbox(t *testing.T) {
+	testFixture(t, "outbox", orderService, "-mock")
+}
+
+// TestTx checks that the middleware generated for kit:tx commits or rolls
+// back the transactions of the annotated methods, and joins those of their
+// callers.
+func TestTx(t *testing.T) {
+	testFixture(t, "tx", orderService, "-mock")
+}
+
+// TestTenant checks that the handler generated with -tenant path serves
+// the routes under the tenant of the request, which it puts in its context.
+func TestTenant(t *testing.T) {
+	testFixture(t, "tenant", userService, "-tenant", "path", "-mock")
+}
+
+// TestSplit checks that the package generated with -split, a file per
+// method and layer, serves the same requests as a single file.
+func TestSplit(t *testing.T) {
+	testFixture(t, "tenant", userService, "-tenant", "path", "-mock", "-split")
+}
+
+// TestEndpointSet checks that the Endpoints generated with -endpoint-set
+// implement the interface by calling the endpoints, with and without the
+// DTOs of -convert.
+func TestEndpointSet(t *testing.T) {
+	testFixture(t, "endpointset", userService, "-endpoint-set", "-mock")
+	testFixture(t, "endpointset", userService, "-endpoint-set", "-convert", "-mock")
+}
+
+// TestAssertions checks that the assertions of -assertions compile, for a
+// service and for an outbound interface.
+func TestAssertions(t *testing.T) {
+	for _, iface := range []string{orderService, userEvents} {
+		dir := copyFixtures(t)
+		defer os.RemoveAll(dir)
+		files := generate(t, dir, "-assertions", "-mock", iface)
+		if files["assertions.go"] == "" {
+			t.Errorf("%s: assertions.go isn't generated", iface)
+		}
+		goTest(t, dir, "./endpoints")
+	}
+}
+
+// TestUnwrap checks that the handler generated for the methods annotated
+// with kit:unwrap responds with their result rather than a response struct.
+func TestUnwrap(t *testing.T) {
+	testFixture(t, "unwrap", blobService, "-mock")
+}
+
+// TestRoutes checks that the handler generated for the methods annotated
+// with kit:http serves their routes on the routers of -router, decoding
+// their path variables and query parameters, and that routes sharing a path
+// are rejected without a router.
+func TestRoutes(t *testing.T) {
+	for _, router := range []string{"chi", "mux"} {
+		t.Run(router, func(t *testing.T) {
+			testFixture(t, "routes", directory, "-router", router, "-mock")
+		})
+	}
+
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	writeFile(t, filepath.Join(dir, "api", "shared.go"), `package api
+
+import "context"
+
+type SharedService interface {
+	//kit:http GET /users
+	List(ctx context.Context) (n int, err error)
+	//kit:http POST /users
+	Create(ctx context.Context, name string) (err error)
+}
+`)
+	out := kitboilerFails(t, dir, "-o", "endpoints", "example.com/fixtures/api.SharedService")
+	if want := "Create: path /users is taken by List, serving several methods on a path requires -router chi or mux"; !strings.Contains(out, want) {
+		t.Errorf("kitboiler: %s, want %s", out, want)
+	}
+}
+
+// TestGetMethods checks that the methods matching -get-methods are served
+// on GET, decoding their parameters from the query string by their type,
+// and that a pattern matching no method is rejected.
+func TestGetMethods(t *testing.T) {
+	testFixture(t, "query", queryService, "-get-methods", "Find*", "-mock")
+
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	out := kitboilerFails(t, dir, "-o", "endpoints", "-get-methods", "Fetch*", queryService)
+	if want := `-get-methods: no method matches "Fetch*"`; !strings.Contains(out, want) {
+		t.Errorf("kitboiler: %s, want %s", out, want)
+	}
+}
+
+// TestReplay checks that the handler of -replay decodes, validates and
+// replays recorded requests, tracing every step.
+func TestReplay(t *testing.T) {
+	testFixture(t, "replay", userService, "-replay", "-mock")
+}
+
+// TestLoggingMiddleware checks that the middleware of -middleware logging
+// logs calls with their masked parameters, error and duration, sampled and
+// leveled by kit:log and LoggingControls, and that unknown middlewares are
+// rejected.
+func TestLoggingMiddleware(t *testing.T) {
+	testFixture(t, "logging", loginService, "-middleware", "logging", "-mock")
+
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	out := kitboilerFails(t, dir, "-o", "endpoints", "-middleware", "tracing", loginService)
+	if want := `-middleware: unknown middleware "tracing", want logging`; !strings.Contains(out, want) {
+		t.Errorf("kitboiler: %s, want %s", out, want)
+	}
+}
+
+// TestIdents checks that the parameters and results named like predeclared
+// identifiers or the variables of the generated code are renamed in the
+// endpoints, the mock and the middlewares, keeping their fields, and that
+// the HTTP client of a method New is renamed from NewHTTPClient.
+func TestIdents(t *testing.T) {
+	testFixture(t, "words", wordService, "-mock", "-middleware", "logging", "-endpoint-set", "-client")
+}
+
+// TestRoundTrip checks that the results returned by value, like those
+// returned by pointer, go through the generated server and client.
+func TestRoundTrip(t *testing.T) {
+	testFixture(t, "roundtrip", userService, "-client", "-mock")
+}
+
+// TestClient checks that the client generated with -client calls the
+// handler of every method, through its route variables, query parameters
+// and body, hedging the calls of the methods annotated with kit:hedge.
+func TestClient(t *testing.T) {
+	testFixture(t, "client", directory, "-client", "-router", "chi", "-mock")
+}
+
+// TestFallback checks that the client of -client answers the calls of the
+// methods annotated with kit:fallback with their fallback when they time out
+// or their circuit breaker is open, counting them.
+func TestFallback(t *testing.T) {
+	testFixture(t, "fallback", directory, "-client", "-router", "chi", "-mock")
+}
+
+// TestJSONNaming checks that the JSON fields and query parameters of the
+// requests and responses are named by -json-naming, by the handlers and the
+// client alike.
+func TestJSONNaming(t *testing.T) {
+	testFixture(t, "jsonnaming", directory, "-json-naming", "snake", "-client", "-router", "chi", "-mock")
+}
+
+// TestShadow checks that the middleware of -middleware shadow mirrors the
+// calls to the shadow, reporting the mismatches of their results.
+func TestShadow(t *testing.T) {
+	testFixture(t, "shadow", loginService, "-middleware", "shadow", "-mock")
+}
+
+// TestRouting checks that the middleware of -middleware routing dispatches
+// the calls between the two implementations by header and percentage.
+func TestRouting(t *testing.T) {
+	testFixture(t, "routing", loginService, "-middleware", "routing", "-mock")
+}
+
+// TestUnnamed checks that the unnamed results of the methods are named after
+// their types, or by position, with a warning per generated method, given at
+// the embedding of the interface of embedded methods, and that unnamed
+// parameters are reported at their position before generating anything.
+func TestUnnamed(t *testing.T) {
+	testFixture(t, "unnamed", catalogService, "-mock")
+
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	cmd := exec.Command(os.Getenv("KITBOILER"), "-o", "endpoints", catalogService)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("kitboiler: %v\n%s", err, out)
+	}
+	for _, want := range []string{
+		"catalog.go:11:2: warning: Item: naming the fields of its response holding unnamed results user,",
+		"catalog.go:13:2: warning: Stats: naming the fields of its response holding unnamed results r0, r1,",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("kitboiler: %s\nwant %s", out, want)
+		}
+	}
+
+	writeFile(t, filepath.Join(dir, "embedding", "embedding.go"), `package embedding
+
+import (
+	"context"
+	"fmt"
+)
+
+type Service interface {
+	fmt.Stringer
+	Get(ctx context.Context, id string) (name string, err error)
+}
+
+type Included interface {
+	//kit:include
+	fmt.Stringer
+	Get(ctx context.Context, id string) (name string, err error)
+}
+`)
+	for iface, want := range map[string]string{
+		"Service":  "",
+		"Included": "embedding.go:15:2: warning: String of the embedded fmt.Stringer: naming the fields of its response holding unnamed results r0, declare the method in the interface instead to choose\n",
+	} {
+		cmd := exec.Command(os.Getenv("KITBOILER"), "-o", "embeddingendpoints", "example.com/fixtures/embedding."+iface)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil || !strings.HasSuffix(string(out), want) || strings.Count(string(out), "\n") != strings.Count(want, "\n") {
+			t.Errorf("kitboiler embedding.%s: %v\n%s\nwant %q", iface, err, out, want)
+		}
+	}
+
+	writeFile(t, filepath.Join(dir, "typo", "typo.go"), `package typo
+
+import "context"
+
+type Service interface {
+	Get(context.Context, string, int) (err error)
+}
+`)
+	stderr := kitboilerFails(t, dir, "-o", "typoendpoints", "example.com/fixtures/typo.Service")
+	for _, want := range []string{
+		"typo.go:6:23: error: Get: parameter 2 (string) is unnamed: name it, as it names the field of the request holding it",
+		"typo.go:6:31: error: Get: parameter 3 (int) is unnamed",
+	} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("kitboiler: %s\nwant %s", stderr, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "typoendpoints")); !os.IsNotExist(err) {
+		t.Errorf("kitboiler generated code for unnamed parameters: %v", err)
+	}
+}
+
+// TestNATS checks that kit:nats sets the subject of a method, and that
+// invalid subjects, subjects shared by two methods and -tenant are rejected.
+func TestNATS(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	for _, c := range []struct{ annotation, args, want string }{
+		{"//kit:nats users.get", "", `GetNATSSubject = "users.get"`},
+		{"//kit:nats users.*", "", `error: Get: kit:nats: "users.*" isn't a valid subject`},
+		{"//kit:nats service.list", "", "-transports: List and Get are both served on the NATS subject service.list, see kit:nats"},
+		{"", "-tenant host", "-transports: nats can't be combined with -tenant"},
+	} {
+		writeFile(t, filepath.Join(dir, "subject", "subject.go"), `package subject
+
+import "context"
+
+type Service interface {
+	List(ctx context.Context) (ids []string, err error)
+	`+c.annotation+`
+	Get(ctx context.Context, id string) (err error)
+}
+`)
+		args := append([]string{"-o", "subjectendpoints", "-transports", "http,nats"}, strings.Fields(c.args)...)
+		args = append(args, "example.com/fixtures/subject.Service")
+		cmd := exec.Command(os.Getenv("KITBOILER"), args...)
+		cmd.Dir = dir
+		out, _ := cmd.CombinedOutput()
+		if strings.Contains(c.want, "NATSSubject") {
+			b, err := ioutil.ReadFile(filepath.Join(dir, "subjectendpoints", "nats.go"))
+			if err != nil {
+				t.Fatalf("kitboiler %s: %s", c.annotation, out)
+			}
+			out = b
+		}
+		if !strings.Contains(fields(string(out)), fields(c.want)) {
+			t.Errorf("kitboiler %s %s: %s\nwant %s", c.annotation, c.args, out, c.want)
+		}
+	}
+}
+
+// TestAMQP checks that the AMQP subscribers reply to the requests with the
+// responses or errors of the service, and that kit:amqp sets the queue and
+// routing key of a method, rejecting invalid names and shared ones.
+func TestAMQP(t *testing.T) {
+	testFixture(t, "amqpserve", userService, "-transports", "http,amqp", "-mock")
+
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	for _, c := range []struct{ annotation, want string }{
+		{"//kit:amqp queue=users.get key=users.read", `GetAMQPQueue = "users.get" GetAMQPKey = "users.read"`},
+		{"//kit:amqp key=users.read", `GetAMQPQueue = "service.get" GetAMQPKey = "users.read"`},
+		{"//kit:amqp queue=amq.get", "error: Get: kit:amqp: invalid queue=amq.get"},
+		{"//kit:amqp exchange=users", `error: Get: kit:amqp: unknown argument "exchange=users"`},
+		{"//kit:amqp queue=service.list", "-transports: List and Get both consume the AMQP queue service.list, see kit:amqp"},
+		{"//kit:amqp key=service.list", "-transports: List and Get are both bound with the AMQP routing key service.list, see kit:amqp"},
+	} {
+		writeFile(t, filepath.Join(dir, "queue", "queue.go"), `package queue
+
+import "context"
+
+type Service interface {
+	List(ctx context.Context) (ids []string, err error)
+	`+c.annotation+`
+	Get(ctx context.Context, id string) (err error)
+}
+`)
+		cmd := exec.Command(os.Getenv("KITBOILER"), "-o", "queueendpoints", "-transports", "http,amqp", "example.com/fixtures/queue.Service")
+		cmd.Dir = dir
+		out, _ := cmd.CombinedOutput()
+		if strings.Contains(c.want, "AMQPQueue") {
+			b, err := ioutil.ReadFile(filepath.Join(dir, "queueendpoints", "amqp.go"))
+			if err != nil {
+				t.Fatalf("kitboiler %s: %s", c.annotation, out)
+			}
+			out = b
+		}
+		if !strings.Contains(fields(string(out)), fields(c.want)) {
+			t.Errorf("kitboiler %s: %s\nwant %s", c.annotation, out, c.want)
+		}
+	}
+}
+
+// TestCompression checks that the AMQP subscribers of -compression take
+// compressed and uncompressed requests, replying with the codec of the
+// request, and that unknown codecs and transports without payloads to
+// compress are rejected.
+func TestCompression(t *testing.T) {
+	testFixture(t, "compressed", userService, "-transports", "http,amqp", "-compression", "zstd", "-mock")
+
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	for _, c := range []struct {
+		flags []string
+		want  string
+	}{
+		{[]string{"-transports", "http,nats", "-compression", "brotli"}, `-compression: unknown codec "brotli", want gzip, snappy or zstd`},
+		{[]string{"-compression", "gzip"}, "-compression compresses the payloads of the grpc, nats and amqp transports, add one of them to -transports"},
+	} {
+		out := kitboilerFails(t, dir, append(append([]string{"-o", "endpoints"}, c.flags...), userService)...)
+		if !strings.Contains(out, c.want) {
+			t.Errorf("kitboiler %v: %s, want %s", c.flags, out, c.want)
+		}
+	}
+}
+
+// TestDeadLetter checks that the AMQP subscribers of -dead-letter retry the
+// requests failing to be served, and publish those failing after every
+// retry, or to be decoded, to the dead-letter queue, and that -dead-letter
+// without the nats or amqp transport is rejected.
+func TestDeadLetter(t *testing.T) {
+	testFixture(t, "deadletter", userService, "-transports", "http,amqp", "-dead-letter", "-mock")
+
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	out := kitboilerFails(t, dir, "-o", "endpoints", "-dead-letter", userService)
+	if want := "-dead-letter retries the requests of the nats and amqp subscribers, add one of them to -transports"; !strings.Contains(out, want) {
+		t.Errorf("kitboiler: %s, want %s", out, want)
+	}
+}
+
+// TestGRPCWeb checks that -grpc-web without the grpc transport is rejected.
+func TestGRPCWeb(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	out := kitboilerFails(t, dir, "-o", "endpoints", "-grpc-web", userService)
+	if want := "-grpc-web serves the gRPC server to browsers, add grpc to -transports"; !strings.Contains(out, want) {
+		t.Errorf("kitboiler: %s, want %s", out, want)
+	}
+}
+
+// TestGRPCInternal checks that the flags of the HTTP handlers are rejected
+// when -transports leaves http out, as are a scaffolded command serving
+// neither HTTP nor gRPC and -discovery with an unknown registry or without
+// -scaffold.
+func TestGRPCInternal(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	for _, c := range []struct {
+		flags []string
+		want  string
+	}{
+		{[]string{"-transports", "grpc", "-health"}, "-health applies to the HTTP handlers, add http to -transports"},
+		{[]string{"-preset", "grpc-internal", "-openapi"}, "-openapi applies to the HTTP handlers, add http to -transports"},
+		{[]string{"-transports", "nats", "-scaffold"}, "-scaffold serves the HTTP handlers or the gRPC server, add http or grpc to -transports"},
+		{[]string{"-transports", "http,grpc", "-scaffold", "-discovery", "etcd"}, `-discovery: unknown registry "etcd", want consul`},
+		{[]string{"-transports", "http,grpc", "-discovery", "consul"}, "-discovery registers the scaffolded command, add -scaffold"},
+	} {
+		out := kitboilerFails(t, dir, append(append([]string{"-o", "endpoints"}, c.flags...), userService)...)
+		if !strings.Contains(out, c.want) {
+			t.Errorf("kitboiler %v: %s, want %s", c.flags, out, c.want)
+		}
+	}
+}
+
+// TestErrorStatuses checks that the handlers respond to the errors annotated
+// with kit:status, even wrapped, and to those added to ErrorStatuses with
+// their status.
+func TestErrorStatuses(t *testing.T) {
+	testFixture(t, "status", inventory, "-mock")
+}
+
+// TestEnvelope checks that -response-envelope wraps the responses and the
+// errors of the handlers in an envelope, unwrapped by the client.
+func TestEnvelope(t *testing.T) {
+	testFixture(t, "envelope", inventory, "-response-envelope", "-client", "-mock")
+}
+
+// TestScope checks that the handler generated for the methods annotated
+// with kit:scope rejects the requests whose token lacks their scopes.
+func TestScope(t *testing.T) {
+	testFixture(t, "scope", accountService, "-mock")
+}
+
+// TestGroup checks that the methods annotated with kit:group are served by
+// the handler of their group only.
+func TestGroup(t *testing.T) {
+	testFixture(t, "group", orderService, "-mock")
+}
+
+// TestSwitches checks that the admin API generated with -admin turns the
+// rate limiting and the logging of the middleware off at runtime, and
+// resets circuit breakers.
+func TestSwitches(t *testing.T) {
+	testFixture(t, "switches", userService, "-admin", "-ratelimit", "apikey", "-access-log", "-mock")
+}
+
+// TestConstraints checks that the handlers generated with
+// -openapi-constraints reject the requests violating the constraints of the
+// spec, or with a value of an enum type that isn't one of its constants.
+func TestConstraints(t *testing.T) {
+	testFixture(t, "constraints", userService, "-openapi-constraints", "specs/users.yaml", "-mock")
+}
+
+// TestDTO checks that the constraints still apply to the requests when
+// their types are generated into the dto package with -dto.
+func TestDTO(t *testing.T) {
+	testFixture(t, "constraints", userService, "-dto", "-openapi-constraints", "specs/users.yaml", "-mock")
+}
+
+// TestConvert checks that the DTOs and conversions generated with -convert
+// compile.
+func TestConvert(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	generate(t, dir, "-convert", "-mock", userService)
+	goTest(t, dir, "./endpoints")
+}
+
+// TestPackageNames checks that the generated files refer to a package
+// imported under an alias, and named differently from its path, by its name.
+func TestPackageNames(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	files := generate(t, dir, "-convert", "-mock", placeService)
+	for _, want := range []string{"var point *geo.Point", "func (d PointDTO) ToDomain() geo.Point {"} {
+		if !strings.Contains(files["endpoints.go"], want) {
+			t.Errorf("endpoints.go has no %s", want)
+		}
+	}
+	goTest(t, dir, "./endpoints")
+}
+
+// TestNaming checks that the request and response types and their JSON
+// fields are named after -request-name, -response-name and -json-case, and
+// that patterns naming several types the same are rejected.
+func TestNaming(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	files := generate(t, dir, "-request-name", "{method}Input", "-response-name", "{Method}Reply", "-json-case", "snake", "-mock", "-openapi", userService)
+	for _, want := range []string{
+		"type updateUserInput struct { Id string `json:\"id\"` Name string `json:\"name,omitempty\"` Status model.Status `json:\"status\"` }",
+		"type UpdateUserReply struct {",
+	} {
+		if !strings.Contains(fields(files["endpoints.go"]), fields(want)) {
+			t.Errorf("endpoints.go has no %s", want)
+		}
+	}
+	if want := "$ref: '#/components/schemas/UpdateUserReply'"; !strings.Contains(files["openapi.yaml"], want) {
+		t.Errorf("openapi.yaml has no %s", want)
+	}
+	goTest(t, dir, "./endpoints")
+
+	stderr := kitboilerFails(t, dir, "-o", "endpoints", "-request-name", "Request", userService)
+	if want := "-request-name: Request names the types of both CreateUser and GetUser"; !strings.Contains(stderr, want) {
+		t.Errorf("stderr %q, want %q", stderr, want)
+	}
+}
+
+// TestTypeCheck checks that the types of an interface referring to a
+// package imported with a dot, and to an alias, are qualified by the
+// packages declaring them.
+func TestTypeCheck(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	writeFile(t, filepath.Join(dir, "dotted", "dotted.go"), `package dotted
+
+import (
+	"context"
+
+	g "example.com/fixtures/go-geo/v2"
+	. "example.com/fixtures/model"
+)
+
+type Point = g.Point
+
+type Service interface {
+	Get(ctx context.Context, id string) (user *User, err error)
+	Locate(ctx context.Context, at Point) (status Status, err error)
+}
+`)
+	files := generate(t, dir, "-mock", "example.com/fixtures/dotted.Service")
+	for _, want := range []string{"User *model.User", "Status model.Status"} {
+		if !strings.Contains(fields(files["endpoints.go"]), want) {
+			t.Errorf("endpoints.go has no %s", want)
+		}
+	}
+	goTest(t, dir, "./endpoints")
+}
+
+// TestETagField checks that a kit:etag field the result type doesn't have
+// is reported at the annotation.
+func TestETagField(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	writeFile(t, filepath.Join(dir, "etagged", "etagged.go"), `package etagged
+
+import (
+	"context"
+
+	"example.com/fixtures/model"
+)
+
+type Service interface {
+	//kit:etag Revision
+	Get(ctx context.Context, id string) (user *model.User, err error)
+}
+`)
+	stderr := kitboilerFails(t, dir, "-o", "etaggedendpoints", "example.com/fixtures/etagged.Service")
+	if want := "etagged.go:10:4: error: Get: kit:etag: *model.User has no field Revision"; !strings.Contains(stderr, want) {
+		t.Errorf("kitboiler: %s\nwant %s", stderr, want)
+	}
+}
+
+// TestMinimal checks that -minimal rejects the flags adding features.
+func TestMinimal(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	stderr := kitboilerFails(t, dir, "-minimal", "-mock", userService)
+	if want := "-minimal can't be combined with -mock"; !strings.Contains(stderr, want) {
+		t.Errorf("stderr %q, want %q", stderr, want)
+	}
+}
+
+// TestProduction checks that the handlers generated with -recover,
+// -access-log and -health recover panics, log the requests and report the
+// health of the service.
+func TestProduction(t *testing.T) {
+	testFixture(t, "production", userService, "-recover", "-access-log", "-health", "-mock")
+}
+
+// TestHooks checks that the request decoders and response encoders
+// generated with -hooks call the hooks, the handlers apply the
+// ServerOptions and the endpoints their EndpointMiddlewares.
+func TestHooks(t *testing.T) {
+	testFixture(t, "hooks", userService, "-hooks", "-mock", "-endpoint-set")
+}
+
+// TestNilResult checks that the handlers generated with -nil-result
+// not-found respond to nil results with 404 Not Found.
+func TestNilResult(t *testing.T) {
+	testFixture(t, "nilresult", userService, "-nil-result", "not-found", "-mock")
+}
+
+// TestUnion checks that the responses generated for kit:oneof encode the
+// result with its type and decode it back into that type.
+func TestUnion(t *testing.T) {
+	testFixture(t, "union", shapeService, "-mock")
+}
+
+// TestWebhook checks that the dispatcher generated for a kit:outbound
+// interface signs its webhooks, retries failed deliveries and reports
+// those that gave up.
+func TestWebhook(t *testing.T) {
+	testFixture(t, "webhook", userEvents)
+}
+
+// TestRepository checks that the commands scaffolded with -repository
+// declare a repository of the entities of the service and build with
+// either adapter.
+func TestRepository(t *testing.T) {
+	for _, flavor := range []string{"sqlc", "ent"} {
+		t.Run(flavor, func(t *testing.T) {
+			dir := copyFixtures(t)
+			defer os.RemoveAll(dir)
+			files := generate(t, dir, "-repository", flavor, userService)
+			repo := fields(files["cmd/user-service/repository.go"])
+			for _, want := range []string{
+				"GetUser(ctx context.Context, id string) (*model.User, error)",
+				"ListUsers(ctx context.Context, limit, offset int) ([]model.User, error)",
+				"DeleteUser(ctx context.Context, id string) error",
+			} {
+				if !strings.Contains(repo, want) {
+					t.Errorf("the repository has no %s", want)
+				}
+			}
+			if _, ok := files["cmd/user-service/repository_"+flavor+".go"]; !ok {
+				t.Errorf("the %s adapter isn't generated", flavor)
+			}
+			if _, ok := files["cmd/user-service/query.sql"]; ok != (flavor == "sqlc") {
+				t.Errorf("query.sql generated: %v, want it with sqlc only", ok)
+			}
+			goBuild(t, dir, "./endpoints/cmd/user-service")
+		})
+	}
+}
+
+// TestMetrics checks that the handlers generated with -metrics count the
+// requests by status code and the options they set, and observe their
+// sizes, and that unknown exporters are rejected.
+func TestMetrics(t *testing.T) {
+	testFixture(t, "httpmetrics", userService, "-metrics", "-mock")
+
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	out := kitboilerFails(t, dir, "-o", "endpoints", "-metrics-exporter", "statsd", userService)
+	if want := `-metrics-exporter: unknown exporter "statsd", want prometheus or otel`; !strings.Contains(out, want) {
+		t.Errorf("kitboiler -metrics-exporter statsd: %s, want %s", out, want)
+	}
+}
+
+// TestSLORules checks the thresholds of the burn-rate alerts generated with
+// -slo for the objective of the flag and of kit:slo.
+func TestSLORules(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	files := generate(t, dir, "-slo", "99", paymentService)
+	var rules struct {
+		Groups []struct {
+			Rules []struct {
+				Alert  string            `yaml:"alert"`
+				Expr   string            `yaml:"expr"`
+				Labels map[string]string `yaml:"labels"`
+			} `yaml:"rules"`
+		} `yaml:"groups"`
+	}
+	if err := yaml.Unmarshal([]byte(files["slo.rules.yaml"]), &rules); err != nil {
+		t.Fatal(err)
+	}
+	exprs := map[string]string{}
+	for _, g := range rules.Groups {
+		for _, r := range g.Rules {
+			if _, ok := exprs[r.Alert]; !ok && r.Labels["severity"] == "page" {
+				exprs[r.Alert] = r.Expr
+			}
+		}
+	}
+	// the fast burn alert fires at 14.4 times the error budget
+	for alert, threshold := range map[string]string{
+		"ChargeErrorsBudgetBurn": "> 0.0144",
+		"RefundErrorsBudgetBurn": "> 0.144",
+	} {
+		if !strings.Contains(exprs[alert], threshold) {
+			t.Errorf("%s: %q, want a threshold %s", alert, exprs[alert], threshold)
+		}
+	}
+}
+
+// TestDashboard checks that the dashboard generated with -dashboard has a
+// row of panels per method, querying the metrics of the service.
+func TestDashboard(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	files := generate(t, dir, "-dashboard", paymentService)
+	var dashboard struct {
+		Panels []struct {
+			Type    string `json:"type"`
+			Title   string `json:"title"`
+			Targets []struct {
+				Expr string `json:"expr"`
+			} `json:"targets"`
+		} `json:"panels"`
+	}
+	if err := json.Unmarshal([]byte(files["dashboard.json"]), &dashboard); err != nil {
+		t.Fatal(err)
+	}
+	var rows []string
+	for _, p := range dashboard.Panels {
+		if p.Type == "row" {
+			rows = append(rows, p.Title)
+			continue
+		}
+		for _, target := range p.Targets {
+			if !strings.Contains(target.Expr, `payment_service_http_`) {
+				t.Errorf("%s: %q does not query the service metrics", p.Title, target.Expr)
+			}
+		}
+	}
+	if want := []string{"Charge", "Refund"}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows %v, want %v", rows, want)
+	}
+}
+
+// TestInit answers the questions of kitboiler init and checks the config it
+// writes and the package it generates from it.
+func TestInit(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	cmd := exec.Command(os.Getenv("KITBOILER"), "init")
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(strings.Join([]string{
+		"example.com/fixtures/api.NoSuchService", // rejected, asked again
+		userService,
+		"http,smtp",         // rejected, asked again
+		"http,grpc",         // transports
+		"gorilla",           // rejected, asked again
+		"chi",               // router
+		"y",                 // OpenAPI spec
+		"",                  // no proto file
+		"recover,ratelimit", // middleware
+		"apikey",            // rate limit key
+		"",                  // output directory endpoints
+		"api",               // package name
+		"",                  // no dto package
+		"",                  // no scaffold
+	}, "\n") + "\n")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("kitboiler init: %v\n%s", err, out)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "kitboiler.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg struct {
+		Interface string                 `yaml:"interface"`
+		Options   map[string]interface{} `yaml:"options"`
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"transports": "http,grpc", "router": "chi", "openapi": true, "recover": true, "ratelimit": "apikey", "o": "endpoints", "pkg": "api"}
+	if cfg.Interface != userService || !reflect.DeepEqual(cfg.Options, want) {
+		t.Errorf("kitboiler.yaml:\n%s\nwant interface %s and options %v", data, userService, want)
+	}
+	for _, name := range []string{"api.go", "openapi.yaml", "grpc.go"} {
+		if _, err := os.Stat(filepath.Join(dir, "endpoints", name)); err != nil {
+			t.Errorf("kitboiler init didn't generate %s: %v", name, err)
+		}
+	}
+}
+
+// TestProfiles checks that -profile applies the options of a profile of
+// kitboiler.yaml on top of its other options, and rejects unknown ones.
+func TestProfiles(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	writeFile(t, filepath.Join(dir, "kitboiler.yaml"), `interface: `+userService+`
+options:
+  o: endpoints
+  health: true
+profiles:
+  dev:
+    mock: true
+  prod:
+    health: false
+`)
+	for _, c := range []struct {
+		profile string
+		mock    bool
+		health  bool
+	}{
+		{"", false, true},
+		{"dev", true, true},
+		{"prod", false, false},
+	} {
+		if err := os.RemoveAll(filepath.Join(dir, "endpoints")); err != nil {
+			t.Fatal(err)
+		}
+		args := []string{}
+		if c.profile != "" {
+			args = append(args, "-profile", c.profile)
+		}
+		kitboiler(t, dir, args...)
+		_, err := os.Stat(filepath.Join(dir, "endpoints", "mock.go"))
+		if mock := err == nil; mock != c.mock {
+			t.Errorf("-profile %q: mock.go generated %t, want %t", c.profile, mock, c.mock)
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, "endpoints", "endpoints.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if health := strings.Contains(string(data), "/healthz"); health != c.health {
+			t.Errorf("-profile %q: /healthz served %t, want %t", c.profile, health, c.health)
+		}
+	}
+	out := kitboilerFails(t, dir, "-profile", "staging")
+	if want := `-profile: unknown profile "staging", want one of [dev prod]`; !strings.Contains(out, want) {
+		t.Errorf("kitboiler -profile staging: %s, want %s", out, want)
+	}
+}
+
+// TestList checks the routes and annotations listed by kitboiler list.
+func TestList(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	out := fields(kitboiler(t, dir, "list", userService))
+	for _, want := range []string{
+		"GetUser POST /get-user kit:etag Version",
+		"UpdateUser POST /update-user kit:ifmatch GetUser, kit:budget 250ms, kit:optional name",
+		"Ping - kit:skip",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("kitboiler list: %s\nwant %s", out, want)
+		}
+	}
+}
+
+// TestInspect checks the model written by kitboiler inspect, to stdout and
+// to the file of -o.
+func TestInspect(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	type model struct {
+		IFace string
+		Mock  bool
+		Funcs []struct {
+			Name       string
+			HTTPMethod string
+			HTTPPath   string
+			Pos        string
+			Params     []struct {
+				Name, Type, Field string
+			}
+			Annotations []struct {
+				Name string
+				Args []string
+			}
+		}
+	}
+	var m model
+	if err := json.Unmarshal([]byte(kitboiler(t, dir, "inspect", "-mock", userService)), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.IFace != "api.UserService" || !m.Mock {
+		t.Errorf("IFace %s, Mock %t, want api.UserService with -mock", m.IFace, m.Mock)
+	}
+	var names []string
+	for _, f := range m.Funcs {
+		names = append(names, f.Name)
+		if f.Name != "GetUser" {
+			continue
+		}
+		if f.HTTPMethod != "POST" || f.HTTPPath != "/get-user" || !strings.HasSuffix(f.Pos, filepath.Join("api", "api.go")+":16:2") {
+			t.Errorf("GetUser: %s %s at %s, want POST /get-user at api/api.go:16:2", f.HTTPMethod, f.HTTPPath, f.Pos)
+		}
+		if len(f.Params) != 2 || f.Params[1].Name != "id" || f.Params[1].Type != "string" || f.Params[1].Field != "Id" {
+			t.Errorf("GetUser: params %+v, want ctx and id", f.Params)
+		}
+		if len(f.Annotations) != 1 || f.Annotations[0].Name != "etag" || !reflect.DeepEqual(f.Annotations[0].Args, []string{"Version"}) {
+			t.Errorf("GetUser: annotations %+v, want kit:etag Version", f.Annotations)
+		}
+	}
+	if want := []string{"CreateUser", "GetUser", "UpdateUser", "ListUsers", "DeleteUser", "Profile"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("methods %v, want %v", names, want)
+	}
+
+	kitboiler(t, dir, "inspect", "-o", "model.json", userService)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "model.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromFile model
+	if err := json.Unmarshal(data, &fromFile); err != nil {
+		t.Fatal(err)
+	}
+	if len(fromFile.Funcs) != len(m.Funcs) || fromFile.Mock {
+		t.Errorf("model.json has %d methods, Mock %t, want %d without -mock", len(fromFile.Funcs), fromFile.Mock, len(m.Funcs))
+	}
+}
+
+// TestImportOpenAPI checks that kitboiler import derives an interface from
+// an OpenAPI spec that the package is then generated for.
+func TestImportOpenAPI(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	out := kitboiler(t, dir, "import", "-o", "pets", "specs/pets.yaml")
+	if want := "kitboiler example.com/fixtures/pets.PetStore"; !strings.Contains(out, want) {
+		t.Errorf("kitboiler import: %s\nwant %s", out, want)
+	}
+	src, err := ioutil.ReadFile(filepath.Join(dir, "pets", "pet_store.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"// GET /pets/{id} GetPet(ctx context.Context, id string) (pet Pet, err error)",
+		"// POST /pets //kit:optional age AddPet(ctx context.Context, name string, age int64) (id string, err error)",
+		"type Pet struct { Name string `json:\"name\"` Tags []string `json:\"tags,omitempty\"` }",
+	} {
+		if !strings.Contains(fields(string(src)), want) {
+			t.Errorf("imported interface:\n%s\nwant %s", src, want)
+		}
+	}
+	generate(t, dir, "-mock", "example.com/fixtures/pets.PetStore")
+	goTest(t, dir, "./endpoints")
+}
+
+// TestImportProto checks that kitboiler import derives an interface from a
+// .proto service that the package is then generated for.
+func TestImportProto(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	kitboiler(t, dir, "import", "-o", "pets", "specs/pets.proto")
+	src, err := ioutil.ReadFile(filepath.Join(dir, "pets", "pet_store.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"GetPet(ctx context.Context, id string) (pet *Pet, err error)",
+		"//kit:optional owner ListPets(ctx context.Context, owner string, opts ...ListOptionsSetter) (pets []Pet, err error)",
+		"Ping(ctx context.Context) (err error)",
+		`KindCat Kind = "KIND_CAT"`,
+		"Born time.Time `json:\"born\"`",
+		"type ListOptionsSetter func(*ListOptions)",
+	} {
+		if !strings.Contains(fields(string(src)), want) {
+			t.Errorf("imported interface:\n%s\nwant %s", src, want)
+		}
+	}
+	generate(t, dir, "example.com/fixtures/pets.PetStore")
+	goTest(t, dir, "./endpoints")
+}
+
+// TestDemo checks that the project written by kitboiler demo builds and
+// passes its tests.
+func TestDemo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kitboiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	kitboiler(t, dir, "demo", "todo")
+	for _, name := range []string{"api/api.go", "endpoints/cmd/todo-service/service.go", "endpoints/openapi.yaml", "endpoints/harness_test.go"} {
+		if _, err := os.Stat(filepath.Join(dir, "todo", name)); err != nil {
+			t.Error(err)
+		}
+	}
+	project := filepath.Join(dir, "todo")
+	goTest(t, project, "./endpoints", "-update-golden")
+	goTest(t, project, "./...")
+}
+
+// TestVet checks that kitboiler vet reports misspelt annotations.
+func TestVet(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	kitboiler(t, dir, "vet", userService)
+	writeFile(t, filepath.Join(dir, "typo", "typo.go"), `package typo
+
+import "context"
+
+type Service interface {
+	//kit:etga Version
+	Get(ctx context.Context, id string) (version string, err error)
+}
+`)
+	stderr := kitboilerFails(t, dir, "vet", "example.com/fixtures/typo.Service")
+	if want := "typo.go:6:4: error: Get: unknown annotation kit:etga"; !strings.Contains(stderr, want) {
+		t.Errorf("kitboiler vet: %q, want %q", stderr, want)
+	}
+}
+
+// TestDiagnostics checks that an invalid annotation is reported at its
+// position, with the source line and a caret pointing at it.
+func TestDiagnostics(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	writeFile(t, filepath.Join(dir, "typo", "typo.go"), `package typo
+
+import "context"
+
+type Service interface {
+	Get(ctx context.Context, id string) (version string, err error)
+	//kit:budget soon
+	Put(ctx context.Context, id string) (err error)
+}
+`)
+	stderr := kitboilerFails(t, dir, "example.com/fixtures/typo.Service")
+	want := filepath.Join(dir, "typo", "typo.go") + `:7:4: error: Put: kit:budget: invalid duration "soon"
+		//kit:budget soon
+		  ^
+`
+	if stderr != want {
+		t.Errorf("stderr:\n%s\nwant:\n%s", stderr, want)
+	}
+
+	stderr = kitboilerFails(t, dir, "example.com/fixtures/typo.Nope")
+	if want := "error: interface example.com/fixtures/typo.Nope not found"; !strings.HasPrefix(stderr, want) {
+		t.Errorf("stderr %q, want %q", stderr, want)
+	}
+}
+
+// TestGOOS checks that an interface declared in a file constrained to
+// another platform is found with -goos, and that the error without it
+// suggests it.
+func TestGOOS(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	writeFile(t, filepath.Join(dir, "platform", "doc.go"), "package platform\n")
+	writeFile(t, filepath.Join(dir, "platform", "service_plan9.go"), `package platform
+
+import "context"
+
+type Service interface {
+	Get(ctx context.Context, id string) (version string, err error)
+}
+`)
+	stderr := kitboilerFails(t, dir, "example.com/fixtures/platform.Service")
+	if want := "files constrained to other platforms than"; !strings.Contains(stderr, want) {
+		t.Errorf("stderr %q, want %q", stderr, want)
+	}
+	files := generate(t, dir, "-goos", "plan9", "example.com/fixtures/platform.Service")
+	if want := "func GetEndPoint(svc platform.Service) endpoint.Endpoint {"; !strings.Contains(files["endpoints.go"], want) {
+		t.Errorf("endpoints.go has no %s", want)
+	}
+}
+
+// TestGeneratedError checks that invalid generated code is reported with
+// its template and the numbered lines around the syntax error.
+func TestGeneratedError(t *testing.T) {
+	src := []byte("package endpoints\n\nfunc f() {\n\treturn 1 +\n}\n")
+	_, err := format.Source(src)
+	if err == nil {
+		t.Fatal("the invalid code is formatted")
+	}
+	var buf bytes.Buffer
+	printError(&buf, generatedError("server", src, err), false)
+	want := `error: template server generated invalid Go code, line 5:1: expected operand, found '}' (pass -allow-invalid to write it unformatted)
+    3 | func f() {
+    4 | 	return 1 +
+    5 | }
+      | ^
+    6 | 
+`
+	if buf.String() != want {
+		t.Errorf("printError:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+// TestDiff checks that kitboiler diff fails once the generated files are
+// edited.
+func TestDiff(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	files := generate(t, dir, userService)
+	kitboiler(t, dir, "diff", "-o", "endpoints", userService)
+
+	edited := strings.Replace(files["endpoints.go"], "func MakeHTTPHandler(", "func makeHTTPHandler(", 1)
+	if err := ioutil.WriteFile(filepath.Join(dir, "endpoints", "endpoints.go"), []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(os.Getenv("KITBOILER"), "diff", "-o", "endpoints", userService)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err == nil {
+		t.Fatal("kitboiler diff succeeded after the edit")
+	}
+	for _, want := range []string{"-func makeHTTPHandler(", "+func MakeHTTPHandler("} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("kitboiler diff:\n%s\nwant %s", out, want)
+		}
+	}
+}
+
+// TestPlan checks that kitboiler apply writes the files planned by
+// kitboiler plan, unless they changed since.
+func TestPlan(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	readPlan := func() map[string]PlanFile {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "kitboiler.plan.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var plan Plan
+		if err := json.Unmarshal(data, &plan); err != nil {
+			t.Fatal(err)
+		}
+		files := map[string]PlanFile{}
+		for _, f := range plan.Files {
+			files[f.Name] = f
+		}
+		return files
+	}
+
+	kitboiler(t, dir, "plan", "-o", "endpoints", "-mock", userService)
+	if _, err := os.Stat(filepath.Join(dir, "endpoints")); !os.IsNotExist(err) {
+		t.Fatalf("plan wrote the package: %v", err)
+	}
+	planned := readPlan()
+	if f := planned["endpoints.go"]; f.Action != "create" || !contains(f.Symbols, "MakeHTTPHandler") || !contains(f.Symbols, "UpdateUserRequest.Validate") {
+		t.Errorf("endpoints.go planned to %s with symbols %v, want to create MakeHTTPHandler", f.Action, f.Symbols)
+	}
+	kitboiler(t, dir, "apply")
+	applied := map[string]string{}
+	for name, f := range planned {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "endpoints", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		applied[name] = string(data)
+		if hash(data) != f.SHA256 {
+			t.Errorf("%s applied doesn't match the plan", name)
+		}
+	}
+	if files := generate(t, dir, "-mock", userService); !reflect.DeepEqual(files, applied) {
+		t.Error("the files applied differ from the generated ones")
+	}
+
+	kitboiler(t, dir, "plan", "-o", "endpoints", "-mock", "-hedge", userService)
+	planned = readPlan()
+	if planned["endpoints.go"].Action != "update" || planned["mock.go"].Action != "unchanged" {
+		t.Errorf("planned endpoints.go to %s and mock.go to %s, want update and unchanged", planned["endpoints.go"].Action, planned["mock.go"].Action)
+	}
+	writeFile(t, filepath.Join(dir, "endpoints", "endpoints.go"), applied["endpoints.go"]+"// edited\n")
+	if out := kitboilerFails(t, dir, "apply"); !strings.Contains(out, "endpoints.go changed since the plan was made") {
+		t.Errorf("kitboiler apply after an edit: %s", out)
+	}
+}
+
+// contains reports whether s contains v.
+func contains(s []string, v string) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// TestPackagesDriver checks that the packages are listed by the package
+// driver set by GOPACKAGESDRIVER, their dependencies, such as the model
+// package, along with them.
+func TestPackagesDriver(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	log := filepath.Join(dir, "driver.log")
+	cmd := exec.Command(os.Getenv("KITBOILER"), orderService)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOPACKAGESDRIVER="+os.Args[0], "KITBOILER_DRIVER_LOG="+log)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("kitboiler with GOPACKAGESDRIVER: %v\n%s", err, stderr.Bytes())
+	}
+	if want := "func MakeHTTPHandler(svc orders.OrderService) http.Handler {"; !strings.Contains(string(out), want) {
+		t.Errorf("kitboiler with GOPACKAGESDRIVER generated:\n%s\nwant %s", out, want)
+	}
+	requests, err := ioutil.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(requests) != "example.com/fixtures/orders\n" {
+		t.Errorf("driver requests:\n%s\nwant example.com/fixtures/orders alone, with its dependencies", requests)
+	}
+}
+
+// TestManifest checks that the manifest lists the generated files with
+// their roles and hashes.
+func TestManifest(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	files := generate(t, dir, "-harness", userService)
+	var manifest struct {
+		Interface string `json:"interface"`
+		Files     []struct {
+			Name   string `json:"name"`
+			Role   string `json:"role"`
+			Keep   bool   `json:"keep"`
+			SHA256 string `json:"sha256"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(files["kitboiler.manifest.json"]), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Interface != userService {
+		t.Errorf("interface %s, want %s", manifest.Interface, userService)
+	}
+	roles := map[string]string{}
+	for _, f := range manifest.Files {
+		roles[f.Name] = f.Role
+		content, ok := files[f.Name]
+		switch {
+		case !ok:
+			t.Errorf("%s is listed but wasn't generated", f.Name)
+		case f.SHA256 != fmt.Sprintf("%x", sha256.Sum256([]byte(content))):
+			t.Errorf("%s: hash %s doesn't match its content", f.Name, f.SHA256)
+		}
+	}
+	for name, role := range map[string]string{"endpoints.go": "endpoints", "mock.go": "mock", "testdata/golden/GetUser/zero.json": "golden"} {
+		if roles[name] != role {
+			t.Errorf("%s has role %q, want %q", name, roles[name], role)
+		}
+	}
+
+	dir = copyFixtures(t)
+	defer os.RemoveAll(dir)
+	if files := generate(t, dir, "-manifest=false", userService); files["kitboiler.manifest.json"] != "" {
+		t.Error("-manifest=false wrote the manifest")
+	}
+}
+
+// TestKeepRemoved checks that the route of a method removed from the
+// interface responds with 410 Gone for the generations of -keep-removed.
+func TestKeepRemoved(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	generate(t, dir, "-keep-removed", "2", "-mock", userService)
+	api := filepath.Join(dir, "api", "api.go")
+	src, err := ioutil.ReadFile(api)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src = bytes.Replace(src, []byte("DeleteUser(ctx context.Context, id string) (err error)"), nil, 1)
+	writeFile(t, api, string(src))
+
+	for gen, remaining := range []int{1, 0, -1} {
+		files := generate(t, dir, "-keep-removed", "2", "-mock", userService)
+		var manifest struct {
+			Removed []struct {
+				Name      string `json:"name"`
+				Route     string `json:"route"`
+				Remaining int    `json:"remaining"`
+			} `json:"removed"`
+		}
+		if err := json.Unmarshal([]byte(files["kitboiler.manifest.json"]), &manifest); err != nil {
+			t.Fatal(err)
+		}
+		kept := strings.Contains(files["endpoints.go"], "func DeleteUserEndPoint(")
+		switch {
+		case remaining < 0 && (kept || len(manifest.Removed) > 0):
+			t.Errorf("generation %d keeps the route of DeleteUser", gen+1)
+		case remaining >= 0 && !kept:
+			t.Errorf("generation %d drops the route of DeleteUser", gen+1)
+		case remaining >= 0 && (len(manifest.Removed) != 1 || manifest.Removed[0].Route != "POST /delete-user" || manifest.Removed[0].Remaining != remaining):
+			t.Errorf("generation %d lists removed %+v, want POST /delete-user remaining %d", gen+1, manifest.Removed, remaining)
+		}
+		if gen == 0 {
+			goTest(t, dir, "./gone/")
+		}
+	}
+}
+
+// TestClean checks that kitboiler clean removes the files generated for
+// options that are now off, but keeps those edited since.
+func TestClean(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	generate(t, dir, "-harness", userService)
+	edited := filepath.Join(dir, "endpoints", "testdata", "golden", "GetUser", "zero.json")
+	writeFile(t, edited, `{"id": "1"}`+"\n")
+
+	generate(t, dir, userService)
+	out := kitboiler(t, dir, "clean", "-o", "endpoints", userService)
+	for _, want := range []string{
+		"removed harness_test.go\n",
+		"removed mock.go\n",
+		"removed testdata/golden/DeleteUser/zero.json\n",
+		"kept testdata/golden/GetUser/zero.json, edited since it was generated\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("kitboiler clean:\n%s\nwant %s", out, want)
+		}
+	}
+	files := generate(t, dir, userService)
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	want := []string{"endpoints.go", "kitboiler.manifest.json", "testdata/golden/GetUser/zero.json"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("files after clean: %v, want %v", names, want)
+	}
+}
+
+// TestSplitSwitch checks that generating a package removes the files of
+// -split when switching back from it, and <pkg>.go when switching to it,
+// keeping the edited ones.
+func TestSplitSwitch(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	generate(t, dir, "-split", userService)
+	writeFile(t, filepath.Join(dir, "endpoints", "profile_transport.go"), "package endpoints\n")
+
+	out := kitboiler(t, dir, "-o", "endpoints", userService)
+	for _, want := range []string{
+		"removed common.go\n",
+		"removed get_user_endpoint.go\n",
+		"removed get_user_transport.go\n",
+		"kept profile_transport.go, edited since it was generated\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("kitboiler:\n%s\nwant %s", out, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "endpoints", "get_user_endpoint.go")); !os.IsNotExist(err) {
+		t.Errorf("get_user_endpoint.go left after switching back from -split: %v", err)
+	}
+
+	os.Remove(filepath.Join(dir, "endpoints", "profile_transport.go"))
+	if out := kitboiler(t, dir, "-o", "endpoints", "-split", userService); !strings.Contains(out, "removed endpoints.go\n") {
+		t.Errorf("kitboiler -split:\n%s\nwant removed endpoints.go", out)
+	}
+}
+
+// TestFollowRenames checks that -follow-renames moves the edited golden
+// file of a renamed method to its new name.
+func TestFollowRenames(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	generate(t, dir, "-harness", userService)
+	golden := `{"id": "1"}` + "\n"
+	writeFile(t, filepath.Join(dir, "endpoints", "testdata", "golden", "GetUser", "zero.json"), golden)
+
+	api := filepath.Join(dir, "api", "api.go")
+	src, err := ioutil.ReadFile(api)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, api, strings.Replace(string(src), "GetUser", "FetchUser", -1))
+	out := kitboiler(t, dir, "-o", "endpoints", "-harness", "-follow-renames", userService)
+	if want := "renamed testdata/golden/GetUser/zero.json to testdata/golden/FetchUser/zero.json\n"; out != want {
+		t.Errorf("kitboiler -follow-renames: %q, want %q", out, want)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "endpoints", "testdata", "golden", "FetchUser", "zero.json"))
+	if err != nil || string(data) != golden {
+		t.Errorf("FetchUser/zero.json: %q, %v, want the edited %q", data, err, golden)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "endpoints", "testdata", "golden", "GetUser")); !os.IsNotExist(err) {
+		t.Errorf("GetUser golden files left behind: %v", err)
+	}
+}
+
+// TestModule checks that the package generated with -module builds as a
+// module of its own, requiring the module of the interface.
+func TestModule(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	kitboiler(t, dir, "-o", "sdk", "-module", "example.com/usersdk", "-mock", userService)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "sdk", "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"module example.com/usersdk",
+		"example.com/fixtures v0.0.0-00010101000000-000000000000",
+		"github.com/go-kit/kit v0.9.0",
+		"replace example.com/fixtures => ..",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("go.mod:\n%s\nwant %s", data, want)
+		}
+	}
+	goTest(t, filepath.Join(dir, "sdk"), "./...")
+}
+
+// TestChangelog checks the versions suggested by -changelog and the
+// changelog entry of a removed method.
+func TestChangelog(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	out := kitboiler(t, dir, "-o", "endpoints", "-changelog", userService)
+	if want := "suggested version v0.1.0, see endpoints/CHANGELOG.md\n"; out != want {
+		t.Errorf("first run: %q, want %q", out, want)
+	}
+	if out := kitboiler(t, dir, "-o", "endpoints", "-changelog", userService); out != "" {
+		t.Errorf("run without changes: %q, want nothing", out)
+	}
+
+	api := filepath.Join(dir, "api", "api.go")
+	src, err := ioutil.ReadFile(api)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, api, strings.Replace(string(src), "\tDeleteUser(ctx context.Context, id string) (err error)\n", "", 1))
+	out = kitboiler(t, dir, "-o", "endpoints", "-changelog", userService)
+	if want := "suggested version v0.2.0 (was v0.1.0), see endpoints/CHANGELOG.md\n"; out != want {
+		t.Errorf("run after removing DeleteUser: %q, want %q", out, want)
+	}
+	changelog, err := ioutil.ReadFile(filepath.Join(dir, "endpoints", "CHANGELOG.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "### Removed (breaking)\n\n- `DeleteUser` (`POST /delete-user`)\n\n## v0.1.0"; !strings.Contains(string(changelog), want) {
+		t.Errorf("CHANGELOG.md:\n%s\nwant %q", changelog, want)
+	}
+}
+
+// TestGoGenerate checks the directive written by -go-generate, and that go
+// generate regenerates the package with it.
+func TestGoGenerate(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	kitboiler(t, dir, "-o", "endpoints", "-mock", "-openapi-constraints", "specs/users.yaml", "-go-generate", userService)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "api", "generate.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "//go:generate kitboiler -mock -o=../endpoints -openapi-constraints=../specs/users.yaml " + userService + "\n"; !strings.HasSuffix(string(data), want) {
+		t.Errorf("generate.go:\n%s\nwant %s", data, want)
+	}
+
+	if err := os.RemoveAll(filepath.Join(dir, "endpoints")); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("go", "generate", "./api")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "PATH="+filepath.Dir(os.Getenv("KITBOILER"))+string(filepath.ListSeparator)+os.Getenv("PATH"))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go generate: %v\n%s", err, out)
+	}
+	for _, name := range []string{"endpoints.go", "mock.go"} {
+		if _, err := os.Stat(filepath.Join(dir, "endpoints", name)); err != nil {
+			t.Errorf("go generate didn't generate %s: %v", name, err)
+		}
+	}
+}
+
+// TestOpenAPI checks the optionality of the fields in the spec generated
+// with -openapi: pointers are nullable, values aren't, and kit:optional
+// parameters are not required. It also checks that the summary of an
+// operation isn't repeated in its description, nor in the doc comment of the
+// method imported back from the spec.
+func TestOpenAPI(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	files := generate(t, dir, "-openapi", userService)
+	var spec struct {
+		Paths map[string]map[string]struct {
+			Summary     string `yaml:"summary"`
+			Description string `yaml:"description"`
+		} `yaml:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]struct {
+					Nullable bool `yaml:"nullable"`
+				} `yaml:"properties"`
+				Required []string `yaml:"required"`
+			} `yaml:"schemas"`
+		} `yaml:"components"`
+	}
+	if err := yaml.Unmarshal([]byte(files["openapi.yaml"]), &spec); err != nil {
+		t.Fatal(err)
+	}
+	schemas := spec.Components.Schemas
+	if got, want := schemas["UpdateUserRequest"].Required, []string{"Id", "Status"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("UpdateUserRequest requires %v, want %v", got, want)
+	}
+	if !schemas["GetUserResponse"].Properties["User"].Nullable {
+		t.Error("the User of GetUserResponse isn't nullable")
+	}
+	if schemas["ProfileResponse"].Properties["Profile"].Nullable {
+		t.Error("the Profile of ProfileResponse, returned by value, is nullable")
+	}
+	if schemas["User"].Properties["Name"].Nullable {
+		t.Error("the Name of User is nullable")
+	}
+	op := spec.Paths["/delete-user"]["post"]
+	if want := "DeleteUser removes the user id."; op.Summary != want {
+		t.Errorf("DeleteUser summary %q, want %q", op.Summary, want)
+	}
+	if want := "DeleteUser removes the user id. It fails if the user owns\nresources."; op.Description != want {
+		t.Errorf("DeleteUser description %q, want %q", op.Description, want)
+	}
+	op = spec.Paths["/get-user"]["post"]
+	if want := "GetUser returns the user with the given id."; op.Summary != want || op.Description != "" {
+		t.Errorf("GetUser summary %q and description %q, want only the summary %q", op.Summary, op.Description, want)
+	}
+	if op := spec.Paths["/list-users"]["post"]; op.Summary != "" || op.Description != "" {
+		t.Errorf("ListUsers, undocumented, has summary %q and description %q", op.Summary, op.Description)
+	}
+
+	kitboiler(t, dir, "import", "-o", "imported", "endpoints/openapi.yaml")
+	src, err := ioutil.ReadFile(filepath.Join(dir, "imported", "user_service.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "// DeleteUser removes the user id. It fails if the user owns // resources. //"; strings.Count(fields(string(src)), "removes the user id.") != 1 || !strings.Contains(fields(string(src)), want) {
+		t.Errorf("imported interface:\n%s\nwant the doc of DeleteUser once: %s", src, want)
+	}
+}
+
+// TestExamples checks that the example requests and responses of -examples
+// are embedded in the OpenAPI spec and the stub server, and that examples
+// not matching the types fail the generation with the faulty properties.
+func TestExamples(t *testing.T) {
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	writeFile(t, filepath.Join(dir, "examples", "GetUser.request.json"), `{"Id": "42"}`)
+	writeFile(t, filepath.Join(dir, "examples", "GetUser.response.json"), `{"User": {"ID": "42", "Name": "ann", "Age": 31, "Version": 3, "Status": "active"}}`)
+	files := generate(t, dir, "-openapi", "-stub-server", "-examples", "examples", userService)
+	var spec struct {
+		Paths map[string]map[string]struct {
+			RequestBody struct {
+				Content map[string]struct {
+					Example map[string]interface{} `yaml:"example"`
+				} `yaml:"content"`
+			} `yaml:"requestBody"`
+			Responses map[string]struct {
+				Content map[string]struct {
+					Example map[string]map[string]interface{} `yaml:"example"`
+				} `yaml:"content"`
+			} `yaml:"responses"`
+		} `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal([]byte(files["openapi.yaml"]), &spec); err != nil {
+		t.Fatal(err)
+	}
+	op := spec.Paths["/get-user"]["post"]
+	if got := op.RequestBody.Content["application/json"].Example["Id"]; got != "42" {
+		t.Errorf("request example Id %v, want 42", got)
+	}
+	if got := op.Responses["200"].Content["application/json"].Example["User"]["Age"]; got != 31 {
+		t.Errorf("response example User.Age %v, want 31", got)
+	}
+	if want := `load("GetUser", "{\"User\":{\"ID\":\"42\",`; !strings.Contains(files["stubserver/main.go"], want) {
+		t.Errorf("the stub server doesn't load the example response, want %s", want)
+	}
+
+	writeFile(t, filepath.Join(dir, "examples", "GetUser.response.json"), `{"User": {"ID": "42", "Name": "ann", "Age": "31", "Version": 3, "Status": "active", "Nick": "a"}}`)
+	stderr := kitboilerFails(t, dir, "-o", "endpoints", "-openapi", "-examples", "examples", userService)
+	for _, want := range []string{
+		`examples/GetUser.response.json: User.Age: string "31", want integer`,
+		"examples/GetUser.response.json: User.Nick: unknown property",
+	} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("kitboiler: %s\nwant %s", stderr, want)
+		}
+	}
+}
+
+// TestPruneImports checks that the imports the generated files don't use
+// are dropped, whatever names the packages are imported by.
+func TestPruneImports(t *testing.T) {
+	src := `package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"example.com/fixtures/go-geo/v2"
+	yml "gopkg.in/yaml.v2"
+	_ "net/http/pprof"
+)
+
+func handle(ctx context.Context, p geo.Point) error {
+	_, err := yml.Marshal(p)
+	return err
+}
+`
+	out, err := pruneImports([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, imp := range []string{`"context"`, `"example.com/fixtures/go-geo/v2"`, `yml "gopkg.in/yaml.v2"`, `_ "net/http/pprof"`} {
+		if !strings.Contains(string(out), imp) {
+			t.Errorf("the used import %s is dropped", imp)
+		}
+	}
+	for _, imp := range []string{`"encoding/json"`, `"net/http"`} {
+		if strings.Contains(string(out), imp) {
+			t.Errorf("the unused import %s is kept", imp)
+		}
+	}
+}
+
+// TestProtoGoName checks the Go names of the messages and methods of the
+// proto file, as protoc-gen-go derives them.
+func TestProtoGoName(t *testing.T) {
+	for name, want := range map[string]string{
+		"GetUserRequest": "GetUserRequest",
+		"getUserReq":     "GetUserReq",
+		"get_user_req":   "GetUserReq",
+		"_private":       "XPrivate",
+		"v2_api":         "V2Api",
+	} {
+		if got := protoGoName(name); got != want {
+			t.Errorf("protoGoName(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+// TestGRPCServiceConfig checks that the default service config of the gRPC
+// clients retries every method and times out those with a latency budget.
+func TestGRPCServiceConfig(t *testing.T) {
+	svc := Service{Pkg: "endpoints", IFace: "api.UserService", Funcs: []Func{{Name: "GetUser"}, {Name: "UpdateUser", Budget: 250 * time.Millisecond}}}
+	var config struct {
+		MethodConfig []struct {
+			Name []struct {
+				Service string `json:"service"`
+				Method  string `json:"method"`
+			} `json:"name"`
+			Timeout     string `json:"timeout"`
+			RetryPolicy struct {
+				MaxAttempts          int      `json:"maxAttempts"`
+				RetryableStatusCodes []string `json:"retryableStatusCodes"`
+			} `json:"retryPolicy"`
+		} `json:"methodConfig"`
+	}
+	if err := json.Unmarshal([]byte(svc.GRPCServiceConfig()), &config); err != nil {
+		t.Fatal(err)
+	}
+	if len(config.MethodConfig) != 2 {
+		t.Fatalf("%d method configs, want the service's and UpdateUser's", len(config.MethodConfig))
+	}
+	for i, want := range []struct{ method, timeout string }{{"", ""}, {"UpdateUser", "0.25s"}} {
+		c := config.MethodConfig[i]
+		if len(c.Name) != 1 || c.Name[0].Service != "endpoints.UserService" || c.Name[0].Method != want.method || c.Timeout != want.timeout {
+			t.Errorf("method config %d names %+v with timeout %q, want method %q with timeout %q", i, c.Name, c.Timeout, want.method, want.timeout)
+		}
+		if c.RetryPolicy.MaxAttempts != 3 || !reflect.DeepEqual(c.RetryPolicy.RetryableStatusCodes, []string{"UNAVAILABLE"}) {
+			t.Errorf("method config %d retries %+v", i, c.RetryPolicy)
+		}
+	}
+}
+
+// TestKebabCase checks the route paths derived from method names.
+func TestKebabCase(t *testing.T) {
+	for name, want := range map[string]string{
+		"GetUser":     "get-user",
+		"GetUserByID": "get-user-by-id",
+		"HTTPStatus":  "http-status",
+		"ping":        "ping",
+	} {
+		if got := kebabCase(name); got != want {
+			t.Errorf("kebabCase(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// testFixture generates the package of the fixture interface iface with
+// flags into a copy of the fixtures and runs the tests of their package pkg,
+// which call it.
+func testFixture(t *testing.T, pkg, iface string, flags ...string) {
+	t.Helper()
+	dir := copyFixtures(t)
+	defer os.RemoveAll(dir)
+	generate(t, dir, append(flags, iface)...)
+	goTest(t, dir, "./"+pkg+"/")
+}
+
+// goBuild builds the command pkg in dir, a copy of the fixtures, and returns
+// the path of its binary, in dir. Like goTest, -short skips it.
+func goBuild(t *testing.T, dir string, pkg string) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("builds the generated package")
+	}
+	bin := filepath.Join(dir, filepath.Base(pkg))
+	cmd := exec.Command("go", "build", "-o", bin, pkg)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build %s: %v\n%s", pkg, err, out)
+	}
+	return bin
+}
+
+// writeFile writes content to the file name, creating its directory.
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// copyDir copies the files under src to dst.
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0755)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(dst, rel), data, 0644)
+	})
+}
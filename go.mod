@@ -2,4 +2,7 @@ module github.com/jeroenvand/kitboiler
 
 go 1.12
 
-require golang.org/x/tools v0.0.0-20190420181800-aa740d480789
+require (
+	golang.org/x/tools v0.0.0-20190420181800-aa740d480789
+	gopkg.in/yaml.v2 v2.2.2
+)
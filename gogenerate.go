@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// generateFileName is the name of the file holding the go:generate
+// directive in the package of the interface.
+const generateFileName = "generate.go"
+
+// pathFlags are the flags whose values are paths, relative to the working
+// directory on the command line and to the package directory in the
+// directive.
+var pathFlags = map[string]bool{"o": true, "openapi-constraints": true, "scalars": true}
+
+// directiveExcludedFlags are the flags left out of the directive: -dir and
+// -config only matter to find the interface, which the directive names, and
+// -profile to find options of the config file, which it spells out.
+var directiveExcludedFlags = map[string]bool{"dir": true, "config": true, "profile": true, "go-generate": true}
+
+// generateDirective returns the go:generate directive running kitboiler
+// for iface, from the package directory pkgDir, with the flags set now.
+func generateDirective(iface, pkgDir string) (string, error) {
+	args := []string{"kitboiler"}
+	var err error
+	flag.Visit(func(f *flag.Flag) {
+		if directiveExcludedFlags[f.Name] || err != nil {
+			return
+		}
+		value := f.Value.String()
+		if pathFlags[f.Name] && value != "" && !filepath.IsAbs(value) {
+			var abs string
+			if abs, err = filepath.Abs(value); err != nil {
+				return
+			}
+			if value, err = filepath.Rel(pkgDir, abs); err != nil {
+				return
+			}
+			value = filepath.ToSlash(value)
+		}
+		switch {
+		case isBoolFlag(f) && value == "true":
+			args = append(args, "-"+f.Name)
+		case strings.ContainsAny(value, " \t\"") || value == "":
+			args = append(args, "-"+f.Name+"="+strconv.Quote(value))
+		default:
+			args = append(args, "-"+f.Name+"="+value)
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	return "//go:generate " + strings.Join(append(args, iface), " "), nil
+}
+
+// writeGenerateFile writes the go:generate directive regenerating the
+// package for svc to generate.go in the package of the interface, unless
+// generate.go exists and wasn't written by KitBoiler.
+func writeGenerateFile(svc Service) error {
+	pkg, err := importPackage(svc.IFacePath, *flagSrcDir)
+	if err != nil {
+		return err
+	}
+	directive, err := generateDirective(svc.IFacePath+"."+svc.IFaceName(), pkg.Dir)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(pkg.Dir, generateFileName)
+	if old, err := ioutil.ReadFile(path); err == nil && !isGenerated(old) {
+		return fmt.Errorf("%s exists and wasn't generated by KitBoiler", path)
+	} else if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkg.Name)
+	fmt.Fprintf(&buf, "// Run go generate to regenerate package %s for %s.\n", svc.Pkg, svc.IFace)
+	fmt.Fprintf(&buf, "%s\n", directive)
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
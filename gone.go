@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// GoneMethod is a method removed from the interface whose route still
+// responds, with 410 Gone, see -keep-removed.
+type GoneMethod struct {
+	Name      string
+	Route     string // as in the manifest, e.g. "POST /delete-user"
+	Remaining int    // generations the route is kept for after this one
+	carried   bool   // kept by the previous generation already
+}
+
+// Path returns the path of the route of m.
+func (m GoneMethod) Path() string {
+	return m.Route[strings.LastIndex(m.Route, " ")+1:]
+}
+
+// resolveGone sets the methods removed from the interface of svc whose
+// routes are kept, as the code in the -o directory has them: those the
+// manifest lists as kept and, for -keep-removed generations counting this
+// one, the methods removed since it was written. Routes taken by a current
+// method aren't kept.
+func resolveGone(svc *Service) error {
+	if *flagKeepRemoved <= 0 {
+		return nil
+	}
+	if *flagOutDir == "" || !*flagManifest {
+		return errors.New("-keep-removed requires -o and -manifest, the removed methods being read from the manifest")
+	}
+	prev, err := readManifest(*flagOutDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	current := map[string]bool{}
+	for _, f := range svc.Funcs {
+		current[f.Name] = true
+		current[f.HTTPPath] = true
+	}
+	keep := func(m GoneMethod) {
+		if !current[m.Name] && !current[m.Path()] {
+			current[m.Name] = true
+			svc.Gone = append(svc.Gone, m)
+		}
+	}
+	for _, r := range prev.Removed {
+		keep(GoneMethod{Name: r.Name, Route: r.Route, Remaining: r.Remaining, carried: true})
+	}
+	for _, m := range prev.Methods {
+		keep(GoneMethod{Name: m.Name, Route: m.Route, Remaining: *flagKeepRemoved - 1})
+	}
+	return nil
+}
+
+// advanceGone returns the methods of gone kept by the generation after
+// theirs: those removed since and, one generation less, those kept before.
+// Only gen and plan make a generation; diff and clean compare with the
+// last one.
+func advanceGone(gone []GoneMethod) []GoneMethod {
+	var next []GoneMethod
+	for _, m := range gone {
+		if m.carried {
+			if m.Remaining == 0 {
+				continue
+			}
+			m.Remaining--
+		}
+		next = append(next, m)
+	}
+	return next
+}
+
+const goneTemplate = `
+{{ define "gone" }}{{ $svc := . }}{{ range .Gone }}
+// {{ .Name }}EndPoint fails with a GoneError.
+//
+// Deprecated: {{ .Name }} has been removed from {{ $svc.IFace }}. Its route is kept
+// responding with 410 Gone for {{ .Remaining }} more generation(s) of this package.
+func {{ .Name }}EndPoint(svc {{ $svc.IFace }}) endpoint.Endpoint {
+	return func(context.Context, interface{}) (interface{}, error) {
+		return nil, GoneError{Method: "{{ .Name }}"}
+	}
+}
+
+// {{ .Name }}HTTPJSONHandler serves {{ .Route }} with the endpoint of the removed method.
+//
+// Deprecated: {{ .Name }} has been removed from {{ $svc.IFace }}.
+func {{ .Name }}HTTPJSONHandler(e endpoint.Endpoint) http.Handler {
+	return httptransport.NewServer(e, decodeGoneRequest, EncodeResponse{{ if $svc.EncodesErrors }}, httptransport.ServerErrorEncoder(EncodeError){{ end }})
+}
+{{ end }}
+// GoneError is returned by the endpoints of the methods removed from
+// {{ .IFace }} whose routes are kept for a while, so that their clients
+// get 410 Gone and a Deprecation header rather than 404 Not Found.
+type GoneError struct {
+	Method string
+}
+
+func (e GoneError) Error() string { return e.Method + " has been removed" }
+
+// StatusCode makes the error encoder respond with 410 Gone.
+func (GoneError) StatusCode() int { return http.StatusGone }
+
+// Headers makes the error encoder set the Deprecation header.
+func (GoneError) Headers() http.Header { return http.Header{"Deprecation": {"true"}} }
+
+// decodeGoneRequest ignores the requests to the routes of removed methods.
+func decodeGoneRequest(context.Context, *http.Request) (interface{}, error) {
+	return nil, nil
+}
+{{ end }}
+`
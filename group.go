@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// groupNameRE matches valid method group names, which name the handler,
+// the listen address and the flag of the group.
+var groupNameRE = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+
+// resolveGroups assigns the methods of fns annotated with "//kit:group name"
+// to the group name, served by a handler and listener of its own.
+func resolveGroups(fns []Func) error {
+	for i := range fns {
+		fn := &fns[i]
+		a, ok := fn.Annotation("group")
+		if !ok {
+			continue
+		}
+		if len(a.Args) != 1 {
+			return fn.errorf(a, "kit:group takes exactly one group name")
+		}
+		if !groupNameRE.MatchString(a.Args[0]) {
+			return fn.errorf(a, "kit:group: %q isn't a valid group name, use lower case letters and digits", a.Args[0])
+		}
+		fn.Group = a.Args[0]
+	}
+	return nil
+}
+
+// HandlerGroup is a group of methods served by the same HTTP handler. The
+// group without a name is served by MakeHTTPHandler.
+type HandlerGroup struct {
+	Name        string
+	Funcs       []Func
+	DefaultAddr string // listen address of the scaffold and stub server, e.g. ":8081"
+}
+
+// Handler returns the name of the function making the HTTP handler of g,
+// e.g. MakeAdminHTTPHandler.
+func (g HandlerGroup) Handler() string {
+	return "Make" + Exported(g.Name) + "HTTPHandler"
+}
+
+// Addr returns the name of the Config field of the scaffold holding the
+// listen address of g, e.g. AdminAddr.
+func (g HandlerGroup) Addr() string {
+	return Exported(g.Name) + "Addr"
+}
+
+// Env returns the suffix of the environment variable of the listen address
+// of g, e.g. ADMIN_ADDR.
+func (g HandlerGroup) Env() string {
+	return strings.ToUpper(g.Name) + "_ADDR"
+}
+
+// HandlerGroups returns the methods outside a group followed by the groups
+// of the other methods, in the order of their first method.
+func (s Service) HandlerGroups() []HandlerGroup {
+	groups := []HandlerGroup{{DefaultAddr: listenAddr(0)}}
+	index := map[string]int{"": 0}
+	for _, f := range s.Funcs {
+		i, ok := index[f.Group]
+		if !ok {
+			i = len(groups)
+			index[f.Group] = i
+			groups = append(groups, HandlerGroup{Name: f.Group, DefaultAddr: listenAddr(i)})
+		}
+		groups[i].Funcs = append(groups[i].Funcs, f)
+	}
+	return groups
+}
+
+// listenAddr returns the default listen address of the i-th listener of the
+// scaffold, the first being the one of MakeHTTPHandler.
+func listenAddr(i int) string {
+	return fmt.Sprintf(":%d", 8080+i)
+}
+
+// Groups returns the named groups of methods, see kit:group.
+func (s Service) Groups() []HandlerGroup {
+	return s.HandlerGroups()[1:]
+}
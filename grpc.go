@@ -0,0 +1,803 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// grpcImports are the imports of the gRPC transport, besides those of the
+// endpoints and of the generated protobuf package.
+var grpcImports = map[string]string{
+	"context":                              "",
+	"encoding/json":                        "",
+	"fmt":                                  "",
+	"net/http":                             "",
+	"reflect":                              "",
+	"strings":                              "",
+	"sync":                                 "",
+	"time":                                 "",
+	"github.com/go-kit/kit/transport/grpc": "grpctransport",
+	"google.golang.org/grpc":               "",
+	"google.golang.org/grpc/codes":         "",
+	"google.golang.org/grpc/keepalive":     "",
+	"google.golang.org/grpc/metadata":      "",
+	"google.golang.org/grpc/status":        "",
+	"google.golang.org/protobuf/encoding/protojson":      "",
+	"google.golang.org/protobuf/reflect/protoreflect":    "",
+	"google.golang.org/protobuf/types/known/durationpb":  "",
+	"google.golang.org/protobuf/types/known/structpb":    "",
+	"google.golang.org/protobuf/types/known/timestamppb": "",
+}
+
+// parseTransports returns the set of the transports of -transports.
+func parseTransports(list string) (map[string]bool, error) {
+	transports := map[string]bool{}
+	for _, t := range strings.Split(list, ",") {
+		switch t = strings.TrimSpace(t); t {
+		case "http", "grpc", "nats", "amqp":
+			transports[t] = true
+		default:
+			return nil, fmt.Errorf("-transports: unknown transport %q, want http, grpc, nats or amqp", t)
+		}
+	}
+	return transports, nil
+}
+
+// httpFlags are the flags adding features to the HTTP handlers, or to the
+// clients, specs and tools built around them, which -transports can't
+// leave http out with.
+var httpFlags = map[string]bool{
+	"access-log":        true,
+	"admin":             true,
+	"client":            true,
+	"client-cache":      true,
+	"dashboard":         true,
+	"examples":          true,
+	"get-methods":       true,
+	"grpc-web":          true,
+	"harness":           true,
+	"health":            true,
+	"hot-reload":        true,
+	"keep-removed":      true,
+	"loadtest":          true,
+	"metrics":           true,
+	"metrics-exporter":  true,
+	"openapi":           true,
+	"options-head":      true,
+	"ratelimit":         true,
+	"recover":           true,
+	"replay":            true,
+	"response-envelope": true,
+	"route-prefix":      true,
+	"router":            true,
+	"slo":               true,
+	"stub-server":       true,
+	"tenant":            true,
+}
+
+// checkHTTPFlags returns an error if a flag of the HTTP handlers is set
+// while -transports leaves http out.
+func checkHTTPFlags() error {
+	var err error
+	flag.Visit(func(f *flag.Flag) {
+		if err == nil && httpFlags[f.Name] {
+			err = fmt.Errorf("-%s applies to the HTTP handlers, add http to -transports", f.Name)
+		}
+	})
+	return err
+}
+
+// stripHTTP strips the features of the HTTP handlers added by annotations
+// from the methods of fns, such as the ETags of kit:etag and the routes of
+// kit:http, for the services -transports leaves http out of.
+func stripHTTP(fns []Func) {
+	for i := range fns {
+		fn := &fns[i]
+		fn.ETag, fn.IfMatch = nil, nil
+		fn.SLO = 0
+		fn.HTTPMethod = "POST"
+	}
+}
+
+// GRPCImports returns the imports of the gRPC transport of s.
+func (s Service) GRPCImports() map[string]string {
+	imps := map[string]string{s.ImportPath + "/pb": ""}
+	for imp, alias := range s.Imports {
+		imps[imp] = alias
+	}
+	for imp, alias := range grpcImports {
+		imps[imp] = alias
+	}
+	if s.GRPCWeb {
+		imps["github.com/improbable-eng/grpc-web/go/grpcweb"] = ""
+	}
+	return imps
+}
+
+// protoGoName returns the Go name protoc-gen-go gives to the message,
+// service or method name, e.g. GetUserReq for getUserReq.
+func protoGoName(name string) string {
+	var b []byte
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '_' && i == 0:
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(name) && isASCIILower(name[i+1]):
+			// the next letter is upper cased instead
+		case '0' <= c && c <= '9':
+			b = append(b, c)
+		default:
+			if isASCIILower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(name) && isASCIILower(name[i+1]); i++ {
+				b = append(b, name[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+func isASCIILower(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
+
+// GRPCHandler returns the name of the field of the gRPC server holding the
+// handler of f, e.g. createUserHandler.
+func GRPCHandler(f Func) string {
+	return lowerFirst(f.Name) + "Handler"
+}
+
+const grpcTemplate = `
+{{ define "grpc" }}
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package {{ .Pkg }}
+{{ $svc := . }}
+import ({{ range $imp, $alias := .GRPCImports }}{{ $alias }} "{{ $imp }}"
+{{ end }}
+)
+{{ if .Hooks }}
+// GRPCServerOptions are passed to the server of every gRPC method, e.g. to
+// add grpctransport.ServerBefore functions. Set them at init time, before
+// NewGRPCServer is called.
+var GRPCServerOptions []grpctransport.ServerOption
+{{ end }}
+// NewGRPCServer returns the gRPC server of the endpoints of svc, to register
+// with pb.Register{{ ProtoGoName .IFaceName }}Server. Its requests and responses are the
+// messages of pb/{{ .Pkg }}.proto, converted from and to the request and
+// response types of the endpoints field by field, matched by JSON name.
+func NewGRPCServer(svc {{ .IFace }}) pb.{{ ProtoGoName .IFaceName }}Server {
+	var options []grpctransport.ServerOption{{ if .Tenant }}
+	options = append(options, grpctransport.ServerBefore(tenantFromGRPCMetadata)){{ end }}{{ if .Tracing }}
+	options = append(options, grpctransport.ServerBefore(traceFromGRPCMetadata)){{ end }}{{ if .UsesBudgets }}
+	options = append(options, grpctransport.ServerBefore(deadlineFromGRPC)){{ end }}{{ if .Hooks }}
+	options = append(options, GRPCServerOptions...){{ end }}
+	return &grpcServer{ {{ range .Funcs }}
+		{{ GRPCHandler . }}: grpctransport.NewServer(
+			{{ $svc.Endpoint . }},
+			DecodeGRPC{{ .Name }}Request,
+			EncodeGRPC{{ .Name }}Response,
+			options...,
+		),{{ end }}
+	}
+}
+{{ if .GRPCWeb }}
+// MakeGRPCWebHandler returns a handler serving the gRPC server of svc to
+// browsers over gRPC-Web, CORS preflight requests included, and the other
+// requests with rest, e.g. MakeHTTPHandler(svc), for both to share a
+// listener. The options configure the gRPC-Web wrapper, e.g. with
+// grpcweb.WithOriginFunc to allow the calls of other origins.
+func MakeGRPCWebHandler(svc {{ .IFace }}, rest http.Handler, options ...grpcweb.Option) http.Handler {
+	server := grpc.NewServer()
+	pb.Register{{ ProtoGoName .IFaceName }}Server(server, NewGRPCServer(svc))
+	web := grpcweb.WrapServer(server, options...)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if web.IsGrpcWebRequest(r) || web.IsAcceptableGrpcCorsRequest(r) {
+			web.ServeHTTP(w, r)
+			return
+		}
+		rest.ServeHTTP(w, r)
+	})
+}
+{{ end }}
+type grpcServer struct {
+	pb.Unimplemented{{ ProtoGoName .IFaceName }}Server{{ range .Funcs }}
+	{{ GRPCHandler . }} grpctransport.Handler{{ end }}
+}
+{{ range .Funcs }}
+func (s *grpcServer) {{ ProtoGoName .Name }}(ctx context.Context, req *pb.{{ ProtoGoName .RequestName }}) (*pb.{{ ProtoGoName .ResponseName }}, error) {
+	_, res, err := s.{{ GRPCHandler . }}.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return res.(*pb.{{ ProtoGoName .ResponseName }}), nil
+}
+
+// DecodeGRPC{{ .Name }}Request converts a *pb.{{ ProtoGoName .RequestName }} into a {{ $svc.Request . }}.
+func DecodeGRPC{{ .Name }}Request(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	var request {{ $svc.Request . }}
+	if err := fromProto(grpcReq.(*pb.{{ ProtoGoName .RequestName }}).ProtoReflect(), reflect.ValueOf(&request).Elem()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}{{ if .RequestType }}
+	if v, ok := interface{}(request).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}{{ else if HasConstraints . }}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}{{ end }}
+	return request, nil
+}
+
+// EncodeGRPC{{ .Name }}Response converts a {{ $svc.Response . }} into a *pb.{{ ProtoGoName .ResponseName }}{{ if eq .NilResult "not-found" }},
+// failing with codes.NotFound when {{ .NilResultParam.Name }} is nil{{ end }}.
+func EncodeGRPC{{ .Name }}Response(_ context.Context, response interface{}) (interface{}, error) { {{ if eq .NilResult "not-found" }}
+	if res, ok := response.({{ $svc.Response . }}); ok && res{{ if not .Unwrap }}.{{ .NilResultParam.Field }}{{ end }} == nil {
+		return nil, status.Error(codes.NotFound, "{{ .Name }}: no {{ .NilResultParam.Name }}")
+	}{{ end }}
+	res := &pb.{{ ProtoGoName .ResponseName }}{}{{ if .Unwrap }}
+	m := res.ProtoReflect()
+	if err := setProtoField(m, m.Descriptor().Fields().Get(0), reflect.ValueOf(response)); err != nil { {{ else }}
+	if err := toProto(reflect.ValueOf(response), res.ProtoReflect()); err != nil { {{ end }}
+		return nil, err
+	}
+	return res, nil
+}
+{{ end }}{{ if .Tenant }}
+// tenantFromGRPCMetadata puts the tenant of the "tenant" metadata of a gRPC
+// request into its context.
+func tenantFromGRPCMetadata(ctx context.Context, md metadata.MD) context.Context {
+	if tenant := md.Get("tenant"); len(tenant) > 0 && tenant[0] != "" {
+		return ContextWithTenant(ctx, tenant[0])
+	}
+	return ctx
+}
+{{ end }}{{ if .UsesBudgets }}
+// deadlineFromGRPC stores the deadline of a gRPC request, propagated by its
+// client in the grpc-timeout header, in its context for ShedOnBudget.
+func deadlineFromGRPC(ctx context.Context, _ metadata.MD) context.Context {
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithValue(ctx, deadlineContextKey, deadline)
+	}
+	return ctx
+}
+{{ end }}{{ if .Tracing }}
+// traceFromGRPCMetadata continues the trace propagated by the metadata of a
+// gRPC request, as extracted by the global TextMapPropagator, in its
+// context.
+func traceFromGRPCMetadata(ctx context.Context, md metadata.MD) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, grpcMetadataCarrier(md))
+}
+
+// grpcMetadataCarrier is the propagation.TextMapCarrier of the metadata of
+// a gRPC request.
+type grpcMetadataCarrier metadata.MD
+
+func (c grpcMetadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c grpcMetadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c grpcMetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+{{ end }}
+// grpcCodes are the gRPC codes of the errors with a StatusCode method, such
+// as those of the generated middleware, by HTTP status code.
+var grpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusForbidden:           codes.PermissionDenied,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.AlreadyExists,
+	http.StatusPreconditionFailed:  codes.FailedPrecondition,
+	http.StatusUnprocessableEntity: codes.InvalidArgument,
+	http.StatusTooManyRequests:     codes.ResourceExhausted,
+	http.StatusNotImplemented:      codes.Unimplemented,
+	http.StatusServiceUnavailable:  codes.Unavailable,
+	http.StatusGatewayTimeout:      codes.DeadlineExceeded,
+}
+
+// grpcError returns err as a gRPC status error, whose code follows the
+{{ if .ErrorStatuses }}// status code of err, that of ErrorStatuses matching it or of its StatusCode
+// method, or the context error it is, and is codes.Unknown otherwise.{{ else }}// status code of err if it has a StatusCode method, or the context error it
+// is, and is codes.Unknown otherwise.{{ end }}
+func grpcError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	code := codes.Unknown
+	switch err {
+	case context.DeadlineExceeded:
+		code = codes.DeadlineExceeded
+	case context.Canceled:
+		code = codes.Canceled
+	}
+{{ if .ErrorStatuses }}	if sc, ok := errorStatus(err); ok {
+		if c, ok := grpcCodes[sc]; ok {
+			code = c
+		}
+	}{{ else }}	if sc, ok := err.(interface{ StatusCode() int }); ok {
+		if c, ok := grpcCodes[sc.StatusCode()]; ok {
+			code = c
+		}
+	}{{ end }}
+	return status.Error(code, err.Error())
+}
+
+// grpcUnions lists the variants of the unions, by their JSON names, which
+// are the names of their fields in the oneof of the message of the union.
+var grpcUnions = map[reflect.Type]map[string]reflect.Type{ {{ range .Funcs }}{{ range FilterError .Res }}{{ with .Union }}
+	reflect.TypeOf({{ $svc.DTOQual }}{{ .Name }}{}): { {{ range .Variants }}
+		"{{ .Name }}": reflect.TypeOf((*{{ .GoType }})(nil)).Elem(),{{ end }}
+	},{{ end }}{{ end }}{{ end }}
+}
+
+// toProto sets the fields of m from the fields of the struct v, or of the
+// struct v points to, with the same JSON names.
+func toProto(v reflect.Value, m protoreflect.Message) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if variants, ok := grpcUnions[v.Type()]; ok {
+		return unionToProto(v.FieldByName("Value"), variants, m)
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("can't convert %s to %s", v.Type(), m.Descriptor().FullName())
+	}
+	fields := jsonFields(v.Type())
+	fds := m.Descriptor().Fields()
+	for i := 0; i < fds.Len(); i++ {
+		fd := fds.Get(i)
+		index, ok := fields[fd.JSONName()]
+		if !ok {
+			continue
+		}
+		fv, ok := fieldByIndex(v, index)
+		if !ok {
+			continue
+		}
+		if err := setProtoField(m, fd, fv); err != nil {
+			return fmt.Errorf("%s: %v", fd.JSONName(), err)
+		}
+	}
+	return nil
+}
+
+// unionToProto sets the field of the oneof of m holding the variant of the
+// union whose value is value.
+func unionToProto(value reflect.Value, variants map[string]reflect.Type, m protoreflect.Message) error {
+	if value.IsNil() {
+		return nil
+	}
+	for name, t := range variants {
+		if value.Elem().Type() == t {
+			fd := m.Descriptor().Fields().ByJSONName(name)
+			if fd == nil {
+				return fmt.Errorf("%s has no field %s", m.Descriptor().FullName(), name)
+			}
+			return setProtoField(m, fd, value.Elem())
+		}
+	}
+	return fmt.Errorf("unexpected type %s", value.Elem().Type())
+}
+
+// setProtoField sets the field fd of m to v, leaving it unset if v is nil.
+func setProtoField(m protoreflect.Message, fd protoreflect.FieldDescriptor, v reflect.Value) error {
+	switch {
+	case fd.IsList():
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return fmt.Errorf("can't convert %s to a list", v.Type())
+		}
+		list := m.Mutable(fd).List()
+		for i := 0; i < v.Len(); i++ {
+			pv, err := protoValue(fd, v.Index(i), list.NewElement)
+			if err != nil {
+				return err
+			}
+			list.Append(pv)
+		}
+		return nil
+	case fd.IsMap():
+		if v.Kind() != reflect.Map {
+			return fmt.Errorf("can't convert %s to a map", v.Type())
+		}
+		mp := m.Mutable(fd).Map()
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := protoValue(fd.MapKey(), iter.Key(), nil)
+			if err != nil {
+				return err
+			}
+			value, err := protoValue(fd.MapValue(), iter.Value(), mp.NewValue)
+			if err != nil {
+				return err
+			}
+			mp.Set(key.MapKey(), value)
+		}
+		return nil
+	}
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return nil
+	}
+	pv, err := protoValue(fd, v, func() protoreflect.Value { return m.NewField(fd) })
+	if err != nil {
+		return err
+	}
+	m.Set(fd, pv)
+	return nil
+}
+
+// protoValue returns the value of a field of kind fd holding v. newMessage
+// returns an empty message of the field, for messages other than the
+// well-known types.
+func protoValue(fd protoreflect.FieldDescriptor, v reflect.Value, newMessage func() protoreflect.Value) (protoreflect.Value, error) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v = reflect.Zero(v.Type().Elem())
+		} else {
+			v = v.Elem()
+		}
+	}
+	if fd.Kind() == protoreflect.MessageKind {
+		switch fd.Message().FullName() {
+		case "google.protobuf.Timestamp":
+			if t, ok := v.Interface().(time.Time); ok {
+				return protoreflect.ValueOfMessage(timestamppb.New(t).ProtoReflect()), nil
+			}
+		case "google.protobuf.Duration":
+			if d, ok := v.Interface().(time.Duration); ok {
+				return protoreflect.ValueOfMessage(durationpb.New(d).ProtoReflect()), nil
+			}
+		case "google.protobuf.Value":
+			data, err := json.Marshal(v.Interface())
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			value := &structpb.Value{}
+			if err := protojson.Unmarshal(data, value); err != nil {
+				return protoreflect.Value{}, err
+			}
+			return protoreflect.ValueOfMessage(value.ProtoReflect()), nil
+		default:
+			pv := newMessage()
+			return pv, toProto(v, pv.Message())
+		}
+		return protoreflect.Value{}, fmt.Errorf("can't convert %s to %s", v.Type(), fd.Message().FullName())
+	}
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		if v.Kind() == reflect.Bool {
+			return protoreflect.ValueOfBool(v.Bool()), nil
+		}
+	case protoreflect.StringKind:
+		if v.Kind() == reflect.String {
+			return protoreflect.ValueOfString(v.String()), nil
+		}
+	case protoreflect.BytesKind:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return protoreflect.ValueOfBytes(v.Bytes()), nil
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		if n, ok := intOf(v); ok {
+			return protoreflect.ValueOfInt32(int32(n)), nil
+		}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		if n, ok := intOf(v); ok {
+			return protoreflect.ValueOfInt64(n), nil
+		}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		if n, ok := intOf(v); ok {
+			return protoreflect.ValueOfUint32(uint32(n)), nil
+		}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uintptr {
+			return protoreflect.ValueOfUint64(v.Uint()), nil
+		}
+		if n, ok := intOf(v); ok {
+			return protoreflect.ValueOfUint64(uint64(n)), nil
+		}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		if v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64 {
+			if fd.Kind() == protoreflect.FloatKind {
+				return protoreflect.ValueOfFloat32(float32(v.Float())), nil
+			}
+			return protoreflect.ValueOfFloat64(v.Float()), nil
+		}
+	}
+	// scalars such as uuid.UUID are converted through their JSON encoding
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return protoreflect.Value{}, err
+	}
+	if fd.Kind() == protoreflect.StringKind {
+		var s string
+		if err := json.Unmarshal(data, &s); err == nil {
+			return protoreflect.ValueOfString(s), nil
+		}
+	}
+	return protoreflect.Value{}, fmt.Errorf("can't convert %s to %s", v.Type(), fd.Kind())
+}
+
+// intOf returns the value of the integer v.
+func intOf(v reflect.Value) (int64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int64(v.Uint()), true
+	}
+	return 0, false
+}
+
+// fromProto sets the fields of the struct v from the fields of m with the
+// same JSON names.
+func fromProto(m protoreflect.Message, v reflect.Value) error {
+	if variants, ok := grpcUnions[v.Type()]; ok {
+		return unionFromProto(m, variants, v.FieldByName("Value"))
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("can't convert %s to %s", m.Descriptor().FullName(), v.Type())
+	}
+	fields := jsonFields(v.Type())
+	fds := m.Descriptor().Fields()
+	for i := 0; i < fds.Len(); i++ {
+		fd := fds.Get(i)
+		index, ok := fields[fd.JSONName()]
+		if !ok || !m.Has(fd) {
+			continue
+		}
+		if err := setGoField(fieldByIndexAlloc(v, index), fd, m.Get(fd)); err != nil {
+			return fmt.Errorf("%s: %v", fd.JSONName(), err)
+		}
+	}
+	return nil
+}
+
+// unionFromProto sets value, the value of a union, to the variant held by
+// the oneof of m.
+func unionFromProto(m protoreflect.Message, variants map[string]reflect.Type, value reflect.Value) error {
+	fd := m.WhichOneof(m.Descriptor().Oneofs().ByName("value"))
+	if fd == nil {
+		return nil
+	}
+	t, ok := variants[fd.JSONName()]
+	if !ok {
+		return fmt.Errorf("unexpected variant %s", fd.JSONName())
+	}
+	variant := reflect.New(t).Elem()
+	if err := setGoValue(variant, fd, m.Get(fd)); err != nil {
+		return err
+	}
+	value.Set(variant)
+	return nil
+}
+
+// setGoField sets dst to pv, the value of the field fd.
+func setGoField(dst reflect.Value, fd protoreflect.FieldDescriptor, pv protoreflect.Value) error {
+	switch {
+	case fd.IsList():
+		if dst.Kind() != reflect.Slice {
+			return fmt.Errorf("can't convert a list to %s", dst.Type())
+		}
+		list := pv.List()
+		s := reflect.MakeSlice(dst.Type(), list.Len(), list.Len())
+		for i := 0; i < list.Len(); i++ {
+			if err := setGoValue(s.Index(i), fd, list.Get(i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(s)
+		return nil
+	case fd.IsMap():
+		if dst.Kind() != reflect.Map {
+			return fmt.Errorf("can't convert a map to %s", dst.Type())
+		}
+		out := reflect.MakeMapWithSize(dst.Type(), pv.Map().Len())
+		var err error
+		pv.Map().Range(func(k protoreflect.MapKey, value protoreflect.Value) bool {
+			key := reflect.New(dst.Type().Key()).Elem()
+			if err = setGoValue(key, fd.MapKey(), k.Value()); err != nil {
+				return false
+			}
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err = setGoValue(elem, fd.MapValue(), value); err != nil {
+				return false
+			}
+			out.SetMapIndex(key, elem)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		dst.Set(out)
+		return nil
+	}
+	return setGoValue(dst, fd, pv)
+}
+
+// setGoValue sets dst to pv, a single value of the kind of fd.
+func setGoValue(dst reflect.Value, fd protoreflect.FieldDescriptor, pv protoreflect.Value) error {
+	if dst.Kind() == reflect.Ptr {
+		p := reflect.New(dst.Type().Elem())
+		if err := setGoValue(p.Elem(), fd, pv); err != nil {
+			return err
+		}
+		dst.Set(p)
+		return nil
+	}
+	if fd.Kind() == protoreflect.MessageKind {
+		switch msg := pv.Message().Interface().(type) {
+		case *timestamppb.Timestamp:
+			return setConverted(dst, reflect.ValueOf(msg.AsTime()))
+		case *durationpb.Duration:
+			return setConverted(dst, reflect.ValueOf(msg.AsDuration()))
+		case *structpb.Value:
+			data, err := protojson.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			return json.Unmarshal(data, dst.Addr().Interface())
+		}
+		return fromProto(pv.Message(), dst)
+	}
+	switch dst.Kind() {
+	case reflect.Bool:
+		if fd.Kind() == protoreflect.BoolKind {
+			dst.SetBool(pv.Bool())
+			return nil
+		}
+	case reflect.String:
+		if fd.Kind() == protoreflect.StringKind {
+			dst.SetString(pv.String())
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch fd.Kind() {
+		case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind, protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+			dst.SetInt(pv.Int())
+			return nil
+		case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+			dst.SetInt(int64(pv.Uint()))
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		switch fd.Kind() {
+		case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind, protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+			dst.SetUint(uint64(pv.Int()))
+			return nil
+		case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+			dst.SetUint(pv.Uint())
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if fd.Kind() == protoreflect.FloatKind || fd.Kind() == protoreflect.DoubleKind {
+			dst.SetFloat(pv.Float())
+			return nil
+		}
+	case reflect.Slice:
+		if fd.Kind() == protoreflect.BytesKind && dst.Type().Elem().Kind() == reflect.Uint8 {
+			dst.SetBytes(pv.Bytes())
+			return nil
+		}
+	}
+	// scalars such as uuid.UUID are converted through their JSON encoding
+	data, err := json.Marshal(pv.Interface())
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst.Addr().Interface())
+}
+
+// setConverted sets dst to v, converted to the type of dst.
+func setConverted(dst, v reflect.Value) error {
+	if !v.Type().ConvertibleTo(dst.Type()) {
+		return fmt.Errorf("can't convert %s to %s", v.Type(), dst.Type())
+	}
+	dst.Set(v.Convert(dst.Type()))
+	return nil
+}
+
+var jsonFieldsCache sync.Map // reflect.Type to map[string][]int
+
+// jsonFields returns the indexes of the fields of the struct type t by JSON
+// name, as encoding/json names them: by their json tag or else their name,
+// with the fields of embedded structs promoted unless shadowed.
+func jsonFields(t reflect.Type) map[string][]int {
+	if fields, ok := jsonFieldsCache.Load(t); ok {
+		return fields.(map[string][]int)
+	}
+	fields := map[string][]int{}
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			fieldIndex := append(append([]int(nil), index...), i)
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				walk(ft, fieldIndex)
+				continue
+			}
+			if f.PkgPath != "" {
+				continue // unexported
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if prev, ok := fields[name]; ok && len(prev) <= len(fieldIndex) {
+				continue
+			}
+			fields[name] = fieldIndex
+		}
+	}
+	walk(t, nil)
+	jsonFieldsCache.Store(t, fields)
+	return fields
+}
+
+// fieldByIndex returns the field of v at index, or false if it is in a nil
+// embedded struct.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// fieldByIndexAlloc returns the field of v at index, allocating the nil
+// embedded structs on the way.
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+{{ end }}
+
+{{ define "pb" }}
+// Package pb holds the protobuf messages and gRPC service of {{ .IFace }},
+// generated from {{ .Pkg }}.proto by protoc with the protoc-gen-go and
+// protoc-gen-go-grpc plugins. Run go generate after changing the proto file.
+package pb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative {{ .Pkg }}.proto
+{{ end }}
+`
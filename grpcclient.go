@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// The retry policy of the default service config of the gRPC clients:
+// calls failing with UNAVAILABLE, which the server didn't get to handle,
+// are retried with exponential backoff.
+const (
+	grpcMaxAttempts    = 3
+	grpcInitialBackoff = 100 * time.Millisecond
+	grpcMaxBackoff     = time.Second
+)
+
+// grpcServiceConfig is a gRPC service config, see
+// https://github.com/grpc/grpc/blob/master/doc/service_config.md.
+type grpcServiceConfig struct {
+	MethodConfig []grpcMethodConfig `json:"methodConfig"`
+}
+
+type grpcMethodConfig struct {
+	Name        []grpcMethodName `json:"name"`
+	Timeout     string           `json:"timeout,omitempty"`
+	RetryPolicy grpcRetryPolicy  `json:"retryPolicy"`
+}
+
+// grpcMethodName names a method, or all the methods of the service without
+// a config of their own if Method is empty.
+type grpcMethodName struct {
+	Service string `json:"service"`
+	Method  string `json:"method,omitempty"`
+}
+
+type grpcRetryPolicy struct {
+	MaxAttempts          int      `json:"maxAttempts"`
+	InitialBackoff       string   `json:"initialBackoff"`
+	MaxBackoff           string   `json:"maxBackoff"`
+	BackoffMultiplier    float64  `json:"backoffMultiplier"`
+	RetryableStatusCodes []string `json:"retryableStatusCodes"`
+}
+
+// protoDuration formats d as the JSON of a google.protobuf.Duration, e.g.
+// "0.25s".
+func protoDuration(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
+
+// GRPCService returns the full name of the gRPC service of s, e.g.
+// "endpoints.UserService".
+func (s Service) GRPCService() string {
+	return s.Pkg + "." + s.IFaceName()
+}
+
+// GRPCServiceConfig returns the default service config of the gRPC clients
+// of s, in JSON: every method is retried by the retry policy, and the
+// methods with a latency budget time out after it, as they do over HTTP.
+func (s Service) GRPCServiceConfig() string {
+	retry := grpcRetryPolicy{
+		MaxAttempts:          grpcMaxAttempts,
+		InitialBackoff:       protoDuration(grpcInitialBackoff),
+		MaxBackoff:           protoDuration(grpcMaxBackoff),
+		BackoffMultiplier:    2,
+		RetryableStatusCodes: []string{"UNAVAILABLE"},
+	}
+	config := grpcServiceConfig{MethodConfig: []grpcMethodConfig{{Name: []grpcMethodName{{Service: s.GRPCService()}}, RetryPolicy: retry}}}
+	for _, f := range s.Funcs {
+		if f.Budget > 0 {
+			config.MethodConfig = append(config.MethodConfig, grpcMethodConfig{
+				Name:        []grpcMethodName{{Service: s.GRPCService(), Method: f.Name}},
+				Timeout:     protoDuration(f.Budget),
+				RetryPolicy: retry,
+			})
+		}
+	}
+	data, _ := json.MarshalIndent(config, "", "  ")
+	return string(data)
+}
+
+// GRPCMaxAttempts returns the attempts the default service config makes
+// at most per call.
+func (s Service) GRPCMaxAttempts() int {
+	return grpcMaxAttempts
+}
+
+const grpcClientTemplate = `
+{{ define "grpcclient" }}
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package {{ .Pkg }}
+{{ $svc := . }}
+import ({{ range $imp, $alias := .GRPCImports }}{{ $alias }} "{{ $imp }}"
+{{ end }}
+)
+
+// GRPCServiceConfig is the default service config of the gRPC clients of
+// {{ .IFace }}, as written to grpc_service_config.json: calls failing with
+// UNAVAILABLE are made up to {{ .GRPCMaxAttempts }} times, with exponential backoff{{ if .UsesBudgets }}, and the
+// calls of the methods with a latency budget time out after it{{ end }}.
+const GRPCServiceConfig = ` + "`" + `{{ .GRPCServiceConfig }}` + "`" + `
+
+// GRPCKeepalive are the keepalive parameters of the gRPC clients: the
+// connection is pinged after 30 seconds without activity, and closed when
+// the ping isn't acknowledged within 10 seconds.
+var GRPCKeepalive = keepalive.ClientParameters{
+	Time:    30 * time.Second,
+	Timeout: 10 * time.Second,
+}
+
+// GRPCDialOptions returns the options to dial the gRPC server of
+// {{ .IFace }} with: GRPCServiceConfig and GRPCKeepalive,{{ if .Compression }} compressing
+// the requests with GRPCCompression unless it is "",{{ end }} followed by
+// options, e.g. the transport credentials.
+func GRPCDialOptions(options ...grpc.DialOption) []grpc.DialOption { {{ if .Compression }}
+	defaults := []grpc.DialOption{
+		grpc.WithDefaultServiceConfig(GRPCServiceConfig),
+		grpc.WithKeepaliveParams(GRPCKeepalive),
+	}
+	if GRPCCompression != "" {
+		defaults = append(defaults, grpc.WithDefaultCallOptions(grpc.UseCompressor(GRPCCompression)))
+	}
+	return append(defaults, options...){{ else }}
+	return append([]grpc.DialOption{
+		grpc.WithDefaultServiceConfig(GRPCServiceConfig),
+		grpc.WithKeepaliveParams(GRPCKeepalive),
+	}, options...){{ end }}
+}
+{{ range .Funcs }}
+// {{ .Name }}GRPCClient returns an endpoint calling {{ .Name }} on the gRPC server conn
+// is connected to.
+func {{ .Name }}GRPCClient(conn *grpc.ClientConn, options ...grpctransport.ClientOption) endpoint.Endpoint {
+	return grpctransport.NewClient(
+		conn,
+		"{{ $svc.GRPCService }}",
+		"{{ .Name }}",
+		EncodeGRPC{{ .Name }}Request,
+		DecodeGRPC{{ .Name }}Response,
+		pb.{{ ProtoGoName .ResponseName }}{},{{ if or $svc.Tenant $svc.Tracing }}
+		append([]grpctransport.ClientOption{grpctransport.ClientBefore({{ if $svc.Tenant }}tenantToGRPCMetadata{{ if $svc.Tracing }}, {{ end }}{{ end }}{{ if $svc.Tracing }}traceToGRPCMetadata{{ end }})}, options...)...,{{ else }}
+		options...,{{ end }}
+	).Endpoint()
+}
+
+// EncodeGRPC{{ .Name }}Request converts a {{ $svc.Request . }} into a *pb.{{ ProtoGoName .RequestName }}.
+func EncodeGRPC{{ .Name }}Request(_ context.Context, request interface{}) (interface{}, error) {
+	req := &pb.{{ ProtoGoName .RequestName }}{}
+	if err := toProto(reflect.ValueOf(request), req.ProtoReflect()); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// DecodeGRPC{{ .Name }}Response converts a *pb.{{ ProtoGoName .ResponseName }} into a {{ $svc.Response . }}.
+func DecodeGRPC{{ .Name }}Response(_ context.Context, grpcRes interface{}) (interface{}, error) {
+	var response {{ $svc.Response . }}
+	m := grpcRes.(*pb.{{ ProtoGoName .ResponseName }}).ProtoReflect(){{ if .Unwrap }}
+	if fd := m.Descriptor().Fields().Get(0); m.Has(fd) {
+		if err := setGoField(reflect.ValueOf(&response).Elem(), fd, m.Get(fd)); err != nil {
+			return nil, err
+		}
+	}{{ else }}
+	if err := fromProto(m, reflect.ValueOf(&response).Elem()); err != nil {
+		return nil, err
+	}{{ end }}
+	return response, nil
+}
+{{ end }}{{ if .EndpointSet }}
+// NewGRPCClient returns the Endpoints calling the gRPC server conn is
+// connected to, a client implementing {{ .IFace }}. Dial conn with
+// GRPCDialOptions.
+func NewGRPCClient(conn *grpc.ClientConn, options ...grpctransport.ClientOption) Endpoints {
+	return Endpoints{ {{ range .Funcs }}
+		{{ .Name }}Endpoint: {{ $svc.ClientEndpoint . (printf "%sGRPCClient(conn, options...)" .Name) }},{{ end }}
+	}
+}
+{{ end }}{{ if .Tenant }}
+// tenantToGRPCMetadata puts the tenant of the context of a gRPC call into
+// its "tenant" metadata.
+func tenantToGRPCMetadata(ctx context.Context, md *metadata.MD) context.Context {
+	if tenant, ok := TenantFromContext(ctx); ok {
+		md.Set("tenant", tenant)
+	}
+	return ctx
+}
+{{ end }}{{ if .Tracing }}
+// traceToGRPCMetadata propagates the trace of the context of a gRPC call in
+// its metadata, as injected by the global TextMapPropagator.
+func traceToGRPCMetadata(ctx context.Context, md *metadata.MD) context.Context {
+	otel.GetTextMapPropagator().Inject(ctx, grpcMetadataCarrier(*md))
+	return ctx
+}
+{{ end }}{{ end }}
+`
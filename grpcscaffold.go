@@ -0,0 +1,194 @@
+package main
+
+import "errors"
+
+// checkGRPCScaffold returns an error if s, without the http transport, has
+// no gRPC server for -scaffold to serve.
+func checkGRPCScaffold(s Service) error {
+	if !s.GRPC {
+		return errors.New("-scaffold serves the HTTP handlers or the gRPC server, add http or grpc to -transports")
+	}
+	return nil
+}
+
+const grpcScaffoldTemplate = `
+{{ define "grpcscaffold" }}
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+
+// Command {{ .CommandName }} serves {{ .IFace }} over gRPC, along with the gRPC
+// health service. The implementation of the service is returned by
+// newService, in service.go.{{ if .Discovery }} It registers itself in Consul while serving.{{ end }}
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"{{ if .Instrumenting }}
+	"net/http"{{ end }}
+	"os"
+	"os/signal"{{ if .Discovery }}
+	"strconv"{{ end }}
+	"syscall"
+	"time"
+
+	"github.com/go-kit/kit/log"{{ if .Discovery }}
+	consulsd "github.com/go-kit/kit/sd/consul"
+	consulapi "github.com/hashicorp/consul/api"{{ end }}{{ if .Instrumenting }}
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"{{ end }}{{ if .Tracing }}
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"{{ end }}
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	{{ .Pkg }} "{{ .ImportPath }}"
+	"{{ .ImportPath }}/pb"
+)
+
+// envPrefix is the prefix of the environment variables configuring the
+// command.
+const envPrefix = "{{ .EnvPrefix }}"
+
+// Config is the configuration of the command, read from the {{ .EnvPrefix }}*
+// environment variables and overridden by the command line flags.
+type Config struct {
+	Addr            string        // listen address of the gRPC server{{ if .Instrumenting }}
+	MetricsAddr     string        // listen address of the Prometheus metrics, served on /metrics{{ end }}{{ if .Discovery }}
+	AdvertiseAddr   string        // address registered in Consul, the hostname and the port of Addr if empty{{ end }}
+	ShutdownTimeout time.Duration // to finish the calls in flight when shutting down{{ if .Repository }}
+	DatabaseDriver  string        // name of the database/sql driver of the repository
+	DatabaseURL     string        // data source name of the repository{{ end }}
+}
+
+// loadConfig returns the configuration set by the environment and args.
+func loadConfig(args []string) (Config, error) {
+	cfg := Config{
+		Addr:            ":8080",{{ if .Instrumenting }}
+		MetricsAddr:     ":8081",{{ end }}
+		ShutdownTimeout: 15 * time.Second,{{ if .Repository }}
+		DatabaseDriver:  "postgres",{{ end }}
+	}
+	if v, ok := os.LookupEnv(envPrefix + "ADDR"); ok {
+		cfg.Addr = v
+	}{{ if .Instrumenting }}
+	if v, ok := os.LookupEnv(envPrefix + "METRICS_ADDR"); ok {
+		cfg.MetricsAddr = v
+	}{{ end }}{{ if .Discovery }}
+	if v, ok := os.LookupEnv(envPrefix + "ADVERTISE_ADDR"); ok {
+		cfg.AdvertiseAddr = v
+	}{{ end }}{{ if .Repository }}
+	if v, ok := os.LookupEnv(envPrefix + "DATABASE_DRIVER"); ok {
+		cfg.DatabaseDriver = v
+	}
+	if v, ok := os.LookupEnv(envPrefix + "DATABASE_URL"); ok {
+		cfg.DatabaseURL = v
+	}{{ end }}
+	if v, ok := os.LookupEnv(envPrefix + "SHUTDOWN_TIMEOUT"); ok {
+		var err error
+		if cfg.ShutdownTimeout, err = time.ParseDuration(v); err != nil {
+			return cfg, fmt.Errorf("%sSHUTDOWN_TIMEOUT: %v", envPrefix, err)
+		}
+	}
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.StringVar(&cfg.Addr, "addr", cfg.Addr, "listen ` + "`address`" + ` of the gRPC server ($"+envPrefix+"ADDR)"){{ if .Instrumenting }}
+	fs.StringVar(&cfg.MetricsAddr, "metrics-addr", cfg.MetricsAddr, "listen ` + "`address`" + ` of the metrics ($"+envPrefix+"METRICS_ADDR)"){{ end }}{{ if .Discovery }}
+	fs.StringVar(&cfg.AdvertiseAddr, "advertise-addr", cfg.AdvertiseAddr, "` + "`address`" + ` registered in Consul ($"+envPrefix+"ADVERTISE_ADDR)"){{ end }}
+	fs.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "time to finish the calls in flight ($"+envPrefix+"SHUTDOWN_TIMEOUT)"){{ if .Repository }}
+	fs.StringVar(&cfg.DatabaseDriver, "database-driver", cfg.DatabaseDriver, "database/sql ` + "`driver`" + ` of the repository ($"+envPrefix+"DATABASE_DRIVER)")
+	fs.StringVar(&cfg.DatabaseURL, "database-url", cfg.DatabaseURL, "data source ` + "`name`" + ` of the repository ($"+envPrefix+"DATABASE_URL)"){{ end }}
+	return cfg, fs.Parse(args)
+}
+
+func main() {
+	logger := log.NewJSONLogger(log.NewSyncWriter(os.Stderr))
+	logger = log.With(logger, "ts", log.DefaultTimestampUTC)
+
+	cfg, err := loadConfig(os.Args[1:])
+	if err != nil {
+		logger.Log("err", err)
+		os.Exit(2)
+	}
+	svc, err := newService(cfg, logger)
+	if err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}{{ if .Logging }}
+	svc = {{ .Pkg }}.LoggingMiddleware(log.With(logger, "component", "service"))(svc){{ end }}{{ if .Instrumenting }}
+	instrumenting, err := {{ .Pkg }}.NewPrometheusInstrumentingMiddleware(prometheus.DefaultRegisterer)
+	if err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}
+	svc = instrumenting(svc){{ end }}{{ if .Tracing }}
+
+	// the spans of the endpoints continue the traces propagated by the W3C
+	// Trace Context metadata of the calls, and are exported by the global
+	// TracerProvider, which discards them until set, e.g. to one with an OTLP
+	// exporter in newService
+	otel.SetTextMapPropagator(propagation.TraceContext{}){{ end }}
+
+	server := grpc.NewServer()
+	pb.Register{{ ProtoGoName .IFaceName }}Server(server, {{ .Pkg }}.NewGRPCServer(svc))
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	lis, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}
+	errc := make(chan error, 2)
+	go func() {
+		logger.Log("msg", "listening", "addr", lis.Addr())
+		errc <- server.Serve(lis)
+	}(){{ if .Instrumenting }}
+	metrics := &http.Server{Addr: cfg.MetricsAddr, Handler: promhttp.Handler()}
+	go func() {
+		logger.Log("msg", "serving metrics", "addr", metrics.Addr)
+		errc <- metrics.ListenAndServe()
+	}(){{ end }}{{ if .Discovery }}
+	registrar, err := newRegistrar(cfg, logger)
+	if err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}
+	registrar.Register(){{ end }}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	select {
+	case err := <-errc:
+		logger.Log("err", err)
+		os.Exit(1)
+	case s := <-sig:
+		logger.Log("msg", "shutting down", "signal", s)
+	}
+{{ if .Discovery }}	registrar.Deregister()
+{{ end }}	healthServer.Shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	failed := false
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		logger.Log("err", "calls still in flight after the shutdown timeout, canceling them")
+		server.Stop()
+		failed = true
+	}{{ if .Instrumenting }}
+	if err := metrics.Shutdown(ctx); err != nil {
+		logger.Log("err", err)
+		failed = true
+	}{{ end }}
+	if failed {
+		os.Exit(1)
+	}
+}
+{{ if .Discovery }}{{ template "registrar" . }}{{ end }}{{ end }}
+`
@@ -0,0 +1,82 @@
+package main
+
+const harnessTemplate = `
+{{ define "harness" }}
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+
+package {{ .Pkg }}
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"{{ if .UsesScopes }}
+
+	"{{ .ImportPath }}/scopes"{{ end }}
+)
+
+var updateGolden = flag.Bool("update-golden", false, "record the golden files of TestHTTPGolden")
+
+// configureGoldenService, when set by a test file of the package, configures
+// the mock service that TestHTTPGolden replays the fixtures against.
+var configureGoldenService func(*MockService)
+
+// TestHTTPGolden replays the request fixtures in testdata/golden/<Method>/*.json
+// against MakeHTTPHandler, or the handler of their group, backed by MockService and compares the responses with
+// the corresponding .golden files. Run it with -update-golden to record them.{{ if .UsesScopes }}
+// The requests are made with a token granted every scope.{{ end }}
+func TestHTTPGolden(t *testing.T) {
+	svc := &MockService{}
+	if configureGoldenService != nil {
+		configureGoldenService(svc)
+	}
+	handlers := map[string]http.Handler{
+		"": MakeHTTPHandler(svc),{{ range .Groups }}
+		"{{ .Name }}": {{ .Handler }}(svc),{{ end }}
+	}
+
+	routes := []struct{ name, group, method, path string }{ {{ range .Funcs }}
+		{"{{ .Name }}", "{{ .Group }}", "{{ .HTTPMethod }}", "{{ if eq $.Tenant "path" }}/t/test{{ end }}{{ .HTTPPath }}"},{{ end }}
+	}
+	for _, route := range routes {
+		fixtures, err := filepath.Glob(filepath.Join("testdata", "golden", route.name, "*.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, fixture := range fixtures {
+			t.Run(route.name+"/"+filepath.Base(fixture), func(t *testing.T) {
+				body, err := ioutil.ReadFile(fixture)
+				if err != nil {
+					t.Fatal(err)
+				}
+				w := httptest.NewRecorder(){{ if .UsesScopes }}
+				r := httptest.NewRequest(route.method, route.path, bytes.NewReader(body))
+				handlers[route.group].ServeHTTP(w, r.WithContext(ContextWithScopes(r.Context(), scopes.All))){{ else }}
+				handlers[route.group].ServeHTTP(w, httptest.NewRequest(route.method, route.path, bytes.NewReader(body))){{ end }}
+				got := fmt.Sprintf("%d\n%s\n%s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+
+				golden := strings.TrimSuffix(fixture, ".json") + ".golden"
+				if *updateGolden {
+					if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+						t.Fatal(err)
+					}
+					return
+				}
+				want, err := ioutil.ReadFile(golden)
+				if err != nil {
+					t.Fatalf("%v (run with -update-golden to record it)", err)
+				}
+				if got != string(want) {
+					t.Errorf("response does not match %s\ngot:\n%s\nwant:\n%s", golden, got, want)
+				}
+			})
+		}
+	}
+}
+{{ end }}
+`
@@ -0,0 +1,83 @@
+package main
+
+import "time"
+
+// resolveHedges sets the hedging delay of the methods of fns with a
+// "//kit:hedge <delay>" annotation, whose client endpoints are wrapped in
+// Hedge. Only idempotent methods may be annotated.
+func resolveHedges(fns []Func) error {
+	for i := range fns {
+		fn := &fns[i]
+		a, ok := fn.Annotation("hedge")
+		if !ok {
+			continue
+		}
+		if len(a.Args) != 1 {
+			return fn.errorf(a, "kit:hedge takes exactly one delay")
+		}
+		d, err := time.ParseDuration(a.Args[0])
+		if err != nil || d <= 0 {
+			return fn.errorf(a, "kit:hedge: invalid delay %q", a.Args[0])
+		}
+		fn.HedgeDelay = d
+	}
+	return nil
+}
+
+// UsesHedges reports whether any method is hedged by kit:hedge.
+func (s Service) UsesHedges() bool {
+	for _, f := range s.Funcs {
+		if f.HedgeDelay > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+const hedgeTemplate = `
+{{ define "hedge" }}{{ if .UsesHedges }}
+// Hedging delays of the client endpoints of the methods annotated with
+// kit:hedge, which are idempotent.
+const ({{ range .Funcs }}{{ if .HedgeDelay }}
+	{{ .Name }}HedgeDelay = {{ DurationLiteral .HedgeDelay }}{{ end }}{{ end }}
+)
+{{ end }}
+// Hedge returns an endpoint.Middleware that sends a second, hedged request when
+// the first one has not completed within delay and returns the first successful
+// response. The slower request is canceled. Only use it for idempotent methods:
+// the clients wrap the endpoints of the methods annotated with kit:hedge in it.
+func Hedge(delay time.Duration) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			type result struct {
+				response interface{}
+				err      error
+			}
+			results := make(chan result, 2)
+			attempt := func() {
+				response, err := next(ctx, request)
+				results <- result{response, err}
+			}
+
+			go attempt()
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+			select {
+			case r := <-results:
+				return r.response, r.err
+			case <-timer.C:
+				go attempt()
+			}
+			r := <-results
+			if r.err != nil {
+				r = <-results
+			}
+			return r.response, r.err
+		}
+	}
+}
+{{ end }}
+`
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// predeclared are the predeclared identifiers of Go, which the generated
+// code refers to, e.g. string and len, and mustn't be shadowed by the
+// identifiers it declares.
+var predeclared = map[string]bool{
+	"any": true, "bool": true, "byte": true, "comparable": true, "complex64": true, "complex128": true,
+	"error": true, "float32": true, "float64": true, "int": true, "int8": true, "int16": true,
+	"int32": true, "int64": true, "rune": true, "string": true, "uint": true, "uint8": true,
+	"uint16": true, "uint32": true, "uint64": true, "uintptr": true,
+	"true": true, "false": true, "iota": true, "nil": true,
+	"append": true, "cap": true, "clear": true, "close": true, "complex": true, "copy": true,
+	"delete": true, "imag": true, "len": true, "make": true, "max": true, "min": true, "new": true,
+	"panic": true, "print": true, "println": true, "real": true, "recover": true,
+}
+
+// generatedLocals are the identifiers the generated methods and endpoints
+// declare next to the parameters and results of the methods: their
+// receivers, e.g. mw, and their local variables, e.g. req.
+var generatedLocals = map[string]bool{
+	"svc": true, "request": true, "req": true, "response": true, "res": true,
+	"m": true, "mw": true, "e": true, "d": true, "s": true, "next": true,
+	"begin": true, "lvs": true, "tx": true, "beginErr": true, "fixture": true, "ferr": true,
+}
+
+// Rename is an identifier of the generated code renamed from the name in
+// the interface, as it would collide with a Go keyword, a predeclared
+// identifier or an identifier of the generated code.
+type Rename struct {
+	From, To string
+}
+
+// safeIdent returns name, or name followed by underscores if it is a
+// keyword or taken, such as len_ for len.
+func safeIdent(name string, taken func(string) bool) string {
+	for token.IsKeyword(name) || taken(name) {
+		name += "_"
+	}
+	return name
+}
+
+// resolveIdents renames the parameters and results of the methods of fns
+// named like a predeclared identifier, e.g. len or string, or like an
+// identifier the generated code declares next to them, e.g. req, in the
+// generated code. Their fields and JSON names are kept.
+func resolveIdents(fns []Func) error {
+	for i := range fns {
+		fn := &fns[i]
+		names := map[string]bool{}
+		for _, p := range append(append([]Param(nil), fn.Params...), fn.Res...) {
+			names[p.Name] = true
+		}
+		for _, params := range [][]Param{fn.Params, fn.Res} {
+			for j := range params {
+				p := &params[j]
+				if p.Name == "" || p.Name == "_" {
+					continue
+				}
+				ctx := p.Name == "ctx" && p.Type != "context.Context"
+				if !predeclared[p.Name] && !generatedLocals[p.Name] && !ctx {
+					continue
+				}
+				ident := safeIdent(p.Name+"_", func(name string) bool { return names[name] })
+				names[ident] = true
+				p.Ident = ident
+				fn.Renames = append(fn.Renames, Rename{p.Name, ident})
+			}
+		}
+	}
+	return nil
+}
+
+// resolveUnnamed names the unnamed results of the methods of fns, which name
+// the fields of their responses, warning about each generated method, and
+// their unnamed context parameters, the others failing checkInterface: after
+// their types if possible, e.g. ctx, user, users or err, and r0, r1, ...
+// otherwise.
+func resolveUnnamed(fns []Func) error {
+	for i := range fns {
+		fn := &fns[i]
+		names := map[string]bool{}
+		quals := map[string]bool{}
+		for _, p := range append(append([]Param(nil), fn.Params...), fn.Res...) {
+			names[p.Name] = true
+			for _, q := range typeQualifiers.FindAllStringSubmatch(p.Type, -1) {
+				quals[q[1]] = true
+			}
+		}
+		var named []string
+		for _, ps := range []struct {
+			params []Param
+			prefix string
+		}{{fn.Params, "p"}, {fn.Res, "r"}} {
+			for j := range ps.params {
+				p := &ps.params[j]
+				if p.Name != "" && p.Name != "_" || ps.prefix == "p" && p.Type != "context.Context" {
+					continue
+				}
+				name := typeName(p.Type)
+				if name == "" || names[name] || quals[name] || token.IsKeyword(name) || predeclared[name] || generatedLocals[name] {
+					name = safeIdent(ps.prefix+strconv.Itoa(j), func(name string) bool { return names[name] })
+				}
+				names[name] = true
+				p.Name, p.Field = name, Exported(name)
+				if p.Type != "context.Context" && p.Type != "error" {
+					named = append(named, name)
+				}
+			}
+		}
+		// the fields of skipped methods aren't generated
+		if len(named) == 0 || fn.Skip {
+			continue
+		}
+		if fn.embedded != nil {
+			fn.warnf("naming the fields of its response holding unnamed results %s, declare the method in the interface instead to choose", strings.Join(named, ", "))
+		} else {
+			fn.warnf("naming the fields of its response holding unnamed results %s, name them in the interface to choose", strings.Join(named, ", "))
+		}
+	}
+	return nil
+}
+
+// typeQualifiers matches the package qualifiers of a type, e.g. model in
+// []*model.User.
+var typeQualifiers = regexp.MustCompile(`(\w+)\.`)
+
+// typeName returns the name of a parameter of type typ after the type, e.g.
+// ctx for context.Context, err for error, user for *model.User and users
+// for []*model.User, or "" if typ isn't a plain named type.
+func typeName(typ string) string {
+	switch typ {
+	case "context.Context":
+		return "ctx"
+	case "error":
+		return "err"
+	}
+	typ = strings.TrimLeft(typ, "*")
+	plural := strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "...")
+	typ = strings.TrimLeft(strings.TrimPrefix(strings.TrimPrefix(typ, "[]"), "..."), "*")
+	typ = typ[strings.LastIndex(typ, ".")+1:]
+	if !token.IsIdentifier(typ) || predeclared[typ] {
+		return ""
+	}
+	name := lowerFirst(typ)
+	if plural {
+		if strings.HasSuffix(name, "s") {
+			return name + "es"
+		}
+		return name + "s"
+	}
+	return name
+}
+
+// Renamed lists the identifiers of the endpoint of f renamed from the
+// interface, e.g. "len as len_, string as string_": those of its results,
+// of the parameters it converts and of its request and response types. The
+// other parameters are only read from the fields of the request.
+func (f Func) Renamed() string {
+	idents := map[string]bool{
+		f.RequestName:  TakesParams(f) && f.RequestType == nil,
+		f.ResponseName: f.UnwrappedResult() == nil && f.ResponseType == nil,
+	}
+	for _, p := range f.Res {
+		idents[p.VarName()] = true
+	}
+	for _, p := range f.Params {
+		if p.ToDomain != "" {
+			idents[p.VarName()] = true
+		}
+	}
+	var renames []string
+	for _, r := range f.Renames {
+		if idents[r.To] {
+			renames = append(renames, r.From+" as "+r.To)
+		}
+	}
+	return strings.Join(renames, ", ")
+}
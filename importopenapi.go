@@ -0,0 +1,505 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/imports"
+	"gopkg.in/yaml.v2"
+)
+
+// runImport writes the Go interface described by the API spec args[0], to
+// stdout or to a file in the -o directory, which names its package.
+func runImport(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	pkg := "api"
+	if *flagOutDir != "" {
+		abs, err := filepath.Abs(*flagOutDir)
+		if err != nil {
+			return err
+		}
+		pkg = strings.ToLower(strings.TrimSuffix(goIdent(filepath.Base(abs), false), "_"))
+	}
+	var (
+		src   []byte
+		iface string
+		err   error
+	)
+	switch strings.ToLower(filepath.Ext(args[0])) {
+	case ".yaml", ".yml", ".json":
+		src, iface, err = importOpenAPI(args[0], pkg)
+	case ".proto":
+		src, iface, err = importProto(args[0], pkg)
+	default:
+		return fmt.Errorf("%s: unsupported spec, want an OpenAPI or Swagger spec (.yaml, .yml or .json) or a .proto file", args[0])
+	}
+	if err != nil {
+		return err
+	}
+	if *flagOutDir == "" {
+		_, err := os.Stdout.Write(src)
+		return err
+	}
+
+	path := filepath.Join(*flagOutDir, snakeCase(iface)+".go")
+	if old, err := ioutil.ReadFile(path); err == nil && !isGenerated(old) {
+		return fmt.Errorf("%s exists and wasn't generated by KitBoiler", path)
+	}
+	if err := os.MkdirAll(*flagOutDir, 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, src, 0644); err != nil {
+		return err
+	}
+	if dir, err := importPath(*flagOutDir); err == nil {
+		fmt.Printf("wrote %s, generate its package with: kitboiler %s.%s\n", path, dir, iface)
+	}
+	return nil
+}
+
+// specImporter derives Go declarations from the schemas of an OpenAPI
+// spec, as decoded with ordered mappings.
+type specImporter struct {
+	spec      interface{}
+	decls     bytes.Buffer
+	declared  map[string]bool // Go type names
+	flattened map[string]bool // $refs of the request and response schemas turned into parameters and results
+}
+
+// importedMethod is a method of the imported interface.
+type importedMethod struct {
+	Name     string
+	Doc      []string
+	Params   []string // "name type"
+	Optional []string
+	Results  []string
+}
+
+// importOpenAPI returns the Go source of package pkg declaring the
+// interface described by the OpenAPI 3 or Swagger 2 spec at path, with a
+// method per operation, and the types of its schemas. The parameters of a
+// method are those of its operation and the properties of its JSON request
+// body, its results the properties of its JSON success response, unless
+// they refer to a schema not named after the method, such as User, which is
+// then taken and returned as a whole. Properties that aren't required are
+// marked with kit:optional.
+func importOpenAPI(path, pkg string) ([]byte, string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, "", fmt.Errorf("%s: %v", path, err)
+	}
+	im := &specImporter{spec: doc, declared: map[string]bool{}, flattened: map[string]bool{}}
+
+	var methods []importedMethod
+	names := map[string]bool{}
+	for _, item := range entries(get(doc, "paths")) {
+		route := fmt.Sprint(item.Key)
+		for _, verb := range []string{"get", "put", "post", "delete", "patch"} {
+			op := get(item.Value, verb)
+			if op == nil {
+				continue
+			}
+			m := im.method(route, verb, item.Value, op)
+			if names[m.Name] {
+				return nil, "", fmt.Errorf("%s: more than one operation named %s", path, m.Name)
+			}
+			names[m.Name] = true
+			methods = append(methods, m)
+		}
+	}
+	if len(methods) == 0 {
+		return nil, "", fmt.Errorf("%s: no operations", path)
+	}
+	for _, schemas := range []string{"#/components/schemas/", "#/definitions/"} {
+		keys := strings.Split(strings.Trim(schemas, "#/"), "/")
+		for _, item := range entries(get(doc, keys...)) {
+			if ref := schemas + fmt.Sprint(item.Key); !im.flattened[ref] {
+				im.refType(ref)
+			}
+		}
+	}
+
+	iface := goIdent(fmt.Sprint(get(doc, "info", "title")), true)
+	if iface == "" || get(doc, "info", "title") == nil {
+		iface = "Service"
+	}
+	desc, _ := get(doc, "info", "description").(string)
+	src, err := renderImport(path, pkg, iface, strings.Split(desc, "\n"), methods, im.decls.Bytes())
+	return src, iface, err
+}
+
+// renderImport returns the source of the package pkg declaring the
+// interface iface imported from the spec at path, documented by doc, with
+// methods, followed by the type declarations decls.
+func renderImport(path, pkg, iface string, doc []string, methods []importedMethod, decls []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.\n")
+	fmt.Fprintf(&buf, "// Imported from %s with kitboiler import: edit the spec and import it again.\n\n", filepath.Base(path))
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	writeDoc(&buf, "", strings.Join(doc, " "))
+	fmt.Fprintf(&buf, "type %s interface {\n", iface)
+	for i, m := range methods {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		for _, line := range m.Doc {
+			fmt.Fprintf(&buf, "\t// %s\n", line)
+		}
+		if len(m.Optional) > 0 {
+			fmt.Fprintf(&buf, "\t//kit:optional %s\n", strings.Join(m.Optional, " "))
+		}
+		fmt.Fprintf(&buf, "\t%s(%s) (%s)\n", m.Name, strings.Join(m.Params, ", "), strings.Join(m.Results, ", "))
+	}
+	buf.WriteString("}\n")
+	buf.Write(decls)
+
+	src, err := imports.Process(filepath.Join(*flagOutDir, snakeCase(iface)+".go"), buf.Bytes(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("imported %s: %v", path, err)
+	}
+	return src, nil
+}
+
+// method returns the method of the operation op on route with the HTTP
+// method verb, whose path item is item.
+func (im *specImporter) method(route, verb string, item, op interface{}) importedMethod {
+	m := importedMethod{Params: []string{"ctx context.Context"}}
+	if id, ok := get(op, "operationId").(string); ok {
+		m.Name = goIdent(id, true)
+	} else {
+		m.Name = goIdent(verb+" "+strings.NewReplacer("{", "by ", "}", "").Replace(route), true)
+	}
+	// the description usually repeats the summary, as the spec of a
+	// generated package does
+	summary, _ := get(op, "summary").(string)
+	description, _ := get(op, "description").(string)
+	if strings.HasPrefix(strings.Join(strings.Fields(description), " "), strings.Join(strings.Fields(summary), " ")) {
+		summary = ""
+	}
+	for _, text := range []string{summary, description} {
+		if text = strings.TrimSpace(text); text != "" {
+			m.Doc = append(m.Doc, strings.Split(text, "\n")...)
+		}
+	}
+	if len(m.Doc) > 0 {
+		m.Doc = append(m.Doc, "")
+	}
+	m.Doc = append(m.Doc, strings.ToUpper(verb)+" "+route)
+
+	taken := map[string]bool{"ctx": true, "err": true}
+	unique := func(name string) string {
+		ident := goIdent(name, false)
+		for i := 2; taken[ident]; i++ {
+			ident = goIdent(name, false) + strconv.Itoa(i)
+		}
+		taken[ident] = true
+		return ident
+	}
+	param := func(name string, schema interface{}, required bool) {
+		ident := unique(name)
+		m.Params = append(m.Params, ident+" "+im.goType(schema, m.Name+goIdent(name, true)))
+		if !required {
+			m.Optional = append(m.Optional, ident)
+		}
+	}
+	params := append(asSlice(get(item, "parameters")), asSlice(get(op, "parameters"))...)
+	var body interface{}
+	bodyName := ""
+	for _, p := range params {
+		p = resolve(im.spec, p)
+		name, _ := get(p, "name").(string)
+		switch get(p, "in") {
+		case "path", "query":
+			schema := get(p, "schema")
+			if schema == nil {
+				schema = p // Swagger 2 declares the type on the parameter itself
+			}
+			param(name, schema, get(p, "required") == true)
+		case "body":
+			body, bodyName = get(p, "schema"), name
+		}
+	}
+	if body == nil {
+		body = get(op, "requestBody", "content", "application/json", "schema")
+	}
+	if body != nil {
+		if props, required, ok := im.flatten(body, m.Name+"Request"); ok {
+			for _, prop := range props {
+				param(fmt.Sprint(prop.Key), prop.Value, required[fmt.Sprint(prop.Key)])
+			}
+		} else {
+			if bodyName == "" {
+				bodyName = refName(body, "body")
+			}
+			param(bodyName, body, true)
+		}
+	}
+
+	for _, res := range entries(get(op, "responses")) {
+		if !strings.HasPrefix(fmt.Sprint(res.Key), "2") {
+			continue
+		}
+		res := resolve(im.spec, res.Value)
+		schema := get(res, "content", "application/json", "schema")
+		if schema == nil {
+			schema = get(res, "schema")
+		}
+		if schema == nil {
+			break
+		}
+		if props, _, ok := im.flatten(schema, m.Name+"Response"); ok {
+			for _, prop := range props {
+				m.Results = append(m.Results, unique(fmt.Sprint(prop.Key))+" "+im.goType(prop.Value, m.Name+goIdent(fmt.Sprint(prop.Key), true)))
+			}
+		} else {
+			m.Results = append(m.Results, unique(refName(schema, "result"))+" "+im.goType(schema, m.Name+"Result"))
+		}
+		break
+	}
+	m.Results = append(m.Results, "err error")
+	return m
+}
+
+// flatten returns the properties of the request or response schema of a
+// method, and which are required, if it is an inline object or a reference
+// to the schema named name.
+func (im *specImporter) flatten(schema interface{}, name string) (yaml.MapSlice, map[string]bool, bool) {
+	if ref, ok := get(schema, "$ref").(string); ok {
+		if !strings.EqualFold(ref[strings.LastIndex(ref, "/")+1:], name) {
+			return nil, nil, false
+		}
+		im.flattened[ref] = true
+		schema = resolve(im.spec, schema)
+	} else if get(schema, "properties") == nil {
+		return nil, nil, false
+	}
+	required := map[string]bool{}
+	for _, r := range asSlice(get(schema, "required")) {
+		required[fmt.Sprint(r)] = true
+	}
+	return entries(get(schema, "properties")), required, true
+}
+
+// refName returns the name of the schema schema refers to, or def.
+func refName(schema interface{}, def string) string {
+	if ref, ok := get(schema, "$ref").(string); ok {
+		return ref[strings.LastIndex(ref, "/")+1:]
+	}
+	return def
+}
+
+// goType returns the Go type of schema, declaring the types it refers to.
+// Inline objects are declared as types named hint.
+func (im *specImporter) goType(schema interface{}, hint string) string {
+	typ := im.baseType(schema, hint)
+	nullable := get(schema, "nullable") == true || get(schema, "x-nullable") == true
+	if nullable && !strings.HasPrefix(typ, "*") && !strings.HasPrefix(typ, "[]") && !strings.HasPrefix(typ, "map[") && typ != "interface{}" {
+		return "*" + typ
+	}
+	return typ
+}
+
+func (im *specImporter) baseType(schema interface{}, hint string) string {
+	if ref, ok := get(schema, "$ref").(string); ok {
+		return im.refType(ref)
+	}
+	if all := asSlice(get(schema, "allOf")); len(all) == 1 {
+		return im.goType(all[0], hint)
+	} else if len(all) > 1 {
+		im.declare(hint, schema)
+		return hint
+	}
+	if get(schema, "oneOf") != nil || get(schema, "anyOf") != nil {
+		return "interface{}"
+	}
+	format, _ := get(schema, "format").(string)
+	switch get(schema, "type") {
+	case "string":
+		switch format {
+		case "date-time":
+			return "time.Time"
+		case "byte", "binary":
+			return "[]byte"
+		}
+		return "string"
+	case "integer":
+		if format == "int32" {
+			return "int32"
+		}
+		return "int64"
+	case "number":
+		if format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + im.goType(get(schema, "items"), hint+"Item")
+	case "object", nil:
+		if get(schema, "properties") != nil {
+			im.declare(hint, schema)
+			return hint
+		}
+		if values := get(schema, "additionalProperties"); entries(values) != nil {
+			return "map[string]" + im.goType(values, hint+"Value")
+		}
+		if get(schema, "type") == "object" {
+			return "map[string]interface{}"
+		}
+	}
+	return "interface{}"
+}
+
+// refType returns the Go type of the schema referred to by ref, declaring it.
+func (im *specImporter) refType(ref string) string {
+	name := goIdent(ref[strings.LastIndex(ref, "/")+1:], true)
+	im.declare(name, resolve(im.spec, yaml.MapSlice{{Key: "$ref", Value: ref}}))
+	return name
+}
+
+// declare declares the type name of schema, unless it is declared already:
+// structs for objects, string or integer types with a constant per value
+// for enums, and the Go type of other schemas.
+func (im *specImporter) declare(name string, schema interface{}) {
+	if im.declared[name] {
+		return
+	}
+	im.declared[name] = true
+	var buf bytes.Buffer
+	buf.WriteString("\n")
+	if desc, ok := get(schema, "description").(string); ok {
+		writeDoc(&buf, "", desc)
+	}
+
+	typ := get(schema, "type")
+	switch {
+	case get(schema, "properties") != nil || len(asSlice(get(schema, "allOf"))) > 1:
+		fmt.Fprintf(&buf, "type %s struct {\n", name)
+		im.fields(&buf, name, schema)
+		buf.WriteString("}\n")
+	case (typ == "string" || typ == "integer") && len(asSlice(get(schema, "enum"))) > 0:
+		fmt.Fprintf(&buf, "type %s %s\n\nconst (\n", name, im.baseType(yaml.MapSlice{{Key: "type", Value: typ}, {Key: "format", Value: get(schema, "format")}}, name))
+		for _, v := range asSlice(get(schema, "enum")) {
+			value := fmt.Sprint(v)
+			if typ == "string" {
+				value = fmt.Sprintf("%q", v)
+			}
+			fmt.Fprintf(&buf, "\t%s%s %s = %s\n", name, goIdent(fmt.Sprint(v), true), name, value)
+		}
+		buf.WriteString(")\n")
+	default:
+		fmt.Fprintf(&buf, "type %s %s\n", name, im.baseType(schema, name+"Value"))
+	}
+	im.decls.Write(buf.Bytes())
+}
+
+// fields writes the fields of the struct name declared for schema to buf:
+// its properties and those of its allOf schemas, embedding the schemas
+// they refer to.
+func (im *specImporter) fields(buf *bytes.Buffer, name string, schema interface{}) {
+	for _, sub := range asSlice(get(schema, "allOf")) {
+		if ref, ok := get(sub, "$ref").(string); ok {
+			fmt.Fprintf(buf, "\t%s\n", im.refType(ref))
+		} else {
+			im.fields(buf, name, resolve(im.spec, sub))
+		}
+	}
+	required := map[string]bool{}
+	for _, r := range asSlice(get(schema, "required")) {
+		required[fmt.Sprint(r)] = true
+	}
+	for _, prop := range entries(get(schema, "properties")) {
+		json := fmt.Sprint(prop.Key)
+		field := goIdent(json, true)
+		if desc, ok := get(prop.Value, "description").(string); ok {
+			writeDoc(buf, "\t", desc)
+		}
+		tag := json
+		if !required[json] {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(buf, "\t%s %s `json:\"%s\"`\n", field, im.goType(prop.Value, name+field), tag)
+	}
+}
+
+// writeDoc writes text as a comment indented by indent, wrapped at about
+// 80 columns.
+func writeDoc(buf *bytes.Buffer, indent, text string) {
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(indent)*4+len(line)+len(word) > 76 {
+			fmt.Fprintf(buf, "%s// %s\n", indent, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		fmt.Fprintf(buf, "%s// %s\n", indent, line)
+	}
+}
+
+// initialisms are the words written in upper case in Go identifiers.
+var initialisms = map[string]bool{"API": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true, "SQL": true, "TTL": true, "URI": true, "URL": true, "UUID": true, "XML": true}
+
+// goIdent converts name, such as "user_id", "user-id", "userId" or "User
+// API", to a Go identifier: "UserID" or "userID" if not exported. Keywords
+// get a trailing underscore.
+func goIdent(name string, exported bool) string {
+	var words []string
+	word := []rune{}
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) || (unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1]))):
+			flush()
+		}
+		word = append(word, r)
+	}
+	flush()
+
+	var b strings.Builder
+	for i, w := range words {
+		switch upper := strings.ToUpper(w); {
+		case i == 0 && !exported:
+			b.WriteString(strings.ToLower(w))
+		case initialisms[upper]:
+			b.WriteString(upper)
+		default:
+			b.WriteString(strings.ToUpper(w[:1]) + strings.ToLower(w[1:]))
+		}
+	}
+	ident := b.String()
+	if ident != "" && unicode.IsDigit([]rune(ident)[0]) {
+		ident = "V" + ident
+	}
+	if token.Lookup(ident).IsKeyword() {
+		ident += "_"
+	}
+	return ident
+}
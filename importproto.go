@@ -0,0 +1,581 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"text/scanner"
+)
+
+// protoFile is the part of a parsed .proto file the import cares about.
+type protoFile struct {
+	Package  string
+	Services []*protoService
+	Messages map[string]*protoMessage // by full name within the package, e.g. "User.Address"
+	Enums    map[string]*protoEnum
+	Order    []string // full names of the messages and enums, in declaration order
+}
+
+type protoService struct {
+	Name string
+	Doc  []string
+	RPCs []protoRPC
+}
+
+type protoRPC struct {
+	Name                string
+	Doc                 []string
+	Request, Response   string
+	StreamIn, StreamOut bool
+	Pos                 scanner.Position
+}
+
+type protoMessage struct {
+	Name   string // full name
+	Doc    []string
+	Fields []protoField
+}
+
+type protoField struct {
+	Name     string
+	JSONName string // from the json_name option, if any
+	Type     string // as written, of the values of maps
+	Key      string // key type of maps
+	Repeated bool
+	Optional bool // explicit presence: the optional label or a member of a oneof
+	Doc      []string
+	Scope    string // full name of the message declaring the field, to resolve its type
+}
+
+type protoEnum struct {
+	Name   string
+	Doc    []string
+	Values []string
+}
+
+// protoParser is a recursive descent parser of the declarations of proto2
+// and proto3 files. Options other than json_name are skipped.
+type protoParser struct {
+	s    scanner.Scanner
+	tok  rune
+	text string
+	doc  []string // comments preceding the current token
+	file *protoFile
+	err  error
+}
+
+// parseProto parses the .proto file at path.
+func parseProto(path string) (*protoFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &protoParser{file: &protoFile{Messages: map[string]*protoMessage{}, Enums: map[string]*protoEnum{}}}
+	p.s.Init(bytes.NewReader(data))
+	p.s.Filename = path
+	p.s.Mode = scanner.ScanIdents | scanner.ScanInts | scanner.ScanFloats | scanner.ScanStrings | scanner.ScanComments
+	p.s.Error = func(s *scanner.Scanner, msg string) { p.fail("%s", msg) }
+	p.next()
+	for p.tok != scanner.EOF && p.err == nil {
+		p.topLevel()
+	}
+	return p.file, p.err
+}
+
+func (p *protoParser) fail(format string, args ...interface{}) {
+	if p.err == nil {
+		p.err = fmt.Errorf("%s: %s", p.s.Position, fmt.Sprintf(format, args...))
+	}
+}
+
+// next moves to the next token, collecting the comments before it.
+func (p *protoParser) next() {
+	p.doc = nil
+	for {
+		p.tok = p.s.Scan()
+		p.text = p.s.TokenText()
+		if p.tok != scanner.Comment {
+			return
+		}
+		text := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(p.text, "//"), "/*"), "*/"))
+		p.doc = append(p.doc, strings.Split(text, "\n")...)
+	}
+}
+
+func (p *protoParser) expect(text string) {
+	if p.text != text {
+		p.fail("expected %q, found %q", text, p.text)
+	}
+	p.next()
+}
+
+// ident returns the current (possibly qualified) identifier and moves past it.
+func (p *protoParser) ident() string {
+	if p.tok != scanner.Ident && p.text != "." {
+		p.fail("expected an identifier, found %q", p.text)
+		return ""
+	}
+	name := ""
+	for p.err == nil {
+		if p.text == "." {
+			name += "."
+			p.next()
+		}
+		if p.tok != scanner.Ident {
+			p.fail("expected an identifier, found %q", p.text)
+			return name
+		}
+		name += p.text
+		p.next()
+		if p.text != "." {
+			return name
+		}
+	}
+	return name
+}
+
+// skipStatement skips to the end of the current statement or block.
+func (p *protoParser) skipStatement() {
+	for depth := 0; p.tok != scanner.EOF && p.err == nil; p.next() {
+		switch p.text {
+		case "{", "[", "(":
+			depth++
+		case "}", "]", ")":
+			depth--
+			if depth == 0 && p.text == "}" {
+				p.next()
+				return
+			}
+		case ";":
+			if depth == 0 {
+				p.next()
+				return
+			}
+		}
+	}
+}
+
+func (p *protoParser) topLevel() {
+	switch p.text {
+	case "package":
+		p.next()
+		p.file.Package = p.ident()
+		p.expect(";")
+	case "message":
+		p.message("")
+	case "enum":
+		p.enum("")
+	case "service":
+		p.service()
+	case ";":
+		p.next()
+	default: // syntax, import, option, extend
+		p.skipStatement()
+	}
+}
+
+func (p *protoParser) message(scope string) {
+	doc := p.doc
+	p.next()
+	name := p.ident()
+	if scope != "" {
+		name = scope + "." + name
+	}
+	m := &protoMessage{Name: name, Doc: doc}
+	p.file.Messages[name] = m
+	p.file.Order = append(p.file.Order, name)
+	p.expect("{")
+	p.body(m, false)
+}
+
+// body parses the declarations of the message m up to its closing brace.
+// Fields of oneofs have explicit presence.
+func (p *protoParser) body(m *protoMessage, oneof bool) {
+	for p.text != "}" && p.tok != scanner.EOF && p.err == nil {
+		switch p.text {
+		case "message":
+			p.message(m.Name)
+		case "enum":
+			p.enum(m.Name)
+		case "oneof":
+			p.next()
+			p.ident()
+			p.expect("{")
+			p.body(m, true)
+		case "option", "reserved", "extensions", "extend":
+			p.skipStatement()
+		case ";":
+			p.next()
+		default:
+			m.Fields = append(m.Fields, p.field(m.Name, oneof))
+		}
+	}
+	p.expect("}")
+}
+
+func (p *protoParser) field(scope string, oneof bool) protoField {
+	f := protoField{Doc: p.doc, Scope: scope, Optional: oneof}
+	switch p.text {
+	case "repeated":
+		f.Repeated = true
+		p.next()
+	case "optional":
+		f.Optional = true
+		p.next()
+	case "required":
+		p.next()
+	}
+	if p.text == "map" {
+		p.next()
+		p.expect("<")
+		f.Key = p.ident()
+		p.expect(",")
+		f.Type = p.ident()
+		p.expect(">")
+	} else {
+		f.Type = p.ident()
+	}
+	f.Name = p.ident()
+	p.expect("=")
+	p.next() // number
+	if p.text == "[" {
+		p.next()
+		for p.text != "]" && p.tok != scanner.EOF && p.err == nil {
+			opt := p.ident()
+			p.expect("=")
+			if opt == "json_name" && p.tok == scanner.String {
+				f.JSONName, _ = strconv.Unquote(p.text)
+			}
+			p.next()
+			if p.text == "," {
+				p.next()
+			}
+		}
+		p.expect("]")
+	}
+	p.expect(";")
+	return f
+}
+
+func (p *protoParser) enum(scope string) {
+	doc := p.doc
+	p.next()
+	name := p.ident()
+	if scope != "" {
+		name = scope + "." + name
+	}
+	e := &protoEnum{Name: name, Doc: doc}
+	p.file.Enums[name] = e
+	p.file.Order = append(p.file.Order, name)
+	p.expect("{")
+	for p.text != "}" && p.tok != scanner.EOF && p.err == nil {
+		if p.text == "option" || p.text == "reserved" {
+			p.skipStatement()
+			continue
+		}
+		e.Values = append(e.Values, p.ident())
+		p.skipStatement()
+	}
+	p.expect("}")
+}
+
+func (p *protoParser) service() {
+	svc := &protoService{Doc: p.doc}
+	p.next()
+	svc.Name = p.ident()
+	p.expect("{")
+	for p.text != "}" && p.tok != scanner.EOF && p.err == nil {
+		if p.text != "rpc" {
+			p.skipStatement()
+			continue
+		}
+		rpc := protoRPC{Doc: p.doc, Pos: p.s.Position}
+		p.next()
+		rpc.Name = p.ident()
+		p.expect("(")
+		if p.text == "stream" {
+			rpc.StreamIn = true
+			p.next()
+		}
+		rpc.Request = p.ident()
+		p.expect(")")
+		p.expect("returns")
+		p.expect("(")
+		if p.text == "stream" {
+			rpc.StreamOut = true
+			p.next()
+		}
+		rpc.Response = p.ident()
+		p.expect(")")
+		if p.text == "{" {
+			p.next()
+			for p.text != "}" && p.tok != scanner.EOF && p.err == nil {
+				p.skipStatement()
+			}
+			p.expect("}")
+		} else {
+			p.expect(";")
+		}
+		svc.RPCs = append(svc.RPCs, rpc)
+	}
+	p.expect("}")
+	p.file.Services = append(p.file.Services, svc)
+}
+
+// protoScalars are the Go types of the scalar proto types.
+var protoScalars = map[string]string{
+	"double": "float64", "float": "float32",
+	"int32": "int32", "sint32": "int32", "sfixed32": "int32",
+	"int64": "int64", "sint64": "int64", "sfixed64": "int64",
+	"uint32": "uint32", "fixed32": "uint32",
+	"uint64": "uint64", "fixed64": "uint64",
+	"bool": "bool", "string": "string", "bytes": "[]byte",
+}
+
+// protoWellKnown are the Go types of the well-known types. Wrappers are
+// pointers, as they are nullable.
+var protoWellKnown = map[string]string{
+	"google.protobuf.Timestamp":   "time.Time",
+	"google.protobuf.Duration":    "time.Duration",
+	"google.protobuf.Value":       "interface{}",
+	"google.protobuf.Struct":      "map[string]interface{}",
+	"google.protobuf.ListValue":   "[]interface{}",
+	"google.protobuf.Any":         "json.RawMessage",
+	"google.protobuf.StringValue": "*string",
+	"google.protobuf.BytesValue":  "*[]byte",
+	"google.protobuf.BoolValue":   "*bool",
+	"google.protobuf.DoubleValue": "*float64",
+	"google.protobuf.FloatValue":  "*float32",
+	"google.protobuf.Int32Value":  "*int32",
+	"google.protobuf.Int64Value":  "*int64",
+	"google.protobuf.UInt32Value": "*uint32",
+	"google.protobuf.UInt64Value": "*uint64",
+}
+
+// protoImporter derives Go declarations from the messages and enums of a
+// proto file.
+type protoImporter struct {
+	file     *protoFile
+	decls    bytes.Buffer
+	declared map[string]bool // full proto names
+}
+
+// importProto returns the Go source of package pkg declaring the interface
+// of the service of the .proto file at path, with a method per RPC, and the
+// types of its messages and enums. The parameters of a method are the
+// fields of its request message and its results those of its response
+// message if they are named after the RPC, such as GetUserRequest, or else
+// the messages as a whole; google.protobuf.Empty stands for none. Optional
+// request fields are marked with kit:optional, and a last request field of
+// a message type named <Name>Options becomes a variadic <Name>OptionsSetter.
+// Enums are string types with a constant per value, as they are encoded
+// in JSON.
+func importProto(path, pkg string) ([]byte, string, error) {
+	file, err := parseProto(path)
+	if err != nil {
+		return nil, "", err
+	}
+	switch len(file.Services) {
+	case 0:
+		return nil, "", fmt.Errorf("%s: no service", path)
+	case 1:
+	default:
+		return nil, "", fmt.Errorf("%s: more than one service, import a file declaring only one", path)
+	}
+	svc := file.Services[0]
+	im := &protoImporter{file: file, declared: map[string]bool{}}
+	flattened := map[string]bool{}
+
+	var methods []importedMethod
+	for _, rpc := range svc.RPCs {
+		if rpc.StreamIn || rpc.StreamOut {
+			return nil, "", fmt.Errorf("%s: %s: streaming RPCs aren't supported", rpc.Pos, rpc.Name)
+		}
+		m := importedMethod{Name: goIdent(rpc.Name, true), Doc: rpc.Doc, Params: []string{"ctx context.Context"}}
+		taken := map[string]bool{"ctx": true, "err": true}
+		unique := func(name string) string {
+			ident := goIdent(name, false)
+			for i := 2; taken[ident]; i++ {
+				ident = goIdent(name, false) + strconv.Itoa(i)
+			}
+			taken[ident] = true
+			return ident
+		}
+
+		req := im.resolve(rpc.Request, "")
+		switch msg := file.Messages[req]; {
+		case req == "google.protobuf.Empty":
+		case msg != nil && strings.EqualFold(msg.Name, rpc.Name+"Request"):
+			flattened[req] = true
+			for i, f := range msg.Fields {
+				typ := im.goType(f, false)
+				if setter := optionSetter(typ); i == len(msg.Fields)-1 && setter != "" {
+					m.Params = append(m.Params, unique(f.Name)+" ..."+setter)
+					continue
+				}
+				name := unique(f.Name)
+				m.Params = append(m.Params, name+" "+typ)
+				if f.Optional {
+					m.Optional = append(m.Optional, name)
+				}
+			}
+		default:
+			m.Params = append(m.Params, unique(req[strings.LastIndex(req, ".")+1:])+" "+im.goType(protoField{Type: rpc.Request}, false))
+		}
+
+		res := im.resolve(rpc.Response, "")
+		switch msg := file.Messages[res]; {
+		case res == "google.protobuf.Empty":
+		case msg != nil && strings.EqualFold(msg.Name, rpc.Name+"Response"):
+			flattened[res] = true
+			for _, f := range msg.Fields {
+				m.Results = append(m.Results, unique(f.Name)+" "+im.goType(f, true))
+			}
+		default:
+			m.Results = append(m.Results, unique(res[strings.LastIndex(res, ".")+1:])+" "+im.goType(protoField{Type: rpc.Response}, true))
+		}
+		m.Results = append(m.Results, "err error")
+		methods = append(methods, m)
+	}
+	for _, name := range file.Order {
+		if !flattened[name] {
+			im.declare(name)
+		}
+	}
+
+	iface := goIdent(svc.Name, true)
+	src, err := renderImport(path, pkg, iface, svc.Doc, methods, im.decls.Bytes())
+	return src, iface, err
+}
+
+// resolve returns the full name of the message or enum typ as referred to
+// from the message scope, following the scoping rules of protobuf, or typ
+// if it isn't declared in the file.
+func (im *protoImporter) resolve(typ, scope string) string {
+	if strings.HasPrefix(typ, ".") {
+		return strings.TrimPrefix(typ[1:], im.file.Package+".")
+	}
+	typ = strings.TrimPrefix(typ, im.file.Package+".")
+	for s := scope; s != ""; {
+		if name := s + "." + typ; im.file.Messages[name] != nil || im.file.Enums[name] != nil {
+			return name
+		}
+		if i := strings.LastIndex(s, "."); i >= 0 {
+			s = s[:i]
+		} else {
+			s = ""
+		}
+	}
+	return typ
+}
+
+// goType returns the Go type of the field f, declaring the messages and
+// enums it refers to. Optional fields of a message type are pointers, and
+// so are other optional fields and all those of a message type if pointers
+// is set, for fields of results and messages, which always have presence.
+func (im *protoImporter) goType(f protoField, pointers bool) string {
+	typ := im.elemType(f.Type, f.Scope)
+	switch {
+	case f.Key != "":
+		return "map[" + im.elemType(f.Key, f.Scope) + "]" + typ
+	case f.Repeated:
+		return "[]" + typ
+	case strings.HasPrefix(typ, "*") || strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[") || typ == "interface{}":
+		return typ
+	}
+	message := im.file.Messages[im.resolve(f.Type, f.Scope)] != nil
+	if pointers && (message || f.Optional) || message && f.Optional {
+		return "*" + typ
+	}
+	return typ
+}
+
+func (im *protoImporter) elemType(typ, scope string) string {
+	if t, ok := protoScalars[typ]; ok {
+		return t
+	}
+	if t, ok := protoWellKnown[strings.TrimPrefix(typ, ".")]; ok {
+		return t
+	}
+	name := im.resolve(typ, scope)
+	if im.file.Messages[name] == nil && im.file.Enums[name] == nil {
+		return "interface{}" // declared in an imported file
+	}
+	im.declare(name)
+	return goIdent(strings.Replace(name, ".", " ", -1), true)
+}
+
+// declare declares the Go type of the message or enum name, unless it is
+// declared already.
+func (im *protoImporter) declare(name string) {
+	if im.declared[name] {
+		return
+	}
+	im.declared[name] = true
+	goName := goIdent(strings.Replace(name, ".", " ", -1), true)
+	var buf bytes.Buffer
+	buf.WriteString("\n")
+	if e := im.file.Enums[name]; e != nil {
+		writeDoc(&buf, "", strings.Join(e.Doc, " "))
+		fmt.Fprintf(&buf, "type %s string\n\nconst (\n", goName)
+		// Values are conventionally prefixed by the name of their enum, such
+		// as STATE_OPEN of State, which the name of the Go type repeats.
+		prefix := snakeCase(name[strings.LastIndex(name, ".")+1:]) + "_"
+		for _, v := range e.Values {
+			fmt.Fprintf(&buf, "\t%s%s %s = %q\n", goName, goIdent(strings.TrimPrefix(strings.ToLower(v), prefix), true), goName, v)
+		}
+		buf.WriteString(")\n")
+		im.decls.Write(buf.Bytes())
+		return
+	}
+	m := im.file.Messages[name]
+	writeDoc(&buf, "", strings.Join(m.Doc, " "))
+	fmt.Fprintf(&buf, "type %s struct {\n", goName)
+	for _, f := range m.Fields {
+		writeDoc(&buf, "\t", strings.Join(f.Doc, " "))
+		tag := f.JSONName
+		if tag == "" {
+			tag = protoJSONName(f.Name)
+		}
+		if f.Optional || f.Repeated || f.Key != "" {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&buf, "\t%s %s `json:\"%s\"`\n", goIdent(f.Name, true), im.goType(f, true), tag)
+	}
+	buf.WriteString("}\n")
+	if setter := optionSetter(goName); setter != "" {
+		fmt.Fprintf(&buf, "\n// %s sets the options of a call.\ntype %s func(*%s)\n", setter, setter, goName)
+	}
+	im.decls.Write(buf.Bytes())
+}
+
+// protoJSONName returns the JSON name of the proto field name, in lower
+// camel case as protoc derives it: "user_id" becomes "userId".
+func protoJSONName(name string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range name {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = []rune(strings.ToUpper(string(r)))[0]
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// optionSetter returns the name of the option setter of the options struct
+// typ, such as ListOptionsSetter for ListOptions, or "" if typ isn't named
+// like one.
+func optionSetter(typ string) string {
+	if !strings.HasSuffix(typ, "Options") || strings.ContainsAny(typ, "*[]. ") {
+		return ""
+	}
+	return typ + "Setter"
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+)
+
+// Model is the analysis of an interface written by kitboiler inspect, for
+// external generators and linters to build on: the Service the templates
+// are executed with, with the positions of the methods and of their
+// annotations as file:line:column. Its fields follow those of Service and
+// Func, and are only as stable as them.
+type Model struct {
+	Kitboiler string // version of kitboiler, see kitboiler version
+	Service
+	Funcs    []ModelFunc // methods to generate code for
+	AllFuncs []ModelFunc // all methods of the interface, including skipped ones
+}
+
+// ModelFunc is a method of the interface in the Model.
+type ModelFunc struct {
+	Func
+	Pos         string // of the method name
+	Annotations []ModelAnnotation
+}
+
+// ModelAnnotation is an annotation of a method in the Model.
+type ModelAnnotation struct {
+	Annotation
+	Pos string // of the annotation name, including its prefix
+}
+
+// newModel returns the model of svc.
+func newModel(svc Service) Model {
+	m := Model{Kitboiler: kitboilerVersion(), Service: svc}
+	m.Funcs, m.AllFuncs = modelFuncs(svc.Funcs), modelFuncs(svc.AllFuncs)
+	return m
+}
+
+func modelFuncs(fns []Func) []ModelFunc {
+	var mfs []ModelFunc
+	for _, f := range fns {
+		mf := ModelFunc{Func: f}
+		if fset := f.src.FileSet; fset != nil {
+			mf.Pos = fset.Position(f.Pos).String()
+		}
+		for _, a := range f.Annotations {
+			ma := ModelAnnotation{Annotation: a}
+			if fset := f.src.FileSet; fset != nil {
+				ma.Pos = fset.Position(a.Pos).String()
+			}
+			mf.Annotations = append(mf.Annotations, ma)
+		}
+		mfs = append(mfs, mf)
+	}
+	return mfs
+}
+
+// runInspect writes the Model of the interface as JSON to the -o file, or
+// to stdout. The -o flag names a file rather than the output directory of
+// gen, so the model doesn't depend on it.
+func runInspect(args []string) error {
+	out := *flagOutDir
+	*flagOutDir = ""
+	svc, err := loadService(args)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(newModel(svc), "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if out == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(out, data, 0644)
+}
@@ -0,0 +1,67 @@
+package main
+
+// instrumentingImports are the imports required by the instrumenting
+// middleware.
+var instrumentingImports = map[string]string{
+	"strconv":                       "",
+	"time":                          "",
+	"github.com/go-kit/kit/metrics": "",
+	"github.com/go-kit/kit/metrics/prometheus":       "",
+	"github.com/prometheus/client_golang/prometheus": "stdprometheus",
+}
+
+const instrumentingTemplate = `
+{{ define "instrumenting" }}
+// InstrumentingMiddleware returns a service middleware counting the calls
+// of every method of {{ .IFace }} with requestCount and observing their
+// seconds with requestLatency, both labeled by "method" and "error", true if
+// the call returned an error.
+func InstrumentingMiddleware(requestCount metrics.Counter, requestLatency metrics.Histogram) func({{ .IFace }}) {{ .IFace }} {
+	return func(next {{ .IFace }}) {{ .IFace }} {
+		return instrumentingMiddleware{next, requestCount, requestLatency}
+	}
+}
+
+// NewPrometheusInstrumentingMiddleware registers the Prometheus collectors
+// of the calls of the methods of {{ .IFace }}, {{ .MetricsNamespace }}_service_requests_total
+// and {{ .MetricsNamespace }}_service_request_duration_seconds, with registerer, e.g.
+// stdprometheus.DefaultRegisterer, and returns the InstrumentingMiddleware
+// recording to them.
+func NewPrometheusInstrumentingMiddleware(registerer stdprometheus.Registerer) (func({{ .IFace }}) {{ .IFace }}, error) {
+	requestCount := stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+		Namespace: "{{ .MetricsNamespace }}",
+		Subsystem: "service",
+		Name:      "requests_total",
+		Help:      "Number of calls of the methods of the service.",
+	}, []string{"method", "error"})
+	requestLatency := stdprometheus.NewHistogramVec(stdprometheus.HistogramOpts{
+		Namespace: "{{ .MetricsNamespace }}",
+		Subsystem: "service",
+		Name:      "request_duration_seconds",
+		Help:      "Time taken by a call of a method of the service.",
+		Buckets:   []float64{ {{ .LatencyBuckets }} },
+	}, []string{"method", "error"})
+	for _, c := range []stdprometheus.Collector{requestCount, requestLatency} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return InstrumentingMiddleware(prometheus.NewCounter(requestCount), prometheus.NewHistogram(requestLatency)), nil
+}
+
+type instrumentingMiddleware struct {
+	{{ .IFace }}
+	requestCount   metrics.Counter
+	requestLatency metrics.Histogram
+}
+{{ range $fun := .Funcs }}
+func (mw instrumentingMiddleware) {{ .Name }}{{ Signature . }} {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "{{ .Name }}", "error", {{ with ErrorName . }}strconv.FormatBool({{ . }} != nil){{ else }}"false"{{ end }}}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	{{ if .Res }}return {{ end }}mw.{{ $.IFaceName }}.{{ .Name }}({{ CallArgs . }})
+}
+{{ end }}{{ end }}
+`
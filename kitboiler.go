@@ -10,17 +10,18 @@ import (
 	"go/parser"
 	"go/printer"
 	"go/token"
-	"os"
+	"go/types"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"text/template"
+	"time"
+	"unicode"
 
 	"golang.org/x/tools/imports"
-	"go/format"
 )
 
-const usage = `kitboiler <iface>
+const usage = `usage: kitboiler [<command>] [flags] [<iface>]
 
 kitboiler generates Go kit (https://gokit.io) endpoints, request/response types, request decoders and http handlers 
 based on an interface that defines a service.
@@ -37,8 +38,13 @@ You should call kitboiler like this:
 
 kitboiler github.com/me/mypkg/api.MyService 
 
-NOTE: you HAVE to provide names for both the parameters and the return vars in your interface definition as
-those are used by kitboiler. Choose the names wisely as they will become part of your public interface.
+or run kitboiler init to answer a few questions and generate kitboiler.yaml, which is used when no
+interface is given.
+
+NOTE: the names of the parameters and the return vars in your interface definition name the fields of the
+requests and responses, so choose them wisely as they will become part of your public interface. Unnamed
+return vars are named after their types, with a warning; unnamed parameters other than a context.Context
+are errors.
 
 Implementation is based on the impl package: https://github.com/josharian/impl and inspiration was generously provided 
 by SQLBoiler (https://github.com/volatiletech/sqlboiler)
@@ -46,7 +52,75 @@ by SQLBoiler (https://github.com/volatiletech/sqlboiler)
 
 var (
 	flagSrcDir = flag.String("dir", "", "package source directory, useful for vendored code")
+	flagGOOS = flag.String("goos", "", "load the packages for this `os` instead of the host's, to find the declarations in files constrained to it, e.g. _linux.go files")
+	flagGOARCH = flag.String("goarch", "", "load the packages for this `arch` instead of the host's, to find the declarations in files constrained to it")
 	flagPkgName = flag.String("pkg", "endpoints", "name of resulting package")
+	flagOutDir = flag.String("o", "", "write the generated files to `dir` instead of printing the package to stdout")
+	flagMock = flag.Bool("mock", false, "generate a mock implementation of the interface")
+	flagHarness = flag.Bool("harness", false, "generate a golden file test harness for the HTTP handlers (implies -mock)")
+	flagLoadTest = flag.Bool("loadtest", false, "generate a load test package for the HTTP routes")
+	flagStubServer = flag.Bool("stub-server", false, "generate a stub server serving canned responses from YAML fixtures (implies -mock)")
+	flagOptionsHead = flag.Bool("options-head", false, "answer OPTIONS requests on every route and serve HEAD on GET routes")
+	flagRateLimit = flag.String("ratelimit", "", "generate per-client rate limiting keyed by `ip`, apikey or jwt")
+	flagHedge = flag.Bool("hedge", false, "generate hedged request endpoint middleware")
+	flagClientCache = flag.Bool("client-cache", false, "generate a caching client transport for read requests")
+	flagSkipEmbedded = flag.String("skip-embedded", "io.Closer,fmt.Stringer", "comma separated `list` of embedded interfaces whose methods are skipped")
+	flagNilResult = flag.String("nil-result", "null", "respond to nil pointer results without an error with `policy`: null, not-found or no-content")
+	flagBudget = flag.Duration("budget", 0, "default latency budget of methods without a kit:budget annotation")
+	flagConstraints = flag.String("openapi-constraints", "", "validate requests against the constraints of the OpenAPI `spec`")
+	flagScalars = flag.String("scalars", "", "YAML `file` registering additional scalar types")
+	flagEnvelope = flag.Bool("response-envelope", false, "wrap the JSON responses in an envelope, {\"data\": ...} on success and {\"error\": \"...\"} on failure, decoded by the client")
+	flagExamples = flag.String("examples", "", "`dir`ectory of <Method>.request.json and <Method>.response.json examples, validated and embedded in the OpenAPI spec and the stub server")
+	flagMetrics = flag.Bool("metrics", false, "generate request count, latency and size metrics for the HTTP handlers")
+	flagMetricsExporter = flag.String("metrics-exporter", "", "set the HTTP metrics to the instruments of `exporter`: prometheus or otel (implies -metrics)")
+	flagSLO = flag.Float64("slo", 0, "default service level `objective` in percent of methods without a kit:slo annotation (implies -metrics)")
+	flagDashboard = flag.Bool("dashboard", false, "generate a Grafana dashboard of the HTTP metrics (implies -metrics)")
+	flagDTO = flag.Bool("dto", false, "generate the request and response types into a separate dto package")
+	flagConfig = flag.String("config", "kitboiler.yaml", "config `file` naming the interface and options, used when no interface is given")
+	flagProfile = flag.String("profile", "", "`profile` of the config file whose options are applied on top of its other options, e.g. dev or prod")
+	flagPreset = flag.String("preset", "", "turn on the features of a `preset`: production, or grpc-internal for a gRPC-only internal service")
+	flagRecover = flag.Bool("recover", false, "recover panics in the HTTP handlers, responding with 500 Internal Server Error")
+	flagAccessLog = flag.Bool("access-log", false, "log every request handled by the HTTP handlers")
+	flagHealth = flag.Bool("health", false, "serve /healthz and /readyz health endpoints")
+	flagAdmin = flag.Bool("admin", false, "generate a registry of switches flipping the log level, rate limits and circuit breakers at runtime, and its admin API, served on a listener of its own by the scaffolded command")
+	flagHotReload = flag.Bool("hot-reload", false, "reload the handler timeout, log level, rate limits and latency budgets of the scaffolded command from its -config-file without a restart (implies -scaffold)")
+	flagScaffold = flag.Bool("scaffold", false, "generate a command serving the service, with configuration and graceful shutdown")
+	flagRepository = flag.String("repository", "", "generate a repository of the entities of the service and an adapter skeleton of this `flavor`, sqlc or ent, behind the scaffolded command (implies -scaffold)")
+	flagMinimal = flag.Bool("minimal", false, "generate only the endpoints and bare HTTP handlers, without any middleware")
+	flagConvert = flag.Bool("convert", false, "generate DTOs mirroring the domain structs used by requests and responses, and conversions between the two")
+	flagHooks = flag.Bool("hooks", false, "generate per-method hook variables called by the request decoders and response encoders, per-method endpoint middlewares, and server options applied to every handler")
+	flagOpenAPI = flag.Bool("openapi", false, "generate an OpenAPI spec of the HTTP routes")
+	flagProto = flag.Bool("proto", false, "generate a proto file describing the service")
+	flagManifest = flag.Bool("manifest", true, "write a manifest of the generated files to the -o directory")
+	flagModule = flag.String("module", "", "generate the package as a standalone module with this `path`, scaffolding its go.mod in the -o directory")
+	flagChangelog = flag.Bool("changelog", false, "version the generated API, adding an entry to CHANGELOG.md in the -o directory when its methods change")
+	flagGoGenerate = flag.Bool("go-generate", false, "write generate.go to the package of the interface, with the go:generate directive regenerating the package with the flags used")
+	flagRoutePrefix = flag.String("route-prefix", "", "mount the routes of the methods under this `path`, e.g. /internal/billing")
+	flagTenant = flag.String("tenant", "", "extract the tenant of every request into its context from a leading /t/{tenant} `segment` of its path (path) or from its host (host)")
+	flagNamespace = flag.String("namespace", "", "`prefix` of the names of the Prometheus metrics, the service name in snake case by default")
+	flagPlan = flag.String("plan", "kitboiler.plan.json", "plan `file` written by kitboiler plan and executed by kitboiler apply")
+	flagRequestName = flag.String("request-name", "{Method}Request", "`pattern` of the names of the request types, in which {Method} is the method name and {method} the same with a lower case first letter")
+	flagResponseName = flag.String("response-name", "{Method}Response", "`pattern` of the names of the response types, see -request-name")
+	flagJSONCase = flag.String("json-case", "", "`case` of the JSON fields of the requests and responses: camel, snake, kebab or lower; the Go field names if empty")
+	flagSplit = flag.Bool("split", false, "write the endpoint and HTTP transport of every method to files of their own, <method>_endpoint.go and <method>_transport.go, and the code they share to common.go")
+	flagTokenURL = flag.String("token-url", "/oauth/token", "`URL` of the token endpoint granting the scopes of kit:scope, in the OpenAPI spec")
+	flagAuthScheme = flag.String("auth-scheme", "oauth2", "security `scheme` required by the OpenAPI operations of the methods annotated with kit:scope, the only ones the generated code checks the token of: oauth2, jwt, apikey or basic")
+	flagAssertions = flag.Bool("assertions", false, "write assertions.go, asserting that the generated implementations of the interface, such as the mock and the middlewares, implement it")
+	flagKeepRemoved = flag.Int("keep-removed", 0, "keep the routes of the methods removed from the interface responding with 410 Gone for `n` generations, read from the manifest")
+	flagEndpointSet = flag.Bool("endpoint-set", false, "generate an Endpoints struct holding the endpoints of all methods, which implements the interface by calling them")
+	flagTransports = flag.String("transports", "http", "comma-separated `list` of the transports to generate: http, grpc for a go-kit gRPC server and the proto file of its messages, nats for go-kit NATS subscribers and amqp for go-kit AMQP subscribers")
+	flagCompression = flag.String("compression", "", "compress the requests of the grpc, nats and amqp clients with `codec` gzip, snappy or zstd, the servers answering with the codec of the request")
+	flagGRPCWeb = flag.Bool("grpc-web", false, "generate MakeGRPCWebHandler, serving the grpc server to browsers over gRPC-Web on the listener of the HTTP handlers, and mount it in the scaffolded command")
+	flagDiscovery = flag.String("discovery", "", "register the scaffolded command in the service `registry` consul while it serves, with a health check")
+	flagDeadLetter = flag.Bool("dead-letter", false, "retry the requests the nats and amqp subscribers fail to serve with exponential backoff, then publish them to the dead-letter subject or queue of their subject or queue, <name>.dlq")
+	flagRouter = flag.String("router", "", "`router` MakeHTTPHandler registers the routes on: chi (github.com/go-chi/chi/v5) or mux (github.com/gorilla/mux), with a Register<Method>Route function per method; http.ServeMux if empty")
+	flagMiddleware = flag.String("middleware", "", "comma separated `list` of the service middlewares to generate: logging, logging every call with its parameters, error and duration, instrumentation, recording the count and latency of the calls in Prometheus metrics, shadow, mirroring a percentage of the calls to a second implementation and reporting the mismatches, and routing, dispatching the calls to one of two implementations")
+	flagClient = flag.Bool("client", false, "write client.go, with an HTTP client of every method made with httptransport.NewClient, and NewHTTPClient returning a client implementing the interface (implies -endpoint-set)")
+	flagTracing = flag.Bool("tracing", false, "record every call of an endpoint in an OpenTelemetry span named after its method, continuing the trace propagated by the headers of the HTTP request or the metadata of the gRPC request")
+	flagReplay = flag.Bool("replay", false, "write replay.go, with a development-only handler replaying recorded requests on the service with verbose tracing, served by the scaffolded command with -replay")
+	flagGetMethods = flag.String("get-methods", "", "comma separated `list` of the methods, or patterns such as Get*, served on GET with their requests decoded from the query string")
+	flagAllowInvalid = flag.Bool("allow-invalid", false, "write generated Go code that doesn't parse, unformatted, instead of failing with its syntax error")
+	flagFollowRenames = flag.Bool("follow-renames", false, "move the files generated for a method renamed since the manifest was written to its new name")
 )
 
 // findInterface returns the import path and identifier of an interface.
@@ -114,21 +188,25 @@ type Pkg struct {
 	*build.Package
 	*token.FileSet
 	srcDir string
+	typed  *typedFiles // files parsed by typeSpec, type checked on demand
 }
 
 // typeSpec locates the *ast.TypeSpec for type id in the import path.
 func typeSpec(path string, id string, srcDir string) (Pkg, *ast.TypeSpec, error) {
-	pkg, err := build.Import(path, srcDir, 0)
+	pkg, err := importPackage(path, srcDir)
 	if err != nil {
 		return Pkg{}, nil, fmt.Errorf("couldn't find package %s: %v", path, err)
 	}
 
 	fset := token.NewFileSet() // share one fset across the whole package
+	typed := &typedFiles{}
+	var found *ast.TypeSpec
 	for _, file := range pkg.GoFiles {
-		f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, file), nil, 0)
+		f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, file), nil, parser.ParseComments)
 		if err != nil {
 			continue
 		}
+		typed.files = append(typed.files, f)
 
 		for _, decl := range f.Decls {
 			decl, ok := decl.(*ast.GenDecl)
@@ -137,13 +215,22 @@ func typeSpec(path string, id string, srcDir string) (Pkg, *ast.TypeSpec, error)
 			}
 			for _, spec := range decl.Specs {
 				spec := spec.(*ast.TypeSpec)
-				if spec.Name.Name != id {
+				if found != nil || spec.Name.Name != id {
 					continue
 				}
-				return Pkg{Package: pkg, FileSet: fset, srcDir: srcDir}, spec, nil
+				if spec.Doc == nil && len(decl.Specs) == 1 {
+					spec.Doc = decl.Doc // the doc comment of "type T ..."
+				}
+				found = spec
 			}
 		}
 	}
+	if found != nil {
+		return Pkg{Package: pkg, FileSet: fset, srcDir: srcDir, typed: typed}, found, nil
+	}
+	if len(pkg.IgnoredGoFiles) > 0 {
+		return Pkg{}, nil, fmt.Errorf("type %s not found in %s, whose files constrained to other platforms than %s/%s are skipped, see -goos and -goarch", id, path, buildContext().GOOS, buildContext().GOARCH)
+	}
 	return Pkg{}, nil, fmt.Errorf("type %s not found in %s", id, path)
 }
 
@@ -172,6 +259,13 @@ func (p Pkg) fullType(e ast.Expr) string {
 				n.Name = p.Package.Name + "." + n.Name
 			}
 		case *ast.SelectorExpr:
+			// refer to the package by its name rather than by the
+			// alias of the file, as the generated files import it
+			if x, ok := n.X.(*ast.Ident); ok {
+				if path := p.importPathOf(x.Name); path != "" && x.Name != guessPackageName(path) {
+					x.Name = packageName(path, p.srcDir)
+				}
+			}
 			return false
 		}
 		return true
@@ -179,39 +273,176 @@ func (p Pkg) fullType(e ast.Expr) string {
 	return p.gofmt(e)
 }
 
-func (p Pkg) generateOptionSetters(name, typ string) []string {
-	var optionSetters []string
-	if strings.HasPrefix(typ, "...") && strings.HasSuffix(typ,"Setter") {
-		typ = typ[3:len(typ)-6]
-		srcPkg := p.Name
-		importPath := p.ImportPath
-		bareType := typ
-		if strings.Contains(typ, ".") {
-			bareType = typ[strings.Index(typ, ".")+1:]
-			srcPkg = typ[:strings.Index(typ, ".")]
-			if !strings.HasSuffix(importPath, srcPkg) {
-				for _, ip := range p.Imports {
-					if strings.HasSuffix(ip, srcPkg) {
-						importPath = ip
-						break
-					}
+// importNames maps the names packages are referred to by in the files of a
+// package to their import paths, by directory of the package.
+var importNames = map[string]map[string]string{}
+
+// packageNames caches the names declared by packages, by import path.
+var packageNames = map[string]string{}
+
+// importPathOf returns the import path of the package referred to as qual
+// in p, or "" if it is unknown.
+func (p Pkg) importPathOf(qual string) string {
+	if qual == p.Name {
+		return p.ImportPath
+	}
+	names, ok := importNames[p.Dir]
+	if !ok {
+		names = p.parseImportNames()
+		importNames[p.Dir] = names
+	}
+	if path, ok := names[qual]; ok {
+		return path
+	}
+	// the name a package declares needn't match its path, e.g. package geo
+	// in github.com/me/go-geo-lib
+	for _, ip := range p.Imports {
+		if packageName(ip, p.srcDir) == qual {
+			names[qual] = ip
+			return ip
+		}
+	}
+	return ""
+}
+
+// parseImportNames returns the import paths of the packages imported by the
+// files of p, by their aliases or, if they have none, the names guessed from
+// their paths.
+func (p Pkg) parseImportNames() map[string]string {
+	names := map[string]string{}
+	if p.Package == nil {
+		return names
+	}
+	fset := token.NewFileSet()
+	for _, file := range p.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(p.Dir, file), nil, parser.ImportsOnly)
+		if err != nil {
+			continue
+		}
+		for _, spec := range f.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			name := guessPackageName(path)
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			if name != "_" && name != "." {
+				names[name] = path
+			}
+		}
+	}
+	return names
+}
+
+// packageName returns the name declared by the package path, or the name
+// guessed from its path if it can't be imported.
+func packageName(path, srcDir string) string {
+	if name, ok := packageNames[path]; ok {
+		return name
+	}
+	name := guessPackageName(path)
+	if pkg, err := importPackage(path, srcDir); err == nil && pkg.Name != "" {
+		name = pkg.Name
+	}
+	packageNames[path] = name
+	return name
+}
+
+// guessPackageName returns the name a package is most likely to declare
+// given its import path: the last element of the path without a major
+// version, e.g. yaml for gopkg.in/yaml.v2 and geo for github.com/me/geo/v2.
+func guessPackageName(path string) string {
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = elems[len(elems)-2]
+	}
+	if i := strings.Index(name, ".v"); i > 0 {
+		name = name[:i]
+	}
+	return strings.TrimPrefix(name, "go-")
+}
+
+// typeImports returns the import paths of the packages referred to by the
+// type typ, as used in p.
+func (p Pkg) typeImports(typ string) []string {
+	x, err := parser.ParseExpr(strings.TrimPrefix(typ, "..."))
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	ast.Inspect(x, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				if path := p.importPathOf(id.Name); path != "" {
+					paths = append(paths, path)
 				}
 			}
+			return false
 		}
+		return true
+	})
+	return paths
+}
 
-		_, spec, err := typeSpec(importPath, bareType, p.srcDir)
-		if err != nil { panic(err) }
-		if idecl, ok := spec.Type.(*ast.StructType); ok {
-			for _, field := range idecl.Fields.List {
-				optionSetters = append(optionSetters, fmt.Sprintf("\nfunc(v %v) func(*%s) { return func(opts *%s) { opts.%s = v } }(req.%s.%s)",
-					field.Type, typ, typ, field.Names[0], name, field.Names[0]))
+// optionsStruct returns the options struct set by typ setters, or nil if typ
+// isn't an option setter.
+func (p Pkg) optionsStruct(typ string) *ast.StructType {
+	if !IsOptionSetter(typ) {
+		return nil
+	}
+	typ = typ[3:len(typ)-6]
+	srcPkg := p.Name
+	importPath := p.ImportPath
+	bareType := typ
+	if strings.Contains(typ, ".") {
+		bareType = typ[strings.Index(typ, ".")+1:]
+		srcPkg = typ[:strings.Index(typ, ".")]
+		if !strings.HasSuffix(importPath, srcPkg) {
+			for _, ip := range p.Imports {
+				if strings.HasSuffix(ip, srcPkg) {
+					importPath = ip
+					break
+				}
 			}
 		}
+	}
 
+	_, spec, err := typeSpec(importPath, bareType, p.srcDir)
+	if err != nil { panic(err) }
+	st, _ := spec.Type.(*ast.StructType)
+	return st
+}
+
+// generateOptionSetters returns the option setters passing the fields of the
+// options struct in the request field name to a method taking typ setters.
+func (p Pkg) generateOptionSetters(name, typ string) []string {
+	var optionSetters []string
+	if st := p.optionsStruct(typ); st != nil {
+		typ = OptionSetterStruct(typ)
+		for _, field := range st.Fields.List {
+			optionSetters = append(optionSetters, fmt.Sprintf("\nfunc(v %v) func(*%s) { return func(opts *%s) { opts.%s = v } }(req.%s.%s)",
+				field.Type, typ, typ, field.Names[0], name, field.Names[0]))
+		}
 	}
 	return optionSetters
 }
 
+// optionNames returns the names of the options set by typ setters.
+func (p Pkg) optionNames(typ string) []string {
+	var names []string
+	if st := p.optionsStruct(typ); st != nil {
+		for _, field := range st.Fields.List {
+			for _, name := range field.Names {
+				names = append(names, name.Name)
+			}
+		}
+	}
+	return names
+}
+
 func (p Pkg) generateOptionStructName(typ string) string {
 	if strings.HasPrefix(typ, "...") && strings.HasSuffix(typ,"Setter") {
 		typ = typ[3:len(typ)-6]
@@ -221,14 +452,15 @@ func (p Pkg) generateOptionStructName(typ string) string {
 
 func (p Pkg) params(field *ast.Field) []Param {
 	var params []Param
-	typ := p.fullType(field.Type)
+	typ, imports := p.resolveType(field.Type)
+	checked := p.typeOf(field.Type)
 
 	for _, name := range field.Names {
-		params = append(params, Param{Name: name.Name, Type: typ})
+		params = append(params, Param{Name: name.Name, Type: typ, imports: imports, typ: checked, pos: name.Pos()})
 	}
 	// Handle anonymous params
 	if len(params) == 0 {
-		params = []Param{Param{Type: typ}}
+		params = []Param{Param{Type: typ, imports: imports, typ: checked, pos: field.Type.Pos()}}
 	}
 	return params
 }
@@ -237,51 +469,294 @@ type Service struct {
 	Pkg string
 	IFace string
 	Imports map[string]string
-	Funcs []Func
+	Funcs []Func // methods to generate code for
+	AllFuncs []Func // all methods of the interface, including skipped ones
+	OptionsHead bool
+	RateLimit string
+	Hedge bool
+	ClientCache bool
+	Mock bool
+	Harness bool
+	StubServer bool
+	LoadTest bool
+	Metrics bool
+	MetricsExporter string // prometheus or otel, see -metrics-exporter
+	Dashboard bool
+	DTO bool
+	Recover bool
+	AccessLog bool
+	Health bool
+	Admin bool // see -admin
+	HotReload bool // see -hot-reload
+	Scaffold bool
+	Namespace string // of the metrics, see -namespace
+	Tenant string // how the tenant of a request is told, path or host, see -tenant
+	Repository string // flavor of the repository adapter of the scaffolded command, see -repository
+	Entities []Entity // stored by the repository
+	entityImports []string
+	Hooks bool
+	Conversions []*Conversion // DTOs of the domain structs, see -convert
+	conversionImports []string
+	OpenAPI bool
+	Proto bool
+	HTTP bool // see -transports
+	GRPC bool // see -transports
+	NATS bool // see -transports
+	AMQP bool // see -transports
+	GRPCWeb bool // see -grpc-web
+	Compression string // codec of the requests of the grpc, nats and amqp clients, see -compression
+	DeadLetter bool // see -dead-letter
+	Discovery string // consul, see -discovery
+	EndpointSet bool // see -endpoint-set
+	Client bool // see -client
+	Assertions bool // see -assertions
+	Replay bool // see -replay
+	Logging bool // see -middleware logging
+	Instrumenting bool // see -middleware instrumentation
+	Shadow bool // see -middleware shadow
+	Routing bool // see -middleware routing
+	ErrorStatuses []ErrorStatus // see kit:status
+	Envelope bool // see -response-envelope
+	Tracing bool // see -tracing
+	Router string // chi or mux, see -router
+	Gone []GoneMethod // removed methods whose routes are kept, see -keep-removed
+	ImportPath string // import path of the generated package, if known
+	Module *Module // set if the generated package is a module of its own, see -module
+	IFacePath string // import path of the package declaring the interface
+	Outbound bool // the interface is implemented by a webhook dispatcher, see kit:outbound
+}
+
+// IFaceName returns the name of the interface without its package qualifier.
+func (s Service) IFaceName() string {
+	return s.IFace[strings.LastIndex(s.IFace, ".")+1:]
+}
+
+// UsesEvents reports whether any method emits a domain event.
+func (s Service) UsesEvents() bool {
+	for _, f := range s.Funcs {
+		if f.Event != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// UsesConstraints reports whether any request is validated against the
+// constraints of an API spec.
+func (s Service) UsesConstraints() bool {
+	for _, f := range s.Funcs {
+		if HasConstraints(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// UsesBudgets reports whether any method has a latency budget.
+func (s Service) UsesBudgets() bool {
+	for _, f := range s.Funcs {
+		if f.Budget > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ContextKeys returns the names of the context keys used by the generated code.
+func (s Service) ContextKeys() []string {
+	var keys []string
+	for _, f := range s.Funcs {
+		if f.IfMatch != nil {
+			keys = append(keys, "ifMatchContextKey")
+			break
+		}
+	}
+	if s.UsesBudgets() {
+		keys = append(keys, "deadlineContextKey")
+	}
+	if s.Compression != "" && (s.NATS || s.AMQP) {
+		keys = append(keys, "codecContextKey")
+	}
+	if s.DeadLetter {
+		keys = append(keys, "messageContextKey")
+	}
+	return keys
+}
+
+// Endpoint returns the expression constructing the endpoint of f, wrapped
+// in the middlewares enabled for it.
+func (s Service) Endpoint(f Func) string {
+	e := f.Name + "EndPoint(svc)"
+	if s.Hooks {
+		e = fmt.Sprintf("chainEndpoint(%sEndpointMiddlewares, %s)", f.Name, e)
+	}
+	if f.IfMatch != nil {
+		e = f.Name + "IfMatch(svc)(" + e + ")"
+	}
+	if f.Budget > 0 {
+		e = fmt.Sprintf("ShedOnBudget(%q, %sBudget)(%s)", f.Name, f.Name, e)
+	}
+	if len(f.Scopes) > 0 {
+		e = fmt.Sprintf("RequireScopes(%s)(%s)", ScopeArgs(f), e)
+	}
+	if s.Tracing {
+		e = fmt.Sprintf("TraceEndpoint(%q)(%s)", f.Name, e)
+	}
+	return e
+}
+
+// HTTPHandler returns the expression constructing the HTTP handler of f,
+// wrapped in the HTTP middlewares enabled for it.
+func (s Service) HTTPHandler(f Func) string {
+	h := f.Name + "HTTPJSONHandler(" + s.Endpoint(f) + ")"
+	if s.Routing {
+		h = fmt.Sprintf("headerToContext(%s)", h)
+	}
+	if s.Tracing {
+		h = fmt.Sprintf("traceHTTP(%s)", h)
+	}
+	if s.Recover {
+		h = fmt.Sprintf("recoverHTTP(%q, %s)", f.Name, h)
+	}
+	if s.Metrics {
+		h = fmt.Sprintf("instrumentHTTP(%q, %s)", f.Name, h)
+	}
+	if s.AccessLog {
+		h = fmt.Sprintf("logHTTP(%q, %s)", f.Name, h)
+	}
+	if s.OptionsHead {
+		h = fmt.Sprintf("allowMethods(%q, %s)", f.HTTPMethod, h)
+	}
+	return h
+}
+
+// UsesETags reports whether any method emits an ETag.
+func (s Service) UsesETags() bool {
+	for _, f := range s.Funcs {
+		if f.ETag != nil {
+			return true
+		}
+	}
+	return false
 }
 
 // Func represents a function signature.
 type Func struct {
 	Name   string
+	Doc    string // doc comment of the method, without its annotations
 	Params []Param
 	Res    []Param
 	RequiredImports []string
 	OptionSetters []string
+	HTTPMethod string
+	HTTPPath string
+	Annotations []Annotation
+	ETag *ETag
+	IfMatch *IfMatch
+	Budget time.Duration
+	HedgeDelay time.Duration // delay of the hedged requests of its clients, see kit:hedge
+	SLO float64 // objective in percent, e.g. 99.9
+	Event string
+	Tx bool // run in a transaction by the transaction middleware, see kit:tx
+	TxReadOnly bool
+	Group string // group of the handler serving the method, see kit:group
+	Scopes []string // scopes a token needs to call the method, see kit:scope
+	NilResult string // not-found or no-content response to a nil pointer result, see kit:nil
+	Unwrap bool // single result encoded as the response, without a response struct, see kit:unwrap
+	Skip bool // left out of the generated code, except for implementations of the interface
+	RequestName string // name of the generated request type, see -request-name
+	Renames []Rename // identifiers of the generated code renamed from the interface, see resolveIdents
+	LogCalls string // calls logged by the logging middleware, errors or none if not all, see kit:log
+	LogSample int // 1 in LogSample successful calls is logged by the logging middleware if above 1, see kit:log
+	Fallback string // JSON of the response the clients answer with in degraded mode, see kit:fallback
+	NATSSubject string // subject the method is served on over NATS, if not the default, see kit:nats
+	AMQPQueue string // queue the method consumes its requests from over AMQP, if not the default, see kit:amqp
+	AMQPKey string // routing key binding the queue of the method, if not the queue, see kit:amqp
+	RequestExample string // JSON of an example request, see -examples
+	ResponseExample string // JSON of an example response, see -examples
+	ResponseName string // name of the generated response type, see -response-name
+	RequestType *TypeRef // existing type used as the request, see kit:request
+	ResponseType *TypeRef // existing type used as the response, see kit:response
+	Pos token.Pos // of the method name in src
+	src Pkg // package declaring the method, the types of its signature are relative to it
+	embedded *embedding // outermost embedding of the interface declaring the method, if embedded
+}
+
+// embedding is the embedding of an interface in another one.
+type embedding struct {
+	iface string // embedded interface, e.g. fmt.Stringer
+	src Pkg // package declaring the embedding interface
+	pos token.Pos // of the embedded interface in src
 }
 
 // Param represents a parameter in a function or method signature.
 type Param struct {
 	Name string
 	Type string
+	Constraints *Constraints // checked by the request decoder, if set
+	Enum *Enum // set if the type is an enum, whose values are checked by the request decoder
+	Scalar *Scalar // set if the type is a known scalar such as a UUID
+	Optional bool // may be left out of the request, see kit:optional
+	Sensitive bool // masked when the request is formatted or logged, see kit:sensitive
+	PII string // category of the personal data held, if any, see kit:pii
+	Field string // name of the field of the request or response type holding the parameter
+	JSONName string // name of the JSON field if renamed by -json-case
+	DTOType string // type of the field if the parameter refers to domain structs, see -convert
+	ToDomain string // statements declaring the parameter from the request field, or assigning the result from the response field of res, if converted
+	FromDomain string // statements declaring <Name>DTO from the result or parameter, if converted
+	Options []string // names of the fields of the options struct, if the parameter is an option setter
+	Union *Union // concrete types of an interface result, see kit:oneof
+	PathVar bool // decoded from the path of the route, see kit:http
+	Ident string // name of the parameter in the generated code, if renamed, see resolveIdents
+	imports []string // import paths of the packages referred to by Type
+	typ types.Type // type checked type, nil if it doesn't type check
+	pos token.Pos // of the parameter in the source of the interface
+}
+
+// FieldType returns the type of the request or response field holding p.
+func (p Param) FieldType() string {
+	if p.DTOType != "" {
+		return p.DTOType
+	}
+	if p.Union != nil {
+		return p.Union.Name
+	}
+	return sliceType(OptionSetterStruct(p.Type))
 }
 
 func (p Pkg) funcsig(f *ast.Field) Func {
-	fn := Func{Name: f.Names[0].Name,}
+	fn := Func{Name: f.Names[0].Name, Doc: parseDoc(f.Doc), Annotations: parseAnnotations(f.Doc), Pos: f.Names[0].Pos(), src: p}
+	fn.HTTPMethod = "POST"
+	fn.HTTPPath = "/" + kebabCase(fn.Name)
 	typ := f.Type.(*ast.FuncType)
 	if typ.Params != nil {
 		for _, field := range typ.Params.List {
 			fn.Params = append(fn.Params, p.params(field)...)
 		}
 	}
-	for _, param := range fn.Params {
+	for i, param := range fn.Params {
+		fn.Params[i].Field = Exported(param.Name)
 		if IsOptionSetter(param.Type) {
-			fn.OptionSetters = append(fn.OptionSetters, p.generateOptionSetters(param.Name, param.Type)...)
+			fn.OptionSetters = append(fn.OptionSetters, p.generateOptionSetters(fn.Params[i].Field, param.Type)...)
+			fn.Params[i].Options = p.optionNames(param.Type)
 		}
+		fn.Params[i].Enum = p.enum(param.Type)
+		fn.Params[i].Scalar = p.scalar(param.Type)
 	}
 	if typ.Results != nil {
 		for _, field := range typ.Results.List {
 			fn.Res = append(fn.Res, p.params(field)...)
 		}
 	}
-	for _, i := range p.Imports {
-		k := i[strings.LastIndex(i, "/")+1:]
-		for _, param := range fn.Params {
-			if strings.Contains(param.Type, k) {
-				fn.RequiredImports = append(fn.RequiredImports, i)
-			}
-		}
-		for _, res := range fn.Res {
-			if strings.Contains(res.Type, k) {
+	for i, res := range fn.Res {
+		fn.Res[i].Field = Exported(res.Name)
+		fn.Res[i].Scalar = p.scalar(res.Type)
+	}
+	seen := map[string]bool{}
+	for _, param := range append(append([]Param(nil), fn.Params...), fn.Res...) {
+		for _, i := range param.imports {
+			if !seen[i] {
+				seen[i] = true
 				fn.RequiredImports = append(fn.RequiredImports, i)
 			}
 		}
@@ -290,7 +765,8 @@ func (p Pkg) funcsig(f *ast.Field) Func {
 	return fn
 }
 
-// funcs returns the set of methods required to implement iface.
+// funcs returns the set of methods required to implement iface,
+// including the ones marked to be skipped.
 // It is called funcs rather than methods because the
 // function descriptions are functions; there is no receiver.
 func funcs(iface string, srcDir string) ([]Func, error) {
@@ -307,11 +783,11 @@ func funcs(iface string, srcDir string) ([]Func, error) {
 	}
 	idecl, ok := spec.Type.(*ast.InterfaceType)
 	if !ok {
-		return nil, fmt.Errorf("not an interface: %s", iface)
+		return nil, errorAt(p.FileSet, spec.Type.Pos(), "not an interface: %s", iface)
 	}
 
 	if idecl.Methods == nil {
-		return nil, fmt.Errorf("empty interface: %s", iface)
+		return nil, errorAt(p.FileSet, spec.Name.Pos(), "empty interface: %s", iface)
 	}
 
 	//fmt.Printf("imports: %v\n", p.Imports)
@@ -319,71 +795,340 @@ func funcs(iface string, srcDir string) ([]Func, error) {
 	for _, fndecl := range idecl.Methods.List {
 		if len(fndecl.Names) == 0 {
 			// Embedded interface: recurse
-			embedded, err := funcs(p.fullType(fndecl.Type), srcDir)
+			pos := fndecl.Type.Pos()
+			name := p.fullType(fndecl.Type)
+			embedded, err := funcs(name, srcDir)
 			if err != nil {
-				return nil, err
+				if _, ok := err.(*PosError); ok {
+					return nil, err
+				}
+				return nil, errorAt(p.FileSet, pos, "embedded interface %s: %v", name, err)
+			}
+			skip := skipEmbedded(name, parseAnnotations(fndecl.Doc))
+			for i := range embedded {
+				embedded[i].embedded = &embedding{iface: name, src: p, pos: pos}
+				embedded[i].Skip = embedded[i].Skip || skip
 			}
 			fns = append(fns, embedded...)
 			continue
 		}
 
 		fn := p.funcsig(fndecl)
+		if _, ok := fn.Annotation("skip"); ok {
+			fn.Skip = true
+		}
 		fns = append(fns, fn)
 	}
 	return fns, nil
 }
 
+// skipEmbedded reports whether the methods of the embedded interface name
+// are left out of the generated code, either because the embedding carries
+// a kit:skip annotation or because name is in the -skip-embedded list and
+// the embedding does not carry a kit:include annotation.
+func skipEmbedded(name string, annotations []Annotation) bool {
+	for _, a := range annotations {
+		switch a.Name {
+		case "skip":
+			return true
+		case "include":
+			return false
+		}
+	}
+	for _, skip := range strings.Split(*flagSkipEmbedded, ",") {
+		if strings.TrimSpace(skip) == name {
+			return true
+		}
+	}
+	return false
+}
+
 const stub = `
+{{ define "stubheader" }}
 // Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
 // This file is meant to be re-generated in place and/or deleted at any time.
 
 package {{ .Pkg }}
-{{ $svc := . }}
+
 import ({{ range $imp, $alias := .Imports }}{{ $alias }} "{{ $imp }}"
 {{ end }}
 )
-{{ range $fun := .Funcs }}
-
-
-type {{$fun.Name}}Request struct { {{ range .Params}}{{.Name}} {{ OptionSetterStruct .Type}} 
-{{end}} }
-
-type {{.Name}}Response struct { {{ range FilterError .Res }}{{ .Name }} {{.Type}}
-{{end}} }
-
+{{ end }}{{ template "stubheader" . }}{{ $svc := . }}{{ range .Funcs }}{{ $m := $svc.Only . }}{{ template "endpoint" $m }}{{ if $svc.HTTP }}{{ template "transport" $m }}{{ end }}{{ template "ifmatch" $m }}{{ end }}
+{{ template "common" . }}
+
+{{ define "endpoint" }}{{ $svc := . }}{{ range $fun := .Funcs }}
+{{ if not $svc.DTO }}{{ template "types" . }}{{ end }}
+{{ with .Renamed }}
+// Renamed in the endpoint of {{ $fun.Name }}, as they collide with Go keywords,
+// predeclared identifiers or identifiers of the generated code: {{ . }}.
+{{- end }}
 func {{.Name}}EndPoint(svc {{$svc.IFace}}) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) { {{ if TakesParams $fun }}
-		req := request.({{.Name}}Request){{ end }}
-		{{ JoinParams .Res }} := svc.{{.Name}}({{ GenerateFuncParams $fun }})
-		return {{.Name}}Response{
-			{{ range FilterError .Res  }}{{.Name}}: {{.Name}},
+		req := request.({{ $svc.Request . }}){{ end }}{{ range .Params }}{{ with .ToDomain }}
+		{{ . }}{{ end }}{{ end }}
+		{{ JoinParams .Res }} := svc.{{.Name}}({{ GenerateFuncParams $fun }}){{ range .Res }}{{ with .FromDomain }}
+		{{ . }}{{ end }}{{ end }}{{ with .UnwrappedResult }}
+		return {{ .VarName }}, {{ $fun.ErrVar }}{{ else }}
+		return {{ $svc.Response . }}{
+			{{ range FilterError .Res  }}{{ .Field }}: {{ if .Union }}{{ $svc.DTOQual }}{{ .Union.Name }}{Value: {{.VarName}}}{{ else }}{{.VarName}}{{ if .FromDomain }}DTO{{ end }}{{ end }},
 			{{end}}
-		}, err
+		}, {{ $fun.ErrVar }}{{ end }}
 	}
 }
-
+{{ if $svc.Hooks }}
+// {{.Name}}EndpointMiddlewares wrap the endpoint of {{.Name}}, the first one outermost,
+// inside the middlewares generated for it, such as the checks of scopes. Append
+// to them at init time, before {{ if $svc.HTTP }}MakeHTTPHandler is called{{ else }}the servers are made{{ end }}.
+var {{.Name}}EndpointMiddlewares []endpoint.Middleware
+{{ end }}{{ end }}{{ end }}
+
+{{ define "transport" }}{{ $svc := . }}{{ if .Router }}{{ template "register" . }}{{ end }}{{ range $fun := .Funcs }}
+// {{.Name}}HTTPJSONHandler serves {{ .HTTPMethod }} {{ $svc.Route . }} with the endpoint of
+// {{ $svc.IFace }}.{{ .Name }}{{ with .Doc }}, documented as:
+//
+{{ Comment . }}{{ else }}.{{ end }}
 func {{.Name}}HTTPJSONHandler(e endpoint.Endpoint) http.Handler {
+{{ if $svc.Hooks }}	options := []httptransport.ServerOption{ {{ if .IfMatch }}
+		httptransport.ServerBefore(ifMatchToContext),{{ end }}{{ if .Budget }}
+		httptransport.ServerBefore(DeadlineFromHTTPHeader),{{ end }}{{ if $svc.EncodesErrors }}
+		httptransport.ServerErrorEncoder(EncodeError),{{ end }}
+	}
 	return httptransport.NewServer(
 		e,
 		Decode{{.Name}}Request,
-		EncodeResponse,
-	)
+		Encode{{.Name}}Response,
+		append(options, ServerOptions...)...,
+	){{ else }}	return httptransport.NewServer(
+		e,
+		Decode{{.Name}}Request,
+		{{ if or .ETag .NilResult .RawBytes }}Encode{{.Name}}Response{{ else }}EncodeResponse{{ end }},{{ if .IfMatch }}
+		httptransport.ServerBefore(ifMatchToContext),{{ end }}{{ if .Budget }}
+		httptransport.ServerBefore(DeadlineFromHTTPHeader),{{ end }}{{ if $svc.EncodesErrors }}
+		httptransport.ServerErrorEncoder(EncodeError),{{ end }}
+	){{ end }}
 }
 
 func Decode{{.Name}}Request(_ context.Context, r *http.Request) (interface{}, error) {
-	var request {{.Name}}Request
+	var request {{ $svc.Request . }}{{ if .HasBody }}
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		return nil, err
-	}
+	}{{ end }}{{ range .Params }}{{ if .PathVar }}
+	if err := decodeRouteVar("{{ .Name }}", {{ $svc.PathVar .Name }}, &request.{{ .Field }}); err != nil {
+		return nil, err
+	}{{ end }}{{ end }}{{ range .QueryParams }}
+	if v, ok := r.URL.Query()["{{ .JSONField }}"]; ok {
+		if err := decodeQueryParam("{{ .JSONField }}", v, &request.{{ .Field }}); err != nil {
+			return nil, err
+		}
+	}{{ end }}{{ if $svc.Hooks }}
+	if Decode{{.Name}}Hook != nil {
+		if err := Decode{{.Name}}Hook(r, &request); err != nil {
+			return nil, err
+		}
+	}{{ end }}{{ if $svc.Metrics }}{{ range .Params }}{{ $p := . }}{{ range .Options }}
+	if !reflect.ValueOf(request.{{ $p.Field }}.{{ . }}).IsZero() {
+		OptionCount.With("method", "{{ $fun.Name }}", "option", "{{ . }}").Add(1)
+	}{{ end }}{{ end }}{{ end }}{{ if .RequestType }}
+	if v, ok := interface{}(request).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}{{ else if HasConstraints . }}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}{{ end }}
 	return request, nil
 }
-
+{{ if $svc.Hooks }}
+// Decode{{.Name}}Hook, if set, is called with every decoded {{.Name}} request
+// before it is validated. It may change the request, e.g. to fill in fields
+// from headers, or reject it by returning an error.
+var Decode{{.Name}}Hook func(*http.Request, *{{ $svc.Request . }}) error
+
+// Encode{{.Name}}Hook, if set, is called with every successful {{.Name}}
+// response before it is encoded. It may change the response or set headers.
+var Encode{{.Name}}Hook func(http.ResponseWriter, *{{ $svc.Response . }}) error
+{{ end }}{{ if or .ETag $svc.Hooks .NilResult .RawBytes }}
+// Encode{{.Name}}Response encodes {{.Name}} responses{{ if .ETag }}, setting the ETag header from {{ .ETag.Result.Name }}.{{ .ETag.Field }}{{ end }}{{ if $svc.Hooks }}, calling Encode{{.Name}}Hook{{ end }}{{ if .NilResult }}, responding with {{ .NilResultStatus }} when {{ .NilResultParam.Name }} is nil{{ end }}{{ if .RawBytes }}, writing {{ .UnwrappedResult.Name }} as the body{{ end }}.
+func Encode{{.Name}}Response(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if res, ok := response.({{ $svc.Response . }}); ok { {{ if .ETag }}{{ if .ETag.Nillable }}
+		if res.{{ .ETag.Result.Field }} != nil {
+			w.Header().Set("ETag", etag(res.{{ .ETag.Result.Field }}.{{ .ETag.Field }}))
+		}{{ else }}
+		w.Header().Set("ETag", etag(res.{{ .ETag.Result.Field }}.{{ .ETag.Field }})){{ end }}{{ end }}{{ if $svc.Hooks }}
+		if Encode{{.Name}}Hook != nil {
+			if err := Encode{{.Name}}Hook(w, &res); err != nil {
+				return err
+			}
+			response = res
+		}{{ end }}{{ if .NilResult }}
+		if res{{ if not .Unwrap }}.{{ .NilResultParam.Field }}{{ end }} == nil { {{ if eq .NilResult "not-found" }}
+{{ if $svc.Envelope }}			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(Envelope{Error: http.StatusText(http.StatusNotFound)}){{ else }}			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound){{ end }}{{ else }}
+			w.WriteHeader(http.StatusNoContent){{ end }}
+			return nil
+		}{{ end }}{{ if .RawBytes }}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, err := w.Write(res)
+		return err{{ end }}
+	}
+	return EncodeResponse(ctx, w, response)
+}
+{{ end }}{{ end }}{{ end }}
+
+{{ define "ifmatch" }}{{ $svc := . }}{{ range $fun := .Funcs }}{{ if .IfMatch }}
+// {{.Name}}IfMatch rejects {{.Name}} requests with ErrPreconditionFailed when their
+// If-Match header does not match the current ETag as reported by {{ .IfMatch.Get }}.
+func {{.Name}}IfMatch(svc {{$svc.IFace}}) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			ifMatch, _ := ctx.Value(ifMatchContextKey).(string)
+			if ifMatch != "" { {{ if .IfMatch.UsesReq }}
+				req := request.({{ $svc.Request . }}){{ end }}
+				{{ .IfMatch.Results }} := svc.{{ .IfMatch.Get }}({{ .IfMatch.Args }}){{ if .IfMatch.HasErr }}
+				if err != nil {
+					return nil, err
+				}{{ end }}
+				if {{ if .IfMatch.ETag.Nillable }}{{ .IfMatch.ETag.Result.Name }} == nil || {{ end }}!etagMatches(ifMatch, etag({{ .IfMatch.ETag.Result.Name }}.{{ .IfMatch.ETag.Field }})) {
+					return nil, ErrPreconditionFailed
+				}
+			}
+			return next(ctx, request)
+		}
+	}
+}
 {{ end }}
+{{ end }}{{ end }}
+
+{{ define "common" }}{{ $svc := . }}{{ if .UsesRouteVars }}{{ template "routevars" . }}{{ end }}{{ if .EndpointSet }}{{ template "endpointset" . }}{{ end }}{{ if .Gone }}{{ template "gone" . }}{{ end }}{{ if and .Hooks .HTTP }}
+// ServerOptions are passed to the server of every HTTP handler, after the
+// options the handler needs itself, e.g. to add httptransport.ServerBefore
+// and ServerAfter functions or a ServerErrorEncoder. Set them at init time,
+// before MakeHTTPHandler is called.
+var ServerOptions []httptransport.ServerOption
+{{ end }}{{ if .Hooks }}
+// chainEndpoint returns e wrapped in mws, the first one outermost.
+func chainEndpoint(mws []endpoint.Middleware, e endpoint.Endpoint) endpoint.Endpoint {
+	for i := len(mws) - 1; i >= 0; i-- {
+		e = mws[i](e)
+	}
+	return e
+}
+{{ end }}{{ if .HTTP }}
+{{ range .HandlerGroups }}{{ if .Name }}
+// {{ .Handler }} mounts the HTTP handlers of the endpoints of the {{ .Name }}
+// group on a single http.Handler, to serve on a listener of its own.{{ else if $svc.Groups }}
+// MakeHTTPHandler mounts the HTTP handlers of the endpoints outside a group on
+// a single http.Handler.{{ else }}
+// MakeHTTPHandler mounts the HTTP handlers of all endpoints on a single http.Handler.{{ end }}
+func {{ .Handler }}(svc {{ $svc.IFace }}) http.Handler { {{ if $svc.Router }}
+	routes := {{ $svc.NewRouter }}
+	{{ range .Funcs }}Register{{ .Name }}Route(routes, svc)
+	{{ end }}{{ if not .Name }}{{ range $svc.Gone }}routes.Handle("{{ .Path }}", {{ .Name }}HTTPJSONHandler({{ .Name }}EndPoint(svc)))
+	{{ end }}{{ end }}root := http.NewServeMux()
+	root.Handle("/", {{ if $svc.Tenant }}withTenant(routes){{ else }}routes{{ end }}){{ if and $svc.Health (not .Name) }}
+	root.HandleFunc("/healthz", healthz)
+	root.HandleFunc("/readyz", readyz){{ end }}
+	return root{{ else }}
+	mux := http.NewServeMux(){{ if $svc.Tenant }}
+	routes := http.NewServeMux()
+	{{ range .Funcs }}routes.Handle("{{ .HTTPPath }}", {{ $svc.HTTPHandler . }})
+	{{ end }}{{ if not .Name }}{{ range $svc.Gone }}routes.Handle("{{ .Path }}", {{ .Name }}HTTPJSONHandler({{ .Name }}EndPoint(svc)))
+	{{ end }}{{ end }}mux.Handle("/", withTenant(routes))
+	{{ else }}
+	{{ range .Funcs }}mux.Handle("{{ .HTTPPath }}", {{ $svc.HTTPHandler . }})
+	{{ end }}{{ if not .Name }}{{ range $svc.Gone }}mux.Handle("{{ .Path }}", {{ .Name }}HTTPJSONHandler({{ .Name }}EndPoint(svc)))
+	{{ end }}{{ end }}{{ end }}{{ if and $svc.Health (not .Name) }}mux.HandleFunc("/healthz", healthz)
+	mux.HandleFunc("/readyz", readyz)
+	{{ end }}
+	return mux{{ end }}
+}
+{{ end }}{{ end }}{{ if .OptionsHead }}
+// allowMethods restricts h to method, answers OPTIONS requests with the allowed
+// methods and, for GET routes, serves HEAD requests through h without a body.
+func allowMethods(method string, h http.Handler) http.Handler {
+	allow := method + ", " + http.MethodOptions
+	if method == http.MethodGet {
+		allow = method + ", " + http.MethodHead + ", " + http.MethodOptions
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == method:
+			h.ServeHTTP(w, r)
+		case r.Method == http.MethodOptions:
+			w.Header().Set("Allow", allow)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodHead && method == http.MethodGet:
+			h.ServeHTTP(headResponseWriter{w}, r)
+		default:
+			w.Header().Set("Allow", allow)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
+	})
+}
 
-func EncodeResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
-	return json.NewEncoder(w).Encode(response)
+// headResponseWriter discards the body written by a GET handler.
+type headResponseWriter struct {
+	http.ResponseWriter
 }
 
+func (w headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+{{ end }}{{ if .UsesETags }}
+// ErrPreconditionFailed is returned when the If-Match header of a request does
+// not match the current ETag of the resource.
+var ErrPreconditionFailed error = preconditionFailedError{}
+
+type preconditionFailedError struct{}
+
+func (preconditionFailedError) Error() string { return "precondition failed" }
+
+// StatusCode makes the error encoder respond with 412 Precondition Failed.
+func (preconditionFailedError) StatusCode() int { return http.StatusPreconditionFailed }
+
+// ifMatchToContext stores the If-Match request header in the context.
+func ifMatchToContext(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, ifMatchContextKey, r.Header.Get("If-Match"))
+}
+
+// etag formats v as a strong entity tag.
+func etag(v interface{}) string {
+	return strconv.Quote(fmt.Sprint(v))
+}
+
+// etagMatches reports whether the If-Match header value matches tag.
+func etagMatches(header, tag string) bool {
+	for _, v := range strings.Split(header, ",") {
+		if v = strings.TrimSpace(v); v == "*" || v == tag {
+			return true
+		}
+	}
+	return false
+}
+{{ end }}{{ with .ContextKeys }}
+type contextKey int
+
+const ({{ range $i, $key := . }}
+	{{ $key }}{{ if eq $i 0 }} contextKey = iota{{ end }}{{ end }}
+)
+{{ end }}{{ if and .UsesConstraints (not .DTO) }}{{ template "validationerror" . }}{{ end }}{{ if .UsesBudgets }}{{ template "budget" . }}{{ end }}{{ if .UsesEvents }}{{ template "outbox" . }}{{ end }}{{ if .UsesTx }}{{ template "tx" . }}{{ end }}{{ if .Logging }}{{ template "logging" . }}{{ end }}{{ if .Instrumenting }}{{ template "instrumenting" . }}{{ end }}{{ if .Shadow }}{{ template "shadow" . }}{{ end }}{{ if .Routing }}{{ template "routing" . }}{{ end }}{{ if .EncodesErrors }}{{ template "errorstatus" . }}{{ end }}{{ if .Envelope }}{{ template "envelope" . }}{{ end }}{{ if .Tracing }}{{ template "tracing" . }}{{ end }}{{ if .UsesFallbacks }}{{ template "fallback" . }}{{ end }}{{ if .Tenant }}{{ template "tenant" . }}{{ end }}{{ if .UsesScopes }}{{ template "scope" . }}{{ end }}{{ if .RateLimit }}{{ template "ratelimit" . }}{{ end }}{{ if .Hedge }}{{ template "hedge" . }}{{ end }}{{ if .Metrics }}{{ template "metrics" . }}{{ end }}{{ if .UsesPrometheus }}{{ template "prometheus" . }}{{ end }}{{ if .UsesOTelMetrics }}{{ template "otelmetrics" . }}{{ end }}{{ if and .Conversions (not .DTO) }}{{ template "convert" . }}{{ end }}{{ if .UsesPII }}{{ template "pii" . }}{{ end }}{{ if .UsesLogger }}{{ template "logger" . }}{{ end }}{{ if .Recover }}{{ template "recover" . }}{{ end }}{{ if .AccessLog }}{{ template "accesslog" . }}{{ end }}{{ if .Health }}{{ template "health" . }}{{ end }}{{ if .UsesSwitches }}{{ template "switches" . }}{{ end }}{{ if .ClientCache }}{{ template "clientcache" . }}{{ end }}
+{{ if .HTTP }}{{ if .Envelope }}
+// EncodeResponse encodes the response of a successful call as the data of an
+// Envelope.
+{{ end }}func EncodeResponse(_ context.Context, w http.ResponseWriter, response interface{}) error { {{ if .Envelope }}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8"){{ end }}
+	return json.NewEncoder(w).Encode({{ if .Envelope }}Envelope{Data: response}{{ else }}response{{ end }})
+}
+{{ end }}
+{{ end }}
+
+{{ define "splitendpoint" }}{{ template "stubheader" . }}{{ template "endpoint" . }}{{ template "ifmatch" . }}{{ end }}
+
+{{ define "splittransport" }}{{ template "stubheader" . }}{{ template "transport" . }}{{ end }}
+
+{{ define "splitcommon" }}{{ template "stubheader" . }}{{ template "common" . }}{{ end }}
 `
 
 func IsOptionSetter(typ string) bool {
@@ -397,8 +1142,12 @@ func GenerateFuncParams(f Func) string {
 			params = append(params, fmt.Sprintf("ctx"))
 			continue
 		}
-		if !IsOptionSetter(p.Type) {
-			params = append(params, fmt.Sprintf("req.%s", p.Name))
+		if p.ToDomain != "" {
+			params = append(params, p.VarName())
+		} else if strings.HasPrefix(p.Type, "...") && !IsOptionSetter(p.Type) {
+			params = append(params, "req."+p.Field+"...")
+		} else if !IsOptionSetter(p.Type) {
+			params = append(params, "req."+p.Field)
 		}
 	}
 	for _, optSetter := range f.OptionSetters {
@@ -415,8 +1164,91 @@ func OptionSetterStruct(typ string) string {
 	return typ
 }
 
+// sliceType returns the type of the slice a variadic parameter of type typ
+// receives, e.g. "[]string" for "...string", or typ if it isn't variadic.
+func sliceType(typ string) string {
+	if strings.HasPrefix(typ, "...") {
+		return "[]" + typ[3:]
+	}
+	return typ
+}
+
+// kebabCase converts a Go identifier such as "GetUserByID" to "get-user-by-id".
+func kebabCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// HasError reports whether f returns an error.
+func HasError(f Func) bool {
+	for _, r := range f.Res {
+		if r.Type == "error" {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorName returns the name of the error result of f, as named by Signature.
+func ErrorName(f Func) string {
+	for i, r := range f.Res {
+		if r.Type == "error" {
+			return paramName(r, "r", i)
+		}
+	}
+	return ""
+}
+
+// ErrVar returns the variable the endpoint of f holds its error result in,
+// as renamed by resolveIdents, e.g. error_ for a result named error, or nil
+// if f has none, like the String method of an included fmt.Stringer.
+func (f Func) ErrVar() string {
+	for _, r := range f.Res {
+		if r.Type == "error" {
+			return r.VarName()
+		}
+	}
+	return "nil"
+}
+
+// ContextArg returns the name of the context parameter of f, as named by
+// Signature, or an expression for an empty context if f has none.
+func ContextArg(f Func) string {
+	for i, p := range f.Params {
+		if p.Type == "context.Context" {
+			return paramName(p, "p", i)
+		}
+	}
+	return "context.Background()"
+}
+
+// TakesParams reports whether f takes parameters from its request, i.e.
+// parameters other than a context.
 func TakesParams(f Func) bool {
-	return len(f.Params) > 0
+	for _, p := range f.Params {
+		if p.Type != "context.Context" {
+			return true
+		}
+	}
+	return false
+}
+
+// Exported returns name with its first letter in upper case.
+func Exported(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
 }
 
 
@@ -433,26 +1265,61 @@ func FilterError(params []Param) []Param {
 func JoinParams(params []Param) string {
 	var names []string
 	for _, p := range params {
-		names = append(names, p.Name)
+		names = append(names, p.VarName())
 	}
 	return strings.Join(names, ",")
 }
 
-var tmpl = template.Must(template.New("test").Funcs(template.FuncMap{
-	"JoinParams": JoinParams,
-	"FilterError": FilterError,
-	"TakesParams": TakesParams,
-	"IsOptionSetter": IsOptionSetter,
-	"OptionSetterStruct": OptionSetterStruct,
-	"GenerateFuncParams": GenerateFuncParams,
-}).Parse(stub))
-
-// genStubs prints nicely formatted method stubs
-// for fns using receiver expression recv.
-// If recv is not a valid receiver expression,
-// genStubs will panic.
-func genStubs(iface, pkg string, fns []Func) []byte {
-	var buf bytes.Buffer
+// templates are the texts of the templates tmpl is parsed from.
+var templates = []string{stub, rateLimitTemplate, hedgeTemplate, clientCacheTemplate, budgetTemplate, mockTemplate, harnessTemplate, stubServerTemplate, dtoTemplate, loadTestTemplate, outboxTemplate, validationTemplate, protoTemplate, metricsTemplate, prometheusTemplate, convertTemplate, redactTemplate, piiTemplate, productionTemplate, scaffoldTemplate, unionTemplate, dispatcherTemplate, repositoryTemplate, txTemplate, tenantTemplate, switchesTemplate, reloadTemplate, scopeTemplate, grpcTemplate, grpcClientTemplate, routeTemplate, endpointSetTemplate, goneTemplate, assertionsTemplate, replayTemplate, loggingTemplate, instrumentingTemplate, shadowTemplate, routingTemplate, errorStatusTemplate, envelopeTemplate, natsTemplate, natsClientTemplate, amqpTemplate, amqpClientTemplate, compressionTemplate, deadLetterTemplate, tracingTemplate, clientTemplate, fallbackTemplate, discoveryTemplate, grpcScaffoldTemplate}
+
+var tmpl = parseTemplates(templates...)
+
+// parseTemplates parses the main stub template together with the
+// templates it includes.
+func parseTemplates(texts ...string) *template.Template {
+	t := template.New("test").Funcs(template.FuncMap{
+		"JoinParams": JoinParams,
+		"FilterError": FilterError,
+		"TakesParams": TakesParams,
+		"IsOptionSetter": IsOptionSetter,
+		"OptionSetterStruct": OptionSetterStruct,
+		"GenerateFuncParams": GenerateFuncParams,
+		"DurationLiteral": durationLiteral,
+		"Signature": Signature,
+		"CallArgs": CallArgs,
+		"HasError": HasError,
+		"Comment": Comment,
+		"Exported": Exported,
+		"ResultName": ResultName,
+		"ResultKey": ResultKey,
+		"ErrorName": ErrorName,
+		"ContextArg": ContextArg,
+		"LogKeyvals": LogKeyvals,
+		"ShadowResults": ShadowResults,
+		"ShadowContext": ShadowContext,
+		"ShadowCall": ShadowCall,
+		"StubExample": StubExample,
+		"HasConstraints": HasConstraints,
+		"ValidationPatterns": ValidationPatterns,
+		"Validation": Validation,
+		"IsRedacted": IsRedacted,
+		"RedactedString": RedactedString,
+		"RedactedAttrs": RedactedAttrs,
+		"ScopeConst": scopeConst,
+		"ProtoGoName": protoGoName,
+		"GRPCHandler": GRPCHandler,
+		"ParamName": ParamName,
+	})
+	for _, text := range texts {
+		t = template.Must(t.Parse(text))
+	}
+	return t
+}
+
+// newService collects everything the templates
+// need to generate the package for fns.
+func newService(iface, pkg string, fns []Func) (Service, error) {
 	ifaceName := iface[strings.LastIndex(iface, "/")+1:]
 	ifacePkg := iface[:strings.LastIndex(iface, ".")]
 
@@ -464,54 +1331,281 @@ func genStubs(iface, pkg string, fns []Func) []byte {
 		"github.com/go-kit/kit/endpoint": "",
 		ifacePkg: "",
 	}
+	var exported []Func
 	for _, f := range fns {
+		if f.Skip {
+			continue
+		}
+		exported = append(exported, f)
 		for _, i := range f.RequiredImports {
 			if _, ok := importMap[i]; !ok {
 				importMap[i] = ""
 			}
 		}
 	}
-	svc := Service{Funcs: fns, IFace: ifaceName, Imports: importMap, Pkg: pkg}
-	err := tmpl.Execute(&buf, svc)
+	svc := Service{Funcs: exported, AllFuncs: fns, IFace: ifaceName, Imports: importMap, Pkg: pkg, OptionsHead: *flagOptionsHead, RateLimit: *flagRateLimit, Hedge: *flagHedge, ClientCache: *flagClientCache, Mock: *flagMock || *flagHarness || *flagStubServer, Harness: *flagHarness, StubServer: *flagStubServer, LoadTest: *flagLoadTest, Metrics: *flagMetrics, Dashboard: *flagDashboard, DTO: *flagDTO, OpenAPI: *flagOpenAPI, Proto: *flagProto, Recover: *flagRecover, AccessLog: *flagAccessLog, Health: *flagHealth, Admin: *flagAdmin, HotReload: *flagHotReload, Scaffold: *flagScaffold || *flagHotReload, Hooks: *flagHooks, EndpointSet: *flagEndpointSet || *flagClient, Client: *flagClient, Assertions: *flagAssertions, Replay: *flagReplay, Tracing: *flagTracing, Envelope: *flagEnvelope, IFacePath: ifacePkg}
+	ns, err := namespace(svc.IFaceName(), *flagNamespace)
 	if err != nil {
-		panic(err)
+		return Service{}, err
 	}
-
-	pretty, err := format.Source(buf.Bytes())
+	svc.Namespace = ns
+	if svc.MetricsExporter = *flagMetricsExporter; svc.MetricsExporter != "" {
+		if !metricsExporters[svc.MetricsExporter] {
+			return Service{}, fmt.Errorf("-metrics-exporter: unknown exporter %q, want prometheus or otel", svc.MetricsExporter)
+		}
+		svc.Metrics = true
+	}
+	transports, err := parseTransports(*flagTransports)
 	if err != nil {
-		return buf.Bytes()
+		return Service{}, err
 	}
-	return pretty
-}
-
-func main() {
-	flag.Parse()
-	fmt.Println("// " + *flagPkgName)
-	fmt.Println("// " + *flagSrcDir)
-	//os.Exit(0)
-	if len(flag.Args()) < 1 {
-		_, _ = fmt.Fprint(os.Stderr, usage)
-		os.Exit(2)
+	svc.HTTP, svc.GRPC, svc.NATS, svc.AMQP = transports["http"], transports["grpc"], transports["nats"], transports["amqp"]
+	if !svc.HTTP {
+		if err := checkHTTPFlags(); err != nil {
+			return Service{}, err
+		}
+		stripHTTP(svc.Funcs)
+		stripHTTP(svc.AllFuncs)
 	}
-
-	iface := flag.Arg(0)
-
-
-	if *flagSrcDir == "" {
-		if dir, err := os.Getwd(); err == nil {
-			*flagSrcDir = dir
+	svc.Proto = svc.Proto || svc.GRPC
+	if svc.GRPCWeb = *flagGRPCWeb; svc.GRPCWeb && !svc.GRPC {
+		return Service{}, fmt.Errorf("-grpc-web serves the gRPC server to browsers, add grpc to -transports")
+	}
+	if svc.Compression = *flagCompression; svc.Compression != "" {
+		if err := checkCompression(svc); err != nil {
+			return Service{}, err
+		}
+	}
+	if svc.DeadLetter = *flagDeadLetter; svc.DeadLetter {
+		if err := checkDeadLetter(svc); err != nil {
+			return Service{}, err
 		}
 	}
-	fns, err := funcs(iface, *flagSrcDir)
+	mws, err := parseMiddleware(*flagMiddleware)
 	if err != nil {
-		fatal(err)
+		return Service{}, err
+	}
+	svc.Logging, svc.Instrumenting, svc.Shadow, svc.Routing = mws["logging"], mws["instrumentation"], mws["shadow"], mws["routing"]
+	if err := checkTenant(*flagTenant); err != nil {
+		return Service{}, err
+	}
+	if svc.Tenant = *flagTenant; svc.Tenant != "" {
+		for _, i := range tenantImports {
+			importMap[i] = ""
+		}
+	}
+	if _, ok := authSchemes[*flagAuthScheme]; !ok && *flagAuthScheme != "oauth2" {
+		return Service{}, fmt.Errorf("-auth-scheme: unknown scheme %q, want oauth2, jwt, apikey or basic", *flagAuthScheme)
 	}
+	if *flagRepository != "" {
+		if !repositoryFlavors[*flagRepository] {
+			return Service{}, fmt.Errorf("-repository: unknown flavor %q, want sqlc or ent", *flagRepository)
+		}
+		svc.Scaffold, svc.Repository = true, *flagRepository
+		if err := resolveEntities(&svc); err != nil {
+			return Service{}, err
+		}
+	}
+	if svc.Scaffold && !svc.HTTP {
+		if err := checkGRPCScaffold(svc); err != nil {
+			return Service{}, err
+		}
+	}
+	if svc.Discovery = *flagDiscovery; svc.Discovery != "" {
+		if err := checkDiscovery(svc); err != nil {
+			return Service{}, err
+		}
+	}
+	if svc.UsesETags() {
+		for _, i := range []string{"fmt", "strconv", "strings"} {
+			importMap[i] = ""
+		}
+	}
+	if svc.Hedge = svc.Hedge || svc.UsesHedges(); svc.Hedge {
+		importMap["time"] = ""
+	}
+	if svc.EndpointSet && svc.HasSkipped() {
+		importMap["errors"] = ""
+	}
+	if svc.Router = *flagRouter; svc.Router != "" {
+		importMap[routers[svc.Router]] = ""
+	}
+	if svc.UsesRouteVars() {
+		for _, i := range []string{"encoding", "reflect", "strconv", "time"} {
+			importMap[i] = ""
+		}
+		if svc.Router == "chi" {
+			importMap["net/url"] = ""
+		}
+	}
+	if svc.UsesUnions() && !svc.DTO {
+		importMap["fmt"] = ""
+	}
+	for _, i := range ValidationImports(exported) {
+		importMap[i] = ""
+	}
+	if svc.UsesLogger() {
+		for _, i := range loggerImports {
+			importMap[i] = ""
+		}
+	}
+	if svc.Recover {
+		for _, i := range recoverImports {
+			importMap[i] = ""
+		}
+	}
+	if svc.AccessLog {
+		importMap["time"] = ""
+	}
+	if svc.UsesRedaction() {
+		for _, i := range redactImports {
+			importMap[i] = ""
+		}
+	}
+	if *flagConvert {
+		svc.Conversions, svc.conversionImports = convertTypes(&svc)
+		for _, i := range svc.conversionImports {
+			importMap[i] = ""
+		}
+	}
+	for _, f := range exported {
+		for _, t := range []*TypeRef{f.RequestType, f.ResponseType} {
+			if t != nil {
+				importMap[t.Path] = ""
+			}
+		}
+	}
+	if svc.UsesBudgets() {
+		for _, i := range budgetImports {
+			importMap[i] = ""
+		}
+	}
+	if svc.UsesEvents() {
+		for _, i := range outboxImports {
+			importMap[i] = ""
+		}
+	}
+	if svc.UsesTx() {
+		for _, i := range txImports {
+			importMap[i] = ""
+		}
+	}
+	if svc.Logging {
+		for _, i := range loggingImports {
+			importMap[i] = ""
+		}
+	}
+	if svc.Instrumenting {
+		for i, name := range instrumentingImports {
+			importMap[i] = name
+		}
+	}
+	if svc.Shadow {
+		for i, name := range shadowImports {
+			importMap[i] = name
+		}
+	}
+	if svc.Routing {
+		for i, name := range routingImports {
+			importMap[i] = name
+		}
+		if svc.GRPC {
+			importMap["google.golang.org/grpc/metadata"] = ""
+		}
+	}
+	if !*flagMinimal {
+		if svc.ErrorStatuses, err = findErrorStatuses(errorPackages(ifacePkg, exported), *flagSrcDir, importMap); err != nil {
+			return Service{}, err
+		}
+	}
+	if len(svc.ErrorStatuses) > 0 || svc.Envelope {
+		importMap["errors"] = ""
+	}
+	if svc.UsesScopes() {
+		for _, i := range scopeImports {
+			importMap[i] = ""
+		}
+	}
+	if svc.UsesPrometheus() {
+		svc.Metrics = true
+		for i, name := range prometheusImports {
+			importMap[i] = name
+		}
+	}
+	if svc.Metrics {
+		for _, i := range metricsImports {
+			importMap[i] = ""
+		}
+	}
+	if svc.UsesOTelMetrics() {
+		for _, i := range otelMetricsImports {
+			importMap[i] = ""
+		}
+	}
+	if svc.UsesFallbacks() {
+		for _, i := range fallbackImports {
+			importMap[i] = ""
+		}
+		if svc.GRPC {
+			importMap["google.golang.org/grpc/codes"] = ""
+			importMap["google.golang.org/grpc/status"] = ""
+		}
+	}
+	if svc.Tracing {
+		for i, name := range tracingImports {
+			importMap[i] = name
+		}
+	}
+	if svc.UsesOptionMetrics() {
+		importMap["reflect"] = ""
+	}
+	if svc.ClientCache {
+		for _, i := range clientCacheImports {
+			importMap[i] = ""
+		}
+		if len(svc.CachedFuncs()) > 0 {
+			importMap["io"] = ""
+		}
+	}
+	if svc.UsesSwitches() {
+		for _, i := range switchesImports(svc) {
+			importMap[i] = ""
+		}
+	}
+	if svc.RateLimit != "" {
+		imps, err := rateLimitImports(svc.RateLimit)
+		if err != nil {
+			return Service{}, err
+		}
+		for _, i := range imps {
+			importMap[i] = ""
+		}
+	}
+	if svc.NATS {
+		if err := checkNATS(svc); err != nil {
+			return Service{}, err
+		}
+	}
+	if svc.AMQP {
+		if err := checkAMQP(svc); err != nil {
+			return Service{}, err
+		}
+	}
+	return svc, nil
+}
 
-	src := genStubs(iface, *flagPkgName, fns)
-	fmt.Print(string(src))
+// Only returns a copy of s generating the code of f alone, to render the
+// templates of a method on their own.
+func (s Service) Only(f Func) Service {
+	s.Funcs = []Func{f}
+	return s
 }
 
-func fatal(msg interface{}) {
-	_, _ = fmt.Fprintln(os.Stderr, msg)
-	os.Exit(1)
+// genStubs returns the nicely formatted source
+// of the package implementing svc.
+func genStubs(svc Service) ([]byte, error) {
+	if svc.Outbound {
+		return render("dispatcher", svc)
+	}
+	return render("test", svc)
 }
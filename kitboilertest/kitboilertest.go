@@ -0,0 +1,210 @@
+// Package kitboilertest checks the files KitBoiler generates for fixture
+// interfaces against golden files, so that changes to the templates, presets
+// or flags a project relies on show up as failing tests:
+//
+//	func TestGenerated(t *testing.T) {
+//		kitboilertest.Run(t,
+//			kitboilertest.Case{Name: "minimal", Iface: "example.com/fixtures/api.UserService", Flags: []string{"-minimal"}},
+//			kitboilertest.Case{Name: "production", Iface: "example.com/fixtures/api.UserService", Flags: []string{"-preset", "production"}},
+//		)
+//	}
+//
+// Every case has the kitboiler command, the one named by $KITBOILER or else
+// the one on the PATH, plan the generation of the package, and compares the
+// files of the plan with those of testdata/golden/<Name>. Nothing is written
+// but the golden files, recorded by running the tests with
+// -update-kitboiler-golden.
+package kitboilertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update-kitboiler-golden", false, "record the golden files of kitboilertest.Run")
+
+// Case is a generation checked against golden files.
+type Case struct {
+	Name  string   // name of the subtest and of the directory of its golden files
+	Iface string   // interface to generate the package of, e.g. example.com/fixtures/api.UserService; read from kitboiler.yaml in Dir if empty
+	Flags []string // flags of kitboiler, e.g. -scaffold; -o and -plan are set by Generate
+	Dir   string   // directory kitboiler runs in, in the module of Iface; the working directory if empty
+	// Out is the output directory, relative to Dir, which the import paths
+	// of the generated code follow. It must not exist, "endpoints" if empty.
+	Out string
+}
+
+// Generate has kitboiler plan the generation of c and returns the files of
+// the plan, keyed by their slash separated paths relative to the output
+// directory.
+func Generate(c Case) (map[string][]byte, error) {
+	tmp, err := ioutil.TempDir("", "kitboilertest")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	bin := os.Getenv("KITBOILER")
+	if bin == "" {
+		bin = "kitboiler"
+	}
+	out := c.Out
+	if out == "" {
+		out = "endpoints"
+	}
+	planFile := filepath.Join(tmp, "plan.json")
+	args := append([]string{"plan"}, c.Flags...)
+	args = append(args, "-o", out, "-plan", planFile)
+	if c.Iface != "" {
+		args = append(args, c.Iface)
+	}
+	cmd := exec.Command(bin, args...)
+	cmd.Dir = c.Dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s %s: %v\n%s", bin, strings.Join(args, " "), err, stderr.Bytes())
+	}
+
+	data, err := ioutil.ReadFile(planFile)
+	if err != nil {
+		return nil, err
+	}
+	var plan struct {
+		Files []struct {
+			Name    string `json:"name"`
+			Action  string `json:"action"`
+			Content string `json:"content"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("%s: %v", planFile, err)
+	}
+	files := map[string][]byte{}
+	for _, f := range plan.Files {
+		if f.Action != "create" {
+			return nil, fmt.Errorf("%s exists, set Case.Out to a directory that doesn't", filepath.Join(c.Dir, out))
+		}
+		files[f.Name] = []byte(f.Content)
+	}
+	return files, nil
+}
+
+// Run runs every case as a subtest of t, failing it when the generated files
+// differ from its golden files, or when either has files the other lacks.
+func Run(t *testing.T, cases ...Case) {
+	t.Helper()
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			got, err := Generate(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", "golden", c.Name)
+			if *updateGolden {
+				if err := writeFiles(golden, got); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := readFiles(golden)
+			if err != nil {
+				t.Fatalf("%v (run with -update-kitboiler-golden to record them)", err)
+			}
+			for _, name := range sortedNames(got, want) {
+				path := filepath.Join(golden, filepath.FromSlash(name))
+				g, generated := got[name]
+				w, recorded := want[name]
+				switch {
+				case !recorded:
+					t.Errorf("%s is generated but has no golden file", name)
+				case !generated:
+					t.Errorf("%s isn't generated anymore", path)
+				case !bytes.Equal(g, w):
+					t.Errorf("%s doesn't match %s: %s", name, path, firstDiff(g, w))
+				}
+			}
+		})
+	}
+}
+
+// readFiles returns the files under dir, keyed by their slash separated
+// paths relative to it.
+func readFiles(dir string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	return files, err
+}
+
+// writeFiles replaces the files under dir with files.
+func writeFiles(dir string, files map[string][]byte) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedNames returns the names of the files of a and b, sorted.
+func sortedNames(a, b map[string][]byte) []string {
+	var names []string
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// firstDiff describes the first line where got differs from want.
+func firstDiff(got, want []byte) string {
+	g := strings.Split(string(got), "\n")
+	w := strings.Split(string(want), "\n")
+	for i := 0; i < len(g) || i < len(w); i++ {
+		switch {
+		case i >= len(g):
+			return fmt.Sprintf("line %d is missing, want %q", i+1, w[i])
+		case i >= len(w):
+			return fmt.Sprintf("line %d is extra: %q", i+1, g[i])
+		case g[i] != w[i]:
+			return fmt.Sprintf("line %d: got %q, want %q", i+1, g[i], w[i])
+		}
+	}
+	return "they differ"
+}
@@ -0,0 +1,70 @@
+package kitboilertest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFiles(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "kitboilertest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	dir := filepath.Join(tmp, "golden")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "stale.go"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string][]byte{
+		"endpoints.go":       []byte("package endpoints\n"),
+		"pb/endpoints.proto": []byte("syntax = \"proto3\";\n"),
+	}
+	if err := writeFiles(dir, files); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, files) {
+		t.Errorf("readFiles = %q, want %q", got, files)
+	}
+
+	delete(files, "endpoints.go")
+	if err := writeFiles(dir, files); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := readFiles(dir); !reflect.DeepEqual(got, files) {
+		t.Errorf("readFiles after rewriting = %q, want %q", got, files)
+	}
+}
+
+func TestSortedNames(t *testing.T) {
+	a := map[string][]byte{"b.go": nil, "a.go": nil}
+	b := map[string][]byte{"c.go": nil, "a.go": nil}
+	if got, want := sortedNames(a, b), []string{"a.go", "b.go", "c.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sortedNames = %q, want %q", got, want)
+	}
+}
+
+func TestFirstDiff(t *testing.T) {
+	for _, tc := range []struct {
+		got, want string
+		diff      string
+	}{
+		{"a\nb\n", "a\nc\n", `line 2: got "b", want "c"`},
+		{"a", "a\nb", `line 2 is missing, want "b"`},
+		{"a\nb", "a", `line 2 is extra: "b"`},
+	} {
+		if diff := firstDiff([]byte(tc.got), []byte(tc.want)); diff != tc.diff {
+			t.Errorf("firstDiff(%q, %q) = %s, want %s", tc.got, tc.want, diff, tc.diff)
+		}
+	}
+}
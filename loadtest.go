@@ -0,0 +1,206 @@
+package main
+
+const loadTestTemplate = `
+{{ define "loadtest" }}
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+
+// Package loadtest generates load against the HTTP routes of {{ .IFace }}.
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Scenario is a single kind of call made by Run.
+type Scenario struct {
+	Name   string
+	Weight int // relative frequency of the call, defaults to 1
+	Call   func(ctx context.Context) error
+}
+
+// Payloads holds a factory per method returning the body of the next request,
+// which is encoded as JSON. Methods without a factory are called with an empty
+// request.
+type Payloads struct { {{ range .Funcs }}
+	{{ .Name }} func() interface{}{{ end }}
+}
+
+// HTTPScenarios returns a scenario per method calling its route on baseURL
+// with client, using the payloads from p.{{ if .Groups }} The methods of a group, served on
+// a listener of their own, are left out.{{ end }}
+func HTTPScenarios(baseURL string, client *http.Client, p Payloads) []Scenario {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return []Scenario{ {{ range .Funcs }}{{ if not .Group }}
+		{Name: "{{ .Name }}", Call: httpCall(client, "{{ .HTTPMethod }}", baseURL+"{{ .HTTPPath }}", p.{{ .Name }})},{{ end }}{{ end }}
+	}
+}
+
+func httpCall(client *http.Client, method, url string, payload func() interface{}) func(context.Context) error {
+	return func(ctx context.Context) error {
+		var body interface{} = struct{}{}
+		if payload != nil {
+			body = payload()
+		}
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r, err := http.NewRequest(method, url, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		r.Header.Set("Content-Type", "application/json")
+		res, err := client.Do(r.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		_, _ = io.Copy(ioutil.Discard, res.Body)
+		if res.StatusCode >= 400 {
+			return fmt.Errorf("%s %s: %s", method, url, res.Status)
+		}
+		return nil
+	}
+}
+
+// Options configures Run.
+type Options struct {
+	RPS      float64       // calls started per second
+	Workers  int           // maximum number of concurrent calls
+	Duration time.Duration // how long to generate load
+}
+
+// Stats summarizes the calls of one scenario.
+type Stats struct {
+	Calls     int
+	Errors    int
+	Latencies []time.Duration // sorted
+}
+
+// Percentile returns the latency below which p (0-100) percent of the calls completed.
+func (s *Stats) Percentile(p float64) time.Duration {
+	if len(s.Latencies) == 0 {
+		return 0
+	}
+	i := int(float64(len(s.Latencies)-1) * p / 100)
+	return s.Latencies[i]
+}
+
+// Report is the result of Run.
+type Report struct {
+	Stats   map[string]*Stats
+	Dropped int // calls not started because all workers were busy
+}
+
+// String formats the report as a table.
+func (r Report) String() string {
+	var names []string
+	for name := range r.Stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%-24s %8s %8s %12s %12s %12s\n", "method", "calls", "errors", "p50", "p90", "p99")
+	for _, name := range names {
+		s := r.Stats[name]
+		fmt.Fprintf(&buf, "%-24s %8d %8d %12s %12s %12s\n", name, s.Calls, s.Errors, s.Percentile(50), s.Percentile(90), s.Percentile(99))
+	}
+	fmt.Fprintf(&buf, "dropped: %d\n", r.Dropped)
+	return buf.String()
+}
+
+// Run calls the scenarios, picked at random according to their weights, at
+// the configured rate until the duration has passed or ctx is done.
+func Run(ctx context.Context, scenarios []Scenario, opts Options) Report {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.RPS <= 0 {
+		opts.RPS = 1
+	}
+	var picks []int
+	for i, s := range scenarios {
+		w := s.Weight
+		if w <= 0 {
+			w = 1
+		}
+		for j := 0; j < w; j++ {
+			picks = append(picks, i)
+		}
+	}
+	report := Report{Stats: map[string]*Stats{}}
+	for _, s := range scenarios {
+		report.Stats[s.Name] = &Stats{}
+	}
+	if len(picks) == 0 {
+		return report
+	}
+
+	// calls in flight when the duration has passed are completed
+	callCtx := ctx
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan Scenario)
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for s := range jobs {
+				start := time.Now()
+				err := s.Call(callCtx)
+				latency := time.Since(start)
+				mu.Lock()
+				stats := report.Stats[s.Name]
+				stats.Calls++
+				if err != nil {
+					stats.Errors++
+				}
+				stats.Latencies = append(stats.Latencies, latency)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.RPS))
+	defer ticker.Stop()
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+			select {
+			case jobs <- scenarios[picks[rand.Intn(len(picks))]]:
+			default:
+				report.Dropped++
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, s := range report.Stats {
+		sort.Slice(s.Latencies, func(i, j int) bool { return s.Latencies[i] < s.Latencies[j] })
+	}
+	return report
+}
+{{ end }}
+`
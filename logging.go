@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// loggingImports are the imports required by the logging middleware.
+var loggingImports = []string{"sync/atomic", "time", "github.com/go-kit/kit/log"}
+
+// loggingLevels are the constants of the levels of kit:log, by name.
+var loggingLevels = map[string]string{"all": "LogAllCalls", "errors": "LogFailedCalls", "none": "LogNoCalls"}
+
+// serviceMiddlewares are the service middlewares generated on demand by
+// -middleware.
+var serviceMiddlewares = map[string]bool{"logging": true, "instrumentation": true, "shadow": true, "routing": true}
+
+// parseMiddleware returns the service middlewares named by the comma
+// separated list of -middleware.
+func parseMiddleware(list string) (map[string]bool, error) {
+	mws := map[string]bool{}
+	if list == "" {
+		return mws, nil
+	}
+	for _, m := range strings.Split(list, ",") {
+		m = strings.TrimSpace(m)
+		if !serviceMiddlewares[m] {
+			return nil, fmt.Errorf("-middleware: unknown middleware %q, want logging, instrumentation, shadow or routing", m)
+		}
+		mws[m] = true
+	}
+	return mws, nil
+}
+
+// resolveLogging sets which calls of the methods of fns the logging
+// middleware logs by default from their "//kit:log [all|errors|none]
+// [sample=<n>]" annotations: all of them, only the failing ones or none,
+// and 1 in n of the successful ones.
+func resolveLogging(fns []Func) error {
+	for i := range fns {
+		fn := &fns[i]
+		a, ok := fn.Annotation("log")
+		if !ok {
+			continue
+		}
+		if len(a.Args) == 0 {
+			return fn.errorf(a, "kit:log takes a level, all, errors or none, and/or sample=<n>")
+		}
+		for _, arg := range a.Args {
+			switch {
+			case strings.HasPrefix(arg, "sample="):
+				n, err := strconv.Atoi(strings.TrimPrefix(arg, "sample="))
+				if err != nil || n < 1 {
+					return fn.errorf(a, "kit:log: invalid %s, want a positive number of calls", arg)
+				}
+				fn.LogSample = n
+			case loggingLevels[arg] != "":
+				if fn.LogCalls != "" {
+					return fn.errorf(a, "kit:log takes a single level")
+				}
+				fn.LogCalls = arg
+			default:
+				return fn.errorf(a, "kit:log: unknown level %q, want all, errors or none", arg)
+			}
+		}
+	}
+	return nil
+}
+
+// LoggingLevel returns the constant of the level of the calls of f logged
+// by default, see kit:log.
+func (f Func) LoggingLevel() string {
+	if f.LogCalls == "" {
+		return loggingLevels["all"]
+	}
+	return loggingLevels[f.LogCalls]
+}
+
+// LogKeyvals returns the keys and values logging the parameters of f by
+// the logging middleware, as named by Signature, other than its context.
+// The values of kit:sensitive and kit:pii parameters are masked.
+func LogKeyvals(f Func) string {
+	var keyvals []string
+	for i, p := range f.Params {
+		if p.Type == "context.Context" {
+			continue
+		}
+		value := paramName(p, "p", i)
+		if p.Sensitive || p.PII != "" {
+			value = `"[REDACTED]"`
+		}
+		keyvals = append(keyvals, fmt.Sprintf("%q, %s", paramKey(p, "p", i), value))
+	}
+	return strings.Join(keyvals, ", ")
+}
+
+const loggingTemplate = `
+{{ define "logging" }}
+// LoggingLevel is which calls of a method LoggingMiddleware logs.
+type LoggingLevel int32
+
+const (
+	LogAllCalls    LoggingLevel = iota // the failing calls and a sample of the successful ones
+	LogFailedCalls                     // only the failing calls
+	LogNoCalls                         // no call
+)
+
+// LoggingControl controls how LoggingMiddleware logs the calls of a method.
+// Its level and sample rate can be changed while serving.
+type LoggingControl struct {
+	calls      uint64 // successful calls, sampled by sampleRate
+	sampleRate int64
+	level      int32
+}
+
+// Level returns which calls are logged.
+func (c *LoggingControl) Level() LoggingLevel {
+	return LoggingLevel(atomic.LoadInt32(&c.level))
+}
+
+// SetLevel sets which calls are logged.
+func (c *LoggingControl) SetLevel(l LoggingLevel) {
+	atomic.StoreInt32(&c.level, int32(l))
+}
+
+// SampleRate returns n if 1 in n successful calls is logged.
+func (c *LoggingControl) SampleRate() int {
+	return int(atomic.LoadInt64(&c.sampleRate))
+}
+
+// SetSampleRate logs 1 in n successful calls, or all of them if n is 1 or
+// less. Failing calls are always logged, unless the level is LogNoCalls.
+func (c *LoggingControl) SetSampleRate(n int) {
+	atomic.StoreInt64(&c.sampleRate, int64(n))
+}
+
+// logs reports whether a call returning err is logged, counting it.
+func (c *LoggingControl) logs(err error) bool {
+	switch c.Level() {
+	case LogNoCalls:
+		return false
+	case LogFailedCalls:
+		return err != nil
+	}
+	if n := c.SampleRate(); err == nil && n > 1 {
+		return atomic.AddUint64(&c.calls, 1)%uint64(n) == 1
+	}
+	return true
+}
+
+// LoggingControls control how LoggingMiddleware logs the calls of every
+// method, by method name. Their defaults are set by the kit:log annotations
+// of the methods: all calls, unsampled, without one.
+var LoggingControls = map[string]*LoggingControl{ {{ range .Funcs }}
+	"{{ .Name }}": {level: int32({{ .LoggingLevel }}){{ if gt .LogSample 1 }}, sampleRate: {{ .LogSample }}{{ end }}},{{ end }}
+}
+
+// LoggingMiddleware returns a service middleware logging the calls of the
+// methods of {{ .IFace }} to logger, with their parameters, error and
+// duration, as controlled by LoggingControls. Parameters annotated with
+// kit:sensitive or kit:pii are masked.
+func LoggingMiddleware(logger log.Logger) func({{ .IFace }}) {{ .IFace }} {
+	return func(next {{ .IFace }}) {{ .IFace }} {
+		return loggingMiddleware{next, logger}
+	}
+}
+
+type loggingMiddleware struct {
+	{{ .IFace }}
+	logger log.Logger
+}
+{{ range $fun := .Funcs }}
+func (mw loggingMiddleware) {{ .Name }}{{ Signature . }} {
+	defer func(begin time.Time) {
+		if !LoggingControls["{{ .Name }}"].logs({{ or (ErrorName .) "nil" }}) {
+			return
+		}
+		mw.logger.Log("method", "{{ .Name }}"{{ with LogKeyvals . }}, {{ . }}{{ end }}{{ with ErrorName . }}, "err", {{ . }}{{ end }}, "took", time.Since(begin))
+	}(time.Now())
+	{{ if .Res }}return {{ end }}mw.{{ $.IFaceName }}.{{ .Name }}({{ CallArgs . }})
+}
+{{ end }}{{ end }}
+`
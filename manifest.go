@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// manifestName is the name of the manifest in the output directory.
+const manifestName = "kitboiler.manifest.json"
+
+// Manifest lists the files generated for an interface, so that build
+// systems and clean-up tooling can tell them apart from the rest of the
+// output directory.
+type Manifest struct {
+	Interface string            `json:"interface"`
+	Package   string            `json:"package"`
+	Version   string            `json:"version,omitempty"` // of the generated API, see -changelog
+	Methods   []ManifestMethod  `json:"methods"`
+	Removed   []ManifestRemoved `json:"removed,omitempty"` // see -keep-removed
+	Files     []ManifestFile    `json:"files"`
+}
+
+// ManifestMethod is a method code is generated for, with the signature it
+// had, to recognize the method when it is renamed.
+type ManifestMethod struct {
+	Name      string `json:"name"`
+	Signature string `json:"signature"`
+	Route     string `json:"route"`
+}
+
+// ManifestRemoved is a method removed from the interface whose route is
+// kept responding with 410 Gone.
+type ManifestRemoved struct {
+	Name      string `json:"name"`
+	Route     string `json:"route"`
+	Remaining int    `json:"remaining"` // generations it is kept for after the one writing the manifest
+}
+
+// ManifestFile is a generated file in the manifest.
+type ManifestFile struct {
+	Name string `json:"name"` // slash separated path relative to the output directory
+	Role string `json:"role"`
+	// Method is the name of the method the file is generated for, if any.
+	Method string `json:"method,omitempty"`
+	// Keep is set for files that are generated only if they don't exist and
+	// may be edited afterwards, such as fixtures.
+	Keep bool `json:"keep,omitempty"`
+	// SHA256 is the hash of the generated content, telling whether the file
+	// has been edited since.
+	SHA256 string `json:"sha256"`
+	// Stale is set for files generated by an earlier run but not anymore,
+	// until they are removed by kitboiler clean.
+	Stale bool `json:"stale,omitempty"`
+}
+
+// genManifest returns the manifest of the files generated for svc. The
+// files of the previous manifest that still exist in dir but aren't
+// generated anymore are carried over as stale.
+func genManifest(svc Service, files []File, prev Manifest, dir, version string) (File, error) {
+	m := Manifest{Interface: svc.IFacePath + "." + svc.IFaceName(), Package: svc.Pkg, Version: version}
+	m.Methods = manifestMethods(svc.Funcs)
+	for _, g := range svc.Gone {
+		m.Removed = append(m.Removed, ManifestRemoved{Name: g.Name, Route: g.Route, Remaining: g.Remaining})
+	}
+	current := map[string]bool{}
+	for _, f := range files {
+		name := filepath.ToSlash(f.Name)
+		current[name] = true
+		m.Files = append(m.Files, ManifestFile{Name: name, Role: f.Role, Method: f.Method, Keep: f.Keep, SHA256: hash(f.Content)})
+	}
+	for _, f := range prev.Files {
+		if current[f.Name] {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(f.Name))); err == nil {
+			f.Stale = true
+			m.Files = append(m.Files, f)
+		}
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return File{}, err
+	}
+	return File{Name: manifestName, Content: append(data, '\n'), Role: "manifest"}, nil
+}
+
+func manifestMethods(fns []Func) []ManifestMethod {
+	var methods []ManifestMethod
+	for _, f := range fns {
+		methods = append(methods, ManifestMethod{Name: f.Name, Signature: f.Signature(), Route: f.HTTPMethod + " " + f.HTTPPath})
+	}
+	return methods
+}
+
+func hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// withManifest appends the manifest to files unless -manifest is off.
+func withManifest(svc Service, files []File) ([]File, error) {
+	if !*flagManifest {
+		return files, nil
+	}
+	prev, err := readManifest(*flagOutDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	version := prev.Version
+	if *flagChangelog {
+		version, _ = releaseVersion(prev, svc.Funcs)
+	}
+	manifest, err := genManifest(svc, files, prev, *flagOutDir, version)
+	if err != nil {
+		return nil, err
+	}
+	return append(files, manifest), nil
+}
+
+// readManifest reads the manifest in dir.
+func readManifest(dir string) (Manifest, error) {
+	var m Manifest
+	data, err := ioutil.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("%s: %v", filepath.Join(dir, manifestName), err)
+	}
+	return m, nil
+}
@@ -0,0 +1,209 @@
+package main
+
+// metricsImports are the imports required by the HTTP instrumentation.
+var metricsImports = []string{"io", "strconv", "time", "github.com/go-kit/kit/metrics", "github.com/go-kit/kit/metrics/discard"}
+
+// otelMetricsImports are the imports required by UseOTelMetrics.
+var otelMetricsImports = []string{"context", "go.opentelemetry.io/otel/attribute", "go.opentelemetry.io/otel/metric"}
+
+// metricsExporters are the values of -metrics-exporter, generating a function
+// setting the HTTP metrics to those of the exporter.
+var metricsExporters = map[string]bool{"prometheus": true, "otel": true}
+
+// UsesOTelMetrics reports whether UseOTelMetrics sets the HTTP metrics to
+// OpenTelemetry instruments, see -metrics-exporter otel.
+func (s Service) UsesOTelMetrics() bool {
+	return s.MetricsExporter == "otel"
+}
+
+// UsesOptionMetrics reports whether the request decoders count the options
+// set by the requests of methods taking option setters.
+func (s Service) UsesOptionMetrics() bool {
+	if !s.Metrics {
+		return false
+	}
+	for _, f := range s.Funcs {
+		for _, p := range f.Params {
+			if len(p.Options) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+const metricsTemplate = `
+{{ define "metrics" }}
+// Metrics of the HTTP handlers, recorded by the handlers of MakeHTTPHandler.
+// They discard all observations until set to real metrics, e.g. Prometheus
+// ones.
+var (
+	// RequestCount counts the requests handled, labeled by "method" and
+	// "code", the HTTP status code of the response.
+	RequestCount metrics.Counter = discard.NewCounter()
+
+	// RequestLatency observes the seconds taken to handle a request, labeled
+	// by "method".
+	RequestLatency metrics.Histogram = discard.NewHistogram()
+
+	// RequestSize observes the size of request bodies in bytes, labeled by
+	// "method".
+	RequestSize metrics.Histogram = discard.NewHistogram()
+
+	// ResponseSize observes the size of response bodies in bytes, labeled by
+	// "method".
+	ResponseSize metrics.Histogram = discard.NewHistogram()
+{{ if .UsesOptionMetrics }}
+	// OptionCount counts the options set by requests, labeled by "method"
+	// and "option", the name of the field of the options struct. Options
+	// are set if they aren't the zero value.
+	OptionCount metrics.Counter = discard.NewCounter()
+{{ end }})
+
+// instrumentHTTP records the metrics of the requests of method handled by h.
+func instrumentHTTP(method string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		begin := time.Now()
+		body := &countingReadCloser{ReadCloser: r.Body}
+		r.Body = body
+		mw := &metricsResponseWriter{ResponseWriter: w, code: http.StatusOK}
+		h.ServeHTTP(mw, r)
+		RequestCount.With("method", method, "code", strconv.Itoa(mw.code)).Add(1)
+		RequestLatency.With("method", method).Observe(time.Since(begin).Seconds())
+		RequestSize.With("method", method).Observe(float64(body.n))
+		ResponseSize.With("method", method).Observe(float64(mw.n))
+	})
+}
+
+// countingReadCloser counts the bytes read from a request body.
+type countingReadCloser struct {
+	io.ReadCloser
+	n int
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += n
+	return n, err
+}
+
+// metricsResponseWriter records the status code and the size of a response.
+type metricsResponseWriter struct {
+	http.ResponseWriter
+	code int
+	n    int
+}
+
+func (w *metricsResponseWriter) WriteHeader(code int) {
+	w.code = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *metricsResponseWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.n += n
+	return n, err
+}
+{{ end }}
+
+{{ define "otelmetrics" }}
+// UseOTelMetrics sets the HTTP metrics to OpenTelemetry instruments named
+// "{{ .MetricsNamespace }}.http.*" created with meter, e.g. the one returned
+// by otel.Meter("{{ .ImportPath }}") for the global MeterProvider, exporting
+// them over OTLP. Call it once, before serving requests.{{ if .UsesFallbacks }} It sets
+// FallbackCount as well, to "{{ .MetricsNamespace }}.client.fallbacks".{{ end }}{{ if .DeadLetter }} It sets
+// RetryCount and DeadLetterCount as well, to "{{ .MetricsNamespace }}.messages.retries" and
+// "{{ .MetricsNamespace }}.messages.dead_letters".{{ end }}
+func UseOTelMetrics(meter metric.Meter) error {
+	requests, err := meter.Float64Counter("{{ .MetricsNamespace }}.http.requests",
+		metric.WithDescription("Number of requests handled."), metric.WithUnit("{request}"))
+	if err != nil {
+		return err
+	}
+	latency, err := meter.Float64Histogram("{{ .MetricsNamespace }}.http.request.duration",
+		metric.WithDescription("Time taken to handle a request."), metric.WithUnit("s"))
+	if err != nil {
+		return err
+	}
+	requestSize, err := meter.Float64Histogram("{{ .MetricsNamespace }}.http.request.size",
+		metric.WithDescription("Size of request bodies."), metric.WithUnit("By"))
+	if err != nil {
+		return err
+	}
+	responseSize, err := meter.Float64Histogram("{{ .MetricsNamespace }}.http.response.size",
+		metric.WithDescription("Size of response bodies."), metric.WithUnit("By"))
+	if err != nil {
+		return err
+	}{{ if .UsesOptionMetrics }}
+	options, err := meter.Float64Counter("{{ .MetricsNamespace }}.http.options",
+		metric.WithDescription("Number of options set by requests."), metric.WithUnit("{option}"))
+	if err != nil {
+		return err
+	}
+	OptionCount = otelCounter{c: options}{{ end }}{{ if .UsesFallbacks }}
+	fallbacks, err := meter.Float64Counter("{{ .MetricsNamespace }}.client.fallbacks",
+		metric.WithDescription("Number of calls answered with the fallback response of their method."), metric.WithUnit("{call}"))
+	if err != nil {
+		return err
+	}
+	FallbackCount = otelCounter{c: fallbacks}{{ end }}{{ if .DeadLetter }}
+	retries, err := meter.Float64Counter("{{ .MetricsNamespace }}.messages.retries",
+		metric.WithDescription("Number of retries of the requests of the message subscribers."), metric.WithUnit("{retry}"))
+	if err != nil {
+		return err
+	}
+	deadLetters, err := meter.Float64Counter("{{ .MetricsNamespace }}.messages.dead_letters",
+		metric.WithDescription("Number of requests of the message subscribers published to a dead-letter subject or queue."), metric.WithUnit("{request}"))
+	if err != nil {
+		return err
+	}
+	RetryCount = otelCounter{c: retries}
+	DeadLetterCount = otelCounter{c: deadLetters}{{ end }}
+	RequestCount = otelCounter{c: requests}
+	RequestLatency = otelHistogram{h: latency}
+	RequestSize = otelHistogram{h: requestSize}
+	ResponseSize = otelHistogram{h: responseSize}
+	return nil
+}
+
+// otelCounter is a metrics.Counter adding to an OpenTelemetry counter, with
+// the labels as attributes.
+type otelCounter struct {
+	c      metric.Float64Counter
+	labels []string
+}
+
+func (c otelCounter) With(labelValues ...string) metrics.Counter {
+	return otelCounter{c.c, append(append([]string(nil), c.labels...), labelValues...)}
+}
+
+func (c otelCounter) Add(delta float64) {
+	c.c.Add(context.Background(), delta, metric.WithAttributes(otelAttributes(c.labels)...))
+}
+
+// otelHistogram is a metrics.Histogram recording to an OpenTelemetry
+// histogram, with the labels as attributes.
+type otelHistogram struct {
+	h      metric.Float64Histogram
+	labels []string
+}
+
+func (h otelHistogram) With(labelValues ...string) metrics.Histogram {
+	return otelHistogram{h.h, append(append([]string(nil), h.labels...), labelValues...)}
+}
+
+func (h otelHistogram) Observe(value float64) {
+	h.h.Record(context.Background(), value, metric.WithAttributes(otelAttributes(h.labels)...))
+}
+
+// otelAttributes returns the attributes of the label name and value pairs
+// labels.
+func otelAttributes(labels []string) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		attrs = append(attrs, attribute.String(labels[i], labels[i+1]))
+	}
+	return attrs
+}
+{{ end }}
+`
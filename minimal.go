@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// minimalFlags are the flags -minimal can be combined with; all others add
+// features to the generated code.
+var minimalFlags = map[string]bool{
+	"minimal":        true,
+	"dir":            true,
+	"pkg":            true,
+	"o":              true,
+	"skip-embedded":  true,
+	"scalars":        true,
+	"config":         true,
+	"profile":        true,
+	"manifest":       true,
+	"module":         true,
+	"changelog":      true,
+	"go-generate":    true,
+	"follow-renames": true,
+	"get-methods":    true,
+	"plan":           true,
+	"route-prefix":   true,
+	"router":         true,
+	"split":          true,
+}
+
+// checkMinimal returns an error if a flag adding features is set along
+// with -minimal.
+func checkMinimal() error {
+	var err error
+	flag.Visit(func(f *flag.Flag) {
+		if err == nil && !minimalFlags[f.Name] {
+			err = fmt.Errorf("-minimal can't be combined with -%s", f.Name)
+		}
+	})
+	return err
+}
+
+// minimize strips the features added by annotations from the methods of
+// fns, leaving only their endpoints and bare HTTP handlers. Annotations
+// shaping the request and response types, such as kit:skip and kit:request,
+// still apply.
+func minimize(fns []Func) {
+	for i := range fns {
+		fn := &fns[i]
+		fn.ETag, fn.IfMatch = nil, nil
+		fn.Budget, fn.SLO, fn.Event, fn.NilResult = 0, 0, "", ""
+		fn.Tx, fn.TxReadOnly = false, false
+		fn.Fallback = ""
+		for j := range fn.Params {
+			p := &fn.Params[j]
+			p.Constraints, p.Enum = nil, nil
+			p.Sensitive, p.PII = false, ""
+		}
+		for j := range fn.Res {
+			fn.Res[j].PII = ""
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Signature returns the parameter and result lists of f, e.g.
+// "(ctx context.Context, id string) (user *model.User, err error)".
+// Unnamed parameters and results are named p0, p1, ... and r0, r1, ...
+func Signature(f Func) string {
+	var params, res []string
+	for i, p := range f.Params {
+		params = append(params, paramName(p, "p", i)+" "+p.Type)
+	}
+	for i, r := range f.Res {
+		res = append(res, paramName(r, "r", i)+" "+r.Type)
+	}
+	return "(" + strings.Join(params, ", ") + ") (" + strings.Join(res, ", ") + ")"
+}
+
+// CallArgs returns the arguments passing the parameters of f, as named by
+// Signature, on to another function with the same signature.
+func CallArgs(f Func) string {
+	var args []string
+	for i, p := range f.Params {
+		arg := paramName(p, "p", i)
+		if strings.HasPrefix(p.Type, "...") {
+			arg += "..."
+		}
+		args = append(args, arg)
+	}
+	return strings.Join(args, ", ")
+}
+
+// paramName returns the name of p in the generated code, or prefix
+// followed by its index i if it is unnamed.
+func paramName(p Param, prefix string, i int) string {
+	if p.Ident != "" {
+		return p.Ident
+	}
+	return paramKey(p, prefix, i)
+}
+
+// paramKey returns the name of p, or prefix followed by its index i if it
+// is unnamed, as the key of its value in JSON or YAML.
+func paramKey(p Param, prefix string, i int) string {
+	if p.Name == "" || p.Name == "_" {
+		return prefix + strconv.Itoa(i)
+	}
+	return p.Name
+}
+
+// VarName returns the name of the variable holding p in the generated code.
+func (p Param) VarName() string {
+	if p.Ident != "" {
+		return p.Ident
+	}
+	return p.Name
+}
+
+// MockImports returns the imports required by the types in the method
+// signatures of s.
+func (s Service) MockImports() []string {
+	seen := map[string]bool{}
+	var imps []string
+	// types declared along with the interface
+	qual := s.IFace[:strings.LastIndex(s.IFace, ".")+1]
+	for _, f := range s.AllFuncs {
+		if !seen[s.IFacePath] && strings.Contains(Signature(f), qual) {
+			seen[s.IFacePath] = true
+			imps = append(imps, s.IFacePath)
+		}
+		for _, i := range f.RequiredImports {
+			if !seen[i] {
+				seen[i] = true
+				imps = append(imps, i)
+			}
+		}
+	}
+	sort.Strings(imps)
+	return imps
+}
+
+const mockTemplate = `
+{{ define "mock" }}
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+
+package {{ .Pkg }}
+
+import ({{ range .MockImports }}
+	"{{ . }}"{{ end }}
+)
+
+// MockService implements {{ .IFace }} by calling the function field of each
+// method, returning zero values when it is nil.
+type MockService struct { {{ range .AllFuncs }}
+	{{ .Name }}Func func{{ Signature . }}{{ end }}
+}
+{{ range .AllFuncs }}
+func (m *MockService) {{ .Name }}{{ Signature . }} {
+	if m.{{ .Name }}Func == nil {
+		return
+	}
+	{{ if .Res }}return {{ end }}m.{{ .Name }}Func({{ CallArgs . }})
+}
+{{ end }}
+{{ end }}
+`
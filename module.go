@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GoMod is the part of a go.mod file KitBoiler cares about.
+type GoMod struct {
+	Dir      string            // directory of the go.mod file
+	Path     string            // module path
+	Go       string            // go directive
+	Requires map[string]string // required versions, by module path
+}
+
+// Module is the standalone module the package is generated as.
+type Module struct {
+	Path string
+	Dir  string // output directory
+	Src  *GoMod // module of the interface
+}
+
+// findGoMod returns the go.mod of the module enclosing dir.
+func findGoMod(dir string) (*GoMod, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for d := abs; ; d = filepath.Dir(d) {
+		data, err := ioutil.ReadFile(filepath.Join(d, "go.mod"))
+		if err == nil {
+			mod := parseGoMod(data)
+			mod.Dir = d
+			return mod, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		if filepath.Dir(d) == d {
+			return nil, fmt.Errorf("no go.mod in %s or its parents", dir)
+		}
+	}
+}
+
+// parseGoMod parses the module path, go directive and requirements of the
+// contents of a go.mod file.
+func parseGoMod(data []byte) *GoMod {
+	mod := &GoMod{Path: modulePath(data), Requires: map[string]string{}}
+	inRequire := false
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case inRequire && fields[0] == ")":
+			inRequire = false
+		case inRequire && len(fields) == 2:
+			mod.Requires[unquote(fields[0])] = fields[1]
+		case fields[0] == "require" && len(fields) == 2 && fields[1] == "(":
+			inRequire = true
+		case fields[0] == "require" && len(fields) == 3:
+			mod.Requires[unquote(fields[1])] = fields[2]
+		case fields[0] == "go" && len(fields) == 2:
+			mod.Go = fields[1]
+		}
+	}
+	return mod
+}
+
+func unquote(s string) string {
+	if u, err := strconv.Unquote(s); err == nil {
+		return u
+	}
+	return s
+}
+
+// genGoMod returns the go.mod of the module path holding the generated
+// files. The modules imported by the files are required at the versions
+// required by src, the module of the interface; src itself is required
+// with a replace directive pointing at its directory, relative to the
+// output directory dir, to be dropped once it's published. Imports of
+// modules src doesn't require are left to go mod tidy.
+func genGoMod(path string, files []File, src *GoMod, dir string) ([]byte, error) {
+	imports := map[string]bool{}
+	fset := token.NewFileSet()
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name, ".go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, f.Name, f.Content, parser.ImportsOnly)
+		if err != nil {
+			return nil, fmt.Errorf("generated %s: %v", f.Name, err)
+		}
+		for _, imp := range file.Imports {
+			imports[unquote(imp.Path.Value)] = true
+		}
+	}
+
+	requires := map[string]string{}
+	replaceSrc := false
+	for imp := range imports {
+		if !strings.Contains(strings.Split(imp, "/")[0], ".") || hasPathPrefix(imp, path) {
+			continue // standard library or the module itself
+		}
+		if hasPathPrefix(imp, src.Path) {
+			requires[src.Path] = "v0.0.0-00010101000000-000000000000"
+			replaceSrc = true
+			continue
+		}
+		best := ""
+		for mod := range src.Requires {
+			if hasPathPrefix(imp, mod) && len(mod) > len(best) {
+				best = mod
+			}
+		}
+		if best != "" {
+			requires[best] = src.Requires[best]
+		}
+	}
+
+	goVersion := src.Go
+	if goVersion == "" {
+		goVersion = strings.Join(strings.SplitN(strings.TrimPrefix(runtime.Version(), "go"), ".", 3)[:2], ".")
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "module %s\n\ngo %s\n", path, goVersion)
+	if len(requires) > 0 {
+		var mods []string
+		for mod := range requires {
+			mods = append(mods, mod)
+		}
+		sort.Strings(mods)
+		buf.WriteString("\nrequire (\n")
+		for _, mod := range mods {
+			fmt.Fprintf(&buf, "\t%s %s\n", mod, requires[mod])
+		}
+		buf.WriteString(")\n")
+	}
+	if replaceSrc {
+		out, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(out, src.Dir)
+		if err != nil {
+			return nil, err
+		}
+		rel = filepath.ToSlash(rel)
+		if !strings.HasPrefix(rel, ".") {
+			rel = "./" + rel
+		}
+		fmt.Fprintf(&buf, "\n// the module of the interface, until it is published\nreplace %s => %s\n", src.Path, rel)
+	}
+	return buf.Bytes(), nil
+}
+
+// hasPathPrefix reports whether the import path p is prefix or in it.
+func hasPathPrefix(p, prefix string) bool {
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
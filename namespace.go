@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// metricNameRE matches valid Prometheus metric name prefixes.
+var metricNameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// prefixRoutes mounts the routes of the methods of fns under prefix, such
+// as "/internal/billing", set by -route-prefix.
+func prefixRoutes(fns []Func, prefix string) error {
+	if prefix == "" {
+		return nil
+	}
+	if !strings.HasPrefix(prefix, "/") || strings.ContainsAny(prefix, " ?#{}") {
+		return fmt.Errorf("-route-prefix: %q isn't an absolute path, such as /internal/billing", prefix)
+	}
+	prefix = strings.TrimRight(prefix, "/")
+	for i := range fns {
+		fns[i].HTTPPath = prefix + fns[i].HTTPPath
+	}
+	return nil
+}
+
+// namespace returns the namespace of the metrics of the service iface, set
+// by -namespace or else derived from its name, e.g. "user_service".
+func namespace(iface, flagValue string) (string, error) {
+	if flagValue == "" {
+		return snakeCase(iface), nil
+	}
+	if !metricNameRE.MatchString(flagValue) {
+		return "", fmt.Errorf("-namespace: %q isn't a valid metric name prefix, use letters, digits and underscores", flagValue)
+	}
+	return flagValue, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// jsonCases convert the Go name of a request or response field to its JSON
+// name, for -json-case.
+var jsonCases = map[string]func(Param) string{
+	"camel": func(p Param) string { return lowerFirst(p.Field) },
+	"snake": func(p Param) string { return snakeCase(p.Field) },
+	"kebab": func(p Param) string { return kebabCase(p.Field) },
+	"lower": func(p Param) string { return strings.ToLower(p.Field) },
+}
+
+// -json-naming is an alias of -json-case, under the name of the option in
+// other generators.
+func init() {
+	flag.StringVar(flagJSONCase, "json-naming", "", "alias of -json-case")
+}
+
+// resolveNames names the request and response types of fns after the
+// patterns of -request-name and -response-name, in which {Method} is the
+// method name and {method} the same with a lower case first letter, and the
+// JSON fields of those types after -json-case; the Go field names are kept
+// without it. Types named like a predeclared identifier or one the
+// templates declare, e.g. EncodeResponse for the response of Encode, are
+// renamed.
+func resolveNames(fns []Func, requestName, responseName, jsonCase string) error {
+	caseOf, ok := jsonCases[jsonCase]
+	if !ok && jsonCase != "" {
+		return fmt.Errorf("-json-case: unknown case %q, want camel, snake, kebab or lower", jsonCase)
+	}
+	types := map[string]string{}
+	declared := packageIdents(fns)
+	for i := range fns {
+		fn := &fns[i]
+		fn.RequestName = methodPattern(requestName, fn.Name)
+		fn.ResponseName = methodPattern(responseName, fn.Name)
+		for _, name := range []*string{&fn.RequestName, &fn.ResponseName} {
+			if ident := safeIdent(*name, func(name string) bool { return predeclared[name] || declared[name] }); ident != *name {
+				fn.Renames = append(fn.Renames, Rename{*name, ident})
+				*name = ident
+			}
+		}
+		for _, n := range []struct{ flag, name string }{{"-request-name", fn.RequestName}, {"-response-name", fn.ResponseName}} {
+			if !token.IsIdentifier(n.name) {
+				return fmt.Errorf("%s: %q isn't a valid type name for %s", n.flag, n.name, fn.Name)
+			}
+			if *flagDTO && !token.IsExported(n.name) {
+				return fmt.Errorf("%s: %s isn't exported, which the types of the dto package must be", n.flag, n.name)
+			}
+			if other, ok := types[n.name]; ok && !fn.Skip {
+				return fmt.Errorf("%s: %s names the types of both %s and %s, use {Method} in the pattern", n.flag, n.name, other, fn.Name)
+			}
+			if !fn.Skip {
+				types[n.name] = fn.Name
+			}
+		}
+		if caseOf == nil {
+			continue
+		}
+		for j := range fn.Params {
+			fn.Params[j].JSONName = caseOf(fn.Params[j])
+		}
+		for j := range fn.Res {
+			fn.Res[j].JSONName = caseOf(fn.Res[j])
+		}
+	}
+	return nil
+}
+
+// declRE matches the package-level funcs, types, vars and consts the
+// templates declare, after the template actions starting their line if
+// any, capturing their keyword and name, or "(" for the methods and the
+// declaration blocks.
+var declRE = regexp.MustCompile(`(?m)^(?:\{\{[^}]*\}\})*(func|type|var|const) ((?:\w|\{\{ ?\.Name ?\}\})+|\()`)
+
+// blockDeclRE matches the names declared in a var or const block, and
+// blockEndRE its closing parenthesis, after the template actions ending
+// the line before it if any.
+var (
+	blockDeclRE = regexp.MustCompile(`(?m)^\t(\w+)[ ,]`)
+	blockEndRE  = regexp.MustCompile(`(?m)^(?:\{\{[^}]*\}\})*\)`)
+)
+
+// templateDecls are the names the templates declare at the package level,
+// with {{ .Name }} standing for the name of a method, e.g. EncodeResponse or
+// Decode{{ .Name }}Request. The names made of other template actions, such
+// as those of the request types, are left out.
+var templateDecls = func() []string {
+	var names []string
+	for _, text := range templates {
+		for _, m := range declRE.FindAllStringSubmatchIndex(text, -1) {
+			keyword, name := text[m[2]:m[3]], text[m[4]:m[5]]
+			if name != "(" {
+				if name != "{{ .Name }}" && name != "{{.Name}}" {
+					names = append(names, strings.Replace(name, "{{.Name}}", "{{ .Name }}", -1))
+				}
+				continue
+			}
+			if keyword == "func" {
+				continue
+			}
+			block := text[m[1]:]
+			if end := blockEndRE.FindStringIndex(block); end != nil {
+				block = block[:end[0]]
+			}
+			for _, b := range blockDeclRE.FindAllStringSubmatch(block, -1) {
+				names = append(names, b[1])
+			}
+		}
+	}
+	return names
+}()
+
+// packageIdents returns the identifiers the templates may declare next to
+// the request and response types of fns.
+func packageIdents(fns []Func) map[string]bool {
+	idents := map[string]bool{}
+	for _, name := range templateDecls {
+		if !strings.Contains(name, "{{") {
+			idents[name] = true
+			continue
+		}
+		for _, fn := range fns {
+			idents[strings.Replace(name, "{{ .Name }}", fn.Name, -1)] = true
+		}
+	}
+	return idents
+}
+
+// methodPattern returns pattern with its {Method} and {method} placeholders
+// replaced by the name of method.
+func methodPattern(pattern, method string) string {
+	return strings.NewReplacer("{Method}", method, "{method}", lowerFirst(method)).Replace(pattern)
+}
+
+// lowerFirst returns name with its leading upper case letters lowered, up to
+// the start of the next word, so that "ID" becomes "id" and "URLPath"
+// becomes "urlPath".
+func lowerFirst(name string) string {
+	runes := []rune(name)
+	for i := range runes {
+		if i > 0 && i+1 < len(runes) && unicode.IsUpper(runes[i]) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// JSONField returns the name of the JSON field holding p, its Go name
+// unless renamed by -json-case.
+func (p Param) JSONField() string {
+	if p.JSONName != "" {
+		return p.JSONName
+	}
+	return p.Field
+}
+
+// Tag returns the struct tag of the request or response field holding p,
+// if it needs one.
+func (p Param) Tag() string {
+	if p.JSONName == "" && !p.Optional {
+		return ""
+	}
+	tag := p.JSONName
+	if p.Optional {
+		tag += ",omitempty"
+	}
+	return "`json:" + strconv.Quote(tag) + "`"
+}
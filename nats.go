@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// natsImports are the imports of the NATS transport, besides those of the
+// endpoints.
+var natsImports = map[string]string{
+	"context":                              "",
+	"encoding/json":                        "",
+	"github.com/go-kit/kit/endpoint":       "",
+	"github.com/go-kit/kit/transport/nats": "natstransport",
+	"github.com/nats-io/nats.go":           "nats",
+}
+
+// natsSubjectRE matches the NATS subjects a method can be served on:
+// dot-separated tokens without wildcards.
+var natsSubjectRE = regexp.MustCompile(`^[^.\s*>]+(\.[^.\s*>]+)*$`)
+
+// resolveNATS sets the NATS subjects of the methods of fns from their
+// "//kit:nats <subject>" annotations.
+func resolveNATS(fns []Func) error {
+	for i := range fns {
+		fn := &fns[i]
+		a, ok := fn.Annotation("nats")
+		if !ok {
+			continue
+		}
+		if len(a.Args) != 1 {
+			return fn.errorf(a, "kit:nats takes exactly one subject")
+		}
+		if !natsSubjectRE.MatchString(a.Args[0]) {
+			return fn.errorf(a, "kit:nats: %q isn't a valid subject, use dot-separated tokens without wildcards, e.g. users.get", a.Args[0])
+		}
+		fn.NATSSubject = a.Args[0]
+	}
+	return nil
+}
+
+// NATSSubject returns the subject f is served on over NATS: that of its
+// kit:nats annotation, or else made of the names of the interface and of
+// f, e.g. user-service.get-user.
+func (s Service) NATSSubject(f Func) string {
+	if f.NATSSubject != "" {
+		return f.NATSSubject
+	}
+	return kebabCase(s.IFaceName()) + "." + kebabCase(f.Name)
+}
+
+// checkNATS returns an error if two methods of s are served on the same
+// NATS subject, or if s takes the tenant from the HTTP request, which NATS
+// messages don't carry.
+func checkNATS(s Service) error {
+	if s.Tenant != "" {
+		return fmt.Errorf("-transports: nats can't be combined with -tenant, NATS messages don't carry the tenant")
+	}
+	methods := map[string]string{}
+	for _, f := range s.Funcs {
+		subject := s.NATSSubject(f)
+		if m, ok := methods[subject]; ok {
+			return fmt.Errorf("-transports: %s and %s are both served on the NATS subject %s, see kit:nats", m, f.Name, subject)
+		}
+		methods[subject] = f.Name
+	}
+	return nil
+}
+
+// NATSImports returns the imports of the NATS transport of s.
+func (s Service) NATSImports() map[string]string {
+	imps := map[string]string{}
+	for imp, alias := range s.Imports {
+		imps[imp] = alias
+	}
+	for imp, alias := range natsImports {
+		imps[imp] = alias
+	}
+	return imps
+}
+
+const natsTemplate = `
+{{ define "nats" }}
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package {{ .Pkg }}
+{{ $svc := . }}
+import ({{ range $imp, $alias := .NATSImports }}{{ $alias }} "{{ $imp }}"
+{{ end }}
+)
+
+// The subjects the methods of {{ .IFace }} are served on over NATS.
+const ({{ range .Funcs }}
+	{{ .Name }}NATSSubject = "{{ $svc.NATSSubject . }}"{{ end }}
+)
+{{ if .Hooks }}
+// NATSSubscriberOptions are passed to the subscriber of every method, e.g. to
+// add natstransport.SubscriberBefore functions. Set them at init time, before
+// NewNATSSubscribers is called.
+var NATSSubscriberOptions []natstransport.SubscriberOption
+{{ end }}
+// NewNATSSubscribers returns the NATS subscribers of the endpoints of svc, by
+// subject, answering the requests with the JSON of the response types of the
+// endpoints,{{ if .Compression }} compressed with the codec of the request,
+//{{ end }} or with {"err": "<message>"} if they fail.{{ if .DeadLetter }} They retry the requests
+// as set by MessageRetry, and publish those they dead-letter on nc, see
+// natsDeadLetter.{{ end }}
+func NewNATSSubscribers({{ if .DeadLetter }}nc *nats.Conn, {{ end }}svc {{ .IFace }}) map[string]*natstransport.Subscriber {
+	var options []natstransport.SubscriberOption{{ if .Compression }}
+	options = append(options, natstransport.SubscriberBefore(natsCodecToContext)){{ end }}{{ if .DeadLetter }}
+	options = append(options, natstransport.SubscriberBefore(natsMessageToContext)){{ end }}{{ if .Hooks }}
+	options = append(options, NATSSubscriberOptions...){{ end }}
+	return map[string]*natstransport.Subscriber{ {{ range .Funcs }}
+		{{ .Name }}NATSSubject: natstransport.NewSubscriber(
+			{{ if $svc.DeadLetter }}retryMessages("nats", "{{ .Name }}")({{ $svc.Endpoint . }}){{ else }}{{ $svc.Endpoint . }}{{ end }},
+			DecodeNATS{{ .Name }}Request,
+			{{ if $svc.Compression }}encodeNATSResponse{{ else }}natstransport.EncodeJSONResponse{{ end }},
+			{{ if $svc.DeadLetter }}append([]natstransport.SubscriberOption{natstransport.SubscriberErrorHandler(natsDeadLetter{nc, "{{ .Name }}"})}, options...)...{{ else }}options...{{ end }},
+		),{{ end }}
+	}
+}
+
+// SubscribeNATS subscribes the subscribers of NewNATSSubscribers({{ if .DeadLetter }}nc, {{ end }}svc) to
+// their subjects on nc, in the queue group queue if not empty, for the
+// instances of the service to share the requests. Drain nc, or unsubscribe
+// the subscriptions, to stop serving.
+func SubscribeNATS(nc *nats.Conn, queue string, svc {{ .IFace }}) ([]*nats.Subscription, error) {
+	var subs []*nats.Subscription
+	for subject, s := range NewNATSSubscribers({{ if .DeadLetter }}nc, {{ end }}svc) {
+		sub, err := nc.QueueSubscribe(subject, queue, s.ServeMsg(nc))
+		if err != nil {
+			for _, sub := range subs {
+				sub.Unsubscribe()
+			}
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+{{ if .Compression }}
+// natsCodecToContext puts the codec a request is compressed with in its
+// context, for encodeNATSResponse to answer with.
+func natsCodecToContext(ctx context.Context, msg *nats.Msg) context.Context {
+	return context.WithValue(ctx, codecContextKey, payloadCodec(msg.Data))
+}
+
+// encodeNATSResponse publishes the JSON of a response to reply, compressed
+// with the codec of the request.
+func encodeNATSResponse(ctx context.Context, reply string, nc *nats.Conn, response interface{}) error {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	codec, _ := ctx.Value(codecContextKey).(string)
+	if data, err = compress(codec, data); err != nil {
+		return err
+	}
+	return nc.Publish(reply, data)
+}
+{{ end }}{{ if .DeadLetter }}
+// natsMessageToContext puts the state of a request in its context, for
+// retryMessages to record how its endpoint did and natsDeadLetter to
+// dead-letter it.
+func natsMessageToContext(ctx context.Context, msg *nats.Msg) context.Context {
+	return context.WithValue(ctx, messageContextKey, &message{msg: msg})
+}
+
+// natsDeadLetter is the error handler of the NATS subscriber of method. It
+// publishes the requests dead-lettered by deadLetterReason on nc to the
+// dead-letter subject of their subject, <subject>.dlq, as they were
+// received, counting them in DeadLetterCount.{{ if .Hooks }} NATSSubscriberOptions can
+// replace it.{{ end }}
+type natsDeadLetter struct {
+	nc     *nats.Conn
+	method string
+}
+
+func (h natsDeadLetter) Handle(ctx context.Context, err error) {
+	reason := deadLetterReason(ctx, err)
+	if reason == "" {
+		return
+	}
+	msg := ctx.Value(messageContextKey).(*message).msg
+	if h.nc.Publish(msg.Subject+".dlq", msg.Data) == nil {
+		DeadLetterCount.With("transport", "nats", "method", h.method, "reason", reason).Add(1)
+	}
+}
+{{ end }}{{ range .Funcs }}
+// DecodeNATS{{ .Name }}Request decodes the JSON {{ $svc.Request . }} of a request to
+// {{ .Name }}NATSSubject, {{ if $svc.Compression }}decompressing it first if it is compressed{{ else }}as encoded by natstransport.EncodeJSONRequest{{ end }}.
+func DecodeNATS{{ .Name }}Request(_ context.Context, msg *nats.Msg) (interface{}, error) {
+	var request {{ $svc.Request . }}{{ if $svc.Compression }}
+	data, err := decompress(msg.Data)
+	if err != nil {
+		return nil, err
+	}{{ end }}
+	if len({{ if $svc.Compression }}data{{ else }}msg.Data{{ end }}) > 0 {
+		if err := json.Unmarshal({{ if $svc.Compression }}data{{ else }}msg.Data{{ end }}, &request); err != nil {
+			return nil, err
+		}
+	}{{ if .RequestType }}
+	if v, ok := interface{}(request).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}{{ else if HasConstraints . }}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}{{ end }}
+	return request, nil
+}
+{{ end }}{{ end }}
+`
+
+const natsClientTemplate = `
+{{ define "natsclient" }}
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package {{ .Pkg }}
+{{ $svc := . }}
+import ({{ range $imp, $alias := .NATSImports }}{{ $alias }} "{{ $imp }}"
+{{ end }}
+)
+{{ if .Compression }}
+// encodeNATSRequest encodes the JSON of a request into the data of msg,
+// compressed with NATSCompression.
+func encodeNATSRequest(_ context.Context, msg *nats.Msg, request interface{}) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	msg.Data, err = compress(NATSCompression, data)
+	return err
+}
+{{ end }}{{ range .Funcs }}
+// {{ .Name }}NATSClient returns an endpoint calling {{ .Name }} with a request to
+// {{ .Name }}NATSSubject on nc{{ if .Budget }}, timing out after {{ .Name }}Budget{{ end }}.
+func {{ .Name }}NATSClient(nc *nats.Conn, options ...natstransport.PublisherOption) endpoint.Endpoint {
+	return natstransport.NewPublisher(
+		nc,
+		{{ .Name }}NATSSubject,
+		{{ if $svc.Compression }}encodeNATSRequest{{ else }}natstransport.EncodeJSONRequest{{ end }},
+		DecodeNATS{{ .Name }}Response,{{ if .Budget }}
+		append([]natstransport.PublisherOption{natstransport.PublisherTimeout({{ .Name }}Budget)}, options...)...,{{ else }}
+		options...,{{ end }}
+	).Endpoint()
+}
+
+// DecodeNATS{{ .Name }}Response decodes the JSON {{ $svc.Response . }} of a reply from
+// {{ .Name }}NATSSubject, or the error it answers with into a *NATSError.
+func DecodeNATS{{ .Name }}Response(_ context.Context, msg *nats.Msg) (interface{}, error) {
+	if err := decodeNATSError(msg); err != nil {
+		return nil, err
+	}{{ if $svc.Compression }}
+	data, err := decompress(msg.Data)
+	if err != nil {
+		return nil, err
+	}{{ end }}
+	var response {{ $svc.Response . }}
+	if err := json.Unmarshal({{ if $svc.Compression }}data{{ else }}msg.Data{{ end }}, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+{{ end }}
+// NATSError is the error a NATS subscriber answered a request with.
+type NATSError struct {
+	Message string
+}
+
+func (e *NATSError) Error() string { return e.Message }
+
+// decodeNATSError returns the *NATSError of a reply holding one, encoded by
+// natstransport.DefaultErrorEncoder as {"err": "<message>"}, or nil.
+func decodeNATSError(msg *nats.Msg) error {
+	var reply map[string]json.RawMessage
+	if json.Unmarshal(msg.Data, &reply) != nil || len(reply) != 1 {
+		return nil
+	}
+	var message string
+	if raw, ok := reply["err"]; !ok || json.Unmarshal(raw, &message) != nil {
+		return nil
+	}
+	return &NATSError{Message: message}
+}
+{{ if .EndpointSet }}
+// NewNATSClient returns the Endpoints calling the NATS subscribers of
+// {{ .IFace }} with requests on nc, a client implementing {{ .IFace }}.
+func NewNATSClient(nc *nats.Conn, options ...natstransport.PublisherOption) Endpoints {
+	return Endpoints{ {{ range .Funcs }}
+		{{ .Name }}Endpoint: {{ $svc.ClientEndpoint . (printf "%sNATSClient(nc, options...)" .Name) }},{{ end }}
+	}
+}
+{{ end }}{{ end }}
+`
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// nilResultStatus are the HTTP statuses of the responses to a nil
+// pointer result, by policy. The null policy encodes the response as usual.
+var nilResultStatus = map[string]string{
+	"null":       "",
+	"not-found":  "404 Not Found",
+	"no-content": "204 No Content",
+}
+
+// resolveNilResults sets how every method of fns with a single pointer
+// result responds when the result is nil without an error, from its
+// "//kit:nil <policy>" annotation, or to def if it has none.
+func resolveNilResults(fns []Func, def string) error {
+	if _, ok := nilResultStatus[def]; !ok && def != "" {
+		return fmt.Errorf("-nil-result: unknown policy %q, want null, not-found or no-content", def)
+	}
+	for i := range fns {
+		fn := &fns[i]
+		a, ok := fn.Annotation("nil")
+		if !ok {
+			if fn.NilResultParam() != nil && def != "null" {
+				fn.NilResult = def
+			}
+			continue
+		}
+		if len(a.Args) != 1 {
+			return fn.errorf(a, "kit:nil takes exactly one policy")
+		}
+		if _, ok := nilResultStatus[a.Args[0]]; !ok {
+			return fn.errorf(a, "kit:nil: unknown policy %q, want null, not-found or no-content", a.Args[0])
+		}
+		if fn.NilResultParam() == nil {
+			return fn.errorf(a, "kit:nil requires a single non-error result of a pointer type")
+		}
+		if a.Args[0] != "null" {
+			fn.NilResult = a.Args[0]
+		}
+	}
+	return nil
+}
+
+// NilResultParam returns the result of f that may be nil, if f has a single
+// non-error result of a pointer type.
+func (f Func) NilResultParam() *Param {
+	res := FilterError(f.Res)
+	if len(res) != 1 || !strings.HasPrefix(res[0].Type, "*") {
+		return nil
+	}
+	return &res[0]
+}
+
+// NilResultStatus returns the status code f responds with when its result
+// is nil, or "" if it encodes the response as usual.
+func (f Func) NilResultStatus() string {
+	return nilResultStatus[f.NilResult]
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// genOpenAPI returns the OpenAPI 3 spec of the HTTP routes of svc, in YAML.
+func genOpenAPI(svc Service, spec *Spec) ([]byte, error) {
+	var paths yaml.MapSlice
+	pathIndex := map[string]int{}
+	for _, f := range svc.Funcs {
+		op := yaml.MapSlice{{Key: "operationId", Value: f.Name}}
+		if f.Doc != "" {
+			op = append(op, yaml.MapItem{Key: "summary", Value: f.Summary()})
+			if f.Summary() != strings.Join(strings.Fields(f.Doc), " ") {
+				op = append(op, yaml.MapItem{Key: "description", Value: f.Doc})
+			}
+		}
+		if f.Group != "" {
+			op = append(op, yaml.MapItem{Key: "tags", Value: []string{f.Group}})
+		}
+		if f.HasBody() {
+			op = append(op, yaml.MapItem{Key: "requestBody", Value: yaml.MapSlice{
+				{Key: "required", Value: true},
+				{Key: "content", Value: withExample(jsonContent(f.RequestName), f.RequestExample)},
+			}})
+		}
+		content, example := responseContent(f, spec), f.ResponseExample
+		if svc.Envelope {
+			content, example = envelopeContent(content, example)
+		}
+		responses := yaml.MapSlice{
+			{Key: "200", Value: yaml.MapSlice{
+				{Key: "description", Value: "OK"},
+				{Key: "content", Value: withExample(content, example)},
+			}},
+		}
+		switch f.NilResult {
+		case "not-found":
+			responses = append(responses, yaml.MapItem{Key: "404", Value: yaml.MapSlice{{Key: "description", Value: "The service returned no " + f.NilResultParam().Name + "."}}})
+		case "no-content":
+			responses = append(responses, yaml.MapItem{Key: "204", Value: yaml.MapSlice{{Key: "description", Value: "The service returned no " + f.NilResultParam().Name + "."}}})
+		}
+		if HasConstraints(f) {
+			responses = append(responses, yaml.MapItem{Key: "400", Value: yaml.MapSlice{{Key: "description", Value: "The request violates the constraints of a field."}}})
+		}
+		if f.IfMatch != nil {
+			responses = append(responses, yaml.MapItem{Key: "412", Value: yaml.MapSlice{{Key: "description", Value: "The If-Match header does not match the current ETag."}}})
+		}
+		if len(f.Scopes) > 0 {
+			responses = append(responses,
+				yaml.MapItem{Key: "401", Value: yaml.MapSlice{{Key: "description", Value: "The request carries no token."}}},
+				yaml.MapItem{Key: "403", Value: yaml.MapSlice{{Key: "description", Value: insufficientScope(f)}}},
+			)
+		}
+		if HasError(f) {
+			responses = append(responses, yaml.MapItem{Key: "default", Value: yaml.MapSlice{{Key: "description", Value: "The error returned by the service."}}})
+		}
+		if svc.Envelope {
+			for i, r := range responses {
+				if r.Key != "200" && r.Key != "204" {
+					responses[i].Value = append(r.Value.(yaml.MapSlice), yaml.MapItem{Key: "content", Value: envelopeError})
+				}
+			}
+		}
+		op = append(op, yaml.MapItem{Key: "responses", Value: responses})
+		if len(f.Scopes) > 0 {
+			op = append(op, yaml.MapItem{Key: "security", Value: []yaml.MapSlice{securityRequirement(f)}})
+		}
+		var params []yaml.MapSlice
+		if svc.Tenant == "path" {
+			params = append(params, yaml.MapSlice{
+				{Key: "name", Value: "tenant"},
+				{Key: "in", Value: "path"},
+				{Key: "required", Value: true},
+				{Key: "schema", Value: yaml.MapSlice{{Key: "type", Value: "string"}}},
+			})
+		}
+		params = append(params, routeParameters(f, spec)...)
+		if len(params) > 0 {
+			op = append(op, yaml.MapItem{Key: "parameters", Value: params})
+		}
+		// kit:http routes may share their path with another method
+		item := yaml.MapItem{Key: strings.ToLower(f.HTTPMethod), Value: op}
+		if i, ok := pathIndex[svc.Route(f)]; ok {
+			paths[i].Value = append(paths[i].Value.(yaml.MapSlice), item)
+		} else {
+			pathIndex[svc.Route(f)] = len(paths)
+			paths = append(paths, yaml.MapItem{Key: svc.Route(f), Value: yaml.MapSlice{item}})
+		}
+	}
+
+	var schemas yaml.MapSlice
+	for _, c := range spec.Components {
+		if c.Unwrapped {
+			continue
+		}
+		var props yaml.MapSlice
+		var required []string
+		for _, p := range c.Properties {
+			props = append(props, yaml.MapItem{Key: p.Name, Value: openAPISchema(p.Schema)})
+			if !p.Optional {
+				required = append(required, p.Name)
+			}
+		}
+		schema := yaml.MapSlice{{Key: "type", Value: "object"}}
+		if len(props) > 0 {
+			schema = append(schema, yaml.MapItem{Key: "properties", Value: props})
+		}
+		if len(required) > 0 {
+			schema = append(schema, yaml.MapItem{Key: "required", Value: required})
+		}
+		if c.AllOf != "" {
+			schema = yaml.MapSlice{{Key: "allOf", Value: []yaml.MapSlice{
+				{{Key: "$ref", Value: "#/components/schemas/" + c.AllOf}},
+				schema,
+			}}}
+		}
+		if len(c.OneOf) > 0 {
+			var oneOf []yaml.MapSlice
+			var mapping yaml.MapSlice
+			for _, v := range c.OneOf {
+				oneOf = append(oneOf, openAPISchema(v.Schema))
+				mapping = append(mapping, yaml.MapItem{Key: v.Name, Value: "#/components/schemas/" + v.Schema.Ref})
+			}
+			schema = yaml.MapSlice{
+				{Key: "oneOf", Value: oneOf},
+				{Key: "discriminator", Value: yaml.MapSlice{{Key: "propertyName", Value: c.Discriminator}, {Key: "mapping", Value: mapping}}},
+			}
+		}
+		schemas = append(schemas, yaml.MapItem{Key: c.Name, Value: schema})
+	}
+
+	components := yaml.MapSlice{{Key: "schemas", Value: schemas}}
+	if svc.UsesScopes() {
+		components = append(components, yaml.MapItem{Key: "securitySchemes", Value: securitySchemes(svc)})
+	}
+	doc := yaml.MapSlice{
+		{Key: "openapi", Value: "3.0.3"},
+		{Key: "info", Value: yaml.MapSlice{
+			{Key: "title", Value: svc.IFaceName()},
+			{Key: "version", Value: "1.0.0"},
+		}},
+		{Key: "paths", Value: paths},
+		{Key: "components", Value: components},
+	}
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte("# Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.\n"), data...), nil
+}
+
+// authSchemes are the security schemes of -auth-scheme other than oauth2,
+// which lists the scopes it grants. The token of apikey is sent in the
+// same X-API-Key header -ratelimit apikey identifies clients by.
+var authSchemes = map[string]yaml.MapItem{
+	"jwt": {Key: "bearerAuth", Value: yaml.MapSlice{
+		{Key: "type", Value: "http"},
+		{Key: "scheme", Value: "bearer"},
+		{Key: "bearerFormat", Value: "JWT"},
+	}},
+	"apikey": {Key: "apiKey", Value: yaml.MapSlice{
+		{Key: "type", Value: "apiKey"},
+		{Key: "in", Value: "header"},
+		{Key: "name", Value: "X-API-Key"},
+	}},
+	"basic": {Key: "basicAuth", Value: yaml.MapSlice{
+		{Key: "type", Value: "http"},
+		{Key: "scheme", Value: "basic"},
+	}},
+}
+
+// securityRequirement returns the security requirement of the operation of
+// f. Only OAuth2 requirements list scopes in OpenAPI 3.0, so with the other
+// schemes they are listed by the description of its 403 response instead.
+func securityRequirement(f Func) yaml.MapSlice {
+	if scheme, ok := authSchemes[*flagAuthScheme]; ok {
+		return yaml.MapSlice{{Key: scheme.Key, Value: []string{}}}
+	}
+	return yaml.MapSlice{{Key: "oauth2", Value: f.Scopes}}
+}
+
+// insufficientScope returns the description of the 403 response of the
+// operation of f.
+func insufficientScope(f Func) string {
+	if _, ok := authSchemes[*flagAuthScheme]; ok {
+		return "The token lacks one of the scopes " + strings.Join(f.Scopes, ", ") + "."
+	}
+	return "The token lacks a required scope."
+}
+
+// securitySchemes returns the security scheme of -auth-scheme. The OAuth2
+// one lists the scopes of the methods of svc, granted by the client
+// credentials flow of the token endpoint of -token-url.
+func securitySchemes(svc Service) yaml.MapSlice {
+	if scheme, ok := authSchemes[*flagAuthScheme]; ok {
+		return yaml.MapSlice{scheme}
+	}
+	var scopes yaml.MapSlice
+	for _, s := range svc.Scopes() {
+		scopes = append(scopes, yaml.MapItem{Key: s.Value, Value: s.Description()})
+	}
+	return yaml.MapSlice{{Key: "oauth2", Value: yaml.MapSlice{
+		{Key: "type", Value: "oauth2"},
+		{Key: "flows", Value: yaml.MapSlice{{Key: "clientCredentials", Value: yaml.MapSlice{
+			{Key: "tokenUrl", Value: *flagTokenURL},
+			{Key: "scopes", Value: scopes},
+		}}}},
+	}}}
+}
+
+// withExample adds example, the JSON of an example of -examples, to the
+// media type of content, unless empty.
+func withExample(content yaml.MapSlice, example string) yaml.MapSlice {
+	if example == "" {
+		return content
+	}
+	media := content[0].Value.(yaml.MapSlice)
+	content[0].Value = append(media, yaml.MapItem{Key: "example", Value: decodeExample(example)})
+	return content
+}
+
+func jsonContent(component string) yaml.MapSlice {
+	return yaml.MapSlice{{Key: "application/json", Value: yaml.MapSlice{
+		{Key: "schema", Value: yaml.MapSlice{{Key: "$ref", Value: "#/components/schemas/" + component}}},
+	}}}
+}
+
+// routeParameters returns the path variables and query parameters of the
+// kit:http route of f, with the schemas of their request properties and
+// their values in its example request, if any.
+func routeParameters(f Func, spec *Spec) []yaml.MapSlice {
+	schema := func(p Param) yaml.MapSlice {
+		if req := spec.byName[f.RequestName]; req != nil {
+			for _, prop := range req.Properties {
+				if prop.Name == p.JSONField() {
+					return openAPISchema(prop.Schema)
+				}
+			}
+		}
+		return yaml.MapSlice{{Key: "type", Value: "string"}}
+	}
+	example, _ := decodeExample(f.RequestExample).(yaml.MapSlice)
+	withExample := func(param yaml.MapSlice, p Param) yaml.MapSlice {
+		if v := lookupExample(example, p.JSONField()); v != nil {
+			param = append(param, yaml.MapItem{Key: "example", Value: v})
+		}
+		return param
+	}
+	var params []yaml.MapSlice
+	for _, p := range f.Params {
+		if p.PathVar {
+			params = append(params, withExample(yaml.MapSlice{
+				{Key: "name", Value: p.Name},
+				{Key: "in", Value: "path"},
+				{Key: "required", Value: true},
+				{Key: "schema", Value: schema(p)},
+			}, p))
+		}
+	}
+	for _, p := range f.QueryParams() {
+		params = append(params, withExample(yaml.MapSlice{
+			{Key: "name", Value: p.JSONField()},
+			{Key: "in", Value: "query"},
+			{Key: "required", Value: !p.Optional},
+			{Key: "schema", Value: schema(p)},
+		}, p))
+	}
+	return params
+}
+
+// responseContent returns the content of the successful responses of f: the
+// schema of its result if it's unwrapped, a binary body for a []byte one.
+func responseContent(f Func, spec *Spec) yaml.MapSlice {
+	if !f.Unwrap {
+		return jsonContent(f.ResponseName)
+	}
+	if f.RawBytes() {
+		return yaml.MapSlice{{Key: "application/octet-stream", Value: yaml.MapSlice{
+			{Key: "schema", Value: yaml.MapSlice{{Key: "type", Value: "string"}, {Key: "format", Value: "binary"}}},
+		}}}
+	}
+	return yaml.MapSlice{{Key: "application/json", Value: yaml.MapSlice{
+		{Key: "schema", Value: openAPISchema(spec.byName[f.ResponseName].Properties[0].Schema)},
+	}}}
+}
+
+// openAPISchema returns the OpenAPI schema object of s.
+func openAPISchema(s *Schema) yaml.MapSlice {
+	var schema yaml.MapSlice
+	set := func(key string, value interface{}) {
+		schema = append(schema, yaml.MapItem{Key: key, Value: value})
+	}
+	if s.Ref != "" {
+		ref := yaml.MapSlice{{Key: "$ref", Value: "#/components/schemas/" + s.Ref}}
+		if !s.Nullable {
+			return ref
+		}
+		// siblings of $ref are ignored, so nullable needs a wrapper
+		set("allOf", []yaml.MapSlice{ref})
+		set("nullable", true)
+		return schema
+	}
+	if s.Type != "" {
+		set("type", s.Type)
+	}
+	if s.Format != "" {
+		set("format", s.Format)
+	}
+	if s.Items != nil {
+		if s.Type == "array" {
+			set("items", openAPISchema(s.Items))
+		} else {
+			set("additionalProperties", openAPISchema(s.Items))
+		}
+	}
+	if s.Nullable {
+		set("nullable", true)
+	}
+	var enum []interface{}
+	for _, v := range s.Enum {
+		enum = append(enum, literalValue(v))
+	}
+	if c := s.Constraints; c != nil {
+		if c.Minimum != nil {
+			set("minimum", *c.Minimum)
+			if c.ExclusiveMinimum {
+				set("exclusiveMinimum", true)
+			}
+		}
+		if c.Maximum != nil {
+			set("maximum", *c.Maximum)
+			if c.ExclusiveMaximum {
+				set("exclusiveMaximum", true)
+			}
+		}
+		if c.MinLength != nil {
+			set("minLength", *c.MinLength)
+		}
+		if c.MaxLength != nil {
+			set("maxLength", *c.MaxLength)
+		}
+		if c.MinItems != nil {
+			set("minItems", *c.MinItems)
+		}
+		if c.MaxItems != nil {
+			set("maxItems", *c.MaxItems)
+		}
+		if c.Pattern != "" {
+			set("pattern", c.Pattern)
+		}
+		if len(enum) == 0 {
+			enum = c.Enum
+		}
+	}
+	if len(enum) > 0 {
+		set("enum", enum)
+	}
+	if schema == nil {
+		// any value
+		return yaml.MapSlice{}
+	}
+	return schema
+}
+
+// literalValue returns the value of the Go literal v.
+func literalValue(v string) interface{} {
+	if s, err := strconv.Unquote(v); err == nil {
+		return s
+	}
+	if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f
+	}
+	return v
+}
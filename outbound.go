@@ -0,0 +1,254 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// dispatcherImports are the imports required by the webhook dispatcher.
+var dispatcherImports = []string{"bytes", "context", "crypto/hmac", "crypto/sha256", "encoding/hex", "encoding/json", "fmt", "io", "io/ioutil", "net/http", "strconv", "strings", "sync", "time"}
+
+// outboundFlags are the flags that apply to outbound interfaces, whose
+// package holds a webhook dispatcher instead of a server transport.
+var outboundFlags = map[string]bool{"dto": true, "mock": true, "assertions": true}
+
+// interfaceAnnotations returns the annotations of the doc comment of the
+// declaration of iface, such as "//kit:outbound".
+func interfaceAnnotations(iface, srcDir string) ([]Annotation, error) {
+	path, id, err := findInterface(iface, srcDir)
+	if err != nil {
+		return nil, err
+	}
+	_, spec, err := typeSpec(path, id, srcDir)
+	if err != nil {
+		return nil, fmt.Errorf("interface %s not found: %s", iface, err)
+	}
+	return parseAnnotations(spec.Doc), nil
+}
+
+// checkOutbound returns an error if a flag adding server features is set
+// or a method of fns returns more than an error, for the outbound
+// interface iface.
+func checkOutbound(iface string, fns []Func) error {
+	var err error
+	flag.Visit(func(f *flag.Flag) {
+		if err == nil && !minimalFlags[f.Name] && !outboundFlags[f.Name] {
+			err = fmt.Errorf("-%s doesn't apply to the outbound interface %s", f.Name, iface)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for _, fn := range fns {
+		if fn.Skip {
+			continue
+		}
+		if len(fn.Res) != 1 || fn.Res[0].Type != "error" {
+			return errorAt(fn.src.FileSet, fn.Pos, "%s: methods of outbound interfaces return only an error", fn.Name)
+		}
+		for _, p := range fn.Params {
+			if IsOptionSetter(p.Type) {
+				return errorAt(fn.src.FileSet, fn.Pos, "%s: methods of outbound interfaces can't take option setters", fn.Name)
+			}
+		}
+	}
+	return nil
+}
+
+const dispatcherTemplate = `
+{{ define "dispatcher" }}
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package {{ .Pkg }}
+{{ $svc := . }}
+import ({{ range $imp, $alias := .Imports }}{{ $alias }} "{{ $imp }}"
+{{ end }}
+)
+{{ range .Funcs }}{{ if not $svc.DTO }}{{ template "types" . }}{{ end }}{{ end }}
+// Events are the names of the webhooks of {{ .IFace }}, sent in the
+// X-Webhook-Event header.
+const ({{ range .Funcs }}
+	{{ .Name }}Event = "{{ .Name }}"{{ end }}
+)
+
+// Subscriber is a consumer of the webhooks of {{ .IFace }}.
+type Subscriber struct {
+	URL        string
+	Secret     string        // key of the HMAC-SHA256 X-Webhook-Signature header, unsigned if empty
+	Events     []string      // webhooks sent to the subscriber, all if empty
+	Header     http.Header   // added to every request, e.g. for authentication
+	MaxRetries int           // retries of failed deliveries, DefaultMaxRetries if 0 and none if negative
+	Timeout    time.Duration // of every delivery attempt, DefaultTimeout if 0
+}
+
+// Defaults of the delivery of webhooks.
+var (
+	DefaultMaxRetries = 3
+	DefaultTimeout    = 10 * time.Second
+	DefaultBackoff    = 500 * time.Millisecond
+)
+
+// Dispatcher implements {{ .IFace }} by sending every call as a webhook
+// to the subscribers of its event, in parallel. A call returns once every
+// delivery succeeded or gave up, with a *DeliveryError if any gave up.
+type Dispatcher struct {
+	Subscribers []Subscriber
+	Client      *http.Client  // http.DefaultClient if nil
+	Backoff     time.Duration // delay before the first retry, doubled for every other; DefaultBackoff if 0
+}
+
+{{ if not .Assertions }}
+var _ {{ .IFace }} = (*Dispatcher)(nil)
+{{ end }}
+// NewDispatcher returns a Dispatcher sending webhooks to subscribers.
+func NewDispatcher(subscribers ...Subscriber) *Dispatcher {
+	return &Dispatcher{Subscribers: subscribers}
+}
+{{ range .AllFuncs }}{{ if .Skip }}
+// {{ .Name }} isn't sent as a webhook.
+func (d *Dispatcher) {{ .Name }}{{ Signature . }} {
+	return
+}
+{{ else }}
+// {{ .Name }} sends a {{ .Name }}Event webhook with the JSON encoded {{ $svc.Request . }}.
+func (d *Dispatcher) {{ .Name }}{{ Signature . }} {
+	return d.dispatch({{ ContextArg . }}, {{ .Name }}Event, {{ $svc.Request . }}{ {{ range .Params }}{{ if ne .Type "context.Context" }}
+		{{ .Field }}: {{ .VarName }},{{ end }}{{ end }}
+	})
+}
+{{ end }}{{ end }}
+// DeliveryError reports the subscribers a webhook couldn't be delivered to.
+type DeliveryError struct {
+	Event  string
+	Failed map[string]error // by subscriber URL
+}
+
+func (e *DeliveryError) Error() string {
+	var msgs []string
+	for url, err := range e.Failed {
+		msgs = append(msgs, url+": "+err.Error())
+	}
+	return fmt.Sprintf("%s webhook not delivered to %d subscriber(s): %s", e.Event, len(e.Failed), strings.Join(msgs, "; "))
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, event string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed = map[string]error{}
+	)
+	for _, s := range d.Subscribers {
+		if !s.wants(event) {
+			continue
+		}
+		wg.Add(1)
+		go func(s Subscriber) {
+			defer wg.Done()
+			if err := d.deliver(ctx, s, event, body); err != nil {
+				mu.Lock()
+				failed[s.URL] = err
+				mu.Unlock()
+			}
+		}(s)
+	}
+	wg.Wait()
+	if len(failed) > 0 {
+		return &DeliveryError{Event: event, Failed: failed}
+	}
+	return nil
+}
+
+func (s Subscriber) wants(event string) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver posts body to s, retrying with exponential backoff on network
+// errors, 429 and 5xx responses until it succeeds, runs out of retries or
+// ctx is done.
+func (d *Dispatcher) deliver(ctx context.Context, s Subscriber, event string, body []byte) error {
+	retries := s.MaxRetries
+	if retries == 0 {
+		retries = DefaultMaxRetries
+	}
+	backoff := d.Backoff
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		retry, err := d.send(ctx, s, event, body)
+		if err == nil || !retry || attempt >= retries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff << uint(attempt)):
+		}
+	}
+}
+
+// send makes a single delivery attempt, reporting whether a failure is
+// worth retrying.
+func (d *Dispatcher) send(ctx context.Context, s Subscriber, event string, body []byte) (retry bool, err error) {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequest("POST", s.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range s.Header {
+		req.Header[k] = v
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", event)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	if s.Secret != "" {
+		req.Header.Set("X-Webhook-Signature", "sha256="+WebhookSignature(s.Secret, timestamp, body))
+	}
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, fmt.Errorf("%s", resp.Status)
+}
+
+// WebhookSignature returns the hex encoded HMAC-SHA256 of the timestamp and
+// body of a webhook, joined by a dot, keyed by secret. Subscribers verify
+// the X-Webhook-Signature header against it, with hmac.Equal, and reject
+// stale timestamps to prevent replays.
+func WebhookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+{{ end }}
+`
@@ -0,0 +1,166 @@
+package main
+
+// outboxImports are the imports required by the outbox code.
+var outboxImports = []string{"context", "crypto/rand", "encoding/hex", "encoding/json", "time"}
+
+// resolveEvents sets the domain event of every method of fns from its
+// "//kit:event <Name>" annotation.
+func resolveEvents(fns []Func) error {
+	emittedBy := map[string]string{}
+	for i := range fns {
+		fn := &fns[i]
+		a, ok := fn.Annotation("event")
+		if !ok {
+			continue
+		}
+		if len(a.Args) != 1 {
+			return fn.errorf(a, "kit:event takes exactly one event name")
+		}
+		if !HasError(*fn) {
+			return fn.errorf(a, "kit:event requires the method to return an error")
+		}
+		if other, ok := emittedBy[a.Args[0]]; ok {
+			return fn.errorf(a, "kit:event %s is already emitted by %s", a.Args[0], other)
+		}
+		emittedBy[a.Args[0]] = fn.Name
+		fn.Event = a.Args[0]
+	}
+	return nil
+}
+
+const outboxTemplate = `
+{{ define "outbox" }}
+// Event is the envelope of a domain event stored in the outbox.
+type Event struct {
+	ID         string          ` + "`json:\"id\"`" + `
+	Type       string          ` + "`json:\"type\"`" + `
+	OccurredAt time.Time       ` + "`json:\"occurredAt\"`" + `
+	Payload    json.RawMessage ` + "`json:\"payload\"`" + `
+}
+
+// Types of the events emitted by the service.
+const ({{ range .Funcs }}{{ if .Event }}
+	{{ .Event }}Event = "{{ .Event }}"{{ end }}{{ end }}
+)
+{{ range $fun := .Funcs }}{{ if .Event }}
+// {{ .Event }}Payload is the payload of {{ .Event }} events, emitted by {{ .Name }}.
+type {{ .Event }}Payload struct { {{ range $i, $r := .Res }}{{ if ne $r.Type "error" }}
+	{{ Exported (ResultKey $fun $i) }} {{ $r.Type }} ` + "`json:\"{{ ResultKey $fun $i }}\"`" + `{{ end }}{{ end }}
+}
+{{ end }}{{ end }}
+// OutboxWriter stores events in the outbox. Implementations should store them
+// in the transaction of the state change that caused them, e.g. by taking
+// the transaction from ctx, so that events are stored if and only if the
+// change is committed.
+type OutboxWriter interface {
+	Write(ctx context.Context, events ...Event) error
+}
+
+// OutboxStore is the outbox the relay publishes events from.
+type OutboxStore interface {
+	OutboxWriter
+	// Pending returns at most limit unpublished events in the order they were written.
+	Pending(ctx context.Context, limit int) ([]Event, error)
+	// MarkPublished marks the events with the given IDs as published.
+	MarkPublished(ctx context.Context, ids ...string) error
+}
+
+// Publisher publishes events to a message broker.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// OutboxMiddleware returns a service middleware writing the domain events of
+// successful calls to w.
+func OutboxMiddleware(w OutboxWriter) func({{ .IFace }}) {{ .IFace }} {
+	return func(next {{ .IFace }}) {{ .IFace }} {
+		return outboxMiddleware{next, w}
+	}
+}
+
+type outboxMiddleware struct {
+	{{ .IFace }}
+	w OutboxWriter
+}
+{{ range $fun := .Funcs }}{{ if .Event }}
+func (m outboxMiddleware) {{ .Name }}{{ Signature . }} {
+	{{ range $i, $r := .Res }}{{ if $i }}, {{ end }}{{ ResultName $fun $i }}{{ end }} = m.{{ $.IFaceName }}.{{ .Name }}({{ CallArgs . }})
+	if {{ ErrorName . }} != nil {
+		return
+	}
+	{{ ErrorName . }} = m.write({{ ContextArg . }}, {{ .Event }}Event, {{ .Event }}Payload{ {{ range $i, $r := .Res }}{{ if ne $r.Type "error" }}
+		{{ Exported (ResultKey $fun $i) }}: {{ ResultName $fun $i }},{{ end }}{{ end }}
+	})
+	return
+}
+{{ end }}{{ end }}
+func (m outboxMiddleware) write(ctx context.Context, typ string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	return m.w.Write(ctx, Event{
+		ID:         hex.EncodeToString(id),
+		Type:       typ,
+		OccurredAt: time.Now().UTC(),
+		Payload:    data,
+	})
+}
+
+// OutboxRelay publishes the pending events of an outbox in order.
+type OutboxRelay struct {
+	Store     OutboxStore
+	Publisher Publisher
+	Interval  time.Duration // time between polls of the outbox, defaults to a second
+	BatchSize int           // maximum number of events published per poll, defaults to 100
+	OnError   func(error)   // called with the errors of failed polls, if set
+}
+
+// Run publishes pending events every Interval until ctx is done.
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.RelayOnce(ctx); err != nil && r.OnError != nil {
+			r.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayOnce publishes a batch of pending events, marking each event as
+// published after publishing it. It stops at the first failure, so events
+// are published in order and at least once.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) error {
+	limit := r.BatchSize
+	if limit <= 0 {
+		limit = 100
+	}
+	events, err := r.Store.Pending(ctx, limit)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		if err := r.Publisher.Publish(ctx, e); err != nil {
+			return err
+		}
+		if err := r.Store.MarkPublished(ctx, e.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+{{ end }}
+`
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/build"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// File is a generated file.
+type File struct {
+	Name    string // path relative to the output directory
+	Content []byte
+	Keep    bool   // don't overwrite an existing file, e.g. user-editable fixtures
+	Role    string // what the file is, as recorded in the manifest
+	Method  string // name of the method the file is generated for, if any
+}
+
+// execute executes the template name for data.
+func execute(name string, data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// render executes the template name for data and formats the result,
+// failing with a *GeneratedError if it doesn't parse, unless -allow-invalid
+// is set.
+func render(name string, data interface{}) ([]byte, error) {
+	src, err := execute(name, data)
+	if err != nil {
+		return nil, err
+	}
+	pretty, err := format.Source(src)
+	if err != nil {
+		if *flagAllowInvalid {
+			return src, nil
+		}
+		return nil, generatedError(name, src, err)
+	}
+	return pretty, nil
+}
+
+// splitStubs returns the files of the main package with -split: the
+// endpoint and transport of every method, in <method>_endpoint.go and
+// <method>_transport.go, and the code they share, in common.go. Every file
+// imports the packages of all of them, pruned by genFiles. Without the http
+// transport, the methods have no transport file.
+func splitStubs(svc Service) ([]File, error) {
+	src, err := render("splitcommon", svc)
+	if err != nil {
+		return nil, err
+	}
+	files := []File{{Name: "common.go", Content: src, Role: "endpoints"}}
+	parts := []string{"endpoint", "transport"}
+	if !svc.HTTP {
+		parts = parts[:1]
+	}
+	for _, f := range svc.Funcs {
+		for _, part := range parts {
+			src, err := render("split"+part, svc.Only(f))
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, File{Name: snakeCase(f.Name) + "_" + part + ".go", Content: src, Role: part, Method: f.Name})
+		}
+	}
+	return files, nil
+}
+
+// genFiles returns all files generated for svc, starting with the main
+// package file.
+func genFiles(svc Service) ([]File, error) {
+	var files []File
+	if *flagSplit && !svc.Outbound {
+		split, err := splitStubs(svc)
+		if err != nil {
+			return nil, err
+		}
+		files = split
+	} else {
+		src, err := genStubs(svc)
+		if err != nil {
+			return nil, err
+		}
+		files = []File{{Name: svc.Pkg + ".go", Content: src, Role: "endpoints"}}
+	}
+
+	if svc.DTO {
+		src, err := render("dto", svc)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: filepath.Join("dto", "dto.go"), Content: src, Role: "dto"})
+	}
+
+	if svc.UsesScopes() {
+		src, err := render("scopes", svc)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: filepath.Join("scopes", "scopes.go"), Content: src, Role: "scopes"})
+	}
+
+	if svc.Assertions && len(svc.Implementations()) > 0 {
+		src, err := render("assertions", svc)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: "assertions.go", Content: src, Role: "assertions"})
+	}
+
+	if svc.Replay {
+		src, err := render("replay", svc)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: "replay.go", Content: src, Role: "replay"})
+	}
+
+	if svc.Client {
+		src, err := render("client", svc)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: "client.go", Content: src, Role: "client"})
+	}
+
+	if svc.GRPC {
+		src, err := render("grpc", svc)
+		if err != nil {
+			return nil, err
+		}
+		client, err := render("grpcclient", svc)
+		if err != nil {
+			return nil, err
+		}
+		doc, err := render("pb", svc)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files,
+			File{Name: "grpc.go", Content: src, Role: "grpc"},
+			File{Name: "grpc_client.go", Content: client, Role: "grpc"},
+			File{Name: "grpc_service_config.json", Content: []byte(svc.GRPCServiceConfig() + "\n"), Role: "grpc"},
+			File{Name: filepath.Join("pb", "doc.go"), Content: doc, Role: "grpc"},
+		)
+	}
+
+	if svc.NATS {
+		src, err := render("nats", svc)
+		if err != nil {
+			return nil, err
+		}
+		client, err := render("natsclient", svc)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files,
+			File{Name: "nats.go", Content: src, Role: "nats"},
+			File{Name: "nats_client.go", Content: client, Role: "nats"},
+		)
+	}
+
+	if svc.AMQP {
+		src, err := render("amqp", svc)
+		if err != nil {
+			return nil, err
+		}
+		client, err := render("amqpclient", svc)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files,
+			File{Name: "amqp.go", Content: src, Role: "amqp"},
+			File{Name: "amqp_client.go", Content: client, Role: "amqp"},
+		)
+	}
+
+	if svc.Compression != "" {
+		src, err := render("compression", svc)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: "compression.go", Content: src, Role: "compression"})
+	}
+	if svc.DeadLetter {
+		src, err := render("deadletter", svc)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: "dead_letter.go", Content: src, Role: "dead-letter"})
+	}
+
+	if svc.Mock {
+		src, err := render("mock", svc)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: "mock.go", Content: src, Role: "mock"})
+	}
+	if svc.Harness {
+		src, err := render("harness", svc)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: "harness_test.go", Content: src, Role: "test"})
+		for _, f := range svc.Funcs {
+			files = append(files, File{
+				Name:    filepath.Join("testdata", "golden", f.Name, "zero.json"),
+				Content: []byte("{}\n"),
+				Keep:    true,
+				Role:    "golden",
+				Method:  f.Name,
+			})
+		}
+	}
+	if svc.StubServer {
+		src, err := render("stubserver", svc)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: filepath.Join("stubserver", "main.go"), Content: src, Role: "stubserver"})
+		for _, f := range svc.Funcs {
+			fixture, err := execute("stubfixture", f)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, File{
+				Name:    filepath.Join("stubserver", "fixtures", f.Name+".yaml"),
+				Content: fixture,
+				Keep:    true,
+				Role:    "fixture",
+				Method:  f.Name,
+			})
+		}
+	}
+	if svc.Scaffold {
+		scaffold := "scaffold"
+		if !svc.HTTP {
+			scaffold = "grpcscaffold"
+		}
+		src, err := render(scaffold, svc)
+		if err != nil {
+			return nil, err
+		}
+		service, err := render("scaffoldservice", svc)
+		if err != nil {
+			return nil, err
+		}
+		dir := filepath.Join("cmd", svc.CommandName())
+		files = append(files,
+			File{Name: filepath.Join(dir, "main.go"), Content: src, Role: "command"},
+			File{Name: filepath.Join(dir, "service.go"), Content: service, Keep: true, Role: "implementation"},
+		)
+		if svc.HotReload {
+			reload, err := render("reload", svc)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, File{Name: filepath.Join(dir, "reload.go"), Content: reload, Role: "config"})
+		}
+		if svc.Repository != "" {
+			repo, err := render("repository", svc)
+			if err != nil {
+				return nil, err
+			}
+			adapter, err := render("repository"+svc.Repository, svc)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files,
+				File{Name: filepath.Join(dir, "repository.go"), Content: repo, Role: "repository"},
+				File{Name: filepath.Join(dir, "repository_"+svc.Repository+".go"), Content: adapter, Keep: true, Role: "repository-adapter"},
+			)
+			if svc.Repository == "sqlc" {
+				queries, err := execute("querysql", svc)
+				if err != nil {
+					return nil, err
+				}
+				files = append(files, File{Name: filepath.Join(dir, "query.sql"), Content: queries, Keep: true, Role: "queries"})
+			}
+		}
+	}
+	if svc.LoadTest {
+		src, err := render("loadtest", svc)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: filepath.Join("loadtest", "loadtest.go"), Content: src, Role: "loadtest"})
+	}
+	if svc.UsesSLOs() {
+		src, err := genSLORules(svc)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: "slo.rules.yaml", Content: src, Role: "alert-rules"})
+	}
+	if svc.Dashboard {
+		src, err := genDashboard(svc)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: "dashboard.json", Content: src, Role: "dashboard"})
+	}
+	// the templates import the packages any combination of options may
+	// use, and the types of the method signatures may only be used by the
+	// dto package, so drop the imports the files don't use before genGoMod
+	// requires them
+	for i, f := range files {
+		if !strings.HasSuffix(f.Name, ".go") {
+			continue
+		}
+		src, err := pruneImports(f.Content)
+		if err != nil {
+			// -allow-invalid writes the code that doesn't parse as is
+			if *flagAllowInvalid {
+				continue
+			}
+			return nil, fmt.Errorf("pruning the imports of %s: %v", f.Name, err)
+		}
+		files[i].Content = src
+	}
+	if svc.Module != nil {
+		src, err := genGoMod(svc.Module.Path, files, svc.Module.Src, svc.Module.Dir)
+		if err != nil {
+			return nil, err
+		}
+		// go mod tidy is left to maintain the requirements
+		files = append(files, File{Name: "go.mod", Content: src, Keep: true, Role: "module"})
+	}
+	if svc.OpenAPI || svc.Proto || svc.UsesExamples() {
+		spec := newSpec(svc)
+		if err := checkExamples(svc, spec); err != nil {
+			return nil, err
+		}
+		if svc.OpenAPI {
+			src, err := genOpenAPI(svc, spec)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, File{Name: "openapi.yaml", Content: src, Role: "openapi"})
+		}
+		if svc.Proto {
+			src, err := execute("proto", newProtoFile(svc, spec))
+			if err != nil {
+				return nil, err
+			}
+			name := svc.Pkg + ".proto"
+			if svc.GRPC {
+				name = filepath.Join("pb", name)
+			}
+			files = append(files, File{Name: name, Content: src, Role: "proto"})
+		}
+	}
+	return files, nil
+}
+
+// importPath returns the import path of the package in dir, based on the
+// go.mod file of the enclosing module or, failing that, on GOPATH.
+func importPath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for d := abs; ; d = filepath.Dir(d) {
+		if data, err := ioutil.ReadFile(filepath.Join(d, "go.mod")); err == nil {
+			mod := modulePath(data)
+			if mod == "" {
+				return "", fmt.Errorf("no module path in %s", filepath.Join(d, "go.mod"))
+			}
+			rel, err := filepath.Rel(d, abs)
+			if err != nil {
+				return "", err
+			}
+			return path.Join(mod, filepath.ToSlash(rel)), nil
+		}
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	pkg, err := build.ImportDir(abs, build.FindOnly)
+	if err != nil {
+		return "", err
+	}
+	if pkg.ImportPath == "." {
+		return "", fmt.Errorf("couldn't determine the import path of %s", dir)
+	}
+	return pkg.ImportPath, nil
+}
+
+// modulePath returns the module path declared in the contents of a go.mod file.
+func modulePath(gomod []byte) string {
+	for _, line := range strings.Split(string(gomod), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != "module" {
+			continue
+		}
+		mod := fields[1]
+		if unquoted, err := strconv.Unquote(mod); err == nil {
+			mod = unquoted
+		}
+		return mod
+	}
+	return ""
+}
+
+// writeFiles writes files to dir, creating directories as needed.
+func writeFiles(dir string, files []File) error {
+	for _, f := range files {
+		path := filepath.Join(dir, f.Name)
+		if f.Keep {
+			if _, err := os.Stat(path); err == nil {
+				continue
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, f.Content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
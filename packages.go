@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// driverPackages caches the packages listed by the package driver, and
+// their dependencies, by import path.
+var driverPackages = map[string]*build.Package{}
+
+// usePackagesDriver reports whether packages are listed by the package
+// driver named by GOPACKAGESDRIVER, such as the one of rules_go for Bazel
+// workspaces, rather than found by go/build.
+func usePackagesDriver() bool {
+	driver := os.Getenv("GOPACKAGESDRIVER")
+	return driver != "" && driver != "off"
+}
+
+// buildContext returns the context packages are loaded in: the default one,
+// for the platform of -goos and -goarch if set, so that the files of the
+// package constrained to another platform than the host's are found.
+func buildContext() build.Context {
+	ctx := build.Default
+	if *flagGOOS != "" {
+		ctx.GOOS = *flagGOOS
+	}
+	if *flagGOARCH != "" {
+		ctx.GOARCH = *flagGOARCH
+	}
+	return ctx
+}
+
+// importPackage returns the package with the import path path, as imported
+// from srcDir.
+func importPackage(path, srcDir string) (*build.Package, error) {
+	if !usePackagesDriver() {
+		ctx := buildContext()
+		return ctx.Import(path, srcDir, 0)
+	}
+	if pkg, ok := driverPackages[path]; ok {
+		return pkg, nil
+	}
+	ctx := buildContext()
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps,
+		Dir:  srcDir,
+		Env:  append(os.Environ(), "GOOS="+ctx.GOOS, "GOARCH="+ctx.GOARCH),
+	}
+	pkgs, err := packages.Load(cfg, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("package driver %s returned %d packages for %s", os.Getenv("GOPACKAGESDRIVER"), len(pkgs), path)
+	}
+	var errs []error
+	packages.Visit(pkgs, nil, func(p *packages.Package) {
+		if _, ok := driverPackages[p.PkgPath]; ok {
+			return
+		}
+		dep, err := buildPackage(p)
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		driverPackages[p.PkgPath] = dep
+	})
+	pkg, ok := driverPackages[pkgs[0].PkgPath]
+	if !ok {
+		return nil, errs[len(errs)-1]
+	}
+	driverPackages[path] = pkg
+	return pkg, nil
+}
+
+// buildPackage converts a package listed by the package driver to the
+// build.Package go/build would have found. Its Go files are relative to the
+// directory of the first one, as the build system may put generated files
+// elsewhere.
+func buildPackage(p *packages.Package) (*build.Package, error) {
+	if len(p.Errors) > 0 {
+		return nil, p.Errors[0]
+	}
+	if len(p.GoFiles) == 0 {
+		return nil, fmt.Errorf("package %s has no Go files", p.PkgPath)
+	}
+	pkg := &build.Package{
+		Name:       p.Name,
+		ImportPath: p.PkgPath,
+		Dir:        filepath.Dir(p.GoFiles[0]),
+	}
+	for _, file := range p.GoFiles {
+		rel, err := filepath.Rel(pkg.Dir, file)
+		if err != nil {
+			return nil, err
+		}
+		pkg.GoFiles = append(pkg.GoFiles, rel)
+	}
+	for path := range p.Imports {
+		pkg.Imports = append(pkg.Imports, path)
+	}
+	sort.Strings(pkg.Imports)
+	goroot := filepath.Join(build.Default.GOROOT, "src") + string(filepath.Separator)
+	pkg.Goroot = strings.HasPrefix(pkg.Dir, goroot)
+	return pkg, nil
+}
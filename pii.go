@@ -0,0 +1,80 @@
+package main
+
+import "strings"
+
+// resolvePII records the parameters and results listed by the "//kit:pii
+// <name>... [category=<category>]" annotations of every method of fns as
+// personal data. Such parameters are sensitive as well.
+func resolvePII(fns []Func) error {
+	for i := range fns {
+		fn := &fns[i]
+		for _, a := range fn.Annotations {
+			if a.Name != "pii" {
+				continue
+			}
+			category, ok := a.Arg("category")
+			if !ok {
+				category = "personal"
+			}
+			n := 0
+		args:
+			for _, name := range a.Args {
+				if strings.HasPrefix(name, "category=") {
+					continue
+				}
+				n++
+				for j := range fn.Params {
+					if p := &fn.Params[j]; p.Name == name && p.Type != "context.Context" {
+						p.PII, p.Sensitive = category, true
+						continue args
+					}
+				}
+				for j := range fn.Res {
+					if r := &fn.Res[j]; r.Name == name && r.Type != "error" {
+						r.PII = category
+						continue args
+					}
+				}
+				return fn.errorf(a, "kit:pii %s: no such parameter or result", name)
+			}
+			if n == 0 {
+				return fn.errorf(a, "kit:pii takes at least one parameter or result name")
+			}
+		}
+	}
+	return nil
+}
+
+// UsesPII reports whether any method has parameters or results carrying
+// personal data.
+func (s Service) UsesPII() bool {
+	for _, f := range s.Funcs {
+		for _, ps := range [][]Param{f.Params, f.Res} {
+			for _, p := range ps {
+				if p.PII != "" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+const piiTemplate = `
+{{ define "pii" }}
+// PersonalDataField is a request or response field carrying personal data.
+type PersonalDataField struct {
+	Method   string
+	Field    string
+	Response bool // a field of the response rather than the request
+	Category string
+}
+
+// PersonalData lists the fields carrying personal data, as annotated with
+// kit:pii, for audit tooling. The request fields are masked when logged.
+var PersonalData = []PersonalDataField{ {{ range .Funcs }}{{ $fun := . }}{{ range .Params }}{{ if .PII }}
+	{Method: "{{ $fun.Name }}", Field: "{{ .Field }}", Category: "{{ .PII }}"},{{ end }}{{ end }}{{ range .Res }}{{ if .PII }}
+	{Method: "{{ $fun.Name }}", Field: "{{ .Field }}", Response: true, Category: "{{ .PII }}"},{{ end }}{{ end }}{{ end }}
+}
+{{ end }}
+`
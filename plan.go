@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Plan lists the files a generation would write, for kitboiler apply to
+// write them once reviewed.
+type Plan struct {
+	Interface string     `json:"interface"`
+	Dir       string     `json:"dir"` // output directory, relative to the plan file
+	Files     []PlanFile `json:"files"`
+}
+
+// PlanFile is a file of a plan.
+type PlanFile struct {
+	Name   string `json:"name"` // slash separated path relative to the output directory
+	Role   string `json:"role"`
+	Action string `json:"action"` // create, update, unchanged, or keep for an existing file edited by hand
+	// Symbols are the top-level declarations of Go files, with methods
+	// qualified by their receiver type, e.g. "CreateUserRequest.String".
+	Symbols []string `json:"symbols,omitempty"`
+	// Base is the hash of the existing file when planned, which apply
+	// requires to be unchanged.
+	Base    string `json:"base,omitempty"`
+	SHA256  string `json:"sha256"`
+	Content string `json:"content,omitempty"` // written by apply if created or updated
+}
+
+// planUnsupported are the flags making gen write files beside those of the
+// plan.
+var planUnsupported = []string{"follow-renames", "changelog", "go-generate"}
+
+func runPlan(args []string) error {
+	if *flagOutDir == "" {
+		return errors.New("plan requires -o")
+	}
+	var err error
+	flag.Visit(func(f *flag.Flag) {
+		for _, name := range planUnsupported {
+			if err == nil && f.Name == name {
+				err = fmt.Errorf("-%s isn't supported by plan, run gen instead", name)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	svc, err := loadService(args)
+	if err != nil {
+		return err
+	}
+	svc.Gone = advanceGone(svc.Gone)
+	files, err := genFiles(svc)
+	if err == nil {
+		files, err = withManifest(svc, files)
+	}
+	if err != nil {
+		return err
+	}
+	plan, err := makePlan(svc.IFacePath+"."+svc.IFaceName(), *flagOutDir, *flagPlan, files)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(*flagPlan, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+
+	counts := map[string]int{}
+	for _, f := range plan.Files {
+		counts[f.Action]++
+		switch f.Action {
+		case "create":
+			if strings.HasSuffix(f.Name, ".go") {
+				fmt.Printf("+ %s (%d symbols)\n", f.Name, len(f.Symbols))
+			} else {
+				fmt.Printf("+ %s\n", f.Name)
+			}
+		case "update":
+			fmt.Printf("~ %s\n", f.Name)
+		}
+	}
+	fmt.Printf("wrote %s: %d to create, %d to update, %d unchanged, %d kept; run kitboiler apply to write them\n",
+		*flagPlan, counts["create"], counts["update"], counts["unchanged"], counts["keep"])
+	return nil
+}
+
+// makePlan returns the plan of writing files to dir, in the plan file path.
+func makePlan(iface, dir, path string, files []File) (Plan, error) {
+	rel, err := relDir(filepath.Dir(path), dir)
+	if err != nil {
+		return Plan{}, err
+	}
+	plan := Plan{Interface: iface, Dir: filepath.ToSlash(rel)}
+	for _, f := range files {
+		pf := PlanFile{Name: filepath.ToSlash(f.Name), Role: f.Role, SHA256: hash(f.Content)}
+		old, err := ioutil.ReadFile(filepath.Join(dir, f.Name))
+		switch {
+		case os.IsNotExist(err):
+			pf.Action = "create"
+		case err != nil:
+			return Plan{}, err
+		case f.Keep:
+			pf.Action, pf.SHA256 = "keep", hash(old)
+		case string(old) == string(f.Content):
+			pf.Action = "unchanged"
+		default:
+			pf.Action, pf.Base = "update", hash(old)
+		}
+		if pf.Action == "create" || pf.Action == "update" {
+			pf.Content = string(f.Content)
+		}
+		if strings.HasSuffix(f.Name, ".go") && pf.Action != "keep" {
+			if pf.Symbols, err = goSymbols(f.Content); err != nil {
+				return Plan{}, fmt.Errorf("%s: %v", f.Name, err)
+			}
+		}
+		plan.Files = append(plan.Files, pf)
+	}
+	return plan, nil
+}
+
+// relDir returns dir relative to base.
+func relDir(base, dir string) (string, error) {
+	absBase, err := filepath.Abs(base)
+	if err != nil {
+		return "", err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(absBase, absDir)
+}
+
+// goSymbols returns the names of the top-level declarations of the Go
+// source src.
+func goSymbols(src []byte) ([]string, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		return nil, err
+	}
+	var symbols []string
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			name := decl.Name.Name
+			if decl.Recv != nil && len(decl.Recv.List) == 1 {
+				name = receiverName(decl.Recv.List[0].Type) + "." + name
+			}
+			symbols = append(symbols, name)
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					symbols = append(symbols, spec.Name.Name)
+				case *ast.ValueSpec:
+					for _, id := range spec.Names {
+						if id.Name != "_" {
+							symbols = append(symbols, id.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+	return symbols, nil
+}
+
+// receiverName returns the name of the type of the receiver x.
+func receiverName(x ast.Expr) string {
+	switch x := x.(type) {
+	case *ast.StarExpr:
+		return receiverName(x.X)
+	case *ast.Ident:
+		return x.Name
+	}
+	return "?"
+}
+
+func runApply(args []string) error {
+	if len(args) > 0 {
+		return errUsage
+	}
+	data, err := ioutil.ReadFile(*flagPlan)
+	if err != nil {
+		return err
+	}
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return fmt.Errorf("%s: %v", *flagPlan, err)
+	}
+	dir := filepath.Join(filepath.Dir(*flagPlan), filepath.FromSlash(plan.Dir))
+
+	// check every file first, so that the plan is applied entirely or not at all
+	var files []File
+	for _, f := range plan.Files {
+		if f.Action != "create" && f.Action != "update" {
+			continue
+		}
+		if hash([]byte(f.Content)) != f.SHA256 {
+			return fmt.Errorf("%s: content of %s doesn't match its hash", *flagPlan, f.Name)
+		}
+		old, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(f.Name)))
+		switch {
+		case f.Action == "create" && err == nil:
+			return fmt.Errorf("%s was created since the plan was made, plan again", f.Name)
+		case f.Action == "update" && os.IsNotExist(err):
+			return fmt.Errorf("%s was removed since the plan was made, plan again", f.Name)
+		case err != nil && !os.IsNotExist(err):
+			return err
+		case f.Action == "update" && hash(old) != f.Base:
+			return fmt.Errorf("%s changed since the plan was made, plan again", f.Name)
+		}
+		files = append(files, File{Name: filepath.FromSlash(f.Name), Content: []byte(f.Content)})
+	}
+	if err := writeFiles(dir, files); err != nil {
+		return err
+	}
+	for _, f := range files {
+		fmt.Println("wrote", filepath.Join(dir, f.Name))
+	}
+	return nil
+}
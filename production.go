@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// presets are the flags set by -preset, unless set explicitly.
+var presets = map[string]map[string]string{
+	"production": {
+		"recover":    "true",
+		"access-log": "true",
+		"health":     "true",
+		"scaffold":   "true",
+		"metrics":    "true",
+		"dashboard":  "true",
+		"slo":        "99.9",
+	},
+	"grpc-internal": {
+		"transports":   "grpc",
+		"endpoint-set": "true",
+		"scaffold":     "true",
+		"middleware":   "logging,instrumentation",
+		"tracing":      "true",
+		"discovery":    "consul",
+	},
+}
+
+// applyPreset sets the flags of the preset name that aren't set on the
+// command line.
+func applyPreset(name string) error {
+	preset, ok := presets[name]
+	if !ok {
+		var names []string
+		for n := range presets {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("-preset: unknown preset %q, want one of %v", name, names)
+	}
+	if err := setUnset(preset); err != nil {
+		return fmt.Errorf("-preset %s: %v", name, err)
+	}
+	return nil
+}
+
+// loggerImports are the imports required by the Logger of the HTTP handlers.
+var loggerImports = []string{"github.com/go-kit/kit/log"}
+
+// recoverImports are the imports required by recoverHTTP.
+var recoverImports = []string{"fmt", "runtime/debug"}
+
+// UsesLogger reports whether the HTTP handlers log to Logger.
+func (s Service) UsesLogger() bool {
+	return s.Recover || s.AccessLog
+}
+
+const productionTemplate = `
+{{ define "logger" }}
+{{ if .AccessLog }}// Logger receives the access log{{ if .Recover }} and the recovered panics{{ end }} of the HTTP handlers.{{ else }}// Logger receives the panics recovered by the HTTP handlers.{{ end }}
+// It discards everything until set{{ if .UsesSwitches }}, and what is below Switches.LogLevel{{ end }}.
+var Logger log.Logger = log.NewNopLogger()
+{{ end }}
+
+{{ define "recover" }}
+// recoverHTTP responds with 500 Internal Server Error when h panics, logging
+// the panic to Logger instead of crashing the connection.
+func recoverHTTP(method string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			{{ if .UsesSwitches }}if Switches.logs(LogError) {
+				Logger.Log("method", method, "panic", fmt.Sprint(v), "stack", string(debug.Stack()))
+			}{{ else }}Logger.Log("method", method, "panic", fmt.Sprint(v), "stack", string(debug.Stack())){{ end }}
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}()
+		h.ServeHTTP(w, r)
+	})
+}
+{{ end }}
+
+{{ define "accesslog" }}
+// logHTTP logs the requests of method handled by h to Logger.
+func logHTTP(method string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		begin := time.Now()
+		sw := &statusResponseWriter{ResponseWriter: w, code: http.StatusOK}
+		h.ServeHTTP(sw, r){{ if .UsesSwitches }}
+		if Switches.logs(LogInfo) {
+			Logger.Log("method", method, "path", r.URL.Path, "code", sw.code, "took", time.Since(begin))
+		}{{ else }}
+		Logger.Log("method", method, "path", r.URL.Path, "code", sw.code, "took", time.Since(begin)){{ end }}
+	})
+}
+
+// statusResponseWriter records the status code of a response.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *statusResponseWriter) WriteHeader(code int) {
+	w.code = code
+	w.ResponseWriter.WriteHeader(code)
+}
+{{ end }}
+
+{{ define "health" }}
+// Ready reports whether the service is ready to serve requests, e.g. by
+// pinging its database: /readyz responds with 503 Service Unavailable while
+// it returns an error. It always reports ready until set.
+var Ready = func(ctx context.Context) error { return nil }
+
+// healthz reports that the process is alive.
+func healthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// readyz reports whether the service is ready, see Ready.
+func readyz(w http.ResponseWriter, r *http.Request) {
+	if err := Ready(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+{{ end }}
+`
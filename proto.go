@@ -0,0 +1,142 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// ProtoFile is the data of the proto template.
+type ProtoFile struct {
+	Package   string
+	GoPackage string // import path of the Go package generated from it, if any
+	Service   string
+	Methods   []Func
+	Imports   []string
+	Messages  []ProtoMessage
+}
+
+// ProtoMessage is a message of a proto file.
+type ProtoMessage struct {
+	Name   string
+	Fields []ProtoField
+	OneOf  []ProtoField // fields of the value oneof, for unions
+}
+
+// ProtoField is a field of a proto message.
+type ProtoField struct {
+	Label    string // optional or repeated, if any
+	Type     string
+	Name     string
+	JSONName string
+	Number   int
+	Comment  string
+}
+
+// protoImports are the files declaring the well-known types used for scalars.
+var protoImports = map[string]string{
+	"google.protobuf.Timestamp": "google/protobuf/timestamp.proto",
+	"google.protobuf.Duration":  "google/protobuf/duration.proto",
+	"google.protobuf.Value":     "google/protobuf/struct.proto",
+}
+
+// newProtoFile returns the proto file describing the methods of svc and
+// the messages of spec. Pointer and optional fields are optional, so their
+// presence is tracked as it is in JSON. Unions are messages holding a
+// oneof of their variants.
+func newProtoFile(svc Service, spec *Spec) ProtoFile {
+	pf := ProtoFile{Package: svc.Pkg, Service: svc.IFaceName()}
+	if svc.GRPC {
+		pf.GoPackage = svc.ImportPath + "/pb"
+	}
+	for _, f := range svc.Funcs {
+		pf.Methods = append(pf.Methods, f)
+	}
+	imports := map[string]bool{}
+	for _, c := range spec.Components {
+		if c.AllOf != "" {
+			continue // variant of a union, whose discriminator is the oneof
+		}
+		msg := ProtoMessage{Name: c.Name}
+		for i, v := range c.OneOf {
+			typ := spec.byName[v.Schema.Ref].AllOf
+			msg.OneOf = append(msg.OneOf, ProtoField{Type: typ, Name: snakeCase(v.Name), JSONName: v.Name, Number: i + 1})
+		}
+		for i, p := range c.Properties {
+			label, typ := protoType(p.Schema)
+			if label == "" && (p.Optional || p.Schema.Nullable) {
+				label = "optional"
+			}
+			field := ProtoField{Label: label, Type: typ, Name: snakeCase(p.Name), JSONName: p.Name, Number: i + 1}
+			if len(p.Schema.Enum) > 0 {
+				field.Comment = "one of " + strings.Join(p.Schema.Enum, ", ")
+			}
+			for t, file := range protoImports {
+				if strings.Contains(typ, t) {
+					imports[file] = true
+				}
+			}
+			msg.Fields = append(msg.Fields, field)
+		}
+		pf.Messages = append(pf.Messages, msg)
+	}
+	for file := range imports {
+		pf.Imports = append(pf.Imports, file)
+	}
+	sort.Strings(pf.Imports)
+	return pf
+}
+
+// protoType returns the label and type of a proto field holding s.
+func protoType(s *Schema) (label, typ string) {
+	switch {
+	case s.Ref != "":
+		return "", s.Ref
+	case s.Type == "array":
+		if _, item := protoType(s.Items); s.Items.Type != "array" && !isProtoMap(s.Items) {
+			return "repeated", item
+		}
+		return "repeated", "google.protobuf.Value"
+	case isProtoMap(s):
+		if _, value := protoType(s.Items); s.Items.Type != "array" && !isProtoMap(s.Items) {
+			return "", "map<string, " + value + ">"
+		}
+		return "", "map<string, google.protobuf.Value>"
+	case s.Proto != "":
+		return "", s.Proto
+	}
+	return "", "google.protobuf.Value"
+}
+
+func isProtoMap(s *Schema) bool {
+	return s.Type == "object" && s.Ref == "" && s.Items != nil
+}
+
+// snakeCase converts a Go identifier such as "UserID" to "user_id".
+func snakeCase(name string) string {
+	return strings.Replace(kebabCase(name), "-", "_", -1)
+}
+
+const protoTemplate = `
+{{ define "proto" }}// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+
+syntax = "proto3";
+
+package {{ .Package }};
+{{ with .GoPackage }}
+option go_package = "{{ . }}";
+{{ end }}{{ range .Imports }}
+import "{{ . }}";{{ end }}
+
+service {{ .Service }} {{ "{" }}{{ range .Methods }}
+  rpc {{ .Name }}({{ .RequestName }}) returns ({{ .ResponseName }});{{ end }}
+}
+{{ range .Messages }}
+message {{ .Name }} {{ "{" }}{{ range .Fields }}{{ if .Comment }}
+  // {{ .Comment }}{{ end }}
+  {{ with .Label }}{{ . }} {{ end }}{{ .Type }} {{ .Name }} = {{ .Number }} [json_name = "{{ .JSONName }}"];{{ end }}{{ if .OneOf }}
+  oneof value {{ "{" }}{{ range .OneOf }}
+    {{ .Type }} {{ .Name }} = {{ .Number }} [json_name = "{{ .JSONName }}"];{{ end }}
+  }{{ end }}
+}
+{{ end }}{{ end }}
+`
@@ -0,0 +1,130 @@
+package main
+
+import "fmt"
+
+// rateLimitKeys are the supported ways of identifying the client of a request
+// for rate limiting.
+var rateLimitKeys = map[string][]string{
+	"ip":     {"net"},
+	"apikey": {"context", "net"},
+	"jwt":    {"context", "net"},
+}
+
+// rateLimitImports returns the imports required by the rate limiting code for key.
+func rateLimitImports(key string) ([]string, error) {
+	imps, ok := rateLimitKeys[key]
+	if !ok {
+		return nil, fmt.Errorf("unsupported rate limit key %q (expected ip, apikey or jwt)", key)
+	}
+	return append([]string{"math", "sync", "time"}, imps...), nil
+}
+
+const rateLimitTemplate = `
+{{ define "ratelimit" }}
+// RateLimitStore decides whether the client identified by key may make another request.
+type RateLimitStore interface {
+	Allow(key string) bool
+}
+
+// NewRateLimitHandler wraps h, responding with 429 Too Many Requests when store
+// does not allow the client of a request to proceed. Clients are identified by
+// {{ if eq .RateLimit "apikey" }}their API key{{ else if eq .RateLimit "jwt" }}the subject of their bearer token{{ end }}{{ if ne .RateLimit "ip" }} as verified by the authentication middleware in front
+// of it, see WithRateLimitClient, and by {{ end }}their IP address{{ if ne .RateLimit "ip" }} otherwise{{ end }}.{{ if .UsesSwitches }} Every request
+// proceeds while Switches.RateLimitEnabled reports false.{{ end }}
+func NewRateLimitHandler(store RateLimitStore, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if {{ if .UsesSwitches }}Switches.RateLimitEnabled() && {{ end }}!store.Allow(rateLimitKey(r)) {
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+{{ if ne .RateLimit "ip" }}
+type rateLimitClientKey struct{}
+
+// WithRateLimitClient returns a copy of r whose client NewRateLimitHandler
+// identifies by client, {{ if eq .RateLimit "apikey" }}its API key{{ else }}the subject of its bearer token{{ end }}. Call it from the authentication
+// middleware in front of NewRateLimitHandler once it has verified the
+// {{ if eq .RateLimit "apikey" }}X-API-Key header{{ else }}token{{ end }} of r. Unverified {{ if eq .RateLimit "apikey" }}keys{{ else }}tokens{{ end }} are never trusted, as a client could
+// evade its limit by sending another one with every request: the requests
+// without a verified client are limited by their IP address.
+func WithRateLimitClient(r *http.Request, client string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), rateLimitClientKey{}, client))
+}
+{{ end }}
+// rateLimitKey identifies the client of r, falling back to its IP address.
+func rateLimitKey(r *http.Request) string { {{ if ne .RateLimit "ip" }}
+	if client, _ := r.Context().Value(rateLimitClientKey{}).(string); client != "" {
+		return "{{ if eq .RateLimit "apikey" }}key{{ else }}sub{{ end }}:" + client
+	}{{ end }}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// NewMemoryRateLimitStore returns a RateLimitStore keeping a token bucket per
+// client in memory, refilled at rate tokens per second up to burst tokens{{ if .UsesSwitches }},
+// unless overridden by Switches.SetRateLimit{{ end }}.
+func NewMemoryRateLimitStore(rate float64, burst int) RateLimitStore {
+	return &memoryRateLimitStore{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: map[string]*tokenBucket{},
+	}
+}
+
+type memoryRateLimitStore struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*tokenBucket
+	swept   time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func (s *memoryRateLimitStore) Allow(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rate, burst := s.rate, s.burst{{ if .UsesSwitches }}
+	if o, ok := Switches.RateLimit(); ok {
+		rate, burst = o.Rate, float64(o.Burst)
+	}{{ end }}
+	now := time.Now()
+	if now.Sub(s.swept) > time.Minute {
+		s.sweep(now, rate, burst)
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: burst, last: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// sweep drops the buckets that have been refilled completely, as they are
+// equivalent to new ones.
+func (s *memoryRateLimitStore) sweep(now time.Time, rate, burst float64) {
+	for key, b := range s.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rate >= burst {
+			delete(s.buckets, key)
+		}
+	}
+	s.swept = now
+}
+{{ end }}
+`
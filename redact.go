@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// redactImports are the imports of the redacting String and LogValue methods.
+var redactImports = []string{"fmt", "log/slog"}
+
+// redacted replaces the values of sensitive fields.
+const redacted = "[REDACTED]"
+
+// resolveSensitive marks the parameters listed by the "//kit:sensitive
+// <param>..." annotation of every method of fns as sensitive.
+func resolveSensitive(fns []Func) error {
+	for i := range fns {
+		fn := &fns[i]
+		a, ok := fn.Annotation("sensitive")
+		if !ok {
+			continue
+		}
+		if len(a.Args) == 0 {
+			return fn.errorf(a, "kit:sensitive takes at least one parameter name")
+		}
+	args:
+		for _, name := range a.Args {
+			for j := range fn.Params {
+				if fn.Params[j].Name == name && fn.Params[j].Type != "context.Context" {
+					fn.Params[j].Sensitive = true
+					continue args
+				}
+			}
+			return fn.errorf(a, "kit:sensitive %s: no such parameter", name)
+		}
+	}
+	return nil
+}
+
+// IsRedacted reports whether the generated request type of f has sensitive
+// fields, which its String and LogValue methods mask.
+func IsRedacted(f Func) bool {
+	if f.RequestType != nil {
+		return false
+	}
+	for _, p := range f.Params {
+		if p.Sensitive {
+			return true
+		}
+	}
+	return false
+}
+
+// UsesRedaction reports whether any generated request type has sensitive
+// fields.
+func (s Service) UsesRedaction() bool {
+	for _, f := range s.Funcs {
+		if IsRedacted(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactedString returns the expression formatting the request r of f with
+// its sensitive fields masked.
+func RedactedString(f Func) string {
+	var format, args []string
+	for _, p := range f.Params {
+		switch {
+		case p.Type == "context.Context":
+		case p.Sensitive:
+			format = append(format, p.Field+":"+redacted)
+		default:
+			format = append(format, p.Field+":%+v")
+			args = append(args, "r."+p.Field)
+		}
+	}
+	return fmt.Sprintf("fmt.Sprintf(%q, %s)", f.RequestName+"{"+strings.Join(format, " ")+"}", strings.Join(args, ", "))
+}
+
+// RedactedAttrs returns the slog attributes of the fields of the request r
+// of f, with its sensitive fields masked.
+func RedactedAttrs(f Func) []string {
+	var attrs []string
+	for _, p := range f.Params {
+		switch {
+		case p.Type == "context.Context":
+		case p.Sensitive:
+			attrs = append(attrs, fmt.Sprintf("slog.String(%q, %q)", p.Field, redacted))
+		default:
+			attrs = append(attrs, fmt.Sprintf("slog.Any(%q, r.%s)", p.Field, p.Field))
+		}
+	}
+	return attrs
+}
+
+const redactTemplate = `
+{{ define "redact" }}{{ if IsRedacted . }}
+// String formats the request with its sensitive fields masked, so that
+// logging it doesn't leak them.
+func (r {{ .RequestName }}) String() string {
+	return {{ RedactedString . }}
+}
+
+// LogValue implements slog.LogValuer, masking the sensitive fields.
+func (r {{ .RequestName }}) LogValue() slog.Value {
+	return slog.GroupValue({{ range RedactedAttrs . }}
+		{{ . }},{{ end }}
+	)
+}
+{{ end }}{{ end }}
+`
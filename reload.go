@@ -0,0 +1,171 @@
+package main
+
+const reloadTemplate = `
+{{ define "reload" }}
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	{{ .Pkg }} "{{ .ImportPath }}"
+)
+
+// DynamicConfig is the part of the configuration of the command reloaded
+// without a restart, in JSON. The settings left out have their static or
+// generated values.
+type DynamicConfig struct {
+	HandlerTimeout Duration ` + "`json:\"handler_timeout,omitempty\"`" + ` // e.g. "5s"{{ if .UsesLogger }}
+	LogLevel       string   ` + "`json:\"log_level,omitempty\"`" + ` // info, error or none{{ end }}{{ if .RateLimit }}
+	RateLimitEnabled *bool ` + "`json:\"rate_limit_enabled,omitempty\"`" + `
+	RateLimit *{{ .Pkg }}.RateLimitOverride ` + "`json:\"rate_limit,omitempty\"`" + `{{ end }}{{ if .UsesBudgets }}
+	Budgets map[string]Duration ` + "`json:\"budgets,omitempty\"`" + ` // latency budgets by method name{{ end }}
+}
+
+// Duration is a time.Duration read from a string such as "250ms".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// ConfigSource loads the dynamic configuration, from a file or a remote
+// configuration store.
+type ConfigSource interface {
+	Load(ctx context.Context) (DynamicConfig, error)
+}
+
+// FileSource loads the dynamic configuration from the JSON file Path.
+type FileSource struct {
+	Path string
+}
+
+func (s FileSource) Load(ctx context.Context) (DynamicConfig, error) {
+	var c DynamicConfig
+	data, err := ioutil.ReadFile(s.Path)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("%s: %v", s.Path, err)
+	}
+	return c, nil
+}
+
+// newConfigSource returns the source of the dynamic configuration, the
+// file of cfg.ConfigFile if set, or nil to keep the static configuration.
+// Set it in an init function of another file of the command to load the
+// configuration from elsewhere.
+var newConfigSource = func(cfg Config) (ConfigSource, error) {
+	if cfg.ConfigFile == "" {
+		return nil, nil
+	}
+	return FileSource{Path: cfg.ConfigFile}, nil
+}
+
+// handlerTimeout is the time to handle a request, read atomically by
+// withHandlerTimeout.
+var handlerTimeout int64
+
+// withHandlerTimeout bounds the requests to h by the current handler timeout,
+// after which they fail with 503 Service Unavailable.
+func withHandlerTimeout(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.TimeoutHandler(h, time.Duration(atomic.LoadInt64(&handlerTimeout)), "").ServeHTTP(w, r)
+	})
+}
+
+// applyConfig swaps the settings of the middleware for those of c, falling
+// back to cfg for the ones c leaves out. c is checked entirely first, so
+// that it is applied entirely or not at all.
+func applyConfig(cfg Config, c DynamicConfig) error {
+	timeout := cfg.HandlerTimeout
+	if c.HandlerTimeout < 0 {
+		return fmt.Errorf("handler_timeout: negative duration %v", time.Duration(c.HandlerTimeout))
+	}
+	if c.HandlerTimeout > 0 {
+		timeout = time.Duration(c.HandlerTimeout)
+	}{{ if .UsesLogger }}
+	level := {{ .Pkg }}.LogInfo
+	if c.LogLevel != "" {
+		var err error
+		if level, err = {{ .Pkg }}.ParseLogLevel(c.LogLevel); err != nil {
+			return fmt.Errorf("log_level: %v", err)
+		}
+	}{{ end }}{{ if .RateLimit }}
+	var override {{ .Pkg }}.RateLimitOverride
+	if c.RateLimit != nil {
+		if o := *c.RateLimit; o.Rate <= 0 || o.Burst <= 0 {
+			return fmt.Errorf("rate_limit: want a positive rate and burst")
+		}
+		override = *c.RateLimit
+	}{{ end }}{{ if .UsesBudgets }}
+	budgets := map[string]time.Duration{}
+	for method, d := range c.Budgets {
+		if d <= 0 {
+			return fmt.Errorf("budgets: %s: want a positive duration", method)
+		}
+		budgets[method] = time.Duration(d)
+	}{{ end }}
+
+	atomic.StoreInt64(&handlerTimeout, int64(timeout)){{ if .UsesLogger }}
+	{{ .Pkg }}.Switches.SetLogLevel(level){{ end }}{{ if .RateLimit }}
+	{{ .Pkg }}.Switches.SetRateLimitEnabled(c.RateLimitEnabled == nil || *c.RateLimitEnabled)
+	{{ .Pkg }}.Switches.SetRateLimit(override){{ end }}{{ if .UsesBudgets }}
+	{{ .Pkg }}.Switches.SetBudgets(budgets){{ end }}
+	return nil
+}
+
+// watchConfig loads the dynamic configuration from src every
+// cfg.ReloadInterval and applies it when it changes, until ctx is done. A
+// configuration failing to load or to apply is logged, once, and the
+// previous one is kept.
+func watchConfig(ctx context.Context, cfg Config, src ConfigSource, current DynamicConfig, logger log.Logger) {
+	ticker := time.NewTicker(cfg.ReloadInterval)
+	defer ticker.Stop()
+	var failure string
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		c, err := src.Load(ctx)
+		if err == nil && reflect.DeepEqual(c, current) {
+			continue
+		}
+		if err == nil {
+			err = applyConfig(cfg, c)
+		}
+		if err != nil {
+			if err.Error() != failure {
+				logger.Log("msg", "keeping the configuration", "err", err)
+				failure = err.Error()
+			}
+			continue
+		}
+		current, failure = c, ""
+		logger.Log("msg", "reloaded the configuration")
+	}
+}
+{{ end }}
+`
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Signature returns the types of the parameters and results of f, e.g.
+// "(context.Context, string) (*model.User, error)".
+func (f Func) Signature() string {
+	types := func(params []Param) string {
+		var ts []string
+		for _, p := range params {
+			ts = append(ts, p.Type)
+		}
+		return "(" + strings.Join(ts, ", ") + ")"
+	}
+	return types(f.Params) + " " + types(f.Res)
+}
+
+// detectRenames returns the new names of the methods of the manifest prev
+// that have been renamed in fns, by old name. A method is renamed if it is
+// gone and a single new method has its signature, which no other method
+// that is gone had.
+func detectRenames(prev Manifest, fns []Func) map[string]string {
+	current := map[string]bool{}
+	for _, f := range fns {
+		current[f.Name] = true
+	}
+	previous := map[string]bool{}
+	gone := map[string][]string{} // by signature
+	for _, m := range prev.Methods {
+		previous[m.Name] = true
+		if !current[m.Name] {
+			gone[m.Signature] = append(gone[m.Signature], m.Name)
+		}
+	}
+	added := map[string][]string{}
+	for _, f := range fns {
+		if !previous[f.Name] {
+			added[f.Signature()] = append(added[f.Signature()], f.Name)
+		}
+	}
+	renames := map[string]string{}
+	for sig, old := range gone {
+		if len(old) == 1 && len(added[sig]) == 1 {
+			renames[old[0]] = added[sig][0]
+		}
+	}
+	return renames
+}
+
+// followRenames moves the files in dir generated for the methods of the
+// manifest prev that have been renamed to the files generated for their new
+// name, keeping the edits made to them, such as fixtures. Files that
+// already exist under the new name are left alone.
+func followRenames(dir string, prev Manifest, files []File, renames map[string]string) error {
+	for _, pf := range prev.Files {
+		to, ok := renames[pf.Method]
+		if !ok {
+			continue
+		}
+		for _, f := range files {
+			if f.Method != to || f.Role != pf.Role {
+				continue
+			}
+			oldPath := filepath.Join(dir, filepath.FromSlash(pf.Name))
+			newPath := filepath.Join(dir, f.Name)
+			if _, err := os.Stat(newPath); err == nil {
+				break
+			}
+			if _, err := os.Stat(oldPath); err != nil {
+				break
+			}
+			if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+				return err
+			}
+			if err := os.Rename(oldPath, newPath); err != nil {
+				return err
+			}
+			fmt.Printf("renamed %s to %s\n", pf.Name, filepath.ToSlash(f.Name))
+			removeEmptyDirs(dir, filepath.Dir(filepath.FromSlash(pf.Name)))
+			break
+		}
+	}
+	return nil
+}
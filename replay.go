@@ -0,0 +1,169 @@
+package main
+
+// replayImports are the imports of NewReplayHandler on top of those of the
+// package.
+var replayImports = []string{"context", "encoding/json", "fmt", "net/http", "time", "github.com/go-kit/kit/endpoint"}
+
+// ReplayImports returns the imports of replay.go.
+func (s Service) ReplayImports() map[string]string {
+	imps := map[string]string{}
+	for imp, alias := range s.Imports {
+		imps[imp] = alias
+	}
+	for _, imp := range replayImports {
+		imps[imp] = ""
+	}
+	return imps
+}
+
+const replayTemplate = `
+{{ define "replay" }}
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package {{ .Pkg }}
+{{ $svc := . }}
+import ({{ range $imp, $alias := .ReplayImports }}{{ $alias }} "{{ $imp }}"
+{{ end }}
+)
+
+// ReplayRequest is a recorded request of a method of {{ .IFace }}, as
+// replayed by NewReplayHandler, e.g.
+//
+//	{"method": "GetUser", "request": {"Id": "42"}}
+//
+// Request is the JSON of the request type of the method, as decoded from the
+// body of its route.
+type ReplayRequest struct {
+	Method  string          ` + "`json:\"method\"`" + `
+	Request json.RawMessage ` + "`json:\"request,omitempty\"`" + `{{ if .Tenant }}
+	Tenant  string          ` + "`json:\"tenant,omitempty\"`" + `{{ end }}
+}
+
+// ReplayResult is the outcome of a replayed request: the request as decoded,
+// the response or error of the endpoint, the status code it would have been
+// answered with and a trace of the steps of the replay.
+type ReplayResult struct {
+	Method   string        ` + "`json:\"method\"`" + `
+	Request  interface{}   ` + "`json:\"request,omitempty\"`" + `
+	Response interface{}   ` + "`json:\"response,omitempty\"`" + `
+	Error    string        ` + "`json:\"error,omitempty\"`" + `
+	Status   int           ` + "`json:\"status\"`" + `
+	Duration string        ` + "`json:\"duration\"`" + `
+	Trace    []ReplayEvent ` + "`json:\"trace\"`" + `
+}
+
+// ReplayEvent is a step of a replayed request, at the time since the replay
+// started.
+type ReplayEvent struct {
+	At    string ` + "`json:\"at\"`" + `
+	Event string ` + "`json:\"event\"`" + `
+}
+
+// replayers decode the recorded requests of the methods and make their
+// endpoints, by method name.
+var replayers = map[string]struct {
+	decode   func(json.RawMessage) (interface{}, error)
+	endpoint func({{ .IFace }}) endpoint.Endpoint
+}{ {{ range .Funcs }}
+	"{{ .Name }}": {
+		func(data json.RawMessage) (interface{}, error) {
+			var request {{ $svc.Request . }}
+			if len(data) == 0 {
+				return request, nil
+			}
+			err := json.Unmarshal(data, &request)
+			return request, err
+		},
+		{{ .Name }}EndPoint,
+	},{{ end }}
+}
+
+// NewReplayHandler returns a handler replaying the ReplayRequest POSTed to
+// it on svc, responding with its ReplayResult. The request is decoded into
+// the request type of its method, validated and passed to the bare endpoint
+// of the method, skipping the HTTP transport and the endpoint middlewares,
+// such as the checks of If-Match headers and scopes.
+//
+// It is meant to reproduce the issues of recorded requests locally, with
+// verbose tracing: never serve it in production, as it bypasses
+// authorization.
+func NewReplayHandler(svc {{ .IFace }}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		var replay ReplayRequest
+		if err := json.NewDecoder(r.Body).Decode(&replay); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, ok := replayers[replay.Method]; !ok {
+			http.Error(w, fmt.Sprintf("unknown method %q", replay.Method), http.StatusNotFound)
+			return
+		}
+		ctx := r.Context(){{ if .Tenant }}
+		if replay.Tenant != "" {
+			ctx = ContextWithTenant(ctx, replay.Tenant)
+		}{{ end }}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(replayRequest(ctx, svc, replay))
+	})
+}
+
+// replayRequest replays replay on svc, tracing every step.
+func replayRequest(ctx context.Context, svc {{ .IFace }}, replay ReplayRequest) ReplayResult {
+	start := time.Now()
+	result := ReplayResult{Method: replay.Method, Status: http.StatusOK}
+	trace := func(format string, args ...interface{}) {
+		result.Trace = append(result.Trace, ReplayEvent{At: time.Since(start).String(), Event: fmt.Sprintf(format, args...)})
+	}
+	fail := func(status int, err error) ReplayResult {
+		if sc, ok := err.(interface{ StatusCode() int }); ok {
+			status = sc.StatusCode()
+		}
+		result.Status, result.Error = status, err.Error()
+		result.Duration = time.Since(start).String()
+		return result
+	}
+	replayer := replayers[replay.Method]{{ if .Tenant }}
+	if tenant, ok := TenantFromContext(ctx); ok {
+		trace("replaying %s for tenant %q", replay.Method, tenant)
+	} else {
+		trace("replaying %s without a tenant", replay.Method)
+	}{{ else }}
+	trace("replaying %s", replay.Method){{ end }}
+	request, err := replayer.decode(replay.Request)
+	if err != nil {
+		trace("decoding the request failed: %v", err)
+		return fail(http.StatusBadRequest, err)
+	}
+	result.Request = request
+	trace("decoded the request into a %T: %+v", request, request)
+	if v, ok := request.(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			trace("validating the request failed: %v", err)
+			return fail(http.StatusBadRequest, err)
+		}
+		trace("validated the request")
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		trace("calling the endpoint, with a deadline in %s", time.Until(deadline))
+	} else {
+		trace("calling the endpoint")
+	}
+	called := time.Now()
+	response, err := replayer.endpoint(svc)(ctx, request)
+	if err != nil {
+		trace("the endpoint failed after %s with a %T: %v", time.Since(called), err, err)
+		return fail(http.StatusInternalServerError, err)
+	}
+	result.Response = response
+	trace("the endpoint returned after %s a %T: %+v", time.Since(called), response, response)
+	result.Duration = time.Since(start).String()
+	return result
+}
+{{ end }}
+`
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"strconv"
+	"strings"
+)
+
+// repositoryFlavors are the flavors of the repository adapters, see
+// -repository.
+var repositoryFlavors = map[string]bool{"sqlc": true, "ent": true}
+
+// Entity is a domain struct with an ID field, stored by the repository of
+// the scaffolded command, see -repository.
+type Entity struct {
+	Name    string   // e.g. "User"
+	Type    string   // as referred to in the command, e.g. "model.User"
+	ID      string   // name of the ID field
+	IDType  string   // type of the ID field, e.g. "string"
+	Columns []string // of the exported fields, named in snake case, the ID first
+}
+
+// Plural returns the plural of the name of e, e.g. "Users".
+func (e Entity) Plural() string {
+	n := e.Name
+	switch {
+	case strings.HasSuffix(n, "s"), strings.HasSuffix(n, "x"), strings.HasSuffix(n, "ch"), strings.HasSuffix(n, "sh"):
+		return n + "es"
+	case strings.HasSuffix(n, "y") && len(n) > 1 && !strings.ContainsAny(n[len(n)-2:len(n)-1], "aeiou"):
+		return n[:len(n)-1] + "ies"
+	}
+	return n + "s"
+}
+
+// Table returns the name of the table of e, e.g. "users".
+func (e Entity) Table() string {
+	return snakeCase(e.Plural())
+}
+
+// Placeholders returns the positional parameters of the columns of e, e.g.
+// "$1, $2, $3".
+func (e Entity) Placeholders() string {
+	var ps []string
+	for i := range e.Columns {
+		ps = append(ps, "$"+strconv.Itoa(i+1))
+	}
+	return strings.Join(ps, ", ")
+}
+
+// Assignments returns the assignments of the columns of e but the ID to
+// the positional parameters following that of the ID, e.g. "name = $2".
+func (e Entity) Assignments() string {
+	var as []string
+	for i, c := range e.Columns[1:] {
+		as = append(as, c+" = $"+strconv.Itoa(i+2))
+	}
+	return strings.Join(as, ", ")
+}
+
+// resolveEntities sets the entities of svc, the domain structs with a
+// field named ID referred to by the parameters and results of its methods,
+// directly or as the elements of pointers, slices and maps.
+func resolveEntities(svc *Service) error {
+	c := &converter{byType: map[string]*Conversion{}, names: map[string]bool{}, pkgs: map[string]*specPkg{}, imports: map[string]bool{}}
+	seen := map[string]bool{}
+	for _, f := range svc.Funcs {
+		c.srcDir = f.src.srcDir
+		for _, ps := range [][]Param{f.Params, f.Res} {
+			for _, p := range ps {
+				x, err := parser.ParseExpr(p.Type)
+				if err != nil || IsOptionSetter(p.Type) {
+					continue
+				}
+				path, name := namedType(f.src, x)
+				if path == "" || seen[path+"."+name] {
+					continue
+				}
+				seen[path+"."+name] = true
+				if e := c.entity(path, name); e != nil {
+					svc.Entities = append(svc.Entities, *e)
+				}
+			}
+		}
+	}
+	if len(svc.Entities) == 0 {
+		return fmt.Errorf("-repository: no entities, structs with an ID field, in the methods of %s", svc.IFace)
+	}
+	for i := range c.imports {
+		svc.entityImports = append(svc.entityImports, i)
+	}
+	return nil
+}
+
+// namedType returns the import path and name of the type named by x, as
+// used in the package p, past any pointers, slices and maps.
+func namedType(p Pkg, x ast.Expr) (path, name string) {
+	switch x := x.(type) {
+	case *ast.StarExpr:
+		return namedType(p, x.X)
+	case *ast.ArrayType:
+		return namedType(p, x.Elt)
+	case *ast.MapType:
+		return namedType(p, x.Value)
+	case *ast.Ident:
+		if x.IsExported() {
+			return p.ImportPath, x.Name
+		}
+	case *ast.SelectorExpr:
+		if qual, ok := x.X.(*ast.Ident); ok {
+			return p.importPathOf(qual.Name), x.Sel.Name
+		}
+	}
+	return "", ""
+}
+
+// entity returns the entity of the domain struct name declared in the
+// package path, or nil if it isn't one.
+func (c *converter) entity(path, name string) *Entity {
+	st := c.domainStruct(path, name)
+	if st == nil {
+		return nil
+	}
+	pkg := c.pkg(path)
+	e := &Entity{Name: name}
+	for _, field := range st.Fields.List {
+		for _, id := range field.Names {
+			if !id.IsExported() {
+				continue
+			}
+			if strings.EqualFold(id.Name, "id") && e.ID == "" {
+				e.ID, e.IDType = id.Name, c.typeString(pkg.Pkg, field.Type, false, "")
+				e.Columns = append([]string{snakeCase(id.Name)}, e.Columns...)
+				continue
+			}
+			e.Columns = append(e.Columns, snakeCase(id.Name))
+		}
+	}
+	if e.ID == "" {
+		return nil
+	}
+	e.Type = c.named(path, name, false, "")
+	return e
+}
+
+// EntityImports returns the imports required by the types of the entities.
+func (s Service) EntityImports() []string {
+	return s.entityImports
+}
+
+const repositoryTemplate = `
+{{ define "repository" }}
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+
+package main
+
+import (
+	"context"
+	"errors"
+{{ range .EntityImports }}
+	"{{ . }}"{{ end }}
+)
+
+// ErrNotFound is returned by the Repository when no entity has the ID.
+var ErrNotFound = errors.New("not found")
+
+// Repository stores the entities of {{ .IFace }}. It is implemented by the
+// {{ .Repository }} adapter returned by newRepository, in repository_{{ .Repository }}.go.
+type Repository interface { {{ range .Entities }}
+	// Get{{ .Name }} returns the {{ .Name }} with the ID id, or ErrNotFound.
+	Get{{ .Name }}(ctx context.Context, id {{ .IDType }}) (*{{ .Type }}, error)
+	// List{{ .Plural }} returns at most limit {{ .Plural }} ordered by ID, skipping the first offset.
+	List{{ .Plural }}(ctx context.Context, limit, offset int) ([]{{ .Type }}, error)
+	// Create{{ .Name }} stores the new {{ .Name }} e.
+	Create{{ .Name }}(ctx context.Context, e *{{ .Type }}) error
+	// Update{{ .Name }} replaces the {{ .Name }} with the ID of e, or returns ErrNotFound.
+	Update{{ .Name }}(ctx context.Context, e *{{ .Type }}) error
+	// Delete{{ .Name }} deletes the {{ .Name }} with the ID id, or returns ErrNotFound.
+	Delete{{ .Name }}(ctx context.Context, id {{ .IDType }}) error
+{{ end }}}
+{{ end }}
+
+{{ define "repositorysqlc" }}package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+{{ range .EntityImports }}
+	"{{ . }}"{{ end }}
+)
+
+// errNotImplemented is returned by the methods of the repository until
+// they are implemented.
+var errNotImplemented = errors.New("not implemented")
+
+// newRepository returns the Repository of the service. KitBoiler doesn't
+// overwrite this file once it exists.
+//
+// Its methods are meant to call the queries generated by sqlc
+// (https://sqlc.dev) from query.sql into a package of your choice, db
+// below: write the schema of the tables, run sqlc generate, import the
+// package and the driver named by cfg.DatabaseDriver, and replace the
+// bodies of the methods.
+func newRepository(cfg Config) (Repository, error) {
+	db, err := sql.Open(cfg.DatabaseDriver, cfg.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlcRepository{db: db}, nil
+}
+
+type sqlcRepository struct {
+	db *sql.DB // queried by db.New(r.db)
+}
+{{ range .Entities }}
+func (r *sqlcRepository) Get{{ .Name }}(ctx context.Context, id {{ .IDType }}) (*{{ .Type }}, error) {
+	// row, err := db.New(r.db).Get{{ .Name }}(ctx, id), with sql.ErrNoRows meaning ErrNotFound
+	return nil, errNotImplemented
+}
+
+func (r *sqlcRepository) List{{ .Plural }}(ctx context.Context, limit, offset int) ([]{{ .Type }}, error) {
+	// rows, err := db.New(r.db).List{{ .Plural }}(ctx, db.List{{ .Plural }}Params{Limit: int32(limit), Offset: int32(offset)})
+	return nil, errNotImplemented
+}
+
+func (r *sqlcRepository) Create{{ .Name }}(ctx context.Context, e *{{ .Type }}) error {
+	// err := db.New(r.db).Create{{ .Name }}(ctx, db.Create{{ .Name }}Params{...})
+	return errNotImplemented
+}
+
+func (r *sqlcRepository) Update{{ .Name }}(ctx context.Context, e *{{ .Type }}) error {
+	// n, err := db.New(r.db).Update{{ .Name }}(ctx, db.Update{{ .Name }}Params{...}), with n == 0 meaning ErrNotFound
+	return errNotImplemented
+}
+
+func (r *sqlcRepository) Delete{{ .Name }}(ctx context.Context, id {{ .IDType }}) error {
+	// n, err := db.New(r.db).Delete{{ .Name }}(ctx, id), with n == 0 meaning ErrNotFound
+	return errNotImplemented
+}
+{{ end }}{{ end }}
+
+{{ define "repositoryent" }}package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+{{ range .EntityImports }}
+	"{{ . }}"{{ end }}
+)
+
+// errNotImplemented is returned by the methods of the repository until
+// they are implemented.
+var errNotImplemented = errors.New("not implemented")
+
+// newRepository returns the Repository of the service. KitBoiler doesn't
+// overwrite this file once it exists.
+//
+// Its methods are meant to use a client generated by ent
+// (https://entgo.io): describe the entities with ent new{{ range .Entities }} {{ .Name }}{{ end }},
+// run go generate ./ent, open the client with
+// ent.Open(cfg.DatabaseDriver, cfg.DatabaseURL) instead of the database
+// below, and replace the bodies of the methods.
+func newRepository(cfg Config) (Repository, error) {
+	db, err := sql.Open(cfg.DatabaseDriver, cfg.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+	return &entRepository{db: db}, nil
+}
+
+type entRepository struct {
+	db *sql.DB // to be replaced by client *ent.Client
+}
+{{ range .Entities }}
+func (r *entRepository) Get{{ .Name }}(ctx context.Context, id {{ .IDType }}) (*{{ .Type }}, error) {
+	// e, err := r.client.{{ .Name }}.Get(ctx, id), with ent.IsNotFound(err) meaning ErrNotFound
+	return nil, errNotImplemented
+}
+
+func (r *entRepository) List{{ .Plural }}(ctx context.Context, limit, offset int) ([]{{ .Type }}, error) {
+	// es, err := r.client.{{ .Name }}.Query().Order(ent.Asc("{{ index .Columns 0 }}")).Limit(limit).Offset(offset).All(ctx)
+	return nil, errNotImplemented
+}
+
+func (r *entRepository) Create{{ .Name }}(ctx context.Context, e *{{ .Type }}) error {
+	// _, err := r.client.{{ .Name }}.Create().Set...(e....).Save(ctx)
+	return errNotImplemented
+}
+
+func (r *entRepository) Update{{ .Name }}(ctx context.Context, e *{{ .Type }}) error {
+	// err := r.client.{{ .Name }}.UpdateOneID(e.{{ .ID }}).Set...(e....).Exec(ctx), with ent.IsNotFound(err) meaning ErrNotFound
+	return errNotImplemented
+}
+
+func (r *entRepository) Delete{{ .Name }}(ctx context.Context, id {{ .IDType }}) error {
+	// err := r.client.{{ .Name }}.DeleteOneID(id).Exec(ctx), with ent.IsNotFound(err) meaning ErrNotFound
+	return errNotImplemented
+}
+{{ end }}{{ end }}
+
+{{ define "querysql" }}-- Queries of the Repository of {{ .IFace }}, for sqlc (https://sqlc.dev).
+-- KitBoiler doesn't overwrite this file once it exists.
+{{ range .Entities }}
+-- name: Get{{ .Name }} :one
+SELECT * FROM {{ .Table }} WHERE {{ index .Columns 0 }} = $1;
+
+-- name: List{{ .Plural }} :many
+SELECT * FROM {{ .Table }} ORDER BY {{ index .Columns 0 }} LIMIT $1 OFFSET $2;
+
+-- name: Create{{ .Name }} :exec
+INSERT INTO {{ .Table }} ({{ range $i, $c := .Columns }}{{ if $i }}, {{ end }}{{ $c }}{{ end }}) VALUES ({{ .Placeholders }});
+{{ if .Assignments }}
+-- name: Update{{ .Name }} :execrows
+UPDATE {{ .Table }} SET {{ .Assignments }} WHERE {{ index .Columns 0 }} = $1;
+{{ end }}
+-- name: Delete{{ .Name }} :execrows
+DELETE FROM {{ .Table }} WHERE {{ index .Columns 0 }} = $1;
+{{ end }}{{ end }}
+`
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// routers are the import paths of the routers of -router, by name.
+var routers = map[string]string{
+	"chi": "github.com/go-chi/chi/v5",
+	"mux": "github.com/gorilla/mux",
+}
+
+// httpMethods are the HTTP methods of kit:http routes, by whether their
+// requests have a body.
+var httpMethods = map[string]bool{
+	"GET":    false,
+	"DELETE": false,
+	"POST":   true,
+	"PUT":    true,
+	"PATCH":  true,
+}
+
+// routeVar matches the variables of the path of a route, e.g. {id}.
+var routeVar = regexp.MustCompile(`\{([^{}/]*)\}`)
+
+// checkRouter returns an error if router isn't one of -router.
+func checkRouter(router string) error {
+	if _, ok := routers[router]; !ok && router != "" {
+		return fmt.Errorf("-router: unknown router %q, want chi or mux", router)
+	}
+	return nil
+}
+
+// resolveRoutes sets the HTTP method and path of the methods of fns with a
+// "//kit:http <METHOD> <path>" annotation. The variables of the path, such
+// as {id}, name parameters of the method, decoded from the path by router.
+func resolveRoutes(fns []Func, router string) error {
+	if err := checkRouter(router); err != nil {
+		return err
+	}
+	routes := map[string]string{}
+	for i := range fns {
+		fn := &fns[i]
+		a, ok := fn.Annotation("http")
+		if !ok {
+			continue
+		}
+		if len(a.Args) != 2 {
+			return fn.errorf(a, "kit:http takes a method and a path, e.g. kit:http GET /users/{id}")
+		}
+		method, path := a.Args[0], a.Args[1]
+		if _, ok := httpMethods[method]; !ok {
+			return fn.errorf(a, "kit:http: unsupported method %q, want GET, POST, PUT, PATCH or DELETE", method)
+		}
+		if !strings.HasPrefix(path, "/") {
+			return fn.errorf(a, "kit:http: path %q doesn't start with /", path)
+		}
+		vars := map[string]bool{}
+		for _, m := range routeVar.FindAllStringSubmatch(path, -1) {
+			if router == "" {
+				return fn.errorf(a, "kit:http %s: path variables require -router chi or mux", path)
+			}
+			if vars[m[1]] {
+				return fn.errorf(a, "kit:http %s: variable {%s} used twice", path, m[1])
+			}
+			vars[m[1]] = true
+			p := fn.param(m[1])
+			if p == nil {
+				return fn.errorf(a, "kit:http %s: no parameter %s", path, m[1])
+			}
+			if IsOptionSetter(p.Type) {
+				return fn.errorf(a, "kit:http %s: option setters can't be path variables", path)
+			}
+			p.PathVar = true
+		}
+		route := method + " " + path
+		if other, ok := routes[route]; ok {
+			return fn.errorf(a, "kit:http: route %s is taken by %s", route, other)
+		}
+		routes[route] = fn.Name
+		fn.HTTPMethod, fn.HTTPPath = method, path
+	}
+	if router == "" {
+		return checkPaths(fns)
+	}
+	return nil
+}
+
+// checkPaths returns an error if two methods of fns are served on the same
+// path, which the http.ServeMux of MakeHTTPHandler can't tell apart by
+// method.
+func checkPaths(fns []Func) error {
+	paths := map[string]string{}
+	for i := range fns {
+		fn := &fns[i]
+		if other, ok := paths[fn.HTTPPath]; ok {
+			a, _ := fn.Annotation("http")
+			return fn.errorf(a, "path %s is taken by %s, serving several methods on a path requires -router chi or mux", fn.HTTPPath, other)
+		}
+		paths[fn.HTTPPath] = fn.Name
+	}
+	return nil
+}
+
+// resolveGetMethods serves the methods of fns matching the comma separated
+// names or path.Match patterns of list, e.g. "Get*,List*", on GET, at their
+// default path unless they have a kit:http route.
+func resolveGetMethods(fns []Func, list string) error {
+	if list == "" {
+		return nil
+	}
+	for _, pattern := range strings.Split(list, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("-get-methods: invalid pattern %q", pattern)
+		}
+		matched := false
+		for i := range fns {
+			fn := &fns[i]
+			if ok, _ := path.Match(pattern, fn.Name); !ok {
+				continue
+			}
+			matched = true
+			if a, ok := fn.Annotation("http"); ok && fn.HTTPMethod != "GET" {
+				return fn.errorf(a, "-get-methods: %s is routed on %s by kit:http", fn.Name, fn.HTTPMethod)
+			}
+			fn.HTTPMethod = "GET"
+		}
+		if !matched {
+			return fmt.Errorf("-get-methods: no method matches %q", pattern)
+		}
+	}
+	return nil
+}
+
+// param returns the parameter of fn named name, other than a context.
+func (fn *Func) param(name string) *Param {
+	for i := range fn.Params {
+		if fn.Params[i].Name == name && fn.Params[i].Type != "context.Context" {
+			return &fn.Params[i]
+		}
+	}
+	return nil
+}
+
+// HasBody reports whether the requests of f carry the request as a JSON
+// body. The parameters of GET and DELETE routes are passed as path
+// variables and query parameters instead.
+func (f Func) HasBody() bool {
+	return httpMethods[f.HTTPMethod]
+}
+
+// QueryParams returns the parameters of f passed as query parameters.
+func (f Func) QueryParams() []Param {
+	if f.HasBody() {
+		return nil
+	}
+	var params []Param
+	for _, p := range f.Params {
+		if !p.PathVar && p.Type != "context.Context" {
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+// UsesRouteVars reports whether any request is decoded from path variables
+// or query parameters.
+func (s Service) UsesRouteVars() bool {
+	for _, f := range s.Funcs {
+		if len(f.QueryParams()) > 0 {
+			return true
+		}
+		for _, p := range f.Params {
+			if p.PathVar {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NewRouter returns the expression creating the router of the routes.
+func (s Service) NewRouter() string {
+	switch s.Router {
+	case "chi":
+		return "chi.NewRouter()"
+	case "mux":
+		return "mux.NewRouter()"
+	}
+	return "http.NewServeMux()"
+}
+
+// RouterType returns the type of the router the routes are registered on.
+func (s Service) RouterType() string {
+	if s.Router == "chi" {
+		return "chi.Router"
+	}
+	return "*mux.Router"
+}
+
+// PathVar returns the expression of the value of the path variable name
+// of a request r.
+func (s Service) PathVar(name string) string {
+	if s.Router == "chi" {
+		return fmt.Sprintf("chiURLParam(r, %q)", name)
+	}
+	return fmt.Sprintf("mux.Vars(r)[%q]", name)
+}
+
+const routeTemplate = `
+{{ define "register" }}{{ $svc := . }}{{ range .Funcs }}
+// Register{{ .Name }}Route registers the HTTP handler of {{ .Name }} on r, serving
+// {{ .HTTPMethod }} {{ .HTTPPath }}.
+func Register{{ .Name }}Route(r {{ $svc.RouterType }}, svc {{ $svc.IFace }}) { {{ if eq $svc.Router "chi" }}{{ if $svc.OptionsHead }}
+	r.Handle("{{ .HTTPPath }}", {{ $svc.HTTPHandler . }}){{ else }}
+	r.Method("{{ .HTTPMethod }}", "{{ .HTTPPath }}", {{ $svc.HTTPHandler . }}){{ end }}{{ else }}{{ if $svc.OptionsHead }}
+	r.Path("{{ .HTTPPath }}").Handler({{ $svc.HTTPHandler . }}){{ else }}
+	r.Methods("{{ .HTTPMethod }}").Path("{{ .HTTPPath }}").Handler({{ $svc.HTTPHandler . }}){{ end }}{{ end }}
+}
+{{ end }}{{ end }}
+
+{{ define "routevars" }}
+// RouteVarError reports a path variable or query parameter that couldn't be
+// decoded into its request field. Its status code is 400 Bad Request.
+type RouteVarError struct {
+	Name string
+	Err  error
+}
+
+func (e RouteVarError) Error() string { return "invalid " + e.Name + ": " + e.Err.Error() }
+
+// StatusCode makes the error encoder respond with 400 Bad Request.
+func (RouteVarError) StatusCode() int { return http.StatusBadRequest }
+
+{{ if eq .Router "chi" }}
+// chiURLParam returns the value of the path variable name of r, unescaped,
+// as chi matches the escaped path of r when it isn't the default encoding
+// of its path, e.g. for an escaped /.
+func chiURLParam(r *http.Request, name string) string {
+	value := chi.URLParam(r, name)
+	if r.URL.RawPath == "" {
+		return value
+	}
+	if unescaped, err := url.PathUnescape(value); err == nil {
+		return unescaped
+	}
+	return value
+}
+{{ end }}
+// decodeRouteVar decodes value, the value of the path variable or query
+// parameter name, into dst, a pointer to a request field.
+func decodeRouteVar(name, value string, dst interface{}) error {
+	if err := decodeRouteValue(value, reflect.ValueOf(dst).Elem()); err != nil {
+		return RouteVarError{Name: name, Err: err}
+	}
+	return nil
+}
+
+// decodeQueryParam decodes values, the values of the query parameter name,
+// into dst, a pointer to a request field: every value into an element of a
+// slice, e.g. ?id=1&id=2, the first one into anything else.
+func decodeQueryParam(name string, values []string, dst interface{}) error {
+	v := reflect.ValueOf(dst).Elem()
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		return decodeRouteVar(name, values[0], dst)
+	}
+	s := reflect.MakeSlice(v.Type(), len(values), len(values))
+	for i, value := range values {
+		if err := decodeRouteValue(value, s.Index(i)); err != nil {
+			return RouteVarError{Name: name, Err: err}
+		}
+	}
+	v.Set(s)
+	return nil
+}
+
+// decodeRouteValue decodes value into v: by its UnmarshalText method if it
+// has one, e.g. a time.Time in RFC 3339 format, by parsing value if v is a
+// string, bool, number or time.Duration, e.g. 1m30s, and as JSON otherwise,
+// falling back to a JSON string.
+func decodeRouteValue(value string, v reflect.Value) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == reflect.TypeOf(time.Duration(0)) {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			v.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Ptr:
+		p := reflect.New(v.Type().Elem())
+		if err := decodeRouteValue(value, p.Elem()); err != nil {
+			return err
+		}
+		v.Set(p)
+	default:
+		if err := json.Unmarshal([]byte(value), v.Addr().Interface()); err != nil {
+			if json.Unmarshal([]byte(strconv.Quote(value)), v.Addr().Interface()) != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+{{ end }}
+`
@@ -0,0 +1,122 @@
+package main
+
+// routingImports are the imports required by the routing middleware, by
+// path, with math/rand renamed not to collide with crypto/rand.
+var routingImports = map[string]string{"context": "", "hash/fnv": "", "math/rand": "mathrand", "net/http": ""}
+
+const routingTemplate = `
+{{ define "routing" }}
+// Route reports whether a call of method, with ctx, is routed to the second
+// implementation of RoutingMiddleware rather than to the service.
+type Route func(ctx context.Context, method string) bool
+
+// RoutingMiddleware returns a service middleware dispatching every call of a
+// method of {{ .IFace }} to b if route returns true for it, and to the service
+// otherwise, e.g. to migrate to a rewrite of the service incrementally:
+//
+//	svc = RoutingMiddleware(rewrite, RouteAny(RouteHeader("X-Rewrite"), RoutePercent(10, nil)))(svc)
+func RoutingMiddleware(b {{ .IFace }}, route Route) func({{ .IFace }}) {{ .IFace }} {
+	return func(next {{ .IFace }}) {{ .IFace }} {
+		return routingMiddleware{next, b, route}
+	}
+}
+
+// RouteAny routes the calls any of routes routes.
+func RouteAny(routes ...Route) Route {
+	return func(ctx context.Context, method string) bool {
+		for _, route := range routes {
+			if route(ctx, method) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RoutePercent routes percent of the calls, from 0 to 100: by the hash of
+// the key returned by key, if not nil, so that the calls with the same key,
+// e.g. of the same user, are routed alike, and at random otherwise.
+func RoutePercent(percent float64, key func(context.Context) (string, bool)) Route {
+	return func(ctx context.Context, _ string) bool {
+		if key != nil {
+			if k, ok := key(ctx); ok {
+				h := fnv.New32a()
+				h.Write([]byte(k))
+				return float64(h.Sum32()%10000) < percent*100
+			}
+		}
+		return mathrand.Float64()*100 < percent
+	}
+}
+
+// RouteHeader routes the calls whose HTTP request{{ if .GRPC }}, or gRPC metadata,{{ end }} has the header
+// name with one of values, or with any value if none is given.
+func RouteHeader(name string, values ...string) Route {
+	return func(ctx context.Context, _ string) bool {
+		for _, got := range requestHeader(ctx, name) {
+			if len(values) == 0 {
+				return true
+			}
+			for _, v := range values {
+				if got == v {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+{{ if .Tenant }}
+// RouteTenants routes the calls of tenants.
+func RouteTenants(tenants ...string) Route {
+	return func(ctx context.Context, _ string) bool {
+		tenant, ok := TenantFromContext(ctx)
+		if !ok {
+			return false
+		}
+		for _, t := range tenants {
+			if t == tenant {
+				return true
+			}
+		}
+		return false
+	}
+}
+{{ end }}
+type headerKey struct{}
+
+// headerToContext stores the header of the requests to h in their context,
+// for RouteHeader.
+func headerToContext(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), headerKey{}, r.Header)))
+	})
+}
+
+// requestHeader returns the values of the header name of the request ctx
+// belongs to.
+func requestHeader(ctx context.Context, name string) []string {
+	if header, ok := ctx.Value(headerKey{}).(http.Header); ok {
+		return header[http.CanonicalHeaderKey(name)]
+	}{{ if .GRPC }}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		return md.Get(name)
+	}{{ end }}
+	return nil
+}
+
+type routingMiddleware struct {
+	{{ .IFace }}
+	b     {{ .IFace }}
+	route Route
+}
+{{ range $fun := .Funcs }}
+func (mw routingMiddleware) {{ .Name }}{{ Signature . }} {
+	if mw.route({{ ContextArg . }}, "{{ .Name }}") {
+		{{ if .Res }}return {{ end }}mw.b.{{ .Name }}({{ CallArgs . }}){{ if not .Res }}
+		return{{ end }}
+	}
+	{{ if .Res }}return {{ end }}mw.{{ $.IFaceName }}.{{ .Name }}({{ CallArgs . }})
+}
+{{ end }}{{ end }}
+`
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// CommandName returns the name of the generated command serving the
+// service, e.g. "user-service".
+func (s Service) CommandName() string {
+	return kebabCase(s.IFaceName())
+}
+
+// EnvPrefix returns the prefix of the environment variables configuring the
+// generated command, e.g. "USER_SERVICE_".
+func (s Service) EnvPrefix() string {
+	return strings.ToUpper(snakeCase(s.IFaceName())) + "_"
+}
+
+// ServiceImports returns the imports of the implementation of the service
+// in the scaffolded command, in groups: the standard library, go-kit and
+// the others. The methods implemented on top of the repository require the
+// imports of their signatures.
+func (s Service) ServiceImports() [][]string {
+	groups := [][]string{{"errors"}, {"github.com/go-kit/kit/log"}, {s.IFacePath}}
+	if s.Repository != "" {
+		for _, i := range s.MockImports() {
+			if i == s.IFacePath {
+				continue
+			}
+			if strings.Contains(strings.Split(i, "/")[0], ".") {
+				groups[2] = append(groups[2], i)
+			} else {
+				groups[0] = append(groups[0], i)
+			}
+		}
+	}
+	for _, g := range groups {
+		sort.Strings(g)
+	}
+	return groups
+}
+
+const scaffoldTemplate = `
+{{ define "scaffold" }}
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+
+// Command {{ .CommandName }} serves {{ .IFace }} over HTTP. The implementation
+// of the service is returned by newService, in service.go.{{ if .GRPCWeb }} It serves the gRPC
+// server to browsers over gRPC-Web as well, on the listener of the HTTP handlers.{{ end }}{{ if .Discovery }}
+// It registers itself in Consul while serving.{{ end }}
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"{{ if .Discovery }}
+	"net"{{ end }}
+	"net/http"
+	"os"
+	"os/signal"{{ if .Discovery }}
+	"strconv"{{ end }}
+	"syscall"
+	"time"
+
+	"github.com/go-kit/kit/log"{{ if .Discovery }}
+	consulsd "github.com/go-kit/kit/sd/consul"
+	consulapi "github.com/hashicorp/consul/api"{{ end }}{{ if .Instrumenting }}
+	"github.com/prometheus/client_golang/prometheus"{{ end }}{{ if or .UsesPrometheus .Instrumenting }}
+	"github.com/prometheus/client_golang/prometheus/promhttp"{{ end }}{{ if or .UsesOTelMetrics .Tracing }}
+	"go.opentelemetry.io/otel"{{ end }}{{ if .Tracing }}
+	"go.opentelemetry.io/otel/propagation"{{ end }}
+
+	{{ .Pkg }} "{{ .ImportPath }}"
+)
+
+// envPrefix is the prefix of the environment variables configuring the
+// command.
+const envPrefix = "{{ .EnvPrefix }}"
+
+// Config is the configuration of the command, read from the {{ .EnvPrefix }}*
+// environment variables and overridden by the command line flags.
+type Config struct {
+	Addr            string        // listen address{{ range .Listeners }}
+	{{ .Addr }} string // listen address of the {{ .Name }} group{{ end }}{{ if .Discovery }}
+	AdvertiseAddr   string        // address registered in Consul, the hostname and the port of Addr if empty{{ end }}
+	ReadTimeout     time.Duration // to read a request, including its body
+	WriteTimeout    time.Duration // to write a response
+	IdleTimeout     time.Duration // to wait for the next request on a keep-alive connection
+	HandlerTimeout  time.Duration // to handle a request, after which it fails with 503 Service Unavailable
+	ShutdownTimeout time.Duration // to finish the requests in flight when shutting down{{ if .HotReload }}
+	ConfigFile      string        // JSON file of the DynamicConfig, reloaded when it changes
+	ReloadInterval  time.Duration // to check whether the DynamicConfig changed{{ end }}{{ if .Repository }}
+	DatabaseDriver  string        // name of the database/sql driver of the repository
+	DatabaseURL     string        // data source name of the repository{{ end }}{{ if .Replay }}
+	Replay          bool          // serve the replay handler on /debug/replay, for development only{{ end }}
+}
+
+// loadConfig returns the configuration set by the environment and args.
+func loadConfig(args []string) (Config, error) {
+	cfg := Config{
+		Addr:            ":8080",{{ range .Listeners }}
+		{{ .Addr }}: "{{ .DefaultAddr }}",{{ end }}
+		ReadTimeout:     5 * time.Second,
+		WriteTimeout:    10 * time.Second,
+		IdleTimeout:     2 * time.Minute,
+		HandlerTimeout:  9 * time.Second,
+		ShutdownTimeout: 15 * time.Second,{{ if .HotReload }}
+		ReloadInterval:  5 * time.Second,{{ end }}{{ if .Repository }}
+		DatabaseDriver:  "postgres",{{ end }}
+	}
+	if v, ok := os.LookupEnv(envPrefix + "ADDR"); ok {
+		cfg.Addr = v
+	}{{ range .Listeners }}
+	if v, ok := os.LookupEnv(envPrefix + "{{ .Env }}"); ok {
+		cfg.{{ .Addr }} = v
+	}{{ end }}{{ if .Discovery }}
+	if v, ok := os.LookupEnv(envPrefix + "ADVERTISE_ADDR"); ok {
+		cfg.AdvertiseAddr = v
+	}{{ end }}{{ if .HotReload }}
+	if v, ok := os.LookupEnv(envPrefix + "CONFIG_FILE"); ok {
+		cfg.ConfigFile = v
+	}{{ end }}{{ if .Repository }}
+	if v, ok := os.LookupEnv(envPrefix + "DATABASE_DRIVER"); ok {
+		cfg.DatabaseDriver = v
+	}
+	if v, ok := os.LookupEnv(envPrefix + "DATABASE_URL"); ok {
+		cfg.DatabaseURL = v
+	}{{ end }}
+	durations := []struct {
+		name string
+		d    *time.Duration
+	}{
+		{"READ_TIMEOUT", &cfg.ReadTimeout},
+		{"WRITE_TIMEOUT", &cfg.WriteTimeout},
+		{"IDLE_TIMEOUT", &cfg.IdleTimeout},
+		{"HANDLER_TIMEOUT", &cfg.HandlerTimeout},
+		{"SHUTDOWN_TIMEOUT", &cfg.ShutdownTimeout},{{ if .HotReload }}
+		{"RELOAD_INTERVAL", &cfg.ReloadInterval},{{ end }}
+	}
+	for _, d := range durations {
+		if v, ok := os.LookupEnv(envPrefix + d.name); ok {
+			var err error
+			if *d.d, err = time.ParseDuration(v); err != nil {
+				return cfg, fmt.Errorf("%s%s: %v", envPrefix, d.name, err)
+			}
+		}
+	}
+
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.StringVar(&cfg.Addr, "addr", cfg.Addr, "listen ` + "`address`" + ` ($"+envPrefix+"ADDR)"){{ range .Listeners }}
+	fs.StringVar(&cfg.{{ .Addr }}, "{{ .Name }}-addr", cfg.{{ .Addr }}, "listen ` + "`address`" + ` of the {{ .Name }} group ($"+envPrefix+"{{ .Env }})"){{ end }}{{ if .Discovery }}
+	fs.StringVar(&cfg.AdvertiseAddr, "advertise-addr", cfg.AdvertiseAddr, "` + "`address`" + ` registered in Consul ($"+envPrefix+"ADVERTISE_ADDR)"){{ end }}
+	fs.DurationVar(&cfg.ReadTimeout, "read-timeout", cfg.ReadTimeout, "time to read a request ($"+envPrefix+"READ_TIMEOUT)")
+	fs.DurationVar(&cfg.WriteTimeout, "write-timeout", cfg.WriteTimeout, "time to write a response ($"+envPrefix+"WRITE_TIMEOUT)")
+	fs.DurationVar(&cfg.IdleTimeout, "idle-timeout", cfg.IdleTimeout, "time to wait for the next request ($"+envPrefix+"IDLE_TIMEOUT)")
+	fs.DurationVar(&cfg.HandlerTimeout, "handler-timeout", cfg.HandlerTimeout, "time to handle a request ($"+envPrefix+"HANDLER_TIMEOUT)")
+	fs.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "time to finish the requests in flight ($"+envPrefix+"SHUTDOWN_TIMEOUT)"){{ if .HotReload }}
+	fs.StringVar(&cfg.ConfigFile, "config-file", cfg.ConfigFile, "JSON ` + "`file`" + ` of the configuration reloaded without a restart ($"+envPrefix+"CONFIG_FILE)")
+	fs.DurationVar(&cfg.ReloadInterval, "reload-interval", cfg.ReloadInterval, "time between checks of the -config-file ($"+envPrefix+"RELOAD_INTERVAL)"){{ end }}{{ if .Repository }}
+	fs.StringVar(&cfg.DatabaseDriver, "database-driver", cfg.DatabaseDriver, "database/sql ` + "`driver`" + ` of the repository ($"+envPrefix+"DATABASE_DRIVER)")
+	fs.StringVar(&cfg.DatabaseURL, "database-url", cfg.DatabaseURL, "data source ` + "`name`" + ` of the repository ($"+envPrefix+"DATABASE_URL)"){{ end }}{{ if .Replay }}
+	fs.BoolVar(&cfg.Replay, "replay", false, "serve /debug/replay, replaying recorded requests on the service bypassing authorization; never in production"){{ end }}
+	return cfg, fs.Parse(args)
+}
+
+func main() {
+	logger := log.NewJSONLogger(log.NewSyncWriter(os.Stderr))
+	logger = log.With(logger, "ts", log.DefaultTimestampUTC)
+
+	cfg, err := loadConfig(os.Args[1:])
+	if err != nil {
+		logger.Log("err", err)
+		os.Exit(2)
+	}{{ if .HotReload }}
+	src, err := newConfigSource(cfg)
+	var dynamic DynamicConfig
+	if err == nil && src != nil {
+		dynamic, err = src.Load(context.Background())
+	}
+	if err == nil {
+		err = applyConfig(cfg, dynamic)
+	}
+	if err != nil {
+		logger.Log("err", err)
+		os.Exit(2)
+	}
+	if src != nil {
+		go watchConfig(context.Background(), cfg, src, dynamic, logger)
+	}{{ end }}
+	svc, err := newService(cfg, logger)
+	if err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}{{ if .Logging }}
+	svc = {{ .Pkg }}.LoggingMiddleware(log.With(logger, "component", "service"))(svc){{ end }}{{ if .Instrumenting }}
+	instrumenting, err := {{ .Pkg }}.NewPrometheusInstrumentingMiddleware(prometheus.DefaultRegisterer)
+	if err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}
+	svc = instrumenting(svc){{ end }}
+{{ if .UsesLogger }}
+	{{ .Pkg }}.Logger = logger{{ end }}{{ if .UsesPrometheus }}
+	{{ .Pkg }}.UsePrometheusMetrics(){{ end }}{{ if .UsesOTelMetrics }}
+	// the metrics are exported by the global MeterProvider, which discards
+	// them until set, e.g. to one with an OTLP exporter in newService
+	if err := {{ .Pkg }}.UseOTelMetrics(otel.Meter("{{ .ImportPath }}")); err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}{{ end }}{{ if .Tracing }}
+	// the spans of the endpoints continue the traces propagated by the W3C
+	// Trace Context headers of the requests, and are exported by the global
+	// TracerProvider, which discards them until set, e.g. to one with an OTLP
+	// exporter in newService
+	otel.SetTextMapPropagator(propagation.TraceContext{}){{ end }}
+
+	mux := http.NewServeMux(){{ $h := printf "%s.MakeHTTPHandler(svc)" .Pkg }}{{ if .GRPCWeb }}{{ $h = printf "%s.MakeGRPCWebHandler(svc, %s)" .Pkg $h }}{{ end }}
+	mux.Handle("/", {{ if .HotReload }}withHandlerTimeout({{ $h }}){{ else }}http.TimeoutHandler({{ $h }}, cfg.HandlerTimeout, ""){{ end }}){{ if or .UsesPrometheus .Instrumenting }}
+	mux.Handle("/metrics", promhttp.Handler()){{ end }}{{ if .Replay }}
+	if cfg.Replay {
+		logger.Log("msg", "serving /debug/replay, which bypasses authorization")
+		mux.Handle("/debug/replay", {{ .Pkg }}.NewReplayHandler(svc))
+	}{{ end }}
+{{ if .Admin }}
+	admin := http.NewServeMux(){{ if .AdminListener.Funcs }}
+	admin.Handle("/", {{ if .HotReload }}withHandlerTimeout({{ .Pkg }}.MakeAdminHTTPHandler(svc)){{ else }}http.TimeoutHandler({{ .Pkg }}.MakeAdminHTTPHandler(svc), cfg.HandlerTimeout, ""){{ end }}){{ end }}
+	switches := {{ .Pkg }}.MakeSwitchesHandler()
+	admin.Handle("/switches", switches)
+	admin.Handle("/switches/", switches){{ end }}
+	servers := []*http.Server{
+		newServer(cfg, cfg.Addr, mux),{{ range .Listeners }}
+		newServer(cfg, cfg.{{ .Addr }}, {{ if and $.Admin (eq .Name "admin") }}admin{{ else if $.HotReload }}withHandlerTimeout({{ $.Pkg }}.{{ .Handler }}(svc)){{ else }}http.TimeoutHandler({{ $.Pkg }}.{{ .Handler }}(svc), cfg.HandlerTimeout, ""){{ end }}),{{ end }}
+	}
+
+	errc := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func(srv *http.Server) {
+			logger.Log("msg", "listening", "addr", srv.Addr)
+			errc <- srv.ListenAndServe()
+		}(srv)
+	}{{ if .Discovery }}
+	registrar, err := newRegistrar(cfg, logger)
+	if err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}
+	registrar.Register(){{ end }}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	select {
+	case err := <-errc:
+		logger.Log("err", err)
+		os.Exit(1)
+	case s := <-sig:
+		logger.Log("msg", "shutting down", "signal", s)
+	}
+{{ if .Discovery }}	registrar.Deregister()
+{{ end }}
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	failed := false
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			logger.Log("err", err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// newServer returns the server of h on addr.
+func newServer(cfg Config, addr string, h http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         addr,
+		Handler:      h,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+}
+{{ if .Discovery }}{{ template "registrar" . }}{{ end }}{{ end }}
+
+{{ define "scaffoldservice" }}package main
+
+import ({{ range $i, $group := .ServiceImports }}{{ if $i }}
+{{ end }}{{ range $group }}
+	"{{ . }}"{{ end }}{{ end }}
+)
+{{ if .Repository }}
+// newService returns the implementation of {{ .IFace }} served by the
+// command, on top of the Repository returned by newRepository. KitBoiler
+// doesn't overwrite this file once it exists.
+func newService(cfg Config, logger log.Logger) ({{ .IFace }}, error) {
+	repo, err := newRepository(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &service{repo: repo, logger: logger}, nil
+}
+
+type service struct {
+	repo   Repository
+	logger log.Logger
+}
+{{ range $f := .AllFuncs }}
+func (s *service) {{ .Name }}{{ Signature . }} { {{ with ErrorName . }}
+	{{ . }} = errors.New("{{ $f.Name }}: not implemented"){{ end }}
+	return
+}
+{{ end }}{{ else }}
+// newService returns the implementation of {{ .IFace }} served by the
+// command. KitBoiler doesn't overwrite this file once it exists.
+func newService(cfg Config, logger log.Logger) ({{ .IFace }}, error) {
+	return nil, errors.New("newService: not implemented")
+}
+{{ end }}{{ end }}
+`
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Scalar describes how a type encoded as a single JSON value, such as a
+// UUID or a decimal, is represented in API specs and fixtures.
+type Scalar struct {
+	Type    string `yaml:"type"`    // OpenAPI type
+	Format  string `yaml:"format"`  // OpenAPI format
+	Proto   string `yaml:"proto"`   // proto type
+	Example string `yaml:"example"` // example value, as JSON
+}
+
+// scalars holds the known scalars by fully qualified type, e.g.
+// "github.com/google/uuid.UUID". More can be registered with -scalars.
+var scalars = map[string]Scalar{
+	"time.Time":                             {Type: "string", Format: "date-time", Proto: "google.protobuf.Timestamp", Example: `"2006-01-02T15:04:05Z"`},
+	"github.com/google/uuid.UUID":           {Type: "string", Format: "uuid", Proto: "string", Example: `"3fa85f64-5717-4562-b3fc-2c963f66afa6"`},
+	"github.com/gofrs/uuid.UUID":            {Type: "string", Format: "uuid", Proto: "string", Example: `"3fa85f64-5717-4562-b3fc-2c963f66afa6"`},
+	"github.com/shopspring/decimal.Decimal": {Type: "string", Format: "decimal", Proto: "string", Example: `"12.34"`},
+	"cloud.google.com/go/civil.Date":        {Type: "string", Format: "date", Proto: "string", Example: `"2006-01-02"`},
+	"cloud.google.com/go/civil.Time":        {Type: "string", Format: "time", Proto: "string", Example: `"15:04:05"`},
+	"cloud.google.com/go/civil.DateTime":    {Type: "string", Format: "date-time", Proto: "string", Example: `"2006-01-02T15:04:05"`},
+}
+
+// loadScalars registers the scalars in the YAML file at path, a map from
+// fully qualified type to Scalar, overriding known ones. The type and proto
+// type of a scalar default to string.
+func loadScalars(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var table map[string]Scalar
+	if err := yaml.Unmarshal(data, &table); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	for typ, s := range table {
+		if strings.LastIndex(typ, ".") <= strings.LastIndex(typ, "/") {
+			return fmt.Errorf("%s: %s: not a fully qualified type", path, typ)
+		}
+		if s.Type == "" {
+			s.Type = "string"
+		}
+		if s.Proto == "" {
+			s.Proto = "string"
+		}
+		scalars[typ] = s
+	}
+	return nil
+}
+
+// scalar returns the scalar of the (pointer to the) type typ, as used in the
+// package p, or nil if typ isn't a known scalar.
+func (p Pkg) scalar(typ string) *Scalar {
+	typ = strings.TrimPrefix(typ, "*")
+	dot := strings.Index(typ, ".")
+	if dot < 0 || strings.ContainsAny(typ, "[]() ") {
+		return nil
+	}
+	path := p.importPathOf(typ[:dot])
+	if path == "" {
+		return nil
+	}
+	if s, ok := scalars[path+typ[dot:]]; ok {
+		return &s
+	}
+	return nil
+}
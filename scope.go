@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// scopeImports are the imports required by the scope checks.
+var scopeImports = []string{"context", "strings"}
+
+// scopeRE matches valid scopes, such as orders:write or admin.
+var scopeRE = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*([:._/-][A-Za-z0-9]+)*$`)
+
+// resolveScopes sets the scopes a token needs to call the methods of fns
+// from their "//kit:scope <scope>..." annotations. A method annotated more
+// than once needs the scopes of every annotation.
+func resolveScopes(fns []Func) error {
+	consts := map[string]string{}
+	for i := range fns {
+		fn := &fns[i]
+		for _, a := range fn.Annotations {
+			if a.Name != "scope" {
+				continue
+			}
+			if len(a.Args) == 0 {
+				return fn.errorf(a, "kit:scope takes at least one scope")
+			}
+			for _, scope := range a.Args {
+				if !scopeRE.MatchString(scope) {
+					return fn.errorf(a, "kit:scope: %q isn't a valid scope, use letters and digits separated by : . _ / or -", scope)
+				}
+				name := scopeConst(scope)
+				if other, ok := consts[name]; ok && other != scope {
+					return fn.errorf(a, "kit:scope: %s and %s both make the constant scopes.%s", other, scope, name)
+				}
+				consts[name] = scope
+				if !hasString(fn.Scopes, scope) {
+					fn.Scopes = append(fn.Scopes, scope)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func hasString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// scopeConst returns the name of the constant of the scopes package holding
+// scope, e.g. OrdersWrite for orders:write.
+func scopeConst(scope string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(scope, func(r rune) bool { return strings.ContainsRune(":._/-", r) }) {
+		b.WriteString(Exported(word))
+	}
+	return b.String()
+}
+
+// Scope is a scope a token needs to call some methods, see kit:scope.
+type Scope struct {
+	Value   string   // e.g. orders:write
+	Const   string   // name of its constant in the scopes package, e.g. OrdersWrite
+	Methods []string // methods requiring it
+}
+
+// Description returns the description of s in the OpenAPI spec.
+func (s Scope) Description() string {
+	return "Required by " + strings.Join(s.Methods, ", ") + "."
+}
+
+// Scopes returns the scopes required by the methods of s, sorted.
+func (s Service) Scopes() []Scope {
+	index := map[string]int{}
+	var scopes []Scope
+	for _, f := range s.Funcs {
+		for _, scope := range f.Scopes {
+			i, ok := index[scope]
+			if !ok {
+				i = len(scopes)
+				index[scope] = i
+				scopes = append(scopes, Scope{Value: scope, Const: scopeConst(scope)})
+			}
+			scopes[i].Methods = append(scopes[i].Methods, f.Name)
+		}
+	}
+	sort.Slice(scopes, func(i, j int) bool { return scopes[i].Value < scopes[j].Value })
+	return scopes
+}
+
+// UsesScopes reports whether any method requires scopes.
+func (s Service) UsesScopes() bool {
+	for _, f := range s.Funcs {
+		if len(f.Scopes) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopeArgs returns the arguments of RequireScopes for f, the method name
+// followed by the constants of its scopes.
+func ScopeArgs(f Func) string {
+	args := []string{fmt.Sprintf("%q", f.Name)}
+	for _, scope := range f.Scopes {
+		args = append(args, "scopes."+scopeConst(scope))
+	}
+	return strings.Join(args, ", ")
+}
+
+const scopeTemplate = `
+{{ define "scopes" }}
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+
+// Package scopes holds the scopes the API keys and tokens calling
+// {{ .IFace }} need, as declared by the kit:scope annotations of its methods.
+package scopes
+
+const ({{ range .Scopes }}
+	{{ .Const }} = "{{ .Value }}" // {{ .Description }}{{ end }}
+)
+
+// All lists every scope, sorted.
+var All = []string{ {{ range .Scopes }}
+	{{ .Const }},{{ end }}
+}
+
+// ByMethod lists the scopes a token needs to call each method, by method
+// name. Methods missing from it need none.
+var ByMethod = map[string][]string{ {{ range .Funcs }}{{ if .Scopes }}
+	"{{ .Name }}": { {{ range .Scopes }}{{ ScopeConst . }}, {{ end }}},{{ end }}{{ end }}
+}
+{{ end }}
+
+{{ define "scope" }}
+type scopesKey struct{}
+
+// ContextWithScopes returns a copy of ctx carrying the scopes granted to the
+// token of its request.
+func ContextWithScopes(ctx context.Context, granted []string) context.Context {
+	return context.WithValue(ctx, scopesKey{}, granted)
+}
+
+// TokenScopes returns the scopes granted to the token of the request ctx
+// belongs to, or false if the request carries no token. By default it
+// returns those stored by ContextWithScopes, e.g. from an
+// httptransport.ServerBefore function verifying the token. Set it at init
+// time, before MakeHTTPHandler is called, to read them from elsewhere.
+var TokenScopes = func(ctx context.Context) ([]string, bool) {
+	granted, ok := ctx.Value(scopesKey{}).([]string)
+	return granted, ok
+}
+
+// ErrUnauthenticated is returned by the endpoints of the methods requiring
+// scopes when their request carries no token.
+var ErrUnauthenticated error = unauthenticatedError{}
+
+type unauthenticatedError struct{}
+
+func (unauthenticatedError) Error() string { return "request carries no token" }
+
+// StatusCode makes the error encoder respond with 401 Unauthorized.
+func (unauthenticatedError) StatusCode() int { return http.StatusUnauthorized }
+
+// InsufficientScopeError is returned by the endpoints of the methods
+// requiring scopes when the token of their request lacks some of them.
+type InsufficientScopeError struct {
+	Method  string
+	Missing []string
+}
+
+func (e InsufficientScopeError) Error() string {
+	return e.Method + ": token lacks scopes " + strings.Join(e.Missing, ", ")
+}
+
+// StatusCode makes the error encoder respond with 403 Forbidden.
+func (e InsufficientScopeError) StatusCode() int { return http.StatusForbidden }
+
+// RequireScopes rejects the requests to method whose token, as returned by
+// TokenScopes, lacks any of required.
+func RequireScopes(method string, required ...string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			granted, ok := TokenScopes(ctx)
+			if !ok {
+				return nil, ErrUnauthenticated
+			}
+			var missing []string
+		check:
+			for _, scope := range required {
+				for _, g := range granted {
+					if g == scope {
+						continue check
+					}
+				}
+				missing = append(missing, scope)
+			}
+			if len(missing) > 0 {
+				return nil, InsufficientScopeError{Method: method, Missing: missing}
+			}
+			return next(ctx, request)
+		}
+	}
+}
+{{ end }}
+`
@@ -0,0 +1,132 @@
+package main
+
+import "strings"
+
+// shadowImports are the imports required by the shadow middleware, by
+// path, with math/rand renamed not to collide with crypto/rand.
+var shadowImports = map[string]string{"context": "", "math/rand": "mathrand", "reflect": "", "time": ""}
+
+// ShadowResults returns the results of f, as named by Signature, other than
+// its error, which the shadow middleware compares.
+func ShadowResults(f Func) string {
+	var res []string
+	for i, r := range f.Res {
+		if r.Type != "error" {
+			res = append(res, ResultName(f, i))
+		}
+	}
+	return strings.Join(res, ", ")
+}
+
+// ShadowContext returns the name of the context parameter of the function
+// calling f on the shadow implementation: that of f, for its arguments to
+// pass it on, or a blank one if f takes no context.
+func ShadowContext(f Func) string {
+	for i, p := range f.Params {
+		if p.Type == "context.Context" {
+			return paramName(p, "p", i)
+		}
+	}
+	return "_"
+}
+
+// ShadowCall returns the statement calling f on the shadow implementation,
+// assigning its results to variables named as those of Signature.
+func ShadowCall(f Func) string {
+	call := "mw.shadow." + f.Name + "(" + CallArgs(f) + ")"
+	if len(f.Res) == 0 {
+		return call
+	}
+	var res []string
+	for i := range f.Res {
+		res = append(res, ResultName(f, i))
+	}
+	return strings.Join(res, ", ") + " := " + call
+}
+
+const shadowTemplate = `
+{{ define "shadow" }}
+// ShadowOptions configures ShadowMiddleware.
+type ShadowOptions struct {
+	Percent float64                     // of the calls mirrored to the shadow, from 0 to 100
+	Timeout time.Duration               // of the mirrored calls, 10s if 0
+	Equal   func(a, b interface{}) bool // compares the results, reflect.DeepEqual if nil
+	Report  func(ShadowMismatch)        // called with every mismatch, from the goroutine of the mirrored call
+}
+
+// ShadowMismatch is a mirrored call whose shadow returned other results, or
+// failed where the service succeeded or the other way round. The errors
+// themselves aren't compared.
+type ShadowMismatch struct {
+	Method        string
+	Args          []interface{} // keys and values of the parameters, kit:sensitive and kit:pii ones masked
+	Results       []interface{} // of the service, without the error
+	Err           error
+	ShadowResults []interface{}
+	ShadowErr     error
+}
+
+// ShadowMiddleware returns a service middleware mirroring opts.Percent of
+// the calls of every method of {{ .IFace }} to shadow, e.g. a rewrite of the
+// service, and reporting the calls whose results differ to opts.Report. The
+// shadow is called once the service returned, in a goroutine of its own,
+// with the values of the context of the call but not its cancellation, so
+// it doesn't slow the calls down, and its results are discarded. Mirror
+// methods with side effects only to a shadow with storage of its own.
+func ShadowMiddleware(shadow {{ .IFace }}, opts ShadowOptions) func({{ .IFace }}) {{ .IFace }} {
+	if opts.Timeout == 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.Equal == nil {
+		opts.Equal = reflect.DeepEqual
+	}
+	return func(next {{ .IFace }}) {{ .IFace }} {
+		return shadowMiddleware{next, shadow, opts}
+	}
+}
+
+type shadowMiddleware struct {
+	{{ .IFace }}
+	shadow {{ .IFace }}
+	opts   ShadowOptions
+}
+
+// mirror calls the shadow of a call of method in a goroutine with
+// opts.Percent chance, reporting a mismatch of its results with those of the
+// service.
+func (mw shadowMiddleware) mirror(ctx context.Context, method string, args, results []interface{}, err error, call func(context.Context) ([]interface{}, error)) {
+	if mathrand.Float64()*100 >= mw.opts.Percent {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(detachedContext{ctx}, mw.opts.Timeout)
+		defer cancel()
+		shadowResults, shadowErr := call(ctx)
+		if (err == nil) == (shadowErr == nil) && (err != nil || mw.opts.Equal(results, shadowResults)) {
+			return
+		}
+		if mw.opts.Report != nil {
+			mw.opts.Report(ShadowMismatch{method, args, results, err, shadowResults, shadowErr})
+		}
+	}()
+}
+
+// detachedContext carries the values of a context without its deadline and
+// cancellation.
+type detachedContext struct{ context.Context }
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+{{ range $fun := .Funcs }}
+func (mw shadowMiddleware) {{ .Name }}{{ Signature . }} {
+	defer func() {
+		mw.mirror({{ ContextArg . }}, "{{ .Name }}", []interface{}{ {{ LogKeyvals . }} }, []interface{}{ {{ ShadowResults . }} }, {{ or (ErrorName .) "nil" }}, func({{ ShadowContext . }} context.Context) ([]interface{}, error) {
+			{{ ShadowCall . }}
+			return []interface{}{ {{ ShadowResults . }} }, {{ or (ErrorName .) "nil" }}
+		})
+	}()
+	{{ if .Res }}return {{ end }}mw.{{ $.IFaceName }}.{{ .Name }}({{ CallArgs . }})
+}
+{{ end }}{{ end }}
+`
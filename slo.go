@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// prometheusImports are the imports required to set the HTTP metrics to
+// Prometheus metrics.
+var prometheusImports = map[string]string{
+	"github.com/go-kit/kit/metrics/prometheus":       "",
+	"github.com/prometheus/client_golang/prometheus": "stdprometheus",
+}
+
+// resolveSLOs sets the objective of every method of fns from its
+// "//kit:slo <percentage>" annotation, or to def if it has none.
+func resolveSLOs(fns []Func, def float64) error {
+	if def < 0 || def >= 100 {
+		return fmt.Errorf("-slo: objective must be a percentage below 100")
+	}
+	for i := range fns {
+		fn := &fns[i]
+		fn.SLO = def
+		a, ok := fn.Annotation("slo")
+		if !ok {
+			continue
+		}
+		if len(a.Args) != 1 {
+			return fn.errorf(a, "kit:slo takes exactly one objective")
+		}
+		o, err := strconv.ParseFloat(strings.TrimSuffix(a.Args[0], "%"), 64)
+		if err != nil || o <= 0 || o >= 100 {
+			return fn.errorf(a, "kit:slo: invalid objective %q, must be a percentage below 100", a.Args[0])
+		}
+		fn.SLO = o
+	}
+	return nil
+}
+
+// UsesSLOs reports whether any method has a service level objective.
+func (s Service) UsesSLOs() bool {
+	for _, f := range s.Funcs {
+		if f.SLO > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// UsesPrometheus reports whether the HTTP metrics get fixed Prometheus names,
+// which the generated SLO rules and dashboard refer to, or -metrics
+// prometheus asks for them.
+func (s Service) UsesPrometheus() bool {
+	return s.MetricsExporter == "prometheus" || s.Dashboard || s.UsesSLOs()
+}
+
+// MetricsNamespace returns the namespace of the Prometheus metrics of the
+// service, e.g. "user_service", see -namespace.
+func (s Service) MetricsNamespace() string {
+	return s.Namespace
+}
+
+// LatencyBuckets returns the buckets of the latency histogram: the default
+// Prometheus buckets and the latency budgets of the methods, so that the
+// ratio of requests within budget can be computed exactly.
+func (s Service) LatencyBuckets() string {
+	seen := map[float64]bool{}
+	buckets := []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	for _, b := range buckets {
+		seen[b] = true
+	}
+	for _, f := range s.Funcs {
+		if b := f.Budget.Seconds(); b > 0 && !seen[b] {
+			seen[b] = true
+			buckets = append(buckets, b)
+		}
+	}
+	sort.Float64s(buckets)
+	var lits []string
+	for _, b := range buckets {
+		lits = append(lits, strconv.FormatFloat(b, 'g', -1, 64))
+	}
+	return strings.Join(lits, ", ")
+}
+
+// burnRateAlerts are the multiwindow, multi-burn-rate alerts of the SRE
+// workbook: the alert fires when the error budget burns at Rate over both
+// windows.
+var burnRateAlerts = []struct {
+	Long, Short string
+	Rate        float64
+	Severity    string
+}{
+	{"1h", "5m", 14.4, "page"},
+	{"6h", "30m", 6, "page"},
+	{"1d", "2h", 3, "ticket"},
+	{"3d", "6h", 1, "ticket"},
+}
+
+// genSLORules returns the Prometheus recording and alerting rules of the
+// objectives of the methods of svc, in YAML. Requests fail the availability
+// objective when answered with a 5xx status and the latency objective (of
+// methods with a latency budget) when taking longer than the budget.
+func genSLORules(svc Service) ([]byte, error) {
+	ns := svc.MetricsNamespace()
+	windows := map[string]bool{}
+	for _, a := range burnRateAlerts {
+		windows[a.Long], windows[a.Short] = true, true
+	}
+	var ws []string
+	for w := range windows {
+		ws = append(ws, w)
+	}
+	sort.Slice(ws, func(i, j int) bool { return windowSeconds(ws[i]) < windowSeconds(ws[j]) })
+
+	var recording, alerting []yaml.MapSlice
+	for _, w := range ws {
+		total := fmt.Sprintf("sum by (method) (rate(%s_http_requests_total[%s]))", ns, w)
+		errors := fmt.Sprintf("sum by (method) (rate(%s_http_requests_total{code=~\"5..\"}[%s]))", ns, w)
+		recording = append(recording, yaml.MapSlice{
+			{Key: "record", Value: "method:" + ns + "_http_errors:ratio_rate" + w},
+			{Key: "expr", Value: fmt.Sprintf("(%s or %s * 0) / %s", errors, total, total)},
+		})
+	}
+	for _, f := range svc.Funcs {
+		if f.SLO <= 0 {
+			continue
+		}
+		budget := 1 - f.SLO/100
+		slis := []struct{ name, record, summary string }{
+			{"Errors", "method:" + ns + "_http_errors:ratio_rate", "availability"},
+		}
+		if f.Budget > 0 {
+			le := strconv.FormatFloat(f.Budget.Seconds(), 'g', -1, 64)
+			for _, w := range ws {
+				recording = append(recording, yaml.MapSlice{
+					{Key: "record", Value: "method:" + ns + "_http_slow:ratio_rate" + w},
+					{Key: "expr", Value: fmt.Sprintf("1 - sum by (method) (rate(%s_http_request_duration_seconds_bucket{method=%q,le=%q}[%s])) / sum by (method) (rate(%s_http_request_duration_seconds_count{method=%q}[%s]))", ns, f.Name, le, w, ns, f.Name, w)},
+				})
+			}
+			slis = append(slis, struct{ name, record, summary string }{"Latency", "method:" + ns + "_http_slow:ratio_rate", "latency (" + f.Budget.String() + ")"})
+		}
+		for _, sli := range slis {
+			for _, a := range burnRateAlerts {
+				threshold := strconv.FormatFloat(a.Rate*budget, 'g', 6, 64)
+				alerting = append(alerting, yaml.MapSlice{
+					{Key: "alert", Value: f.Name + sli.name + "BudgetBurn"},
+					{Key: "expr", Value: fmt.Sprintf("%s%s{method=%q} > %s and %s%s{method=%q} > %s", sli.record, a.Long, f.Name, threshold, sli.record, a.Short, f.Name, threshold)},
+					{Key: "labels", Value: yaml.MapSlice{{Key: "severity", Value: a.Severity}, {Key: "method", Value: f.Name}}},
+					{Key: "annotations", Value: yaml.MapSlice{{Key: "summary", Value: fmt.Sprintf("%s is burning its %s error budget (%g%% objective) %gx too fast over %s", f.Name, sli.summary, f.SLO, a.Rate, a.Long)}}},
+				})
+			}
+		}
+	}
+
+	doc := yaml.MapSlice{{Key: "groups", Value: []yaml.MapSlice{
+		{{Key: "name", Value: ns + "-slo-recording"}, {Key: "rules", Value: recording}},
+		{{Key: "name", Value: ns + "-slo-alerts"}, {Key: "rules", Value: alerting}},
+	}}}
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte("# Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.\n"), data...), nil
+}
+
+// windowSeconds returns the length of a Prometheus range such as "5m" or "1d".
+func windowSeconds(w string) int {
+	units := map[byte]int{'m': 60, 'h': 3600, 'd': 86400}
+	n, _ := strconv.Atoi(w[:len(w)-1])
+	return n * units[w[len(w)-1]]
+}
+
+const prometheusTemplate = `
+{{ define "prometheus" }}
+// UsePrometheusMetrics sets the HTTP metrics to Prometheus metrics in the
+// "{{ .MetricsNamespace }}" namespace, registered with the default registry.
+// The generated SLO rules and dashboard refer to these metrics. Call it
+// once, before serving requests.{{ if .UsesFallbacks }} It sets FallbackCount as well, to
+// "{{ .MetricsNamespace }}_client_fallbacks_total".{{ end }}{{ if .DeadLetter }} It sets RetryCount and
+// DeadLetterCount as well, to "{{ .MetricsNamespace }}_messages_retries_total" and
+// "{{ .MetricsNamespace }}_messages_dead_letters_total".{{ end }}
+func UsePrometheusMetrics() {
+	RequestCount = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "{{ .MetricsNamespace }}",
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of requests handled.",
+	}, []string{"method", "code"})
+	RequestLatency = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+		Namespace: "{{ .MetricsNamespace }}",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Time taken to handle a request.",
+		Buckets:   []float64{ {{ .LatencyBuckets }} },
+	}, []string{"method"})
+	RequestSize = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+		Namespace: "{{ .MetricsNamespace }}",
+		Subsystem: "http",
+		Name:      "request_size_bytes",
+		Help:      "Size of request bodies.",
+		Buckets:   stdprometheus.ExponentialBuckets(64, 4, 8),
+	}, []string{"method"})
+	ResponseSize = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+		Namespace: "{{ .MetricsNamespace }}",
+		Subsystem: "http",
+		Name:      "response_size_bytes",
+		Help:      "Size of response bodies.",
+		Buckets:   stdprometheus.ExponentialBuckets(64, 4, 8),
+	}, []string{"method"}){{ if .UsesOptionMetrics }}
+	OptionCount = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "{{ .MetricsNamespace }}",
+		Subsystem: "http",
+		Name:      "options_total",
+		Help:      "Number of options set by requests.",
+	}, []string{"method", "option"}){{ end }}{{ if .UsesFallbacks }}
+	FallbackCount = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "{{ .MetricsNamespace }}",
+		Subsystem: "client",
+		Name:      "fallbacks_total",
+		Help:      "Number of calls answered with the fallback response of their method.",
+	}, []string{"method", "reason"}){{ end }}{{ if .DeadLetter }}
+	RetryCount = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "{{ .MetricsNamespace }}",
+		Subsystem: "messages",
+		Name:      "retries_total",
+		Help:      "Number of retries of the requests of the message subscribers.",
+	}, []string{"transport", "method"})
+	DeadLetterCount = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "{{ .MetricsNamespace }}",
+		Subsystem: "messages",
+		Name:      "dead_letters_total",
+		Help:      "Number of requests of the message subscribers published to a dead-letter subject or queue.",
+	}, []string{"transport", "method", "reason"}){{ end }}
+}
+{{ end }}
+`
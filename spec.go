@@ -0,0 +1,320 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Schema describes the JSON encoding of a Go type, for the generation of API
+// specs.
+type Schema struct {
+	Type        string  // JSON type: object, array, string, integer, number or boolean; empty for any value
+	Format      string  // OpenAPI format
+	Proto       string  // proto type of scalars
+	Ref         string  // name of the component of a struct type
+	Items       *Schema // elements of arrays, values of maps
+	Nullable    bool    // encoded as null when nil, i.e. a pointer
+	Enum        []string
+	Constraints *Constraints
+}
+
+// Property is a field of a component.
+type Property struct {
+	Name     string // JSON name
+	Schema   *Schema
+	Optional bool // left out of the JSON object when empty, through omitempty or kit:optional
+}
+
+// Component is a named object schema: a struct type or the request or
+// response of a method.
+type Component struct {
+	Name          string
+	Properties    []Property
+	AllOf         string     // component extended by the properties, for the variants of a union
+	OneOf         []Property // variants of a union, by discriminator value
+	Discriminator string     // property naming the variant of a union
+	Unwrapped     bool       // response of a kit:unwrap method, encoded as its single property
+}
+
+// Spec holds the schemas of the requests and responses of a service and of
+// the types they refer to, shared by the generated API specs.
+type Spec struct {
+	Components []*Component
+	byName     map[string]*Component
+	byType     map[string]*Component // by fully qualified type
+	pkgs       map[string]*specPkg
+}
+
+// specPkg is a parsed package whose types are referred to by a spec.
+type specPkg struct {
+	Pkg
+	types map[string]*ast.TypeSpec
+}
+
+// basicSchemas are the schemas of the predeclared types.
+var basicSchemas = map[string]Schema{
+	"bool":    {Type: "boolean", Proto: "bool"},
+	"string":  {Type: "string", Proto: "string"},
+	"int":     {Type: "integer", Format: "int64", Proto: "int64"},
+	"int8":    {Type: "integer", Format: "int32", Proto: "int32"},
+	"int16":   {Type: "integer", Format: "int32", Proto: "int32"},
+	"int32":   {Type: "integer", Format: "int32", Proto: "int32"},
+	"rune":    {Type: "integer", Format: "int32", Proto: "int32"},
+	"int64":   {Type: "integer", Format: "int64", Proto: "int64"},
+	"uint":    {Type: "integer", Format: "int64", Proto: "uint64"},
+	"uint8":   {Type: "integer", Format: "int32", Proto: "uint32"},
+	"byte":    {Type: "integer", Format: "int32", Proto: "uint32"},
+	"uint16":  {Type: "integer", Format: "int32", Proto: "uint32"},
+	"uint32":  {Type: "integer", Format: "int64", Proto: "uint32"},
+	"uint64":  {Type: "integer", Format: "int64", Proto: "uint64"},
+	"float32": {Type: "number", Format: "float", Proto: "float"},
+	"float64": {Type: "number", Format: "double", Proto: "double"},
+}
+
+// resolveOptional marks the parameters listed by the "//kit:optional
+// <param>..." annotation of every method of fns as optional.
+func resolveOptional(fns []Func) error {
+	for i := range fns {
+		fn := &fns[i]
+		a, ok := fn.Annotation("optional")
+		if !ok {
+			continue
+		}
+		if len(a.Args) == 0 {
+			return fn.errorf(a, "kit:optional takes at least one parameter name")
+		}
+	args:
+		for _, name := range a.Args {
+			for j := range fn.Params {
+				if fn.Params[j].Name == name && fn.Params[j].Type != "context.Context" {
+					fn.Params[j].Optional = true
+					continue args
+				}
+			}
+			return fn.errorf(a, "kit:optional %s: no such parameter", name)
+		}
+	}
+	return nil
+}
+
+// newSpec returns the spec of the requests and responses of the methods of svc.
+func newSpec(svc Service) *Spec {
+	s := &Spec{byName: map[string]*Component{}, byType: map[string]*Component{}, pkgs: map[string]*specPkg{}}
+	// reserve the names of the requests and responses before adding the
+	// components of the types they refer to
+	for _, f := range svc.Funcs {
+		s.add(f.RequestName, "")
+		s.add(f.ResponseName, "")
+	}
+	for _, f := range svc.Funcs {
+		req := s.byName[f.RequestName]
+		if f.RequestType != nil {
+			s.userType(f.src, *f.RequestType, req)
+		} else {
+			for _, p := range f.Params {
+				if p.Type == "context.Context" {
+					continue
+				}
+				schema := s.resolve(f.src, sliceType(OptionSetterStruct(p.Type)))
+				schema.Constraints = p.Constraints
+				req.Properties = append(req.Properties, Property{Name: p.JSONField(), Schema: schema, Optional: p.Optional})
+			}
+		}
+		res := s.byName[f.ResponseName]
+		if f.ResponseType != nil {
+			s.userType(f.src, *f.ResponseType, res)
+		} else {
+			for _, r := range FilterError(f.Res) {
+				schema := s.resolve(f.src, r.Type)
+				if r.Union != nil {
+					schema = s.union(f.src, r.Union)
+				}
+				res.Properties = append(res.Properties, Property{Name: r.JSONField(), Schema: schema})
+			}
+			res.Unwrapped = f.Unwrap
+		}
+	}
+	return s
+}
+
+// userType sets the properties of the request or response component c from
+// the existing struct type typ, see kit:request.
+func (s *Spec) userType(p Pkg, typ TypeRef, c *Component) {
+	pkg := s.pkg(typ.Path, p.srcDir)
+	if pkg == nil || pkg.types[typ.Name] == nil {
+		return
+	}
+	if st, ok := pkg.types[typ.Name].Type.(*ast.StructType); ok {
+		s.byType[typ.Path+"."+typ.Name] = c
+		c.Properties = s.properties(pkg.Pkg, st)
+	}
+}
+
+// add adds a component named name, qualified by the package name qual if
+// the name is taken.
+func (s *Spec) add(name, qual string) *Component {
+	unique := name
+	if s.byName[unique] != nil {
+		unique = Exported(qual) + name
+	}
+	for i := 2; s.byName[unique] != nil; i++ {
+		unique = Exported(qual) + name + strconv.Itoa(i)
+	}
+	c := &Component{Name: unique}
+	s.byName[unique] = c
+	s.Components = append(s.Components, c)
+	return c
+}
+
+// resolve returns the schema of the type typ, as used in the package p.
+func (s *Spec) resolve(p Pkg, typ string) *Schema {
+	x, err := parser.ParseExpr(typ)
+	if err != nil {
+		return &Schema{}
+	}
+	return s.schemaOf(p, x)
+}
+
+func (s *Spec) schemaOf(p Pkg, x ast.Expr) *Schema {
+	switch x := x.(type) {
+	case *ast.StarExpr:
+		schema := *s.schemaOf(p, x.X)
+		schema.Nullable = true
+		return &schema
+	case *ast.ArrayType:
+		if elt, ok := x.Elt.(*ast.Ident); ok && x.Len == nil && (elt.Name == "byte" || elt.Name == "uint8") {
+			return &Schema{Type: "string", Format: "byte", Proto: "bytes"}
+		}
+		return &Schema{Type: "array", Items: s.schemaOf(p, x.Elt)}
+	case *ast.MapType:
+		return &Schema{Type: "object", Items: s.schemaOf(p, x.Value)}
+	case *ast.Ident:
+		if schema, ok := basicSchemas[x.Name]; ok {
+			return &schema
+		}
+		return s.named(p, p.ImportPath, x.Name)
+	case *ast.SelectorExpr:
+		if qual, ok := x.X.(*ast.Ident); ok {
+			return s.named(p, p.importPathOf(qual.Name), x.Sel.Name)
+		}
+	}
+	return &Schema{}
+}
+
+// named returns the schema of the type name declared in the package path,
+// as referred to from the package p.
+func (s *Spec) named(p Pkg, path, name string) *Schema {
+	if path == "" {
+		return &Schema{}
+	}
+	key := path + "." + name
+	if sc, ok := scalars[key]; ok {
+		return &Schema{Type: sc.Type, Format: sc.Format, Proto: sc.Proto}
+	}
+	if c, ok := s.byType[key]; ok {
+		return &Schema{Type: "object", Ref: c.Name}
+	}
+	pkg := s.pkg(path, p.srcDir)
+	if pkg == nil || pkg.types[name] == nil {
+		return &Schema{}
+	}
+	switch t := pkg.types[name].Type.(type) {
+	case *ast.StructType:
+		c := s.add(name, pkg.Name)
+		s.byType[key] = c
+		c.Properties = s.properties(pkg.Pkg, t)
+		return &Schema{Type: "object", Ref: c.Name}
+	case *ast.Ident:
+		schema := s.schemaOf(pkg.Pkg, t)
+		if e := pkg.enum(pkg.Name + "." + name); e != nil {
+			schema.Enum = e.Values
+		}
+		return schema
+	case *ast.ArrayType, *ast.MapType, *ast.StarExpr, *ast.SelectorExpr:
+		return s.schemaOf(pkg.Pkg, t)
+	}
+	return &Schema{}
+}
+
+// properties returns the properties of the JSON encoding of the struct st.
+func (s *Spec) properties(p Pkg, st *ast.StructType) []Property {
+	var props []Property
+	for _, field := range st.Fields.List {
+		tag := ""
+		if field.Tag != nil {
+			tag, _ = strconv.Unquote(field.Tag.Value)
+		}
+		opts := strings.Split(reflect.StructTag(tag).Get("json"), ",")
+		if opts[0] == "-" && len(opts) == 1 {
+			continue
+		}
+		omitempty := false
+		for _, opt := range opts[1:] {
+			omitempty = omitempty || opt == "omitempty"
+		}
+		schema := s.schemaOf(p, field.Type)
+		names := field.Names
+		if len(names) == 0 {
+			// the fields of embedded structs are promoted unless the tag names it
+			if c := s.byName[schema.Ref]; c != nil && opts[0] == "" {
+				props = append(props, c.Properties...)
+				continue
+			}
+			typ := field.Type
+			if star, ok := typ.(*ast.StarExpr); ok {
+				typ = star.X
+			}
+			if sel, ok := typ.(*ast.SelectorExpr); ok {
+				typ = sel.Sel
+			}
+			if id, ok := typ.(*ast.Ident); ok {
+				names = []*ast.Ident{id}
+			}
+		}
+		for _, name := range names {
+			if !name.IsExported() {
+				continue
+			}
+			prop := Property{Name: name.Name, Schema: schema, Optional: omitempty}
+			if opts[0] != "" {
+				prop.Name = opts[0]
+			}
+			props = append(props, prop)
+		}
+	}
+	return props
+}
+
+// pkg returns the parsed package path, or nil if it can't be found.
+func (s *Spec) pkg(path, srcDir string) *specPkg {
+	if pkg, ok := s.pkgs[path]; ok {
+		return pkg
+	}
+	s.pkgs[path] = nil
+	bpkg, err := importPackage(path, srcDir)
+	if err != nil {
+		return nil
+	}
+	pkg := &specPkg{Pkg: Pkg{Package: bpkg, FileSet: token.NewFileSet(), srcDir: srcDir}, types: map[string]*ast.TypeSpec{}}
+	for _, file := range bpkg.GoFiles {
+		f, err := parser.ParseFile(pkg.FileSet, filepath.Join(bpkg.Dir, file), nil, 0)
+		if err != nil {
+			continue
+		}
+		for _, decl := range f.Decls {
+			if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.TYPE {
+				for _, spec := range decl.Specs {
+					spec := spec.(*ast.TypeSpec)
+					pkg.types[spec.Name.Name] = spec
+				}
+			}
+		}
+	}
+	s.pkgs[path] = pkg
+	return pkg
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrorStatus is a domain error mapped to the HTTP status of the responses
+// failing with it by the "//kit:status <code>" annotation of its
+// declaration.
+type ErrorStatus struct {
+	Error  string // sentinel error, e.g. model.ErrNotFound, or error type, e.g. *model.ConflictError
+	Type   bool   // whether Error is a type, matched with errors.As rather than errors.Is
+	Status int
+}
+
+// findErrorStatuses returns the errors annotated with kit:status declared by
+// the packages paths, in the order of their declarations, adding the
+// packages declaring them to imports.
+func findErrorStatuses(paths []string, srcDir string, imports map[string]string) ([]ErrorStatus, error) {
+	var statuses []ErrorStatus
+	for _, path := range paths {
+		pkg, err := importPackage(path, srcDir)
+		if err != nil {
+			continue
+		}
+		fset := token.NewFileSet()
+		var files []*ast.File
+		for _, file := range pkg.GoFiles {
+			f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, file), nil, parser.ParseComments)
+			if err == nil {
+				files = append(files, f)
+			}
+		}
+		found := false
+		for _, f := range files {
+			for _, decl := range f.Decls {
+				decl, ok := decl.(*ast.GenDecl)
+				if !ok || decl.Tok != token.VAR && decl.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range decl.Specs {
+					var names []*ast.Ident
+					doc := decl.Doc
+					switch spec := spec.(type) {
+					case *ast.ValueSpec:
+						names = spec.Names
+						if spec.Doc != nil || len(decl.Specs) > 1 {
+							doc = spec.Doc
+						}
+					case *ast.TypeSpec:
+						names = []*ast.Ident{spec.Name}
+						if spec.Doc != nil || len(decl.Specs) > 1 {
+							doc = spec.Doc
+						}
+					}
+					var a *Annotation
+					for _, an := range parseAnnotations(doc) {
+						if an.Name == "status" {
+							an := an
+							a = &an
+						}
+					}
+					if a == nil {
+						continue
+					}
+					status, err := strconv.Atoi(strings.Join(a.Args, " "))
+					if err != nil || status < 400 || status > 599 {
+						return nil, errorAt(fset, a.Pos, "kit:status takes the HTTP status of the error, from 400 to 599, e.g. kit:status 404")
+					}
+					for _, name := range names {
+						if !name.IsExported() {
+							return nil, errorAt(fset, a.Pos, "kit:status: %s isn't exported", name.Name)
+						}
+						es := ErrorStatus{Error: pkg.Name + "." + name.Name, Status: status}
+						if decl.Tok == token.TYPE {
+							ptr, ok := errorReceiver(files, name.Name)
+							if !ok {
+								return nil, errorAt(fset, a.Pos, "kit:status: %s doesn't have an Error method", name.Name)
+							}
+							if ptr {
+								es.Error = "*" + es.Error
+							}
+							es.Type = true
+						}
+						statuses = append(statuses, es)
+						found = true
+					}
+				}
+			}
+		}
+		if found {
+			imports[path] = ""
+		}
+	}
+	return statuses, nil
+}
+
+// errorReceiver reports whether the type name declared in files has an
+// Error method and whether its receiver is a pointer, i.e. the error is a
+// pointer to the type.
+func errorReceiver(files []*ast.File, name string) (ptr, ok bool) {
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			fn, isFunc := decl.(*ast.FuncDecl)
+			if !isFunc || fn.Recv == nil || fn.Name.Name != "Error" || len(fn.Recv.List) != 1 {
+				continue
+			}
+			typ := fn.Recv.List[0].Type
+			star, isStar := typ.(*ast.StarExpr)
+			if isStar {
+				typ = star.X
+			}
+			if id, isIdent := typ.(*ast.Ident); isIdent && id.Name == name {
+				return isStar, true
+			}
+		}
+	}
+	return false, false
+}
+
+// errorPackages returns the import paths of the packages declaring the
+// errors of the service, which findErrorStatuses looks for: the package of
+// the interface and the packages of the types of its methods.
+func errorPackages(ifacePkg string, fns []Func) []string {
+	paths := []string{ifacePkg}
+	seen := map[string]bool{ifacePkg: true}
+	for _, f := range fns {
+		for _, path := range f.RequiredImports {
+			if !seen[path] && strings.Contains(strings.Split(path, "/")[0], ".") {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// EncodesErrors reports whether the servers of the HTTP handlers encode
+// errors with the generated EncodeError rather than go-kit's default.
+func (s Service) EncodesErrors() bool {
+	return len(s.ErrorStatuses) > 0 || s.Envelope
+}
+
+const errorStatusTemplate = `
+{{ define "errorstatus" }}{{ if .ErrorStatuses }}
+// ErrorStatus maps the errors Is reports to the HTTP status of the
+// responses failing with them{{ if .HTTP }}, see EncodeError{{ end }}.
+type ErrorStatus struct {
+	Is     func(error) bool
+	Status int
+}
+
+// ErrorStatuses are the statuses of the errors {{ if .HTTP }}EncodeError responds with{{ else }}errorStatus reports{{ end }}, the
+// first matching one winning, from the kit:status annotations of their
+// declarations. Add to them at init time, before {{ if .HTTP }}MakeHTTPHandler is called{{ else }}the servers are made{{ end }}.
+var ErrorStatuses = []ErrorStatus{ {{ range .ErrorStatuses }}
+	{func(err error) bool { {{ if .Type }}var target {{ .Error }}
+		return errors.As(err, &target){{ else }}return errors.Is(err, {{ .Error }}){{ end }} }, {{ .Status }}},{{ end }}
+}
+{{ end }}
+// errorStatus returns the HTTP status of err: that of {{ if .ErrorStatuses }}the first of
+// ErrorStatuses matching it, or that of {{ end }}its StatusCode method.
+func errorStatus(err error) (int, bool) { {{ if .ErrorStatuses }}
+	for _, s := range ErrorStatuses {
+		if s.Is(err) {
+			return s.Status, true
+		}
+	}{{ end }}
+	if sc, ok := err.(interface{ StatusCode() int }); ok {
+		return sc.StatusCode(), true
+	}
+	return 0, false
+}
+{{ if .HTTP }}
+// EncodeError is the error encoder of the servers of the HTTP handlers. It
+// responds like go-kit's DefaultErrorEncoder, with {{ if .Envelope }}an Envelope holding the
+// message of err{{ else }}the message of err, or its
+// JSON if it's a json.Marshaler,{{ end }} and its headers if it's a Headerer, with
+// the status of errorStatus, 500 Internal Server Error if none.
+func EncodeError(_ context.Context, err error, w http.ResponseWriter) { {{ if .Envelope }}
+	contentType := "application/json; charset=utf-8"
+	body, _ := json.Marshal(Envelope{Error: err.Error()}){{ else }}
+	contentType, body := "text/plain; charset=utf-8", []byte(err.Error())
+	if marshaler, ok := err.(json.Marshaler); ok {
+		if jsonBody, marshalErr := marshaler.MarshalJSON(); marshalErr == nil {
+			contentType, body = "application/json; charset=utf-8", jsonBody
+		}
+	}{{ end }}
+	w.Header().Set("Content-Type", contentType)
+	if headerer, ok := err.(httptransport.Headerer); ok {
+		for k, values := range headerer.Headers() {
+			for _, v := range values {
+				w.Header().Add(k, v)
+			}
+		}
+	}
+	status, ok := errorStatus(err)
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+{{ end }}{{ end }}
+`
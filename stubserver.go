@@ -0,0 +1,118 @@
+package main
+
+// ResultName returns the name of result i of f, as named by Signature.
+func ResultName(f Func, i int) string {
+	return paramName(f.Res[i], "r", i)
+}
+
+// ResultKey returns the name of result i of f as the key of its value in
+// JSON or YAML, which isn't renamed like ResultName.
+func ResultKey(f Func, i int) string {
+	return paramKey(f.Res[i], "r", i)
+}
+
+const stubServerTemplate = `
+{{ define "stubserver" }}
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+
+// Command stubserver serves canned responses for {{ .IFace }}, read from a
+// YAML fixture per method{{ if .StubExamples }} over its example response, if any{{ end }}, using the generated HTTP handlers.
+package main
+
+import ({{ if .StubExamples }}
+	"encoding/json"{{ end }}
+	"errors"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"{{ range .MockImports }}
+	"{{ . }}"{{ end }}
+
+	"gopkg.in/yaml.v2"
+
+	{{ .Pkg }} "{{ .ImportPath }}"{{ if .UsesScopes }}
+	"{{ .ImportPath }}/scopes"{{ end }}
+)
+
+var (
+	addr     = flag.String("addr", ":8080", "listen address")
+	fixtures = flag.String("fixtures", "fixtures", "directory containing the <Method>.yaml fixtures"){{ range .Groups }}
+	{{ .Name }}Addr = flag.String("{{ .Name }}-addr", "{{ .DefaultAddr }}", "listen address of the {{ .Name }} group"){{ end }}
+)
+
+func main() {
+	flag.Parse()
+
+	svc := &{{ .Pkg }}.MockService{}
+	{{ range $fun := .AllFuncs }}
+	svc.{{ .Name }}Func = func{{ Signature . }} {
+		var fixture struct { {{ range $i, $r := .Res }}{{ if ne $r.Type "error" }}
+			{{ Exported (ResultKey $fun $i) }} {{ $r.Type }} ` + "`yaml:\"{{ ResultKey $fun $i }}\"{{ if StubExample $fun }} json:\"{{ $r.JSONField }}\"{{ end }}`" + `{{ end }}{{ end }}
+			Error string ` + "`yaml:\"error\"{{ if StubExample $fun }} json:\"-\"{{ end }}`" + `
+		}
+		ferr := load("{{ .Name }}", {{ if $.StubExamples }}{{ printf "%q" (StubExample .) }}, {{ end }}&fixture){{ range $i, $r := .Res }}
+		{{ if eq $r.Type "error" }}{{ ResultName $fun $i }} = fixtureError(ferr, fixture.Error){{ else }}{{ ResultName $fun $i }} = fixture.{{ Exported (ResultKey $fun $i) }}{{ end }}{{ end }}{{ if not (HasError .) }}
+		if ferr != nil {
+			log.Println(ferr)
+		}{{ end }}
+		return
+	}
+	{{ end }}{{ range .Groups }}
+	go func() {
+		log.Printf("serving the {{ .Name }} group on %s", *{{ .Name }}Addr)
+		log.Fatal(http.ListenAndServe(*{{ .Name }}Addr, {{ if $.UsesScopes }}grantScopes({{ end }}{{ $.Pkg }}.{{ .Handler }}(svc){{ if $.UsesScopes }}){{ end }}))
+	}()
+	{{ end }}
+	log.Printf("serving fixtures from %s on %s", *fixtures, *addr)
+	log.Fatal(http.ListenAndServe(*addr, {{ if .UsesScopes }}grantScopes({{ end }}{{ .Pkg }}.MakeHTTPHandler(svc){{ if .UsesScopes }}){{ end }}))
+}
+{{ if .UsesScopes }}
+// grantScopes serves the requests to h as if made with a token granted
+// every scope.
+func grantScopes(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext({{ .Pkg }}.ContextWithScopes(r.Context(), scopes.All)))
+	})
+}
+{{ end }}
+
+{{ if .StubExamples }}// load decodes the example response of method, if not empty, and its fixture
+// into v. A missing fixture leaves v unchanged.
+func load(method, example string, v interface{}) error {
+	if example != "" {
+		if err := json.Unmarshal([]byte(example), v); err != nil {
+			return err
+		}
+	}
+	data, err{{ else }}// load decodes the fixture of method into v. A missing fixture leaves v unchanged.
+func load(method string, v interface{}) error {
+	data, err{{ end }} := ioutil.ReadFile(filepath.Join(*fixtures, method+".yaml"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, v)
+}
+
+// fixtureError returns the error a method responds with: the error loading its
+// fixture, or the error configured in the fixture.
+func fixtureError(err error, configured string) error {
+	if err != nil {
+		return err
+	}
+	if configured != "" {
+		return errors.New(configured)
+	}
+	return nil
+}
+{{ end }}
+
+{{ define "stubfixture" }}# Canned response of {{ .Name }}{{ if StubExample . }}, overriding its example response{{ end }}. Struct fields are matched by their lower case names.
+{{ range $i, $r := .Res }}{{ if ne $r.Type "error" }}# {{ ResultKey $ $i }}:{{ with $r.Scalar }} {{ .Example }}{{ end }} # {{ $r.Type }}
+{{ end }}{{ end }}# error: "" # responds with this error when not empty
+{{ end }}
+`
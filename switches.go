@@ -0,0 +1,272 @@
+package main
+
+// switchesImports returns the imports required by the switch registry of s
+// and its admin API.
+func switchesImports(s Service) []string {
+	imps := []string{"sort", "sync"}
+	if s.UsesLogger() || s.RateLimit != "" || s.UsesBudgets() {
+		imps = append(imps, "sync/atomic")
+	}
+	if s.UsesLogger() {
+		imps = append(imps, "fmt")
+	}
+	if s.Admin {
+		imps = append(imps, "encoding/json", "net/http", "strings")
+	}
+	return imps
+}
+
+// UsesSwitches reports whether the middleware reads its parameters from the
+// switch registry, to change them at runtime with -admin or -hot-reload.
+func (s Service) UsesSwitches() bool {
+	return s.Admin || s.HotReload
+}
+
+// AdminListener returns the listener of the admin API of the scaffold,
+// shared with the methods of the admin group if there is one.
+func (s Service) AdminListener() HandlerGroup {
+	groups := s.HandlerGroups()
+	for _, g := range groups {
+		if g.Name == "admin" {
+			return g
+		}
+	}
+	return HandlerGroup{Name: "admin", DefaultAddr: listenAddr(len(groups))}
+}
+
+// Listeners returns the groups served by a listener of their own in the
+// scaffold: the groups of methods and the admin API of -admin.
+func (s Service) Listeners() []HandlerGroup {
+	groups := s.Groups()
+	if s.Admin && len(s.AdminListener().Funcs) == 0 {
+		groups = append(groups, s.AdminListener())
+	}
+	return groups
+}
+
+const switchesTemplate = `
+{{ define "switches" }}
+// Switches holds the switches of the generated middleware, flipped at runtime{{ if .Admin }}
+// through the admin API served by MakeSwitchesHandler{{ end }}{{ if .HotReload }}{{ if .Admin }} and{{ end }}
+// by the configuration reloaded by the scaffolded command{{ end }}.
+var Switches = &SwitchRegistry{breakers: map[string]func(){}}
+
+// SwitchRegistry holds switches read by the middleware on every request. Its
+// methods are safe for concurrent use.
+type SwitchRegistry struct { {{ if .UsesLogger }}
+	logLevel int32 // LogLevel{{ end }}{{ if .RateLimit }}
+	rateLimitOff int32 // 1 to let every request through
+	rateLimit    atomic.Value // RateLimitOverride{{ end }}{{ if .UsesBudgets }}
+	budgets atomic.Value // map[string]time.Duration{{ end }}
+
+	mu       sync.Mutex
+	breakers map[string]func()
+}
+{{ if .UsesLogger }}
+// LogLevel is the lowest level of the entries logged to Logger.
+type LogLevel int32
+
+const (
+	LogInfo  LogLevel = iota // the access log and everything below, the default
+	LogError                 // the recovered panics
+	LogNone                  // nothing
+)
+
+var logLevelNames = []string{"info", "error", "none"}
+
+func (l LogLevel) String() string {
+	if l < 0 || int(l) >= len(logLevelNames) {
+		return "unknown"
+	}
+	return logLevelNames[l]
+}
+
+// LogLevel returns the lowest level of the entries logged to Logger.
+func (r *SwitchRegistry) LogLevel() LogLevel {
+	return LogLevel(atomic.LoadInt32(&r.logLevel))
+}
+
+// SetLogLevel sets the lowest level of the entries logged to Logger.
+func (r *SwitchRegistry) SetLogLevel(l LogLevel) {
+	atomic.StoreInt32(&r.logLevel, int32(l))
+}
+
+// ParseLogLevel returns the level named name: info, error or none.
+func ParseLogLevel(name string) (LogLevel, error) {
+	for l, n := range logLevelNames {
+		if n == name {
+			return LogLevel(l), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q, want one of %v", name, logLevelNames)
+}
+
+// logs reports whether entries of level l are logged.
+func (r *SwitchRegistry) logs(l LogLevel) bool {
+	return r.LogLevel() <= l
+}
+{{ end }}{{ if .RateLimit }}
+// RateLimitOverride replaces the rate and burst of the stores returned by
+// NewMemoryRateLimitStore.
+type RateLimitOverride struct {
+	Rate  float64 ` + "`json:\"rate\"`" + ` // tokens per second
+	Burst int     ` + "`json:\"burst\"`" + `
+}
+
+// RateLimitEnabled reports whether NewRateLimitHandler limits requests.
+func (r *SwitchRegistry) RateLimitEnabled() bool {
+	return atomic.LoadInt32(&r.rateLimitOff) == 0
+}
+
+// SetRateLimitEnabled turns the rate limiting of NewRateLimitHandler on or off.
+func (r *SwitchRegistry) SetRateLimitEnabled(enabled bool) {
+	var off int32
+	if !enabled {
+		off = 1
+	}
+	atomic.StoreInt32(&r.rateLimitOff, off)
+}
+
+// RateLimit returns the override of the rate and burst of the memory stores,
+// if any.
+func (r *SwitchRegistry) RateLimit() (RateLimitOverride, bool) {
+	o, ok := r.rateLimit.Load().(RateLimitOverride)
+	return o, ok && o.Rate > 0
+}
+
+// SetRateLimit overrides the rate and burst of the memory stores; a zero
+// override restores those they were made with.
+func (r *SwitchRegistry) SetRateLimit(o RateLimitOverride) {
+	r.rateLimit.Store(o)
+}
+{{ end }}{{ if .UsesBudgets }}
+// Budget returns the latency budget of method, which is budget unless
+// overridden by SetBudgets.
+func (r *SwitchRegistry) Budget(method string, budget time.Duration) time.Duration {
+	budgets, _ := r.budgets.Load().(map[string]time.Duration)
+	if d, ok := budgets[method]; ok {
+		return d
+	}
+	return budget
+}
+
+// SetBudgets overrides the latency budgets of the methods of budgets, keyed
+// by method name; the others have the budget they were generated with.
+func (r *SwitchRegistry) SetBudgets(budgets map[string]time.Duration) {
+	r.budgets.Store(budgets)
+}
+{{ end }}
+// RegisterBreaker registers the reset function of the circuit breaker name,
+// such as one of github.com/sony/gobreaker wrapping an endpoint, for the
+// admin API to reset it.
+func (r *SwitchRegistry) RegisterBreaker(name string, reset func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.breakers[name] = reset
+}
+
+// ResetBreaker resets the circuit breaker name, reporting whether it is
+// registered.
+func (r *SwitchRegistry) ResetBreaker(name string) bool {
+	r.mu.Lock()
+	reset, ok := r.breakers[name]
+	r.mu.Unlock()
+	if ok {
+		reset()
+	}
+	return ok
+}
+
+// Breakers returns the names of the registered circuit breakers, sorted.
+func (r *SwitchRegistry) Breakers() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.breakers))
+	for name := range r.breakers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+{{ if .Admin }}
+// switchesState is the state of the switches, as served and patched by the
+// admin API. The fields of a patch left out are unchanged.
+type switchesState struct { {{ if .UsesLogger }}
+	LogLevel *string ` + "`json:\"log_level,omitempty\"`" + `{{ end }}{{ if .RateLimit }}
+	RateLimitEnabled *bool ` + "`json:\"rate_limit_enabled,omitempty\"`" + `
+	RateLimit *RateLimitOverride ` + "`json:\"rate_limit,omitempty\"`" + `{{ end }}
+	Breakers []string ` + "`json:\"breakers,omitempty\"`" + `
+}
+
+// MakeSwitchesHandler returns the admin API of Switches, to serve under
+// /switches on a listener of its own, out of reach of the clients of the
+// service:
+//
+//	GET /switches                        the state of the switches
+//	PATCH /switches                      set the switches of the JSON body, e.g. {"log_level": "error"}
+//	POST /switches/breakers/{name}/reset reset the circuit breaker name
+func MakeSwitchesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/switches" {
+			name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/switches/breakers/"), "/reset")
+			switch {
+			case !strings.HasPrefix(r.URL.Path, "/switches/breakers/") || !strings.HasSuffix(r.URL.Path, "/reset") || name == "" || strings.Contains(name, "/"):
+				http.NotFound(w, r)
+			case r.Method != http.MethodPost:
+				w.Header().Set("Allow", http.MethodPost)
+				http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			case !Switches.ResetBreaker(name):
+				http.Error(w, "unknown circuit breaker "+name, http.StatusNotFound)
+			default:
+				w.WriteHeader(http.StatusNoContent)
+			}
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPatch:
+			var patch switchesState
+			if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}{{ if .UsesLogger }}
+			var level LogLevel
+			if patch.LogLevel != nil {
+				var err error
+				if level, err = ParseLogLevel(*patch.LogLevel); err != nil {
+					http.Error(w, "log_level: "+err.Error(), http.StatusBadRequest)
+					return
+				}
+			}{{ end }}{{ if .RateLimit }}
+			if o := patch.RateLimit; o != nil && (o.Rate < 0 || o.Burst < 0 || (o.Rate > 0) != (o.Burst > 0)) {
+				http.Error(w, "rate_limit: want a positive rate and burst, or zeros to restore them", http.StatusBadRequest)
+				return
+			}{{ end }}{{ if .UsesLogger }}
+			if patch.LogLevel != nil {
+				Switches.SetLogLevel(level)
+			}{{ end }}{{ if .RateLimit }}
+			if patch.RateLimitEnabled != nil {
+				Switches.SetRateLimitEnabled(*patch.RateLimitEnabled)
+			}
+			if patch.RateLimit != nil {
+				Switches.SetRateLimit(*patch.RateLimit)
+			}{{ end }}
+		default:
+			w.Header().Set("Allow", http.MethodGet+", "+http.MethodPatch)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		state := switchesState{Breakers: Switches.Breakers()}{{ if .UsesLogger }}
+		level := Switches.LogLevel().String()
+		state.LogLevel = &level{{ end }}{{ if .RateLimit }}
+		enabled := Switches.RateLimitEnabled()
+		state.RateLimitEnabled = &enabled
+		if o, ok := Switches.RateLimit(); ok {
+			state.RateLimit = &o
+		}{{ end }}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(state)
+	})
+}
+{{ end }}{{ end }}
+`
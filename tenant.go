@@ -0,0 +1,85 @@
+package main
+
+import "fmt"
+
+// tenantImports are the imports required by the tenant extraction.
+var tenantImports = []string{"context", "strings"}
+
+// tenantSegment is the path segment naming the tenant of a request, ahead
+// of the route of the method, see -tenant path.
+const tenantSegment = "/t/{tenant}"
+
+// checkTenant returns an error if mode isn't a way of telling the tenant
+// of a request, see -tenant.
+func checkTenant(mode string) error {
+	switch mode {
+	case "", "path", "host":
+		return nil
+	}
+	return fmt.Errorf("-tenant: unknown mode %q, want path or host", mode)
+}
+
+// Route returns the path requests to f are made to, e.g.
+// "/t/{tenant}/create-user" if the tenant leads the path.
+func (s Service) Route(f Func) string {
+	if s.Tenant == "path" {
+		return tenantSegment + f.HTTPPath
+	}
+	return f.HTTPPath
+}
+
+const tenantTemplate = `
+{{ define "tenant" }}
+type tenantKey struct{}
+
+// ContextWithTenant returns a copy of ctx carrying tenant.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant of the request ctx belongs to, which
+// the handlers of MakeHTTPHandler extract {{ if eq .Tenant "path" }}from the /t/{tenant} segment
+// leading its path{{ else }}from its host with TenantFromHost{{ end }} before decoding it.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok
+}
+{{ if eq .Tenant "host" }}
+// TenantFromHost returns the tenant of requests to host, which may include
+// a port, or false to respond to them with 404 Not Found. By default it is
+// the first label of the host, e.g. "acme" for acme.example.com. Set it at
+// init time, before MakeHTTPHandler is called.
+var TenantFromHost = func(host string) (string, bool) {
+	i := strings.Index(host, ".")
+	if i <= 0 {
+		return "", false
+	}
+	return host[:i], true
+}
+{{ end }}
+// withTenant serves the requests to h with their tenant in their context,
+// responding with 404 Not Found to those without one.
+func withTenant(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { {{ if eq .Tenant "path" }}
+		rest := strings.TrimPrefix(r.URL.Path, "/t/")
+		i := strings.Index(rest, "/")
+		if rest == r.URL.Path || i <= 0 {
+			http.NotFound(w, r)
+			return
+		}
+		tenant := rest[:i]
+		r = r.WithContext(ContextWithTenant(r.Context(), tenant))
+		u := *r.URL
+		u.Path, u.RawPath = rest[i:], ""
+		r.URL = &u{{ else }}
+		tenant, ok := TenantFromHost(r.Host)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		r = r.WithContext(ContextWithTenant(r.Context(), tenant)){{ end }}
+		h.ServeHTTP(w, r)
+	})
+}
+{{ end }}
+`
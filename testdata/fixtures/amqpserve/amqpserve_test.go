@@ -0,0 +1,92 @@
+// Package amqpserve serves requests through the AMQP subscribers generated
+// into example.com/fixtures/endpoints, with -transports http,amqp and -mock,
+// by TestAMQP of kitboiler, on a fake channel.
+package amqpserve
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+	"example.com/fixtures/model"
+	"github.com/streadway/amqp"
+)
+
+// channel records the messages published on it.
+type channel struct {
+	keys     []string
+	messages []amqp.Publishing
+}
+
+func (c *channel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	c.keys = append(c.keys, key)
+	c.messages = append(c.messages, msg)
+	return nil
+}
+
+func (c *channel) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	return nil, errors.New("not implemented")
+}
+
+// acknowledger counts the deliveries acknowledged.
+type acknowledger struct{ acks int }
+
+func (a *acknowledger) Ack(tag uint64, multiple bool) error { a.acks++; return nil }
+
+func (a *acknowledger) Nack(tag uint64, multiple, requeue bool) error { return nil }
+
+func (a *acknowledger) Reject(tag uint64, requeue bool) error { return nil }
+
+func TestSubscriber(t *testing.T) {
+	subscribers := endpoints.NewAMQPSubscribers(&endpoints.MockService{
+		GetUserFunc: func(ctx context.Context, id string) (*model.User, error) {
+			if id == "0" {
+				return nil, errors.New("no such user")
+			}
+			return &model.User{ID: id}, nil
+		},
+	})
+	serve := subscribers[endpoints.GetUserAMQPQueue]
+	if serve == nil || endpoints.GetUserAMQPQueue != "user-service.get-user" {
+		t.Fatalf("no subscriber for %q", endpoints.GetUserAMQPQueue)
+	}
+
+	for _, c := range []struct {
+		body, want string
+		err        bool
+	}{
+		{`{"Id": "7"}`, "7", false},
+		{`{"Id": "0"}`, "no such user", true},
+	} {
+		ch, ack := &channel{}, &acknowledger{}
+		serve.ServeDelivery(ch)(&amqp.Delivery{
+			Acknowledger:  ack,
+			CorrelationId: "c1",
+			ReplyTo:       "replies",
+			Body:          []byte(c.body),
+		})
+		if len(ch.messages) != 1 || ch.keys[0] != "replies" || ch.messages[0].CorrelationId != "c1" {
+			t.Fatalf("%s: replied %v to %v, want one reply to replies with c1", c.body, ch.messages, ch.keys)
+		}
+		if ack.acks != 1 {
+			t.Errorf("%s: acknowledged %d times, want once", c.body, ack.acks)
+		}
+
+		reply := &amqp.Delivery{Body: ch.messages[0].Body}
+		response, err := endpoints.DecodeAMQPGetUserResponse(context.Background(), reply)
+		if c.err {
+			var aerr *endpoints.AMQPError
+			if !errors.As(err, &aerr) || aerr.Error() != c.want {
+				t.Errorf("%s: replied %s, want the error %q", c.body, reply.Body, c.want)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", c.body, err)
+		}
+		if user := response.(endpoints.GetUserResponse).User; user == nil || user.ID != c.want {
+			t.Errorf("%s: replied %s, want the user %s", c.body, reply.Body, c.want)
+		}
+	}
+}
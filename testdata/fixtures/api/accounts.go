@@ -0,0 +1,12 @@
+package api
+
+import "context"
+
+type AccountService interface {
+	//kit:scope accounts:read
+	GetBalance(ctx context.Context, id string) (cents int64, err error)
+	//kit:scope accounts:write
+	//kit:scope admin
+	CloseAccount(ctx context.Context, id string) (err error)
+	Ping(ctx context.Context) (err error)
+}
@@ -0,0 +1,30 @@
+// Package api declares the interface the tests of kitboiler generate
+// packages for.
+package api
+
+import (
+	"context"
+
+	"example.com/fixtures/model"
+)
+
+type UserService interface {
+	// CreateUser creates a user.
+	CreateUser(ctx context.Context, name string, age int) (user *model.User, err error)
+	// GetUser returns the user with the given id.
+	//kit:etag Version
+	GetUser(ctx context.Context, id string) (user *model.User, err error)
+	//kit:ifmatch GetUser
+	//kit:budget 250ms
+	//kit:optional name
+	UpdateUser(ctx context.Context, id string, name string, status model.Status) (user *model.User, err error)
+	ListUsers(ctx context.Context, opts ...model.ListOptionsSetter) (users []*model.User, err error)
+	// DeleteUser removes the user id. It fails if the user owns
+	// resources.
+	DeleteUser(ctx context.Context, id string) (err error)
+	// Profile returns the profile of the user id, returned by value.
+	//kit:etag Version
+	Profile(ctx context.Context, id string) (profile model.User, err error)
+	//kit:skip
+	Ping() (err error)
+}
@@ -0,0 +1,17 @@
+package api
+
+import (
+	"context"
+
+	"example.com/fixtures/model"
+)
+
+type BlobService interface {
+	//kit:unwrap
+	Read(ctx context.Context, key string) (data []byte, err error)
+	//kit:unwrap
+	Size(ctx context.Context, key string) (n int64, err error)
+	//kit:unwrap
+	//kit:nil not-found
+	Owner(ctx context.Context, key string) (user *model.User, err error)
+}
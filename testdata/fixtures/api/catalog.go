@@ -0,0 +1,14 @@
+package api
+
+import (
+	"context"
+
+	"example.com/fixtures/model"
+)
+
+// CatalogService has unnamed results.
+type CatalogService interface {
+	Item(ctx context.Context, id string) (*model.User, error)
+	Items(ctx context.Context, offset, limit int) ([]*model.User, error)
+	Stats(context.Context) (int, int, error)
+}
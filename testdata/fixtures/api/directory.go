@@ -0,0 +1,23 @@
+package api
+
+import (
+	"context"
+
+	"example.com/fixtures/model"
+)
+
+// DirectoryService has methods served on kit:http routes.
+type DirectoryService interface {
+	//kit:http GET /users/{id}
+	//kit:hedge 50ms
+	Lookup(ctx context.Context, id string) (user *model.User, err error)
+	//kit:http GET /users
+	//kit:optional limit
+	//kit:fallback {"Users": []}
+	Search(ctx context.Context, name string, limit int) (users []*model.User, err error)
+	//kit:http PUT /users/{id}
+	Rename(ctx context.Context, id string, name string) (user *model.User, err error)
+	//kit:http DELETE /users/{id}
+	Remove(ctx context.Context, id string) (err error)
+	Count(ctx context.Context) (n int, err error)
+}
@@ -0,0 +1,14 @@
+package api
+
+import (
+	"context"
+
+	"example.com/fixtures/model"
+)
+
+// UserEvents is notified of changes to users.
+//kit:outbound
+type UserEvents interface {
+	UserCreated(ctx context.Context, user *model.User) error
+	UserDeleted(ctx context.Context, id string) error
+}
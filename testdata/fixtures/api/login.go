@@ -0,0 +1,13 @@
+package api
+
+import "context"
+
+// LoginService has parameters masked in logs.
+type LoginService interface {
+	//kit:sensitive password
+	//kit:pii email
+	//kit:log sample=2
+	Login(ctx context.Context, email string, password string, remember bool) (token string, err error)
+	//kit:log errors
+	Logout(ctx context.Context, token string) (err error)
+}
@@ -0,0 +1,12 @@
+package api
+
+import (
+	"context"
+
+	g "example.com/fixtures/go-geo/v2"
+)
+
+type PlaceService interface {
+	Locate(ctx context.Context, name string) (point g.Point, err error)
+	Near(ctx context.Context, point *g.Point, km float64) (names []string, err error)
+}
@@ -0,0 +1,15 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// QueryService has methods whose parameters are decoded from query strings.
+type QueryService interface {
+	FindEvents(ctx context.Context, ids []int, active bool, since time.Time, within time.Duration, ratio *float64) (n int, err error)
+	FindNames(ctx context.Context, prefix string, max uint8) (names []string, err error)
+	FindTagged(ctx context.Context, tags ...string) (names []string, err error)
+	Store(ctx context.Context, names []string) (err error)
+	Tag(ctx context.Context, tag string, ids ...int) (n int, err error)
+}
@@ -0,0 +1,12 @@
+package api
+
+import (
+	"context"
+
+	"example.com/fixtures/model"
+)
+
+type ShapeService interface {
+	//kit:oneof shape model.Circle *model.Square
+	GetShape(ctx context.Context, id string) (shape model.Shape, err error)
+}
@@ -0,0 +1,14 @@
+package api
+
+import "context"
+
+// WordService has methods, parameters and results named like predeclared
+// identifiers and the variables of the generated code, and a method whose
+// response type is named like a function of the generated code.
+type WordService interface {
+	New(ctx context.Context, string string, len int) (req string, err error)
+	Error(ctx context.Context, svc string) (err error)
+	String(ctx context.Context, request int, response int) (s string, err error)
+	Encode(ctx context.Context, text string) (encoded string, err error)
+	Fail(ctx context.Context, reason string) (error error)
+}
@@ -0,0 +1,72 @@
+// Package budget calls endpoints through the middlewares generated into
+// example.com/fixtures/endpoints, with -budget 1s -client, by TestBudget of
+// kitboiler.
+package budget
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"example.com/fixtures/endpoints"
+)
+
+// deadline returns a context with the deadline propagated by a request
+// with timeout left.
+func deadline(timeout time.Duration) context.Context {
+	r := httptest.NewRequest("POST", "/update-user", nil)
+	r.Header.Set("X-Request-Timeout", strconv.FormatInt(int64(timeout/time.Millisecond), 10))
+	return endpoints.DeadlineFromHTTPHeader(context.Background(), r)
+}
+
+func TestShedOnBudget(t *testing.T) {
+	called := false
+	e := endpoints.ShedOnBudget("UpdateUser", endpoints.UpdateUserBudget)(func(ctx context.Context, request interface{}) (interface{}, error) {
+		called = true
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("the request runs without the propagated deadline")
+		}
+		return nil, nil
+	})
+	if _, err := e(deadline(100*time.Millisecond), nil); err != endpoints.ErrInsufficientBudget || called {
+		t.Errorf("100ms left of a 250ms budget: %v, want ErrInsufficientBudget", err)
+	}
+	if _, err := e(deadline(time.Second), nil); err != nil || !called {
+		t.Errorf("1s left of a 250ms budget: %v, want the request to run", err)
+	}
+}
+
+func TestWithBudget(t *testing.T) {
+	e := endpoints.WithBudget(endpoints.GetUserBudget)(func(ctx context.Context, request interface{}) (interface{}, error) {
+		r := httptest.NewRequest("POST", "/get-user", nil)
+		endpoints.DeadlineToHTTPHeader(ctx, r)
+		ms, err := strconv.Atoi(r.Header.Get("X-Request-Timeout"))
+		if err != nil || ms <= 0 || ms > 1000 {
+			t.Errorf("X-Request-Timeout: %q, want the 1s budget", r.Header.Get("X-Request-Timeout"))
+		}
+		return nil, nil
+	})
+	e(context.Background(), nil)
+}
+
+func TestHTTPClientBudget(t *testing.T) {
+	var timeout string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout = r.Header.Get("X-Request-Timeout")
+		w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+	client, err := endpoints.NewHTTPClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.DeleteUser(context.Background(), "7"); err != nil {
+		t.Fatal(err)
+	}
+	if ms, err := strconv.Atoi(timeout); err != nil || ms <= 0 || ms > 1000 {
+		t.Errorf("X-Request-Timeout: %q, want the 1s budget of DeleteUser", timeout)
+	}
+}
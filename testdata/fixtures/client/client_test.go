@@ -0,0 +1,108 @@
+// Package client calls the handler generated into
+// example.com/fixtures/endpoints, with -client, -router chi and -mock, by
+// TestClient of kitboiler, through the generated client.
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"example.com/fixtures/endpoints"
+	"example.com/fixtures/model"
+)
+
+func TestClient(t *testing.T) {
+	var removed string
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(&endpoints.MockService{
+		LookupFunc: func(ctx context.Context, id string) (*model.User, error) {
+			if id == "0" {
+				return nil, errors.New("no such user")
+			}
+			return &model.User{ID: id}, nil
+		},
+		SearchFunc: func(ctx context.Context, name string, limit int) ([]*model.User, error) {
+			users := make([]*model.User, limit)
+			for i := range users {
+				users[i] = &model.User{Name: name}
+			}
+			return users, nil
+		},
+		RenameFunc: func(ctx context.Context, id string, name string) (*model.User, error) {
+			return &model.User{ID: id, Name: name}, nil
+		},
+		RemoveFunc: func(ctx context.Context, id string) error {
+			removed = id
+			return nil
+		},
+		CountFunc: func(ctx context.Context) (int, error) {
+			return 3, nil
+		},
+	}))
+	defer srv.Close()
+
+	client, err := endpoints.NewHTTPClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, id := range []string{"7", "a b/7", "100%"} {
+		if user, err := client.Lookup(ctx, id); err != nil || user.ID != id {
+			t.Errorf("Lookup: %+v, %v, want user %s", user, err, id)
+		}
+	}
+	var httpErr *endpoints.HTTPError
+	if _, err := client.Lookup(ctx, "0"); !errors.As(err, &httpErr) || httpErr.Code != http.StatusInternalServerError {
+		t.Errorf("Lookup: %v, want an HTTPError with status 500", err)
+	}
+	if users, err := client.Search(ctx, "ann", 2); err != nil || !reflect.DeepEqual(users, []*model.User{{Name: "ann"}, {Name: "ann"}}) {
+		t.Errorf("Search: %v, %v, want 2 users named ann", users, err)
+	}
+	if user, err := client.Rename(ctx, "7", "bob"); err != nil || *user != (model.User{ID: "7", Name: "bob"}) {
+		t.Errorf("Rename: %+v, %v, want user 7 named bob", user, err)
+	}
+	if err := client.Remove(ctx, "7"); err != nil || removed != "7" {
+		t.Errorf("Remove: %v, removed %q, want 7", err, removed)
+	}
+	if n, err := client.Count(ctx); err != nil || n != 3 {
+		t.Errorf("Count: %d, %v, want 3", n, err)
+	}
+}
+
+// TestHedge checks that a slow call of Lookup, annotated with kit:hedge
+// 50ms, is raced by a hedged one, whose response is returned.
+func TestHedge(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(&endpoints.MockService{
+		LookupFunc: func(ctx context.Context, id string) (*model.User, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				select {
+				case <-ctx.Done():
+				case <-time.After(5 * time.Second):
+				}
+				return &model.User{ID: "slow"}, nil
+			}
+			return &model.User{ID: id}, nil
+		},
+	}))
+	defer srv.Close()
+	client, err := endpoints.NewHTTPClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	begin := time.Now()
+	if user, err := client.Lookup(context.Background(), "7"); err != nil || user.ID != "7" {
+		t.Errorf("Lookup: %+v, %v, want user 7 of the hedged call", user, err)
+	}
+	if d := time.Since(begin); d > time.Second {
+		t.Errorf("Lookup took %v, want the hedged call to answer after 50ms", d)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("%d calls, want 2", n)
+	}
+}
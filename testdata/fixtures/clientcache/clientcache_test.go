@@ -0,0 +1,157 @@
+// Package clientcache sends requests through the caching transport generated
+// into example.com/fixtures/endpoints, with -client-cache, by TestClientCache
+// of kitboiler.
+package clientcache
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+)
+
+// server answers with cacheControl and an ETag, and counts the requests it
+// answers and those it answers with 304 Not Modified.
+func server(cacheControl string, requests, notModified *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Header().Set("ETag", `"1"`)
+		if r.Header.Get("If-None-Match") == `"1"` {
+			*notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("user"))
+	}))
+}
+
+func get(t *testing.T, client *http.Client, url string) {
+	t.Helper()
+	res, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || string(body) != "user" {
+		t.Errorf("GET %s: %s %q, want 200 OK %q", url, res.Status, body, "user")
+	}
+}
+
+func TestFresh(t *testing.T) {
+	var requests, notModified int
+	srv := server("max-age=60", &requests, &notModified)
+	defer srv.Close()
+	client := &http.Client{Transport: endpoints.NewCachingTransport(endpoints.NewMemoryCacheStore(), nil)}
+	get(t, client, srv.URL)
+	get(t, client, srv.URL)
+	if requests != 1 {
+		t.Errorf("%d requests reached the server, want 1", requests)
+	}
+}
+
+func TestRevalidate(t *testing.T) {
+	var requests, notModified int
+	srv := server("no-cache", &requests, &notModified)
+	defer srv.Close()
+	client := &http.Client{Transport: endpoints.NewCachingTransport(endpoints.NewMemoryCacheStore(), nil)}
+	get(t, client, srv.URL)
+	get(t, client, srv.URL)
+	if requests != 2 || notModified != 1 {
+		t.Errorf("%d requests reached the server, %d answered with 304, want 2 and 1", requests, notModified)
+	}
+}
+
+// TestReadRoutes posts to the routes of GetUser, a read method with an ETag,
+// and UpdateUser, behind a base path, and checks that only the responses
+// to GetUser are cached, by request body.
+func TestReadRoutes(t *testing.T) {
+	var requests, notModified int
+	srv := server("max-age=60", &requests, &notModified)
+	defer srv.Close()
+	client := &http.Client{Transport: endpoints.NewCachingTransport(endpoints.NewMemoryCacheStore(), nil)}
+	for _, c := range []struct {
+		path, body string
+		requests   int
+	}{
+		{"/api/get-user", `{"Id":"1"}`, 1},
+		{"/api/get-user", `{"Id":"1"}`, 1},
+		{"/api/get-user", `{"Id":"2"}`, 2},
+		{"/api/update-user", `{"Id":"1"}`, 3},
+		{"/api/update-user", `{"Id":"1"}`, 4},
+	} {
+		res, err := client.Post(srv.URL+c.path, "application/json", strings.NewReader(c.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil || string(body) != "user" {
+			t.Errorf("POST %s %s: %q, %v, want %q", c.path, c.body, body, err, "user")
+		}
+		if requests != c.requests {
+			t.Errorf("POST %s %s: %d requests reached the server, want %d", c.path, c.body, requests, c.requests)
+		}
+	}
+}
+
+// TestCredentials checks that the responses fetched with one bearer token
+// aren't served to the requests with another one, that the responses
+// varying by a header are kept apart by it and that private responses
+// aren't stored.
+func TestCredentials(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/private":
+			w.Header().Set("Cache-Control", "private, max-age=60")
+		case "/vary":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Accept-Language")
+		default:
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+		w.Write([]byte(r.Header.Get("Authorization") + r.Header.Get("Accept-Language")))
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: endpoints.NewCachingTransport(endpoints.NewMemoryCacheStore(), nil)}
+	for _, c := range []struct {
+		path, header, value string
+		requests            int
+	}{
+		{"/user", "Authorization", "Bearer alice", 1},
+		{"/user", "Authorization", "Bearer bob", 2},
+		{"/user", "Authorization", "Bearer alice", 2},
+		{"/vary", "Accept-Language", "en", 3},
+		{"/vary", "Accept-Language", "nl", 4},
+		{"/vary", "Accept-Language", "nl", 4},
+		{"/private", "Authorization", "Bearer alice", 5},
+		{"/private", "Authorization", "Bearer alice", 6},
+	} {
+		r, err := http.NewRequest("GET", srv.URL+c.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set(c.header, c.value)
+		res, err := client.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil || string(body) != c.value {
+			t.Errorf("GET %s with %s %s: %q, %v, want %q", c.path, c.header, c.value, body, err, c.value)
+		}
+		if requests != c.requests {
+			t.Errorf("GET %s with %s %s: %d requests reached the server, want %d", c.path, c.header, c.value, requests, c.requests)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Package compressed serves requests through the AMQP subscribers generated
+// into example.com/fixtures/endpoints, with -transports http,amqp,
+// -compression zstd and -mock, by TestCompression of kitboiler, on a fake
+// channel.
+package compressed
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+	"example.com/fixtures/model"
+	"github.com/klauspost/compress/zstd"
+	"github.com/streadway/amqp"
+)
+
+// channel records the messages published on it.
+type channel struct {
+	messages []amqp.Publishing
+}
+
+func (c *channel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	c.messages = append(c.messages, msg)
+	return nil
+}
+
+func (c *channel) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	return nil, nil
+}
+
+type acknowledger struct{}
+
+func (acknowledger) Ack(tag uint64, multiple bool) error { return nil }
+
+func (acknowledger) Nack(tag uint64, multiple, requeue bool) error { return nil }
+
+func (acknowledger) Reject(tag uint64, requeue bool) error { return nil }
+
+func TestCompression(t *testing.T) {
+	if endpoints.AMQPCompression != "zstd" {
+		t.Errorf("AMQPCompression = %q, want zstd", endpoints.AMQPCompression)
+	}
+	serve := endpoints.NewAMQPSubscribers(&endpoints.MockService{
+		GetUserFunc: func(ctx context.Context, id string) (*model.User, error) {
+			return &model.User{ID: id}, nil
+		},
+	})[endpoints.GetUserAMQPQueue]
+
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := []byte(`{"Id": "7"}`)
+	for _, c := range []struct {
+		body     []byte
+		encoding string
+	}{
+		{enc.EncodeAll(request, nil), "zstd"},
+		{request, ""},
+	} {
+		ch := &channel{}
+		serve.ServeDelivery(ch)(&amqp.Delivery{Acknowledger: acknowledger{}, Body: c.body})
+		if len(ch.messages) != 1 {
+			t.Fatalf("replied %d times, want once", len(ch.messages))
+		}
+		reply := ch.messages[0]
+		if reply.ContentEncoding != c.encoding || (c.encoding == "") != bytes.HasPrefix(reply.Body, []byte("{")) {
+			t.Errorf("replied %q encoded %q to a request encoded %q", reply.Body, reply.ContentEncoding, c.encoding)
+		}
+		response, err := endpoints.DecodeAMQPGetUserResponse(context.Background(), &amqp.Delivery{Body: reply.Body})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user := response.(endpoints.GetUserResponse).User; user == nil || user.ID != "7" {
+			t.Errorf("replied %+v, want the user 7", response)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+// Package constraints sends requests to the handlers generated into
+// example.com/fixtures/endpoints, with -openapi-constraints specs/users.yaml
+// -mock, by TestConstraints of kitboiler, and with -dto added by TestDTO.
+package constraints
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+)
+
+func TestConstraints(t *testing.T) {
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(&endpoints.MockService{}))
+	defer srv.Close()
+	for _, c := range []struct {
+		path, body string
+		status     int
+		field      string // the field the response blames
+	}{
+		{"/create-user", `{"name": "Ann", "age": 42}`, http.StatusOK, ""},
+		{"/create-user", `{"name": "", "age": 42}`, http.StatusBadRequest, "Name"},
+		{"/create-user", `{"name": "ann", "age": 42}`, http.StatusBadRequest, "Name"},
+		{"/create-user", `{"name": "Ann", "age": 151}`, http.StatusBadRequest, "Age"},
+		{"/update-user", `{"id": "1", "name": "Ann", "status": "active"}`, http.StatusOK, ""},
+		{"/update-user", `{"id": "1", "name": "Annabella Smith", "status": "active"}`, http.StatusBadRequest, "Name"},
+		{"/update-user", `{"id": "1", "name": "Ann", "status": "gone"}`, http.StatusBadRequest, "Status"},
+	} {
+		resp, err := http.Post(srv.URL+c.path, "application/json", strings.NewReader(c.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != c.status || !strings.Contains(string(body), c.field) {
+			t.Errorf("POST %s %s: %s %s, want %d blaming %q", c.path, c.body, resp.Status, body, c.status, c.field)
+		}
+	}
+}
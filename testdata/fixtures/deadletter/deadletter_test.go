@@ -0,0 +1,101 @@
+// Package deadletter serves requests through the AMQP subscribers generated
+// into example.com/fixtures/endpoints, with -transports http,amqp,
+// -dead-letter and -mock, by TestDeadLetter of kitboiler, on a fake channel.
+package deadletter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/fixtures/endpoints"
+	"example.com/fixtures/model"
+	"github.com/go-kit/kit/metrics"
+	"github.com/streadway/amqp"
+)
+
+// channel records the keys the messages published on it are routed with.
+type channel struct {
+	keys []string
+}
+
+func (c *channel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	c.keys = append(c.keys, key)
+	return nil
+}
+
+func (c *channel) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	return nil, nil
+}
+
+type acknowledger struct{}
+
+func (acknowledger) Ack(tag uint64, multiple bool) error { return nil }
+
+func (acknowledger) Nack(tag uint64, multiple, requeue bool) error { return nil }
+
+func (acknowledger) Reject(tag uint64, requeue bool) error { return nil }
+
+// counter records the label values of its observations.
+type counter struct {
+	labels *[]string
+	lvs    []string
+}
+
+func (c counter) With(lvs ...string) metrics.Counter {
+	return counter{labels: c.labels, lvs: append(c.lvs, lvs...)}
+}
+
+func (c counter) Add(delta float64) {
+	*c.labels = append(*c.labels, strings.Join(c.lvs, " "))
+}
+
+func TestDeadLetter(t *testing.T) {
+	var retries, deadLetters []string
+	endpoints.RetryCount = counter{labels: &retries}
+	endpoints.DeadLetterCount = counter{labels: &deadLetters}
+	endpoints.MessageRetry.Backoff = time.Millisecond
+
+	calls := 0
+	serve := endpoints.NewAMQPSubscribers(&endpoints.MockService{
+		GetUserFunc: func(ctx context.Context, id string) (*model.User, error) {
+			calls++
+			if id == "down" {
+				return nil, errors.New("database down")
+			}
+			return &model.User{ID: id}, nil
+		},
+	})[endpoints.GetUserAMQPQueue]
+
+	dlq := endpoints.GetUserAMQPQueue + ".dlq"
+	for _, c := range []struct {
+		body        string
+		calls       int
+		retries     int
+		keys        []string
+		deadLetters []string
+	}{
+		{`{"Id": "7"}`, 1, 0, []string{"replies"}, nil},
+		{`{"Id": "down"}`, 3, 2, []string{dlq, "replies"}, []string{"transport amqp method GetUser reason failed"}},
+		{`{"Id":`, 0, 0, []string{dlq, "replies"}, []string{"transport amqp method GetUser reason undecodable"}},
+	} {
+		calls, retries, deadLetters = 0, nil, nil
+		ch := &channel{}
+		serve.ServeDelivery(ch)(&amqp.Delivery{Acknowledger: acknowledger{}, ReplyTo: "replies", Body: []byte(c.body)})
+		if calls != c.calls {
+			t.Errorf("%s: called GetUser %d times, want %d", c.body, calls, c.calls)
+		}
+		if len(retries) != c.retries {
+			t.Errorf("%s: counted retries %q, want %d", c.body, retries, c.retries)
+		}
+		if !reflect.DeepEqual(ch.keys, c.keys) {
+			t.Errorf("%s: published to %q, want %q", c.body, ch.keys, c.keys)
+		}
+		if !reflect.DeepEqual(deadLetters, c.deadLetters) {
+			t.Errorf("%s: counted dead letters %q, want %q", c.body, deadLetters, c.deadLetters)
+		}
+	}
+}
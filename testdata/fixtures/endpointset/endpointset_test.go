@@ -0,0 +1,54 @@
+// Package endpointset calls the service generated into
+// example.com/fixtures/endpoints, with -endpoint-set -mock, by
+// TestEndpointSet of kitboiler through the methods of its Endpoints.
+package endpointset
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"example.com/fixtures/api"
+	"example.com/fixtures/endpoints"
+	"example.com/fixtures/model"
+)
+
+func TestEndpointSet(t *testing.T) {
+	var svc api.UserService = endpoints.MakeEndpoints(&endpoints.MockService{
+		GetUserFunc: func(ctx context.Context, id string) (*model.User, error) {
+			if id == "2" {
+				return nil, model.ErrNotFound
+			}
+			return &model.User{ID: id, Name: "ann"}, nil
+		},
+		ListUsersFunc: func(ctx context.Context, opts ...model.ListOptionsSetter) ([]*model.User, error) {
+			var o model.ListOptions
+			for _, set := range opts {
+				set(&o)
+			}
+			return make([]*model.User, o.Limit), nil
+		},
+		ProfileFunc: func(ctx context.Context, id string) (model.User, error) {
+			return model.User{ID: id, Age: 42}, nil
+		},
+	})
+	ctx := context.Background()
+
+	user, err := svc.GetUser(ctx, "1")
+	if err != nil || user == nil || user.Name != "ann" {
+		t.Errorf("GetUser(1) = %+v, %v, want ann", user, err)
+	}
+	if _, err := svc.GetUser(ctx, "2"); err != model.ErrNotFound {
+		t.Errorf("GetUser(2) fails with %v, want %v", err, model.ErrNotFound)
+	}
+	users, err := svc.ListUsers(ctx, func(o *model.ListOptions) { o.Limit = 3 })
+	if err != nil || len(users) != 3 {
+		t.Errorf("ListUsers(limit 3) = %d users, %v", len(users), err)
+	}
+	if profile, err := svc.Profile(ctx, "1"); err != nil || profile.Age != 42 {
+		t.Errorf("Profile(1) = %+v, %v, want age 42", profile, err)
+	}
+	if err := svc.Ping(); !errors.Is(err, endpoints.ErrNoEndpoint) {
+		t.Errorf("Ping fails with %v, want %v", err, endpoints.ErrNoEndpoint)
+	}
+}
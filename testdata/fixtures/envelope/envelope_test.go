@@ -0,0 +1,71 @@
+// Package envelope calls the handler generated into
+// example.com/fixtures/endpoints, with -response-envelope, -client and
+// -mock, by TestEnvelope of kitboiler, over HTTP and through the client.
+package envelope
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+	"example.com/fixtures/inventory"
+)
+
+func TestEnvelope(t *testing.T) {
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(&endpoints.MockService{
+		ReserveFunc: func(ctx context.Context, item string, n int) (int, error) {
+			switch item {
+			case "unknown":
+				return 0, inventory.ErrNotFound
+			case "broken":
+				return 0, errors.New("database down")
+			}
+			return 10 - n, nil
+		},
+	}))
+	defer srv.Close()
+
+	for _, c := range []struct {
+		item   string
+		status int
+		body   string
+	}{
+		{"pen", http.StatusOK, `{"data":{"Left":8}}`},
+		{"unknown", http.StatusNotFound, `{"error":"item not found"}`},
+		{"broken", http.StatusInternalServerError, `{"error":"database down"}`},
+	} {
+		resp, err := http.Post(srv.URL+"/reserve", "application/json", strings.NewReader(`{"Item": "`+c.item+`", "N": 2}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != c.status || strings.TrimSpace(string(body)) != c.body {
+			t.Errorf("reserving %s: %s %s, want %d %s", c.item, resp.Status, body, c.status, c.body)
+		}
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+			t.Errorf("reserving %s: Content-Type %s, want JSON", c.item, ct)
+		}
+	}
+
+	client, err := endpoints.NewHTTPClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if left, err := client.Reserve(context.Background(), "pen", 3); left != 7 || err != nil {
+		t.Errorf("Reserve: %d, %v, want 7", left, err)
+	}
+	var httpErr *endpoints.HTTPError
+	_, err = client.Reserve(context.Background(), "unknown", 1)
+	if !errors.As(err, &httpErr) || httpErr.Code != http.StatusNotFound || httpErr.Message != "item not found" {
+		t.Errorf("Reserve: %v, want an HTTPError 404 item not found", err)
+	}
+}
@@ -0,0 +1,96 @@
+// Package fallback calls the handler generated into
+// example.com/fixtures/endpoints, with -client, -router chi and -mock, by
+// TestFallback of kitboiler, through a client in degraded mode.
+package fallback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics"
+
+	"example.com/fixtures/endpoints"
+	"example.com/fixtures/model"
+)
+
+// counter counts the observations of a metrics.Counter by their labels.
+type counter struct {
+	counts map[string]float64
+	labels string
+}
+
+func (c counter) With(labelValues ...string) metrics.Counter {
+	for _, v := range labelValues {
+		c.labels += "/" + v
+	}
+	return c
+}
+
+func (c counter) Add(delta float64) { c.counts[c.labels] += delta }
+
+// errOpen is the error of the circuit breaker of the test as it is open.
+var errOpen = errors.New("circuit breaker is open")
+
+func TestFallback(t *testing.T) {
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(&endpoints.MockService{
+		SearchFunc: func(ctx context.Context, name string, limit int) ([]*model.User, error) {
+			if name == "slow" {
+				time.Sleep(100 * time.Millisecond)
+			}
+			return []*model.User{{Name: name}}, nil
+		},
+		CountFunc: func(ctx context.Context) (int, error) {
+			return 3, nil
+		},
+	}))
+	defer srv.Close()
+
+	open := map[string]bool{}
+	endpoints.ClientBreaker = func(method string) endpoint.Middleware {
+		return func(next endpoint.Endpoint) endpoint.Endpoint {
+			return func(ctx context.Context, request interface{}) (interface{}, error) {
+				if open[method] {
+					return nil, fmt.Errorf("%s: %w", method, errOpen)
+				}
+				return next(ctx, request)
+			}
+		}
+	}
+	endpoints.CircuitOpenErrors = []error{errOpen}
+	fallbacks := counter{counts: map[string]float64{}}
+	endpoints.FallbackCount = fallbacks
+	client, err := endpoints.NewHTTPClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if users, err := client.Search(ctx, "ann", 1); err != nil || len(users) != 1 {
+		t.Errorf("Search: %v, %v, want the users of the service", users, err)
+	}
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if users, err := client.Search(timeout, "slow", 1); err != nil || users == nil || len(users) != 0 {
+		t.Errorf("Search timing out: %v, %v, want the fallback", users, err)
+	}
+	open["Search"], open["Count"] = true, true
+	if users, err := client.Search(ctx, "ann", 1); err != nil || users == nil || len(users) != 0 {
+		t.Errorf("Search with the circuit open: %v, %v, want the fallback", users, err)
+	}
+	if _, err := client.Count(ctx); err == nil {
+		t.Error("Count with the circuit open succeeded, want its error as it has no fallback")
+	}
+	if endpoints.IsCircuitOpen(errors.New(errOpen.Error())) {
+		t.Error("IsCircuitOpen of an error with the message of the breaker, want false")
+	}
+	want := map[string]float64{"/method/Search/reason/timeout": 1, "/method/Search/reason/circuit-open": 1}
+	if !reflect.DeepEqual(fallbacks.counts, want) {
+		t.Errorf("FallbackCount %v, want %v", fallbacks.counts, want)
+	}
+}
@@ -0,0 +1,7 @@
+// Package geo is named differently from its import path, which the
+// generated files must import it by.
+package geo
+
+type Point struct {
+	Lat, Lng float64
+}
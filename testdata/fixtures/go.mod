@@ -0,0 +1,17 @@
+module example.com/fixtures
+
+go 1.25
+
+require (
+	github.com/go-chi/chi/v5 v5.3.2
+	github.com/go-kit/kit v0.9.0
+	github.com/gorilla/mux v1.8.1
+	github.com/klauspost/compress v1.20.1
+	github.com/streadway/amqp v1.1.0
+	gopkg.in/yaml.v2 v2.4.0
+)
+
+require (
+	github.com/go-logfmt/logfmt v0.6.1 // indirect
+	github.com/google/go-cmp v0.7.0 // indirect
+)
@@ -0,0 +1,31 @@
+// Package gone sends requests to the handler generated into
+// example.com/fixtures/endpoints, with -keep-removed -mock, by
+// TestKeepRemoved of kitboiler once DeleteUser is removed from the
+// interface.
+package gone
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+)
+
+func TestGone(t *testing.T) {
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(&endpoints.MockService{}))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/delete-user", "application/json", strings.NewReader(`{"Id": "1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGone {
+		t.Errorf("POST /delete-user: %s, want 410 Gone", resp.Status)
+	}
+	if got := resp.Header.Get("Deprecation"); got != "true" {
+		t.Errorf("Deprecation header %q, want true", got)
+	}
+}
@@ -0,0 +1,38 @@
+// Package group sends requests to the handlers generated into
+// example.com/fixtures/endpoints for orders.OrderService, with -mock, by
+// TestGroup of kitboiler.
+package group
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+)
+
+func TestGroup(t *testing.T) {
+	svc := &endpoints.MockService{}
+	for _, tt := range []struct {
+		name    string
+		handler http.Handler
+		routes  map[string]int
+	}{
+		{"MakeHTTPHandler", endpoints.MakeHTTPHandler(svc), map[string]int{"/placed": http.StatusOK, "/cancel-order": http.StatusNotFound}},
+		{"MakeAdminHTTPHandler", endpoints.MakeAdminHTTPHandler(svc), map[string]int{"/placed": http.StatusNotFound, "/cancel-order": http.StatusOK}},
+	} {
+		srv := httptest.NewServer(tt.handler)
+		for path, want := range tt.routes {
+			resp, err := http.Post(srv.URL+path, "application/json", strings.NewReader(`{"id": "1"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != want {
+				t.Errorf("%s: POST %s: %s, want %d", tt.name, path, resp.Status, want)
+			}
+		}
+		srv.Close()
+	}
+}
@@ -0,0 +1,45 @@
+// Package hedge calls endpoints through the middleware generated into
+// example.com/fixtures/endpoints, with -hedge, by TestHedge of kitboiler.
+package hedge
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"example.com/fixtures/endpoints"
+)
+
+func TestHedge(t *testing.T) {
+	var calls int32
+	// the first request hangs until it is canceled, the hedged one answers
+	e := endpoints.Hedge(10 * time.Millisecond)(func(ctx context.Context, request interface{}) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return "hedged", nil
+	})
+	response, err := e(context.Background(), nil)
+	if err != nil || response != "hedged" {
+		t.Errorf("got %v, %v, want the response of the hedged request", response, err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("%d requests, want 2", n)
+	}
+}
+
+func TestHedgeFast(t *testing.T) {
+	var calls int32
+	e := endpoints.Hedge(time.Second)(func(ctx context.Context, request interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return "first", nil
+	})
+	if response, err := e(context.Background(), nil); err != nil || response != "first" {
+		t.Errorf("got %v, %v, want the response of the first request", response, err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("%d requests, want no hedged request", n)
+	}
+}
@@ -0,0 +1,125 @@
+// Package hooks sends requests to the handlers generated into
+// example.com/fixtures/endpoints, with -hooks -mock -endpoint-set, by
+// TestHooks of kitboiler.
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+
+	"example.com/fixtures/endpoints"
+	"example.com/fixtures/model"
+)
+
+func TestHooks(t *testing.T) {
+	endpoints.DecodeCreateUserHook = func(r *http.Request, req *endpoints.CreateUserRequest) error {
+		if req.Name == "" {
+			req.Name = r.Header.Get("X-User-Name")
+		}
+		if req.Age < 0 {
+			return errors.New("negative age")
+		}
+		return nil
+	}
+	endpoints.EncodeGetUserHook = func(w http.ResponseWriter, res *endpoints.GetUserResponse) error {
+		w.Header().Set("X-User-Name", res.User.Name)
+		res.User.Name = strings.ToUpper(res.User.Name)
+		return nil
+	}
+	endpoints.ServerOptions = append(endpoints.ServerOptions, httptransport.ServerAfter(func(ctx context.Context, w http.ResponseWriter) context.Context {
+		w.Header().Set("X-Served-By", "hooks")
+		return ctx
+	}))
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(&endpoints.MockService{
+		CreateUserFunc: func(ctx context.Context, name string, age int) (*model.User, error) {
+			return &model.User{Name: name, Age: age}, nil
+		},
+		GetUserFunc: func(ctx context.Context, id string) (*model.User, error) {
+			return &model.User{ID: id, Name: "ann", Version: 1}, nil
+		},
+	}))
+	defer srv.Close()
+
+	req, err := http.NewRequest("POST", srv.URL+"/create-user", strings.NewReader(`{"age": 42}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-User-Name", "bob")
+	var created endpoints.CreateUserResponse
+	if resp := do(t, req, &created); resp.StatusCode != http.StatusOK || created.User.Name != "bob" {
+		t.Errorf("POST /create-user: %s %+v, want the name of the header", resp.Status, created.User)
+	}
+	req, _ = http.NewRequest("POST", srv.URL+"/create-user", strings.NewReader(`{"name": "bob", "age": -1}`))
+	if resp := do(t, req, nil); resp.StatusCode == http.StatusOK {
+		t.Errorf("POST /create-user with a negative age: %s, want the hook to reject it", resp.Status)
+	}
+
+	req, _ = http.NewRequest("POST", srv.URL+"/get-user", strings.NewReader(`{"id": "1"}`))
+	var got endpoints.GetUserResponse
+	resp := do(t, req, &got)
+	if resp.Header.Get("X-User-Name") != "ann" || got.User.Name != "ANN" || resp.Header.Get("ETag") == "" || resp.Header.Get("X-Served-By") != "hooks" {
+		t.Errorf("POST /get-user: %v %+v, want the header and name set by the hook, the ETag and the header of the server option", resp.Header, got.User)
+	}
+}
+
+// do sends req and decodes the response body into v, if set.
+func do(t *testing.T, req *http.Request, v interface{}) *http.Response {
+	t.Helper()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp
+}
+
+func TestEndpointMiddlewares(t *testing.T) {
+	var calls []string
+	trace := func(name string) endpoint.Middleware {
+		return func(next endpoint.Endpoint) endpoint.Endpoint {
+			return func(ctx context.Context, request interface{}) (interface{}, error) {
+				calls = append(calls, name)
+				return next(ctx, request)
+			}
+		}
+	}
+	defer func(mws []endpoint.Middleware) { endpoints.DeleteUserEndpointMiddlewares = mws }(endpoints.DeleteUserEndpointMiddlewares)
+	endpoints.DeleteUserEndpointMiddlewares = append(endpoints.DeleteUserEndpointMiddlewares, trace("outer"), trace("inner"))
+	svc := &endpoints.MockService{
+		DeleteUserFunc: func(ctx context.Context, id string) error {
+			calls = append(calls, "delete "+id)
+			return nil
+		},
+		GetUserFunc: func(ctx context.Context, id string) (*model.User, error) {
+			calls = append(calls, "get "+id)
+			return &model.User{ID: id}, nil
+		},
+	}
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(svc))
+	defer srv.Close()
+
+	req, _ := http.NewRequest("POST", srv.URL+"/delete-user", strings.NewReader(`{"id": "1"}`))
+	do(t, req, nil)
+	req, _ = http.NewRequest("POST", srv.URL+"/get-user", strings.NewReader(`{"id": "2"}`))
+	do(t, req, nil)
+	if err := endpoints.MakeEndpoints(svc).DeleteUser(context.Background(), "3"); err != nil {
+		t.Fatal(err)
+	}
+	want := "outer,inner,delete 1,get 2,outer,inner,delete 3"
+	if got := strings.Join(calls, ","); got != want {
+		t.Errorf("calls %s, want %s", got, want)
+	}
+}
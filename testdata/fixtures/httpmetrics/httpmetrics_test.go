@@ -0,0 +1,82 @@
+// Package httpmetrics observes the metrics of the handlers generated into
+// example.com/fixtures/endpoints, with -metrics -mock, by TestMetrics of
+// kitboiler.
+package httpmetrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/metrics"
+
+	"example.com/fixtures/endpoints"
+)
+
+// observations records the values of a metric by their labels.
+type observations struct {
+	labels []string
+	values map[string][]float64
+}
+
+func (o *observations) With(labelValues ...string) metrics.Counter {
+	return &observations{labels: append(o.labels, labelValues...), values: o.values}
+}
+
+func (o *observations) Add(delta float64) {
+	key := strings.Join(o.labels, " ")
+	o.values[key] = append(o.values[key], delta)
+}
+
+func (o *observations) Observe(value float64) { o.Add(value) }
+
+type histogram struct{ *observations }
+
+func (h histogram) With(labelValues ...string) metrics.Histogram {
+	return histogram{h.observations.With(labelValues...).(*observations)}
+}
+
+func TestMetrics(t *testing.T) {
+	count := &observations{values: map[string][]float64{}}
+	size := &observations{values: map[string][]float64{}}
+	endpoints.RequestCount = count
+	endpoints.RequestSize = histogram{size}
+	options := &observations{values: map[string][]float64{}}
+	endpoints.OptionCount = options
+
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(&endpoints.MockService{
+		DeleteUserFunc: func(ctx context.Context, id string) error {
+			return errors.New("user owns resources")
+		},
+	}))
+	defer srv.Close()
+	for _, path := range []string{"/get-user", "/get-user", "/delete-user"} {
+		resp, err := http.Post(srv.URL+path, "application/json", strings.NewReader(`{"id": "1"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	if got := count.values["method GetUser code 200"]; len(got) != 2 {
+		t.Errorf("GetUser answered with 200: counted %v, want 2 requests", got)
+	}
+	if got := count.values["method DeleteUser code 500"]; len(got) != 1 {
+		t.Errorf("DeleteUser answered with 500: counted %v, want 1 request", got)
+	}
+	if got := size.values["method GetUser"]; len(got) != 2 || got[0] != float64(len(`{"id": "1"}`)) {
+		t.Errorf("sizes of the GetUser requests: %v, want 2 of %d bytes", got, len(`{"id": "1"}`))
+	}
+
+	resp, err := http.Post(srv.URL+"/list-users", "application/json", strings.NewReader(`{"opts": {"limit": 10}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := options.values; len(got) != 1 || len(got["method ListUsers option Limit"]) != 1 {
+		t.Errorf("options of the ListUsers request: counted %v, want Limit", got)
+	}
+}
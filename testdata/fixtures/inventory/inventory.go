@@ -0,0 +1,27 @@
+// Package inventory declares an interface whose errors map to HTTP
+// statuses.
+package inventory
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound is returned for unknown items.
+//
+//kit:status 404
+var ErrNotFound = errors.New("item not found")
+
+// ConflictError is returned when reserving more items than in stock.
+//
+//kit:status 409
+type ConflictError struct {
+	Item string
+}
+
+func (e *ConflictError) Error() string { return e.Item + " is out of stock" }
+
+// InventoryService reserves items.
+type InventoryService interface {
+	Reserve(ctx context.Context, item string, n int) (left int, err error)
+}
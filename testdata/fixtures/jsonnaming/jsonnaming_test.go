@@ -0,0 +1,72 @@
+// Package jsonnaming calls the handler generated into
+// example.com/fixtures/endpoints, with -json-naming snake, -client,
+// -router chi and -mock, by TestJSONNaming of kitboiler, over HTTP and
+// through the generated client.
+package jsonnaming
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+	"example.com/fixtures/model"
+)
+
+func TestJSONNaming(t *testing.T) {
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(&endpoints.MockService{
+		SearchFunc: func(ctx context.Context, name string, limit int) ([]*model.User, error) {
+			users := make([]*model.User, limit)
+			for i := range users {
+				users[i] = &model.User{Name: name}
+			}
+			return users, nil
+		},
+		RenameFunc: func(ctx context.Context, id string, name string) (*model.User, error) {
+			return &model.User{ID: id, Name: name}, nil
+		},
+		CountFunc: func(ctx context.Context) (int, error) {
+			return 3, nil
+		},
+	}))
+	defer srv.Close()
+
+	for _, c := range []struct {
+		method, path, body, want string
+	}{
+		{"GET", "/users?name=ann&limit=1", "", `{"users":[{"ID":"","Name":"ann"`},
+		{"PUT", "/users/7", `{"name": "bob"}`, `{"user":{"ID":"7","Name":"bob"`},
+		{"POST", "/count", `{}`, `{"n":3}`},
+	} {
+		req, err := http.NewRequest(c.method, srv.URL+c.path, strings.NewReader(c.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(body), c.want) {
+			t.Errorf("%s %s %s: %s, want %s", c.method, c.path, c.body, body, c.want)
+		}
+	}
+
+	client, err := endpoints.NewHTTPClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if users, err := client.Search(context.Background(), "ann", 2); err != nil || len(users) != 2 || users[0].Name != "ann" {
+		t.Errorf("Search: %v, %v, want 2 users named ann", users, err)
+	}
+	if user, err := client.Rename(context.Background(), "7", "bob"); err != nil || user.Name != "bob" {
+		t.Errorf("Rename: %+v, %v, want user 7 named bob", user, err)
+	}
+}
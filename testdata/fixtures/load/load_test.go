@@ -0,0 +1,42 @@
+// Package load runs the load test generated into
+// example.com/fixtures/endpoints/loadtest, with -loadtest -mock, by
+// TestLoadTest of kitboiler.
+package load
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/fixtures/endpoints"
+	"example.com/fixtures/endpoints/loadtest"
+)
+
+func TestRun(t *testing.T) {
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(&endpoints.MockService{
+		DeleteUserFunc: func(ctx context.Context, id string) error {
+			return errors.New("user owns resources")
+		},
+	}))
+	defer srv.Close()
+
+	var scenarios []loadtest.Scenario
+	for _, s := range loadtest.HTTPScenarios(srv.URL, nil, loadtest.Payloads{}) {
+		if s.Name == "GetUser" || s.Name == "DeleteUser" {
+			scenarios = append(scenarios, s)
+		}
+	}
+	report := loadtest.Run(context.Background(), scenarios, loadtest.Options{RPS: 200, Workers: 4, Duration: 250 * time.Millisecond})
+	get, del := report.Stats["GetUser"], report.Stats["DeleteUser"]
+	if get.Calls == 0 || get.Errors != 0 {
+		t.Errorf("GetUser: %d calls, %d errors, want calls without errors", get.Calls, get.Errors)
+	}
+	if del.Calls == 0 || del.Errors != del.Calls {
+		t.Errorf("DeleteUser: %d calls, %d errors, want failing calls", del.Calls, del.Errors)
+	}
+	if len(report.Stats) != 2 {
+		t.Errorf("report of %d scenarios, want 2:\n%s", len(report.Stats), report)
+	}
+}
@@ -0,0 +1,119 @@
+// Package logging checks the logging middleware generated into
+// example.com/fixtures/endpoints, with -middleware logging and -mock, by
+// TestLoggingMiddleware of kitboiler.
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"example.com/fixtures/endpoints"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	var logged []map[string]interface{}
+	logger := log.LoggerFunc(func(keyvals ...interface{}) error {
+		m := map[string]interface{}{}
+		for i := 0; i < len(keyvals); i += 2 {
+			m[fmt.Sprint(keyvals[i])] = keyvals[i+1]
+		}
+		logged = append(logged, m)
+		return nil
+	})
+	fail := errors.New("unknown token")
+	svc := endpoints.LoggingMiddleware(logger)(&endpoints.MockService{
+		LoginFunc: func(ctx context.Context, email string, password string, remember bool) (string, error) {
+			return "t0k3n", nil
+		},
+		LogoutFunc: func(ctx context.Context, token string) error {
+			return fail
+		},
+	})
+
+	token, err := svc.Login(context.Background(), "ann@example.com", "hunter2", true)
+	if token != "t0k3n" || err != nil {
+		t.Errorf("Login: %q, %v, want the result of the service", token, err)
+	}
+	if err := svc.Logout(context.Background(), "t0k3n"); err != fail {
+		t.Errorf("Logout: %v, want %v", err, fail)
+	}
+
+	if len(logged) != 2 {
+		t.Fatalf("logged %v, want a line per call", logged)
+	}
+	for i, want := range []map[string]interface{}{
+		{"method": "Login", "email": "[REDACTED]", "password": "[REDACTED]", "remember": true, "err": nil},
+		{"method": "Logout", "token": "t0k3n", "err": fail},
+	} {
+		if _, ok := logged[i]["took"].(time.Duration); !ok {
+			t.Errorf("%v: no duration", logged[i])
+		}
+		delete(logged[i], "took")
+		if fmt.Sprint(logged[i]) != fmt.Sprint(want) {
+			t.Errorf("logged %v, want %v", logged[i], want)
+		}
+	}
+}
+
+func TestLoggingControls(t *testing.T) {
+	logged := map[string]int{}
+	logger := log.LoggerFunc(func(keyvals ...interface{}) error {
+		logged[fmt.Sprint(keyvals[1])]++
+		return nil
+	})
+	fail := errors.New("unknown token")
+	svc := endpoints.LoggingMiddleware(logger)(&endpoints.MockService{
+		LoginFunc: func(ctx context.Context, email string, password string, remember bool) (string, error) {
+			if email == "" {
+				return "", fail
+			}
+			return "t0k3n", nil
+		},
+		LogoutFunc: func(ctx context.Context, token string) error {
+			if token == "" {
+				return fail
+			}
+			return nil
+		},
+	})
+	calls := func(login, failedLogin, logout, failedLogout int) {
+		logged = map[string]int{}
+		for i := 0; i < login; i++ {
+			svc.Login(context.Background(), "ann@example.com", "hunter2", false)
+		}
+		for i := 0; i < failedLogin; i++ {
+			svc.Login(context.Background(), "", "hunter2", false)
+		}
+		for i := 0; i < logout; i++ {
+			svc.Logout(context.Background(), "t0k3n")
+		}
+		for i := 0; i < failedLogout; i++ {
+			svc.Logout(context.Background(), "")
+		}
+	}
+	login, logout := endpoints.LoggingControls["Login"], endpoints.LoggingControls["Logout"]
+	defer func(level endpoints.LoggingLevel, rate int) {
+		logout.SetLevel(level)
+		login.SetSampleRate(rate)
+	}(logout.Level(), login.SampleRate())
+
+	if login.Level() != endpoints.LogAllCalls || login.SampleRate() != 2 || logout.Level() != endpoints.LogFailedCalls {
+		t.Fatalf("Login logs %v 1 in %d calls, Logout %v, want the defaults of kit:log", login.Level(), login.SampleRate(), logout.Level())
+	}
+	calls(8, 3, 5, 2)
+	if logged["Login"] != 4+3 || logged["Logout"] != 2 {
+		t.Errorf("logged %v, want 1 in 2 successful logins, failed ones and failed logouts", logged)
+	}
+
+	login.SetSampleRate(1)
+	logout.SetLevel(endpoints.LogNoCalls)
+	calls(3, 1, 2, 2)
+	if logged["Login"] != 3+1 || logged["Logout"] != 0 {
+		t.Errorf("logged %v, want every login and no logout", logged)
+	}
+}
@@ -0,0 +1,51 @@
+// Package model declares the types of the fixture interfaces.
+package model
+
+import "errors"
+
+// ErrNotFound is returned for users that don't exist.
+//
+//kit:status 404
+var ErrNotFound = errors.New("not found")
+
+type User struct {
+	ID      string
+	Name    string
+	Age     int
+	Version int
+	Status  Status
+}
+
+type Status string
+
+const (
+	StatusActive    Status = "active"
+	StatusSuspended Status = "suspended"
+)
+
+type ListOptions struct {
+	Limit  int
+	Offset int
+}
+
+type ListOptionsSetter func(*ListOptions)
+
+// Money is an amount with its currency, e.g. "12.34 EUR".
+type Money string
+
+// Shape is a geometric shape, implemented by Circle and *Square.
+type Shape interface {
+	Area() float64
+}
+
+type Circle struct {
+	Radius float64
+}
+
+func (c Circle) Area() float64 { return 3.14159 * c.Radius * c.Radius }
+
+type Square struct {
+	Side float64
+}
+
+func (s *Square) Area() float64 { return s.Side * s.Side }
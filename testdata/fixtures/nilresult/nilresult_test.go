@@ -0,0 +1,38 @@
+// Package nilresult sends requests to the handlers generated into
+// example.com/fixtures/endpoints, with -nil-result not-found -mock, by
+// TestNilResult of kitboiler.
+package nilresult
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+	"example.com/fixtures/model"
+)
+
+func TestNilResult(t *testing.T) {
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(&endpoints.MockService{
+		GetUserFunc: func(ctx context.Context, id string) (*model.User, error) {
+			if id == "1" {
+				return &model.User{ID: id, Name: "ann", Version: 1}, nil
+			}
+			return nil, nil
+		},
+	}))
+	defer srv.Close()
+
+	for id, want := range map[string]int{"1": http.StatusOK, "2": http.StatusNotFound} {
+		resp, err := http.Post(srv.URL+"/get-user", "application/json", strings.NewReader(`{"id": "`+id+`"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("POST /get-user of user %s: %s, want %d", id, resp.Status, want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+// Package orders declares an interface emitting domain events.
+package orders
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"example.com/fixtures/model"
+)
+
+type OrderService interface {
+	//kit:event OrderPlaced
+	//kit:tx
+	PlaceOrder(ctx context.Context, item string, quantity int) (id string, err error)
+	//kit:group admin
+	CancelOrder(ctx context.Context, id string) (err error)
+	Placed(ctx context.Context, id string) (at time.Time, err error)
+	//kit:tx readonly
+	Total(ctx context.Context, id string) (total model.Money, err error)
+}
+
+type PaymentService interface {
+	//kit:slo 99.9
+	//kit:request type=ChargeRequest
+	Charge(ctx context.Context, order string) (err error)
+	//kit:sensitive iban
+	//kit:pii holder category=financial
+	Refund(ctx context.Context, order string, iban string, holder string) (err error)
+}
+
+// ChargeRequest is the request of PaymentService.Charge.
+type ChargeRequest struct {
+	OrderID string `json:"order"`
+}
+
+func (r ChargeRequest) Validate() error {
+	if r.OrderID == "" {
+		return errors.New("order is required")
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Package outbox writes and relays the events of the outbox generated into
+// example.com/fixtures/endpoints for orders.OrderService, with -mock, by
+// TestOutbox of kitboiler.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+)
+
+// store is an OutboxStore in memory.
+type store struct {
+	events    []endpoints.Event
+	published map[string]bool
+}
+
+func (s *store) Write(ctx context.Context, events ...endpoints.Event) error {
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func (s *store) Pending(ctx context.Context, limit int) ([]endpoints.Event, error) {
+	var pending []endpoints.Event
+	for _, e := range s.events {
+		if !s.published[e.ID] && len(pending) < limit {
+			pending = append(pending, e)
+		}
+	}
+	return pending, nil
+}
+
+func (s *store) MarkPublished(ctx context.Context, ids ...string) error {
+	for _, id := range ids {
+		s.published[id] = true
+	}
+	return nil
+}
+
+type publisher []endpoints.Event
+
+func (p *publisher) Publish(ctx context.Context, e endpoints.Event) error {
+	*p = append(*p, e)
+	return nil
+}
+
+func TestOutbox(t *testing.T) {
+	s := &store{published: map[string]bool{}}
+	placed := false
+	svc := endpoints.OutboxMiddleware(s)(&endpoints.MockService{
+		PlaceOrderFunc: func(ctx context.Context, item string, quantity int) (string, error) {
+			if placed {
+				return "", errors.New("out of stock")
+			}
+			placed = true
+			return "order-1", nil
+		},
+	})
+	ctx := context.Background()
+	if _, err := svc.PlaceOrder(ctx, "book", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.PlaceOrder(ctx, "book", 1); err == nil {
+		t.Fatal("the second order succeeded")
+	}
+	if err := svc.CancelOrder(ctx, "order-1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.events) != 1 || s.events[0].Type != endpoints.OrderPlacedEvent {
+		t.Fatalf("events %+v, want an OrderPlaced event for the order placed", s.events)
+	}
+	var payload endpoints.OrderPlacedPayload
+	if err := json.Unmarshal(s.events[0].Payload, &payload); err != nil || payload.Id != "order-1" {
+		t.Errorf("payload %s, want the id of the order", s.events[0].Payload)
+	}
+
+	var p publisher
+	relay := &endpoints.OutboxRelay{Store: s, Publisher: &p}
+	if err := relay.RelayOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := relay.RelayOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(p) != 1 || p[0].ID != s.events[0].ID {
+		t.Errorf("published %+v, want the event once", p)
+	}
+}
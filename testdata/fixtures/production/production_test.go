@@ -0,0 +1,67 @@
+// Package production sends requests to the handlers generated into
+// example.com/fixtures/endpoints, with -recover -access-log -health -mock,
+// by TestProduction of kitboiler.
+package production
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"example.com/fixtures/endpoints"
+)
+
+func TestProduction(t *testing.T) {
+	var logged []string
+	endpoints.Logger = log.LoggerFunc(func(keyvals ...interface{}) error {
+		logged = append(logged, fmt.Sprint(keyvals...))
+		return nil
+	})
+	ready := errors.New("database is down")
+	endpoints.Ready = func(ctx context.Context) error { return ready }
+
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(&endpoints.MockService{
+		DeleteUserFunc: func(ctx context.Context, id string) error {
+			panic("user is corrupt")
+		},
+	}))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/delete-user", "application/json", strings.NewReader(`{"id": "1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("POST /delete-user: %s, want 500 after the panic", resp.Status)
+	}
+	if len(logged) != 2 || !strings.Contains(logged[0], "user is corrupt") || !strings.Contains(logged[1], "code500") {
+		t.Errorf("logged %q, want the panic and the request", logged)
+	}
+
+	for path, status := range map[string]int{"/healthz": http.StatusOK, "/readyz": http.StatusServiceUnavailable} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("GET %s: %s, want %d", path, resp.Status, status)
+		}
+	}
+	ready = nil
+	resp, err = http.Get(srv.URL + "/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /readyz: %s, want 200 once ready", resp.Status)
+	}
+}
@@ -0,0 +1,92 @@
+// Package query sends requests to the handler generated into
+// example.com/fixtures/endpoints, with -get-methods Find* and -mock, by
+// TestGetMethods of kitboiler.
+package query
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/fixtures/endpoints"
+)
+
+func TestQuery(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(&endpoints.MockService{
+		FindEventsFunc: func(ctx context.Context, ids []int, active bool, since time.Time, within time.Duration, ratio *float64) (int, error) {
+			got = fmt.Sprintf("%v %v %s %v %v", ids, active, since.UTC().Format(time.RFC3339), within, *ratio)
+			return len(ids), nil
+		},
+		FindNamesFunc: func(ctx context.Context, prefix string, max uint8) ([]string, error) {
+			got = fmt.Sprintf("%s %d", prefix, max)
+			return []string{prefix}, nil
+		},
+		FindTaggedFunc: func(ctx context.Context, tags ...string) ([]string, error) {
+			got = fmt.Sprintf("%q", tags)
+			return tags, nil
+		},
+		TagFunc: func(ctx context.Context, tag string, ids ...int) (int, error) {
+			got = fmt.Sprintf("%s %v", tag, ids)
+			return len(ids), nil
+		},
+	}))
+	defer srv.Close()
+
+	for _, c := range []struct {
+		query  string
+		status int
+		want   string
+	}{
+		{"/find-events?Ids=1&Ids=2&Active=true&Since=2020-01-02T03:04:05Z&Within=1m30s&Ratio=0.5", http.StatusOK, "[1 2] true 2020-01-02T03:04:05Z 1m30s 0.5"},
+		{"/find-names?Prefix=a%20b&Max=7", http.StatusOK, "a b 7"},
+		{"/find-events?Ids=x", http.StatusBadRequest, ""},
+		{"/find-events?Active=maybe", http.StatusBadRequest, ""},
+		{"/find-events?Since=yesterday", http.StatusBadRequest, ""},
+		{"/find-events?Within=long", http.StatusBadRequest, ""},
+		{"/find-names?Max=300", http.StatusBadRequest, ""},
+		{"/find-tagged?Tags=a&Tags=b", http.StatusOK, `["a" "b"]`},
+		{"/find-tagged", http.StatusOK, `[]`},
+	} {
+		got = ""
+		resp, err := http.Get(srv.URL + c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != c.status {
+			t.Errorf("GET %s: %s %s, want %d", c.query, resp.Status, strings.TrimSpace(string(body)), c.status)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("GET %s: decoded %q, want %q", c.query, got, c.want)
+		}
+	}
+}
+
+func TestVariadic(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(&endpoints.MockService{
+		TagFunc: func(ctx context.Context, tag string, ids ...int) (int, error) {
+			got = fmt.Sprintf("%s %v", tag, ids)
+			return len(ids), nil
+		},
+	}))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/tag", "application/json", strings.NewReader(`{"Tag": "red", "Ids": [1, 2, 3]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if got != "red [1 2 3]" || strings.TrimSpace(string(body)) != `{"N":3}` {
+		t.Errorf("POST /tag: called with %q, responded %s, want red [1 2 3] and 3", got, body)
+	}
+}
@@ -0,0 +1,49 @@
+// Package ratelimit limits requests with the handler generated into
+// example.com/fixtures/endpoints, with -ratelimit apikey, by TestRateLimit of
+// kitboiler.
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+)
+
+func TestRateLimit(t *testing.T) {
+	// no refill: every client has its burst of 2 requests
+	limited := endpoints.NewRateLimitHandler(endpoints.NewMemoryRateLimitStore(0, 2), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	// the authentication middleware in front of it verifies the keys a and b
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := r.Header.Get("X-API-Key"); key == "a" || key == "b" {
+			r = endpoints.WithRateLimitClient(r, key)
+		}
+		limited.ServeHTTP(w, r)
+	})
+	get := func(key, addr string) int {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("X-API-Key", key)
+		r.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+	for i, want := range []int{http.StatusNoContent, http.StatusNoContent, http.StatusTooManyRequests} {
+		if got := get("a", "10.0.0.1:1234"); got != want {
+			t.Errorf("request %d of client a: status %d, want %d", i+1, got, want)
+		}
+	}
+	if got := get("b", "10.0.0.1:1234"); got != http.StatusNoContent {
+		t.Errorf("request of client b: status %d, want %d", got, http.StatusNoContent)
+	}
+	// unverified keys don't evade the limit of the IP address
+	for i, want := range []int{http.StatusNoContent, http.StatusNoContent, http.StatusTooManyRequests} {
+		if got := get("forged"+strconv.Itoa(i), "10.0.0.2:1234"); got != want {
+			t.Errorf("request %d with an unverified key: status %d, want %d", i+1, got, want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Package replay posts recorded requests to the replay handler generated
+// into example.com/fixtures/endpoints, with -replay and -mock, by
+// TestReplay of kitboiler.
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+	"example.com/fixtures/model"
+)
+
+func TestReplay(t *testing.T) {
+	srv := httptest.NewServer(endpoints.NewReplayHandler(&endpoints.MockService{
+		GetUserFunc: func(ctx context.Context, id string) (*model.User, error) {
+			if id == "0" {
+				return nil, errors.New("no such user")
+			}
+			return &model.User{ID: id, Name: "ann"}, nil
+		},
+	}))
+	defer srv.Close()
+
+	for _, c := range []struct {
+		body   string
+		code   int
+		status int
+		error  string
+		trace  string
+	}{
+		{`{"method": "GetUser", "request": {"Id": "42"}}`, http.StatusOK, http.StatusOK, "", "the endpoint returned"},
+		{`{"method": "GetUser", "request": {"Id": "0"}}`, http.StatusOK, http.StatusInternalServerError, "no such user", "the endpoint failed"},
+		{`{"method": "GetUser", "request": {"Id": 42}}`, http.StatusOK, http.StatusBadRequest, "cannot unmarshal", "decoding the request failed"},
+		{`{"method": "UpdateUser", "request": {"Id": "42", "Status": "gone"}}`, http.StatusOK, http.StatusBadRequest, "Status", "validating the request failed"},
+		{`{"method": "Ping"}`, http.StatusNotFound, 0, "", ""},
+		{`{"method":`, http.StatusBadRequest, 0, "", ""},
+	} {
+		resp, err := http.Post(srv.URL, "application/json", strings.NewReader(c.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result endpoints.ReplayResult
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if resp.StatusCode != c.code {
+			t.Errorf("%s: %s, want %d", c.body, resp.Status, c.code)
+			continue
+		}
+		if c.code != http.StatusOK {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", c.body, err)
+		}
+		if result.Status != c.status || !strings.Contains(result.Error, c.error) {
+			t.Errorf("%s: status %d and error %q, want %d and %q", c.body, result.Status, result.Error, c.status, c.error)
+		}
+		if got, want := result.Response != nil, c.status == http.StatusOK; got != want {
+			t.Errorf("%s: response %v, want one %t", c.body, result.Response, want)
+		}
+		var trace []string
+		for _, e := range result.Trace {
+			trace = append(trace, e.Event)
+		}
+		if !strings.Contains(strings.Join(trace, "\n"), c.trace) {
+			t.Errorf("%s: trace %q, want %q", c.body, trace, c.trace)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Package roundtrip calls the methods of api.UserService through the HTTP
+// server and client generated into example.com/fixtures/endpoints, with
+// -client -mock, by TestRoundTrip of kitboiler.
+package roundtrip
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+	"example.com/fixtures/model"
+)
+
+var user = model.User{ID: "1", Name: "Ann", Age: 42, Version: 3, Status: model.StatusActive}
+
+func server(t *testing.T) *httptest.Server {
+	u := user
+	return httptest.NewServer(endpoints.MakeHTTPHandler(&endpoints.MockService{
+		GetUserFunc: func(_ context.Context, id string) (*model.User, error) {
+			return &u, nil
+		},
+		ProfileFunc: func(_ context.Context, id string) (model.User, error) {
+			if id != user.ID {
+				t.Errorf("Profile(%q), want %q", id, user.ID)
+			}
+			return user, nil
+		},
+	}))
+}
+
+// TestValueResult checks that a struct result returned by value, as by
+// Profile, comes back from the client as returned by the service.
+func TestValueResult(t *testing.T) {
+	srv := server(t)
+	defer srv.Close()
+	client, err := endpoints.NewHTTPClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := client.Profile(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != user {
+		t.Errorf("Profile = %+v, want %+v", got, user)
+	}
+}
+
+// TestValueResultJSON checks that a struct result is encoded the same
+// whether it is returned by value, as by Profile, or by pointer, as by
+// GetUser, ETag included.
+func TestValueResultJSON(t *testing.T) {
+	srv := server(t)
+	defer srv.Close()
+	post := func(path string) (string, string) {
+		resp, err := http.Post(srv.URL+path, "application/json", strings.NewReader(`{"id": "1"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("POST %s: %s %s", path, resp.Status, body)
+		}
+		return string(body), resp.Header.Get("ETag")
+	}
+	profile, profileETag := post("/profile")
+	getUser, getUserETag := post("/get-user")
+	// the responses differ in the name of their field only
+	if want := strings.Replace(getUser, `"User"`, `"Profile"`, 1); profile != want {
+		t.Errorf("POST /profile = %s, want %s", profile, want)
+	}
+	if profileETag == "" || profileETag != getUserETag {
+		t.Errorf("ETag of POST /profile = %q, of POST /get-user %q", profileETag, getUserETag)
+	}
+}
@@ -0,0 +1,73 @@
+// Package routes sends requests to the handler generated into
+// example.com/fixtures/endpoints, with -router chi or mux and -mock, by
+// TestRoutes of kitboiler, whose methods have kit:http routes.
+package routes
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+	"example.com/fixtures/model"
+)
+
+func TestRoutes(t *testing.T) {
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(&endpoints.MockService{
+		LookupFunc: func(ctx context.Context, id string) (*model.User, error) {
+			return &model.User{ID: id}, nil
+		},
+		SearchFunc: func(ctx context.Context, name string, limit int) ([]*model.User, error) {
+			users := make([]*model.User, limit)
+			for i := range users {
+				users[i] = &model.User{Name: name}
+			}
+			return users, nil
+		},
+		RenameFunc: func(ctx context.Context, id string, name string) (*model.User, error) {
+			return &model.User{ID: id, Name: name}, nil
+		},
+		CountFunc: func(ctx context.Context) (int, error) {
+			return 3, nil
+		},
+	}))
+	defer srv.Close()
+
+	for _, c := range []struct {
+		method, path, body string
+		status             int
+		want               string
+	}{
+		{"GET", "/users/7", "", http.StatusOK, `"ID":"7"`},
+		{"GET", "/users?Name=ann&Limit=2", "", http.StatusOK, `"Users":[{"ID":"","Name":"ann"`},
+		{"GET", "/users?Name=ann", "", http.StatusOK, `"Users":[]`},
+		{"GET", "/users?Name=ann&Limit=many", "", http.StatusBadRequest, ""},
+		{"PUT", "/users/7", `{"Name": "bob"}`, http.StatusOK, `"ID":"7","Name":"bob"`},
+		{"DELETE", "/users/7", "", http.StatusOK, ""},
+		{"POST", "/count", "{}", http.StatusOK, `{"N":3}`},
+	} {
+		req, err := http.NewRequest(c.method, srv.URL+c.path, strings.NewReader(c.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != c.status {
+			t.Errorf("%s %s: %s, want %d", c.method, c.path, resp.Status, c.status)
+			continue
+		}
+		if !strings.Contains(string(body), c.want) {
+			t.Errorf("%s %s: body %s, want %s", c.method, c.path, body, c.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Package routing checks the routing middleware generated into
+// example.com/fixtures/endpoints, with -middleware routing and -mock, by
+// TestRouting of kitboiler.
+package routing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+)
+
+// impl returns an implementation recording its name in called.
+func impl(name string, called *[]string) *endpoints.MockService {
+	return &endpoints.MockService{
+		LoginFunc: func(ctx context.Context, email string, password string, remember bool) (string, error) {
+			*called = append(*called, name)
+			return name, nil
+		},
+		LogoutFunc: func(ctx context.Context, token string) error {
+			*called = append(*called, name)
+			return nil
+		},
+	}
+}
+
+func TestRouteHeader(t *testing.T) {
+	var called []string
+	svc := endpoints.RoutingMiddleware(impl("b", &called), endpoints.RouteAny(
+		endpoints.RouteHeader("X-Canary", "rewrite"),
+		endpoints.RoutePercent(0, nil),
+	))(impl("a", &called))
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(svc))
+	defer srv.Close()
+
+	for _, canary := range []string{"", "rewrite", "other"} {
+		req, err := http.NewRequest("POST", srv.URL+"/logout", strings.NewReader(`{"Token": "t"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if canary != "" {
+			req.Header.Set("X-Canary", canary)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if got := strings.Join(called, ","); got != "a,b,a" {
+		t.Errorf("called %s, want a,b,a", got)
+	}
+}
+
+func TestRoutePercent(t *testing.T) {
+	var called []string
+	all := endpoints.RoutingMiddleware(impl("b", &called), endpoints.RoutePercent(100, nil))(impl("a", &called))
+	all.Logout(context.Background(), "t")
+	if got := strings.Join(called, ","); got != "b" {
+		t.Errorf("called %s with 100%%, want b", got)
+	}
+
+	type keyKey struct{}
+	key := func(ctx context.Context) (string, bool) {
+		k, ok := ctx.Value(keyKey{}).(string)
+		return k, ok
+	}
+	half := endpoints.RoutingMiddleware(impl("b", &called), endpoints.RoutePercent(50, key))(impl("a", &called))
+	routed := map[string]int{}
+	for i := 0; i < 200; i++ {
+		ctx := context.WithValue(context.Background(), keyKey{}, fmt.Sprint("tenant-", i))
+		for j := 0; j < 3; j++ {
+			token, _ := half.Login(ctx, "", "", false)
+			routed[fmt.Sprint(i)+token]++
+		}
+	}
+	b := 0
+	for i := 0; i < 200; i++ {
+		switch n := routed[fmt.Sprint(i)+"b"]; n {
+		case 3:
+			b++
+		case 0:
+		default:
+			t.Errorf("key %d routed to b %d times in 3, want sticky routing", i, n)
+		}
+	}
+	if b < 60 || b > 140 {
+		t.Errorf("%d keys of 200 routed to b with 50%%", b)
+	}
+}
@@ -0,0 +1,64 @@
+// Package scope sends requests to the handler generated into
+// example.com/fixtures/endpoints, with -mock, by TestScope of kitboiler,
+// with tokens granted the scopes of their X-Scopes header.
+package scope
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+	"example.com/fixtures/endpoints/scopes"
+)
+
+func TestScope(t *testing.T) {
+	handler := endpoints.MakeHTTPHandler(&endpoints.MockService{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if header, ok := r.Header["X-Scopes"]; ok {
+			r = r.WithContext(endpoints.ContextWithScopes(r.Context(), strings.Fields(header[0])))
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	for _, c := range []struct {
+		path   string
+		scopes *string
+		want   int
+	}{
+		{"/get-balance", nil, http.StatusUnauthorized},
+		{"/get-balance", str("accounts:write"), http.StatusForbidden},
+		{"/get-balance", str("accounts:read"), http.StatusOK},
+		{"/close-account", str("accounts:write"), http.StatusForbidden},
+		{"/close-account", str("admin accounts:write"), http.StatusOK},
+		{"/ping", nil, http.StatusOK},
+	} {
+		req, err := http.NewRequest("POST", srv.URL+c.path, strings.NewReader(`{"Id": "1"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.scopes != nil {
+			req.Header.Set("X-Scopes", *c.scopes)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.want {
+			t.Errorf("POST %s with scopes %v: %s, want %d", c.path, c.scopes, resp.Status, c.want)
+		}
+	}
+
+	if want := []string{"accounts:read", "accounts:write", "admin"}; !reflect.DeepEqual(scopes.All, want) {
+		t.Errorf("scopes.All = %v, want %v", scopes.All, want)
+	}
+	if want := []string{scopes.AccountsWrite, scopes.Admin}; !reflect.DeepEqual(scopes.ByMethod["CloseAccount"], want) {
+		t.Errorf("scopes.ByMethod[CloseAccount] = %v, want %v", scopes.ByMethod["CloseAccount"], want)
+	}
+}
+
+func str(s string) *string { return &s }
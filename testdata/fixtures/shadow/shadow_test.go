@@ -0,0 +1,102 @@
+// Package shadow checks the shadow middleware generated into
+// example.com/fixtures/endpoints, with -middleware shadow and -mock, by
+// TestShadow of kitboiler.
+package shadow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"example.com/fixtures/endpoints"
+)
+
+func TestShadow(t *testing.T) {
+	svc := &endpoints.MockService{
+		LoginFunc: func(ctx context.Context, email string, password string, remember bool) (string, error) {
+			return "t0k3n", nil
+		},
+		LogoutFunc: func(ctx context.Context, token string) error {
+			return nil
+		},
+	}
+	shadowed := make(chan string, 10)
+	shadow := &endpoints.MockService{
+		LoginFunc: func(ctx context.Context, email string, password string, remember bool) (string, error) {
+			shadowed <- "Login"
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+			if remember {
+				return "t0k3n", nil
+			}
+			return "other", nil
+		},
+		LogoutFunc: func(ctx context.Context, token string) error {
+			shadowed <- "Logout"
+			if token == "" {
+				return errors.New("no token")
+			}
+			return nil
+		},
+	}
+	mismatches := make(chan endpoints.ShadowMismatch, 10)
+	mirrored := endpoints.ShadowMiddleware(shadow, endpoints.ShadowOptions{
+		Percent: 100,
+		Report:  func(m endpoints.ShadowMismatch) { mismatches <- m },
+	})(svc)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if token, err := mirrored.Login(ctx, "ann@example.com", "hunter2", false); token != "t0k3n" || err != nil {
+		t.Errorf("Login: %q, %v, want the result of the service", token, err)
+	}
+	cancel() // not propagated to the shadow, which would fail
+	m := mismatch(t, mismatches)
+	if got, want := fmt.Sprintf("%s %v %v %v", m.Method, m.Args, m.Results, m.ShadowResults), "Login [email [REDACTED] password [REDACTED] remember false] [t0k3n] [other]"; got != want {
+		t.Errorf("mismatch %s, want %s", got, want)
+	}
+
+	ctx = context.Background()
+	mirrored.Login(ctx, "ann@example.com", "hunter2", true)
+	mirrored.Logout(ctx, "t0k3n")
+	mirrored.Logout(ctx, "")
+	m = mismatch(t, mismatches)
+	if m.Method != "Logout" || m.Err != nil || m.ShadowErr == nil {
+		t.Errorf("mismatch %+v, want Logout failing in the shadow only", m)
+	}
+	select {
+	case m := <-mismatches:
+		t.Errorf("mismatch %+v, want none as the results are equal", m)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	for i := 0; i < 4; i++ {
+		select {
+		case <-shadowed:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%d calls mirrored, want 4", i)
+		}
+	}
+	none := endpoints.ShadowMiddleware(shadow, endpoints.ShadowOptions{})(svc)
+	none.Logout(ctx, "")
+	select {
+	case method := <-shadowed:
+		t.Errorf("%s mirrored with Percent 0", method)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// mismatch returns the next mismatch reported to c, failing if it takes too
+// long.
+func mismatch(t *testing.T, c chan endpoints.ShadowMismatch) endpoints.ShadowMismatch {
+	t.Helper()
+	select {
+	case m := <-c:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mismatch reported")
+	}
+	return endpoints.ShadowMismatch{}
+}
@@ -0,0 +1,69 @@
+// Package status calls the handler generated into
+// example.com/fixtures/endpoints, with -mock, by TestErrorStatuses of
+// kitboiler, for an interface whose errors are annotated with kit:status.
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+	"example.com/fixtures/inventory"
+)
+
+// errGone is mapped to 410 by TestErrorStatuses.
+var errGone = errors.New("item discontinued")
+
+func TestErrorStatuses(t *testing.T) {
+	endpoints.ErrorStatuses = append(endpoints.ErrorStatuses, endpoints.ErrorStatus{
+		Is:     func(err error) bool { return errors.Is(err, errGone) },
+		Status: http.StatusGone,
+	})
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(&endpoints.MockService{
+		ReserveFunc: func(ctx context.Context, item string, n int) (int, error) {
+			switch item {
+			case "unknown":
+				return 0, fmt.Errorf("reserving %s: %w", item, inventory.ErrNotFound)
+			case "popular":
+				return 0, &inventory.ConflictError{Item: item}
+			case "old":
+				return 0, errGone
+			case "broken":
+				return 0, errors.New("database down")
+			}
+			return 10 - n, nil
+		},
+	}))
+	defer srv.Close()
+
+	for _, c := range []struct {
+		item   string
+		status int
+		body   string
+	}{
+		{"pen", http.StatusOK, `{"Left":8}`},
+		{"unknown", http.StatusNotFound, "reserving unknown: item not found"},
+		{"popular", http.StatusConflict, "popular is out of stock"},
+		{"old", http.StatusGone, "item discontinued"},
+		{"broken", http.StatusInternalServerError, "database down"},
+	} {
+		resp, err := http.Post(srv.URL+"/reserve", "application/json", strings.NewReader(`{"Item": "`+c.item+`", "N": 2}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != c.status || strings.TrimSpace(string(body)) != c.body {
+			t.Errorf("reserving %s: %s %s, want %d %s", c.item, resp.Status, body, c.status, c.body)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+// Package store declares an interface embedding others.
+package store
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Store embeds interfaces whose methods are left out of the generated code
+// by default, including one of them anyway.
+type Store interface {
+	io.Closer
+	//kit:include
+	fmt.Stringer
+	Count(ctx context.Context) (n int, err error)
+}
@@ -0,0 +1,65 @@
+// Package switches flips the switches of the middleware generated into
+// example.com/fixtures/endpoints, with -admin -ratelimit apikey -access-log
+// -mock, through the admin API, by TestSwitches of kitboiler.
+package switches
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"example.com/fixtures/endpoints"
+)
+
+func TestSwitches(t *testing.T) {
+	var logged int
+	endpoints.Logger = log.LoggerFunc(func(keyvals ...interface{}) error {
+		logged++
+		return nil
+	})
+	// no refill: a burst of a single request
+	api := endpoints.NewRateLimitHandler(endpoints.NewMemoryRateLimitStore(0, 1), endpoints.MakeHTTPHandler(&endpoints.MockService{}))
+	admin := endpoints.MakeSwitchesHandler()
+	call := func() int {
+		r := httptest.NewRequest("POST", "/delete-user", strings.NewReader(`{"id": "1"}`))
+		r.Header.Set("X-API-Key", "a")
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, r)
+		return w.Code
+	}
+	patch := func(body string) (int, string) {
+		w := httptest.NewRecorder()
+		admin.ServeHTTP(w, httptest.NewRequest("PATCH", "/switches", strings.NewReader(body)))
+		return w.Code, w.Body.String()
+	}
+
+	if call() != http.StatusOK || call() != http.StatusTooManyRequests {
+		t.Fatal("the second request isn't rate limited")
+	}
+	if code, body := patch(`{"rate_limit_enabled": false, "log_level": "none"}`); code != http.StatusOK || !strings.Contains(body, `"log_level":"none"`) || !strings.Contains(body, `"rate_limit_enabled":false`) {
+		t.Errorf("PATCH /switches: %d %s, want the new state", code, body)
+	}
+	logged = 0
+	if code := call(); code != http.StatusOK || logged != 0 {
+		t.Errorf("request with rate limiting and logging off: status %d, %d log entries", code, logged)
+	}
+	if code, _ := patch(`{"log_level": "debug"}`); code != http.StatusBadRequest {
+		t.Errorf("PATCH /switches with an unknown log level: %d, want 400", code)
+	}
+
+	var reset bool
+	endpoints.Switches.RegisterBreaker("store", func() { reset = true })
+	for path, want := range map[string]int{"/switches/breakers/store/reset": http.StatusNoContent, "/switches/breakers/cache/reset": http.StatusNotFound} {
+		w := httptest.NewRecorder()
+		admin.ServeHTTP(w, httptest.NewRequest("POST", path, nil))
+		if w.Code != want {
+			t.Errorf("POST %s: %d, want %d", path, w.Code, want)
+		}
+	}
+	if !reset {
+		t.Error("the store breaker isn't reset")
+	}
+}
@@ -0,0 +1,43 @@
+// Package tenant sends requests to the handler generated into
+// example.com/fixtures/endpoints, with -tenant path -mock, by TestTenant of
+// kitboiler.
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+	"example.com/fixtures/model"
+)
+
+func TestTenant(t *testing.T) {
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(&endpoints.MockService{
+		GetUserFunc: func(ctx context.Context, id string) (*model.User, error) {
+			tenant, ok := endpoints.TenantFromContext(ctx)
+			if !ok || tenant != "acme" {
+				t.Errorf("GetUser called for tenant %q, want acme", tenant)
+			}
+			return &model.User{ID: id}, nil
+		},
+	}))
+	defer srv.Close()
+
+	for path, want := range map[string]int{
+		"/t/acme/get-user": http.StatusOK,
+		"/get-user":        http.StatusNotFound,
+		"/t/acme":          http.StatusNotFound,
+	} {
+		resp, err := http.Post(srv.URL+path, "application/json", strings.NewReader(`{"id": "1"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("POST %s: %s, want %d", path, resp.Status, want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Package tx runs the methods of orders.OrderService in the transactions of
+// the middleware generated into example.com/fixtures/endpoints, with -mock,
+// by TestTx of kitboiler.
+package tx
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+)
+
+// manager is a TxManager logging the transactions it begins and how they
+// end.
+type manager struct {
+	log []string
+}
+
+type tx struct {
+	m    *manager
+	name string
+}
+
+func (m *manager) Begin(ctx context.Context, opts endpoints.TxOptions) (endpoints.Tx, error) {
+	name := "tx"
+	if opts.ReadOnly {
+		name = "readonly tx"
+	}
+	m.log = append(m.log, "begin "+name)
+	return tx{m, name}, nil
+}
+
+func (t tx) Commit() error {
+	t.m.log = append(t.m.log, "commit "+t.name)
+	return nil
+}
+
+func (t tx) Rollback() error {
+	t.m.log = append(t.m.log, "rollback "+t.name)
+	return nil
+}
+
+func TestTx(t *testing.T) {
+	fail := errors.New("out of stock")
+	m := &manager{}
+	svc := endpoints.TxMiddleware(m)(&endpoints.MockService{
+		PlaceOrderFunc: func(ctx context.Context, item string, quantity int) (string, error) {
+			if _, ok := endpoints.TxFromContext(ctx); !ok {
+				t.Error("PlaceOrder isn't called with its transaction")
+			}
+			switch item {
+			case "gone":
+				return "", fail
+			case "panic":
+				panic(item)
+			}
+			return "1", nil
+		},
+	})
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func()
+		want []string
+	}{
+		{"commit", func() { svc.PlaceOrder(ctx, "book", 1) }, []string{"begin tx", "commit tx"}},
+		{"error", func() {
+			if _, err := svc.PlaceOrder(ctx, "gone", 1); err != fail {
+				t.Errorf("PlaceOrder: %v, want %v", err, fail)
+			}
+		}, []string{"begin tx", "rollback tx"}},
+		{"panic", func() {
+			defer func() { recover() }()
+			svc.PlaceOrder(ctx, "panic", 1)
+		}, []string{"begin tx", "rollback tx"}},
+		{"readonly", func() { svc.Total(ctx, "1") }, []string{"begin readonly tx", "commit readonly tx"}},
+		{"join", func() { svc.Total(endpoints.ContextWithTx(ctx, tx{m, "outer"}), "1") }, nil},
+		{"none", func() { svc.CancelOrder(ctx, "1") }, nil},
+	}
+	for _, tt := range tests {
+		m.log = nil
+		tt.call()
+		if !reflect.DeepEqual(m.log, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, m.log, tt.want)
+		}
+	}
+}
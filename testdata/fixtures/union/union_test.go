@@ -0,0 +1,57 @@
+// Package union sends requests to the handler generated into
+// example.com/fixtures/endpoints, for api.ShapeService with -mock, by
+// TestUnion of kitboiler.
+package union
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+	"example.com/fixtures/model"
+)
+
+func TestUnion(t *testing.T) {
+	shapes := map[string]model.Shape{
+		"c": model.Circle{Radius: 2},
+		"s": &model.Square{Side: 3},
+	}
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(&endpoints.MockService{
+		GetShapeFunc: func(ctx context.Context, id string) (model.Shape, error) {
+			return shapes[id], nil
+		},
+	}))
+	defer srv.Close()
+
+	for id, want := range map[string]string{
+		"c": `{"Shape":{"type":"Circle","Radius":2}}`,
+		"s": `{"Shape":{"type":"Square","Side":3}}`,
+		"x": `{"Shape":null}`,
+	} {
+		resp, err := http.Post(srv.URL+"/get-shape", "application/json", strings.NewReader(`{"id": "`+id+`"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(string(body)); got != want {
+			t.Errorf("POST /get-shape of shape %s: %s, want %s", id, got, want)
+		}
+		var res endpoints.GetShapeResponse
+		if err := json.Unmarshal(body, &res); err != nil {
+			t.Fatalf("decoding %s: %v", body, err)
+		}
+		if !reflect.DeepEqual(res.Shape.Value, shapes[id]) {
+			t.Errorf("decoded %s as %#v, want %#v", body, res.Shape.Value, shapes[id])
+		}
+	}
+}
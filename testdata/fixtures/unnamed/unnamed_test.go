@@ -0,0 +1,52 @@
+// Package unnamed calls the handler generated into
+// example.com/fixtures/endpoints, with -mock, by TestUnnamed of kitboiler,
+// for methods whose results are unnamed.
+package unnamed
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+	"example.com/fixtures/model"
+)
+
+func TestUnnamed(t *testing.T) {
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(&endpoints.MockService{
+		ItemFunc: func(ctx context.Context, id string) (*model.User, error) {
+			return &model.User{ID: id}, nil
+		},
+		ItemsFunc: func(ctx context.Context, offset, limit int) ([]*model.User, error) {
+			return make([]*model.User, limit-offset), nil
+		},
+		StatsFunc: func(ctx context.Context) (int, int, error) {
+			return 1, 2, nil
+		},
+	}))
+	defer srv.Close()
+
+	for _, c := range []struct {
+		path, body, want string
+	}{
+		{"/item", `{"Id": "7"}`, `{"User":{"ID":"7"`},
+		{"/items", `{"Offset": 1, "Limit": 3}`, `{"Users":[null,null]}`},
+		{"/stats", `{}`, `{"R0":1,"R1":2}`},
+	} {
+		resp, err := http.Post(srv.URL+c.path, "application/json", strings.NewReader(c.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(body), c.want) {
+			t.Errorf("POST %s %s: %s, want %s", c.path, c.body, body, c.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Package unwrap sends requests to the handler generated into
+// example.com/fixtures/endpoints, with -mock, by TestUnwrap of kitboiler,
+// whose kit:unwrap methods respond with their result as is.
+package unwrap
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/fixtures/endpoints"
+	"example.com/fixtures/model"
+)
+
+func TestUnwrap(t *testing.T) {
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(&endpoints.MockService{
+		ReadFunc: func(ctx context.Context, key string) ([]byte, error) {
+			return []byte("raw " + key), nil
+		},
+		SizeFunc: func(ctx context.Context, key string) (int64, error) {
+			return 42, nil
+		},
+		OwnerFunc: func(ctx context.Context, key string) (*model.User, error) {
+			if key == "orphan" {
+				return nil, nil
+			}
+			return &model.User{ID: "1", Name: "ann"}, nil
+		},
+	}))
+	defer srv.Close()
+
+	for _, c := range []struct {
+		path, body  string
+		status      int
+		contentType string
+		want        string
+	}{
+		{"/read", `{"Key": "a"}`, http.StatusOK, "application/octet-stream", "raw a"},
+		{"/size", `{"Key": "a"}`, http.StatusOK, "", "42\n"},
+		{"/owner", `{"Key": "a"}`, http.StatusOK, "", `{"ID":"1","Name":"ann","Age":0,"Version":0,"Status":""}` + "\n"},
+		{"/owner", `{"Key": "orphan"}`, http.StatusNotFound, "", ""},
+	} {
+		resp, err := http.Post(srv.URL+c.path, "application/json", strings.NewReader(c.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != c.status {
+			t.Errorf("POST %s %s: %s, want %d", c.path, c.body, resp.Status, c.status)
+			continue
+		}
+		if c.status != http.StatusOK {
+			continue
+		}
+		if got := resp.Header.Get("Content-Type"); c.contentType != "" && got != c.contentType {
+			t.Errorf("POST %s: Content-Type %q, want %q", c.path, got, c.contentType)
+		}
+		if string(body) != c.want {
+			t.Errorf("POST %s: body %q, want %q", c.path, body, c.want)
+		}
+	}
+}
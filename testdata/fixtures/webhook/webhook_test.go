@@ -0,0 +1,74 @@
+// Package webhook receives the webhooks of the dispatcher generated into
+// example.com/fixtures/endpoints, for api.UserEvents, by TestWebhook of
+// kitboiler.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"example.com/fixtures/endpoints"
+	"example.com/fixtures/model"
+)
+
+func TestWebhook(t *testing.T) {
+	var calls int32
+	received := make(chan endpoints.UserCreatedRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		sig := "sha256=" + endpoints.WebhookSignature("secret", r.Header.Get("X-Webhook-Timestamp"), body)
+		if r.Header.Get("X-Webhook-Event") != endpoints.UserCreatedEvent || r.Header.Get("X-Webhook-Signature") != sig {
+			t.Errorf("webhook headers %v, want the event and signature %s", r.Header, sig)
+		}
+		var req endpoints.UserCreatedRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Error(err)
+		}
+		received <- req
+	}))
+	defer srv.Close()
+
+	d := endpoints.NewDispatcher(endpoints.Subscriber{URL: srv.URL, Secret: "secret"})
+	d.Backoff = time.Millisecond
+	if err := d.UserCreated(context.Background(), &model.User{ID: "1", Name: "ann"}); err != nil {
+		t.Fatal(err)
+	}
+	if req := <-received; req.User == nil || req.User.Name != "ann" {
+		t.Errorf("received %+v, want the user", req)
+	}
+	if calls != 2 {
+		t.Errorf("%d deliveries, want the failed one retried", calls)
+	}
+}
+
+func TestDeliveryError(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	d := endpoints.NewDispatcher(
+		endpoints.Subscriber{URL: srv.URL},
+		endpoints.Subscriber{URL: "http://unused.invalid", Events: []string{endpoints.UserCreatedEvent}},
+	)
+	err := d.UserDeleted(context.Background(), "1")
+	derr, ok := err.(*endpoints.DeliveryError)
+	if !ok || len(derr.Failed) != 1 || derr.Failed[srv.URL] == nil {
+		t.Fatalf("error %v, want a DeliveryError of the subscriber", err)
+	}
+	if calls != 1 {
+		t.Errorf("%d deliveries, want a 400 not to be retried", calls)
+	}
+}
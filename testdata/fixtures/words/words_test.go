@@ -0,0 +1,76 @@
+// Package words calls the code generated into example.com/fixtures/endpoints,
+// with -mock, -middleware logging, -endpoint-set and -client, by TestIdents
+// of kitboiler, for methods whose parameters and results are renamed.
+package words
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"example.com/fixtures/endpoints"
+)
+
+func TestIdents(t *testing.T) {
+	svc := endpoints.LoggingMiddleware(log.NewNopLogger())(&endpoints.MockService{
+		NewFunc: func(ctx context.Context, s string, n int) (string, error) {
+			return strings.Repeat(s, n), nil
+		},
+		StringFunc: func(ctx context.Context, request int, response int) (string, error) {
+			return strings.Repeat("x", request+response), nil
+		},
+		EncodeFunc: func(ctx context.Context, text string) (string, error) {
+			return strings.ToUpper(text), nil
+		},
+		FailFunc: func(ctx context.Context, reason string) error {
+			return errors.New(reason)
+		},
+	})
+	srv := httptest.NewServer(endpoints.MakeHTTPHandler(svc))
+	defer srv.Close()
+
+	for _, c := range []struct {
+		path, body, want string
+	}{
+		{"/new", `{"String": "ab", "Len": 2}`, `{"Req":"abab"}`},
+		{"/string", `{"Request": 1, "Response": 2}`, `{"S":"xxx"}`},
+		{"/encode", `{"Text": "ab"}`, `{"Encoded":"AB"}`},
+		{"/fail", `{"Reason": "down"}`, "down"},
+	} {
+		resp, err := http.Post(srv.URL+c.path, "application/json", strings.NewReader(c.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(string(body)); got != c.want {
+			t.Errorf("POST %s %s: %s, want %s", c.path, c.body, got, c.want)
+		}
+	}
+
+	set := endpoints.MakeEndpoints(svc)
+	if s, err := set.New(context.Background(), "c", 3); s != "ccc" || err != nil {
+		t.Errorf("Endpoints.New: %q, %v, want ccc", s, err)
+	}
+	if err := set.Fail(context.Background(), "down"); err == nil || err.Error() != "down" {
+		t.Errorf("Endpoints.Fail: %v, want down", err)
+	}
+	var _ endpoints.EncodeResponse_ = endpoints.EncodeResponse_{Encoded: "AB"}
+
+	client, err := endpoints.NewHTTPClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err := client.New(context.Background(), "d", 2); s != "dd" || err != nil {
+		t.Errorf("client New: %q, %v, want dd", s, err)
+	}
+}
@@ -0,0 +1,150 @@
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package endpoints
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/encoding"
+)
+
+// The codecs the clients of each transport compress the payloads of their
+// requests with: gzip, snappy or zstd, or "" to send them uncompressed. The
+// servers take the requests compressed with any of them, and answer with
+// the codec of the request. Set them at init time, before the clients are
+// made.
+var (
+	GRPCCompression = "zstd"
+	NATSCompression = "zstd"
+	AMQPCompression = "zstd"
+)
+
+// compressionMagic are the leading bytes of the payloads compressed with
+// every codec, which no JSON payload starts with.
+var compressionMagic = map[string]string{
+	"gzip":   "\x1f\x8b",
+	"snappy": "\xff\x06\x00\x00sNaPpY",
+	"zstd":   "\x28\xb5\x2f\xfd",
+}
+
+// zstdEncoder and zstdDecoder compress and decompress the zstd payloads,
+// their EncodeAll and DecodeAll being safe for concurrent use.
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// payloadCodec returns the codec data is compressed with, or "" if it isn't.
+func payloadCodec(data []byte) string {
+	for codec, magic := range compressionMagic {
+		if bytes.HasPrefix(data, []byte(magic)) {
+			return codec
+		}
+	}
+	return ""
+}
+
+// compress returns data compressed with codec, or data itself if codec is
+// "".
+func compress(codec string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch codec {
+	case "":
+		return data, nil
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "snappy":
+		w = snappy.NewBufferedWriter(&buf)
+	case "zstd":
+		return zstdEncoder.EncodeAll(data, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown compression codec %q, want gzip, snappy or zstd", codec)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompress returns data decompressed with the codec it is compressed
+// with, or data itself if it isn't compressed.
+func decompress(data []byte) ([]byte, error) {
+	var r io.Reader
+	switch payloadCodec(data) {
+	case "":
+		return data, nil
+	case "gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		r = zr
+	case "snappy":
+		r = snappy.NewReader(bytes.NewReader(data))
+	case "zstd":
+		return zstdDecoder.DecodeAll(data, nil)
+	}
+	return ioutil.ReadAll(r)
+}
+
+func init() {
+	for codec := range compressionMagic {
+		encoding.RegisterCompressor(grpcCompressor(codec))
+	}
+}
+
+// grpcCompressor is the gRPC compressor of a codec, registered under its
+// name, compressing every message whole.
+type grpcCompressor string
+
+func (c grpcCompressor) Name() string { return string(c) }
+
+func (c grpcCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return &compressWriter{codec: string(c), w: w}, nil
+}
+
+func (c grpcCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if codec := payloadCodec(data); codec != string(c) && len(data) > 0 {
+		return nil, fmt.Errorf("message isn't compressed with %s", c)
+	}
+	if data, err = decompress(data); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// compressWriter buffers a message, writing it to w compressed with codec
+// once closed.
+type compressWriter struct {
+	codec string
+	w     io.Writer
+	buf   bytes.Buffer
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	return cw.buf.Write(p)
+}
+
+func (cw *compressWriter) Close() error {
+	data, err := compress(cw.codec, cw.buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = cw.w.Write(data)
+	return err
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/go-kit/kit/log"
+
+	"example.com/fixtures/api"
+)
+
+// newService returns the implementation of api.UserService served by the
+// command. KitBoiler doesn't overwrite this file once it exists.
+func newService(cfg Config, logger log.Logger) (api.UserService, error) {
+	return nil, errors.New("newService: not implemented")
+}
@@ -0,0 +1,6 @@
+// Package pb holds the protobuf messages and gRPC service of api.UserService,
+// generated from endpoints.proto by protoc with the protoc-gen-go and
+// protoc-gen-go-grpc plugins. Run go generate after changing the proto file.
+package pb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative endpoints.proto
@@ -0,0 +1,30 @@
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package endpoints
+
+import (
+	"context"
+	"example.com/fixtures/api"
+	"example.com/fixtures/model"
+	"github.com/go-kit/kit/endpoint"
+)
+
+type CreateUserRequest struct {
+	Name string
+	Age  int
+}
+
+type CreateUserResponse struct {
+	User *model.User
+}
+
+func CreateUserEndPoint(svc api.UserService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CreateUserRequest)
+		user, err := svc.CreateUser(ctx, req.Name, req.Age)
+		return CreateUserResponse{
+			User: user,
+		}, err
+	}
+}
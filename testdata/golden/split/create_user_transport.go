@@ -0,0 +1,33 @@
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"net/http"
+)
+
+// CreateUserHTTPJSONHandler serves POST /create-user with the endpoint of
+// api.UserService.CreateUser, documented as:
+//
+// CreateUser creates a user.
+func CreateUserHTTPJSONHandler(e endpoint.Endpoint) http.Handler {
+	return httptransport.NewServer(
+		e,
+		DecodeCreateUserRequest,
+		EncodeResponse,
+		httptransport.ServerErrorEncoder(EncodeError),
+	)
+}
+
+func DecodeCreateUserRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request CreateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
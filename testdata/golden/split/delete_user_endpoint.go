@@ -0,0 +1,24 @@
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package endpoints
+
+import (
+	"context"
+	"example.com/fixtures/api"
+	"github.com/go-kit/kit/endpoint"
+)
+
+type DeleteUserRequest struct {
+	Id string
+}
+
+type DeleteUserResponse struct{}
+
+func DeleteUserEndPoint(svc api.UserService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(DeleteUserRequest)
+		err := svc.DeleteUser(ctx, req.Id)
+		return DeleteUserResponse{}, err
+	}
+}
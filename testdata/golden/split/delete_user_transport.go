@@ -0,0 +1,34 @@
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"net/http"
+)
+
+// DeleteUserHTTPJSONHandler serves POST /delete-user with the endpoint of
+// api.UserService.DeleteUser, documented as:
+//
+// DeleteUser removes the user id. It fails if the user owns
+// resources.
+func DeleteUserHTTPJSONHandler(e endpoint.Endpoint) http.Handler {
+	return httptransport.NewServer(
+		e,
+		DecodeDeleteUserRequest,
+		EncodeResponse,
+		httptransport.ServerErrorEncoder(EncodeError),
+	)
+}
+
+func DecodeDeleteUserRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request DeleteUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
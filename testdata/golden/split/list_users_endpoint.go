@@ -0,0 +1,31 @@
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package endpoints
+
+import (
+	"context"
+	"example.com/fixtures/api"
+	"example.com/fixtures/model"
+	"github.com/go-kit/kit/endpoint"
+)
+
+type ListUsersRequest struct {
+	Opts model.ListOptions
+}
+
+type ListUsersResponse struct {
+	Users []*model.User
+}
+
+func ListUsersEndPoint(svc api.UserService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ListUsersRequest)
+		users, err := svc.ListUsers(ctx,
+			func(v int) func(*model.ListOptions) { return func(opts *model.ListOptions) { opts.Limit = v } }(req.Opts.Limit),
+			func(v int) func(*model.ListOptions) { return func(opts *model.ListOptions) { opts.Offset = v } }(req.Opts.Offset))
+		return ListUsersResponse{
+			Users: users,
+		}, err
+	}
+}
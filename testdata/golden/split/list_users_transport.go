@@ -0,0 +1,31 @@
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"net/http"
+)
+
+// ListUsersHTTPJSONHandler serves POST /list-users with the endpoint of
+// api.UserService.ListUsers.
+func ListUsersHTTPJSONHandler(e endpoint.Endpoint) http.Handler {
+	return httptransport.NewServer(
+		e,
+		DecodeListUsersRequest,
+		EncodeResponse,
+		httptransport.ServerErrorEncoder(EncodeError),
+	)
+}
+
+func DecodeListUsersRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request ListUsersRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
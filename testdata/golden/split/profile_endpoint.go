@@ -0,0 +1,29 @@
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package endpoints
+
+import (
+	"context"
+	"example.com/fixtures/api"
+	"example.com/fixtures/model"
+	"github.com/go-kit/kit/endpoint"
+)
+
+type ProfileRequest struct {
+	Id string
+}
+
+type ProfileResponse struct {
+	Profile model.User
+}
+
+func ProfileEndPoint(svc api.UserService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ProfileRequest)
+		profile, err := svc.Profile(ctx, req.Id)
+		return ProfileResponse{
+			Profile: profile,
+		}, err
+	}
+}
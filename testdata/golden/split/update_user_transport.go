@@ -0,0 +1,36 @@
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"net/http"
+)
+
+// UpdateUserHTTPJSONHandler serves POST /update-user with the endpoint of
+// api.UserService.UpdateUser.
+func UpdateUserHTTPJSONHandler(e endpoint.Endpoint) http.Handler {
+	return httptransport.NewServer(
+		e,
+		DecodeUpdateUserRequest,
+		EncodeResponse,
+		httptransport.ServerBefore(ifMatchToContext),
+		httptransport.ServerBefore(DeadlineFromHTTPHeader),
+		httptransport.ServerErrorEncoder(EncodeError),
+	)
+}
+
+func DecodeUpdateUserRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, err
+	}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
@@ -0,0 +1,332 @@
+// Code generated by KitBoiler (https://github.com/jeroenvand/kitboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-kit/kit/endpoint"
+	amqptransport "github.com/go-kit/kit/transport/amqp"
+	amqp "github.com/streadway/amqp"
+	"strconv"
+	"sync"
+)
+
+// AMQPReplies receives the replies to the requests of the AMQP clients on an
+// exclusive queue, handing each to the call waiting for its correlation ID,
+// for the calls made concurrently on a channel to get their own replies.
+type AMQPReplies struct {
+	ch    *amqp.Channel
+	queue amqp.Queue
+
+	mu      sync.Mutex
+	seq     uint64
+	pending map[string]chan amqp.Delivery
+	closed  bool
+}
+
+// NewAMQPReplies declares an exclusive reply queue, named by the broker, on
+// ch and consumes it until ch is closed, failing the calls waiting for a
+// reply then with amqp.ErrClosed.
+func NewAMQPReplies(ch *amqp.Channel) (*AMQPReplies, error) {
+	queue, err := ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := ch.Consume(queue.Name, "", true, true, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	r := &AMQPReplies{ch: ch, queue: queue, pending: map[string]chan amqp.Delivery{}}
+	go r.dispatch(deliveries)
+	return r, nil
+}
+
+func (r *AMQPReplies) dispatch(deliveries <-chan amqp.Delivery) {
+	for d := range deliveries {
+		r.mu.Lock()
+		reply, ok := r.pending[d.CorrelationId]
+		delete(r.pending, d.CorrelationId)
+		r.mu.Unlock()
+		if ok {
+			reply <- d
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	for id, reply := range r.pending {
+		close(reply)
+		delete(r.pending, id)
+	}
+}
+
+// deliverer returns the amqptransport.Deliverer of the clients publishing
+// their requests on exchange with the routing key key. It replaces the
+// correlation ID of every request with one unique to r and waits for the
+// reply carrying it, rather than consuming the reply queue itself as
+// amqptransport.DefaultDeliverer does.
+func (r *AMQPReplies) deliverer(exchange, key string) amqptransport.Deliverer {
+	return func(ctx context.Context, _ amqptransport.Publisher, pub *amqp.Publishing) (*amqp.Delivery, error) {
+		reply := make(chan amqp.Delivery, 1)
+		r.mu.Lock()
+		if r.closed {
+			r.mu.Unlock()
+			return nil, amqp.ErrClosed
+		}
+		r.seq++
+		id := strconv.FormatUint(r.seq, 10)
+		r.pending[id] = reply
+		r.mu.Unlock()
+		defer func() {
+			r.mu.Lock()
+			delete(r.pending, id)
+			r.mu.Unlock()
+		}()
+
+		pub.CorrelationId, pub.ReplyTo = id, r.queue.Name
+		if err := r.ch.Publish(exchange, key, false, false, *pub); err != nil {
+			return nil, err
+		}
+		select {
+		case d, ok := <-reply:
+			if !ok {
+				return nil, amqp.ErrClosed
+			}
+			return &d, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// amqpKey returns the routing key of the requests published on exchange to
+// queue: key, or the name of queue itself on the default exchange.
+func amqpKey(exchange, queue, key string) string {
+	if exchange == "" {
+		return queue
+	}
+	return key
+}
+
+// encodeAMQPRequest encodes the JSON of a request into the body of pub.
+func encodeAMQPRequest(_ context.Context, pub *amqp.Publishing, request interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	pub.Body = body
+	return nil
+}
+
+// CreateUserAMQPClient returns an endpoint calling CreateUser with a request
+// published on exchange to CreateUserAMQPQueue, receiving the reply on replies.
+func CreateUserAMQPClient(replies *AMQPReplies, exchange string, options ...amqptransport.PublisherOption) endpoint.Endpoint {
+	options = append([]amqptransport.PublisherOption{
+		amqptransport.PublisherBefore(amqptransport.SetContentType("application/json")),
+		amqptransport.PublisherDeliverer(replies.deliverer(exchange, amqpKey(exchange, CreateUserAMQPQueue, CreateUserAMQPKey))),
+	}, options...)
+	return amqptransport.NewPublisher(
+		replies.ch,
+		&replies.queue,
+		encodeAMQPRequest,
+		DecodeAMQPCreateUserResponse,
+		options...,
+	).Endpoint()
+}
+
+// DecodeAMQPCreateUserResponse decodes the JSON CreateUserResponse of a reply from
+// CreateUserAMQPQueue, or the error it answers with into an *AMQPError.
+func DecodeAMQPCreateUserResponse(_ context.Context, d *amqp.Delivery) (interface{}, error) {
+	if err := decodeAMQPError(d); err != nil {
+		return nil, err
+	}
+	var response CreateUserResponse
+	if err := json.Unmarshal(d.Body, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// GetUserAMQPClient returns an endpoint calling GetUser with a request
+// published on exchange to GetUserAMQPQueue, receiving the reply on replies.
+func GetUserAMQPClient(replies *AMQPReplies, exchange string, options ...amqptransport.PublisherOption) endpoint.Endpoint {
+	options = append([]amqptransport.PublisherOption{
+		amqptransport.PublisherBefore(amqptransport.SetContentType("application/json")),
+		amqptransport.PublisherDeliverer(replies.deliverer(exchange, amqpKey(exchange, GetUserAMQPQueue, GetUserAMQPKey))),
+	}, options...)
+	return amqptransport.NewPublisher(
+		replies.ch,
+		&replies.queue,
+		encodeAMQPRequest,
+		DecodeAMQPGetUserResponse,
+		options...,
+	).Endpoint()
+}
+
+// DecodeAMQPGetUserResponse decodes the JSON GetUserResponse of a reply from
+// GetUserAMQPQueue, or the error it answers with into an *AMQPError.
+func DecodeAMQPGetUserResponse(_ context.Context, d *amqp.Delivery) (interface{}, error) {
+	if err := decodeAMQPError(d); err != nil {
+		return nil, err
+	}
+	var response GetUserResponse
+	if err := json.Unmarshal(d.Body, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// UpdateUserAMQPClient returns an endpoint calling UpdateUser with a request
+// published on exchange to UpdateUserAMQPQueue, receiving the reply on replies,
+// timing out after UpdateUserBudget.
+func UpdateUserAMQPClient(replies *AMQPReplies, exchange string, options ...amqptransport.PublisherOption) endpoint.Endpoint {
+	options = append([]amqptransport.PublisherOption{
+		amqptransport.PublisherBefore(amqptransport.SetContentType("application/json")),
+		amqptransport.PublisherDeliverer(replies.deliverer(exchange, amqpKey(exchange, UpdateUserAMQPQueue, UpdateUserAMQPKey))),
+		amqptransport.PublisherTimeout(UpdateUserBudget),
+	}, options...)
+	return amqptransport.NewPublisher(
+		replies.ch,
+		&replies.queue,
+		encodeAMQPRequest,
+		DecodeAMQPUpdateUserResponse,
+		options...,
+	).Endpoint()
+}
+
+// DecodeAMQPUpdateUserResponse decodes the JSON UpdateUserResponse of a reply from
+// UpdateUserAMQPQueue, or the error it answers with into an *AMQPError.
+func DecodeAMQPUpdateUserResponse(_ context.Context, d *amqp.Delivery) (interface{}, error) {
+	if err := decodeAMQPError(d); err != nil {
+		return nil, err
+	}
+	var response UpdateUserResponse
+	if err := json.Unmarshal(d.Body, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// ListUsersAMQPClient returns an endpoint calling ListUsers with a request
+// published on exchange to ListUsersAMQPQueue, receiving the reply on replies.
+func ListUsersAMQPClient(replies *AMQPReplies, exchange string, options ...amqptransport.PublisherOption) endpoint.Endpoint {
+	options = append([]amqptransport.PublisherOption{
+		amqptransport.PublisherBefore(amqptransport.SetContentType("application/json")),
+		amqptransport.PublisherDeliverer(replies.deliverer(exchange, amqpKey(exchange, ListUsersAMQPQueue, ListUsersAMQPKey))),
+	}, options...)
+	return amqptransport.NewPublisher(
+		replies.ch,
+		&replies.queue,
+		encodeAMQPRequest,
+		DecodeAMQPListUsersResponse,
+		options...,
+	).Endpoint()
+}
+
+// DecodeAMQPListUsersResponse decodes the JSON ListUsersResponse of a reply from
+// ListUsersAMQPQueue, or the error it answers with into an *AMQPError.
+func DecodeAMQPListUsersResponse(_ context.Context, d *amqp.Delivery) (interface{}, error) {
+	if err := decodeAMQPError(d); err != nil {
+		return nil, err
+	}
+	var response ListUsersResponse
+	if err := json.Unmarshal(d.Body, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// DeleteUserAMQPClient returns an endpoint calling DeleteUser with a request
+// published on exchange to DeleteUserAMQPQueue, receiving the reply on replies.
+func DeleteUserAMQPClient(replies *AMQPReplies, exchange string, options ...amqptransport.PublisherOption) endpoint.Endpoint {
+	options = append([]amqptransport.PublisherOption{
+		amqptransport.PublisherBefore(amqptransport.SetContentType("application/json")),
+		amqptransport.PublisherDeliverer(replies.deliverer(exchange, amqpKey(exchange, DeleteUserAMQPQueue, DeleteUserAMQPKey))),
+	}, options...)
+	return amqptransport.NewPublisher(
+		replies.ch,
+		&replies.queue,
+		encodeAMQPRequest,
+		DecodeAMQPDeleteUserResponse,
+		options...,
+	).Endpoint()
+}
+
+// DecodeAMQPDeleteUserResponse decodes the JSON DeleteUserResponse of a reply from
+// DeleteUserAMQPQueue, or the error it answers with into an *AMQPError.
+func DecodeAMQPDeleteUserResponse(_ context.Context, d *amqp.Delivery) (interface{}, error) {
+	if err := decodeAMQPError(d); err != nil {
+		return nil, err
+	}
+	var response DeleteUserResponse
+	if err := json.Unmarshal(d.Body, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// ProfileAMQPClient returns an endpoint calling Profile with a request
+// published on exchange to ProfileAMQPQueue, receiving the reply on replies.
+func ProfileAMQPClient(replies *AMQPReplies, exchange string, options ...amqptransport.PublisherOption) endpoint.Endpoint {
+	options = append([]amqptransport.PublisherOption{
+		amqptransport.PublisherBefore(amqptransport.SetContentType("application/json")),
+		amqptransport.PublisherDeliverer(replies.deliverer(exchange, amqpKey(exchange, ProfileAMQPQueue, ProfileAMQPKey))),
+	}, options...)
+	return amqptransport.NewPublisher(
+		replies.ch,
+		&replies.queue,
+		encodeAMQPRequest,
+		DecodeAMQPProfileResponse,
+		options...,
+	).Endpoint()
+}
+
+// DecodeAMQPProfileResponse decodes the JSON ProfileResponse of a reply from
+// ProfileAMQPQueue, or the error it answers with into an *AMQPError.
+func DecodeAMQPProfileResponse(_ context.Context, d *amqp.Delivery) (interface{}, error) {
+	if err := decodeAMQPError(d); err != nil {
+		return nil, err
+	}
+	var response ProfileResponse
+	if err := json.Unmarshal(d.Body, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// AMQPError is the error an AMQP subscriber replied to a request with.
+type AMQPError struct {
+	Message string
+}
+
+func (e *AMQPError) Error() string { return e.Message }
+
+// decodeAMQPError returns the *AMQPError of a reply holding one, encoded by
+// amqptransport.ReplyErrorEncoder as {"err": "<message>"}, or nil.
+func decodeAMQPError(d *amqp.Delivery) error {
+	var reply map[string]json.RawMessage
+	if json.Unmarshal(d.Body, &reply) != nil || len(reply) != 1 {
+		return nil
+	}
+	var message string
+	if raw, ok := reply["err"]; !ok || json.Unmarshal(raw, &message) != nil {
+		return nil
+	}
+	return &AMQPError{Message: message}
+}
+
+// NewAMQPClient returns the Endpoints calling the AMQP subscribers of
+// api.UserService with requests published on exchange, a client implementing
+// api.UserService.
+func NewAMQPClient(replies *AMQPReplies, exchange string, options ...amqptransport.PublisherOption) Endpoints {
+	return Endpoints{
+		CreateUserEndpoint: CreateUserAMQPClient(replies, exchange, options...),
+		GetUserEndpoint:    GetUserAMQPClient(replies, exchange, options...),
+		UpdateUserEndpoint: UpdateUserAMQPClient(replies, exchange, options...),
+		ListUsersEndpoint:  ListUsersAMQPClient(replies, exchange, options...),
+		DeleteUserEndpoint: DeleteUserAMQPClient(replies, exchange, options...),
+		ProfileEndpoint:    ProfileAMQPClient(replies, exchange, options...),
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+// tracingImports are the imports required by the OpenTelemetry tracing of
+// the endpoints, with the codes of the spans aliased not to collide with
+// those of gRPC.
+var tracingImports = map[string]string{
+	"go.opentelemetry.io/otel":             "",
+	"go.opentelemetry.io/otel/codes":       "otelcodes",
+	"go.opentelemetry.io/otel/propagation": "",
+	"go.opentelemetry.io/otel/trace":       "",
+}
+
+// TracerName returns the instrumentation name of the tracer of the
+// endpoints: the import path of the generated package if known, or its name.
+func (s Service) TracerName() string {
+	if s.ImportPath != "" {
+		return s.ImportPath
+	}
+	return s.Pkg
+}
+
+const tracingTemplate = `
+{{ define "tracing" }}
+// TraceEndpoint returns an endpoint middleware recording every call of the
+// endpoint of method in an OpenTelemetry span named after it, child of the
+// span of the context, if any. Errors are recorded on the span, setting its
+// status.
+//
+// The spans are started by the tracer of the global TracerProvider, which
+// discards them until set with otel.SetTracerProvider, e.g. to one exporting
+// them over OTLP.
+func TraceEndpoint(method string) endpoint.Middleware {
+	tracer := otel.Tracer("{{ .TracerName }}")
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			ctx, span := tracer.Start(ctx, method, trace.WithSpanKind(trace.SpanKindServer))
+			defer func() {
+				if err != nil {
+					span.RecordError(err)
+					span.SetStatus(otelcodes.Error, err.Error())
+				}
+				span.End()
+			}()
+			return next(ctx, request)
+		}
+	}
+}
+{{ if .HTTP }}
+// traceHTTP continues the trace propagated by the headers of the requests
+// handled by h, such as the traceparent header of W3C Trace Context, as
+// extracted by the global TextMapPropagator. The propagator extracts nothing
+// until set with otel.SetTextMapPropagator.
+func traceHTTP(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+{{ end }}{{ end }}
+`
@@ -0,0 +1,132 @@
+package main
+
+// txImports are the imports required by the transaction middleware.
+var txImports = []string{"context", "database/sql"}
+
+// resolveTx marks the methods of fns annotated with "//kit:tx [readonly]"
+// as run in a transaction by the transaction middleware.
+func resolveTx(fns []Func) error {
+	for i := range fns {
+		fn := &fns[i]
+		a, ok := fn.Annotation("tx")
+		if !ok {
+			continue
+		}
+		switch {
+		case len(a.Args) > 1 || len(a.Args) == 1 && a.Args[0] != "readonly":
+			return fn.errorf(a, "kit:tx takes no argument but readonly")
+		case !HasError(*fn):
+			return fn.errorf(a, "kit:tx requires the method to return an error")
+		case !takesContext(*fn):
+			return fn.errorf(a, "kit:tx requires the method to take a context.Context")
+		}
+		fn.Tx, fn.TxReadOnly = true, len(a.Args) == 1
+	}
+	return nil
+}
+
+// takesContext reports whether f takes a context.Context.
+func takesContext(f Func) bool {
+	for _, p := range f.Params {
+		if p.Type == "context.Context" {
+			return true
+		}
+	}
+	return false
+}
+
+// UsesTx reports whether any method runs in a transaction.
+func (s Service) UsesTx() bool {
+	for _, f := range s.Funcs {
+		if f.Tx {
+			return true
+		}
+	}
+	return false
+}
+
+const txTemplate = `
+{{ define "tx" }}
+// Tx is a transaction begun by a TxManager. *sql.Tx is one.
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
+
+// TxOptions are the options of a transaction.
+type TxOptions struct {
+	ReadOnly bool // set for the methods annotated with kit:tx readonly
+}
+
+// TxManager begins the transactions of the methods run in one by
+// TxMiddleware.
+type TxManager interface {
+	Begin(ctx context.Context, opts TxOptions) (Tx, error)
+}
+
+// SQLTxManager begins database/sql transactions on DB.
+type SQLTxManager struct {
+	DB *sql.DB
+}
+
+// Begin begins a transaction with the default isolation level of the driver.
+func (m SQLTxManager) Begin(ctx context.Context, opts TxOptions) (Tx, error) {
+	return m.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: opts.ReadOnly})
+}
+
+type txKey struct{}
+
+// ContextWithTx returns a copy of ctx carrying tx.
+func ContextWithTx(ctx context.Context, tx Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext returns the transaction of the call ctx belongs to, if any.
+// Repositories take it from there to make their queries in it, e.g. with
+// tx.(*sql.Tx) for an SQLTxManager.
+func TxFromContext(ctx context.Context) (Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(Tx)
+	return tx, ok
+}
+
+// TxMiddleware returns a service middleware running the methods annotated
+// with kit:tx in a transaction begun by m and carried by their context. The
+// transaction is committed if the method returns a nil error and rolled
+// back if it returns an error or panics. A method called in a transaction
+// already, by another one, joins it.
+func TxMiddleware(m TxManager) func({{ .IFace }}) {{ .IFace }} {
+	return func(next {{ .IFace }}) {{ .IFace }} {
+		return txMiddleware{next, m}
+	}
+}
+
+type txMiddleware struct {
+	{{ .IFace }}
+	m TxManager
+}
+{{ range $fun := .Funcs }}{{ if .Tx }}
+func (mw txMiddleware) {{ .Name }}{{ Signature . }} {
+	if _, ok := TxFromContext({{ ContextArg . }}); ok {
+		return mw.{{ $.IFaceName }}.{{ .Name }}({{ CallArgs . }})
+	}
+	tx, beginErr := mw.m.Begin({{ ContextArg . }}, TxOptions{ReadOnly: {{ .TxReadOnly }}})
+	if beginErr != nil {
+		{{ ErrorName . }} = beginErr
+		return
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if {{ ErrorName . }} != nil {
+			tx.Rollback()
+			return
+		}
+		{{ ErrorName . }} = tx.Commit()
+	}()
+	{{ ContextArg . }} = ContextWithTx({{ ContextArg . }}, tx)
+	return mw.{{ $.IFaceName }}.{{ .Name }}({{ CallArgs . }})
+}
+{{ end }}{{ end }}{{ end }}
+`
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
+)
+
+// typedFiles are the files of a package parsed by typeSpec, along with
+// their types once type checked.
+type typedFiles struct {
+	files   []*ast.File
+	info    *types.Info
+	checked bool
+}
+
+// sourceImporter imports packages by type checking them from source, as
+// found by importPackage, so that the build context and the package driver
+// apply to them as well. Packages that fail to type check are imported as
+// far as they do.
+type sourceImporter struct {
+	fset *token.FileSet
+	pkgs map[string]*types.Package // by import path
+}
+
+// importer imports the packages the type checked packages depend on,
+// sharing them across the packages.
+var importer = &sourceImporter{fset: token.NewFileSet(), pkgs: map[string]*types.Package{}}
+
+func (imp *sourceImporter) Import(path string) (*types.Package, error) {
+	return imp.ImportFrom(path, "", 0)
+}
+
+func (imp *sourceImporter) ImportFrom(path, dir string, _ types.ImportMode) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	bp, err := importPackage(path, dir)
+	if err != nil {
+		return nil, err
+	}
+	if pkg, ok := imp.pkgs[bp.ImportPath]; ok {
+		return pkg, nil
+	}
+	var files []*ast.File
+	for _, file := range append(append([]string(nil), bp.GoFiles...), bp.CgoFiles...) {
+		f, err := parser.ParseFile(imp.fset, filepath.Join(bp.Dir, file), nil, 0)
+		if err != nil {
+			continue
+		}
+		files = append(files, f)
+	}
+	pkg := types.NewPackage(bp.ImportPath, bp.Name)
+	imp.pkgs[bp.ImportPath] = pkg
+	_ = types.NewChecker(typesConfig(), imp.fset, pkg, nil).Files(files)
+	return pkg, nil
+}
+
+// typesConfig returns the configuration packages are type checked with,
+// ignoring their errors: only the types of declarations matter.
+func typesConfig() *types.Config {
+	return &types.Config{
+		Importer:         importer,
+		FakeImportC:      true,
+		IgnoreFuncBodies: true,
+		Error:            func(error) {},
+	}
+}
+
+// typesInfo returns the types of the expressions of the files of p, type
+// checking them on first use, or nil if p wasn't parsed by typeSpec.
+func (p Pkg) typesInfo() *types.Info {
+	if p.typed == nil {
+		return nil
+	}
+	if !p.typed.checked {
+		p.typed.checked = true
+		p.typed.info = &types.Info{Types: map[ast.Expr]types.TypeAndValue{}}
+		_, _ = typesConfig().Check(p.ImportPath, p.FileSet, p.typed.files, p.typed.info)
+	}
+	return p.typed.info
+}
+
+// resolveType returns the type e as referred to in generated code, its
+// packages qualified by the names they declare, and the import paths of
+// those packages. Types that don't type check are resolved from their
+// syntax by fullType and typeImports instead.
+func (p Pkg) resolveType(e ast.Expr) (string, []string) {
+	x, prefix := e, ""
+	if ell, ok := e.(*ast.Ellipsis); ok {
+		x, prefix = ell.Elt, "..."
+	}
+	if info := p.typesInfo(); info != nil {
+		if tv, ok := info.Types[x]; ok && tv.IsType() {
+			var paths []string
+			typ := types.TypeString(tv.Type, func(pkg *types.Package) string {
+				paths = append(paths, pkg.Path())
+				p.registerImport(pkg.Name(), pkg.Path())
+				return pkg.Name()
+			})
+			if !strings.Contains(typ, "invalid type") {
+				return prefix + typ, paths
+			}
+		}
+	}
+	typ := p.fullType(e)
+	return typ, p.typeImports(typ)
+}
+
+// typeOf returns the type checked type e, or nil if it doesn't type check.
+func (p Pkg) typeOf(e ast.Expr) types.Type {
+	if info := p.typesInfo(); info != nil {
+		if tv, ok := info.Types[e]; ok && tv.IsType() && tv.Type != types.Typ[types.Invalid] {
+			return tv.Type
+		}
+	}
+	return nil
+}
+
+// registerImport records that the package path declares name and is
+// referred to as such in the types of p, for importPathOf, unless name
+// refers to another package.
+func (p Pkg) registerImport(name, path string) {
+	packageNames[path] = name
+	names, ok := importNames[p.Dir]
+	if !ok {
+		names = p.parseImportNames()
+		importNames[p.Dir] = names
+	}
+	if _, ok := names[name]; !ok && name != p.Name {
+		names[name] = path
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"go/ast"
+	"strconv"
+	"strings"
+)
+
+// unionDiscriminator is the JSON property naming the concrete type of a
+// union.
+const unionDiscriminator = "type"
+
+// Union is an interface result encoded as one of a set of concrete types,
+// see kit:oneof.
+type Union struct {
+	Name     string // name of the generated type holding the result, e.g. "GetShapeShape"
+	Type     string // interface type of the result, e.g. "model.Shape"
+	Variants []Variant
+}
+
+// Variant is a concrete type of a union.
+type Variant struct {
+	Name    string // value of the discriminator, the name of the type
+	Type    TypeRef
+	Pointer bool
+}
+
+// GoType returns the type of v as referred to in generated code, e.g. "*model.Square".
+func (v Variant) GoType() string {
+	if v.Pointer {
+		return "*" + v.Type.String()
+	}
+	return v.Type.String()
+}
+
+// resolveUnions sets the unions of the interface results of the methods of
+// fns listed by their "//kit:oneof <result> <Type>..." annotations. Every
+// type must be a struct and is qualified like those of kit:request, with a
+// leading * if it implements the interface with a pointer receiver.
+func resolveUnions(fns []Func) error {
+	for i := range fns {
+		fn := &fns[i]
+		for _, a := range fn.Annotations {
+			if a.Name != "oneof" {
+				continue
+			}
+			if len(a.Args) < 2 {
+				return fn.errorf(a, "kit:oneof takes a result name and at least one type")
+			}
+			if fn.ResponseType != nil {
+				return fn.errorf(a, "kit:oneof can't be combined with kit:response")
+			}
+			var r *Param
+			for j := range fn.Res {
+				if fn.Res[j].Name == a.Args[0] && fn.Res[j].Type != "error" {
+					r = &fn.Res[j]
+				}
+			}
+			if r == nil {
+				return fn.errorf(a, "kit:oneof %s: no such result", a.Args[0])
+			}
+			if r.Union != nil {
+				return fn.errorf(a, "kit:oneof %s: already annotated", r.Name)
+			}
+			if _, spec, err := lookupType(fn, a, r.Type); err != nil {
+				return err
+			} else if _, ok := spec.Type.(*ast.InterfaceType); !ok {
+				return fn.errorf(a, "kit:oneof %s: %s is not an interface", r.Name, r.Type)
+			}
+
+			u := &Union{Name: fn.Name + r.Field, Type: r.Type}
+			names := map[string]bool{}
+			for _, typ := range a.Args[1:] {
+				ref, fields, err := structType(fn, a, strings.TrimPrefix(typ, "*"))
+				if err != nil {
+					return err
+				}
+				if field, ok := fields.match(unionDiscriminator); ok {
+					return fn.errorf(a, "kit:oneof %s: field %s clashes with the %q discriminator", typ, field, unionDiscriminator)
+				}
+				if names[ref.Name] {
+					return fn.errorf(a, "kit:oneof %s: more than one type named %s", r.Name, ref.Name)
+				}
+				names[ref.Name] = true
+				u.Variants = append(u.Variants, Variant{Name: ref.Name, Type: *ref, Pointer: strings.HasPrefix(typ, "*")})
+				fn.RequiredImports = append(fn.RequiredImports, ref.Path)
+			}
+			r.Union = u
+		}
+	}
+	return nil
+}
+
+// UsesUnions reports whether any method has a union result.
+func (s Service) UsesUnions() bool {
+	for _, f := range s.Funcs {
+		for _, r := range f.Res {
+			if r.Union != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// union returns the schema of the union u, whose component lists the
+// components of its variants: the components of their types extended by
+// the discriminator.
+func (s *Spec) union(p Pkg, u *Union) *Schema {
+	c := s.add(u.Name, "")
+	c.Discriminator = unionDiscriminator
+	for _, v := range u.Variants {
+		base := s.named(p, v.Type.Path, v.Type.Name)
+		variant := s.add(u.Name+v.Name, "")
+		variant.AllOf = base.Ref
+		variant.Properties = []Property{{Name: unionDiscriminator, Schema: &Schema{Type: "string", Enum: []string{strconv.Quote(v.Name)}}}}
+		c.OneOf = append(c.OneOf, Property{Name: v.Name, Schema: &Schema{Type: "object", Ref: variant.Name}})
+	}
+	return &Schema{Type: "object", Ref: c.Name, Nullable: true}
+}
+
+const unionTemplate = `
+{{ define "union" }}
+// {{ .Name }} holds a {{ .Type }}, one of{{ range $i, $v := .Variants }}{{ if $i }},{{ end }} {{ .GoType }}{{ end }}.
+// It is encoded as the JSON object of the value with a "type" property
+// naming its type.
+type {{ .Name }} struct {
+	Value {{ .Type }}
+}
+
+// MarshalJSON encodes u.Value with a "type" property naming its type.
+func (u {{ .Name }}) MarshalJSON() ([]byte, error) {
+	var typ string
+	switch u.Value.(type) {
+	case nil:
+		return []byte("null"), nil{{ range .Variants }}
+	case {{ .GoType }}:
+		typ = "{{ .Name }}"{{ end }}
+	default:
+		return nil, fmt.Errorf("{{ .Name }}: unexpected type %T", u.Value)
+	}
+	data, err := json.Marshal(u.Value)
+	if err != nil || string(data) == "null" {
+		return data, err
+	}
+	if len(data) < 2 || data[0] != '{' {
+		return nil, fmt.Errorf("{{ .Name }}: %T is not encoded as an object", u.Value)
+	}
+	head := "{\"type\":\"" + typ + "\""
+	if len(data) > 2 {
+		head += ","
+	}
+	return append([]byte(head), data[1:]...), nil
+}
+
+// UnmarshalJSON decodes u.Value as the type named by the "type" property.
+func (u *{{ .Name }}) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		u.Value = nil
+		return nil
+	}
+	var head struct {
+		Type string ` + "`json:\"type\"`" + `
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return err
+	}
+	switch head.Type { {{ range .Variants }}
+	case "{{ .Name }}":
+		{{ if .Pointer }}v := new({{ .Type }}){{ else }}var v {{ .Type }}{{ end }}
+		if err := json.Unmarshal(data, {{ if not .Pointer }}&{{ end }}v); err != nil {
+			return err
+		}
+		u.Value = v{{ end }}
+	default:
+		return fmt.Errorf("{{ .Name }}: unknown type %q", head.Type)
+	}
+	return nil
+}
+{{ end }}
+`
@@ -0,0 +1,49 @@
+package main
+
+// resolveUnwrap marks the methods of fns annotated with "//kit:unwrap" as
+// encoding their single non-error result as is, instead of as the field of
+// a response struct. A []byte result is written as the raw body of the
+// response.
+func resolveUnwrap(fns []Func) error {
+	for i := range fns {
+		fn := &fns[i]
+		a, ok := fn.Annotation("unwrap")
+		if !ok {
+			continue
+		}
+		if len(a.Args) != 0 {
+			return fn.errorf(a, "kit:unwrap takes no arguments")
+		}
+		res := FilterError(fn.Res)
+		if len(res) != 1 {
+			return fn.errorf(a, "kit:unwrap requires a single non-error result")
+		}
+		if fn.ResponseType != nil {
+			return fn.errorf(a, "kit:unwrap can't be combined with kit:response")
+		}
+		if fn.ETag != nil {
+			return fn.errorf(a, "kit:unwrap can't be combined with kit:etag, the ETag is read from a field of the result")
+		}
+		if res[0].Union != nil {
+			return fn.errorf(a, "kit:unwrap can't be combined with kit:oneof, a union is encoded as a field")
+		}
+		fn.Unwrap = true
+	}
+	return nil
+}
+
+// UnwrappedResult returns the result of f encoded as the whole response,
+// if f is annotated with kit:unwrap.
+func (f Func) UnwrappedResult() *Param {
+	if !f.Unwrap {
+		return nil
+	}
+	return &FilterError(f.Res)[0]
+}
+
+// RawBytes reports whether f writes its unwrapped []byte result as the
+// body of the response.
+func (f Func) RawBytes() bool {
+	r := f.UnwrappedResult()
+	return r != nil && (r.Type == "[]byte" || r.Type == "[]uint8")
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"go/ast"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// TypeRef refers to a named type declared in a package.
+type TypeRef struct {
+	Path string // import path of the package
+	Pkg  string // name of the package
+	Name string
+}
+
+// String returns the type as referred to in generated code, e.g. "types.CreateUserRequest".
+func (t TypeRef) String() string {
+	return t.Pkg + "." + t.Name
+}
+
+// resolveUserTypes maps the methods of fns annotated with
+// "//kit:request type=<Type>" or "//kit:response type=<Type>" to existing
+// struct types, which are used instead of generated request or response
+// types. The type is either qualified by the name of a package imported by
+// the interface, or by its import path. Every parameter (or result) must
+// have a field in the struct, matched by name, ignoring case, or by JSON name.
+func resolveUserTypes(fns []Func) error {
+	for i := range fns {
+		fn := &fns[i]
+		if a, ok := fn.Annotation("request"); ok {
+			typ, fields, err := userType(fn, a)
+			if err != nil {
+				return err
+			}
+			fn.OptionSetters = nil
+			for j := range fn.Params {
+				p := &fn.Params[j]
+				if p.Type == "context.Context" {
+					continue
+				}
+				if p.Field, ok = fields.match(p.Name); !ok {
+					return fn.errorf(a, "kit:request %s: no field for parameter %s", typ, p.Name)
+				}
+				if IsOptionSetter(p.Type) {
+					fn.OptionSetters = append(fn.OptionSetters, fn.src.generateOptionSetters(p.Field, p.Type)...)
+				}
+			}
+			fn.RequestType = typ
+		}
+		if a, ok := fn.Annotation("response"); ok {
+			typ, fields, err := userType(fn, a)
+			if err != nil {
+				return err
+			}
+			for j := range fn.Res {
+				r := &fn.Res[j]
+				if r.Type == "error" {
+					continue
+				}
+				if r.Field, ok = fields.match(r.Name); !ok {
+					return fn.errorf(a, "kit:response %s: no field for result %s", typ, r.Name)
+				}
+			}
+			fn.ResponseType = typ
+		}
+	}
+	return nil
+}
+
+// structFields maps the lower case Go and JSON names of the fields of a
+// struct to their Go names.
+type structFields map[string]string
+
+func (fs structFields) match(name string) (string, bool) {
+	field, ok := fs[strings.ToLower(name)]
+	return field, ok
+}
+
+// userType returns the type named by the type argument of the annotation a
+// of fn and its fields.
+func userType(fn *Func, a Annotation) (*TypeRef, structFields, error) {
+	typ, ok := a.Arg("type")
+	if !ok {
+		return nil, nil, fn.errorf(a, "kit:%s takes a type=<pkg.Type> argument", a.Name)
+	}
+	return structType(fn, a, typ)
+}
+
+// lookupType returns the type typ named by the annotation a of fn and its
+// declaration. typ is either qualified by the name of a package imported by
+// the interface, or by its import path.
+func lookupType(fn *Func, a Annotation, typ string) (*TypeRef, *ast.TypeSpec, error) {
+	dot := strings.LastIndex(typ, ".")
+	path := fn.src.ImportPath
+	if dot >= 0 {
+		if path = typ[:dot]; !strings.Contains(path, "/") {
+			path = fn.src.importPathOf(path)
+		}
+	}
+	if path == "" {
+		return nil, nil, fn.errorf(a, "kit:%s %s: package %s not imported", a.Name, typ, typ[:dot])
+	}
+	pkg, spec, err := typeSpec(path, typ[dot+1:], fn.src.srcDir)
+	if err != nil {
+		return nil, nil, fn.errorf(a, "kit:%s: %v", a.Name, err)
+	}
+	return &TypeRef{Path: path, Pkg: pkg.Name, Name: typ[dot+1:]}, spec, nil
+}
+
+// structType returns the struct type typ named by the annotation a of fn
+// and its fields.
+func structType(fn *Func, a Annotation, typ string) (*TypeRef, structFields, error) {
+	ref, spec, err := lookupType(fn, a, typ)
+	if err != nil {
+		return nil, nil, err
+	}
+	st, ok := spec.Type.(*ast.StructType)
+	if !ok {
+		return nil, nil, fn.errorf(a, "kit:%s %s: not a struct", a.Name, typ)
+	}
+
+	fields := structFields{}
+	for _, field := range st.Fields.List {
+		tag := ""
+		if field.Tag != nil {
+			tag, _ = strconv.Unquote(field.Tag.Value)
+		}
+		jsonName := strings.Split(reflect.StructTag(tag).Get("json"), ",")[0]
+		for _, name := range field.Names {
+			if !name.IsExported() {
+				continue
+			}
+			fields[strings.ToLower(name.Name)] = name.Name
+			if jsonName != "" && jsonName != "-" {
+				fields[strings.ToLower(jsonName)] = name.Name
+			}
+		}
+	}
+	return ref, fields, nil
+}
+
+// Request returns the request type of f as referred to in the main package.
+func (s Service) Request(f Func) string {
+	if f.RequestType != nil {
+		return f.RequestType.String()
+	}
+	return s.DTOQual() + f.RequestName
+}
+
+// Response returns the response type of f as referred to in the main package,
+// the type of its result if it's unwrapped.
+func (s Service) Response(f Func) string {
+	if f.ResponseType != nil {
+		return f.ResponseType.String()
+	}
+	if r := f.UnwrappedResult(); r != nil {
+		return r.Type
+	}
+	return s.DTOQual() + f.ResponseName
+}